@@ -47,7 +47,7 @@ func (x Cmd_Kind) String() string {
 }
 
 func (Cmd_Kind) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{25, 0}
+	return fileDescriptor_dd1789c19b148ca6, []int{40, 0}
 }
 
 type Cmd_Scope int32
@@ -75,7 +75,7 @@ func (x Cmd_Scope) String() string {
 }
 
 func (Cmd_Scope) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{25, 1}
+	return fileDescriptor_dd1789c19b148ca6, []int{40, 1}
 }
 
 type Empty struct {
@@ -149,7 +149,10 @@ func (m *Result) GetOk() bool {
 }
 
 type Query struct {
-	Query                string   `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Query string `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	// secrets asks for a stored user's decrypted secrets, they're left out
+	// otherwise. Only storeadmin extensions may ask for them.
+	Secrets              bool     `protobuf:"varint,2,opt,name=secrets,proto3" json:"secrets,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -187,6 +190,13 @@ func (m *Query) GetQuery() string {
 	return ""
 }
 
+func (m *Query) GetSecrets() bool {
+	if m != nil {
+		return m.Secrets
+	}
+	return false
+}
+
 type ListResponse struct {
 	List                 []string `protobuf:"bytes,1,rep,name=list,proto3" json:"list,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -268,6 +278,9 @@ func (m *CountResponse) GetCount() int32 {
 type Access struct {
 	Level                uint32   `protobuf:"varint,1,opt,name=level,proto3" json:"level,omitempty"`
 	Flags                uint64   `protobuf:"varint,2,opt,name=flags,proto3" json:"flags,omitempty"`
+	Expires              int64    `protobuf:"varint,3,opt,name=expires,proto3" json:"expires,omitempty"`
+	DenyLevel            uint32   `protobuf:"varint,4,opt,name=deny_level,json=denyLevel,proto3" json:"deny_level,omitempty"`
+	DenyFlags            uint64   `protobuf:"varint,5,opt,name=deny_flags,json=denyFlags,proto3" json:"deny_flags,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -312,6 +325,27 @@ func (m *Access) GetFlags() uint64 {
 	return 0
 }
 
+func (m *Access) GetExpires() int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return 0
+}
+
+func (m *Access) GetDenyLevel() uint32 {
+	if m != nil {
+		return m.DenyLevel
+	}
+	return 0
+}
+
+func (m *Access) GetDenyFlags() uint64 {
+	if m != nil {
+		return m.DenyFlags
+	}
+	return 0
+}
+
 type ChannelModes struct {
 	Modes                map[string]bool                      `protobuf:"bytes,1,rep,name=modes,proto3" json:"modes,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
 	ArgModes             map[string]string                    `protobuf:"bytes,2,rep,name=arg_modes,json=argModes,proto3" json:"arg_modes,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
@@ -425,6 +459,8 @@ func (m *ChannelModes_AddressMode) GetModeAddresses() []string {
 type StateUser struct {
 	Host                 string   `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
 	Realname             string   `protobuf:"bytes,2,opt,name=realname,proto3" json:"realname,omitempty"`
+	Account              string   `protobuf:"bytes,3,opt,name=account,proto3" json:"account,omitempty"`
+	Certfp               string   `protobuf:"bytes,4,opt,name=certfp,proto3" json:"certfp,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -469,20 +505,93 @@ func (m *StateUser) GetRealname() string {
 	return ""
 }
 
+func (m *StateUser) GetAccount() string {
+	if m != nil {
+		return m.Account
+	}
+	return ""
+}
+
+func (m *StateUser) GetCertfp() string {
+	if m != nil {
+		return m.Certfp
+	}
+	return ""
+}
+
+type TopicChange struct {
+	Topic                string   `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Setter               string   `protobuf:"bytes,2,opt,name=setter,proto3" json:"setter,omitempty"`
+	Time                 int64    `protobuf:"varint,3,opt,name=time,proto3" json:"time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TopicChange) Reset()         { *m = TopicChange{} }
+func (m *TopicChange) String() string { return proto.CompactTextString(m) }
+func (*TopicChange) ProtoMessage()    {}
+func (*TopicChange) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{8}
+}
+
+func (m *TopicChange) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TopicChange.Unmarshal(m, b)
+}
+func (m *TopicChange) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TopicChange.Marshal(b, m, deterministic)
+}
+func (m *TopicChange) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TopicChange.Merge(m, src)
+}
+func (m *TopicChange) XXX_Size() int {
+	return xxx_messageInfo_TopicChange.Size(m)
+}
+func (m *TopicChange) XXX_DiscardUnknown() {
+	xxx_messageInfo_TopicChange.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TopicChange proto.InternalMessageInfo
+
+func (m *TopicChange) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *TopicChange) GetSetter() string {
+	if m != nil {
+		return m.Setter
+	}
+	return ""
+}
+
+func (m *TopicChange) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
 type StateChannel struct {
-	Name                 string        `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Topic                string        `protobuf:"bytes,2,opt,name=topic,proto3" json:"topic,omitempty"`
-	Modes                *ChannelModes `protobuf:"bytes,3,opt,name=modes,proto3" json:"modes,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
-	XXX_unrecognized     []byte        `json:"-"`
-	XXX_sizecache        int32         `json:"-"`
+	Name                 string         `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Topic                string         `protobuf:"bytes,2,opt,name=topic,proto3" json:"topic,omitempty"`
+	Modes                *ChannelModes  `protobuf:"bytes,3,opt,name=modes,proto3" json:"modes,omitempty"`
+	TopicSetter          string         `protobuf:"bytes,4,opt,name=topic_setter,json=topicSetter,proto3" json:"topic_setter,omitempty"`
+	TopicTime            int64          `protobuf:"varint,5,opt,name=topic_time,json=topicTime,proto3" json:"topic_time,omitempty"`
+	Created              int64          `protobuf:"varint,6,opt,name=created,proto3" json:"created,omitempty"`
+	TopicHistory         []*TopicChange `protobuf:"bytes,7,rep,name=topic_history,json=topicHistory,proto3" json:"topic_history,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
 }
 
 func (m *StateChannel) Reset()         { *m = StateChannel{} }
 func (m *StateChannel) String() string { return proto.CompactTextString(m) }
 func (*StateChannel) ProtoMessage()    {}
 func (*StateChannel) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{8}
+	return fileDescriptor_dd1789c19b148ca6, []int{9}
 }
 
 func (m *StateChannel) XXX_Unmarshal(b []byte) error {
@@ -524,6 +633,34 @@ func (m *StateChannel) GetModes() *ChannelModes {
 	return nil
 }
 
+func (m *StateChannel) GetTopicSetter() string {
+	if m != nil {
+		return m.TopicSetter
+	}
+	return ""
+}
+
+func (m *StateChannel) GetTopicTime() int64 {
+	if m != nil {
+		return m.TopicTime
+	}
+	return 0
+}
+
+func (m *StateChannel) GetCreated() int64 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+func (m *StateChannel) GetTopicHistory() []*TopicChange {
+	if m != nil {
+		return m.TopicHistory
+	}
+	return nil
+}
+
 type UserModes struct {
 	Kinds                *ModeKinds `protobuf:"bytes,1,opt,name=kinds,proto3" json:"kinds,omitempty"`
 	Modes                int32      `protobuf:"varint,2,opt,name=modes,proto3" json:"modes,omitempty"`
@@ -536,7 +673,7 @@ func (m *UserModes) Reset()         { *m = UserModes{} }
 func (m *UserModes) String() string { return proto.CompactTextString(m) }
 func (*UserModes) ProtoMessage()    {}
 func (*UserModes) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{9}
+	return fileDescriptor_dd1789c19b148ca6, []int{10}
 }
 
 func (m *UserModes) XXX_Unmarshal(b []byte) error {
@@ -583,7 +720,7 @@ func (m *ModeKinds) Reset()         { *m = ModeKinds{} }
 func (m *ModeKinds) String() string { return proto.CompactTextString(m) }
 func (*ModeKinds) ProtoMessage()    {}
 func (*ModeKinds) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{10}
+	return fileDescriptor_dd1789c19b148ca6, []int{11}
 }
 
 func (m *ModeKinds) XXX_Unmarshal(b []byte) error {
@@ -630,7 +767,7 @@ func (m *ModeKinds_UserPrefix) Reset()         { *m = ModeKinds_UserPrefix{} }
 func (m *ModeKinds_UserPrefix) String() string { return proto.CompactTextString(m) }
 func (*ModeKinds_UserPrefix) ProtoMessage()    {}
 func (*ModeKinds_UserPrefix) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{10, 0}
+	return fileDescriptor_dd1789c19b148ca6, []int{11, 0}
 }
 
 func (m *ModeKinds_UserPrefix) XXX_Unmarshal(b []byte) error {
@@ -693,7 +830,7 @@ func (m *NetworkInfo) Reset()         { *m = NetworkInfo{} }
 func (m *NetworkInfo) String() string { return proto.CompactTextString(m) }
 func (*NetworkInfo) ProtoMessage()    {}
 func (*NetworkInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{11}
+	return fileDescriptor_dd1789c19b148ca6, []int{12}
 }
 
 func (m *NetworkInfo) XXX_Unmarshal(b []byte) error {
@@ -840,22 +977,157 @@ func (m *NetworkInfo) GetExtras() map[string]string {
 	return nil
 }
 
+type LinkedAccount struct {
+	Account              string   `protobuf:"bytes,1,opt,name=account,proto3" json:"account,omitempty"`
+	RequireMask          bool     `protobuf:"varint,2,opt,name=require_mask,json=requireMask,proto3" json:"require_mask,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LinkedAccount) Reset()         { *m = LinkedAccount{} }
+func (m *LinkedAccount) String() string { return proto.CompactTextString(m) }
+func (*LinkedAccount) ProtoMessage()    {}
+func (*LinkedAccount) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{13}
+}
+
+func (m *LinkedAccount) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LinkedAccount.Unmarshal(m, b)
+}
+func (m *LinkedAccount) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LinkedAccount.Marshal(b, m, deterministic)
+}
+func (m *LinkedAccount) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LinkedAccount.Merge(m, src)
+}
+func (m *LinkedAccount) XXX_Size() int {
+	return xxx_messageInfo_LinkedAccount.Size(m)
+}
+func (m *LinkedAccount) XXX_DiscardUnknown() {
+	xxx_messageInfo_LinkedAccount.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LinkedAccount proto.InternalMessageInfo
+
+func (m *LinkedAccount) GetAccount() string {
+	if m != nil {
+		return m.Account
+	}
+	return ""
+}
+
+func (m *LinkedAccount) GetRequireMask() bool {
+	if m != nil {
+		return m.RequireMask
+	}
+	return false
+}
+
+type RoleList struct {
+	Roles                []string `protobuf:"bytes,1,rep,name=roles,proto3" json:"roles,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RoleList) Reset()         { *m = RoleList{} }
+func (m *RoleList) String() string { return proto.CompactTextString(m) }
+func (*RoleList) ProtoMessage()    {}
+func (*RoleList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{14}
+}
+
+func (m *RoleList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RoleList.Unmarshal(m, b)
+}
+func (m *RoleList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RoleList.Marshal(b, m, deterministic)
+}
+func (m *RoleList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RoleList.Merge(m, src)
+}
+func (m *RoleList) XXX_Size() int {
+	return xxx_messageInfo_RoleList.Size(m)
+}
+func (m *RoleList) XXX_DiscardUnknown() {
+	xxx_messageInfo_RoleList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RoleList proto.InternalMessageInfo
+
+func (m *RoleList) GetRoles() []string {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+type PermissionList struct {
+	Permissions          []string `protobuf:"bytes,1,rep,name=permissions,proto3" json:"permissions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PermissionList) Reset()         { *m = PermissionList{} }
+func (m *PermissionList) String() string { return proto.CompactTextString(m) }
+func (*PermissionList) ProtoMessage()    {}
+func (*PermissionList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{15}
+}
+
+func (m *PermissionList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PermissionList.Unmarshal(m, b)
+}
+func (m *PermissionList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PermissionList.Marshal(b, m, deterministic)
+}
+func (m *PermissionList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PermissionList.Merge(m, src)
+}
+func (m *PermissionList) XXX_Size() int {
+	return xxx_messageInfo_PermissionList.Size(m)
+}
+func (m *PermissionList) XXX_DiscardUnknown() {
+	xxx_messageInfo_PermissionList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PermissionList proto.InternalMessageInfo
+
+func (m *PermissionList) GetPermissions() []string {
+	if m != nil {
+		return m.Permissions
+	}
+	return nil
+}
+
 type StoredUser struct {
-	Username             string             `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
-	Password             []byte             `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
-	Masks                []string           `protobuf:"bytes,3,rep,name=masks,proto3" json:"masks,omitempty"`
-	Access               map[string]*Access `protobuf:"bytes,4,rep,name=access,proto3" json:"access,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	Data                 map[string]string  `protobuf:"bytes,5,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
-	XXX_unrecognized     []byte             `json:"-"`
-	XXX_sizecache        int32              `json:"-"`
+	Username    string                     `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Password    []byte                     `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	Masks       []string                   `protobuf:"bytes,3,rep,name=masks,proto3" json:"masks,omitempty"`
+	Access      map[string]*Access         `protobuf:"bytes,4,rep,name=access,proto3" json:"access,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Data        map[string]string          `protobuf:"bytes,5,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Accounts    map[string]*LinkedAccount  `protobuf:"bytes,6,rep,name=accounts,proto3" json:"accounts,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Certs       []string                   `protobuf:"bytes,7,rep,name=certs,proto3" json:"certs,omitempty"`
+	Roles       map[string]*RoleList       `protobuf:"bytes,8,rep,name=roles,proto3" json:"roles,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Permissions map[string]*PermissionList `protobuf:"bytes,9,rep,name=permissions,proto3" json:"permissions,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// revision is the number of times the user was saved, a put only
+	// succeeds if it's still the revision that's saved.
+	Revision uint64 `protobuf:"varint,10,opt,name=revision,proto3" json:"revision,omitempty"`
+	// secrets are only sent when they're asked for, they're encrypted when a
+	// put saves them and a put keeps the secrets it doesn't have.
+	Secrets              map[string]string `protobuf:"bytes,11,rep,name=secrets,proto3" json:"secrets,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *StoredUser) Reset()         { *m = StoredUser{} }
 func (m *StoredUser) String() string { return proto.CompactTextString(m) }
 func (*StoredUser) ProtoMessage()    {}
 func (*StoredUser) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{12}
+	return fileDescriptor_dd1789c19b148ca6, []int{16}
 }
 
 func (m *StoredUser) XXX_Unmarshal(b []byte) error {
@@ -911,10 +1183,58 @@ func (m *StoredUser) GetData() map[string]string {
 	return nil
 }
 
+func (m *StoredUser) GetAccounts() map[string]*LinkedAccount {
+	if m != nil {
+		return m.Accounts
+	}
+	return nil
+}
+
+func (m *StoredUser) GetCerts() []string {
+	if m != nil {
+		return m.Certs
+	}
+	return nil
+}
+
+func (m *StoredUser) GetRoles() map[string]*RoleList {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+func (m *StoredUser) GetPermissions() map[string]*PermissionList {
+	if m != nil {
+		return m.Permissions
+	}
+	return nil
+}
+
+func (m *StoredUser) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *StoredUser) GetSecrets() map[string]string {
+	if m != nil {
+		return m.Secrets
+	}
+	return nil
+}
+
 type StoredChannel struct {
-	Net                  string            `protobuf:"bytes,1,opt,name=net,proto3" json:"net,omitempty"`
-	Name                 string            `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Data                 map[string]string `protobuf:"bytes,3,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Net  string            `protobuf:"bytes,1,opt,name=net,proto3" json:"net,omitempty"`
+	Name string            `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Data map[string]string `protobuf:"bytes,3,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// revision is the number of times the channel was saved, a put only
+	// succeeds if it's still the revision that's saved.
+	Revision uint64 `protobuf:"varint,4,opt,name=revision,proto3" json:"revision,omitempty"`
+	// secrets are only sent when they're asked for, they're encrypted when a
+	// put saves them and a put keeps the secrets it doesn't have.
+	Secrets              map[string]string `protobuf:"bytes,5,rep,name=secrets,proto3" json:"secrets,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
 	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
 	XXX_unrecognized     []byte            `json:"-"`
 	XXX_sizecache        int32             `json:"-"`
@@ -924,7 +1244,7 @@ func (m *StoredChannel) Reset()         { *m = StoredChannel{} }
 func (m *StoredChannel) String() string { return proto.CompactTextString(m) }
 func (*StoredChannel) ProtoMessage()    {}
 func (*StoredChannel) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{13}
+	return fileDescriptor_dd1789c19b148ca6, []int{17}
 }
 
 func (m *StoredChannel) XXX_Unmarshal(b []byte) error {
@@ -966,9 +1286,25 @@ func (m *StoredChannel) GetData() map[string]string {
 	return nil
 }
 
+func (m *StoredChannel) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *StoredChannel) GetSecrets() map[string]string {
+	if m != nil {
+		return m.Secrets
+	}
+	return nil
+}
+
 type SelfResponse struct {
 	User                 *StateUser    `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
 	Modes                *ChannelModes `protobuf:"bytes,2,opt,name=modes,proto3" json:"modes,omitempty"`
+	UserModes            string        `protobuf:"bytes,3,opt,name=user_modes,json=userModes,proto3" json:"user_modes,omitempty"`
+	Oper                 bool          `protobuf:"varint,4,opt,name=oper,proto3" json:"oper,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
 	XXX_unrecognized     []byte        `json:"-"`
 	XXX_sizecache        int32         `json:"-"`
@@ -978,7 +1314,7 @@ func (m *SelfResponse) Reset()         { *m = SelfResponse{} }
 func (m *SelfResponse) String() string { return proto.CompactTextString(m) }
 func (*SelfResponse) ProtoMessage()    {}
 func (*SelfResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{14}
+	return fileDescriptor_dd1789c19b148ca6, []int{18}
 }
 
 func (m *SelfResponse) XXX_Unmarshal(b []byte) error {
@@ -1013,9 +1349,26 @@ func (m *SelfResponse) GetModes() *ChannelModes {
 	return nil
 }
 
+func (m *SelfResponse) GetUserModes() string {
+	if m != nil {
+		return m.UserModes
+	}
+	return ""
+}
+
+func (m *SelfResponse) GetOper() bool {
+	if m != nil {
+		return m.Oper
+	}
+	return false
+}
+
 type NetworkQuery struct {
-	Net                  string   `protobuf:"bytes,1,opt,name=net,proto3" json:"net,omitempty"`
-	Query                string   `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	Net   string `protobuf:"bytes,1,opt,name=net,proto3" json:"net,omitempty"`
+	Query string `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	// secrets asks for a stored channel's decrypted secrets, they're left out
+	// otherwise. Only storeadmin extensions may ask for them.
+	Secrets              bool     `protobuf:"varint,3,opt,name=secrets,proto3" json:"secrets,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -1025,7 +1378,7 @@ func (m *NetworkQuery) Reset()         { *m = NetworkQuery{} }
 func (m *NetworkQuery) String() string { return proto.CompactTextString(m) }
 func (*NetworkQuery) ProtoMessage()    {}
 func (*NetworkQuery) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{15}
+	return fileDescriptor_dd1789c19b148ca6, []int{19}
 }
 
 func (m *NetworkQuery) XXX_Unmarshal(b []byte) error {
@@ -1060,6 +1413,13 @@ func (m *NetworkQuery) GetQuery() string {
 	return ""
 }
 
+func (m *NetworkQuery) GetSecrets() bool {
+	if m != nil {
+		return m.Secrets
+	}
+	return false
+}
+
 type ChannelQuery struct {
 	Net                  string   `protobuf:"bytes,1,opt,name=net,proto3" json:"net,omitempty"`
 	Channel              string   `protobuf:"bytes,2,opt,name=channel,proto3" json:"channel,omitempty"`
@@ -1073,7 +1433,7 @@ func (m *ChannelQuery) Reset()         { *m = ChannelQuery{} }
 func (m *ChannelQuery) String() string { return proto.CompactTextString(m) }
 func (*ChannelQuery) ProtoMessage()    {}
 func (*ChannelQuery) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{16}
+	return fileDescriptor_dd1789c19b148ca6, []int{20}
 }
 
 func (m *ChannelQuery) XXX_Unmarshal(b []byte) error {
@@ -1130,7 +1490,7 @@ func (m *AuthUserRequest) Reset()         { *m = AuthUserRequest{} }
 func (m *AuthUserRequest) String() string { return proto.CompactTextString(m) }
 func (*AuthUserRequest) ProtoMessage()    {}
 func (*AuthUserRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{17}
+	return fileDescriptor_dd1789c19b148ca6, []int{21}
 }
 
 func (m *AuthUserRequest) XXX_Unmarshal(b []byte) error {
@@ -1197,7 +1557,7 @@ func (m *UserResponse) Reset()         { *m = UserResponse{} }
 func (m *UserResponse) String() string { return proto.CompactTextString(m) }
 func (*UserResponse) ProtoMessage()    {}
 func (*UserResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{18}
+	return fileDescriptor_dd1789c19b148ca6, []int{22}
 }
 
 func (m *UserResponse) XXX_Unmarshal(b []byte) error {
@@ -1236,7 +1596,7 @@ func (m *UserModesResponse) Reset()         { *m = UserModesResponse{} }
 func (m *UserModesResponse) String() string { return proto.CompactTextString(m) }
 func (*UserModesResponse) ProtoMessage()    {}
 func (*UserModesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{19}
+	return fileDescriptor_dd1789c19b148ca6, []int{23}
 }
 
 func (m *UserModesResponse) XXX_Unmarshal(b []byte) error {
@@ -1265,19 +1625,23 @@ func (m *UserModesResponse) GetModes() *ChannelModes {
 }
 
 type ChannelResponse struct {
-	Name                 string        `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Topic                string        `protobuf:"bytes,2,opt,name=topic,proto3" json:"topic,omitempty"`
-	Modes                *ChannelModes `protobuf:"bytes,3,opt,name=modes,proto3" json:"modes,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
-	XXX_unrecognized     []byte        `json:"-"`
-	XXX_sizecache        int32         `json:"-"`
+	Name                 string         `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Topic                string         `protobuf:"bytes,2,opt,name=topic,proto3" json:"topic,omitempty"`
+	Modes                *ChannelModes  `protobuf:"bytes,3,opt,name=modes,proto3" json:"modes,omitempty"`
+	TopicSetter          string         `protobuf:"bytes,4,opt,name=topic_setter,json=topicSetter,proto3" json:"topic_setter,omitempty"`
+	TopicTime            int64          `protobuf:"varint,5,opt,name=topic_time,json=topicTime,proto3" json:"topic_time,omitempty"`
+	Created              int64          `protobuf:"varint,6,opt,name=created,proto3" json:"created,omitempty"`
+	TopicHistory         []*TopicChange `protobuf:"bytes,7,rep,name=topic_history,json=topicHistory,proto3" json:"topic_history,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
 }
 
 func (m *ChannelResponse) Reset()         { *m = ChannelResponse{} }
 func (m *ChannelResponse) String() string { return proto.CompactTextString(m) }
 func (*ChannelResponse) ProtoMessage()    {}
 func (*ChannelResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{20}
+	return fileDescriptor_dd1789c19b148ca6, []int{24}
 }
 
 func (m *ChannelResponse) XXX_Unmarshal(b []byte) error {
@@ -1319,21 +1683,49 @@ func (m *ChannelResponse) GetModes() *ChannelModes {
 	return nil
 }
 
-type StoredUsersResponse struct {
-	Users                []*StoredUser `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
-	XXX_unrecognized     []byte        `json:"-"`
-	XXX_sizecache        int32         `json:"-"`
+func (m *ChannelResponse) GetTopicSetter() string {
+	if m != nil {
+		return m.TopicSetter
+	}
+	return ""
 }
 
-func (m *StoredUsersResponse) Reset()         { *m = StoredUsersResponse{} }
-func (m *StoredUsersResponse) String() string { return proto.CompactTextString(m) }
-func (*StoredUsersResponse) ProtoMessage()    {}
-func (*StoredUsersResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{21}
+func (m *ChannelResponse) GetTopicTime() int64 {
+	if m != nil {
+		return m.TopicTime
+	}
+	return 0
 }
 
-func (m *StoredUsersResponse) XXX_Unmarshal(b []byte) error {
+func (m *ChannelResponse) GetCreated() int64 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+func (m *ChannelResponse) GetTopicHistory() []*TopicChange {
+	if m != nil {
+		return m.TopicHistory
+	}
+	return nil
+}
+
+type StoredUsersResponse struct {
+	Users                []*StoredUser `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *StoredUsersResponse) Reset()         { *m = StoredUsersResponse{} }
+func (m *StoredUsersResponse) String() string { return proto.CompactTextString(m) }
+func (*StoredUsersResponse) ProtoMessage()    {}
+func (*StoredUsersResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{25}
+}
+
+func (m *StoredUsersResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_StoredUsersResponse.Unmarshal(m, b)
 }
 func (m *StoredUsersResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
@@ -1369,7 +1761,7 @@ func (m *StoredChannelsResponse) Reset()         { *m = StoredChannelsResponse{}
 func (m *StoredChannelsResponse) String() string { return proto.CompactTextString(m) }
 func (*StoredChannelsResponse) ProtoMessage()    {}
 func (*StoredChannelsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{22}
+	return fileDescriptor_dd1789c19b148ca6, []int{26}
 }
 
 func (m *StoredChannelsResponse) XXX_Unmarshal(b []byte) error {
@@ -1397,6 +1789,597 @@ func (m *StoredChannelsResponse) GetChannels() []*StoredChannel {
 	return nil
 }
 
+type StoreCacheStatsResponse struct {
+	Hits                 uint64   `protobuf:"varint,1,opt,name=hits,proto3" json:"hits,omitempty"`
+	Misses               uint64   `protobuf:"varint,2,opt,name=misses,proto3" json:"misses,omitempty"`
+	Evictions            uint64   `protobuf:"varint,3,opt,name=evictions,proto3" json:"evictions,omitempty"`
+	Size                 int32    `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	Capacity             int32    `protobuf:"varint,5,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StoreCacheStatsResponse) Reset()         { *m = StoreCacheStatsResponse{} }
+func (m *StoreCacheStatsResponse) String() string { return proto.CompactTextString(m) }
+func (*StoreCacheStatsResponse) ProtoMessage()    {}
+func (*StoreCacheStatsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{27}
+}
+
+func (m *StoreCacheStatsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StoreCacheStatsResponse.Unmarshal(m, b)
+}
+func (m *StoreCacheStatsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StoreCacheStatsResponse.Marshal(b, m, deterministic)
+}
+func (m *StoreCacheStatsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StoreCacheStatsResponse.Merge(m, src)
+}
+func (m *StoreCacheStatsResponse) XXX_Size() int {
+	return xxx_messageInfo_StoreCacheStatsResponse.Size(m)
+}
+func (m *StoreCacheStatsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StoreCacheStatsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StoreCacheStatsResponse proto.InternalMessageInfo
+
+func (m *StoreCacheStatsResponse) GetHits() uint64 {
+	if m != nil {
+		return m.Hits
+	}
+	return 0
+}
+
+func (m *StoreCacheStatsResponse) GetMisses() uint64 {
+	if m != nil {
+		return m.Misses
+	}
+	return 0
+}
+
+func (m *StoreCacheStatsResponse) GetEvictions() uint64 {
+	if m != nil {
+		return m.Evictions
+	}
+	return 0
+}
+
+func (m *StoreCacheStatsResponse) GetSize() int32 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *StoreCacheStatsResponse) GetCapacity() int32 {
+	if m != nil {
+		return m.Capacity
+	}
+	return 0
+}
+
+type Lockout struct {
+	Username             string   `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Network              string   `protobuf:"bytes,2,opt,name=network,proto3" json:"network,omitempty"`
+	Host                 string   `protobuf:"bytes,3,opt,name=host,proto3" json:"host,omitempty"`
+	Failures             int32    `protobuf:"varint,4,opt,name=failures,proto3" json:"failures,omitempty"`
+	LastFailure          int64    `protobuf:"varint,5,opt,name=last_failure,json=lastFailure,proto3" json:"last_failure,omitempty"`
+	Until                int64    `protobuf:"varint,6,opt,name=until,proto3" json:"until,omitempty"`
+	Locked               bool     `protobuf:"varint,7,opt,name=locked,proto3" json:"locked,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Lockout) Reset()         { *m = Lockout{} }
+func (m *Lockout) String() string { return proto.CompactTextString(m) }
+func (*Lockout) ProtoMessage()    {}
+func (*Lockout) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{28}
+}
+
+func (m *Lockout) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Lockout.Unmarshal(m, b)
+}
+func (m *Lockout) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Lockout.Marshal(b, m, deterministic)
+}
+func (m *Lockout) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Lockout.Merge(m, src)
+}
+func (m *Lockout) XXX_Size() int {
+	return xxx_messageInfo_Lockout.Size(m)
+}
+func (m *Lockout) XXX_DiscardUnknown() {
+	xxx_messageInfo_Lockout.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Lockout proto.InternalMessageInfo
+
+func (m *Lockout) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *Lockout) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+func (m *Lockout) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+func (m *Lockout) GetFailures() int32 {
+	if m != nil {
+		return m.Failures
+	}
+	return 0
+}
+
+func (m *Lockout) GetLastFailure() int64 {
+	if m != nil {
+		return m.LastFailure
+	}
+	return 0
+}
+
+func (m *Lockout) GetUntil() int64 {
+	if m != nil {
+		return m.Until
+	}
+	return 0
+}
+
+func (m *Lockout) GetLocked() bool {
+	if m != nil {
+		return m.Locked
+	}
+	return false
+}
+
+type LockoutsResponse struct {
+	Lockouts             []*Lockout `protobuf:"bytes,1,rep,name=lockouts,proto3" json:"lockouts,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *LockoutsResponse) Reset()         { *m = LockoutsResponse{} }
+func (m *LockoutsResponse) String() string { return proto.CompactTextString(m) }
+func (*LockoutsResponse) ProtoMessage()    {}
+func (*LockoutsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{29}
+}
+
+func (m *LockoutsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LockoutsResponse.Unmarshal(m, b)
+}
+func (m *LockoutsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LockoutsResponse.Marshal(b, m, deterministic)
+}
+func (m *LockoutsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LockoutsResponse.Merge(m, src)
+}
+func (m *LockoutsResponse) XXX_Size() int {
+	return xxx_messageInfo_LockoutsResponse.Size(m)
+}
+func (m *LockoutsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LockoutsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LockoutsResponse proto.InternalMessageInfo
+
+func (m *LockoutsResponse) GetLockouts() []*Lockout {
+	if m != nil {
+		return m.Lockouts
+	}
+	return nil
+}
+
+type BackupResponse struct {
+	Filename             string   `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Time                 int64    `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	Keys                 int32    `protobuf:"varint,3,opt,name=keys,proto3" json:"keys,omitempty"`
+	Removed              []string `protobuf:"bytes,4,rep,name=removed,proto3" json:"removed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BackupResponse) Reset()         { *m = BackupResponse{} }
+func (m *BackupResponse) String() string { return proto.CompactTextString(m) }
+func (*BackupResponse) ProtoMessage()    {}
+func (*BackupResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{30}
+}
+
+func (m *BackupResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BackupResponse.Unmarshal(m, b)
+}
+func (m *BackupResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BackupResponse.Marshal(b, m, deterministic)
+}
+func (m *BackupResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BackupResponse.Merge(m, src)
+}
+func (m *BackupResponse) XXX_Size() int {
+	return xxx_messageInfo_BackupResponse.Size(m)
+}
+func (m *BackupResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BackupResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BackupResponse proto.InternalMessageInfo
+
+func (m *BackupResponse) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *BackupResponse) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *BackupResponse) GetKeys() int32 {
+	if m != nil {
+		return m.Keys
+	}
+	return 0
+}
+
+func (m *BackupResponse) GetRemoved() []string {
+	if m != nil {
+		return m.Removed
+	}
+	return nil
+}
+
+type AuditQuery struct {
+	Actor                string   `protobuf:"bytes,1,opt,name=actor,proto3" json:"actor,omitempty"`
+	Target               string   `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	User                 string   `protobuf:"bytes,3,opt,name=user,proto3" json:"user,omitempty"`
+	Action               string   `protobuf:"bytes,4,opt,name=action,proto3" json:"action,omitempty"`
+	Net                  string   `protobuf:"bytes,5,opt,name=net,proto3" json:"net,omitempty"`
+	Channel              string   `protobuf:"bytes,6,opt,name=channel,proto3" json:"channel,omitempty"`
+	Since                int64    `protobuf:"varint,7,opt,name=since,proto3" json:"since,omitempty"`
+	Limit                int32    `protobuf:"varint,8,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuditQuery) Reset()         { *m = AuditQuery{} }
+func (m *AuditQuery) String() string { return proto.CompactTextString(m) }
+func (*AuditQuery) ProtoMessage()    {}
+func (*AuditQuery) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{31}
+}
+
+func (m *AuditQuery) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuditQuery.Unmarshal(m, b)
+}
+func (m *AuditQuery) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuditQuery.Marshal(b, m, deterministic)
+}
+func (m *AuditQuery) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditQuery.Merge(m, src)
+}
+func (m *AuditQuery) XXX_Size() int {
+	return xxx_messageInfo_AuditQuery.Size(m)
+}
+func (m *AuditQuery) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditQuery.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditQuery proto.InternalMessageInfo
+
+func (m *AuditQuery) GetActor() string {
+	if m != nil {
+		return m.Actor
+	}
+	return ""
+}
+
+func (m *AuditQuery) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *AuditQuery) GetUser() string {
+	if m != nil {
+		return m.User
+	}
+	return ""
+}
+
+func (m *AuditQuery) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+func (m *AuditQuery) GetNet() string {
+	if m != nil {
+		return m.Net
+	}
+	return ""
+}
+
+func (m *AuditQuery) GetChannel() string {
+	if m != nil {
+		return m.Channel
+	}
+	return ""
+}
+
+func (m *AuditQuery) GetSince() int64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+func (m *AuditQuery) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type AuditEntry struct {
+	Time                 int64    `protobuf:"varint,1,opt,name=time,proto3" json:"time,omitempty"`
+	Actor                string   `protobuf:"bytes,2,opt,name=actor,proto3" json:"actor,omitempty"`
+	Source               string   `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`
+	Action               string   `protobuf:"bytes,4,opt,name=action,proto3" json:"action,omitempty"`
+	Target               string   `protobuf:"bytes,5,opt,name=target,proto3" json:"target,omitempty"`
+	Net                  string   `protobuf:"bytes,6,opt,name=net,proto3" json:"net,omitempty"`
+	Channel              string   `protobuf:"bytes,7,opt,name=channel,proto3" json:"channel,omitempty"`
+	Before               string   `protobuf:"bytes,8,opt,name=before,proto3" json:"before,omitempty"`
+	After                string   `protobuf:"bytes,9,opt,name=after,proto3" json:"after,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AuditEntry) Reset()         { *m = AuditEntry{} }
+func (m *AuditEntry) String() string { return proto.CompactTextString(m) }
+func (*AuditEntry) ProtoMessage()    {}
+func (*AuditEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{32}
+}
+
+func (m *AuditEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuditEntry.Unmarshal(m, b)
+}
+func (m *AuditEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuditEntry.Marshal(b, m, deterministic)
+}
+func (m *AuditEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditEntry.Merge(m, src)
+}
+func (m *AuditEntry) XXX_Size() int {
+	return xxx_messageInfo_AuditEntry.Size(m)
+}
+func (m *AuditEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditEntry proto.InternalMessageInfo
+
+func (m *AuditEntry) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *AuditEntry) GetActor() string {
+	if m != nil {
+		return m.Actor
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetNet() string {
+	if m != nil {
+		return m.Net
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetChannel() string {
+	if m != nil {
+		return m.Channel
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetBefore() string {
+	if m != nil {
+		return m.Before
+	}
+	return ""
+}
+
+func (m *AuditEntry) GetAfter() string {
+	if m != nil {
+		return m.After
+	}
+	return ""
+}
+
+type AuditResponse struct {
+	Entries              []*AuditEntry `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *AuditResponse) Reset()         { *m = AuditResponse{} }
+func (m *AuditResponse) String() string { return proto.CompactTextString(m) }
+func (*AuditResponse) ProtoMessage()    {}
+func (*AuditResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{33}
+}
+
+func (m *AuditResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuditResponse.Unmarshal(m, b)
+}
+func (m *AuditResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuditResponse.Marshal(b, m, deterministic)
+}
+func (m *AuditResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuditResponse.Merge(m, src)
+}
+func (m *AuditResponse) XXX_Size() int {
+	return xxx_messageInfo_AuditResponse.Size(m)
+}
+func (m *AuditResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuditResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AuditResponse proto.InternalMessageInfo
+
+func (m *AuditResponse) GetEntries() []*AuditEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type Role struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Access               *Access  `protobuf:"bytes,2,opt,name=access,proto3" json:"access,omitempty"`
+	Config               bool     `protobuf:"varint,3,opt,name=config,proto3" json:"config,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Role) Reset()         { *m = Role{} }
+func (m *Role) String() string { return proto.CompactTextString(m) }
+func (*Role) ProtoMessage()    {}
+func (*Role) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{34}
+}
+
+func (m *Role) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Role.Unmarshal(m, b)
+}
+func (m *Role) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Role.Marshal(b, m, deterministic)
+}
+func (m *Role) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Role.Merge(m, src)
+}
+func (m *Role) XXX_Size() int {
+	return xxx_messageInfo_Role.Size(m)
+}
+func (m *Role) XXX_DiscardUnknown() {
+	xxx_messageInfo_Role.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Role proto.InternalMessageInfo
+
+func (m *Role) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Role) GetAccess() *Access {
+	if m != nil {
+		return m.Access
+	}
+	return nil
+}
+
+func (m *Role) GetConfig() bool {
+	if m != nil {
+		return m.Config
+	}
+	return false
+}
+
+type RolesResponse struct {
+	Roles                []*Role  `protobuf:"bytes,1,rep,name=roles,proto3" json:"roles,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RolesResponse) Reset()         { *m = RolesResponse{} }
+func (m *RolesResponse) String() string { return proto.CompactTextString(m) }
+func (*RolesResponse) ProtoMessage()    {}
+func (*RolesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{35}
+}
+
+func (m *RolesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RolesResponse.Unmarshal(m, b)
+}
+func (m *RolesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RolesResponse.Marshal(b, m, deterministic)
+}
+func (m *RolesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RolesResponse.Merge(m, src)
+}
+func (m *RolesResponse) XXX_Size() int {
+	return xxx_messageInfo_RolesResponse.Size(m)
+}
+func (m *RolesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RolesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RolesResponse proto.InternalMessageInfo
+
+func (m *RolesResponse) GetRoles() []*Role {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
 type LogoutRequest struct {
 	// Types that are valid to be assigned to Query:
 	//	*LogoutRequest_HostUser_
@@ -1411,7 +2394,7 @@ func (m *LogoutRequest) Reset()         { *m = LogoutRequest{} }
 func (m *LogoutRequest) String() string { return proto.CompactTextString(m) }
 func (*LogoutRequest) ProtoMessage()    {}
 func (*LogoutRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{23}
+	return fileDescriptor_dd1789c19b148ca6, []int{36}
 }
 
 func (m *LogoutRequest) XXX_Unmarshal(b []byte) error {
@@ -1489,7 +2472,7 @@ func (m *LogoutRequest_HostUser) Reset()         { *m = LogoutRequest_HostUser{}
 func (m *LogoutRequest_HostUser) String() string { return proto.CompactTextString(m) }
 func (*LogoutRequest_HostUser) ProtoMessage()    {}
 func (*LogoutRequest_HostUser) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{23, 0}
+	return fileDescriptor_dd1789c19b148ca6, []int{36, 0}
 }
 
 func (m *LogoutRequest_HostUser) XXX_Unmarshal(b []byte) error {
@@ -1535,7 +2518,7 @@ func (m *NetworkInfoRequest) Reset()         { *m = NetworkInfoRequest{} }
 func (m *NetworkInfoRequest) String() string { return proto.CompactTextString(m) }
 func (*NetworkInfoRequest) ProtoMessage()    {}
 func (*NetworkInfoRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{24}
+	return fileDescriptor_dd1789c19b148ca6, []int{37}
 }
 
 func (m *NetworkInfoRequest) XXX_Unmarshal(b []byte) error {
@@ -1547,22 +2530,189 @@ func (m *NetworkInfoRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte,
 func (m *NetworkInfoRequest) XXX_Merge(src proto.Message) {
 	xxx_messageInfo_NetworkInfoRequest.Merge(m, src)
 }
-func (m *NetworkInfoRequest) XXX_Size() int {
-	return xxx_messageInfo_NetworkInfoRequest.Size(m)
+func (m *NetworkInfoRequest) XXX_Size() int {
+	return xxx_messageInfo_NetworkInfoRequest.Size(m)
+}
+func (m *NetworkInfoRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NetworkInfoRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NetworkInfoRequest proto.InternalMessageInfo
+
+func (m *NetworkInfoRequest) GetNet() string {
+	if m != nil {
+		return m.Net
+	}
+	return ""
+}
+
+// UserSearchQuery searches state for users, when net is empty all networks
+// are searched. Results are paged using offset and limit.
+type UserSearchQuery struct {
+	Net                  string   `protobuf:"bytes,1,opt,name=net,proto3" json:"net,omitempty"`
+	Query                string   `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	Channels             []string `protobuf:"bytes,3,rep,name=channels,proto3" json:"channels,omitempty"`
+	Offset               int32    `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit                int32    `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UserSearchQuery) Reset()         { *m = UserSearchQuery{} }
+func (m *UserSearchQuery) String() string { return proto.CompactTextString(m) }
+func (*UserSearchQuery) ProtoMessage()    {}
+func (*UserSearchQuery) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{38}
+}
+
+func (m *UserSearchQuery) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserSearchQuery.Unmarshal(m, b)
+}
+func (m *UserSearchQuery) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserSearchQuery.Marshal(b, m, deterministic)
+}
+func (m *UserSearchQuery) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserSearchQuery.Merge(m, src)
+}
+func (m *UserSearchQuery) XXX_Size() int {
+	return xxx_messageInfo_UserSearchQuery.Size(m)
+}
+func (m *UserSearchQuery) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserSearchQuery.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UserSearchQuery proto.InternalMessageInfo
+
+func (m *UserSearchQuery) GetNet() string {
+	if m != nil {
+		return m.Net
+	}
+	return ""
+}
+
+func (m *UserSearchQuery) GetQuery() string {
+	if m != nil {
+		return m.Query
+	}
+	return ""
+}
+
+func (m *UserSearchQuery) GetChannels() []string {
+	if m != nil {
+		return m.Channels
+	}
+	return nil
+}
+
+func (m *UserSearchQuery) GetOffset() int32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *UserSearchQuery) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type UserSearchResponse struct {
+	Results              []*UserSearchResponse_Result `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	Total                int32                        `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
+	XXX_unrecognized     []byte                       `json:"-"`
+	XXX_sizecache        int32                        `json:"-"`
+}
+
+func (m *UserSearchResponse) Reset()         { *m = UserSearchResponse{} }
+func (m *UserSearchResponse) String() string { return proto.CompactTextString(m) }
+func (*UserSearchResponse) ProtoMessage()    {}
+func (*UserSearchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{39}
+}
+
+func (m *UserSearchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserSearchResponse.Unmarshal(m, b)
+}
+func (m *UserSearchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserSearchResponse.Marshal(b, m, deterministic)
+}
+func (m *UserSearchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserSearchResponse.Merge(m, src)
+}
+func (m *UserSearchResponse) XXX_Size() int {
+	return xxx_messageInfo_UserSearchResponse.Size(m)
+}
+func (m *UserSearchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserSearchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UserSearchResponse proto.InternalMessageInfo
+
+func (m *UserSearchResponse) GetResults() []*UserSearchResponse_Result {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+func (m *UserSearchResponse) GetTotal() int32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+type UserSearchResponse_Result struct {
+	Net                  string     `protobuf:"bytes,1,opt,name=net,proto3" json:"net,omitempty"`
+	User                 *StateUser `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *UserSearchResponse_Result) Reset()         { *m = UserSearchResponse_Result{} }
+func (m *UserSearchResponse_Result) String() string { return proto.CompactTextString(m) }
+func (*UserSearchResponse_Result) ProtoMessage()    {}
+func (*UserSearchResponse_Result) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{39, 0}
+}
+
+func (m *UserSearchResponse_Result) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserSearchResponse_Result.Unmarshal(m, b)
+}
+func (m *UserSearchResponse_Result) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserSearchResponse_Result.Marshal(b, m, deterministic)
+}
+func (m *UserSearchResponse_Result) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserSearchResponse_Result.Merge(m, src)
 }
-func (m *NetworkInfoRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_NetworkInfoRequest.DiscardUnknown(m)
+func (m *UserSearchResponse_Result) XXX_Size() int {
+	return xxx_messageInfo_UserSearchResponse_Result.Size(m)
+}
+func (m *UserSearchResponse_Result) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserSearchResponse_Result.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_NetworkInfoRequest proto.InternalMessageInfo
+var xxx_messageInfo_UserSearchResponse_Result proto.InternalMessageInfo
 
-func (m *NetworkInfoRequest) GetNet() string {
+func (m *UserSearchResponse_Result) GetNet() string {
 	if m != nil {
 		return m.Net
 	}
 	return ""
 }
 
+func (m *UserSearchResponse_Result) GetUser() *StateUser {
+	if m != nil {
+		return m.User
+	}
+	return nil
+}
+
 type Cmd struct {
 	Ext                  string    `protobuf:"bytes,1,opt,name=ext,proto3" json:"ext,omitempty"`
 	Name                 string    `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
@@ -1573,6 +2723,7 @@ type Cmd struct {
 	RequireAuth          bool      `protobuf:"varint,7,opt,name=require_auth,json=requireAuth,proto3" json:"require_auth,omitempty"`
 	ReqLevel             int32     `protobuf:"varint,8,opt,name=req_level,json=reqLevel,proto3" json:"req_level,omitempty"`
 	ReqFlags             string    `protobuf:"bytes,9,opt,name=req_flags,json=reqFlags,proto3" json:"req_flags,omitempty"`
+	ReqPerms             []string  `protobuf:"bytes,10,rep,name=req_perms,json=reqPerms,proto3" json:"req_perms,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
 	XXX_unrecognized     []byte    `json:"-"`
 	XXX_sizecache        int32     `json:"-"`
@@ -1582,7 +2733,7 @@ func (m *Cmd) Reset()         { *m = Cmd{} }
 func (m *Cmd) String() string { return proto.CompactTextString(m) }
 func (*Cmd) ProtoMessage()    {}
 func (*Cmd) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{25}
+	return fileDescriptor_dd1789c19b148ca6, []int{40}
 }
 
 func (m *Cmd) XXX_Unmarshal(b []byte) error {
@@ -1666,6 +2817,13 @@ func (m *Cmd) GetReqFlags() string {
 	return ""
 }
 
+func (m *Cmd) GetReqPerms() []string {
+	if m != nil {
+		return m.ReqPerms
+	}
+	return nil
+}
+
 type CmdEvent struct {
 	IrcEvent                  *IRCEvent              `protobuf:"bytes,1,opt,name=ircEvent,proto3" json:"ircEvent,omitempty"`
 	User                      *StateUser             `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
@@ -1687,7 +2845,7 @@ func (m *CmdEvent) Reset()         { *m = CmdEvent{} }
 func (m *CmdEvent) String() string { return proto.CompactTextString(m) }
 func (*CmdEvent) ProtoMessage()    {}
 func (*CmdEvent) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{26}
+	return fileDescriptor_dd1789c19b148ca6, []int{41}
 }
 
 func (m *CmdEvent) XXX_Unmarshal(b []byte) error {
@@ -1798,7 +2956,7 @@ func (m *CmdEventResponse) Reset()         { *m = CmdEventResponse{} }
 func (m *CmdEventResponse) String() string { return proto.CompactTextString(m) }
 func (*CmdEventResponse) ProtoMessage()    {}
 func (*CmdEventResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{27}
+	return fileDescriptor_dd1789c19b148ca6, []int{42}
 }
 
 func (m *CmdEventResponse) XXX_Unmarshal(b []byte) error {
@@ -1840,9 +2998,13 @@ func (m *CmdEventResponse) GetEvent() *CmdEvent {
 	return nil
 }
 
+// IRCEventResponse is an event for a registered handler. If verdict is set
+// the handler was registered as a pre handler, the bot waits for PreVerdict
+// to be called with it before dispatching the event any further.
 type IRCEventResponse struct {
 	Id                   uint64    `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	Event                *IRCEvent `protobuf:"bytes,2,opt,name=event,proto3" json:"event,omitempty"`
+	Verdict              uint64    `protobuf:"varint,3,opt,name=verdict,proto3" json:"verdict,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
 	XXX_unrecognized     []byte    `json:"-"`
 	XXX_sizecache        int32     `json:"-"`
@@ -1852,7 +3014,7 @@ func (m *IRCEventResponse) Reset()         { *m = IRCEventResponse{} }
 func (m *IRCEventResponse) String() string { return proto.CompactTextString(m) }
 func (*IRCEventResponse) ProtoMessage()    {}
 func (*IRCEventResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{28}
+	return fileDescriptor_dd1789c19b148ca6, []int{43}
 }
 
 func (m *IRCEventResponse) XXX_Unmarshal(b []byte) error {
@@ -1887,6 +3049,13 @@ func (m *IRCEventResponse) GetEvent() *IRCEvent {
 	return nil
 }
 
+func (m *IRCEventResponse) GetVerdict() uint64 {
+	if m != nil {
+		return m.Verdict
+	}
+	return 0
+}
+
 type IRCEvent struct {
 	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Sender               string   `protobuf:"bytes,2,opt,name=sender,proto3" json:"sender,omitempty"`
@@ -1902,7 +3071,7 @@ func (m *IRCEvent) Reset()         { *m = IRCEvent{} }
 func (m *IRCEvent) String() string { return proto.CompactTextString(m) }
 func (*IRCEvent) ProtoMessage()    {}
 func (*IRCEvent) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{29}
+	return fileDescriptor_dd1789c19b148ca6, []int{44}
 }
 
 func (m *IRCEvent) XXX_Unmarshal(b []byte) error {
@@ -1972,7 +3141,7 @@ func (m *RegisterCmdRequest) Reset()         { *m = RegisterCmdRequest{} }
 func (m *RegisterCmdRequest) String() string { return proto.CompactTextString(m) }
 func (*RegisterCmdRequest) ProtoMessage()    {}
 func (*RegisterCmdRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{30}
+	return fileDescriptor_dd1789c19b148ca6, []int{45}
 }
 
 func (m *RegisterCmdRequest) XXX_Unmarshal(b []byte) error {
@@ -2021,67 +3190,419 @@ func (m *RegisterCmdRequest) GetCmd() *Cmd {
 	return nil
 }
 
-type RegisterRequest struct {
+type Permission struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Desc                 string   `protobuf:"bytes,2,opt,name=desc,proto3" json:"desc,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Permission) Reset()         { *m = Permission{} }
+func (m *Permission) String() string { return proto.CompactTextString(m) }
+func (*Permission) ProtoMessage()    {}
+func (*Permission) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{46}
+}
+
+func (m *Permission) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Permission.Unmarshal(m, b)
+}
+func (m *Permission) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Permission.Marshal(b, m, deterministic)
+}
+func (m *Permission) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Permission.Merge(m, src)
+}
+func (m *Permission) XXX_Size() int {
+	return xxx_messageInfo_Permission.Size(m)
+}
+func (m *Permission) XXX_DiscardUnknown() {
+	xxx_messageInfo_Permission.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Permission proto.InternalMessageInfo
+
+func (m *Permission) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Permission) GetDesc() string {
+	if m != nil {
+		return m.Desc
+	}
+	return ""
+}
+
+type DeclarePermissionsRequest struct {
+	Ext                  string        `protobuf:"bytes,1,opt,name=ext,proto3" json:"ext,omitempty"`
+	Permissions          []*Permission `protobuf:"bytes,2,rep,name=permissions,proto3" json:"permissions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *DeclarePermissionsRequest) Reset()         { *m = DeclarePermissionsRequest{} }
+func (m *DeclarePermissionsRequest) String() string { return proto.CompactTextString(m) }
+func (*DeclarePermissionsRequest) ProtoMessage()    {}
+func (*DeclarePermissionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{47}
+}
+
+func (m *DeclarePermissionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeclarePermissionsRequest.Unmarshal(m, b)
+}
+func (m *DeclarePermissionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeclarePermissionsRequest.Marshal(b, m, deterministic)
+}
+func (m *DeclarePermissionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeclarePermissionsRequest.Merge(m, src)
+}
+func (m *DeclarePermissionsRequest) XXX_Size() int {
+	return xxx_messageInfo_DeclarePermissionsRequest.Size(m)
+}
+func (m *DeclarePermissionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeclarePermissionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeclarePermissionsRequest proto.InternalMessageInfo
+
+func (m *DeclarePermissionsRequest) GetExt() string {
+	if m != nil {
+		return m.Ext
+	}
+	return ""
+}
+
+func (m *DeclarePermissionsRequest) GetPermissions() []*Permission {
+	if m != nil {
+		return m.Permissions
+	}
+	return nil
+}
+
+// Setting is a typed channel setting, type is one of bool, int, duration,
+// enum or masks. Values are the choices of an enum.
+type Setting struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Ext                  string   `protobuf:"bytes,2,opt,name=ext,proto3" json:"ext,omitempty"`
+	Desc                 string   `protobuf:"bytes,3,opt,name=desc,proto3" json:"desc,omitempty"`
+	Type                 string   `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	Default              string   `protobuf:"bytes,5,opt,name=default,proto3" json:"default,omitempty"`
+	Values               []string `protobuf:"bytes,6,rep,name=values,proto3" json:"values,omitempty"`
+	Level                uint32   `protobuf:"varint,7,opt,name=level,proto3" json:"level,omitempty"`
+	Flags                string   `protobuf:"bytes,8,opt,name=flags,proto3" json:"flags,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Setting) Reset()         { *m = Setting{} }
+func (m *Setting) String() string { return proto.CompactTextString(m) }
+func (*Setting) ProtoMessage()    {}
+func (*Setting) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{48}
+}
+
+func (m *Setting) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Setting.Unmarshal(m, b)
+}
+func (m *Setting) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Setting.Marshal(b, m, deterministic)
+}
+func (m *Setting) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Setting.Merge(m, src)
+}
+func (m *Setting) XXX_Size() int {
+	return xxx_messageInfo_Setting.Size(m)
+}
+func (m *Setting) XXX_DiscardUnknown() {
+	xxx_messageInfo_Setting.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Setting proto.InternalMessageInfo
+
+func (m *Setting) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Setting) GetExt() string {
+	if m != nil {
+		return m.Ext
+	}
+	return ""
+}
+
+func (m *Setting) GetDesc() string {
+	if m != nil {
+		return m.Desc
+	}
+	return ""
+}
+
+func (m *Setting) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Setting) GetDefault() string {
+	if m != nil {
+		return m.Default
+	}
+	return ""
+}
+
+func (m *Setting) GetValues() []string {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+func (m *Setting) GetLevel() uint32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
+}
+
+func (m *Setting) GetFlags() string {
+	if m != nil {
+		return m.Flags
+	}
+	return ""
+}
+
+type DeclareSettingsRequest struct {
+	Ext                  string     `protobuf:"bytes,1,opt,name=ext,proto3" json:"ext,omitempty"`
+	Settings             []*Setting `protobuf:"bytes,2,rep,name=settings,proto3" json:"settings,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *DeclareSettingsRequest) Reset()         { *m = DeclareSettingsRequest{} }
+func (m *DeclareSettingsRequest) String() string { return proto.CompactTextString(m) }
+func (*DeclareSettingsRequest) ProtoMessage()    {}
+func (*DeclareSettingsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{49}
+}
+
+func (m *DeclareSettingsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeclareSettingsRequest.Unmarshal(m, b)
+}
+func (m *DeclareSettingsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeclareSettingsRequest.Marshal(b, m, deterministic)
+}
+func (m *DeclareSettingsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeclareSettingsRequest.Merge(m, src)
+}
+func (m *DeclareSettingsRequest) XXX_Size() int {
+	return xxx_messageInfo_DeclareSettingsRequest.Size(m)
+}
+func (m *DeclareSettingsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeclareSettingsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeclareSettingsRequest proto.InternalMessageInfo
+
+func (m *DeclareSettingsRequest) GetExt() string {
+	if m != nil {
+		return m.Ext
+	}
+	return ""
+}
+
+func (m *DeclareSettingsRequest) GetSettings() []*Setting {
+	if m != nil {
+		return m.Settings
+	}
+	return nil
+}
+
+type SettingsResponse struct {
+	Settings             []*Setting `protobuf:"bytes,1,rep,name=settings,proto3" json:"settings,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *SettingsResponse) Reset()         { *m = SettingsResponse{} }
+func (m *SettingsResponse) String() string { return proto.CompactTextString(m) }
+func (*SettingsResponse) ProtoMessage()    {}
+func (*SettingsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{50}
+}
+
+func (m *SettingsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SettingsResponse.Unmarshal(m, b)
+}
+func (m *SettingsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SettingsResponse.Marshal(b, m, deterministic)
+}
+func (m *SettingsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SettingsResponse.Merge(m, src)
+}
+func (m *SettingsResponse) XXX_Size() int {
+	return xxx_messageInfo_SettingsResponse.Size(m)
+}
+func (m *SettingsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SettingsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SettingsResponse proto.InternalMessageInfo
+
+func (m *SettingsResponse) GetSettings() []*Setting {
+	if m != nil {
+		return m.Settings
+	}
+	return nil
+}
+
+// RegisterRequest registers an event handler. Handlers of a higher priority
+// finish before those of a lower one start. A pre handler is given the event
+// before any other handler or command and can consume it with PreVerdict.
+type RegisterRequest struct {
+	Ext                  string   `protobuf:"bytes,1,opt,name=ext,proto3" json:"ext,omitempty"`
+	Network              string   `protobuf:"bytes,2,opt,name=network,proto3" json:"network,omitempty"`
+	Channel              string   `protobuf:"bytes,3,opt,name=channel,proto3" json:"channel,omitempty"`
+	Event                string   `protobuf:"bytes,4,opt,name=event,proto3" json:"event,omitempty"`
+	Priority             int32    `protobuf:"varint,5,opt,name=priority,proto3" json:"priority,omitempty"`
+	Pre                  bool     `protobuf:"varint,6,opt,name=pre,proto3" json:"pre,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RegisterRequest) Reset()         { *m = RegisterRequest{} }
+func (m *RegisterRequest) String() string { return proto.CompactTextString(m) }
+func (*RegisterRequest) ProtoMessage()    {}
+func (*RegisterRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{51}
+}
+
+func (m *RegisterRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RegisterRequest.Unmarshal(m, b)
+}
+func (m *RegisterRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RegisterRequest.Marshal(b, m, deterministic)
+}
+func (m *RegisterRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RegisterRequest.Merge(m, src)
+}
+func (m *RegisterRequest) XXX_Size() int {
+	return xxx_messageInfo_RegisterRequest.Size(m)
+}
+func (m *RegisterRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RegisterRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RegisterRequest proto.InternalMessageInfo
+
+func (m *RegisterRequest) GetExt() string {
+	if m != nil {
+		return m.Ext
+	}
+	return ""
+}
+
+func (m *RegisterRequest) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+func (m *RegisterRequest) GetChannel() string {
+	if m != nil {
+		return m.Channel
+	}
+	return ""
+}
+
+func (m *RegisterRequest) GetEvent() string {
+	if m != nil {
+		return m.Event
+	}
+	return ""
+}
+
+func (m *RegisterRequest) GetPriority() int32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
+func (m *RegisterRequest) GetPre() bool {
+	if m != nil {
+		return m.Pre
+	}
+	return false
+}
+
+// PreVerdictRequest answers an event sent to a pre handler, stop consumes it.
+type PreVerdictRequest struct {
 	Ext                  string   `protobuf:"bytes,1,opt,name=ext,proto3" json:"ext,omitempty"`
-	Network              string   `protobuf:"bytes,2,opt,name=network,proto3" json:"network,omitempty"`
-	Channel              string   `protobuf:"bytes,3,opt,name=channel,proto3" json:"channel,omitempty"`
-	Event                string   `protobuf:"bytes,4,opt,name=event,proto3" json:"event,omitempty"`
+	Verdict              uint64   `protobuf:"varint,2,opt,name=verdict,proto3" json:"verdict,omitempty"`
+	Stop                 bool     `protobuf:"varint,3,opt,name=stop,proto3" json:"stop,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *RegisterRequest) Reset()         { *m = RegisterRequest{} }
-func (m *RegisterRequest) String() string { return proto.CompactTextString(m) }
-func (*RegisterRequest) ProtoMessage()    {}
-func (*RegisterRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{31}
+func (m *PreVerdictRequest) Reset()         { *m = PreVerdictRequest{} }
+func (m *PreVerdictRequest) String() string { return proto.CompactTextString(m) }
+func (*PreVerdictRequest) ProtoMessage()    {}
+func (*PreVerdictRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{52}
 }
 
-func (m *RegisterRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_RegisterRequest.Unmarshal(m, b)
+func (m *PreVerdictRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PreVerdictRequest.Unmarshal(m, b)
 }
-func (m *RegisterRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_RegisterRequest.Marshal(b, m, deterministic)
+func (m *PreVerdictRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PreVerdictRequest.Marshal(b, m, deterministic)
 }
-func (m *RegisterRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_RegisterRequest.Merge(m, src)
+func (m *PreVerdictRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PreVerdictRequest.Merge(m, src)
 }
-func (m *RegisterRequest) XXX_Size() int {
-	return xxx_messageInfo_RegisterRequest.Size(m)
+func (m *PreVerdictRequest) XXX_Size() int {
+	return xxx_messageInfo_PreVerdictRequest.Size(m)
 }
-func (m *RegisterRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_RegisterRequest.DiscardUnknown(m)
+func (m *PreVerdictRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PreVerdictRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_RegisterRequest proto.InternalMessageInfo
+var xxx_messageInfo_PreVerdictRequest proto.InternalMessageInfo
 
-func (m *RegisterRequest) GetExt() string {
+func (m *PreVerdictRequest) GetExt() string {
 	if m != nil {
 		return m.Ext
 	}
 	return ""
 }
 
-func (m *RegisterRequest) GetNetwork() string {
-	if m != nil {
-		return m.Network
-	}
-	return ""
-}
-
-func (m *RegisterRequest) GetChannel() string {
+func (m *PreVerdictRequest) GetVerdict() uint64 {
 	if m != nil {
-		return m.Channel
+		return m.Verdict
 	}
-	return ""
+	return 0
 }
 
-func (m *RegisterRequest) GetEvent() string {
+func (m *PreVerdictRequest) GetStop() bool {
 	if m != nil {
-		return m.Event
+		return m.Stop
 	}
-	return ""
+	return false
 }
 
 type RegisterResponse struct {
@@ -2095,7 +3616,7 @@ func (m *RegisterResponse) Reset()         { *m = RegisterResponse{} }
 func (m *RegisterResponse) String() string { return proto.CompactTextString(m) }
 func (*RegisterResponse) ProtoMessage()    {}
 func (*RegisterResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{32}
+	return fileDescriptor_dd1789c19b148ca6, []int{53}
 }
 
 func (m *RegisterResponse) XXX_Unmarshal(b []byte) error {
@@ -2138,7 +3659,7 @@ func (m *SubscriptionRequest) Reset()         { *m = SubscriptionRequest{} }
 func (m *SubscriptionRequest) String() string { return proto.CompactTextString(m) }
 func (*SubscriptionRequest) ProtoMessage()    {}
 func (*SubscriptionRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{33}
+	return fileDescriptor_dd1789c19b148ca6, []int{54}
 }
 
 func (m *SubscriptionRequest) XXX_Unmarshal(b []byte) error {
@@ -2173,6 +3694,146 @@ func (m *SubscriptionRequest) GetIds() []uint64 {
 	return nil
 }
 
+type StoreChangesRequest struct {
+	// The extension name that is subscribing.
+	Ext string `protobuf:"bytes,1,opt,name=ext,proto3" json:"ext,omitempty"`
+	// The kinds of change to receive (user_saved, user_removed,
+	// session_opened, session_closed, channel_saved, channel_removed), may be
+	// omitted to receive every change.
+	Kinds                []string `protobuf:"bytes,2,rep,name=kinds,proto3" json:"kinds,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StoreChangesRequest) Reset()         { *m = StoreChangesRequest{} }
+func (m *StoreChangesRequest) String() string { return proto.CompactTextString(m) }
+func (*StoreChangesRequest) ProtoMessage()    {}
+func (*StoreChangesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{55}
+}
+
+func (m *StoreChangesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StoreChangesRequest.Unmarshal(m, b)
+}
+func (m *StoreChangesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StoreChangesRequest.Marshal(b, m, deterministic)
+}
+func (m *StoreChangesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StoreChangesRequest.Merge(m, src)
+}
+func (m *StoreChangesRequest) XXX_Size() int {
+	return xxx_messageInfo_StoreChangesRequest.Size(m)
+}
+func (m *StoreChangesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StoreChangesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StoreChangesRequest proto.InternalMessageInfo
+
+func (m *StoreChangesRequest) GetExt() string {
+	if m != nil {
+		return m.Ext
+	}
+	return ""
+}
+
+func (m *StoreChangesRequest) GetKinds() []string {
+	if m != nil {
+		return m.Kinds
+	}
+	return nil
+}
+
+// StoreChange is a change made to the store. Users have a username, sessions
+// have a username, network and host and channels have a network and channel.
+type StoreChange struct {
+	Kind                 string   `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Username             string   `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	Network              string   `protobuf:"bytes,3,opt,name=network,proto3" json:"network,omitempty"`
+	Host                 string   `protobuf:"bytes,4,opt,name=host,proto3" json:"host,omitempty"`
+	Channel              string   `protobuf:"bytes,5,opt,name=channel,proto3" json:"channel,omitempty"`
+	Revision             uint64   `protobuf:"varint,6,opt,name=revision,proto3" json:"revision,omitempty"`
+	Time                 int64    `protobuf:"varint,7,opt,name=time,proto3" json:"time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StoreChange) Reset()         { *m = StoreChange{} }
+func (m *StoreChange) String() string { return proto.CompactTextString(m) }
+func (*StoreChange) ProtoMessage()    {}
+func (*StoreChange) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd1789c19b148ca6, []int{56}
+}
+
+func (m *StoreChange) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StoreChange.Unmarshal(m, b)
+}
+func (m *StoreChange) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StoreChange.Marshal(b, m, deterministic)
+}
+func (m *StoreChange) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StoreChange.Merge(m, src)
+}
+func (m *StoreChange) XXX_Size() int {
+	return xxx_messageInfo_StoreChange.Size(m)
+}
+func (m *StoreChange) XXX_DiscardUnknown() {
+	xxx_messageInfo_StoreChange.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StoreChange proto.InternalMessageInfo
+
+func (m *StoreChange) GetKind() string {
+	if m != nil {
+		return m.Kind
+	}
+	return ""
+}
+
+func (m *StoreChange) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *StoreChange) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+func (m *StoreChange) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+func (m *StoreChange) GetChannel() string {
+	if m != nil {
+		return m.Channel
+	}
+	return ""
+}
+
+func (m *StoreChange) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *StoreChange) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
 type UnregisterRequest struct {
 	Ext                  string   `protobuf:"bytes,1,opt,name=ext,proto3" json:"ext,omitempty"`
 	Id                   uint64   `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
@@ -2185,7 +3846,7 @@ func (m *UnregisterRequest) Reset()         { *m = UnregisterRequest{} }
 func (m *UnregisterRequest) String() string { return proto.CompactTextString(m) }
 func (*UnregisterRequest) ProtoMessage()    {}
 func (*UnregisterRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{34}
+	return fileDescriptor_dd1789c19b148ca6, []int{57}
 }
 
 func (m *UnregisterRequest) XXX_Unmarshal(b []byte) error {
@@ -2231,7 +3892,7 @@ func (m *UnregisterAllRequest) Reset()         { *m = UnregisterAllRequest{} }
 func (m *UnregisterAllRequest) String() string { return proto.CompactTextString(m) }
 func (*UnregisterAllRequest) ProtoMessage()    {}
 func (*UnregisterAllRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{35}
+	return fileDescriptor_dd1789c19b148ca6, []int{58}
 }
 
 func (m *UnregisterAllRequest) XXX_Unmarshal(b []byte) error {
@@ -2273,7 +3934,7 @@ func (m *WriteRequest) Reset()         { *m = WriteRequest{} }
 func (m *WriteRequest) String() string { return proto.CompactTextString(m) }
 func (*WriteRequest) ProtoMessage()    {}
 func (*WriteRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd1789c19b148ca6, []int{36}
+	return fileDescriptor_dd1789c19b148ca6, []int{59}
 }
 
 func (m *WriteRequest) XXX_Unmarshal(b []byte) error {
@@ -2330,6 +3991,7 @@ func init() {
 	proto.RegisterMapType((map[string]bool)(nil), "api.ChannelModes.ModesEntry")
 	proto.RegisterType((*ChannelModes_AddressMode)(nil), "api.ChannelModes.AddressMode")
 	proto.RegisterType((*StateUser)(nil), "api.StateUser")
+	proto.RegisterType((*TopicChange)(nil), "api.TopicChange")
 	proto.RegisterType((*StateChannel)(nil), "api.StateChannel")
 	proto.RegisterType((*UserModes)(nil), "api.UserModes")
 	proto.RegisterType((*ModeKinds)(nil), "api.ModeKinds")
@@ -2337,11 +3999,19 @@ func init() {
 	proto.RegisterType((*ModeKinds_UserPrefix)(nil), "api.ModeKinds.UserPrefix")
 	proto.RegisterType((*NetworkInfo)(nil), "api.NetworkInfo")
 	proto.RegisterMapType((map[string]string)(nil), "api.NetworkInfo.ExtrasEntry")
+	proto.RegisterType((*LinkedAccount)(nil), "api.LinkedAccount")
+	proto.RegisterType((*RoleList)(nil), "api.RoleList")
+	proto.RegisterType((*PermissionList)(nil), "api.PermissionList")
 	proto.RegisterType((*StoredUser)(nil), "api.StoredUser")
 	proto.RegisterMapType((map[string]*Access)(nil), "api.StoredUser.AccessEntry")
+	proto.RegisterMapType((map[string]*LinkedAccount)(nil), "api.StoredUser.AccountsEntry")
 	proto.RegisterMapType((map[string]string)(nil), "api.StoredUser.DataEntry")
+	proto.RegisterMapType((map[string]*PermissionList)(nil), "api.StoredUser.PermissionsEntry")
+	proto.RegisterMapType((map[string]*RoleList)(nil), "api.StoredUser.RolesEntry")
+	proto.RegisterMapType((map[string]string)(nil), "api.StoredUser.SecretsEntry")
 	proto.RegisterType((*StoredChannel)(nil), "api.StoredChannel")
 	proto.RegisterMapType((map[string]string)(nil), "api.StoredChannel.DataEntry")
+	proto.RegisterMapType((map[string]string)(nil), "api.StoredChannel.SecretsEntry")
 	proto.RegisterType((*SelfResponse)(nil), "api.SelfResponse")
 	proto.RegisterType((*NetworkQuery)(nil), "api.NetworkQuery")
 	proto.RegisterType((*ChannelQuery)(nil), "api.ChannelQuery")
@@ -2351,9 +4021,21 @@ func init() {
 	proto.RegisterType((*ChannelResponse)(nil), "api.ChannelResponse")
 	proto.RegisterType((*StoredUsersResponse)(nil), "api.StoredUsersResponse")
 	proto.RegisterType((*StoredChannelsResponse)(nil), "api.StoredChannelsResponse")
+	proto.RegisterType((*StoreCacheStatsResponse)(nil), "api.StoreCacheStatsResponse")
+	proto.RegisterType((*Lockout)(nil), "api.Lockout")
+	proto.RegisterType((*LockoutsResponse)(nil), "api.LockoutsResponse")
+	proto.RegisterType((*BackupResponse)(nil), "api.BackupResponse")
+	proto.RegisterType((*AuditQuery)(nil), "api.AuditQuery")
+	proto.RegisterType((*AuditEntry)(nil), "api.AuditEntry")
+	proto.RegisterType((*AuditResponse)(nil), "api.AuditResponse")
+	proto.RegisterType((*Role)(nil), "api.Role")
+	proto.RegisterType((*RolesResponse)(nil), "api.RolesResponse")
 	proto.RegisterType((*LogoutRequest)(nil), "api.LogoutRequest")
 	proto.RegisterType((*LogoutRequest_HostUser)(nil), "api.LogoutRequest.HostUser")
 	proto.RegisterType((*NetworkInfoRequest)(nil), "api.NetworkInfoRequest")
+	proto.RegisterType((*UserSearchQuery)(nil), "api.UserSearchQuery")
+	proto.RegisterType((*UserSearchResponse)(nil), "api.UserSearchResponse")
+	proto.RegisterType((*UserSearchResponse_Result)(nil), "api.UserSearchResponse.Result")
 	proto.RegisterType((*Cmd)(nil), "api.Cmd")
 	proto.RegisterType((*CmdEvent)(nil), "api.CmdEvent")
 	proto.RegisterMapType((map[string]string)(nil), "api.CmdEvent.ArgsEntry")
@@ -2363,9 +4045,17 @@ func init() {
 	proto.RegisterType((*IRCEventResponse)(nil), "api.IRCEventResponse")
 	proto.RegisterType((*IRCEvent)(nil), "api.IRCEvent")
 	proto.RegisterType((*RegisterCmdRequest)(nil), "api.RegisterCmdRequest")
+	proto.RegisterType((*Permission)(nil), "api.Permission")
+	proto.RegisterType((*DeclarePermissionsRequest)(nil), "api.DeclarePermissionsRequest")
+	proto.RegisterType((*Setting)(nil), "api.Setting")
+	proto.RegisterType((*DeclareSettingsRequest)(nil), "api.DeclareSettingsRequest")
+	proto.RegisterType((*SettingsResponse)(nil), "api.SettingsResponse")
 	proto.RegisterType((*RegisterRequest)(nil), "api.RegisterRequest")
+	proto.RegisterType((*PreVerdictRequest)(nil), "api.PreVerdictRequest")
 	proto.RegisterType((*RegisterResponse)(nil), "api.RegisterResponse")
 	proto.RegisterType((*SubscriptionRequest)(nil), "api.SubscriptionRequest")
+	proto.RegisterType((*StoreChangesRequest)(nil), "api.StoreChangesRequest")
+	proto.RegisterType((*StoreChange)(nil), "api.StoreChange")
 	proto.RegisterType((*UnregisterRequest)(nil), "api.UnregisterRequest")
 	proto.RegisterType((*UnregisterAllRequest)(nil), "api.UnregisterAllRequest")
 	proto.RegisterType((*WriteRequest)(nil), "api.WriteRequest")
@@ -2374,148 +4064,234 @@ func init() {
 func init() { proto.RegisterFile("ultimateq.proto", fileDescriptor_dd1789c19b148ca6) }
 
 var fileDescriptor_dd1789c19b148ca6 = []byte{
-	// 2252 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x19, 0xdb, 0x72, 0xdb, 0xc6,
-	0x35, 0x04, 0x2f, 0x02, 0x0f, 0x49, 0x89, 0x5a, 0x2b, 0x0e, 0x4c, 0x3b, 0x89, 0x8c, 0xd8, 0x89,
-	0x52, 0xa7, 0x8c, 0x23, 0xd9, 0xb1, 0x13, 0x3b, 0x17, 0x59, 0x56, 0x62, 0x4f, 0x6d, 0x57, 0x85,
-	0xeb, 0xf4, 0xa1, 0x33, 0xd5, 0xc0, 0xc4, 0x8a, 0xc2, 0x08, 0x17, 0x6a, 0x17, 0x94, 0xc5, 0x8f,
-	0xc8, 0x17, 0xf4, 0xa5, 0x0f, 0xfd, 0x80, 0xfe, 0x41, 0x3f, 0xa3, 0x33, 0xf9, 0x9a, 0xce, 0xd9,
-	0x5d, 0x00, 0x0b, 0x10, 0xa4, 0xe2, 0x4c, 0x5e, 0x38, 0xbb, 0xe7, 0xb6, 0xe7, 0xbe, 0x67, 0x41,
-	0x58, 0x9b, 0x06, 0x89, 0x1f, 0xba, 0x09, 0x3d, 0x1d, 0x4e, 0x58, 0x9c, 0xc4, 0xa4, 0xee, 0x4e,
-	0x7c, 0x7b, 0x05, 0x9a, 0xfb, 0xe1, 0x24, 0x99, 0xd9, 0x16, 0xb4, 0x1c, 0xca, 0xa7, 0x41, 0x42,
-	0x56, 0xc1, 0x88, 0x4f, 0xac, 0xda, 0x66, 0x6d, 0xcb, 0x74, 0x8c, 0xf8, 0xc4, 0x7e, 0x1f, 0x9a,
-	0x7f, 0x99, 0x52, 0x36, 0x23, 0x1b, 0xd0, 0x3c, 0xc5, 0x85, 0xc0, 0xb5, 0x1d, 0xb9, 0xb1, 0x6d,
-	0xe8, 0x3e, 0xf3, 0x79, 0xe2, 0x50, 0x3e, 0x89, 0x23, 0x4e, 0x09, 0x81, 0x46, 0xe0, 0xf3, 0xc4,
-	0xaa, 0x6d, 0xd6, 0xb7, 0xda, 0x8e, 0x58, 0xdb, 0x37, 0xa1, 0xb7, 0x17, 0x4f, 0xa3, 0x9c, 0x68,
-	0x03, 0x9a, 0x23, 0x04, 0x08, 0x51, 0x4d, 0x47, 0x6e, 0xec, 0x3b, 0xd0, 0xda, 0x1d, 0x8d, 0x28,
-	0xe7, 0x88, 0x0f, 0xe8, 0x19, 0x0d, 0x04, 0xbe, 0xe7, 0xc8, 0x0d, 0x42, 0x8f, 0x02, 0x77, 0xcc,
-	0x2d, 0x63, 0xb3, 0xb6, 0xd5, 0x70, 0xe4, 0xc6, 0xfe, 0x67, 0x03, 0xba, 0x7b, 0xc7, 0x6e, 0x14,
-	0xd1, 0xe0, 0x79, 0xec, 0x51, 0x4e, 0xb6, 0xa1, 0x19, 0xe2, 0x42, 0xa8, 0xd0, 0xd9, 0xbe, 0x36,
-	0x74, 0x27, 0xfe, 0x50, 0xa7, 0x18, 0x8a, 0xdf, 0xfd, 0x28, 0x61, 0x33, 0x47, 0x92, 0x92, 0x87,
-	0xd0, 0x76, 0xd9, 0xf8, 0x50, 0xf2, 0x19, 0x82, 0xef, 0xc3, 0x79, 0xbe, 0x5d, 0x36, 0xd6, 0x58,
-	0x4d, 0x57, 0x6d, 0xc9, 0x13, 0xe8, 0xb9, 0x9e, 0xc7, 0x28, 0xe7, 0x4a, 0x42, 0x5d, 0x48, 0xf8,
-	0xa8, 0x42, 0x82, 0x24, 0xd3, 0xa4, 0x74, 0x5d, 0x0d, 0x44, 0xae, 0x41, 0x5b, 0xed, 0x29, 0xb7,
-	0x1a, 0xc2, 0x39, 0x39, 0x80, 0xdc, 0x80, 0xe6, 0x89, 0x1f, 0x79, 0xdc, 0x6a, 0x6e, 0xd6, 0xb6,
-	0x3a, 0xdb, 0xab, 0x42, 0x3e, 0x32, 0xfe, 0x09, 0xa1, 0x8e, 0x44, 0x0e, 0xee, 0x40, 0x47, 0x3b,
-	0x86, 0xdc, 0x84, 0x55, 0x54, 0xea, 0x30, 0x97, 0x2b, 0x43, 0xd3, 0x43, 0xe8, 0x6e, 0x0a, 0x1c,
-	0xdc, 0x07, 0xc8, 0xb5, 0x22, 0x7d, 0xa8, 0x9f, 0xd0, 0x34, 0xd2, 0xb8, 0x44, 0xe7, 0x9f, 0xb9,
-	0xc1, 0x94, 0x0a, 0xe7, 0x9b, 0x8e, 0xdc, 0x7c, 0x6d, 0xdc, 0xaf, 0x0d, 0x1e, 0x40, 0xaf, 0xe0,
-	0x98, 0x8b, 0x98, 0xdb, 0x3a, 0xf3, 0x3f, 0x60, 0x7d, 0xce, 0x27, 0x15, 0x02, 0x76, 0x74, 0x01,
-	0x9d, 0xed, 0xf7, 0x97, 0x7a, 0x56, 0x93, 0x6f, 0x3f, 0x80, 0xf6, 0xcb, 0xc4, 0x4d, 0xe8, 0x2b,
-	0x4e, 0x19, 0xe6, 0xe6, 0x71, 0xcc, 0x13, 0x25, 0x58, 0xac, 0xc9, 0x00, 0x4c, 0x46, 0xdd, 0x20,
-	0x72, 0xc3, 0x54, 0xbb, 0x6c, 0x6f, 0xbb, 0xd0, 0x15, 0xcc, 0xea, 0x20, 0xe4, 0x17, 0x74, 0x8a,
-	0x1f, 0xd7, 0x68, 0x5a, 0x12, 0x4f, 0xfc, 0x51, 0x6a, 0x9a, 0xd8, 0x90, 0x4f, 0xd2, 0x1c, 0xac,
-	0x0b, 0x7d, 0xd7, 0xe7, 0xf4, 0x55, 0x89, 0x67, 0xff, 0x08, 0x6d, 0x54, 0x4d, 0x46, 0x3f, 0x8b,
-	0x6f, 0x6d, 0x49, 0x7c, 0xf1, 0xc4, 0x34, 0x4f, 0x45, 0xf1, 0x48, 0x41, 0x3f, 0x1b, 0xd0, 0xce,
-	0x48, 0xc9, 0xb7, 0xd0, 0x9b, 0x72, 0xca, 0x0e, 0x27, 0x8c, 0x1e, 0xf9, 0xe7, 0x59, 0x2d, 0x5c,
-	0x29, 0x4a, 0x1c, 0xe2, 0xd1, 0x07, 0x82, 0xc4, 0xe9, 0x4e, 0xb3, 0x35, 0xe5, 0x64, 0x1f, 0x7a,
-	0x23, 0xa9, 0x6d, 0xa1, 0x26, 0x36, 0x4b, 0xfc, 0xba, 0x45, 0x2a, 0x9d, 0x47, 0x1a, 0x08, 0x93,
-	0x2a, 0x3f, 0x82, 0x5c, 0x86, 0x16, 0x9f, 0x85, 0xaf, 0xe3, 0x40, 0x39, 0x50, 0xed, 0xd0, 0xad,
-	0xa3, 0x63, 0x97, 0x29, 0x0f, 0x8a, 0xf5, 0xe0, 0x3b, 0x58, 0x9f, 0x13, 0x7e, 0x51, 0x62, 0x35,
-	0xf5, 0xc0, 0xff, 0xd2, 0x80, 0xce, 0x0b, 0x9a, 0xbc, 0x89, 0xd9, 0xc9, 0xd3, 0xe8, 0x28, 0x26,
-	0x1f, 0x42, 0x87, 0x53, 0x76, 0x46, 0xd9, 0xa1, 0x16, 0x42, 0x90, 0xa0, 0x17, 0x18, 0xc8, 0xeb,
-	0xd0, 0xf5, 0xd9, 0xc8, 0x3b, 0x3c, 0xa3, 0x8c, 0xfb, 0x71, 0xa4, 0xb4, 0xe9, 0x20, 0xec, 0x27,
-	0x09, 0xc2, 0xea, 0x44, 0x2f, 0xe5, 0x91, 0x6d, 0x3b, 0x39, 0x80, 0x7c, 0x00, 0x10, 0xa0, 0xf5,
-	0x12, 0xdd, 0x90, 0x07, 0xe4, 0x10, 0xd4, 0x9e, 0x1d, 0x8d, 0x44, 0xed, 0xb6, 0x1d, 0x5c, 0xa2,
-	0xe1, 0x28, 0xde, 0x6a, 0x49, 0xc3, 0x71, 0x4d, 0x36, 0xa1, 0x33, 0x72, 0x39, 0x0d, 0xdd, 0xc9,
-	0xc4, 0x8f, 0xc6, 0xd6, 0x8a, 0xd4, 0x42, 0x03, 0xa1, 0x1b, 0x65, 0x58, 0x2d, 0x53, 0xba, 0x51,
-	0xee, 0x50, 0x3b, 0x3c, 0x2c, 0x99, 0x4d, 0x28, 0xb7, 0xda, 0x52, 0xbb, 0x0c, 0x90, 0x62, 0xa5,
-	0x72, 0x90, 0x63, 0xc3, 0xb4, 0xef, 0xe0, 0x26, 0xf0, 0x43, 0x3f, 0xb1, 0x3a, 0xb2, 0xef, 0x64,
-	0x00, 0xb4, 0x4c, 0x85, 0x35, 0xa0, 0x91, 0xd5, 0x15, 0x68, 0x0d, 0x42, 0x2c, 0x58, 0x89, 0xfc,
-	0xd1, 0x09, 0x22, 0x7b, 0x02, 0x99, 0x6e, 0xb1, 0xba, 0x44, 0x41, 0x20, 0x6a, 0x55, 0xa0, 0xb2,
-	0x3d, 0x72, 0xb9, 0x6f, 0xdc, 0x19, 0xa2, 0xd6, 0x24, 0x97, 0xda, 0x22, 0xe6, 0x44, 0xc9, 0xeb,
-	0x4b, 0x8c, 0xda, 0xe6, 0xb9, 0xbf, 0xae, 0xe5, 0x3e, 0xb9, 0x03, 0x2d, 0x7a, 0x9e, 0x30, 0x97,
-	0x5b, 0x44, 0x6b, 0xf9, 0x5a, 0xf4, 0x87, 0xfb, 0x02, 0x2d, 0x53, 0x54, 0xd1, 0x0e, 0xbe, 0x82,
-	0x8e, 0x06, 0x7e, 0x9b, 0xae, 0x65, 0xff, 0xd7, 0x00, 0x78, 0x99, 0xc4, 0x8c, 0x7a, 0xa2, 0xaf,
-	0x0c, 0xc0, 0xc4, 0x34, 0xd0, 0x12, 0x2b, 0xdb, 0x23, 0x6e, 0xe2, 0x72, 0xfe, 0x26, 0x66, 0x9e,
-	0x90, 0xd3, 0x75, 0xb2, 0xbd, 0xb0, 0xc6, 0xe5, 0x27, 0xf2, 0xbe, 0x68, 0x3b, 0x72, 0x43, 0x76,
-	0xa0, 0xe5, 0x8a, 0x6b, 0xd0, 0x6a, 0x08, 0x6b, 0xae, 0x0a, 0x6b, 0xf2, 0xe3, 0x86, 0xf2, 0x92,
-	0x54, 0xc6, 0x48, 0x52, 0xf2, 0x47, 0x68, 0x78, 0x6e, 0xe2, 0x5a, 0x4d, 0xad, 0xce, 0x35, 0x96,
-	0xc7, 0x6e, 0xe2, 0x4a, 0x06, 0x41, 0x36, 0xf8, 0x01, 0x3a, 0x9a, 0x94, 0x0a, 0xdb, 0xaf, 0x17,
-	0x1b, 0x6e, 0x47, 0x08, 0x94, 0x2c, 0x7a, 0xfb, 0xbe, 0x07, 0xed, 0x4c, 0xf4, 0x5b, 0x79, 0xf0,
-	0x5f, 0x35, 0xe8, 0x49, 0xfd, 0xd2, 0xe6, 0xda, 0x87, 0x7a, 0x44, 0xd3, 0xde, 0x8c, 0xcb, 0xac,
-	0xdd, 0x1a, 0x5a, 0xbb, 0xbd, 0xad, 0xec, 0xac, 0x6b, 0x81, 0x2e, 0xc8, 0x99, 0x33, 0xf5, 0x37,
-	0xab, 0xf8, 0x77, 0xe8, 0xbe, 0xa4, 0xc1, 0x51, 0x36, 0xb4, 0xd8, 0xd0, 0xc0, 0xa8, 0x16, 0x9a,
-	0x73, 0x76, 0xb7, 0x38, 0x02, 0x97, 0xf7, 0x7d, 0xe3, 0x82, 0xbe, 0xff, 0x25, 0x74, 0x55, 0x7e,
-	0xca, 0xe1, 0x6a, 0xde, 0xfa, 0x6c, 0xdc, 0x32, 0xf4, 0x71, 0xeb, 0x20, 0x1b, 0x76, 0x16, 0xf1,
-	0x59, 0xb0, 0xa2, 0x4a, 0x53, 0x71, 0xa6, 0xdb, 0x5c, 0x62, 0x5d, 0x97, 0xf8, 0x73, 0x0d, 0xd6,
-	0x76, 0xa7, 0xc9, 0xb1, 0xb0, 0x82, 0x9e, 0x4e, 0x29, 0x4f, 0xaa, 0x63, 0x21, 0xae, 0x4e, 0xa3,
-	0x78, 0x75, 0x66, 0x69, 0x5f, 0x5f, 0x92, 0xf6, 0xb2, 0x15, 0xe6, 0x69, 0x7f, 0x0d, 0xda, 0x13,
-	0xca, 0x42, 0x37, 0xa2, 0x51, 0x22, 0xda, 0xa1, 0xe9, 0xe4, 0x00, 0x7b, 0x1b, 0xba, 0x52, 0x95,
-	0xdc, 0xed, 0x9c, 0x06, 0x47, 0x8b, 0xdc, 0x8e, 0x38, 0xfb, 0x21, 0xac, 0x67, 0xb7, 0x68, 0xc6,
-	0xf8, 0x49, 0x3e, 0x07, 0x2e, 0x8f, 0x85, 0x07, 0x6b, 0x0a, 0xac, 0x4f, 0xb1, 0xbf, 0xf7, 0x4d,
-	0xff, 0x10, 0x2e, 0xe5, 0x05, 0x99, 0x6b, 0x79, 0x13, 0x9a, 0xe8, 0xb4, 0xf4, 0x86, 0x5e, 0x2b,
-	0x55, 0xae, 0x23, 0xb1, 0xf6, 0x13, 0xb8, 0x5c, 0x48, 0xf3, 0x5c, 0xc0, 0x10, 0x4c, 0x15, 0xe0,
-	0x54, 0x06, 0x99, 0xaf, 0x0a, 0x27, 0xa3, 0xb1, 0xff, 0x5d, 0x83, 0xde, 0xb3, 0x78, 0x1c, 0x4f,
-	0x93, 0x34, 0xda, 0x5f, 0x43, 0x1b, 0xe3, 0x79, 0xa8, 0x65, 0xb7, 0xec, 0x39, 0x05, 0xb2, 0xe1,
-	0x93, 0x98, 0x27, 0xa8, 0xd2, 0x93, 0x77, 0x1c, 0xf3, 0x58, 0xad, 0xc9, 0x35, 0x2d, 0x07, 0x84,
-	0x5f, 0x10, 0x9b, 0x42, 0x06, 0xb7, 0xc1, 0x4c, 0xb9, 0x7e, 0x5d, 0x4e, 0x3d, 0x5a, 0x51, 0x39,
-	0x6a, 0x7f, 0x0c, 0x44, 0x6b, 0xe0, 0x0b, 0x13, 0xd3, 0xfe, 0x9f, 0x01, 0xf5, 0xbd, 0xd0, 0x43,
-	0x0c, 0x3d, 0xcf, 0x30, 0xf4, 0xbc, 0xba, 0x7d, 0x10, 0x68, 0x78, 0x94, 0x8f, 0x54, 0xba, 0x8a,
-	0x35, 0xb9, 0x0e, 0x0d, 0x1c, 0xac, 0x44, 0x9a, 0xae, 0x6e, 0xf7, 0x64, 0x00, 0x43, 0x6f, 0x88,
-	0x23, 0x8e, 0x23, 0x50, 0x38, 0x98, 0xf1, 0x51, 0x3c, 0xa1, 0x22, 0x5b, 0x57, 0x55, 0x12, 0x22,
-	0xcd, 0x4b, 0x84, 0x3a, 0x12, 0x89, 0xc2, 0x5d, 0x36, 0xe6, 0x56, 0x4b, 0x3e, 0x7d, 0x70, 0x8d,
-	0x53, 0x05, 0xa3, 0xa7, 0x53, 0x9f, 0xd1, 0x43, 0x77, 0x9a, 0x1c, 0x8b, 0xfb, 0xdc, 0x74, 0x3a,
-	0x0a, 0x86, 0x75, 0x47, 0xae, 0x42, 0x9b, 0xd1, 0xd3, 0x43, 0xf9, 0xe0, 0x31, 0xe5, 0x25, 0xc9,
-	0xe8, 0xe9, 0x33, 0xf1, 0xe6, 0x51, 0x48, 0xf9, 0xee, 0x69, 0xa7, 0xf3, 0xe9, 0xe9, 0x0f, 0xe2,
-	0xe9, 0xf3, 0x19, 0x34, 0x50, 0x49, 0xd2, 0x81, 0x95, 0x03, 0xe6, 0x9f, 0x85, 0x7c, 0xdc, 0x7f,
-	0x87, 0x00, 0xb4, 0x5e, 0xc4, 0x89, 0x3f, 0xa2, 0xfd, 0x1a, 0x22, 0x76, 0xa3, 0x19, 0xd2, 0xf4,
-	0x0d, 0x7b, 0x08, 0x4d, 0xa1, 0x6e, 0x4a, 0xee, 0x26, 0x54, 0x92, 0x1f, 0x4c, 0x5f, 0x07, 0xfe,
-	0xa8, 0x5f, 0x23, 0x5d, 0x30, 0x77, 0xa3, 0x99, 0x20, 0xea, 0x1b, 0xf6, 0x2f, 0x2d, 0x30, 0xf7,
-	0x42, 0x6f, 0xff, 0x8c, 0x46, 0x09, 0xf9, 0x14, 0x4c, 0x9f, 0x8d, 0xc4, 0x5a, 0xa5, 0x88, 0x74,
-	0xd4, 0x53, 0x67, 0x4f, 0x00, 0x9d, 0x0c, 0x9d, 0xf5, 0x49, 0x63, 0x49, 0x9f, 0xfc, 0x1c, 0x80,
-	0x67, 0x39, 0xae, 0x4a, 0x67, 0x2e, 0xf5, 0x35, 0x12, 0x72, 0x47, 0x0e, 0xb4, 0x98, 0xce, 0xcf,
-	0xb3, 0xf9, 0x2a, 0x95, 0x9e, 0xd7, 0x7e, 0x91, 0x88, 0xdc, 0xca, 0x7b, 0x61, 0x53, 0x2b, 0x4f,
-	0x7d, 0xa8, 0xcf, 0xdb, 0xe3, 0x3d, 0xe8, 0x25, 0x2e, 0x1b, 0xd3, 0x44, 0x61, 0xc4, 0x58, 0x56,
-	0xc9, 0x52, 0xa4, 0x23, 0xdf, 0x43, 0x47, 0x02, 0x44, 0x65, 0x5b, 0x2b, 0xa2, 0x08, 0x3f, 0x48,
-	0x73, 0x44, 0x38, 0x65, 0xf8, 0xd7, 0x9c, 0x40, 0x5e, 0x4e, 0x3a, 0x0b, 0x71, 0x60, 0x5d, 0x6e,
-	0xb5, 0x0e, 0x61, 0x99, 0x42, 0xce, 0x8d, 0x2a, 0x39, 0x1a, 0x99, 0x94, 0x36, 0xcf, 0x4e, 0xbe,
-	0x87, 0x4b, 0x12, 0xf8, 0x93, 0xcb, 0x7c, 0xd7, 0xf3, 0x47, 0x52, 0x6a, 0x5b, 0x48, 0x2d, 0x47,
-	0xa5, 0x8a, 0x94, 0x3c, 0x87, 0x2b, 0x45, 0xb0, 0xae, 0x1d, 0x54, 0xb7, 0xab, 0xc5, 0x1c, 0xe4,
-	0x96, 0x2a, 0x8f, 0x8e, 0xe0, 0x7c, 0xaf, 0x68, 0xd7, 0x2e, 0x1b, 0x2b, 0x53, 0x04, 0xd1, 0xe0,
-	0x05, 0xf4, 0xcb, 0x2e, 0xab, 0xb8, 0xbc, 0x6f, 0x14, 0xa7, 0x94, 0xb2, 0x55, 0xda, 0xa0, 0xf2,
-	0x0a, 0x2e, 0x57, 0xbb, 0xae, 0x42, 0xea, 0xcd, 0xa2, 0xd4, 0xf9, 0x96, 0x5c, 0x98, 0x7f, 0x32,
-	0xcd, 0xdf, 0x72, 0xb8, 0xe8, 0xa7, 0xb6, 0x67, 0x9d, 0x7c, 0x15, 0x0c, 0xdf, 0x13, 0xec, 0x0d,
-	0xc7, 0xf0, 0xbd, 0xca, 0x06, 0xf6, 0x11, 0x34, 0xa9, 0x28, 0xc2, 0xba, 0x56, 0x84, 0x99, 0x24,
-	0x89, 0xb3, 0x7f, 0x84, 0x7e, 0x56, 0x97, 0x8b, 0x84, 0x67, 0x82, 0x8c, 0xaa, 0x6a, 0x56, 0x82,
-	0x26, 0x60, 0xa6, 0xa0, 0xca, 0x2b, 0x11, 0x5f, 0x74, 0x34, 0xf2, 0x68, 0xfa, 0x76, 0x53, 0xbb,
-	0xac, 0x13, 0xd6, 0xb5, 0x4e, 0x48, 0xa0, 0x91, 0xf8, 0x21, 0x15, 0x85, 0x5b, 0x77, 0xc4, 0x3a,
-	0x6d, 0xe7, 0xcd, 0xbc, 0x9d, 0x9f, 0x01, 0x71, 0xe8, 0xd8, 0xe7, 0x09, 0x65, 0x7b, 0xa1, 0xa7,
-	0xb5, 0xfd, 0x52, 0x73, 0xc7, 0x27, 0x87, 0xbc, 0x1e, 0xd2, 0x29, 0x47, 0x6d, 0xf5, 0xf9, 0xa7,
-	0x5e, 0x9c, 0x7f, 0x06, 0x50, 0x1f, 0x85, 0x9e, 0xea, 0x1c, 0x66, 0xea, 0x39, 0x07, 0x81, 0x76,
-	0x08, 0x6b, 0xe9, 0xb9, 0xbf, 0xef, 0xa1, 0x1b, 0xa9, 0x9f, 0xe5, 0x14, 0xa4, 0x1c, 0x6b, 0x43,
-	0x3f, 0x3f, 0xae, 0x3a, 0x42, 0xf6, 0x57, 0x70, 0xe9, 0xe5, 0xf4, 0x35, 0x1f, 0x31, 0x7f, 0x92,
-	0xf8, 0x71, 0xb4, 0x58, 0xad, 0x3e, 0xd4, 0x7d, 0x4f, 0x3e, 0xd1, 0x1b, 0x0e, 0x2e, 0xed, 0xbb,
-	0xb0, 0xfe, 0x2a, 0x62, 0x17, 0xda, 0x23, 0x4f, 0x34, 0xb2, 0x13, 0xb7, 0x60, 0x23, 0x67, 0xdb,
-	0x0d, 0x82, 0x85, 0x9c, 0xf6, 0x63, 0xe8, 0xfe, 0x8d, 0xf9, 0x09, 0x5d, 0xaa, 0x14, 0x86, 0xd6,
-	0xc8, 0xaf, 0xfb, 0x3e, 0xd4, 0x43, 0x3e, 0x16, 0xfe, 0xe9, 0x3a, 0xb8, 0xdc, 0xfe, 0x4f, 0x0f,
-	0xea, 0xfb, 0xe7, 0x09, 0x79, 0x00, 0x2d, 0x91, 0x63, 0x9c, 0x58, 0xb2, 0xd6, 0xe6, 0xcd, 0x1e,
-	0xbc, 0x5b, 0x4c, 0x50, 0xe5, 0xb4, 0xdb, 0x35, 0xf2, 0x0d, 0x98, 0x7b, 0x71, 0x18, 0xba, 0x91,
-	0x77, 0x31, 0x7b, 0xb9, 0xe4, 0x6e, 0xd7, 0xc8, 0xc7, 0xd0, 0x14, 0x96, 0x10, 0xd9, 0xe7, 0x75,
-	0xab, 0x06, 0x20, 0x40, 0xe2, 0x03, 0x29, 0xb9, 0x07, 0x66, 0x1a, 0x31, 0xb2, 0x21, 0xe0, 0xa5,
-	0x7c, 0x51, 0x47, 0xcc, 0x85, 0xf5, 0x1b, 0xe8, 0x68, 0x19, 0x4d, 0xde, 0x2b, 0x50, 0xe5, 0x39,
-	0xbe, 0x88, 0xfd, 0x0b, 0x80, 0x3c, 0x26, 0xe4, 0xb2, 0xbc, 0xef, 0xca, 0xb1, 0x1d, 0x74, 0x14,
-	0xb3, 0xf8, 0x82, 0x7b, 0x07, 0x7a, 0x39, 0x05, 0x9e, 0xf9, 0xab, 0xb8, 0xbe, 0xd4, 0xb9, 0x76,
-	0x83, 0x80, 0x5c, 0x29, 0x71, 0xe5, 0x09, 0x51, 0x70, 0xcc, 0x77, 0x85, 0x41, 0x8d, 0x85, 0x2e,
-	0xba, 0x5d, 0x99, 0x39, 0x3f, 0xc1, 0x0d, 0xfa, 0x65, 0x04, 0xf9, 0x83, 0xfa, 0x44, 0x87, 0x8f,
-	0x2d, 0x22, 0x25, 0x8b, 0xb7, 0xcd, 0x40, 0xdd, 0xbc, 0xfa, 0x1b, 0xec, 0x73, 0x7c, 0x77, 0xab,
-	0xf6, 0xce, 0x55, 0xc8, 0xf4, 0x77, 0xd4, 0xa0, 0x74, 0x05, 0x90, 0xfb, 0xd0, 0xcf, 0x19, 0x1e,
-	0xcd, 0xf0, 0xca, 0xae, 0x62, 0x93, 0xa0, 0xc2, 0x87, 0xec, 0x6f, 0xe1, 0xdd, 0x32, 0xa7, 0xf8,
-	0x88, 0x5d, 0xc5, 0x2e, 0x27, 0xee, 0xe2, 0x37, 0xee, 0x1d, 0x58, 0xcd, 0xf8, 0xe5, 0x34, 0x52,
-	0x78, 0x1b, 0xe8, 0xea, 0xe6, 0x24, 0xf7, 0x4a, 0x5f, 0x1c, 0x2b, 0xce, 0xda, 0xd0, 0xa5, 0x68,
-	0xaf, 0x80, 0x9e, 0xce, 0xc8, 0x2b, 0x1c, 0x59, 0xb0, 0x6e, 0x07, 0xd6, 0x75, 0x7a, 0x69, 0x99,
-	0xce, 0x53, 0x65, 0xd2, 0x2d, 0x15, 0xa9, 0xa7, 0xfc, 0xcf, 0x51, 0x95, 0x35, 0x85, 0x7c, 0xda,
-	0x56, 0x0f, 0xfc, 0xf4, 0x6d, 0xa9, 0xaa, 0xa6, 0xf4, 0xd4, 0x2c, 0xf2, 0xdc, 0x85, 0xb5, 0x8c,
-	0x47, 0x0d, 0x7e, 0x15, 0x1e, 0x28, 0x5f, 0xc8, 0x64, 0x0b, 0xf5, 0x8a, 0x99, 0x8c, 0xb8, 0x6e,
-	0xc4, 0x1c, 0xe5, 0xb6, 0xfa, 0x6e, 0x23, 0xf3, 0x47, 0x4b, 0xe3, 0x81, 0x55, 0x22, 0xcd, 0x1f,
-	0x58, 0x0f, 0xd4, 0xc3, 0x4d, 0x25, 0x82, 0x52, 0xa5, 0x70, 0xce, 0x62, 0xe6, 0x47, 0x45, 0xe6,
-	0x25, 0x71, 0x5d, 0x2c, 0xe3, 0x2e, 0x26, 0x45, 0xcc, 0x96, 0x25, 0x45, 0xc5, 0x93, 0x8f, 0xdc,
-	0x57, 0x01, 0x28, 0xa5, 0x84, 0x34, 0xf7, 0xea, 0x3c, 0x03, 0xd7, 0xe2, 0x2c, 0x0f, 0x3c, 0x98,
-	0xca, 0xa7, 0x5b, 0xd9, 0x8d, 0x85, 0xfa, 0xff, 0x42, 0xc5, 0xec, 0x60, 0x9a, 0x0d, 0xc4, 0x15,
-	0xda, 0x14, 0x58, 0x3e, 0x55, 0x2c, 0x8f, 0x69, 0x40, 0x93, 0xf9, 0xa8, 0xe9, 0xa4, 0x3b, 0x40,
-	0x34, 0xd2, 0x25, 0x1e, 0xd0, 0x99, 0x3e, 0x83, 0x8e, 0x60, 0x92, 0xef, 0xd7, 0x8b, 0xa8, 0x6f,
-	0x61, 0x29, 0x64, 0xd4, 0x8f, 0x66, 0xcb, 0xf4, 0x79, 0xdd, 0x12, 0x7f, 0x9e, 0xed, 0xfc, 0x3f,
-	0x00, 0x00, 0xff, 0xff, 0xd1, 0x2b, 0x30, 0xae, 0x4f, 0x1b, 0x00, 0x00,
+	// 3618 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe4, 0x3a, 0x4b, 0x73, 0x1b, 0xc7,
+	0xd1, 0xc6, 0x8b, 0x04, 0x1a, 0x00, 0x09, 0x8e, 0x68, 0x09, 0x82, 0x2c, 0x9b, 0x5a, 0x59, 0x36,
+	0x65, 0xf9, 0xa3, 0x29, 0x4a, 0xb2, 0x9e, 0x7e, 0x50, 0x94, 0x64, 0xa9, 0x3e, 0x49, 0xe6, 0xb7,
+	0x94, 0xec, 0xc3, 0x57, 0x15, 0x66, 0xb5, 0x18, 0x92, 0x5b, 0x5c, 0x60, 0xa1, 0xdd, 0x05, 0x2d,
+	0xe4, 0x9a, 0xca, 0x2d, 0xce, 0x25, 0x47, 0x5f, 0xfd, 0x0f, 0x52, 0xb9, 0xa6, 0xf2, 0x33, 0x52,
+	0xfe, 0x09, 0x39, 0xbb, 0x2a, 0xd7, 0x54, 0xf7, 0x3c, 0x76, 0x66, 0xb1, 0xa0, 0xac, 0x24, 0xb7,
+	0x5c, 0x50, 0xdb, 0x3d, 0xdd, 0x3d, 0x33, 0x3d, 0xdd, 0x3d, 0xdd, 0x3d, 0x80, 0xc5, 0x71, 0x98,
+	0x06, 0x03, 0x2f, 0xe5, 0x2f, 0xd7, 0x46, 0x71, 0x94, 0x46, 0xac, 0xe2, 0x8d, 0x02, 0x67, 0x1e,
+	0x6a, 0xf7, 0x07, 0xa3, 0x74, 0xe2, 0x74, 0x61, 0xce, 0xe5, 0xc9, 0x38, 0x4c, 0xd9, 0x02, 0x94,
+	0xa3, 0xc3, 0x6e, 0x69, 0xa5, 0xb4, 0x5a, 0x77, 0xcb, 0xd1, 0xa1, 0x73, 0x1d, 0x6a, 0xff, 0x37,
+	0xe6, 0xf1, 0x84, 0x2d, 0x43, 0xed, 0x25, 0x7e, 0xd0, 0x58, 0xc3, 0x15, 0x00, 0xeb, 0xc2, 0x7c,
+	0xc2, 0xfd, 0x98, 0xa7, 0x49, 0xb7, 0x4c, 0x3c, 0x0a, 0x74, 0x1c, 0x68, 0x3d, 0x0e, 0x92, 0xd4,
+	0xe5, 0xc9, 0x28, 0x1a, 0x26, 0x9c, 0x31, 0xa8, 0x86, 0x41, 0x92, 0x76, 0x4b, 0x2b, 0x95, 0xd5,
+	0x86, 0x4b, 0xdf, 0xce, 0x05, 0x68, 0x6f, 0x45, 0xe3, 0x61, 0x46, 0xb4, 0x0c, 0x35, 0x1f, 0x11,
+	0x34, 0x49, 0xcd, 0x15, 0x80, 0xf3, 0xfb, 0x12, 0xcc, 0x6d, 0xfa, 0x3e, 0x4f, 0x12, 0x24, 0x08,
+	0xf9, 0x11, 0x0f, 0x89, 0xa0, 0xed, 0x0a, 0x00, 0xb1, 0x7b, 0xa1, 0xb7, 0x2f, 0xd6, 0x50, 0x75,
+	0x05, 0x80, 0x6b, 0xe3, 0xaf, 0x46, 0x41, 0xcc, 0x93, 0x6e, 0x65, 0xa5, 0xb4, 0x5a, 0x71, 0x15,
+	0xc8, 0xce, 0x02, 0xf4, 0xf9, 0x70, 0xb2, 0x2b, 0x44, 0x55, 0x49, 0x54, 0x03, 0x31, 0x8f, 0x49,
+	0x9c, 0x1a, 0x16, 0x32, 0x6b, 0x24, 0x93, 0x86, 0x1f, 0x20, 0xc2, 0xf9, 0xa1, 0x0a, 0xad, 0xad,
+	0x03, 0x6f, 0x38, 0xe4, 0xe1, 0x93, 0xa8, 0xcf, 0x13, 0xb6, 0x01, 0xb5, 0x01, 0x7e, 0xd0, 0xde,
+	0x9a, 0x1b, 0xef, 0xac, 0x79, 0xa3, 0x60, 0xcd, 0xa4, 0x58, 0xa3, 0xdf, 0xfb, 0xc3, 0x34, 0x9e,
+	0xb8, 0x82, 0x94, 0xdd, 0x81, 0x86, 0x17, 0xef, 0xef, 0x0a, 0xbe, 0x32, 0xf1, 0xbd, 0x37, 0xcd,
+	0xb7, 0x19, 0xef, 0x1b, 0xac, 0x75, 0x4f, 0x82, 0xec, 0x21, 0xb4, 0xbd, 0x7e, 0x3f, 0xe6, 0x49,
+	0x22, 0x25, 0x54, 0x48, 0xc2, 0xf9, 0x02, 0x09, 0x82, 0xcc, 0x90, 0xd2, 0xf2, 0x0c, 0x14, 0x7b,
+	0x07, 0x1a, 0x12, 0xe6, 0x09, 0x69, 0xa2, 0xe6, 0x66, 0x08, 0xf6, 0x3e, 0xd4, 0x0e, 0x83, 0x61,
+	0x5f, 0x28, 0xa1, 0xb9, 0xb1, 0x40, 0xf2, 0x91, 0xf1, 0x7f, 0x11, 0xeb, 0x8a, 0xc1, 0xde, 0x55,
+	0x68, 0x1a, 0xd3, 0xb0, 0x0b, 0xb0, 0x80, 0x8b, 0xda, 0xcd, 0xe4, 0x8a, 0x33, 0x6f, 0x23, 0x76,
+	0x53, 0x21, 0x7b, 0x37, 0x00, 0xb2, 0x55, 0xb1, 0x0e, 0x54, 0x0e, 0xb9, 0x32, 0x2e, 0xfc, 0xc4,
+	0x43, 0x3d, 0xf2, 0xc2, 0x31, 0x97, 0x86, 0x25, 0x80, 0x5b, 0xe5, 0x1b, 0xa5, 0xde, 0x6d, 0x68,
+	0x5b, 0x8a, 0x79, 0x1d, 0x73, 0xc3, 0x64, 0xfe, 0x15, 0x2c, 0x4d, 0xe9, 0xa4, 0x40, 0xc0, 0x15,
+	0x53, 0x40, 0x73, 0xe3, 0xec, 0xb1, 0x9a, 0x35, 0xe4, 0x3b, 0x03, 0x68, 0xec, 0xa4, 0x5e, 0xca,
+	0x9f, 0x27, 0x3c, 0x46, 0xa3, 0x3f, 0x88, 0x92, 0x54, 0x0a, 0xa6, 0x6f, 0xd6, 0x83, 0x7a, 0xcc,
+	0xbd, 0x70, 0xe8, 0x0d, 0xd4, 0xea, 0x34, 0x8c, 0x26, 0xeb, 0xf9, 0xc2, 0x03, 0x2a, 0x34, 0xa4,
+	0x40, 0x76, 0x12, 0xe6, 0x7c, 0x1e, 0xa7, 0x7b, 0x23, 0x3a, 0xa4, 0x86, 0x2b, 0x21, 0xe7, 0x6b,
+	0x68, 0x3e, 0x8b, 0x46, 0x81, 0x8f, 0x4b, 0xdb, 0x27, 0x07, 0x4a, 0x11, 0x54, 0x5e, 0x4a, 0x00,
+	0x32, 0x27, 0x3c, 0x4d, 0x79, 0x2c, 0x27, 0x94, 0x10, 0x2e, 0x2f, 0x0d, 0x06, 0x5c, 0xba, 0x07,
+	0x7d, 0x3b, 0x3f, 0x97, 0xa0, 0x45, 0x1b, 0x90, 0x9b, 0x45, 0x22, 0x5a, 0xab, 0xdc, 0x03, 0xad,
+	0x53, 0x4f, 0x53, 0x36, 0xa7, 0xf9, 0x50, 0xf9, 0x41, 0x85, 0x74, 0xb6, 0x34, 0xa5, 0x33, 0x65,
+	0xfc, 0xe7, 0xa0, 0x45, 0x1c, 0xbb, 0x72, 0x55, 0x62, 0x4b, 0x4d, 0xc2, 0xed, 0x88, 0xa5, 0x9d,
+	0x05, 0x10, 0x24, 0xb4, 0xc0, 0x1a, 0x2d, 0xb0, 0x41, 0x98, 0x67, 0x81, 0x50, 0x94, 0x1f, 0x73,
+	0x2f, 0xe5, 0xfd, 0xee, 0x9c, 0xf0, 0x6d, 0x09, 0xb2, 0x6b, 0xd0, 0x16, 0x8c, 0x07, 0x41, 0x92,
+	0x46, 0xf1, 0xa4, 0x3b, 0x4f, 0xae, 0xd1, 0xa1, 0xc5, 0x18, 0xaa, 0x72, 0xc5, 0x12, 0x1e, 0x0a,
+	0x2a, 0xe7, 0x2b, 0x68, 0xe0, 0x89, 0x09, 0xa7, 0xd0, 0x66, 0x5f, 0x3a, 0xc6, 0xec, 0x51, 0x09,
+	0xca, 0x7d, 0x29, 0x58, 0x11, 0xe0, 0x7c, 0x5f, 0x86, 0x86, 0x26, 0x65, 0x9f, 0x43, 0x7b, 0x9c,
+	0xf0, 0x78, 0x77, 0x14, 0xf3, 0xbd, 0xe0, 0x95, 0x0e, 0x11, 0xa7, 0x6d, 0x89, 0x6b, 0x38, 0xf5,
+	0x36, 0x91, 0xb8, 0xad, 0xb1, 0xfe, 0xe6, 0x09, 0xbb, 0x0f, 0x6d, 0x5f, 0x28, 0xd0, 0x0a, 0x15,
+	0x2b, 0x39, 0x7e, 0x53, 0xc9, 0xd2, 0xcb, 0x7d, 0x03, 0x85, 0xbe, 0x96, 0x4d, 0x41, 0xe6, 0x30,
+	0x19, 0xbc, 0x88, 0x42, 0x79, 0xa6, 0x12, 0xc2, 0x93, 0xf6, 0x0f, 0x3c, 0x65, 0x24, 0xf4, 0xdd,
+	0xfb, 0x02, 0x96, 0xa6, 0x84, 0xbf, 0xce, 0xdf, 0x6a, 0xa6, 0x3f, 0xfc, 0x54, 0x85, 0xe6, 0x53,
+	0x9e, 0x7e, 0x17, 0xc5, 0x87, 0x8f, 0x86, 0x7b, 0x11, 0x7b, 0x0f, 0x9a, 0x09, 0x8f, 0x8f, 0x78,
+	0xbc, 0x6b, 0x58, 0x15, 0x08, 0xd4, 0x53, 0xb4, 0xad, 0x73, 0xd0, 0x0a, 0x62, 0xbf, 0xbf, 0x7b,
+	0xc4, 0xe3, 0x24, 0x88, 0x86, 0x72, 0x35, 0x4d, 0xc4, 0x7d, 0x23, 0x50, 0x18, 0xb4, 0x50, 0x4b,
+	0x99, 0xb1, 0x35, 0xdc, 0x0c, 0xc1, 0xde, 0x05, 0x08, 0x71, 0xf7, 0x62, 0x58, 0xd8, 0x96, 0x81,
+	0xc1, 0xd5, 0xc7, 0x7b, 0x3e, 0xd9, 0x54, 0xc3, 0xc5, 0x4f, 0xdc, 0x38, 0x8a, 0x27, 0x53, 0x6a,
+	0xb8, 0xf4, 0xcd, 0x56, 0xa0, 0xe9, 0x7b, 0x09, 0x1f, 0x78, 0xa3, 0x51, 0x30, 0xdc, 0xef, 0xce,
+	0x8b, 0x55, 0x18, 0x28, 0x54, 0xa3, 0x38, 0xd6, 0x6e, 0x5d, 0xa8, 0x51, 0x40, 0xb8, 0x3a, 0x9c,
+	0x2c, 0x9d, 0x8c, 0x78, 0xd2, 0x6d, 0x88, 0xd5, 0x69, 0x84, 0x1a, 0x15, 0x8b, 0x83, 0x6c, 0x74,
+	0xa0, 0xc2, 0x31, 0x02, 0x61, 0x30, 0x08, 0xd2, 0x6e, 0x53, 0x84, 0x63, 0x8d, 0xc0, 0x9d, 0xc9,
+	0x63, 0x0d, 0xf9, 0xb0, 0xdb, 0xa2, 0x61, 0x03, 0x83, 0x5e, 0x31, 0x0c, 0xfc, 0x43, 0x1c, 0x6c,
+	0xd3, 0xa0, 0x02, 0x31, 0xe8, 0x90, 0xb9, 0xe3, 0xd0, 0x02, 0x0d, 0x69, 0x18, 0xb9, 0xbc, 0xef,
+	0xbc, 0x09, 0x0e, 0x2d, 0x0a, 0x2e, 0x09, 0xe2, 0xc8, 0xa1, 0x94, 0xd7, 0x11, 0x23, 0x12, 0xcc,
+	0x6c, 0x7f, 0xc9, 0xb0, 0x7d, 0x76, 0x15, 0xe6, 0xf8, 0xab, 0x34, 0xf6, 0x92, 0x2e, 0x33, 0x6e,
+	0x42, 0xe3, 0xf4, 0xd7, 0xee, 0xd3, 0xb0, 0x30, 0x51, 0x49, 0xdb, 0xbb, 0x09, 0x4d, 0x03, 0xfd,
+	0x26, 0xc1, 0xdc, 0x79, 0x0c, 0xed, 0xc7, 0xc1, 0xf0, 0x90, 0xf7, 0x37, 0x65, 0x98, 0x34, 0x02,
+	0x68, 0xc9, 0x0e, 0xa0, 0xe7, 0xa0, 0x15, 0xf3, 0x97, 0xe3, 0x20, 0xe6, 0xbb, 0x03, 0x2f, 0x39,
+	0x94, 0xb7, 0x4a, 0x53, 0xe2, 0x9e, 0x78, 0xc9, 0xa1, 0xb3, 0x02, 0x75, 0x37, 0x0a, 0x39, 0xa6,
+	0x2d, 0x38, 0x67, 0x1c, 0x85, 0xfa, 0xee, 0x12, 0x80, 0xb3, 0x01, 0x0b, 0xdb, 0x3c, 0x1e, 0x04,
+	0x09, 0x9a, 0x21, 0xd1, 0xad, 0x40, 0x73, 0xa4, 0x31, 0x8a, 0xda, 0x44, 0x39, 0xbf, 0x9b, 0x07,
+	0xd8, 0x49, 0xa3, 0x98, 0xf7, 0xe9, 0x4a, 0xe8, 0x41, 0x1d, 0x4d, 0xd5, 0x30, 0x7e, 0x0d, 0xe3,
+	0xd8, 0xc8, 0x4b, 0x92, 0xef, 0xa2, 0xb8, 0x4f, 0xeb, 0x6b, 0xb9, 0x1a, 0x26, 0x8d, 0x7b, 0xc9,
+	0xa1, 0xb8, 0xea, 0x1b, 0xae, 0x00, 0xd8, 0x15, 0x98, 0xf3, 0x28, 0x33, 0xea, 0x56, 0x49, 0xe3,
+	0x67, 0x48, 0xe3, 0xd9, 0x74, 0x6b, 0x22, 0x6f, 0x92, 0x0a, 0x17, 0xa4, 0xec, 0x7f, 0xa0, 0xda,
+	0xf7, 0x52, 0xaf, 0x5b, 0x33, 0x62, 0x91, 0xc1, 0x72, 0xcf, 0x4b, 0x3d, 0xc1, 0x40, 0x64, 0xec,
+	0x26, 0xd4, 0xa5, 0x12, 0x93, 0xee, 0xdc, 0x4a, 0x45, 0xdf, 0x86, 0xf6, 0x2c, 0x34, 0xae, 0xf2,
+	0x14, 0x09, 0x52, 0x3e, 0xc7, 0xe3, 0x34, 0xa1, 0x20, 0xdc, 0x70, 0x05, 0xc0, 0xd6, 0x95, 0x6e,
+	0xeb, 0x24, 0xad, 0x97, 0x97, 0x86, 0x87, 0xa0, 0xb2, 0x25, 0x22, 0x64, 0x77, 0x6d, 0x2d, 0x37,
+	0x8c, 0x20, 0x68, 0xf0, 0x65, 0x47, 0x23, 0xb9, 0x4d, 0x26, 0x71, 0xef, 0x1e, 0x05, 0x08, 0x90,
+	0xdf, 0x55, 0x5d, 0x0d, 0xb3, 0x4f, 0xb3, 0x34, 0xb6, 0x69, 0x58, 0xae, 0x21, 0x7b, 0x47, 0x0c,
+	0x0b, 0xb9, 0x8a, 0xb8, 0xf7, 0x00, 0x9a, 0x86, 0x82, 0x0b, 0x4c, 0xf7, 0x9c, 0x9d, 0x46, 0x34,
+	0x49, 0xac, 0x60, 0x31, 0x93, 0x92, 0xeb, 0xd0, 0xd0, 0x5a, 0x7f, 0xa3, 0x6c, 0xe6, 0x6b, 0x68,
+	0x5b, 0xba, 0x2f, 0x60, 0x5e, 0xb5, 0x97, 0xc0, 0x68, 0x09, 0x96, 0xd7, 0x98, 0x02, 0xbf, 0x02,
+	0xc8, 0xd4, 0x5f, 0x20, 0xed, 0xbc, 0x2d, 0xad, 0x4d, 0xd2, 0x94, 0xd7, 0x98, 0x82, 0x76, 0xa0,
+	0x93, 0x3f, 0x8f, 0x02, 0x71, 0x17, 0x6d, 0x71, 0x27, 0x48, 0x9c, 0xed, 0x62, 0xa6, 0xd0, 0x5b,
+	0xd0, 0x32, 0x0f, 0xe2, 0x8d, 0x62, 0xc5, 0x9f, 0xca, 0xd0, 0x16, 0x07, 0xaa, 0x32, 0x9b, 0x0e,
+	0x54, 0x86, 0x5c, 0x05, 0x0a, 0xfc, 0xd4, 0xb9, 0x4e, 0xd9, 0xc8, 0x75, 0xd6, 0xa5, 0xb7, 0x54,
+	0xa6, 0x0c, 0x43, 0xca, 0x99, 0x72, 0x18, 0xd3, 0xd2, 0xaa, 0x39, 0x4b, 0xbb, 0x99, 0x59, 0x5a,
+	0xcd, 0xc8, 0xfa, 0x6d, 0x81, 0xc5, 0xc6, 0xf6, 0x2f, 0x1b, 0xc9, 0xbf, 0xa3, 0xb5, 0x3f, 0x60,
+	0x3a, 0xc8, 0xc3, 0x3d, 0x5d, 0xa2, 0x39, 0x50, 0xc5, 0x78, 0x65, 0xa5, 0x46, 0x3a, 0xe1, 0x75,
+	0x69, 0x2c, 0x4b, 0x04, 0xcb, 0xaf, 0x49, 0x04, 0xcf, 0x02, 0x50, 0x7a, 0x34, 0x75, 0x93, 0x13,
+	0x15, 0x1e, 0x47, 0x34, 0x92, 0xf9, 0x61, 0xdd, 0xa5, 0x6f, 0x67, 0x1b, 0x5a, 0xf2, 0x42, 0x11,
+	0x75, 0xe9, 0xf4, 0x21, 0xea, 0x4a, 0xb5, 0x3c, 0xa3, 0x52, 0xad, 0xd8, 0x95, 0xea, 0xb6, 0x2e,
+	0xe7, 0x66, 0x49, 0xc4, 0x6c, 0x53, 0x50, 0x48, 0x99, 0x0a, 0xcc, 0xe6, 0xaa, 0x18, 0x73, 0x39,
+	0xdf, 0x97, 0x60, 0x71, 0x73, 0x9c, 0x1e, 0x90, 0x4a, 0xf8, 0xcb, 0x31, 0x4f, 0xd2, 0x62, 0x63,
+	0xa3, 0xe2, 0xa0, 0x6c, 0x17, 0x07, 0xfa, 0x76, 0xa8, 0x1c, 0x73, 0x3b, 0x88, 0xac, 0x46, 0xc3,
+	0x98, 0x37, 0x60, 0xac, 0xf3, 0x86, 0x7c, 0x98, 0x52, 0x66, 0x53, 0x77, 0x33, 0x84, 0xb3, 0x01,
+	0x2d, 0xb1, 0x94, 0xec, 0x0c, 0x13, 0x1e, 0xee, 0xcd, 0x3a, 0x43, 0x1c, 0x73, 0xee, 0xc0, 0x92,
+	0x4e, 0x88, 0x35, 0xe3, 0x87, 0x59, 0xa5, 0x7b, 0xec, 0xc1, 0x3a, 0xff, 0x28, 0xc1, 0xa2, 0xc4,
+	0x9b, 0x1d, 0x80, 0xff, 0x82, 0x42, 0xe2, 0x0e, 0x9c, 0xc8, 0xae, 0x8d, 0x4c, 0x73, 0x17, 0xa0,
+	0x86, 0x07, 0xa9, 0x0a, 0x80, 0xc5, 0xdc, 0xfd, 0xe2, 0x8a, 0x51, 0xe7, 0x21, 0x9c, 0xb4, 0x42,
+	0x41, 0x26, 0x60, 0x0d, 0xea, 0xd2, 0xe8, 0x94, 0x0c, 0x36, 0x1d, 0x39, 0x5c, 0x4d, 0xe3, 0xfc,
+	0xb1, 0x04, 0xa7, 0x68, 0x6c, 0xcb, 0xf3, 0x0f, 0x38, 0x9e, 0x6e, 0x62, 0x9e, 0xc4, 0x41, 0x90,
+	0x8a, 0x53, 0xac, 0xba, 0xf4, 0x8d, 0xd9, 0x2c, 0x06, 0x5d, 0xae, 0x9a, 0x28, 0x12, 0x42, 0xcb,
+	0xe2, 0x47, 0x81, 0x9f, 0xd2, 0xc5, 0x5b, 0xa1, 0xa1, 0x0c, 0x81, 0x92, 0x92, 0xe0, 0x37, 0x5c,
+	0x76, 0x0e, 0xe8, 0x1b, 0xed, 0xd4, 0xf7, 0x46, 0x9e, 0x1f, 0xa4, 0x13, 0xd2, 0x77, 0xcd, 0xd5,
+	0xb0, 0xf3, 0xd7, 0x12, 0xcc, 0x3f, 0x8e, 0xfc, 0xc3, 0x68, 0x9c, 0x1e, 0x9b, 0x09, 0x61, 0x26,
+	0x2b, 0xbc, 0x5c, 0x79, 0x9c, 0x04, 0xb5, 0xd7, 0x54, 0x6c, 0xaf, 0xd9, 0xf3, 0x82, 0x70, 0x1c,
+	0xeb, 0x1e, 0x86, 0x86, 0xd1, 0x44, 0x42, 0x2f, 0x49, 0x77, 0x25, 0x42, 0x5a, 0x40, 0x13, 0x71,
+	0x0f, 0x04, 0x0a, 0x8d, 0x70, 0x3c, 0x4c, 0x83, 0x50, 0x5a, 0x80, 0x00, 0x50, 0x21, 0x61, 0xe4,
+	0x1f, 0xf2, 0x3e, 0xe5, 0xfe, 0x75, 0x57, 0x42, 0xce, 0x1d, 0xe8, 0xc8, 0x1d, 0x64, 0x0a, 0x5d,
+	0x85, 0x7a, 0x28, 0x71, 0xf2, 0x70, 0x5a, 0xe2, 0x9a, 0x15, 0x48, 0x57, 0x8f, 0x3a, 0x43, 0x58,
+	0xb8, 0xeb, 0xf9, 0x87, 0xe3, 0x91, 0xe6, 0xc5, 0xc5, 0x07, 0x21, 0x37, 0xd5, 0xa0, 0x60, 0x5d,
+	0xa0, 0x97, 0xb3, 0x02, 0x1d, 0x71, 0x87, 0x7c, 0x22, 0xce, 0xa2, 0xe6, 0xd2, 0x37, 0xaa, 0x2b,
+	0xe6, 0x83, 0xe8, 0x88, 0xf7, 0x29, 0x0f, 0x6c, 0xb8, 0x0a, 0x74, 0xfe, 0x52, 0x02, 0xd8, 0x1c,
+	0xf7, 0x83, 0x54, 0x77, 0xf1, 0x3c, 0x3f, 0x8d, 0x62, 0x39, 0x93, 0x00, 0x70, 0xab, 0xa9, 0x17,
+	0xef, 0x73, 0x15, 0x8b, 0x24, 0x84, 0x53, 0x51, 0xac, 0x97, 0xba, 0xc6, 0x6f, 0xa4, 0xf5, 0xe8,
+	0xf0, 0x55, 0x23, 0x42, 0x40, 0x2a, 0xbe, 0xd5, 0x0a, 0xa3, 0xe6, 0xdc, 0x54, 0xd4, 0x4c, 0x82,
+	0xa1, 0xcf, 0x49, 0xb3, 0x15, 0x57, 0x00, 0x88, 0x15, 0x75, 0x4f, 0x5d, 0xd4, 0x14, 0x04, 0x38,
+	0x7f, 0x53, 0x1b, 0x10, 0x77, 0x97, 0xd2, 0x48, 0xc9, 0xd0, 0x88, 0xde, 0x54, 0x39, 0xb7, 0xa9,
+	0x24, 0x1a, 0xc7, 0xbe, 0x0a, 0xa4, 0x12, 0x9a, 0xb9, 0x81, 0x4c, 0x09, 0x35, 0x4b, 0x09, 0x72,
+	0x63, 0x73, 0x85, 0x1b, 0x9b, 0xb7, 0x37, 0x76, 0x12, 0xe6, 0x5e, 0xf0, 0xbd, 0x28, 0xe6, 0xaa,
+	0x24, 0x14, 0x10, 0xad, 0x70, 0x0f, 0x03, 0x54, 0x43, 0xae, 0x10, 0x01, 0xe7, 0x16, 0xb4, 0x69,
+	0x67, 0xda, 0x14, 0x2e, 0xc2, 0x3c, 0x1f, 0xa6, 0x71, 0xc0, 0xed, 0x30, 0x91, 0x6d, 0xdf, 0x55,
+	0xe3, 0xce, 0xb7, 0x50, 0xc5, 0xac, 0xab, 0x30, 0xa8, 0x9e, 0xd7, 0x45, 0x41, 0x41, 0xd6, 0x29,
+	0x87, 0xa8, 0xa1, 0x14, 0x0d, 0xf7, 0x82, 0x7d, 0x79, 0x1d, 0x4a, 0xc8, 0x59, 0x87, 0x36, 0x0a,
+	0xce, 0x6c, 0xfb, 0x3d, 0xb3, 0x12, 0x6a, 0x6e, 0x34, 0x74, 0xc6, 0xa7, 0x8a, 0xa2, 0x1f, 0x4b,
+	0xd0, 0x7e, 0x1c, 0xed, 0xa3, 0x9d, 0xcb, 0xbb, 0xee, 0x16, 0x34, 0xd0, 0x2f, 0x77, 0x8d, 0x44,
+	0xe1, 0x8c, 0xf4, 0x07, 0x83, 0x6c, 0xed, 0x61, 0x94, 0xa4, 0x18, 0xfc, 0x1e, 0xbe, 0xe5, 0xd6,
+	0x0f, 0xe4, 0x37, 0x7b, 0xc7, 0x88, 0x0a, 0x74, 0x9e, 0x38, 0xaa, 0x30, 0xbd, 0x75, 0xa8, 0x2b,
+	0xae, 0x5f, 0x76, 0xa3, 0xde, 0x9d, 0x97, 0x37, 0xb4, 0xf3, 0x01, 0x30, 0xa3, 0x12, 0x9d, 0x79,
+	0x2d, 0x3b, 0xbf, 0x2d, 0xc1, 0x22, 0xca, 0xdf, 0xe1, 0x5e, 0xec, 0x1f, 0xbc, 0x59, 0x92, 0xd1,
+	0x33, 0x82, 0xb4, 0xa8, 0xd3, 0x34, 0x8c, 0x0a, 0x8f, 0xf6, 0xf6, 0x12, 0x9e, 0xca, 0x10, 0x25,
+	0xa1, 0xcc, 0xec, 0x6b, 0xa6, 0xd9, 0xff, 0x58, 0x02, 0x96, 0xad, 0x42, 0x1f, 0xc6, 0x0d, 0x74,
+	0x74, 0x6c, 0xd4, 0xab, 0xe3, 0x78, 0x97, 0xf4, 0x3a, 0x4d, 0xb9, 0x26, 0xfa, 0xf9, 0xae, 0x22,
+	0x17, 0x37, 0x6d, 0xea, 0x85, 0xaa, 0x43, 0x43, 0x40, 0xef, 0x73, 0xdd, 0xf8, 0x9f, 0xde, 0xa2,
+	0xca, 0xf4, 0xca, 0xb3, 0x33, 0x3d, 0xe7, 0xe7, 0x32, 0x54, 0xb6, 0x06, 0x7d, 0xe4, 0xe6, 0xaf,
+	0x34, 0x37, 0x7f, 0x55, 0x9c, 0x4a, 0x33, 0xa8, 0xf6, 0x79, 0xe2, 0xab, 0x78, 0x82, 0xdf, 0xec,
+	0x1c, 0x54, 0xb1, 0x9d, 0x46, 0x4a, 0x59, 0x90, 0xf5, 0xc4, 0xd6, 0xa0, 0xbf, 0x86, 0x8d, 0x2d,
+	0x97, 0x86, 0xb0, 0x1d, 0x97, 0xf8, 0xd1, 0x48, 0xc4, 0xee, 0x85, 0x8d, 0x05, 0x4d, 0xb3, 0x83,
+	0x58, 0x57, 0x0c, 0xa2, 0x70, 0x2f, 0xde, 0x17, 0x25, 0x6a, 0xc3, 0xa5, 0x6f, 0xb3, 0xe8, 0xf7,
+	0xc6, 0xe9, 0x41, 0x77, 0xde, 0x2a, 0xfa, 0x31, 0x45, 0x63, 0x67, 0xa0, 0x11, 0xf3, 0x97, 0xf2,
+	0x29, 0x40, 0x44, 0x9e, 0x7a, 0xcc, 0x5f, 0x8a, 0x97, 0x00, 0x39, 0x28, 0x1e, 0x02, 0x1a, 0xaa,
+	0x59, 0xfb, 0x92, 0xde, 0x01, 0xd4, 0x20, 0xa6, 0x59, 0xd8, 0xc9, 0xa9, 0xc8, 0x41, 0xac, 0x5e,
+	0x12, 0xe7, 0x63, 0xa8, 0xe2, 0x0e, 0x58, 0x13, 0xe6, 0xb7, 0xe3, 0xe0, 0x68, 0x90, 0xec, 0x77,
+	0xde, 0x62, 0x00, 0x73, 0x4f, 0xa3, 0x34, 0xf0, 0x79, 0xa7, 0x84, 0x03, 0x9b, 0xc3, 0x09, 0xd2,
+	0x74, 0xca, 0xce, 0x1a, 0xd4, 0x68, 0x2f, 0x8a, 0xdc, 0x4b, 0xb9, 0x20, 0xdf, 0x1e, 0xbf, 0x08,
+	0x03, 0xbf, 0x53, 0x62, 0x2d, 0xa8, 0x6f, 0x0e, 0x27, 0x44, 0xd4, 0x29, 0x3b, 0x3f, 0xcd, 0x41,
+	0x7d, 0x6b, 0xd0, 0xbf, 0x7f, 0xc4, 0x87, 0x29, 0xbb, 0x08, 0xf5, 0x20, 0xf6, 0xe9, 0x5b, 0x3a,
+	0x9b, 0xd0, 0xe2, 0x23, 0x77, 0x8b, 0x90, 0xae, 0x1e, 0xfe, 0x25, 0x47, 0xca, 0x3e, 0x01, 0x48,
+	0x74, 0x5e, 0x22, 0x33, 0xb0, 0xa9, 0x74, 0xc5, 0x20, 0x61, 0x57, 0x45, 0x8f, 0x13, 0x53, 0x90,
+	0x27, 0xba, 0xe5, 0xa6, 0xa4, 0x67, 0x39, 0xa4, 0x4d, 0xc4, 0x2e, 0x65, 0x41, 0xb4, 0x66, 0x64,
+	0x79, 0x66, 0xeb, 0x39, 0x8b, 0xab, 0xd7, 0xa1, 0x2d, 0xa2, 0xf1, 0x96, 0x71, 0xa1, 0x14, 0xb2,
+	0xd8, 0x74, 0xec, 0x4b, 0x68, 0x0a, 0xc4, 0x73, 0x4a, 0xbe, 0xe6, 0x0d, 0x9f, 0x51, 0xfa, 0x5b,
+	0x7b, 0x96, 0x11, 0xc8, 0xb6, 0x81, 0xc1, 0xc2, 0x5c, 0x58, 0x12, 0x60, 0xb6, 0x7b, 0xd5, 0xb8,
+	0x78, 0xbf, 0x48, 0x8e, 0x41, 0x26, 0xa4, 0x4d, 0xb3, 0xb3, 0x2f, 0xe1, 0x84, 0x40, 0x7e, 0xe3,
+	0xc5, 0x81, 0xd7, 0x0f, 0x7c, 0x21, 0x55, 0xb4, 0x35, 0xf2, 0xa7, 0x52, 0x44, 0xca, 0x9e, 0xc0,
+	0x69, 0x1b, 0x6d, 0xae, 0x0e, 0x8a, 0x53, 0xcc, 0xd9, 0x1c, 0xec, 0x92, 0xf4, 0x1d, 0xd1, 0xfc,
+	0x38, 0x65, 0xef, 0x6b, 0x33, 0xde, 0x97, 0x5b, 0x21, 0xa2, 0xde, 0x53, 0xe8, 0xe4, 0x55, 0x56,
+	0x50, 0x52, 0xbe, 0x6f, 0x57, 0xf6, 0xf9, 0x5d, 0x19, 0xe5, 0xe9, 0x73, 0x38, 0x59, 0xac, 0xba,
+	0x02, 0xa9, 0x17, 0x6c, 0xa9, 0xd3, 0x69, 0xb4, 0xd5, 0x53, 0xd1, 0x2b, 0x7f, 0xa3, 0x92, 0xf7,
+	0xff, 0xa1, 0xa3, 0xf6, 0xae, 0xe3, 0xee, 0x02, 0x94, 0x83, 0xbe, 0xcc, 0x97, 0xcb, 0x41, 0xbf,
+	0x30, 0xba, 0x9d, 0x87, 0x1a, 0x27, 0x27, 0xac, 0x18, 0x4e, 0xa8, 0x25, 0x89, 0x31, 0xc7, 0x83,
+	0x8e, 0xf6, 0xcb, 0x59, 0xc2, 0xb5, 0xa0, 0x72, 0x91, 0x37, 0x8b, 0x31, 0x4c, 0x42, 0x8e, 0x78,
+	0xdc, 0x0f, 0xfc, 0x54, 0x66, 0xe5, 0x0a, 0x74, 0x46, 0x50, 0x57, 0xc4, 0x85, 0xe9, 0x01, 0xbd,
+	0x06, 0x0d, 0xfb, 0xe6, 0x6b, 0x10, 0x42, 0x3a, 0x80, 0x56, 0x8c, 0x00, 0xaa, 0xd2, 0xad, 0xaa,
+	0x91, 0x6e, 0x4d, 0x65, 0x7a, 0xce, 0x11, 0x30, 0x97, 0xef, 0x07, 0x49, 0xca, 0xe3, 0xad, 0x41,
+	0xdf, 0xb8, 0x5a, 0x73, 0x77, 0xc2, 0xec, 0xac, 0xde, 0x48, 0xa9, 0x2a, 0x76, 0x4a, 0xd5, 0x83,
+	0x8a, 0x3f, 0xe8, 0xcb, 0x98, 0x52, 0x57, 0x3a, 0x75, 0x11, 0xe9, 0x5c, 0x05, 0xc8, 0x7a, 0x45,
+	0x85, 0x7b, 0x55, 0x37, 0x4e, 0x39, 0xbb, 0x71, 0x9c, 0x5f, 0xc3, 0xe9, 0x7b, 0xdc, 0x0f, 0xbd,
+	0x98, 0x67, 0xcc, 0xc9, 0xec, 0x45, 0x5f, 0xb6, 0x7b, 0x8f, 0x65, 0xc3, 0xb9, 0x32, 0x7e, 0xbb,
+	0xe5, 0xfb, 0xe7, 0x12, 0xcc, 0x63, 0xf9, 0x89, 0xaf, 0x04, 0x45, 0xab, 0x92, 0x93, 0x94, 0xad,
+	0xdb, 0x72, 0xea, 0x66, 0x44, 0xdd, 0x4f, 0x46, 0x5c, 0xa6, 0xa9, 0xf4, 0x8d, 0x7a, 0xea, 0xf3,
+	0x3d, 0x6f, 0x1c, 0x2a, 0xfd, 0x2b, 0x10, 0x4f, 0x95, 0x4c, 0x58, 0x5d, 0x80, 0x12, 0xca, 0x5e,
+	0xcc, 0xe7, 0x0b, 0x5f, 0xcc, 0x45, 0x9e, 0x2a, 0x00, 0xe7, 0x19, 0x9c, 0x94, 0x9a, 0x91, 0xab,
+	0x3f, 0x46, 0x2d, 0xab, 0x50, 0x4f, 0x24, 0x51, 0xb7, 0x6c, 0x94, 0x3c, 0x92, 0xd3, 0xd5, 0xa3,
+	0x58, 0x30, 0x65, 0xe2, 0xb2, 0x82, 0x49, 0x73, 0x97, 0x8e, 0xe5, 0xfe, 0xa1, 0x04, 0x8b, 0xca,
+	0xb8, 0xfe, 0xb3, 0x96, 0xb5, 0xac, 0xdc, 0x4c, 0x28, 0x58, 0x00, 0xd4, 0x65, 0x89, 0x83, 0x28,
+	0x36, 0xaa, 0x57, 0x05, 0xe3, 0xbc, 0xa3, 0x98, 0xd3, 0xe5, 0x53, 0x77, 0xf1, 0xd3, 0xd9, 0x81,
+	0xa5, 0xed, 0x98, 0x7f, 0x23, 0x3c, 0xef, 0xd8, 0xe5, 0x29, 0x67, 0x2d, 0x5b, 0xce, 0x4a, 0x05,
+	0x74, 0x1a, 0x8d, 0x64, 0x12, 0x4e, 0xdf, 0x8e, 0x03, 0x9d, 0x6c, 0xc7, 0xc5, 0x31, 0xc2, 0xb9,
+	0x09, 0x27, 0x76, 0xc6, 0x2f, 0x12, 0x3f, 0x0e, 0x46, 0x58, 0xbd, 0xcc, 0x9e, 0xba, 0x03, 0x95,
+	0xa0, 0x2f, 0x8e, 0xa8, 0xea, 0xe2, 0xa7, 0xf3, 0x99, 0xec, 0x50, 0x88, 0xf6, 0xc5, 0x31, 0x47,
+	0xbc, 0xac, 0x9e, 0x41, 0xcb, 0xa2, 0x7b, 0x4f, 0x00, 0x1a, 0x77, 0xd3, 0xe0, 0x67, 0x4c, 0x26,
+	0x70, 0xd2, 0xc0, 0xf1, 0xdb, 0x2a, 0xed, 0xcb, 0xb3, 0x4b, 0xfb, 0x4a, 0x71, 0x69, 0x5f, 0x35,
+	0x4a, 0xfb, 0xae, 0x9d, 0x26, 0x58, 0x81, 0x21, 0xeb, 0xb2, 0xce, 0xe5, 0xba, 0xac, 0x2a, 0x6c,
+	0xcd, 0x1b, 0x0f, 0xdb, 0xd7, 0x60, 0xe9, 0xf9, 0x30, 0x7e, 0xad, 0x25, 0x09, 0x45, 0x97, 0xb5,
+	0xa2, 0x57, 0x61, 0x39, 0x63, 0xdb, 0x0c, 0xc3, 0x99, 0x9c, 0xce, 0x3d, 0x68, 0x7d, 0x1b, 0x07,
+	0x29, 0x3f, 0xf6, 0x2c, 0x86, 0xba, 0xc8, 0xc6, 0x4f, 0xc4, 0x0c, 0x12, 0x51, 0x82, 0xb5, 0x5c,
+	0xfc, 0xdc, 0xf8, 0xfb, 0x09, 0xa8, 0xdc, 0x7f, 0x95, 0xb2, 0xdb, 0x30, 0x47, 0x21, 0x3c, 0x61,
+	0x5d, 0xe1, 0x19, 0xd3, 0xa7, 0xdd, 0x7b, 0xdb, 0xbe, 0x19, 0xa4, 0xad, 0xac, 0x97, 0xd8, 0x67,
+	0x50, 0xdf, 0x8a, 0x06, 0x03, 0x6f, 0xd8, 0x7f, 0x3d, 0x7b, 0xfe, 0xae, 0x5b, 0x2f, 0xb1, 0x3b,
+	0xd0, 0x32, 0x4e, 0x58, 0x8b, 0x98, 0x36, 0x9a, 0x5e, 0x27, 0x3f, 0xb2, 0x5e, 0x62, 0x1f, 0x40,
+	0x8d, 0xf4, 0xc0, 0x44, 0x7a, 0x66, 0xea, 0xa4, 0x07, 0x84, 0xa2, 0x3f, 0x1d, 0xb1, 0xeb, 0x50,
+	0x57, 0x66, 0xce, 0x96, 0x09, 0x9f, 0xf3, 0xf3, 0xde, 0xdb, 0x39, 0xac, 0xf4, 0x85, 0x75, 0x80,
+	0xcc, 0xe9, 0xd8, 0x49, 0x11, 0x8a, 0xf3, 0x5e, 0x68, 0x4d, 0xf5, 0x19, 0x34, 0x8d, 0x0b, 0x8a,
+	0x9d, 0xb2, 0xe4, 0x66, 0x57, 0xd6, 0xac, 0x09, 0x2f, 0x03, 0x64, 0x36, 0x20, 0x27, 0x9c, 0xb2,
+	0xa5, 0x5e, 0x53, 0x32, 0x53, 0x39, 0x75, 0x15, 0xda, 0x19, 0x05, 0xce, 0xf9, 0x8b, 0xb8, 0x3e,
+	0x35, 0xb9, 0x36, 0xc3, 0x90, 0x9d, 0xce, 0x71, 0x65, 0x06, 0x68, 0xed, 0xef, 0x4b, 0x60, 0xd3,
+	0x57, 0x1a, 0x13, 0x79, 0xee, 0xcc, 0xbb, 0xce, 0x92, 0x70, 0x0b, 0x16, 0x73, 0xa1, 0x9f, 0x9d,
+	0x31, 0xd9, 0x73, 0x17, 0x82, 0xc5, 0x7b, 0x05, 0x16, 0xd4, 0xf0, 0x8e, 0x7f, 0xc0, 0x07, 0x1e,
+	0x33, 0x46, 0xa5, 0x4e, 0xa7, 0x6e, 0x80, 0x2f, 0xac, 0x72, 0x3c, 0x1e, 0x78, 0xd4, 0x84, 0x39,
+	0x95, 0x7f, 0x31, 0xb6, 0x0d, 0xcd, 0x18, 0x60, 0x1f, 0xc9, 0x3f, 0xda, 0xe0, 0xeb, 0x84, 0x9c,
+	0x90, 0x8a, 0xf5, 0x9e, 0xac, 0x0a, 0xcc, 0x47, 0x8b, 0x4f, 0x00, 0x74, 0xea, 0x99, 0xb0, 0x25,
+	0x53, 0x96, 0xe0, 0xc9, 0xa5, 0xa7, 0xec, 0x06, 0x74, 0x32, 0x86, 0xbb, 0x13, 0xb4, 0xed, 0x22,
+	0xb6, 0x25, 0xf9, 0x98, 0x66, 0xfc, 0xcf, 0xed, 0x73, 0x78, 0x3b, 0xcf, 0x49, 0xff, 0x71, 0x2b,
+	0x62, 0x17, 0x1d, 0x5c, 0xfb, 0x2f, 0x70, 0xa8, 0x4c, 0xc5, 0x2f, 0x2a, 0x25, 0xab, 0xfd, 0x6d,
+	0x2e, 0x37, 0x23, 0xb9, 0x9e, 0xfb, 0xcf, 0x4e, 0xc1, 0x5c, 0xcb, 0xa6, 0x14, 0xa3, 0xab, 0xdc,
+	0x36, 0x19, 0x93, 0x02, 0x45, 0x5a, 0xbb, 0xbb, 0x02, 0x4b, 0x26, 0xbd, 0xd8, 0x99, 0xc9, 0x53,
+	0xb4, 0xa5, 0x4b, 0xf2, 0xa4, 0x1e, 0x25, 0x5f, 0x0f, 0x8b, 0x76, 0x63, 0xb9, 0xc0, 0x17, 0x72,
+	0xff, 0x0f, 0x82, 0xa1, 0x2c, 0x4e, 0x96, 0x73, 0x2d, 0x0e, 0xc1, 0x74, 0x6a, 0x46, 0xe3, 0x83,
+	0x3d, 0x82, 0xae, 0x2d, 0xe0, 0xee, 0xc4, 0x55, 0xff, 0xaf, 0x7a, 0x43, 0x51, 0x1b, 0xf2, 0xc5,
+	0x50, 0xbd, 0xe5, 0x48, 0xfe, 0xdc, 0xd3, 0x8e, 0xbd, 0xfe, 0x6b, 0xb0, 0xa8, 0x79, 0x64, 0x81,
+	0x5c, 0x70, 0x1a, 0xf9, 0xc2, 0x85, 0xad, 0xa2, 0x8e, 0xa2, 0x58, 0x58, 0x9f, 0xa9, 0xd0, 0x29,
+	0xca, 0x0d, 0xf9, 0x77, 0x02, 0xa1, 0x1c, 0xd3, 0xd3, 0xba, 0x39, 0xd2, 0xcc, 0xd9, 0x6e, 0xcb,
+	0x2b, 0x5f, 0xea, 0x43, 0x2e, 0xc5, 0x9a, 0x67, 0x36, 0xf3, 0x5d, 0x9b, 0xf9, 0x18, 0x1b, 0x9b,
+	0x2d, 0xe3, 0x9a, 0x71, 0xa3, 0xcc, 0x60, 0x2e, 0x78, 0xce, 0x60, 0x37, 0xe4, 0x01, 0xe4, 0xcc,
+	0x53, 0x6c, 0xf7, 0xcc, 0x34, 0x43, 0x62, 0xd8, 0x9c, 0x98, 0x70, 0x7b, 0x2c, 0x9a, 0x85, 0x79,
+	0x35, 0x5a, 0x01, 0xec, 0xb2, 0x3c, 0xb3, 0xed, 0xb1, 0x6e, 0x1c, 0x14, 0xac, 0xc6, 0x62, 0xb9,
+	0x28, 0x59, 0xee, 0xf1, 0x90, 0xa7, 0xd3, 0xa7, 0x66, 0x87, 0x47, 0x66, 0x90, 0x1e, 0xa3, 0x01,
+	0x93, 0xe9, 0x63, 0x99, 0x64, 0x89, 0x8e, 0xe9, 0xeb, 0xa8, 0x2f, 0xc1, 0x92, 0x41, 0x7d, 0x77,
+	0x72, 0xec, 0x7a, 0x6e, 0xc3, 0x62, 0xee, 0x61, 0xc8, 0x52, 0xab, 0xf1, 0xc2, 0x5d, 0xf0, 0x74,
+	0xa4, 0x5c, 0x42, 0x3d, 0x81, 0x14, 0x84, 0xfa, 0xa9, 0xd7, 0x91, 0xab, 0x52, 0x01, 0x5b, 0x21,
+	0xf7, 0xe2, 0x1c, 0xe3, 0xec, 0xa8, 0xb1, 0x26, 0x35, 0x20, 0x9e, 0x4b, 0xac, 0x79, 0xc4, 0xdf,
+	0x04, 0x72, 0xef, 0x28, 0x97, 0xa5, 0x5f, 0x50, 0xb7, 0x9c, 0x19, 0x9d, 0x73, 0x73, 0x0a, 0xbb,
+	0xdf, 0xfe, 0xb1, 0x64, 0xa1, 0x86, 0xb7, 0x35, 0x03, 0xd3, 0x5d, 0x6e, 0xf3, 0x09, 0x4f, 0x9b,
+	0x14, 0x0e, 0xb0, 0xac, 0x13, 0x6e, 0xa9, 0xf7, 0x23, 0xcb, 0x32, 0x88, 0xd2, 0xdc, 0xaa, 0x19,
+	0x2c, 0x5e, 0xcc, 0xd1, 0x9f, 0xb1, 0xaf, 0xfc, 0x73, 0x00, 0x04, 0x06, 0x5d, 0x2e, 0x9f, 0x2d,
+	0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -2537,12 +4313,22 @@ type ExtClient interface {
 	Events(ctx context.Context, in *SubscriptionRequest, opts ...grpc.CallOption) (Ext_EventsClient, error)
 	// Commands is the same as Events above but for Commands.
 	Commands(ctx context.Context, in *SubscriptionRequest, opts ...grpc.CallOption) (Ext_CommandsClient, error)
+	// StoreChanges streams the changes made to the store in the order they
+	// were made, they're also dispatched as STORE_* events.
+	StoreChanges(ctx context.Context, in *StoreChangesRequest, opts ...grpc.CallOption) (Ext_StoreChangesClient, error)
 	Write(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*Empty, error)
 	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterResponse, error)
+	// PreVerdict answers an event sent to a pre handler, if it's not answered
+	// in time the event is dispatched as if it wasn't consumed.
+	PreVerdict(ctx context.Context, in *PreVerdictRequest, opts ...grpc.CallOption) (*Empty, error)
 	RegisterCmd(ctx context.Context, in *RegisterCmdRequest, opts ...grpc.CallOption) (*RegisterResponse, error)
 	Unregister(ctx context.Context, in *UnregisterRequest, opts ...grpc.CallOption) (*Result, error)
 	UnregisterCmd(ctx context.Context, in *UnregisterRequest, opts ...grpc.CallOption) (*Result, error)
 	UnregisterAll(ctx context.Context, in *UnregisterAllRequest, opts ...grpc.CallOption) (*Empty, error)
+	DeclarePermissions(ctx context.Context, in *DeclarePermissionsRequest, opts ...grpc.CallOption) (*Empty, error)
+	DeclareSettings(ctx context.Context, in *DeclareSettingsRequest, opts ...grpc.CallOption) (*Empty, error)
+	// SettingsSchema describes every declared channel setting.
+	SettingsSchema(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SettingsResponse, error)
 	//==================================
 	//Data methods
 	//==================================
@@ -2556,6 +4342,8 @@ type ExtClient interface {
 	StateChannels(ctx context.Context, in *Query, opts ...grpc.CallOption) (*ListResponse, error)
 	StateChannelCount(ctx context.Context, in *Query, opts ...grpc.CallOption) (*CountResponse, error)
 	StateIsOn(ctx context.Context, in *ChannelQuery, opts ...grpc.CallOption) (*Result, error)
+	StateFindUsers(ctx context.Context, in *UserSearchQuery, opts ...grpc.CallOption) (*UserSearchResponse, error)
+	StateFindUsersByRealname(ctx context.Context, in *UserSearchQuery, opts ...grpc.CallOption) (*UserSearchResponse, error)
 	StoreAuthUser(ctx context.Context, in *AuthUserRequest, opts ...grpc.CallOption) (*Result, error)
 	StoreAuthedUser(ctx context.Context, in *NetworkQuery, opts ...grpc.CallOption) (*StoredUser, error)
 	StoreUser(ctx context.Context, in *Query, opts ...grpc.CallOption) (*StoredUser, error)
@@ -2564,12 +4352,23 @@ type ExtClient interface {
 	StoreUsersByChannel(ctx context.Context, in *NetworkQuery, opts ...grpc.CallOption) (*StoredUsersResponse, error)
 	StoreChannel(ctx context.Context, in *NetworkQuery, opts ...grpc.CallOption) (*StoredChannel, error)
 	StoreChannels(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StoredChannelsResponse, error)
+	// Puts are a compare and swap on the revision, a put of a record that was
+	// changed since it was read fails with ABORTED and should be retried.
 	StorePutUser(ctx context.Context, in *StoredUser, opts ...grpc.CallOption) (*Empty, error)
 	StorePutChannel(ctx context.Context, in *StoredChannel, opts ...grpc.CallOption) (*Empty, error)
 	StoreDeleteUser(ctx context.Context, in *Query, opts ...grpc.CallOption) (*Empty, error)
 	StoreDeleteChannel(ctx context.Context, in *NetworkQuery, opts ...grpc.CallOption) (*Empty, error)
 	StoreLogout(ctx context.Context, in *NetworkQuery, opts ...grpc.CallOption) (*Empty, error)
 	StoreLogoutByUser(ctx context.Context, in *Query, opts ...grpc.CallOption) (*Empty, error)
+	StoreCacheStats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StoreCacheStatsResponse, error)
+	StoreLockouts(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*LockoutsResponse, error)
+	StoreClearLockouts(ctx context.Context, in *Query, opts ...grpc.CallOption) (*CountResponse, error)
+	// StoreBackup takes a snapshot of the store into the bot's backupdir.
+	StoreBackup(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*BackupResponse, error)
+	StoreAudit(ctx context.Context, in *AuditQuery, opts ...grpc.CallOption) (*AuditResponse, error)
+	StoreRoles(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*RolesResponse, error)
+	StorePutRole(ctx context.Context, in *Role, opts ...grpc.CallOption) (*Empty, error)
+	StoreDeleteRole(ctx context.Context, in *Query, opts ...grpc.CallOption) (*Result, error)
 }
 
 type extClient struct {
@@ -2644,6 +4443,38 @@ func (x *extCommandsClient) Recv() (*CmdEventResponse, error) {
 	return m, nil
 }
 
+func (c *extClient) StoreChanges(ctx context.Context, in *StoreChangesRequest, opts ...grpc.CallOption) (Ext_StoreChangesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Ext_serviceDesc.Streams[2], "/api.Ext/StoreChanges", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &extStoreChangesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Ext_StoreChangesClient interface {
+	Recv() (*StoreChange, error)
+	grpc.ClientStream
+}
+
+type extStoreChangesClient struct {
+	grpc.ClientStream
+}
+
+func (x *extStoreChangesClient) Recv() (*StoreChange, error) {
+	m := new(StoreChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *extClient) Write(ctx context.Context, in *WriteRequest, opts ...grpc.CallOption) (*Empty, error) {
 	out := new(Empty)
 	err := c.cc.Invoke(ctx, "/api.Ext/Write", in, out, opts...)
@@ -2662,6 +4493,15 @@ func (c *extClient) Register(ctx context.Context, in *RegisterRequest, opts ...g
 	return out, nil
 }
 
+func (c *extClient) PreVerdict(ctx context.Context, in *PreVerdictRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/api.Ext/PreVerdict", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *extClient) RegisterCmd(ctx context.Context, in *RegisterCmdRequest, opts ...grpc.CallOption) (*RegisterResponse, error) {
 	out := new(RegisterResponse)
 	err := c.cc.Invoke(ctx, "/api.Ext/RegisterCmd", in, out, opts...)
@@ -2698,6 +4538,33 @@ func (c *extClient) UnregisterAll(ctx context.Context, in *UnregisterAllRequest,
 	return out, nil
 }
 
+func (c *extClient) DeclarePermissions(ctx context.Context, in *DeclarePermissionsRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/api.Ext/DeclarePermissions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *extClient) DeclareSettings(ctx context.Context, in *DeclareSettingsRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/api.Ext/DeclareSettings", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *extClient) SettingsSchema(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SettingsResponse, error) {
+	out := new(SettingsResponse)
+	err := c.cc.Invoke(ctx, "/api.Ext/SettingsSchema", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *extClient) NetworkInformation(ctx context.Context, in *NetworkInfoRequest, opts ...grpc.CallOption) (*NetworkInfo, error) {
 	out := new(NetworkInfo)
 	err := c.cc.Invoke(ctx, "/api.Ext/NetworkInformation", in, out, opts...)
@@ -2788,6 +4655,24 @@ func (c *extClient) StateIsOn(ctx context.Context, in *ChannelQuery, opts ...grp
 	return out, nil
 }
 
+func (c *extClient) StateFindUsers(ctx context.Context, in *UserSearchQuery, opts ...grpc.CallOption) (*UserSearchResponse, error) {
+	out := new(UserSearchResponse)
+	err := c.cc.Invoke(ctx, "/api.Ext/StateFindUsers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *extClient) StateFindUsersByRealname(ctx context.Context, in *UserSearchQuery, opts ...grpc.CallOption) (*UserSearchResponse, error) {
+	out := new(UserSearchResponse)
+	err := c.cc.Invoke(ctx, "/api.Ext/StateFindUsersByRealname", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *extClient) StoreAuthUser(ctx context.Context, in *AuthUserRequest, opts ...grpc.CallOption) (*Result, error) {
 	out := new(Result)
 	err := c.cc.Invoke(ctx, "/api.Ext/StoreAuthUser", in, out, opts...)
@@ -2851,63 +4736,135 @@ func (c *extClient) StoreChannel(ctx context.Context, in *NetworkQuery, opts ...
 	return out, nil
 }
 
-func (c *extClient) StoreChannels(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StoredChannelsResponse, error) {
-	out := new(StoredChannelsResponse)
-	err := c.cc.Invoke(ctx, "/api.Ext/StoreChannels", in, out, opts...)
+func (c *extClient) StoreChannels(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StoredChannelsResponse, error) {
+	out := new(StoredChannelsResponse)
+	err := c.cc.Invoke(ctx, "/api.Ext/StoreChannels", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *extClient) StorePutUser(ctx context.Context, in *StoredUser, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/api.Ext/StorePutUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *extClient) StorePutChannel(ctx context.Context, in *StoredChannel, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/api.Ext/StorePutChannel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *extClient) StoreDeleteUser(ctx context.Context, in *Query, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/api.Ext/StoreDeleteUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *extClient) StoreDeleteChannel(ctx context.Context, in *NetworkQuery, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/api.Ext/StoreDeleteChannel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *extClient) StoreLogout(ctx context.Context, in *NetworkQuery, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/api.Ext/StoreLogout", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *extClient) StoreLogoutByUser(ctx context.Context, in *Query, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/api.Ext/StoreLogoutByUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *extClient) StoreCacheStats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StoreCacheStatsResponse, error) {
+	out := new(StoreCacheStatsResponse)
+	err := c.cc.Invoke(ctx, "/api.Ext/StoreCacheStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *extClient) StoreLockouts(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*LockoutsResponse, error) {
+	out := new(LockoutsResponse)
+	err := c.cc.Invoke(ctx, "/api.Ext/StoreLockouts", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *extClient) StorePutUser(ctx context.Context, in *StoredUser, opts ...grpc.CallOption) (*Empty, error) {
-	out := new(Empty)
-	err := c.cc.Invoke(ctx, "/api.Ext/StorePutUser", in, out, opts...)
+func (c *extClient) StoreClearLockouts(ctx context.Context, in *Query, opts ...grpc.CallOption) (*CountResponse, error) {
+	out := new(CountResponse)
+	err := c.cc.Invoke(ctx, "/api.Ext/StoreClearLockouts", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *extClient) StorePutChannel(ctx context.Context, in *StoredChannel, opts ...grpc.CallOption) (*Empty, error) {
-	out := new(Empty)
-	err := c.cc.Invoke(ctx, "/api.Ext/StorePutChannel", in, out, opts...)
+func (c *extClient) StoreBackup(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*BackupResponse, error) {
+	out := new(BackupResponse)
+	err := c.cc.Invoke(ctx, "/api.Ext/StoreBackup", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *extClient) StoreDeleteUser(ctx context.Context, in *Query, opts ...grpc.CallOption) (*Empty, error) {
-	out := new(Empty)
-	err := c.cc.Invoke(ctx, "/api.Ext/StoreDeleteUser", in, out, opts...)
+func (c *extClient) StoreAudit(ctx context.Context, in *AuditQuery, opts ...grpc.CallOption) (*AuditResponse, error) {
+	out := new(AuditResponse)
+	err := c.cc.Invoke(ctx, "/api.Ext/StoreAudit", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *extClient) StoreDeleteChannel(ctx context.Context, in *NetworkQuery, opts ...grpc.CallOption) (*Empty, error) {
-	out := new(Empty)
-	err := c.cc.Invoke(ctx, "/api.Ext/StoreDeleteChannel", in, out, opts...)
+func (c *extClient) StoreRoles(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*RolesResponse, error) {
+	out := new(RolesResponse)
+	err := c.cc.Invoke(ctx, "/api.Ext/StoreRoles", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *extClient) StoreLogout(ctx context.Context, in *NetworkQuery, opts ...grpc.CallOption) (*Empty, error) {
+func (c *extClient) StorePutRole(ctx context.Context, in *Role, opts ...grpc.CallOption) (*Empty, error) {
 	out := new(Empty)
-	err := c.cc.Invoke(ctx, "/api.Ext/StoreLogout", in, out, opts...)
+	err := c.cc.Invoke(ctx, "/api.Ext/StorePutRole", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *extClient) StoreLogoutByUser(ctx context.Context, in *Query, opts ...grpc.CallOption) (*Empty, error) {
-	out := new(Empty)
-	err := c.cc.Invoke(ctx, "/api.Ext/StoreLogoutByUser", in, out, opts...)
+func (c *extClient) StoreDeleteRole(ctx context.Context, in *Query, opts ...grpc.CallOption) (*Result, error) {
+	out := new(Result)
+	err := c.cc.Invoke(ctx, "/api.Ext/StoreDeleteRole", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -2923,12 +4880,22 @@ type ExtServer interface {
 	Events(*SubscriptionRequest, Ext_EventsServer) error
 	// Commands is the same as Events above but for Commands.
 	Commands(*SubscriptionRequest, Ext_CommandsServer) error
+	// StoreChanges streams the changes made to the store in the order they
+	// were made, they're also dispatched as STORE_* events.
+	StoreChanges(*StoreChangesRequest, Ext_StoreChangesServer) error
 	Write(context.Context, *WriteRequest) (*Empty, error)
 	Register(context.Context, *RegisterRequest) (*RegisterResponse, error)
+	// PreVerdict answers an event sent to a pre handler, if it's not answered
+	// in time the event is dispatched as if it wasn't consumed.
+	PreVerdict(context.Context, *PreVerdictRequest) (*Empty, error)
 	RegisterCmd(context.Context, *RegisterCmdRequest) (*RegisterResponse, error)
 	Unregister(context.Context, *UnregisterRequest) (*Result, error)
 	UnregisterCmd(context.Context, *UnregisterRequest) (*Result, error)
 	UnregisterAll(context.Context, *UnregisterAllRequest) (*Empty, error)
+	DeclarePermissions(context.Context, *DeclarePermissionsRequest) (*Empty, error)
+	DeclareSettings(context.Context, *DeclareSettingsRequest) (*Empty, error)
+	// SettingsSchema describes every declared channel setting.
+	SettingsSchema(context.Context, *Empty) (*SettingsResponse, error)
 	//==================================
 	//Data methods
 	//==================================
@@ -2942,6 +4909,8 @@ type ExtServer interface {
 	StateChannels(context.Context, *Query) (*ListResponse, error)
 	StateChannelCount(context.Context, *Query) (*CountResponse, error)
 	StateIsOn(context.Context, *ChannelQuery) (*Result, error)
+	StateFindUsers(context.Context, *UserSearchQuery) (*UserSearchResponse, error)
+	StateFindUsersByRealname(context.Context, *UserSearchQuery) (*UserSearchResponse, error)
 	StoreAuthUser(context.Context, *AuthUserRequest) (*Result, error)
 	StoreAuthedUser(context.Context, *NetworkQuery) (*StoredUser, error)
 	StoreUser(context.Context, *Query) (*StoredUser, error)
@@ -2950,12 +4919,23 @@ type ExtServer interface {
 	StoreUsersByChannel(context.Context, *NetworkQuery) (*StoredUsersResponse, error)
 	StoreChannel(context.Context, *NetworkQuery) (*StoredChannel, error)
 	StoreChannels(context.Context, *Empty) (*StoredChannelsResponse, error)
+	// Puts are a compare and swap on the revision, a put of a record that was
+	// changed since it was read fails with ABORTED and should be retried.
 	StorePutUser(context.Context, *StoredUser) (*Empty, error)
 	StorePutChannel(context.Context, *StoredChannel) (*Empty, error)
 	StoreDeleteUser(context.Context, *Query) (*Empty, error)
 	StoreDeleteChannel(context.Context, *NetworkQuery) (*Empty, error)
 	StoreLogout(context.Context, *NetworkQuery) (*Empty, error)
 	StoreLogoutByUser(context.Context, *Query) (*Empty, error)
+	StoreCacheStats(context.Context, *Empty) (*StoreCacheStatsResponse, error)
+	StoreLockouts(context.Context, *Empty) (*LockoutsResponse, error)
+	StoreClearLockouts(context.Context, *Query) (*CountResponse, error)
+	// StoreBackup takes a snapshot of the store into the bot's backupdir.
+	StoreBackup(context.Context, *Empty) (*BackupResponse, error)
+	StoreAudit(context.Context, *AuditQuery) (*AuditResponse, error)
+	StoreRoles(context.Context, *Empty) (*RolesResponse, error)
+	StorePutRole(context.Context, *Role) (*Empty, error)
+	StoreDeleteRole(context.Context, *Query) (*Result, error)
 }
 
 func RegisterExtServer(s *grpc.Server, srv ExtServer) {
@@ -3004,6 +4984,27 @@ func (x *extCommandsServer) Send(m *CmdEventResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Ext_StoreChanges_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StoreChangesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ExtServer).StoreChanges(m, &extStoreChangesServer{stream})
+}
+
+type Ext_StoreChangesServer interface {
+	Send(*StoreChange) error
+	grpc.ServerStream
+}
+
+type extStoreChangesServer struct {
+	grpc.ServerStream
+}
+
+func (x *extStoreChangesServer) Send(m *StoreChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Ext_Write_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(WriteRequest)
 	if err := dec(in); err != nil {
@@ -3040,6 +5041,24 @@ func _Ext_Register_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Ext_PreVerdict_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PreVerdictRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).PreVerdict(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/PreVerdict",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).PreVerdict(ctx, req.(*PreVerdictRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Ext_RegisterCmd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RegisterCmdRequest)
 	if err := dec(in); err != nil {
@@ -3112,6 +5131,60 @@ func _Ext_UnregisterAll_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Ext_DeclarePermissions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeclarePermissionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).DeclarePermissions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/DeclarePermissions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).DeclarePermissions(ctx, req.(*DeclarePermissionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ext_DeclareSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeclareSettingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).DeclareSettings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/DeclareSettings",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).DeclareSettings(ctx, req.(*DeclareSettingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ext_SettingsSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).SettingsSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/SettingsSchema",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).SettingsSchema(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Ext_NetworkInformation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(NetworkInfoRequest)
 	if err := dec(in); err != nil {
@@ -3292,6 +5365,42 @@ func _Ext_StateIsOn_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Ext_StateFindUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserSearchQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).StateFindUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/StateFindUsers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).StateFindUsers(ctx, req.(*UserSearchQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ext_StateFindUsersByRealname_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserSearchQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).StateFindUsersByRealname(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/StateFindUsersByRealname",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).StateFindUsersByRealname(ctx, req.(*UserSearchQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Ext_StoreAuthUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(AuthUserRequest)
 	if err := dec(in); err != nil {
@@ -3544,6 +5653,150 @@ func _Ext_StoreLogoutByUser_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Ext_StoreCacheStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).StoreCacheStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/StoreCacheStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).StoreCacheStats(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ext_StoreLockouts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).StoreLockouts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/StoreLockouts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).StoreLockouts(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ext_StoreClearLockouts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Query)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).StoreClearLockouts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/StoreClearLockouts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).StoreClearLockouts(ctx, req.(*Query))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ext_StoreBackup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).StoreBackup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/StoreBackup",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).StoreBackup(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ext_StoreAudit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).StoreAudit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/StoreAudit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).StoreAudit(ctx, req.(*AuditQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ext_StoreRoles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).StoreRoles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/StoreRoles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).StoreRoles(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ext_StorePutRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Role)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).StorePutRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/StorePutRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).StorePutRole(ctx, req.(*Role))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Ext_StoreDeleteRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Query)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExtServer).StoreDeleteRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Ext/StoreDeleteRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExtServer).StoreDeleteRole(ctx, req.(*Query))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Ext_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "api.Ext",
 	HandlerType: (*ExtServer)(nil),
@@ -3556,6 +5809,10 @@ var _Ext_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Register",
 			Handler:    _Ext_Register_Handler,
 		},
+		{
+			MethodName: "PreVerdict",
+			Handler:    _Ext_PreVerdict_Handler,
+		},
 		{
 			MethodName: "RegisterCmd",
 			Handler:    _Ext_RegisterCmd_Handler,
@@ -3572,6 +5829,18 @@ var _Ext_serviceDesc = grpc.ServiceDesc{
 			MethodName: "UnregisterAll",
 			Handler:    _Ext_UnregisterAll_Handler,
 		},
+		{
+			MethodName: "DeclarePermissions",
+			Handler:    _Ext_DeclarePermissions_Handler,
+		},
+		{
+			MethodName: "DeclareSettings",
+			Handler:    _Ext_DeclareSettings_Handler,
+		},
+		{
+			MethodName: "SettingsSchema",
+			Handler:    _Ext_SettingsSchema_Handler,
+		},
 		{
 			MethodName: "NetworkInformation",
 			Handler:    _Ext_NetworkInformation_Handler,
@@ -3612,6 +5881,14 @@ var _Ext_serviceDesc = grpc.ServiceDesc{
 			MethodName: "StateIsOn",
 			Handler:    _Ext_StateIsOn_Handler,
 		},
+		{
+			MethodName: "StateFindUsers",
+			Handler:    _Ext_StateFindUsers_Handler,
+		},
+		{
+			MethodName: "StateFindUsersByRealname",
+			Handler:    _Ext_StateFindUsersByRealname_Handler,
+		},
 		{
 			MethodName: "StoreAuthUser",
 			Handler:    _Ext_StoreAuthUser_Handler,
@@ -3668,6 +5945,38 @@ var _Ext_serviceDesc = grpc.ServiceDesc{
 			MethodName: "StoreLogoutByUser",
 			Handler:    _Ext_StoreLogoutByUser_Handler,
 		},
+		{
+			MethodName: "StoreCacheStats",
+			Handler:    _Ext_StoreCacheStats_Handler,
+		},
+		{
+			MethodName: "StoreLockouts",
+			Handler:    _Ext_StoreLockouts_Handler,
+		},
+		{
+			MethodName: "StoreClearLockouts",
+			Handler:    _Ext_StoreClearLockouts_Handler,
+		},
+		{
+			MethodName: "StoreBackup",
+			Handler:    _Ext_StoreBackup_Handler,
+		},
+		{
+			MethodName: "StoreAudit",
+			Handler:    _Ext_StoreAudit_Handler,
+		},
+		{
+			MethodName: "StoreRoles",
+			Handler:    _Ext_StoreRoles_Handler,
+		},
+		{
+			MethodName: "StorePutRole",
+			Handler:    _Ext_StorePutRole_Handler,
+		},
+		{
+			MethodName: "StoreDeleteRole",
+			Handler:    _Ext_StoreDeleteRole_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -3680,6 +5989,11 @@ var _Ext_serviceDesc = grpc.ServiceDesc{
 			Handler:       _Ext_Commands_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "StoreChanges",
+			Handler:       _Ext_StoreChanges_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "ultimateq.proto",
 }
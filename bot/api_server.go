@@ -13,6 +13,7 @@ import (
 	"github.com/aarondl/ultimateq/api"
 	"github.com/aarondl/ultimateq/data"
 	"github.com/aarondl/ultimateq/dispatch/cmd"
+	"github.com/aarondl/ultimateq/irc"
 	"github.com/aarondl/ultimateq/registrar"
 
 	"github.com/pkg/errors"
@@ -21,23 +22,43 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
 const (
 	broadcastTimeout = 500 * time.Millisecond
+	// preVerdictTimeout is how long the dispatch of an event waits on the
+	// verdict of a remote pre handler.
+	preVerdictTimeout = 2 * time.Second
 
 	grpcClientPingMinTime = 2 * time.Minute
+
+	// maxSearchPageSize is the most results a search will return at once.
+	maxSearchPageSize = 100
+
+	// changeBuffer is how many store changes can wait for a subscriber.
+	changeBuffer = 64
 )
 
 // apiServer provides a grpc api around a bot
 type apiServer struct {
 	bot *Bot
 
-	mut       sync.RWMutex
-	proxy     *registrar.Proxy
-	nextSubID uint64
-	subs      map[string]map[uint64]*sub
+	mut        sync.RWMutex
+	proxy      *registrar.Proxy
+	nextSubID  uint64
+	subs       map[string]map[uint64]*sub
+	changeSubs map[uint64]*changeSub
+
+	nextVerdict uint64
+	verdicts    map[uint64]*verdict
+}
+
+// verdict waits on a remote pre handler to answer an event.
+type verdict struct {
+	ext  string
+	stop chan bool
 }
 
 type sub struct {
@@ -48,6 +69,15 @@ type sub struct {
 	commandChan chan *api.CmdEventResponse
 }
 
+// changeSub is a subscription to the store's changes.
+type changeSub struct {
+	subID uint64
+	ext   string
+	kinds []data.ChangeKind
+
+	changes chan *api.StoreChange
+}
+
 var _ api.ExtServer = &apiServer{}
 
 // NewAPIServer creates an api server
@@ -56,9 +86,12 @@ func NewAPIServer(b *Bot) *apiServer {
 		bot:   b,
 		proxy: registrar.NewProxy(b),
 
-		nextSubID: 1,
-		subs:      make(map[string]map[uint64]*sub),
+		nextSubID:  1,
+		subs:       make(map[string]map[uint64]*sub),
+		changeSubs: make(map[uint64]*changeSub),
+		verdicts:   make(map[uint64]*verdict),
 	}
+	b.listenChanges(server.broadcastChange)
 
 	return server
 }
@@ -223,6 +256,39 @@ func (a *apiServer) broadcastEvent(ext string, r *api.IRCEventResponse) bool {
 	return a.broadcast(ext, r, nil)
 }
 
+// preEvent sends an event to a remote pre handler and waits for its verdict.
+// An event that isn't answered in time isn't consumed.
+func (a *apiServer) preEvent(ext string, r *api.IRCEventResponse) (stop, sent bool) {
+	v := &verdict{ext: ext, stop: make(chan bool, 1)}
+
+	a.mut.Lock()
+	a.nextVerdict++
+	r.Verdict = a.nextVerdict
+	a.verdicts[r.Verdict] = v
+	a.mut.Unlock()
+
+	defer func() {
+		a.mut.Lock()
+		delete(a.verdicts, r.Verdict)
+		a.mut.Unlock()
+	}()
+
+	if !a.broadcast(ext, r, nil) {
+		return false, false
+	}
+
+	timer := time.NewTimer(preVerdictTimeout)
+	defer timer.Stop()
+
+	select {
+	case stop = <-v.stop:
+	case <-timer.C:
+		a.bot.Logger.Debug("timeout waiting for verdict", "ext", ext, "id", r.Id, "verdict", r.Verdict)
+	}
+
+	return stop, true
+}
+
 func (a *apiServer) broadcastCmd(ext string, r *api.CmdEventResponse) bool {
 	return a.broadcast(ext, nil, r)
 }
@@ -304,6 +370,49 @@ func (a *apiServer) broadcast(ext string, rEvent *api.IRCEventResponse, rCmd *ap
 	return sent
 }
 
+// broadcastChange sends a change made to the store to the subscribers that
+// want it.
+func (a *apiServer) broadcastChange(change data.StoreChange) {
+	var subs []*changeSub
+
+	a.mut.RLock()
+	for _, s := range a.changeSubs {
+		want := len(s.kinds) == 0
+		for _, k := range s.kinds {
+			if k == change.Kind {
+				want = true
+				break
+			}
+		}
+		if want {
+			subs = append(subs, s)
+		}
+	}
+	a.mut.RUnlock()
+
+	if len(subs) == 0 {
+		return
+	}
+
+	r := change.ToProto()
+	timer := time.NewTimer(broadcastTimeout)
+	for _, s := range subs {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(broadcastTimeout)
+
+		select {
+		case s.changes <- r:
+		case <-timer.C:
+			a.bot.Logger.Debug("timeout to change subscriber", "ext", s.ext, "subid", s.subID)
+		}
+	}
+}
+
 func (a *apiServer) makePipe(ext string) *pipeHandler {
 	return &pipeHandler{
 		logger: a.bot.Logger.New("ext", ext),
@@ -330,6 +439,39 @@ func (a *apiServer) getStore() (*data.Store, error) {
 	return store, nil
 }
 
+// verifiedExt is the common name of the verified client certificate the
+// caller presented.
+func verifiedExt(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 ||
+		len(info.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return info.State.VerifiedChains[0][0].Subject.CommonName, true
+}
+
+// storeAdmin returns the name of the calling extension if it presented a
+// verified client certificate whose common name is an extension configured
+// with storeadmin, and a PermissionDenied error otherwise.
+func (a *apiServer) storeAdmin(ctx context.Context) (string, error) {
+	name, ok := verifiedExt(ctx)
+	if !ok {
+		return "", status.Error(codes.PermissionDenied, "a verified client certificate is required")
+	}
+
+	if ext := a.bot.conf.Ext(name); ext != nil {
+		if admin, _ := ext.StoreAdmin(); admin {
+			return name, nil
+		}
+	}
+
+	return "", status.Errorf(codes.PermissionDenied, "extension (%s) is not a storeadmin", name)
+}
+
 func (a *apiServer) unregEvent(ext string, id uint64) {
 	a.bot.Logger.Debug("unregistering event", "ext", ext, "id", id)
 	a.mut.Lock()
@@ -414,6 +556,53 @@ func (a *apiServer) Commands(in *api.SubscriptionRequest, stream api.Ext_Command
 	return nil
 }
 
+func (a *apiServer) StoreChanges(in *api.StoreChangesRequest, stream api.Ext_StoreChangesServer) error {
+	if _, err := a.getStore(); err != nil {
+		return err
+	}
+
+	kinds := make([]data.ChangeKind, len(in.Kinds))
+	for i, k := range in.Kinds {
+		var ok bool
+		if kinds[i], ok = data.ParseChangeKind(k); !ok {
+			return status.Errorf(codes.InvalidArgument, "unknown change kind: %q", k)
+		}
+	}
+
+	a.mut.Lock()
+	s := &changeSub{
+		subID:   a.nextSubID,
+		ext:     in.Ext,
+		kinds:   kinds,
+		changes: make(chan *api.StoreChange, changeBuffer),
+	}
+	a.nextSubID++
+	a.changeSubs[s.subID] = s
+	a.mut.Unlock()
+
+	a.bot.Logger.Debug("change sub", "ext", in.Ext, "subid", s.subID)
+
+Loop:
+	for {
+		select {
+		case change := <-s.changes:
+			if err := stream.Send(change); err != nil {
+				a.bot.Logger.Error("grpc change send err", "err", err, "subid", s.subID)
+				break Loop
+			}
+		case <-stream.Context().Done():
+			break Loop
+		}
+	}
+
+	a.mut.Lock()
+	delete(a.changeSubs, s.subID)
+	a.mut.Unlock()
+
+	a.bot.Logger.Debug("change sub closed", "ext", in.Ext, "subid", s.subID)
+	return nil
+}
+
 func (a *apiServer) Write(ctx context.Context, in *api.WriteRequest) (*api.Empty, error) {
 	net := a.bot.NetworkWriter(in.Net)
 	if net == nil {
@@ -437,14 +626,37 @@ func (a *apiServer) Register(ctx context.Context, in *api.RegisterRequest) (*api
 	pipe := a.makePipe(in.Ext)
 
 	proxy := a.proxy.Get(in.Ext)
-	id := proxy.Register(in.Network, in.Channel, in.Event, pipe)
+	var id uint64
+	if in.Pre {
+		id = proxy.RegisterPre(in.Network, in.Channel, in.Event, int(in.Priority), pipe)
+	} else {
+		id = proxy.RegisterPriority(in.Network, in.Channel, in.Event, int(in.Priority), pipe)
+	}
 	pipe.setEventID(id)
 
-	a.bot.Logger.Info("remote event register", "ext", in.Ext, "net", in.Network, "chan", in.Channel, "ev", in.Event, "id", id)
+	a.bot.Logger.Info("remote event register", "ext", in.Ext, "net", in.Network, "chan", in.Channel, "ev", in.Event, "priority", in.Priority, "pre", in.Pre, "id", id)
 
 	return &api.RegisterResponse{Id: id}, nil
 }
 
+func (a *apiServer) PreVerdict(ctx context.Context, in *api.PreVerdictRequest) (*api.Empty, error) {
+	a.mut.RLock()
+	v, ok := a.verdicts[in.Verdict]
+	a.mut.RUnlock()
+
+	if !ok || v.ext != in.Ext {
+		return nil, status.Errorf(codes.NotFound, "verdict not awaited")
+	}
+
+	select {
+	case v.stop <- in.Stop:
+	default:
+		return nil, status.Errorf(codes.AlreadyExists, "verdict already given")
+	}
+
+	return &api.Empty{}, nil
+}
+
 func (a *apiServer) RegisterCmd(ctx context.Context, in *api.RegisterCmdRequest) (*api.RegisterResponse, error) {
 	a.mut.Lock()
 	defer a.mut.Unlock()
@@ -480,6 +692,7 @@ func (a *apiServer) RegisterCmd(ctx context.Context, in *api.RegisterCmdRequest)
 	if err != nil {
 		return nil, err
 	}
+	command.ReqPerms = in.Cmd.ReqPerms
 
 	proxy := a.proxy.Get(in.Ext)
 	id, err := proxy.RegisterCmd(in.Network, in.Channel, command)
@@ -493,6 +706,49 @@ func (a *apiServer) RegisterCmd(ctx context.Context, in *api.RegisterCmdRequest)
 	return &api.RegisterResponse{Id: id}, nil
 }
 
+func (a *apiServer) DeclarePermissions(ctx context.Context, in *api.DeclarePermissionsRequest) (*api.Empty, error) {
+	perms := make([]data.Permission, len(in.Permissions))
+	for i, p := range in.Permissions {
+		perms[i] = data.Permission{Name: p.Name, Desc: p.Desc}
+	}
+
+	if err := data.DeclarePermissions(in.Ext, perms...); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	a.bot.Logger.Info("remote permissions declare", "ext", in.Ext, "perms", len(perms))
+	return nil, nil
+}
+
+func (a *apiServer) DeclareSettings(ctx context.Context, in *api.DeclareSettingsRequest) (*api.Empty, error) {
+	settings := make([]data.Setting, len(in.Settings))
+	for i, s := range in.Settings {
+		if !settings[i].FromProto(s) {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"setting %q has an unknown type: %q", s.Name, s.Type)
+		}
+	}
+
+	if err := data.DeclareSettings(in.Ext, settings...); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	a.bot.Logger.Info("remote settings declare", "ext", in.Ext, "settings", len(settings))
+	return nil, nil
+}
+
+func (a *apiServer) SettingsSchema(ctx context.Context, in *api.Empty) (*api.SettingsResponse, error) {
+	settings := data.Settings()
+
+	var resp api.SettingsResponse
+	resp.Settings = make([]*api.Setting, len(settings))
+	for i, s := range settings {
+		resp.Settings[i] = s.ToProto()
+	}
+
+	return &resp, nil
+}
+
 func (a *apiServer) Unregister(ctx context.Context, in *api.UnregisterRequest) (*api.Result, error) {
 	a.mut.Lock()
 	defer a.mut.Unlock()
@@ -537,6 +793,8 @@ func (a *apiServer) StateSelf(ctx context.Context, in *api.Query) (*api.SelfResp
 		Realname: self.User.Realname,
 	}
 	ret.Modes = self.ChannelModes.ToProto()
+	ret.UserModes = self.ModeString()
+	ret.Oper = self.IsOper()
 
 	return ret, nil
 }
@@ -622,10 +880,15 @@ func (a *apiServer) StateChannel(ctx context.Context, in *api.NetworkQuery) (*ap
 		return nil, status.Errorf(codes.NotFound, "channel not found")
 	}
 
+	ch := channel.ToProto()
 	return &api.ChannelResponse{
-		Name:  channel.Name,
-		Topic: channel.Topic,
-		Modes: channel.Modes.ToProto(),
+		Name:         ch.Name,
+		Topic:        ch.Topic,
+		Modes:        ch.Modes,
+		TopicSetter:  ch.TopicSetter,
+		TopicTime:    ch.TopicTime,
+		Created:      ch.Created,
+		TopicHistory: ch.TopicHistory,
 	}, nil
 }
 
@@ -665,6 +928,70 @@ func (a *apiServer) StateIsOn(ctx context.Context, in *api.ChannelQuery) (*api.R
 	return &api.Result{Ok: is}, nil
 }
 
+func (a *apiServer) StateFindUsers(ctx context.Context, in *api.UserSearchQuery) (*api.UserSearchResponse, error) {
+	if len(in.Query) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "mask must be supplied")
+	}
+
+	return a.findUsers(in, func(s *data.State) []data.User {
+		return s.FindUsersInChannels(irc.Mask(in.Query), in.Channels...)
+	})
+}
+
+func (a *apiServer) StateFindUsersByRealname(ctx context.Context, in *api.UserSearchQuery) (*api.UserSearchResponse, error) {
+	if len(in.Query) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "realname must be supplied")
+	}
+
+	return a.findUsers(in, func(s *data.State) []data.User {
+		users := s.FindUsersByRealname(in.Query)
+		if len(in.Channels) == 0 {
+			return users
+		}
+
+		filtered := users[:0]
+		for _, u := range users {
+			for _, ch := range in.Channels {
+				if s.IsOn(u.Nick(), ch) {
+					filtered = append(filtered, u)
+					break
+				}
+			}
+		}
+		return filtered
+	})
+}
+
+// findUsers runs a search against one or all networks and pages the results.
+func (a *apiServer) findUsers(in *api.UserSearchQuery, find func(*data.State) []data.User) (*api.UserSearchResponse, error) {
+	if len(in.Net) > 0 {
+		if _, err := a.getState(in.Net); err != nil {
+			return nil, err
+		}
+	}
+
+	users := a.bot.findUsers(in.Net, find)
+
+	limit := int(in.Limit)
+	if limit <= 0 || limit > maxSearchPageSize {
+		limit = maxSearchPageSize
+	}
+	offset := int(in.Offset)
+	if offset < 0 {
+		offset = 0
+	}
+
+	ret := &api.UserSearchResponse{Total: int32(len(users))}
+	for i := offset; i < len(users) && i < offset+limit; i++ {
+		ret.Results = append(ret.Results, &api.UserSearchResponse_Result{
+			Net:  users[i].NetworkID,
+			User: users[i].ToProto(),
+		})
+	}
+
+	return ret, nil
+}
+
 func (a *apiServer) StoreAuthUser(ctx context.Context, in *api.AuthUserRequest) (*api.Result, error) {
 	store, err := a.getStore()
 	if err != nil {
@@ -696,6 +1023,12 @@ func (a *apiServer) StoreAuthedUser(ctx context.Context, in *api.NetworkQuery) (
 }
 
 func (a *apiServer) StoreUser(ctx context.Context, in *api.Query) (*api.StoredUser, error) {
+	if in.Secrets {
+		if _, err := a.storeAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	store, err := a.getStore()
 	if err != nil {
 		return nil, err
@@ -710,6 +1043,9 @@ func (a *apiServer) StoreUser(ctx context.Context, in *api.Query) (*api.StoredUs
 		return nil, status.Errorf(codes.NotFound, "user not found")
 	}
 
+	if in.Secrets {
+		return user.ToProtoSecrets(store)
+	}
 	return user.ToProto(), nil
 }
 
@@ -771,6 +1107,12 @@ func makeUsersResponse(users []*data.StoredUser) *api.StoredUsersResponse {
 }
 
 func (a *apiServer) StoreChannel(ctx context.Context, in *api.NetworkQuery) (*api.StoredChannel, error) {
+	if in.Secrets {
+		if _, err := a.storeAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	store, err := a.getStore()
 	if err != nil {
 		return nil, err
@@ -785,6 +1127,9 @@ func (a *apiServer) StoreChannel(ctx context.Context, in *api.NetworkQuery) (*ap
 		return nil, status.Errorf(codes.NotFound, "channel not found")
 	}
 
+	if in.Secrets {
+		return channel.ToProtoSecrets(store)
+	}
 	return channel.ToProto(), nil
 }
 
@@ -814,7 +1159,9 @@ func (a *apiServer) StoreChannels(ctx context.Context, in *api.Empty) (*api.Stor
 }
 
 func (a *apiServer) StorePutUser(ctx context.Context, in *api.StoredUser) (*api.Empty, error) {
-	return nil, status.Error(codes.PermissionDenied, "this endpoint is currently unavailable pending authentication implementation")
+	if _, err := a.storeAdmin(ctx); err != nil {
+		return nil, err
+	}
 
 	store, err := a.getStore()
 	if err != nil {
@@ -824,11 +1171,39 @@ func (a *apiServer) StorePutUser(ctx context.Context, in *api.StoredUser) (*api.
 	user := new(data.StoredUser)
 	user.FromProto(in)
 
-	return nil, store.SaveUser(user)
+	before, err := store.FindUser(user.Username)
+	if err != nil {
+		return nil, err
+	}
+	var saved data.JSONStorer
+	if before != nil {
+		saved = before.JSONStorer
+	}
+	// The swap fails if the user changed since it was read here, so the
+	// secrets kept are always the saved ones.
+	if err = putSecrets(store, &user.JSONStorer, saved, in.Secrets); err != nil {
+		return nil, err
+	}
+	err = store.CompareAndSwapUserAudit(user,
+		func(before, after *data.StoredUser) []data.AuditEntry {
+			entry := a.auditEntry(ctx, "StorePutUser", after.Username)
+			entries := data.AuditAccessChanges(entry, before, after)
+			if before == nil || len(entries) == 0 {
+				entries = append([]data.AuditEntry{entry}, entries...)
+			}
+			return entries
+		})
+	if err != nil {
+		return nil, revisionError(err)
+	}
+
+	return nil, nil
 }
 
 func (a *apiServer) StorePutChannel(ctx context.Context, in *api.StoredChannel) (*api.Empty, error) {
-	return nil, status.Error(codes.PermissionDenied, "this endpoint is currently unavailable pending authentication implementation")
+	if _, err := a.storeAdmin(ctx); err != nil {
+		return nil, err
+	}
 
 	store, err := a.getStore()
 	if err != nil {
@@ -838,18 +1213,72 @@ func (a *apiServer) StorePutChannel(ctx context.Context, in *api.StoredChannel)
 	channel := new(data.StoredChannel)
 	channel.FromProto(in)
 
-	return nil, store.SaveChannel(channel)
+	before, err := store.FindChannel(channel.NetID, channel.Name)
+	if err != nil {
+		return nil, err
+	}
+	var saved data.JSONStorer
+	if before != nil {
+		saved = before.JSONStorer
+	}
+	if err = putSecrets(store, &channel.JSONStorer, saved, in.Secrets); err != nil {
+		return nil, err
+	}
+
+	entry := a.auditEntry(ctx, "StorePutChannel", channel.Name)
+	entry.Network, entry.Channel = channel.NetID, channel.Name
+	if err = store.CompareAndSwapChannel(channel, entry); err != nil {
+		return nil, revisionError(err)
+	}
+	return nil, nil
+}
+
+// putSecrets keeps the saved secrets of a record rebuilt from a put and
+// encrypts the secrets the put has.
+func putSecrets(store *data.Store, js *data.JSONStorer, saved data.JSONStorer,
+	secrets map[string]string) error {
+
+	if *js == nil {
+		*js = make(data.JSONStorer)
+	}
+	js.CopySecrets(saved)
+	for k, v := range secrets {
+		if err := store.PutSecret(*js, k, v); err != nil {
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+	}
+	return nil
+}
+
+// revisionError tells clients to read a record again when their put lost a
+// race with another change.
+func revisionError(err error) error {
+	if err == data.ErrRevisionMismatch {
+		return status.Error(codes.Aborted,
+			"the record was changed since it was read, read it and try again")
+	}
+	return err
 }
 
 func (a *apiServer) StoreDeleteUser(ctx context.Context, in *api.Query) (*api.Empty, error) {
-	return nil, status.Error(codes.PermissionDenied, "this endpoint is currently unavailable pending authentication implementation")
+	if _, err := a.storeAdmin(ctx); err != nil {
+		return nil, err
+	}
 
 	store, err := a.getStore()
 	if err != nil {
 		return nil, err
 	}
 
-	ok, err := store.RemoveUser(in.Query)
+	ok, err := store.RemoveUserAudit(in.Query,
+		func(before *data.StoredUser) []data.AuditEntry {
+			entry := a.auditEntry(ctx, "StoreDeleteUser", before.Username)
+			entries := data.AuditAccessChanges(entry, before, nil)
+			if len(entries) == 0 {
+				entries = append(entries, entry)
+			}
+			return entries
+		})
 	if err != nil {
 		return nil, err
 	} else if !ok {
@@ -860,18 +1289,22 @@ func (a *apiServer) StoreDeleteUser(ctx context.Context, in *api.Query) (*api.Em
 }
 
 func (a *apiServer) StoreDeleteChannel(ctx context.Context, in *api.NetworkQuery) (*api.Empty, error) {
-	return nil, status.Error(codes.PermissionDenied, "this endpoint is currently unavailable pending authentication implementation")
+	if _, err := a.storeAdmin(ctx); err != nil {
+		return nil, err
+	}
 
 	store, err := a.getStore()
 	if err != nil {
 		return nil, err
 	}
 
-	ok, err := store.RemoveChannel(in.Net, in.Query)
+	entry := a.auditEntry(ctx, "StoreDeleteChannel", in.Query)
+	entry.Network, entry.Channel = in.Net, in.Query
+	ok, err := store.RemoveChannel(in.Net, in.Query, entry)
 	if err != nil {
 		return nil, err
 	} else if !ok {
-		return nil, status.Errorf(codes.NotFound, "user not found")
+		return nil, status.Errorf(codes.NotFound, "channel not found")
 	}
 
 	return nil, nil
@@ -897,6 +1330,209 @@ func (a *apiServer) StoreLogoutByUser(ctx context.Context, in *api.Query) (*api.
 	return nil, nil
 }
 
+func (a *apiServer) StoreCacheStats(ctx context.Context, _ *api.Empty) (*api.StoreCacheStatsResponse, error) {
+	store, err := a.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := store.CacheStats()
+	return &api.StoreCacheStatsResponse{
+		Hits:      stats.Hits,
+		Misses:    stats.Misses,
+		Evictions: stats.Evictions,
+		Size:      int32(stats.Size),
+		Capacity:  int32(stats.Capacity),
+	}, nil
+}
+
+func (a *apiServer) StoreLockouts(ctx context.Context, _ *api.Empty) (*api.LockoutsResponse, error) {
+	if _, err := a.storeAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	store, err := a.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	lockouts := store.Lockouts()
+	resp := &api.LockoutsResponse{
+		Lockouts: make([]*api.Lockout, len(lockouts)),
+	}
+	for i, l := range lockouts {
+		resp.Lockouts[i] = &api.Lockout{
+			Username:    l.Username,
+			Network:     l.Network,
+			Host:        l.Host,
+			Failures:    int32(l.Failures),
+			LastFailure: l.LastFailure.Unix(),
+			Until:       l.Until.Unix(),
+			Locked:      l.Locked,
+		}
+	}
+
+	return resp, nil
+}
+
+func (a *apiServer) StoreClearLockouts(ctx context.Context, in *api.Query) (*api.CountResponse, error) {
+	if _, err := a.storeAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	store, err := a.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.CountResponse{Count: int32(store.ClearLockouts(in.Query))}, nil
+}
+
+func (a *apiServer) StoreBackup(ctx context.Context, in *api.Empty) (*api.BackupResponse, error) {
+	if _, err := a.storeAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	if _, err := a.getStore(); err != nil {
+		return nil, err
+	}
+
+	backup, err := a.bot.Backup()
+	switch {
+	case err == errNoBackupDir:
+		return nil, status.Error(codes.FailedPrecondition, "no backupdir is configured")
+	case err != nil:
+		return nil, err
+	}
+
+	return &api.BackupResponse{
+		Filename: backup.Filename,
+		Time:     backup.Time.Unix(),
+		Keys:     int32(backup.Keys),
+		Removed:  backup.Removed,
+	}, nil
+}
+
+func (a *apiServer) StoreAudit(ctx context.Context, in *api.AuditQuery) (*api.AuditResponse, error) {
+	if _, err := a.storeAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	store, err := a.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	query := data.AuditQuery{
+		Actor:   in.Actor,
+		Target:  in.Target,
+		User:    in.User,
+		Action:  in.Action,
+		Network: in.Net,
+		Channel: in.Channel,
+		Limit:   int(in.Limit),
+	}
+	if in.Since != 0 {
+		query.Since = time.Unix(in.Since, 0)
+	}
+
+	// Entries that can't be read are left out rather than failing the search.
+	entries, err := store.AuditLog(query)
+	if _, ok := err.(data.RecordErrors); err != nil && !ok {
+		return nil, err
+	}
+
+	resp := &api.AuditResponse{
+		Entries: make([]*api.AuditEntry, len(entries)),
+	}
+	for i, e := range entries {
+		resp.Entries[i] = &api.AuditEntry{
+			Time:    e.Time.Unix(),
+			Actor:   e.Actor,
+			Source:  e.Source,
+			Action:  e.Action,
+			Target:  e.Target,
+			Net:     e.Network,
+			Channel: e.Channel,
+			Before:  e.Before,
+			After:   e.After,
+		}
+	}
+
+	return resp, nil
+}
+
+func (a *apiServer) StoreRoles(ctx context.Context, in *api.Empty) (*api.RolesResponse, error) {
+	store, err := a.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	roles := store.Roles()
+	resp := &api.RolesResponse{
+		Roles: make([]*api.Role, len(roles)),
+	}
+	for i, r := range roles {
+		resp.Roles[i] = r.ToProto()
+	}
+
+	return resp, nil
+}
+
+func (a *apiServer) StorePutRole(ctx context.Context, in *api.Role) (*api.Empty, error) {
+	if _, err := a.storeAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	store, err := a.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	var role data.Role
+	role.FromProto(in)
+
+	entry := a.auditEntry(ctx, "StorePutRole", strings.ToLower(role.Name))
+	if before, ok := store.LookupRole(role.Name); ok {
+		entry.Before = before.Access.String()
+	}
+	entry.After = role.Access.String()
+	if err = store.SaveRole(role, entry); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return nil, nil
+}
+
+func (a *apiServer) StoreDeleteRole(ctx context.Context, in *api.Query) (*api.Result, error) {
+	if _, err := a.storeAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	store, err := a.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	before, _ := store.LookupRole(in.Query)
+	entry := a.auditEntry(ctx, "StoreDeleteRole", before.Name)
+	entry.Before = before.Access.String()
+
+	ok, err := store.RemoveRole(in.Query, entry)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &api.Result{Ok: ok}, nil
+}
+
+// auditEntry starts an audit entry for a change made through the api by an
+// extension authorized with storeAdmin, it's named by its certificate.
+func (a *apiServer) auditEntry(ctx context.Context, action, target string) data.AuditEntry {
+	ext, _ := verifiedExt(ctx)
+	return data.AuditEntry{Source: "ext:" + ext, Action: action, Target: target}
+}
+
 func (a *apiServer) NetworkInformation(ctx context.Context, in *api.NetworkInfoRequest) (*api.NetworkInfo, error) {
 	server := a.bot.getServer(in.Net)
 	if server == nil {
@@ -0,0 +1,329 @@
+package bot
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+
+	"github.com/aarondl/ultimateq/api"
+	"github.com/aarondl/ultimateq/data"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// extContext creates a context for a call from an extension that presented a
+// client certificate with the given common name.
+func extContext(name string, verified bool) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: name}}
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	if verified {
+		state.VerifiedChains = [][]*x509.Certificate{{cert}}
+	}
+
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr:     &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000},
+		AuthInfo: credentials.TLSInfo{State: state},
+	})
+}
+
+func checkCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+
+	if s, _ := status.FromError(err); s.Code() != code {
+		t.Errorf("expected code %v, got: %v", code, err)
+	}
+}
+
+func TestAPIServer_StoreAdmin(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	ts.b.conf.NewExt("admin").SetStoreAdmin(true)
+	ts.b.conf.NewExt("reader")
+	a := NewAPIServer(ts.b)
+
+	if name, err := a.storeAdmin(extContext("admin", true)); err != nil || name != "admin" {
+		t.Error("admin should be authorized:", name, err)
+	}
+
+	_, err := a.storeAdmin(context.Background())
+	checkCode(t, err, codes.PermissionDenied)
+	_, err = a.storeAdmin(extContext("admin", false))
+	checkCode(t, err, codes.PermissionDenied)
+	_, err = a.storeAdmin(extContext("reader", true))
+	checkCode(t, err, codes.PermissionDenied)
+	_, err = a.storeAdmin(extContext("unknown", true))
+	checkCode(t, err, codes.PermissionDenied)
+}
+
+func TestAPIServer_StoreLockouts(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	ts.b.conf.NewExt("admin").SetStoreAdmin(true)
+	a := NewAPIServer(ts.b)
+
+	if err := rspChk(ts, registerSuccessFirst, u1host, register, password, u1user); err != nil {
+		t.Fatal(err)
+	}
+	throttleUser(ts, u1user)
+
+	_, err := a.StoreLockouts(extContext("reader", true), &api.Empty{})
+	checkCode(t, err, codes.PermissionDenied)
+	_, err = a.StoreClearLockouts(extContext("reader", true), &api.Query{Query: u1user})
+	checkCode(t, err, codes.PermissionDenied)
+
+	admin := extContext("admin", true)
+	resp, err := a.StoreLockouts(admin, &api.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Lockouts) == 0 {
+		t.Error("expected lockouts to be listed")
+	}
+
+	count, err := a.StoreClearLockouts(admin, &api.Query{Query: u1user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count.Count == 0 {
+		t.Error("expected lockouts to be cleared")
+	}
+}
+
+func TestAPIServer_StoreBackup(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	ts.b.conf.NewExt("admin").SetStoreAdmin(true)
+	a := NewAPIServer(ts.b)
+
+	_, err := a.StoreBackup(context.Background(), &api.Empty{})
+	checkCode(t, err, codes.PermissionDenied)
+	_, err = a.StoreBackup(extContext("reader", true), &api.Empty{})
+	checkCode(t, err, codes.PermissionDenied)
+
+	_, err = a.StoreBackup(extContext("admin", true), &api.Empty{})
+	checkCode(t, err, codes.FailedPrecondition)
+}
+
+func TestAPIServer_StoreDeleteUser(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	ts.b.conf.NewExt("admin").SetStoreAdmin(true)
+	a := NewAPIServer(ts.b)
+
+	if err := rspChk(ts, registerSuccessFirst, u1host, register, password, u1user); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := a.StoreDeleteUser(extContext("reader", true), &api.Query{Query: u1user})
+	checkCode(t, err, codes.PermissionDenied)
+
+	admin := extContext("admin", true)
+	if _, err = a.StoreDeleteUser(admin, &api.Query{Query: u1user}); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := ts.store.FindUser(u1user); u != nil {
+		t.Error("Expected the user to be deleted.")
+	}
+	_, err = a.StoreDeleteUser(admin, &api.Query{Query: u1user})
+	checkCode(t, err, codes.NotFound)
+
+	resp, err := a.StoreAudit(admin, &api.AuditQuery{Action: "StoreDeleteUser"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Entries) == 0 {
+		t.Fatal("Expected the deletion to be audited.")
+	}
+	for _, e := range resp.Entries {
+		if e.Source != "ext:admin" || e.Target != u1user {
+			t.Errorf("Wrong entry: %#v", e)
+		}
+	}
+}
+
+func TestAPIServer_StoreRoles(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	ts.b.conf.NewExt("admin").SetStoreAdmin(true)
+	a := NewAPIServer(ts.b)
+
+	role := &api.Role{Name: "APIRole", Access: data.NewAccess(5, "v").ToProto()}
+	_, err := a.StorePutRole(extContext("reader", true), role)
+	checkCode(t, err, codes.PermissionDenied)
+	_, err = a.StoreDeleteRole(extContext("reader", true), &api.Query{Query: "apirole"})
+	checkCode(t, err, codes.PermissionDenied)
+
+	admin := extContext("admin", true)
+	if _, err = a.StorePutRole(admin, role); err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := ts.store.LookupRole("apirole"); !ok || r.Access.String() != "5 v" {
+		t.Error("Expected the role to be saved:", r)
+	}
+	_, err = a.StorePutRole(admin, &api.Role{Name: "bad name"})
+	checkCode(t, err, codes.InvalidArgument)
+
+	if res, err := a.StoreDeleteRole(admin, &api.Query{Query: "apirole"}); err != nil || !res.Ok {
+		t.Error("Expected the role to be deleted:", res, err)
+	}
+	if res, err := a.StoreDeleteRole(admin, &api.Query{Query: "apirole"}); err != nil || res.Ok {
+		t.Error("Expected nothing to be deleted:", res, err)
+	}
+
+	entries, err := ts.store.AuditLog(data.AuditQuery{Target: "apirole"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Action != "StorePutRole" ||
+		entries[0].After != "5 v" || entries[1].Action != "StoreDeleteRole" ||
+		entries[1].Source != "ext:admin" {
+
+		t.Errorf("Expected the put and delete to be audited: %#v", entries)
+	}
+}
+
+func TestAPIServer_StorePut(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	ts.b.conf.NewExt("admin").SetStoreAdmin(true)
+	a := NewAPIServer(ts.b)
+
+	if err := rspChk(ts, registerSuccessFirst, u1host, register, password, u1user); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := extContext("reader", true)
+	_, err := a.StorePutUser(reader, &api.StoredUser{Username: u1user})
+	checkCode(t, err, codes.PermissionDenied)
+	_, err = a.StorePutChannel(reader, &api.StoredChannel{Net: netID, Name: channel})
+	checkCode(t, err, codes.PermissionDenied)
+	_, err = a.StoreDeleteChannel(reader, &api.NetworkQuery{Net: netID, Query: channel})
+	checkCode(t, err, codes.PermissionDenied)
+
+	admin := extContext("admin", true)
+	user, err := a.StoreUser(admin, &api.Query{Query: u1user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := a.StoreUser(admin, &api.Query{Query: u1user})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user.Data = map[string]string{"key": "value"}
+	if _, err = a.StorePutUser(admin, user); err != nil {
+		t.Fatal(err)
+	}
+	stale.Data = map[string]string{"key": "stale"}
+	_, err = a.StorePutUser(admin, stale)
+	checkCode(t, err, codes.Aborted)
+
+	saved, err := a.StoreUser(admin, &api.Query{Query: u1user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Data["key"] != "value" || saved.Revision != stale.Revision+1 {
+		t.Errorf("Expected only the first put to be saved: %#v", saved)
+	}
+
+	ch := &api.StoredChannel{Net: netID, Name: channel}
+	if _, err = a.StorePutChannel(admin, ch); err != nil {
+		t.Fatal(err)
+	}
+	_, err = a.StorePutChannel(admin, &api.StoredChannel{Net: netID, Name: channel})
+	checkCode(t, err, codes.Aborted)
+
+	if _, err = a.StoreDeleteChannel(admin, &api.NetworkQuery{Net: netID, Query: channel}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = a.StoreDeleteChannel(admin, &api.NetworkQuery{Net: netID, Query: channel})
+	checkCode(t, err, codes.NotFound)
+
+	for _, action := range []string{"StorePutUser", "StorePutChannel", "StoreDeleteChannel"} {
+		entries, err := ts.store.AuditLog(data.AuditQuery{Action: action})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Source != "ext:admin" {
+			t.Errorf("Expected %v to be audited once: %#v", action, entries)
+		}
+	}
+}
+
+func TestAPIServer_StoreSecrets(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	ts.b.conf.NewExt("admin").SetStoreAdmin(true)
+	a := NewAPIServer(ts.b)
+
+	if err := rspChk(ts, registerSuccessFirst, u1host, register, password, u1user); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.store.SaveChannel(data.NewStoredChannel(netID, channel)); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := extContext("reader", true)
+	_, err := a.StoreUser(reader, &api.Query{Query: u1user, Secrets: true})
+	checkCode(t, err, codes.PermissionDenied)
+	_, err = a.StoreChannel(reader, &api.NetworkQuery{Net: netID, Query: channel, Secrets: true})
+	checkCode(t, err, codes.PermissionDenied)
+	_, err = a.StoreUser(context.Background(), &api.Query{Query: u1user, Secrets: true})
+	checkCode(t, err, codes.PermissionDenied)
+
+	if _, err = a.StoreUser(reader, &api.Query{Query: u1user}); err != nil {
+		t.Error("Expected the user without secrets:", err)
+	}
+	if _, err = a.StoreChannel(reader, &api.NetworkQuery{Net: netID, Query: channel}); err != nil {
+		t.Error("Expected the channel without secrets:", err)
+	}
+
+	admin := extContext("admin", true)
+	if _, err = a.StoreUser(admin, &api.Query{Query: u1user, Secrets: true}); err != nil {
+		t.Error("Expected the admin to read secrets:", err)
+	}
+	_, err = a.StoreChannel(admin, &api.NetworkQuery{Net: netID, Query: channel, Secrets: true})
+	if err != nil {
+		t.Error("Expected the admin to read secrets:", err)
+	}
+}
+
+func TestAPIServer_StoreAudit(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	ts.b.conf.NewExt("admin").SetStoreAdmin(true)
+	a := NewAPIServer(ts.b)
+
+	_, err := a.StoreAudit(extContext("reader", true), &api.AuditQuery{})
+	checkCode(t, err, codes.PermissionDenied)
+
+	// The name a client claims is ignored in favor of its certificate.
+	forged := metadata.NewIncomingContext(extContext("admin", true),
+		metadata.Pairs("ext", "someone"))
+	entry := a.auditEntry(forged, "StorePutUser", u1user)
+	if err = ts.store.Audit(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := a.StoreAudit(forged, &api.AuditQuery{Action: "StorePutUser"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].Source != "ext:admin" {
+		t.Errorf("Wrong entries: %#v", resp.Entries)
+	}
+}
@@ -7,8 +7,12 @@ package bot
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"math"
 	"net"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,6 +29,9 @@ import (
 const (
 	// defaultReconnScale is how the config's ReconnTimeout is scaled.
 	defaultReconnScale = time.Second
+	// whoisDelay is the time between the WHOIS lookups sent to learn users'
+	// certificate fingerprints.
+	whoisDelay = 2 * time.Second
 
 	// errFmtReaderClosed is when a write fails due to a closed socket or
 	// a shutdown on the client.
@@ -42,8 +49,16 @@ const (
 	fmtDisconnected = "bot: %v disconnected"
 	// fmtReconnecting shows when the bot is reconnecting
 	fmtReconnecting = "bot: %v reconnecting in %v..."
+	// alertLockoutFmt is noticed to admins when an account is locked out.
+	alertLockoutFmt = "User [%v] was locked out after %v failed " +
+		"authentications, last from %v on %v. Locked until %v."
+	// expiredAccessFmt is noticed to users when their access expires.
+	expiredAccessFmt = "Your access (%v) %v has expired."
 )
 
+// accessReapInterval is how often expired access is revoked.
+var accessReapInterval = time.Minute
+
 var (
 	// errParsingIrcMessage is when the bot fails to parse a message
 	// during it's dispatch loop.
@@ -60,6 +75,10 @@ var (
 	// errServerKilledReconn occurs when the server is killed during a
 	// reconnection pause.
 	errServerKilledReconn = errors.New("bot: Server reconnection aborted")
+	// errNoStore occurs when the store is needed but it's disabled.
+	errNoStore = errors.New("bot: The store is disabled")
+	// errNoBackupDir occurs when a backup is taken without a backupdir.
+	errNoBackupDir = errors.New("bot: No backupdir is configured")
 )
 
 type (
@@ -85,6 +104,10 @@ type Bot struct {
 	conf    *config.Config
 	store   *data.Store
 
+	// reaperStop stops revoking expired access and taking backups when it's
+	// closed.
+	reaperStop chan struct{}
+
 	// Logging
 	log15.Logger
 
@@ -101,6 +124,10 @@ type Bot struct {
 	cmds         *dispatch.CommandDispatcher
 	coreCommands *coreCmds
 
+	// changeListeners are given every change made to the store.
+	protectChanges  sync.RWMutex
+	changeListeners []func(data.StoreChange)
+
 	// IoC and DI components mostly for testing.
 	attachHandlers bool
 	connProvider   ConnProvider
@@ -290,11 +317,8 @@ func (b *Bot) dispatch(srv *Server) (disconnect bool, err error) {
 			ircMsg.NetworkInfo = srv.netInfo
 
 			if srv.state != nil {
-				srv.state.Update(ircMsg)
-			}
-
-			if b.checkIgnored(ircMsg.Sender) {
-				continue
+				update := srv.state.Update(ircMsg)
+				b.updateSessions(srv, ircMsg, update)
 			}
 
 			b.dispatchMessage(srv, ircMsg)
@@ -309,19 +333,29 @@ func (b *Bot) dispatch(srv *Server) (disconnect bool, err error) {
 	return
 }
 
-// dispatch sends a message to both the bot's dispatcher and the given servers
-func (b *Bot) dispatchMessage(s *Server, ev *irc.Event) {
-	if b.coreCommands != nil {
-		// Handle core commands first and stop execution if a core command handled
-		// the request
-		if handled, err := b.coreCommands.commands.Dispatch(s.writer, ev, b); err != nil {
-			b.Logger.Error("cmd dispatch failed", "err", err)
-		} else if handled {
-			return
-		}
+// updateSessions keeps the store's sessions in line with the state. Once the
+// users of a channel are known the sessions on the network are reconciled
+// with the state, this is what confirms sessions restored after a restart.
+func (b *Bot) updateSessions(srv *Server, ev *irc.Event, update data.StateUpdate) {
+	store := b.Store()
+	if store == nil {
+		return
 	}
 
-	b.dispatcher.Dispatch(s.writer, ev)
+	store.Update(srv.networkID, update)
+	switch ev.Name {
+	case irc.RPL_ENDOFNAMES, irc.RPL_ENDOFWHO:
+		store.Reconcile(srv.networkID, srv.state)
+	}
+}
+
+// dispatch sends a message to both the bot's dispatcher and the given servers.
+// The core commands are pre handlers so if they or any other pre handler
+// consumed the message it's not dispatched as a command.
+func (b *Bot) dispatchMessage(s *Server, ev *irc.Event) {
+	if b.dispatcher.Dispatch(s.writer, ev) {
+		return
+	}
 	if _, err := b.cmds.Dispatch(s.writer, ev, b); err != nil {
 		b.Logger.Error("cmd dispatch failed", "err", err)
 	}
@@ -356,6 +390,10 @@ func (b *Bot) stopServer(srv *Server) (stopped bool) {
 
 // Close closes the store database.
 func (b *Bot) Close() error {
+	if b.reaperStop != nil {
+		close(b.reaperStop)
+		b.reaperStop = nil
+	}
 	if b.store != nil {
 		err := b.store.Close()
 		b.store = nil
@@ -379,11 +417,74 @@ func (b *Bot) Register(network, channel, event string,
 	return b.dispatcher.Register(network, channel, event, handler)
 }
 
+// RegisterPriority registers an event handler like Register with a priority,
+// the handlers of an event with a higher priority finish before those with a
+// lower one are started. Register's priority is 0.
+func (b *Bot) RegisterPriority(network, channel, event string, priority int,
+	handler dispatch.Handler) uint64 {
+
+	return b.dispatcher.RegisterPriority(network, channel, event, priority,
+		handler)
+}
+
+// RegisterPre registers a handler that's given an event before any other
+// handler or command and can consume it, see dispatch.PreHandler. The core
+// commands are pre handlers of priority 0 on PRIVMSG, use a higher priority
+// to see a message before them. It's unregistered with Unregister.
+func (b *Bot) RegisterPre(network, channel, event string, priority int,
+	handler dispatch.PreHandler) uint64 {
+
+	return b.dispatcher.RegisterPre(network, channel, event, priority, handler)
+}
+
 // Unregister an event handler from the bot.
 func (b *Bot) Unregister(id uint64) bool {
 	return b.dispatcher.Unregister(id)
 }
 
+// RegisterMiddleware registers middleware around the event handlers of the
+// specified network and channel, leave either blank to not filter on it.
+// Returns an identifier that can be used to unregister the middleware.
+func (b *Bot) RegisterMiddleware(network, channel string,
+	middleware dispatch.Middleware) uint64 {
+
+	return b.dispatcher.RegisterMiddleware(network, channel, middleware)
+}
+
+// UnregisterMiddleware from the bot.
+func (b *Bot) UnregisterMiddleware(id uint64) bool {
+	return b.dispatcher.UnregisterMiddleware(id)
+}
+
+// RegisterPreMiddleware registers middleware around the pre handlers of the
+// specified network and channel, leave either blank to not filter on it.
+// Returns an identifier that can be used to unregister the middleware.
+func (b *Bot) RegisterPreMiddleware(network, channel string,
+	middleware dispatch.PreMiddleware) uint64 {
+
+	return b.dispatcher.RegisterPreMiddleware(network, channel, middleware)
+}
+
+// UnregisterPreMiddleware from the bot.
+func (b *Bot) UnregisterPreMiddleware(id uint64) bool {
+	return b.dispatcher.UnregisterPreMiddleware(id)
+}
+
+// RegisterCmdMiddleware registers middleware around the dispatch of the
+// commands used on the specified network and channel, leave either blank to
+// not filter on it. The core commands don't use it. Returns an identifier
+// that can be used to unregister the middleware.
+func (b *Bot) RegisterCmdMiddleware(network, channel string,
+	middleware dispatch.CmdMiddleware) uint64 {
+
+	return b.cmds.RegisterMiddleware(network, channel, middleware)
+}
+
+// UnregisterCmdMiddleware from the bot.
+func (b *Bot) UnregisterCmdMiddleware(id uint64) bool {
+	return b.cmds.UnregisterMiddleware(id)
+}
+
 // RegisterGlobalCmd registers a command with the bot.
 // See cmds.Cmds.Register for in-depth documentation.
 func (b *Bot) RegisterGlobalCmd(command *cmd.Command) (uint64, error) {
@@ -398,6 +499,18 @@ func (b *Bot) RegisterCmd(network, channel string, command *cmd.Command) (uint64
 	return b.cmds.Register(network, channel, command)
 }
 
+// DeclarePermissions declares the named permissions an extension's commands
+// require so they can be granted, see data.DeclarePermissions.
+func (b *Bot) DeclarePermissions(ext string, perms ...data.Permission) error {
+	return data.DeclarePermissions(ext, perms...)
+}
+
+// DeclareSettings declares the channel settings an extension uses so they
+// can be changed with the set command, see data.DeclareSettings.
+func (b *Bot) DeclareSettings(ext string, settings ...data.Setting) error {
+	return data.DeclareSettings(ext, settings...)
+}
+
 // UnregisterCmd from the bot. All parameters can be blank except for
 // cmd. Leaving ext blank wipes out other extension's commands with the same
 // name.
@@ -416,6 +529,69 @@ func (b *Bot) State(networkID string) *data.State {
 	return s.state
 }
 
+// NetworkUser is a user found in the state of a specific network.
+type NetworkUser struct {
+	NetworkID string
+	data.User
+}
+
+// FindUsers searches the state of every network for users whose host matches
+// the mask. See data.State.FindUsers for the matching rules. The results are
+// ordered by network id and then nick.
+func (b *Bot) FindUsers(mask irc.Mask) []NetworkUser {
+	return b.findUsers("", func(s *data.State) []data.User {
+		return s.FindUsers(mask)
+	})
+}
+
+// FindUsersByRealname searches the state of every network for users whose
+// realname matches the glob. The results are ordered by network id and then
+// nick.
+func (b *Bot) FindUsersByRealname(glob string) []NetworkUser {
+	return b.findUsers("", func(s *data.State) []data.User {
+		return s.FindUsersByRealname(glob)
+	})
+}
+
+// FindUsersInChannels searches the state of every network for users whose
+// host matches the mask and are on at least one of the channels. The results
+// are ordered by network id and then nick.
+func (b *Bot) FindUsersInChannels(mask irc.Mask,
+	channels ...string) []NetworkUser {
+
+	return b.findUsers("", func(s *data.State) []data.User {
+		return s.FindUsersInChannels(mask, channels...)
+	})
+}
+
+// findUsers runs find against the state of the network given, or every
+// network if networkID is empty, and collects the results.
+func (b *Bot) findUsers(networkID string,
+	find func(*data.State) []data.User) []NetworkUser {
+
+	b.protectServers.RLock()
+	var netIDs []string
+	states := make(map[string]*data.State)
+	for id, srv := range b.servers {
+		if srv.state == nil || (len(networkID) > 0 && id != networkID) {
+			continue
+		}
+		netIDs = append(netIDs, id)
+		states[id] = srv.state
+	}
+	b.protectServers.RUnlock()
+
+	sort.Strings(netIDs)
+
+	var ret []NetworkUser
+	for _, id := range netIDs {
+		for _, u := range find(states[id]) {
+			ret = append(ret, NetworkUser{NetworkID: id, User: u})
+		}
+	}
+	return ret
+}
+
 // Store returns the store for the bot. Returns nil if store is disabled.
 func (b *Bot) Store() *data.Store {
 	return b.store
@@ -486,9 +662,45 @@ func createBot(conf *config.Config, connProv ConnProvider,
 
 	if makeStore {
 		sfile, _ := conf.StoreFile()
-		if err = b.createStore(sfile); err != nil {
+		sbackend, _ := conf.StoreBackend()
+		if err = b.createStore(sbackend, sfile); err != nil {
+			return nil, err
+		}
+		b.logMigration(b.store.Migration())
+		if err = b.applyStoreConfig(conf); err != nil {
+			return nil, err
+		}
+
+		cacheSize, _ := conf.StoreCacheSize()
+		cacheTTL, _ := conf.StoreCacheTTL()
+		b.store.SetCacheLimits(int(cacheSize),
+			time.Duration(cacheTTL)*time.Second)
+
+		lifetime, _ := conf.SessionLifetime()
+		b.store.SetSessionLifetime(time.Duration(lifetime) * time.Second)
+		b.store.SetLockoutHandler(b.alertLockout)
+		b.store.SetExpiryHandler(b.expiredAccess)
+		b.store.SetChangeHandler(b.storeChanged)
+
+		auditAge, _ := conf.AuditMaxAge()
+		auditEntries, _ := conf.AuditMaxEntries()
+		err = b.store.SetAuditRetention(time.Duration(auditAge)*time.Second,
+			int(auditEntries))
+		if err != nil {
 			return nil, err
 		}
+
+		backupAge, _ := conf.BackupMaxAge()
+		backupKeep, _ := conf.BackupKeep()
+		b.store.SetBackupRetention(time.Duration(backupAge)*time.Second,
+			int(backupKeep))
+
+		b.reaperStop = make(chan struct{})
+		go b.reapAccess(b.store, b.reaperStop)
+		_, hasDir := conf.BackupDir()
+		if interval, _ := conf.BackupInterval(); hasDir && interval > 0 {
+			go b.backupStore(time.Duration(interval)*time.Second, b.reaperStop)
+		}
 	}
 
 	for _, net := range networks {
@@ -541,7 +753,11 @@ func (b *Bot) createServer(netID string, conf *config.Config) (*Server, error) {
 	}
 
 	if b.attachHandlers {
-		s.handler = &coreHandler{bot: b, untilJoinScale: time.Second}
+		s.handler = &coreHandler{
+			bot:            b,
+			untilJoinScale: time.Second,
+			whoisDelay:     whoisDelay,
+		}
 		s.handlerID = b.dispatcher.Register(netID, "", irc.RAW, s.handler)
 	}
 
@@ -555,18 +771,308 @@ func (b *Bot) createDispatching(channels ...string) {
 	b.dispatchCore = dispatch.NewCore(b.Logger)
 	b.dispatcher = dispatch.NewDispatcher(b.dispatchCore)
 	b.cmds = dispatch.NewCommandDispatcher(b.mkPrefixFetcher(), b.dispatchCore)
+
+	if ignores, _ := b.conf.Ignores(); len(ignores) != 0 {
+		b.dispatcher.RegisterMiddleware("", "", dispatch.Ignore(ignores...))
+		b.dispatcher.RegisterPreMiddleware("", "", dispatch.PreIgnore(ignores...))
+		b.cmds.RegisterMiddleware("", "", dispatch.CmdIgnore(ignores...))
+	}
 }
 
-// createStore creates a store from a filename.
-func (b *Bot) createStore(filename string) (err error) {
+// createStore creates a store from a backend name and filename.
+func (b *Bot) createStore(backend, filename string) (err error) {
 	if b.storeProvider == nil {
-		b.store, err = data.NewStore(data.MakeFileStoreProvider(filename))
+		var prov data.DbProvider
+		if prov, err = data.MakeStoreProvider(backend, filename); err != nil {
+			return err
+		}
+		b.store, err = data.NewStore(prov)
 	} else {
 		b.store, err = b.storeProvider(filename)
 	}
 	return
 }
 
+// applyStoreConfig gives the store the settings it takes from the config,
+// it's done when the bot is made and again on each rehash.
+func (b *Bot) applyStoreConfig(conf *config.Config) error {
+	if b.store == nil {
+		return nil
+	}
+
+	hasherName, _ := conf.PasswordHasher()
+	cost, _ := conf.PasswordCost()
+	hasher, err := data.NewPasswordHasher(hasherName, int(cost))
+	if err != nil {
+		return err
+	}
+	b.store.SetPasswordHasher(hasher)
+	minLength, _ := conf.PasswordMinLength()
+	b.store.SetPasswordMinLength(int(minLength))
+	if err = setSecretKey(b.store, conf); err != nil {
+		return err
+	}
+
+	b.store.SetConfigRoles(configRoles(conf.Roles()))
+	for _, net := range conf.Networks() {
+		noGlobal, _ := conf.Network(net).NoGlobalAccess()
+		b.store.SetGlobalInheritance(net, !noGlobal)
+	}
+	return nil
+}
+
+// configRoles converts the config's roles to the store's, levels above the
+// highest a user can have are given the highest.
+func configRoles(roles map[string]config.Role) []data.Role {
+	rets := make([]data.Role, 0, len(roles))
+	for name, role := range roles {
+		level := role.Level
+		if level > math.MaxUint8 {
+			level = math.MaxUint8
+		}
+		access := data.NewAccess(uint8(level), role.Flags)
+		rets = append(rets, data.Role{Name: name, Access: *access})
+	}
+	return rets
+}
+
+// setSecretKey gives the store the keys its secrets are encrypted with.
+func setSecretKey(store *data.Store, conf *config.Config) error {
+	key, _ := conf.SecretKey()
+	old, _ := conf.OldSecretKeys()
+	return store.SetSecretKey(key, old...)
+}
+
+// logMigration logs what was done to bring the store up to date.
+func (b *Bot) logMigration(report *data.MigrationReport) {
+	if report == nil {
+		return
+	}
+
+	if len(report.Applied) > 0 {
+		b.Logger.Info("Migrated store", "from", report.From, "to", report.To,
+			"changes", len(report.Changes))
+	}
+	for _, failed := range report.Failed {
+		b.Logger.Error("Store record could not be migrated",
+			"key", failed.Key, "err", failed.Err)
+	}
+}
+
+// alertLockout logs a locked account and notices every user with the global
+// G flag about it. That's everyone authenticated with a password, and the
+// users in state that are identified to a linked services account or connect
+// with a known certificate.
+func (b *Bot) alertLockout(lockout data.Lockout) {
+	b.Logger.Warn("Account locked out", "user", lockout.Username,
+		"network", lockout.Network, "host", lockout.Host,
+		"failures", lockout.Failures, "until", lockout.Until)
+
+	store := b.Store()
+	if store == nil {
+		return
+	}
+
+	msg := fmt.Sprintf(alertLockoutFmt, lockout.Username, lockout.Failures,
+		lockout.Host, lockout.Network, lockout.Until.Format(time.RFC3339))
+
+	noticed := make(map[string]bool)
+	alert := func(network, host string, user *data.StoredUser) {
+		key := network + " " + irc.Nick(host)
+		if noticed[key] || user == nil || !user.HasFlags("", "", "G") {
+			return
+		}
+		if w := b.NetworkWriter(network); w != nil {
+			w.Notice(irc.Nick(host), msg)
+			noticed[key] = true
+		}
+	}
+
+	for _, sess := range store.Sessions("") {
+		alert(sess.Network, sess.Host, store.AuthedUser(sess.Network, sess.Host))
+	}
+
+	everyone := func(st *data.State) []data.User {
+		var users []data.User
+		st.EachUser(func(u data.User) bool {
+			users = append(users, u)
+			return false
+		})
+		return users
+	}
+	for _, u := range b.findUsers("", everyone) {
+		host := u.Host.String()
+		user := store.AuthedUser(u.NetworkID, host)
+		if user == nil {
+			user = store.AccountUser(u.NetworkID, host, u.Account)
+		}
+		if fp, ok := data.NormalizeCertFP(u.CertFP); user == nil && ok {
+			user, _ = store.UserByCert(fp)
+		}
+		alert(u.NetworkID, host, user)
+	}
+}
+
+// reapAccess revokes expired access every accessReapInterval until stop is
+// closed.
+func (b *Bot) reapAccess(store *data.Store, stop <-chan struct{}) {
+	ticker := time.NewTicker(accessReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := store.ExpireAccess(); err != nil {
+				b.Logger.Error("Could not revoke expired access", "err", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Backup takes a snapshot of the store into the configured backupdir.
+func (b *Bot) Backup() (data.Backup, error) {
+	store := b.Store()
+	if store == nil {
+		return data.Backup{}, errNoStore
+	}
+	dir, ok := b.conf.BackupDir()
+	if !ok || len(dir) == 0 {
+		return data.Backup{}, errNoBackupDir
+	}
+
+	backup, err := store.Backup(dir)
+	if err != nil {
+		return backup, err
+	}
+
+	b.Logger.Info("Store backed up", "file", backup.Filename,
+		"keys", backup.Keys, "removed", len(backup.Removed))
+	return backup, nil
+}
+
+// backupStore takes a backup every interval until stop is closed.
+func (b *Bot) backupStore(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := b.Backup(); err != nil {
+				b.Logger.Error("Could not back up the store", "err", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// expiredAccess logs access that has expired, and if expirynotice is set
+// notices the user about it on each network they're authenticated on.
+func (b *Bot) expiredAccess(expired data.ExpiredAccess) {
+	b.Logger.Info("Access expired", "user", expired.Username,
+		"network", expired.Network, "channel", expired.Channel,
+		"access", expired.Access)
+
+	if notice, _ := b.conf.ExpiryNotice(); !notice {
+		return
+	}
+	store := b.Store()
+	if store == nil {
+		return
+	}
+
+	var scope string
+	switch {
+	case len(expired.Channel) != 0:
+		scope = "on " + expired.Network + " " + expired.Channel
+	case len(expired.Network) != 0:
+		scope = "on " + expired.Network
+	default:
+		scope = "globally"
+	}
+
+	access := expired.Access
+	access.Expires = 0
+	msg := fmt.Sprintf(expiredAccessFmt, access, scope)
+
+	noticed := make(map[string]bool)
+	for _, sess := range store.Sessions("") {
+		key := sess.Network + " " + irc.Nick(sess.Host)
+		if noticed[key] || !strings.EqualFold(sess.Username, expired.Username) {
+			continue
+		}
+		if w := b.NetworkWriter(sess.Network); w != nil {
+			w.Notice(irc.Nick(sess.Host), msg)
+			noticed[key] = true
+		}
+	}
+}
+
+// storeChangeEvents are the pseudo events store changes are dispatched as.
+var storeChangeEvents = map[data.ChangeKind]string{
+	data.UserSaved:      irc.STORE_USER_SAVED,
+	data.UserRemoved:    irc.STORE_USER_REMOVED,
+	data.SessionOpened:  irc.STORE_SESSION_OPENED,
+	data.SessionClosed:  irc.STORE_SESSION_CLOSED,
+	data.ChannelSaved:   irc.STORE_CHANNEL_SAVED,
+	data.ChannelRemoved: irc.STORE_CHANNEL_REMOVED,
+}
+
+// storeChanged dispatches a change made to the store as a pseudo event and
+// gives it to the change listeners. Changes to users aren't on a network,
+// what handlers write for them is discarded.
+func (b *Bot) storeChanged(change data.StoreChange) {
+	ev := storeChangeEvent(change)
+
+	var w irc.Writer
+	if srv := b.getServer(change.Network); srv != nil {
+		w, ev.NetworkInfo = srv.writer, srv.netInfo
+	} else {
+		w, ev.NetworkInfo = irc.Helper{Writer: ioutil.Discard}, irc.NewNetworkInfo()
+	}
+	b.dispatcher.Dispatch(w, ev)
+
+	b.protectChanges.RLock()
+	defer b.protectChanges.RUnlock()
+	for _, fn := range b.changeListeners {
+		fn(change)
+	}
+}
+
+// listenChanges adds a function that's given every change made to the store
+// in the order they were made.
+func (b *Bot) listenChanges(fn func(data.StoreChange)) {
+	b.protectChanges.Lock()
+	defer b.protectChanges.Unlock()
+
+	b.changeListeners = append(b.changeListeners, fn)
+}
+
+// storeChangeEvent creates the pseudo event for a change to the store.
+func storeChangeEvent(change data.StoreChange) *irc.Event {
+	var sender string
+	var args []string
+	switch change.Kind {
+	case data.UserSaved, data.UserRemoved:
+		args = []string{change.Username}
+	case data.SessionOpened, data.SessionClosed:
+		sender, args = change.Host, []string{change.Username}
+	default:
+		args = []string{change.Channel}
+	}
+	if change.Kind == data.UserSaved || change.Kind == data.ChannelSaved {
+		args = append(args, strconv.FormatUint(change.Revision, 10))
+	}
+
+	ev := irc.NewEvent(change.Network, nil, storeChangeEvents[change.Kind],
+		sender, args...)
+	ev.Time = change.Time
+	return ev
+}
+
 func (b *Bot) initLocalExtensions() error {
 	for name, ext := range extensions {
 		b.Logger.Info("Initializing extension", "name", name)
@@ -599,23 +1105,6 @@ func (b *Bot) getServer(networkID string) *Server {
 	return nil
 }
 
-var ignoreSync sync.Once
-var ignoreList []string
-
-func (b *Bot) checkIgnored(sender string) bool {
-	ignoreSync.Do(func() {
-		ignoreList, _ = b.conf.Ignores()
-	})
-
-	for _, v := range ignoreList {
-		if strings.HasSuffix(sender, v) {
-			return true
-		}
-	}
-
-	return false
-}
-
 // mkPrefixFetcher creates a function that can fetch the prefix for a given
 // network or channel (or if both are omitted global).
 func (b *Bot) mkPrefixFetcher() func(network, channel string) rune {
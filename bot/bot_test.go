@@ -5,11 +5,15 @@ import (
 	"io/ioutil"
 	"log"
 	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aarondl/ultimateq/config"
 	"github.com/aarondl/ultimateq/data"
+	"github.com/aarondl/ultimateq/dispatch"
 	"github.com/aarondl/ultimateq/dispatch/cmd"
 	"github.com/aarondl/ultimateq/irc"
 	"github.com/aarondl/ultimateq/mocks"
@@ -221,6 +225,94 @@ func TestBot_Dispatching(t *testing.T) {
 	}
 }
 
+func TestBot_DispatchingPre(t *testing.T) {
+	t.Parallel()
+	conn := mocks.NewConn()
+	connProvider := func(srv string) (net.Conn, error) {
+		return conn, nil
+	}
+	b, _ := createBot(fakeConfig, connProvider, nil, devNull, false, false)
+
+	result := make(chan string, 2)
+	b.RegisterGlobal(irc.PRIVMSG, &testHandler{
+		func(_ irc.Writer, ev *irc.Event) {
+			result <- ev.Message()
+		},
+	})
+	cresult := make(chan string, 2)
+	_, err := b.RegisterGlobalCmd(cmd.New("a", "cmd", "b", &testCommand{
+		func(command string, _ irc.Writer, _ *cmd.Event) error {
+			cresult <- command
+			return nil
+		},
+	}, cmd.AnyKind, cmd.AnyScope))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := b.RegisterPre("", "", irc.PRIVMSG, 1, dispatch.PreHandlerFunc(
+		func(_ irc.Writer, ev *irc.Event) bool {
+			return strings.Contains(ev.Message(), "spam")
+		}))
+
+	end := b.Start()
+
+	spam := []byte("PRIVMSG bot :cmd spam\r\n")
+	msg := []byte("PRIVMSG bot :cmd\r\n")
+	go func() {
+		conn.Send(spam, len(spam), nil)
+		conn.Send(msg, len(msg), io.EOF)
+	}()
+
+	for range end {
+	}
+	b.dispatchCore.WaitForHandlers()
+
+	if len(result) != 1 || <-result != "cmd" {
+		t.Error("Expected the spam to be kept from the handler.")
+	}
+	if len(cresult) != 1 || <-cresult != "cmd" {
+		t.Error("Expected the spam to be kept from the command.")
+	}
+	if !b.Unregister(id) {
+		t.Error("Should have unregistered the pre handler.")
+	}
+}
+
+func TestBot_DispatchingPreIgnore(t *testing.T) {
+	t.Parallel()
+	conn := mocks.NewConn()
+	connProvider := func(srv string) (net.Conn, error) {
+		return conn, nil
+	}
+	conf := fakeConfig.Clone()
+	conf.SetIgnores([]string{"@ignored.host"})
+	b, _ := createBot(conf, connProvider, nil, devNull, false, false)
+
+	result := make(chan string, 2)
+	b.RegisterPre("", "", irc.PRIVMSG, 0, dispatch.PreHandlerFunc(
+		func(_ irc.Writer, ev *irc.Event) bool {
+			result <- ev.Message()
+			return false
+		}))
+
+	end := b.Start()
+
+	ignored := []byte(":a!b@ignored.host PRIVMSG bot :ignored\r\n")
+	msg := []byte(":a!b@other.host PRIVMSG bot :msg\r\n")
+	go func() {
+		conn.Send(ignored, len(ignored), nil)
+		conn.Send(msg, len(msg), io.EOF)
+	}()
+
+	for range end {
+	}
+	b.dispatchCore.WaitForHandlers()
+
+	if len(result) != 1 || <-result != "msg" {
+		t.Error("Expected the ignored sender to be kept from the pre handler.")
+	}
+}
+
 func TestBot_Dispatch_ConnectDisconnect(t *testing.T) {
 	t.Parallel()
 	conn := mocks.NewConn()
@@ -454,6 +546,87 @@ func TestBot_Store(t *testing.T) {
 	b.Close() // Nothing bad should happen
 }
 
+func TestBot_StoreChanges(t *testing.T) {
+	t.Parallel()
+	conf := fakeConfig.Clone()
+	conf.Network("").SetNoStore(false)
+	goodStoreProv := func(s string) (*data.Store, error) {
+		return data.NewStore(data.MemStoreProvider)
+	}
+	b, err := createBot(conf, nil, goodStoreProv, devNull, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	result := make(chan *irc.Event, 2)
+	b.RegisterGlobal(irc.STORE_USER_SAVED, &testHandler{
+		func(_ irc.Writer, ev *irc.Event) {
+			result <- ev
+		},
+	})
+	changes := make(chan data.StoreChange, 2)
+	b.listenChanges(func(c data.StoreChange) {
+		changes <- c
+	})
+
+	user, _ := data.NewStoredUser("user", "pass")
+	if err = b.store.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-result:
+		if len(ev.Args) != 2 || ev.Args[0] != "user" || ev.Args[1] != "1" {
+			t.Error("Wrong arguments:", ev.Args)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the change to be dispatched.")
+	}
+	select {
+	case c := <-changes:
+		if c.Kind != data.UserSaved || c.Username != "user" {
+			t.Error("Wrong change:", c)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the change to be given to the listener.")
+	}
+}
+
+func TestBot_Backup(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "botbackup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := fakeConfig.Clone()
+	conf.Network("").SetNoStore(false)
+	storeProv := func(s string) (*data.Store, error) {
+		return data.NewStore(data.MakeBoltStoreProvider(
+			filepath.Join(dir, "store.db")))
+	}
+	b, err := createBot(conf, nil, storeProv, devNull, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if _, err = b.Backup(); err != errNoBackupDir {
+		t.Error("Expected the backup to need a backupdir, got:", err)
+	}
+
+	conf.SetBackupDir(filepath.Join(dir, "backups"))
+	backup, err := b.Backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(backup.Filename); err != nil {
+		t.Error("Expected the backup to exist:", err)
+	}
+}
+
 func TestBot_Stop(t *testing.T) {
 	t.Parallel()
 	conn := mocks.NewConn()
@@ -501,6 +674,47 @@ func TestBot_Locker(t *testing.T) {
 	}
 }
 
+func TestBot_FindUsers(t *testing.T) {
+	t.Parallel()
+
+	b, err := createBot(fakeConfig, nil, nil, devNull, false, false)
+	if err != nil {
+		t.Fatal("Unexpected err:", err)
+	}
+
+	state := b.State(netID)
+	state.Update(irc.NewEvent(netID, netInfo, irc.RPL_WELCOME, "",
+		"me", "Welcome to me!user@host"))
+	state.Update(irc.NewEvent(netID, netInfo, irc.JOIN, "me!user@host",
+		"#chan"))
+	for _, nick := range []string{"nick2", "nick1"} {
+		state.Update(irc.NewEvent(netID, netInfo, irc.RPL_WHOREPLY, "",
+			"me", "#chan", "user", "host", "server", nick, "H",
+			"0 Real Name"))
+	}
+
+	found := b.FindUsers("nick*")
+	if exp, got := 2, len(found); exp != got {
+		t.Fatalf("Expected: %v, got: %v", exp, got)
+	}
+	if exp, got := netID, found[0].NetworkID; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if exp, got := "nick1", found[0].Nick(); exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	if found = b.FindUsersByRealname("real*"); len(found) != 2 {
+		t.Error("Expected 2 users, got:", found)
+	}
+	if found = b.FindUsersInChannels("nick*", "#chan"); len(found) != 2 {
+		t.Error("Expected 2 users, got:", found)
+	}
+	if found = b.FindUsersInChannels("*", "#other"); len(found) != 0 {
+		t.Error("Expected no users, got:", found)
+	}
+}
+
 func TestBot_GetEndpoint(t *testing.T) {
 	t.Parallel()
 	conn := mocks.NewConn()
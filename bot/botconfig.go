@@ -36,6 +36,10 @@ func (b *Bot) ReplaceConfig(newConfig *config.Config) bool {
 	if !newConfig.Validate() {
 		return false
 	}
+	if err := b.applyStoreConfig(newConfig); err != nil {
+		b.Logger.Error("Failed to apply the config to the store", "err", err)
+		return false
+	}
 
 	b.protectServers.Lock()
 	defer b.protectServers.Unlock() // LIFO
@@ -93,6 +97,9 @@ func (b *Bot) Rehash() error {
 	if !CheckConfig(conf) {
 		return errInvalidConfig
 	}
+	if err := b.applyStoreConfig(conf); err != nil {
+		return err
+	}
 	b.conf.Replace(conf)
 	return nil
 }
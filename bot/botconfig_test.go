@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/aarondl/ultimateq/config"
+	"github.com/aarondl/ultimateq/data"
 	"github.com/aarondl/ultimateq/irc"
 	"github.com/aarondl/ultimateq/mocks"
 	"gopkg.in/inconshreveable/log15.v2"
@@ -194,3 +195,69 @@ func TestBotConfig_ReplaceConfig(t *testing.T) {
 	for range end {
 	}
 }
+
+func TestBotConfig_ApplyStoreConfig(t *testing.T) {
+	store, err := data.NewStore(data.MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	b := &Bot{store: store}
+
+	user, err := data.NewStoredUser("user", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Grant("", "", 100)
+	user.GiveRole(netID, "", "voice")
+	if err = store.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := config.New()
+	conf.SetRole("voice", config.Role{Level: 5, Flags: "v"})
+	conf.NewNetwork(netID).SetNoGlobalAccess(true)
+	conf.SetPasswordMinLength(10)
+	conf.SetSecretKey("key")
+	if err = b.applyStoreConfig(conf); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.PutSecret(user.JSONStorer, "token", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	if !user.HasFlags(netID, "", "v") {
+		t.Error("Expected the config's roles to be applied.")
+	}
+	if user.HasLevel(netID, "", 100) {
+		t.Error("Expected global access not to apply on the network.")
+	}
+	if err = store.ValidatePassword("user", "shorter"); err == nil {
+		t.Error("Expected the minimum password length to be applied.")
+	}
+
+	// Rehashing to a config without them takes them away.
+	next := config.New()
+	next.NewNetwork(netID)
+	next.SetSecretKey("new").SetOldSecretKeys([]string{"key"})
+	if err = b.applyStoreConfig(next); err != nil {
+		t.Fatal(err)
+	}
+	if user.HasFlags(netID, "", "v") || !user.HasLevel(netID, "", 100) {
+		t.Error("Expected the new config to replace the old.")
+	}
+	if err = store.ValidatePassword("user", "shorter"); err != nil {
+		t.Error("Expected the minimum password length to be removed:", err)
+	}
+	if n, err := store.RotateSecrets(); err != nil || n != 1 {
+		t.Error("Expected the secret to be rotated to the new key:", n, err)
+	}
+
+	bad := config.New().SetPasswordHasher("bcrypt").SetPasswordCost(32)
+	if err = b.applyStoreConfig(bad); err == nil {
+		t.Error("Expected a bad password cost to fail.")
+	}
+}
@@ -2,11 +2,14 @@ package bot
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aarondl/ultimateq/data"
 	"github.com/aarondl/ultimateq/dispatch"
@@ -32,9 +35,15 @@ const (
 	masks     = `masks`
 	addmask   = `addmask`
 	delmask   = `delmask`
+	addcert   = `addcert`
+	delcert   = `delcert`
 
 	resetpasswd = `setpasswd`
 
+	link        = `link`
+	unlink      = `unlink`
+	linkMaskArg = `mask`
+
 	ggive      = `ggive`
 	sgive      = `sgive`
 	give       = `give`
@@ -42,6 +51,40 @@ const (
 	stake      = `stake`
 	take       = `take`
 	takeAllArg = `all`
+	giveForArg = `for`
+
+	roles     = `roles`
+	setrole   = `setrole`
+	delrole   = `delrole`
+	ggiverole = `ggiverole`
+	sgiverole = `sgiverole`
+	giverole  = `giverole`
+	gtakerole = `gtakerole`
+	stakerole = `stakerole`
+	takerole  = `takerole`
+
+	grant       = `grant`
+	revoke      = `revoke`
+	grantNetArg = `net`
+
+	deny   = `deny`
+	undeny = `undeny`
+
+	set   = `set`
+	get   = `get`
+	unset = `unset`
+
+	export = `export`
+	imprt  = `import`
+
+	lockouts = `lockouts`
+	unlock   = `unlock`
+
+	backup = `backup`
+
+	audit = `audit`
+	// auditShown is the most audit entries that are shown at once.
+	auditShown = 10
 
 	help = `help`
 
@@ -71,6 +114,8 @@ const (
 	logoutSuccess  = `Successfully logged out.`
 	accessDesc     = `Access retrieves the access for the user.`
 	accessSuccess  = `Access for [%v]: %v`
+	accessRoles    = `Roles for [%v]: %v`
+	accessPerms    = `Permissions for [%v]: %v`
 	deluserDesc    = `Deletes a user account from the bot.`
 	deluserSuccess = `Removed user [%v].`
 	deluserFailure = `User [%v] does not exist.`
@@ -92,19 +137,48 @@ const (
 		` user param to remove a mask to that user.`
 	delmaskSuccess = `Host [%v] removed successfully.`
 	delmaskFailure = `Host [%v] not found.`
+	addcertDesc    = `Adds a TLS client certificate fingerprint to the ` +
+		`current user, connecting with the certificate authenticates as the ` +
+		`user. Admins can add a user param to add it to that user.`
+	addcertSuccess = `Certificate [%v] added successfully.`
+	addcertFailure = `Certificate [%v] already exists.`
+	addcertInvalid = `Invalid certificate fingerprint, it must be a ` +
+		`SHA-256 fingerprint in hex. (given: %v)`
+	addcertTaken = `The certificate [%v] already belongs to [%v].`
+	delcertDesc  = `Deletes a TLS client certificate fingerprint from the ` +
+		`current user. Admins can add a user param to remove it from that user.`
+	delcertSuccess = `Certificate [%v] removed successfully.`
+	delcertFailure = `Certificate [%v] not found.`
+
+	linkDesc = `Links the services account you're identified to on this ` +
+		`network to your user, so you're authenticated without a password. ` +
+		`Add mask to also require your host to match one of your masks.`
+	linkSuccess     = `Linked services account [%v] to [%v].`
+	linkSuccessMask = `Linked services account [%v] to [%v], your host must ` +
+		`also match one of your masks.`
+	linkFailure      = `You are not identified to a services account.`
+	linkFailureTaken = `The services account [%v] is already linked to [%v].`
+	linkFailureArg   = `Invalid argument, leave empty or use mask. (given: %v)`
+	unlinkDesc       = `Unlinks the services account on this network from ` +
+		`your user.`
+	unlinkSuccess = `Unlinked services account [%v].`
+	unlinkFailure = `No services account is linked on this network.`
 
 	resetpasswdDesc          = `Resets a user's password.`
 	resetpasswdSuccess       = `Password reset successful.`
 	resetpasswdSuccessTarget = `Your password was reset by %v, it is now: %v`
 
 	ggiveDesc = `Gives global access to a user.` +
-		` Arguments can be numeric levels or flags.`
+		` Arguments can be numeric levels or flags, end them with for and ` +
+		`a duration like 12h or 7d to make the access expire.`
 	ggiveSuccess = `User [%v] now has: (%v) globally.`
 	sgiveDesc    = `Gives network access to a user.` +
-		` Arguments can be numeric levels or flags.`
+		` Arguments can be numeric levels or flags, end them with for and ` +
+		`a duration like 12h or 7d to make the access expire.`
 	sgiveSuccess = `User [%v] now has: (%v) network-wide.`
 	giveDesc     = `Gives channel access to a user.` +
-		` Arguments can be numeric levels or flags.`
+		` Arguments can be numeric levels or flags, end them with for and ` +
+		`a duration like 12h or 7d to make the access expire.`
 	giveSuccess = `User [%v] now has: (%v) on %v`
 	gtakeDesc   = `Takes global access from a user. If no arguments are ` +
 		`given, takes the level access, otherwise removes the given flags. ` +
@@ -119,12 +193,93 @@ const (
 	giveFailure = `Invalid arguments, must be numeric accesses from 1-255 or ` +
 		`flags in the range: A-Za-z.`
 	giveFailureHas = `User [%v](%v) already has: %v`
-	takeFailure    = `Invalid arguments, leave empty to delete level access, ` +
+	giveFailureFor = `Invalid duration, use a number followed by s, m, h, ` +
+		`d or w. (given: %v)`
+	giveFailureMixed = `User [%v] has (%v) there, access that expires ` +
+		`and permanent access can't be mixed. Take it first.`
+	takeFailure = `Invalid arguments, leave empty to delete level access, ` +
 		`specific flags to delete those flags, or the keyword all to delete ` +
 		`everything. (given: %v)`
 	takeFailureNo = `No action taken. User [%v](%v) has none of the given ` +
 		`access to remove.`
 
+	rolesDesc   = `Lists the roles that can be given to users.`
+	rolesNone   = `No roles.`
+	rolesHead   = `Showing %v roles:`
+	rolesLine   = `%v: %v`
+	rolesConfig = `%v: %v (config)`
+	setroleDesc = `Creates or changes a role. Arguments can be numeric ` +
+		`levels or flags.`
+	setroleSuccess = `Role [%v] now has: (%v)`
+	roleConfig     = `Role [%v] is defined in the config and can't be ` +
+		`changed.`
+	delroleDesc    = `Deletes a role, users keep it but it gives them nothing.`
+	delroleSuccess = `Deleted role [%v].`
+	delroleFailure = `No role named [%v].`
+
+	ggiveroleDesc = `Gives a role to a user globally.`
+	sgiveroleDesc = `Gives a role to a user network-wide.`
+	giveroleDesc  = `Gives a role to a user on a channel.`
+	gtakeroleDesc = `Takes a role from a user globally.`
+	stakeroleDesc = `Takes a role from a user network-wide.`
+	takeroleDesc  = `Takes a role from a user on a channel.`
+
+	giveroleSuccess    = `User [%v] now has the role [%v] %v.`
+	giveroleFailure    = `No role named [%v], see roles for a list.`
+	giveroleFailureHas = `User [%v] already has the role [%v] %v.`
+	takeroleSuccess    = `User [%v] no longer has the role [%v] %v.`
+	takeroleFailureNo  = `User [%v] does not have the role [%v] %v.`
+	scopeGlobal        = `globally`
+	scopeNetwork       = `network-wide`
+	scopeChannel       = `on %v`
+
+	grantDesc = `Grants a permission an extension declared, like ` +
+		`quotes.delete, quotes.* grants all of a namespace. The grant is ` +
+		`global unless net or a channel is given.`
+	revokeDesc = `Revokes a permission from a user, give the same net or ` +
+		`channel it was granted in.`
+	grantSuccess    = `User [%v] now has the permission [%v] %v.`
+	grantUndeclared = `No extension has declared [%v] yet.`
+	grantFailure    = `Invalid permission [%v], use a name like ` +
+		`quotes.delete or quotes.*.`
+	grantFailureHas   = `User [%v] already has the permission [%v] %v.`
+	grantFailureWhere = `Invalid scope [%v], use net or a channel.`
+	revokeSuccess     = `User [%v] no longer has the permission [%v] %v.`
+	revokeFailureNo   = `User [%v] does not have the permission [%v] %v.`
+
+	denyDesc = `Denies a level and above or flags, overriding what's ` +
+		`given in the same place and everywhere less specific. The deny is ` +
+		`global unless net or a channel is given. Permissions are denied ` +
+		`with grant and a leading -, like -quotes.delete, and single ` +
+		`commands with -cmd.extension.command.`
+	undenyDesc = `Stops denying flags, a level, or all to stop denying ` +
+		`anything. Give the same net or channel it was denied in.`
+	denySuccess     = `User [%v] now has: (%v) %v.`
+	denyFailure     = `Invalid rule [%v], use a level or flags.`
+	undenyFailureNo = `User [%v] has no deny for [%v] %v.`
+
+	setDesc = `Changes a channel setting, see get for the settings and ` +
+		`what they take.`
+	getDesc = `Shows a channel's settings, or a setting's value and ` +
+		`what it takes.`
+	unsetDesc       = `Returns a channel setting to its default.`
+	setSuccess      = `Set [%v] on %v to: %v`
+	setFailure      = `Invalid value for [%v]: %v`
+	unsetSuccess    = `Unset [%v] on %v, it's now: %v`
+	unsetFailureNo  = `Setting [%v] is not set on %v.`
+	settingUnknown  = `No setting named [%v], see get for a list.`
+	settingAccess   = `Setting [%v] needs access (%v) on %v to change.`
+	settingNeedAny  = `Setting [%v] needs some access on %v to change.`
+	settingsNone    = `No settings have been declared.`
+	settingsHead    = `Settings on %v:`
+	settingValue    = `%v = %v`
+	settingDefault  = `%v = %v (default)`
+	settingHelp     = `%v (%v): %v`
+	settingHelpEnum = `Values: %v`
+	settingHelpNeed = `Changing it needs access (%v).`
+	settingHelpAny  = `Changing it needs some access on the channel.`
+	settingEmpty    = `(empty)`
+
 	gusersDesc    = `Lists all the users added to the global access list.`
 	gusersNoUsers = `No global users`
 	gusersHead    = `Showing %v users:`
@@ -142,6 +297,47 @@ const (
 	usersListHeadAccess = `Access`
 	usersList           = `%-*v %v`
 
+	exportDesc = `Exports all users and channels in the store to a file ` +
+		`in the exportdir.`
+	exportSuccess = `Exported %v users and %v channels to [%v].`
+	exportFailure = `Could not export to [%v]: %v`
+	exportPartial = `Could not read %v records, they were left out: %v`
+	importDesc    = `Imports users and channels from a file in the ` +
+		`exportdir created by export, overwriting any that already exist.`
+	importSuccess = `Imported %v users and %v channels from [%v].`
+	importFailure = `Could not import [%v]: %v`
+	exportNoDir   = `No export directory is configured, set exportdir.`
+	exportBadFile = `[%v] is not a file name.`
+
+	lockoutsDesc = `Lists the users and hosts that are throttled or locked ` +
+		`out because of failed authentications.`
+	lockoutsNone     = `No lockouts.`
+	lockoutsHead     = `Showing %v lockouts:`
+	lockoutsUser     = `User [%v]: %v failures, %v until %v`
+	lockoutsHost     = `Host [%v] on %v: %v failures, throttled until %v`
+	lockoutsLocked   = `locked`
+	lockoutsThrottle = `throttled`
+	unlockDesc       = `Clears the failed authentications of a username or ` +
+		`hostname.`
+	unlockSuccess = `Cleared %v lockouts for [%v].`
+	unlockFailure = `No lockouts for [%v].`
+
+	backupDesc = `Takes a snapshot of the store into the backup directory ` +
+		`and rotates the old snapshots.`
+	backupSuccess = `Backed up %v keys to [%v], removed %v old backups.`
+	backupFailure = `Could not back up the store: %v`
+	backupNoDir   = `No backup directory is configured, set backupdir.`
+
+	auditDesc = `Searches the log of changes to users' access. Filter with ` +
+		`actor=, target=, action=, net= and chan=, a name alone matches ` +
+		`the actor or target. The newest entries are shown.`
+	auditNone    = `No audit entries found.`
+	auditHead    = `Showing %v of %v audit entries:`
+	auditLine    = `%v %v [%v] %v: [%v] -> [%v] by [%v] (%v)`
+	auditGlobal  = `global`
+	auditFailure = `Invalid filter, use actor=, target=, action=, net= or ` +
+		`chan=. (given: %v)`
+
 	helpSuccess      = `Cmds:`
 	helpSuccessUsage = `Usage: %v %v`
 	helpFailure      = `No help available for (%v), try "help" for a list of ` +
@@ -265,6 +461,24 @@ var commands = []struct {
 		Flags:  ``,
 		Args:   argv{`[*user]`},
 	},
+	{
+		Name:   link,
+		Desc:   linkDesc,
+		Authed: true,
+		Public: false,
+		Level:  0,
+		Flags:  ``,
+		Args:   argv{`[requireMask]`},
+	},
+	{
+		Name:   unlink,
+		Desc:   unlinkDesc,
+		Authed: true,
+		Public: false,
+		Level:  0,
+		Flags:  ``,
+		Args:   nil,
+	},
 	{
 		Name:   addmask,
 		Desc:   addmaskDesc,
@@ -283,6 +497,24 @@ var commands = []struct {
 		Flags:  ``,
 		Args:   argv{`mask`, `[*user]`},
 	},
+	{
+		Name:   addcert,
+		Desc:   addcertDesc,
+		Authed: true,
+		Public: false,
+		Level:  0,
+		Flags:  ``,
+		Args:   argv{`fingerprint`, `[*user]`},
+	},
+	{
+		Name:   delcert,
+		Desc:   delcertDesc,
+		Authed: true,
+		Public: false,
+		Level:  0,
+		Flags:  ``,
+		Args:   argv{`fingerprint`, `[*user]`},
+	},
 	{
 		Name:   resetpasswd,
 		Desc:   resetpasswdDesc,
@@ -346,6 +578,204 @@ var commands = []struct {
 		Flags:  `GSC`,
 		Args:   argv{`#chan`, `*user`, `[allOrFlags]`},
 	},
+	{
+		Name:   roles,
+		Desc:   rolesDesc,
+		Authed: false,
+		Public: true,
+		Level:  0,
+		Flags:  ``,
+		Args:   nil,
+	},
+	{
+		Name:   setrole,
+		Desc:   setroleDesc,
+		Authed: true,
+		Public: false,
+		Level:  0,
+		Flags:  `G`,
+		Args:   argv{`name`, `levelOrFlags...`},
+	},
+	{
+		Name:   delrole,
+		Desc:   delroleDesc,
+		Authed: true,
+		Public: false,
+		Level:  0,
+		Flags:  `G`,
+		Args:   argv{`name`},
+	},
+	{
+		Name:   ggiverole,
+		Desc:   ggiveroleDesc,
+		Authed: true,
+		Public: true,
+		Level:  0,
+		Flags:  `G`,
+		Args:   argv{`*user`, `role`},
+	},
+	{
+		Name:   sgiverole,
+		Desc:   sgiveroleDesc,
+		Authed: true,
+		Public: true,
+		Level:  0,
+		Flags:  `GS`,
+		Args:   argv{`*user`, `role`},
+	},
+	{
+		Name:   giverole,
+		Desc:   giveroleDesc,
+		Authed: true,
+		Public: true,
+		Level:  0,
+		Flags:  `GSC`,
+		Args:   argv{`#chan`, `*user`, `role`},
+	},
+	{
+		Name:   gtakerole,
+		Desc:   gtakeroleDesc,
+		Authed: true,
+		Public: true,
+		Level:  0,
+		Flags:  `G`,
+		Args:   argv{`*user`, `role`},
+	},
+	{
+		Name:   stakerole,
+		Desc:   stakeroleDesc,
+		Authed: true,
+		Public: true,
+		Level:  0,
+		Flags:  `GS`,
+		Args:   argv{`*user`, `role`},
+	},
+	{
+		Name:   takerole,
+		Desc:   takeroleDesc,
+		Authed: true,
+		Public: true,
+		Level:  0,
+		Flags:  `GSC`,
+		Args:   argv{`#chan`, `*user`, `role`},
+	},
+	{
+		Name:   grant,
+		Desc:   grantDesc,
+		Authed: true,
+		Public: true,
+		Level:  0,
+		Flags:  `G`,
+		Args:   argv{`*user`, `permission`, `[where]`},
+	},
+	{
+		Name:   revoke,
+		Desc:   revokeDesc,
+		Authed: true,
+		Public: true,
+		Level:  0,
+		Flags:  `G`,
+		Args:   argv{`*user`, `permission`, `[where]`},
+	},
+	{
+		Name:   deny,
+		Desc:   denyDesc,
+		Authed: true,
+		Public: true,
+		Level:  0,
+		Flags:  `G`,
+		Args:   argv{`*user`, `rule`, `[where]`},
+	},
+	{
+		Name:   undeny,
+		Desc:   undenyDesc,
+		Authed: true,
+		Public: true,
+		Level:  0,
+		Flags:  `G`,
+		Args:   argv{`*user`, `rule`, `[where]`},
+	},
+	{
+		Name:   set,
+		Desc:   setDesc,
+		Authed: true,
+		Public: true,
+		Level:  0,
+		Flags:  ``,
+		Args:   argv{`#chan`, `setting`, `value...`},
+	},
+	{
+		Name:   get,
+		Desc:   getDesc,
+		Authed: false,
+		Public: true,
+		Level:  0,
+		Flags:  ``,
+		Args:   argv{`#chan`, `[setting]`},
+	},
+	{
+		Name:   unset,
+		Desc:   unsetDesc,
+		Authed: true,
+		Public: true,
+		Level:  0,
+		Flags:  ``,
+		Args:   argv{`#chan`, `setting`},
+	},
+	{
+		Name:   export,
+		Desc:   exportDesc,
+		Authed: true,
+		Public: false,
+		Level:  0,
+		Flags:  `G`,
+		Args:   argv{`file`},
+	},
+	{
+		Name:   imprt,
+		Desc:   importDesc,
+		Authed: true,
+		Public: false,
+		Level:  0,
+		Flags:  `G`,
+		Args:   argv{`file`},
+	},
+	{
+		Name:   lockouts,
+		Desc:   lockoutsDesc,
+		Authed: true,
+		Public: false,
+		Level:  0,
+		Flags:  `G`,
+		Args:   nil,
+	},
+	{
+		Name:   unlock,
+		Desc:   unlockDesc,
+		Authed: true,
+		Public: false,
+		Level:  0,
+		Flags:  `G`,
+		Args:   argv{`target`},
+	},
+	{
+		Name:   backup,
+		Desc:   backupDesc,
+		Authed: true,
+		Public: false,
+		Level:  0,
+		Flags:  `G`,
+		Args:   nil,
+	},
+	{
+		Name:   audit,
+		Desc:   auditDesc,
+		Authed: true,
+		Public: false,
+		Level:  0,
+		Flags:  `G`,
+		Args:   argv{`filters...`},
+	},
 	{
 		Name:   help,
 		Desc:   helpDesc,
@@ -376,6 +806,10 @@ func NewCoreCmds(b *Bot) (*coreCmds, error) {
 		commands: dispatch.NewCommandDispatcher(b.mkPrefixFetcher(), dispatchCore),
 	}
 
+	if ignores, _ := b.conf.Ignores(); len(ignores) != 0 {
+		c.commands.RegisterMiddleware("", "", dispatch.CmdIgnore(ignores...))
+	}
+
 	for _, command := range commands {
 		privacy := cmd.Private
 		if command.Public {
@@ -413,9 +847,22 @@ func NewCoreCmds(b *Bot) (*coreCmds, error) {
 		}
 	}
 
+	b.dispatcher.RegisterPre("", "", irc.PRIVMSG, 0, c)
+
 	return c, nil
 }
 
+// HandlePre dispatches the core commands before any other handler, a message
+// that was a core command isn't given to them.
+func (c *coreCmds) HandlePre(w irc.Writer, ev *irc.Event) bool {
+	handled, err := c.commands.Dispatch(w, ev, c.b)
+	if err != nil {
+		c.b.Logger.Error("cmd dispatch failed", "err", err)
+		return false
+	}
+	return handled
+}
+
 /*
 // unregisterCoreCmds unregisters all core commands. Made for testing.
 func (c *coreCmds) unregisterCoreCmds() {
@@ -469,6 +916,14 @@ func (c *coreCmds) Cmd(cmd string, w irc.Writer,
 		internal, external = c.addmask(w, ev)
 	case delmask:
 		internal, external = c.delmask(w, ev)
+	case addcert:
+		internal, external = c.addcert(w, ev)
+	case delcert:
+		internal, external = c.delcert(w, ev)
+	case link:
+		internal, external = c.link(w, ev)
+	case unlink:
+		internal, external = c.unlink(w, ev)
 	case resetpasswd:
 		internal, external = c.resetpasswd(w, ev)
 	case ggive:
@@ -483,6 +938,52 @@ func (c *coreCmds) Cmd(cmd string, w irc.Writer,
 		internal, external = c.stake(w, ev)
 	case take:
 		internal, external = c.take(w, ev)
+	case roles:
+		internal, external = c.roles(w, ev)
+	case setrole:
+		internal, external = c.setrole(w, ev)
+	case delrole:
+		internal, external = c.delrole(w, ev)
+	case ggiverole:
+		internal, external = c.roleHelper(w, ev, ggiverole, "", "", true)
+	case sgiverole:
+		internal, external = c.roleHelper(w, ev, sgiverole, ev.NetworkID, "", true)
+	case giverole:
+		internal, external = c.roleHelper(w, ev, giverole,
+			ev.NetworkID, ev.Args["chan"], true)
+	case gtakerole:
+		internal, external = c.roleHelper(w, ev, gtakerole, "", "", false)
+	case stakerole:
+		internal, external = c.roleHelper(w, ev, stakerole, ev.NetworkID, "", false)
+	case takerole:
+		internal, external = c.roleHelper(w, ev, takerole,
+			ev.NetworkID, ev.Args["chan"], false)
+	case grant:
+		internal, external = c.permHelper(w, ev, grant, true)
+	case revoke:
+		internal, external = c.permHelper(w, ev, revoke, false)
+	case deny:
+		internal, external = c.denyHelper(w, ev, deny, true)
+	case undeny:
+		internal, external = c.denyHelper(w, ev, undeny, false)
+	case set:
+		internal, external = c.set(w, ev)
+	case get:
+		internal, external = c.get(w, ev)
+	case unset:
+		internal, external = c.unset(w, ev)
+	case export:
+		internal, external = c.export(w, ev)
+	case imprt:
+		internal, external = c.imprt(w, ev)
+	case lockouts:
+		internal, external = c.lockouts(w, ev)
+	case unlock:
+		internal, external = c.unlock(w, ev)
+	case backup:
+		internal, external = c.backup(w, ev)
+	case audit:
+		internal, external = c.audit(w, ev)
 	case help:
 		internal, external = c.help(w, ev)
 	}
@@ -512,33 +1013,26 @@ func (c *coreCmds) register(w irc.Writer,
 	store := c.b.Store()
 	state := c.b.State(ev.NetworkID)
 
-	access, internal = store.FindUser(uname)
-	if internal != nil {
+	if external = store.ValidatePassword(uname, pwd); external != nil {
 		return
 	}
-	if access != nil {
-		return nil, fmt.Errorf(registerFailure, uname)
-	}
 
-	access, internal = data.NewStoredUser(uname, pwd)
+	access, internal = store.NewStoredUser(uname, pwd)
 	if internal != nil {
 		return
 	}
 
 	nChans, _ := state.NChannelsByUser(nick)
 
-	var hasAny bool
-	hasAny, internal = store.HasAny()
-	if internal != nil {
-		return
-	}
-	if !hasAny {
+	// The first user is found with the save so that two can't both be it.
+	var first bool
+	first, internal = store.CreateUser(access, func(u *data.StoredUser) {
 		// Secret from the Access map specifics
-		access.Access[":"] = data.Access{Level: ^uint8(0), Flags: ^uint64(0)}
-	}
-
-	internal = store.SaveUser(access)
-	if internal != nil {
+		u.Access[":"] = data.Access{Level: ^uint8(0), Flags: ^uint64(0)}
+	})
+	if internal == data.ErrRevisionMismatch {
+		return nil, fmt.Errorf(registerFailure, uname)
+	} else if internal != nil {
 		return
 	}
 
@@ -552,7 +1046,7 @@ func (c *coreCmds) register(w irc.Writer,
 	}
 
 	uname = strings.ToLower(uname)
-	if !hasAny {
+	if first {
 		w.Noticef(nick, registerSuccessFirst, uname)
 	} else {
 		w.Noticef(nick, registerSuccess, uname)
@@ -648,6 +1142,12 @@ func (c *coreCmds) access(w irc.Writer, ev *cmd.Event) (
 	}
 	w.Noticef(ev.Nick(), accessSuccess,
 		access.Username, access.String(ev.NetworkID, ch))
+	if roles := access.RoleString(ev.NetworkID, ch); len(roles) != 0 {
+		w.Noticef(ev.Nick(), accessRoles, access.Username, roles)
+	}
+	if perms := access.PermissionString(ev.NetworkID, ch); len(perms) != 0 {
+		w.Noticef(ev.Nick(), accessPerms, access.Username, perms)
+	}
 
 	return
 }
@@ -771,7 +1271,7 @@ func (c *coreCmds) deluser(w irc.Writer, ev *cmd.Event) (
 	store.LogoutByUsername(uname)
 
 	var removed bool
-	removed, internal = store.RemoveUser(uname)
+	removed, internal = store.RemoveUserAudit(uname, c.auditRemoved(ev, deluser))
 	if internal != nil {
 		return
 	}
@@ -795,7 +1295,7 @@ func (c *coreCmds) delme(w irc.Writer, ev *cmd.Event) (
 
 	removed := false
 	store.Logout(ev.NetworkID, host)
-	removed, internal = store.RemoveUser(uname)
+	removed, internal = store.RemoveUserAudit(uname, c.auditRemoved(ev, delme))
 	if internal != nil {
 		return
 	}
@@ -819,28 +1319,94 @@ func (c *coreCmds) passwd(w irc.Writer, ev *cmd.Event) (
 		w.Notice(nick, passwdFailure)
 		return
 	}
-
 	store := c.b.store
+	if external = store.ValidatePassword(uname, newpasswd); external != nil {
+		return
+	}
 
-	var access *data.StoredUser
-	access, internal = store.FindUser(uname)
+	internal = c.updateUser(uname, func(access *data.StoredUser) error {
+		return access.SetPassword(newpasswd)
+	})
 	if internal != nil {
 		return
 	}
-	if access == nil {
-		internal = fmt.Errorf(errFmtExpired, uname)
+	w.Notice(nick, passwdSuccess)
+
+	return
+}
+
+// link links the services account the user is identified to.
+func (c *coreCmds) link(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	requireMask := false
+	if arg := ev.Args["requireMask"]; len(arg) > 0 {
+		if arg != linkMaskArg {
+			external = fmt.Errorf(linkFailureArg, arg)
+			return
+		}
+		requireMask = true
+	}
+
+	account := ev.Account()
+	if len(account) == 0 && ev.User != nil {
+		account = ev.User.Account
+	}
+	if len(account) == 0 {
+		external = errors.New(linkFailure)
 		return
 	}
-	internal = access.SetPassword(newpasswd)
+
+	store := c.b.store
+	uname := ev.StoredUser.Username
+
+	var owner *data.StoredUser
+	owner, internal = store.UserByAccount(ev.NetworkID, account)
 	if internal != nil {
 		return
 	}
-	internal = store.SaveUser(access)
+	if owner != nil && owner.Username != uname {
+		external = fmt.Errorf(linkFailureTaken, account, owner.Username)
+		return
+	}
+
+	internal = c.updateUser(uname, func(access *data.StoredUser) error {
+		access.LinkAccount(ev.NetworkID, account, requireMask)
+		return nil
+	})
 	if internal != nil {
 		return
 	}
-	w.Notice(nick, passwdSuccess)
 
+	if requireMask {
+		w.Noticef(ev.Nick(), linkSuccessMask, account, uname)
+	} else {
+		w.Noticef(ev.Nick(), linkSuccess, account, uname)
+	}
+	return
+}
+
+// unlink removes the services account linked on the network.
+func (c *coreCmds) unlink(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	uname := ev.StoredUser.Username
+
+	var linked data.LinkedAccount
+	internal = c.updateUser(uname, func(access *data.StoredUser) error {
+		var ok bool
+		if linked, ok = access.Account(ev.NetworkID); !ok {
+			external = errors.New(unlinkFailure)
+			return data.ErrUnchanged
+		}
+		access.UnlinkAccount(ev.NetworkID)
+		return nil
+	})
+	if internal != nil || external != nil {
+		return
+	}
+
+	w.Noticef(ev.Nick(), unlinkSuccess, linked.Account)
 	return
 }
 
@@ -885,23 +1451,23 @@ func (c *coreCmds) addmask(w irc.Writer, ev *cmd.Event) (
 		uname = user.Username
 	}
 
-	store := c.b.store
-
-	var access *data.StoredUser
-	access, internal = store.FindUser(uname)
+	var changed bool
+	internal = c.updateUserAudit(uname, func(access *data.StoredUser) error {
+		uname = access.Username
+		if changed = access.AddMask(mask); !changed {
+			return data.ErrUnchanged
+		}
+		return nil
+	}, func(_, after *data.StoredUser) []data.AuditEntry {
+		entry := c.auditEntry(ev, addmask, after.Username)
+		entry.Before, entry.After = "", mask
+		return []data.AuditEntry{entry}
+	})
 	if internal != nil {
 		return
 	}
-	if access == nil {
-		internal = fmt.Errorf(errFmtExpired, uname)
-		return
-	}
 
-	if access.AddMask(mask) {
-		internal = store.SaveUser(access)
-		if internal != nil {
-			return
-		}
+	if changed {
 		w.Noticef(nick, addmaskSuccess, mask)
 	} else {
 		w.Noticef(nick, addmaskFailure, mask)
@@ -927,23 +1493,23 @@ func (c *coreCmds) delmask(w irc.Writer, ev *cmd.Event) (
 		uname = user.Username
 	}
 
-	store := c.b.store
-
-	var access *data.StoredUser
-	access, internal = store.FindUser(uname)
+	var changed bool
+	internal = c.updateUserAudit(uname, func(access *data.StoredUser) error {
+		uname = access.Username
+		if changed = access.RemoveMask(mask); !changed {
+			return data.ErrUnchanged
+		}
+		return nil
+	}, func(_, after *data.StoredUser) []data.AuditEntry {
+		entry := c.auditEntry(ev, delmask, after.Username)
+		entry.Before, entry.After = mask, ""
+		return []data.AuditEntry{entry}
+	})
 	if internal != nil {
 		return
 	}
-	if access == nil {
-		internal = fmt.Errorf(errFmtExpired, uname)
-		return
-	}
 
-	if access.RemoveMask(mask) {
-		internal = store.SaveUser(access)
-		if internal != nil {
-			return
-		}
+	if changed {
 		w.Noticef(nick, delmaskSuccess, mask)
 	} else {
 		w.Noticef(nick, delmaskFailure, mask)
@@ -952,31 +1518,123 @@ func (c *coreCmds) delmask(w irc.Writer, ev *cmd.Event) (
 	return
 }
 
-// resetpasswd resets a user's password
-func (c *coreCmds) resetpasswd(w irc.Writer, ev *cmd.Event) (
+// addcert adds a certificate fingerprint to a user.
+func (c *coreCmds) addcert(w irc.Writer, ev *cmd.Event) (
 	internal, external error) {
 
-	uname := ev.TargetStoredUsers["user"].Username
-	resetnick := ev.Args["nick"]
 	nick := ev.Nick()
-	newpasswd := ""
-
-	store := c.b.store
+	uname := ev.StoredUser.Username
 
-	var access *data.StoredUser
-	access, internal = store.FindUser(uname)
+	fingerprint, ok := data.NormalizeCertFP(ev.Args["fingerprint"])
+	if !ok {
+		w.Noticef(nick, addcertInvalid, ev.Args["fingerprint"])
+		return
+	}
+
+	user := ev.TargetStoredUsers["user"]
+	if user != nil {
+		if !ev.StoredUser.HasFlags("", "", "G") {
+			external = dispatch.MakeGlobalFlagsError("G")
+			return
+		}
+		uname = user.Username
+	}
+
+	store := c.b.store
+
+	var owner *data.StoredUser
+	owner, internal = store.UserByCert(fingerprint)
 	if internal != nil {
 		return
 	}
-	if access == nil {
-		internal = fmt.Errorf(errFmtExpired, uname)
+	if owner != nil && owner.Username != uname {
+		external = fmt.Errorf(addcertTaken, fingerprint, owner.Username)
 		return
 	}
-	newpasswd, internal = access.ResetPassword()
+
+	var changed bool
+	internal = c.updateUserAudit(uname, func(access *data.StoredUser) error {
+		uname = access.Username
+		if changed = access.AddCert(fingerprint); !changed {
+			return data.ErrUnchanged
+		}
+		return nil
+	}, func(_, after *data.StoredUser) []data.AuditEntry {
+		entry := c.auditEntry(ev, addcert, after.Username)
+		entry.Before, entry.After = "", fingerprint
+		return []data.AuditEntry{entry}
+	})
 	if internal != nil {
 		return
 	}
-	internal = store.SaveUser(access)
+
+	if changed {
+		w.Noticef(nick, addcertSuccess, fingerprint)
+	} else {
+		w.Noticef(nick, addcertFailure, fingerprint)
+	}
+
+	return
+}
+
+// delcert deletes a certificate fingerprint from a user.
+func (c *coreCmds) delcert(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	fingerprint := ev.Args["fingerprint"]
+	nick := ev.Nick()
+	uname := ev.StoredUser.Username
+
+	user := ev.TargetStoredUsers["user"]
+	if user != nil {
+		if !ev.StoredUser.HasFlags("", "", "G") {
+			external = dispatch.MakeGlobalFlagsError("G")
+			return
+		}
+		uname = user.Username
+	}
+
+	var changed bool
+	internal = c.updateUserAudit(uname, func(access *data.StoredUser) error {
+		uname = access.Username
+		if changed = access.RemoveCert(fingerprint); !changed {
+			return data.ErrUnchanged
+		}
+		return nil
+	}, func(_, after *data.StoredUser) []data.AuditEntry {
+		entry := c.auditEntry(ev, delcert, after.Username)
+		entry.Before, entry.After = fingerprint, ""
+		return []data.AuditEntry{entry}
+	})
+	if internal != nil {
+		return
+	}
+
+	if changed {
+		w.Noticef(nick, delcertSuccess, fingerprint)
+	} else {
+		w.Noticef(nick, delcertFailure, fingerprint)
+	}
+
+	return
+}
+
+// resetpasswd resets a user's password
+func (c *coreCmds) resetpasswd(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	uname := ev.TargetStoredUsers["user"].Username
+	resetnick := ev.Args["nick"]
+	nick := ev.Nick()
+	newpasswd := ""
+
+	internal = c.updateUserAudit(uname, func(access *data.StoredUser) (err error) {
+		uname = access.Username
+		newpasswd, err = access.ResetPassword()
+		return err
+	}, func(_, after *data.StoredUser) []data.AuditEntry {
+		return []data.AuditEntry{c.auditEntry(ev, resetpasswd, after.Username)}
+	})
 	if internal != nil {
 		return
 	}
@@ -990,7 +1648,7 @@ func (c *coreCmds) resetpasswd(w irc.Writer, ev *cmd.Event) (
 func (c *coreCmds) ggive(w irc.Writer, ev *cmd.Event) (
 	internal, external error) {
 
-	return c.giveHelper(w, ev, "", "")
+	return c.giveHelper(w, ev, ggive, "", "")
 }
 
 // sgive gives network access to a user.
@@ -998,7 +1656,7 @@ func (c *coreCmds) sgive(w irc.Writer, ev *cmd.Event) (
 	internal, external error) {
 
 	network := ev.NetworkID
-	return c.giveHelper(w, ev, network, "")
+	return c.giveHelper(w, ev, sgive, network, "")
 }
 
 // give gives channel access to a user.
@@ -1007,21 +1665,21 @@ func (c *coreCmds) give(w irc.Writer,
 
 	network := ev.NetworkID
 	channel := ev.Args["chan"]
-	return c.giveHelper(w, ev, network, channel)
+	return c.giveHelper(w, ev, give, network, channel)
 }
 
 // gtake takes global access from a user.
 func (c *coreCmds) gtake(w irc.Writer, ev *cmd.Event) (
 	internal, external error) {
 
-	return c.takeHelper(w, ev, "", "")
+	return c.takeHelper(w, ev, gtake, "", "")
 }
 
 // stake takes network access from a user.
 func (c *coreCmds) stake(w irc.Writer, ev *cmd.Event) (
 	internal, external error) {
 	network := ev.NetworkID
-	return c.takeHelper(w, ev, network, "")
+	return c.takeHelper(w, ev, stake, network, "")
 }
 
 // take takes global access from a user.
@@ -1029,36 +1687,35 @@ func (c *coreCmds) take(w irc.Writer, ev *cmd.Event) (
 	internal, external error) {
 	network := ev.NetworkID
 	channel := ev.Args["chan"]
-	return c.takeHelper(w, ev, network, channel)
+	return c.takeHelper(w, ev, take, network, channel)
 }
 
 // giveHelper parses the args to a give function and executes them in context
 func (c *coreCmds) giveHelper(w irc.Writer, ev *cmd.Event,
-	network, channel string) (internal, external error) {
+	action, network, channel string) (internal, external error) {
 
 	uname := ev.TargetStoredUsers["user"].Username
 	args := ev.SplitArg("levelOrFlags")
 	nick := ev.Nick()
 
-	store := c.b.store
-
-	var access *data.StoredUser
-	if access, internal = store.FindUser(uname); internal != nil {
-		return
-	} else if access == nil {
-		internal = fmt.Errorf(errFmtExpired, uname)
-		return
-	}
-
-	username := access.Username
-	a := ignoreOK(access.GetAccess(network, channel))
-
 	var level uint8
-	var flags, filtered string
-	var hasFlags, hasLevel bool
-
-	for _, arg := range args {
-		if rgxFlags.MatchString(arg) {
+	var flags string
+	var duration time.Duration
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.EqualFold(arg, giveForArg) {
+			// A for without a duration would otherwise be taken as flags.
+			if i++; i == len(args) {
+				w.Noticef(nick, giveFailureFor, "")
+				return
+			}
+			var err error
+			if duration, err = parseDuration(args[i]); err != nil {
+				w.Noticef(nick, giveFailureFor, args[i])
+				return
+			}
+		} else if rgxFlags.MatchString(arg) {
 			flags += arg
 		} else if l, err := strconv.ParseUint(arg, 10, 8); err == nil {
 			level = uint8(l)
@@ -1073,37 +1730,70 @@ func (c *coreCmds) giveHelper(w irc.Writer, ev *cmd.Event,
 		return
 	}
 
-	if level > 0 {
-		if a.HasLevel(level) {
-			hasLevel = true
-		} else {
-			access.Grant(network, channel, level)
-		}
+	// Access given without a duration is permanent.
+	var expires time.Time
+	if duration > 0 {
+		expires = time.Now().Add(duration)
 	}
 
-	if len(flags) != 0 {
-		filtered = filterFlags(network, channel, flags, a)
-		if len(filtered) == 0 {
-			hasFlags = true
-		} else {
-			access.Grant(network, channel, 0, filtered)
+	var access *data.StoredUser
+	var a data.Access
+	var hasFlags, hasLevel, granted, renewed bool
+
+	internal = c.updateUserAudit(uname, func(u *data.StoredUser) error {
+		access = u
+		a = ignoreOK(access.GetAccess(network, channel))
+
+		var grantLevel uint8
+		if level > 0 {
+			if a.HasLevel(level) {
+				hasLevel = true
+			} else {
+				grantLevel = level
+			}
+		}
+
+		var filtered string
+		if len(flags) != 0 {
+			filtered = filterFlags(network, channel, flags, a)
+			hasFlags = len(filtered) == 0
+		}
+
+		err := access.GrantUntil(network, channel, expires, grantLevel, filtered)
+		if err != nil {
+			return err
+		}
+
+		granted = (!hasFlags && len(flags) > 0) || (!hasLevel && level > 0)
+		// Giving access that expires again renews it.
+		renewed = !granted && duration > 0 && a.Expires != 0
+
+		if !granted && !renewed {
+			return data.ErrUnchanged
 		}
+		return nil
+	}, c.auditAccess(ev, action))
+	if internal == data.ErrMixedExpiry {
+		w.Noticef(nick, giveFailureMixed, access.Username, a)
+		internal = nil
+		return
+	} else if internal != nil {
+		return
 	}
 
-	if (!hasFlags && len(flags) > 0) || (!hasLevel && level > 0) {
-		if internal = store.SaveUser(access); internal == nil {
-			var msg string
-			var newAccess = ignoreOK(access.GetAccess(network, channel))
-			switch {
-			case len(network) != 0 && len(channel) != 0:
-				msg = fmt.Sprintf(giveSuccess, username, newAccess, channel)
-			case len(network) != 0:
-				msg = fmt.Sprintf(sgiveSuccess, username, newAccess)
-			default:
-				msg = fmt.Sprintf(ggiveSuccess, username, newAccess)
-			}
-			w.Noticef(nick, msg)
+	username := access.Username
+	if granted || renewed {
+		var msg string
+		var newAccess = ignoreOK(access.GetAccess(network, channel))
+		switch {
+		case len(network) != 0 && len(channel) != 0:
+			msg = fmt.Sprintf(giveSuccess, username, newAccess, channel)
+		case len(network) != 0:
+			msg = fmt.Sprintf(sgiveSuccess, username, newAccess)
+		default:
+			msg = fmt.Sprintf(ggiveSuccess, username, newAccess)
 		}
+		w.Noticef(nick, msg)
 		return
 	}
 
@@ -1122,73 +1812,836 @@ func (c *coreCmds) giveHelper(w irc.Writer, ev *cmd.Event,
 	return
 }
 
+// parseDuration parses a duration like time.ParseDuration does, and also
+// allows a whole number of days or weeks like 7d or 2w.
+func parseDuration(str string) (time.Duration, error) {
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(str, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(str, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+
+	var d time.Duration
+	if unit != 0 {
+		n, err := strconv.ParseUint(str[:len(str)-1], 10, 16)
+		if err != nil {
+			return 0, err
+		}
+		d = time.Duration(n) * unit
+	} else {
+		var err error
+		if d, err = time.ParseDuration(str); err != nil {
+			return 0, err
+		}
+	}
+
+	if d <= 0 {
+		return 0, errors.Errorf("duration must be positive: %v", str)
+	}
+	return d, nil
+}
+
 // takeHelper parses the args to a take function and executes them in context
 func (c *coreCmds) takeHelper(w irc.Writer, ev *cmd.Event,
-	network, channel string) (internal, external error) {
+	action, network, channel string) (internal, external error) {
 
 	uname := ev.TargetStoredUsers["user"].Username
 	arg := ev.Args["allOrFlags"]
 	nick := ev.Nick()
 
-	store := c.b.store
-
 	var access *data.StoredUser
-	if access, internal = store.FindUser(uname); internal != nil {
-		return
-	} else if access == nil {
-		internal = fmt.Errorf(errFmtExpired, uname)
+	var taken bool
+
+	internal = c.updateUserAudit(uname, func(u *data.StoredUser) error {
+		access = u
+		a := ignoreOK(access.GetAccess(network, channel))
+
+		var all, level bool
+		var flags string
+
+		if len(arg) == 0 {
+			level = true
+		} else if arg == takeAllArg {
+			all = true
+		} else if rgxFlags.MatchString(arg) {
+			flags = filterMissingFlags(network, channel, arg, a)
+		} else {
+			external = fmt.Errorf(takeFailure, arg)
+		}
+
+		if all && (a.HasLevel(1) || len(flags) != 0) {
+			access.RevokeLevel(network, channel)
+			access.RevokeFlags(network, channel)
+		} else if level && a.HasLevel(1) {
+			access.RevokeLevel(network, channel)
+		} else if len(flags) != 0 {
+			access.RevokeFlags(network, channel, flags)
+		} else {
+			return data.ErrUnchanged
+		}
+		taken = true
+		return nil
+	}, c.auditAccess(ev, action))
+	if internal != nil {
 		return
 	}
 
 	username := access.Username
-	a := ignoreOK(access.GetAccess(network, channel))
+	if taken {
+		var msg string
+		var newAccess = ignoreOK(access.GetAccess(network, channel))
+		switch {
+		case len(network) != 0 && len(channel) != 0:
+			msg = fmt.Sprintf(giveSuccess, username, newAccess, channel)
+		case len(network) != 0:
+			msg = fmt.Sprintf(sgiveSuccess, username, newAccess)
+		default:
+			msg = fmt.Sprintf(ggiveSuccess, username, newAccess)
+		}
+		w.Notice(nick, msg)
+		return
+	}
+
+	w.Noticef(nick, takeFailureNo, username, access.String(network, channel))
+	return
+}
+
+// roles lists the roles.
+func (c *coreCmds) roles(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
 
-	var all, level bool
+	nick := ev.Nick()
+	list := c.b.store.Roles()
+	if len(list) == 0 {
+		w.Notice(nick, rolesNone)
+		return
+	}
+
+	w.Noticef(nick, rolesHead, len(list))
+	for _, role := range list {
+		if role.Config {
+			w.Noticef(nick, rolesConfig, role.Name, role.Access)
+		} else {
+			w.Noticef(nick, rolesLine, role.Name, role.Access)
+		}
+	}
+	return
+}
+
+// setrole creates or changes a role.
+func (c *coreCmds) setrole(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	name := strings.ToLower(ev.Args["name"])
+	nick := ev.Nick()
+
+	var level uint8
 	var flags string
+	for _, arg := range ev.SplitArg("levelOrFlags") {
+		if rgxFlags.MatchString(arg) {
+			flags += arg
+		} else if l, err := strconv.ParseUint(arg, 10, 8); err == nil {
+			level = uint8(l)
+		} else {
+			w.Noticef(nick, giveFailure)
+			return
+		}
+	}
+	if level == 0 && len(flags) == 0 {
+		w.Noticef(nick, giveFailure)
+		return
+	}
 
-	if len(arg) == 0 {
-		level = true
-	} else if arg == takeAllArg {
-		all = true
-	} else if rgxFlags.MatchString(arg) {
-		flags = filterMissingFlags(network, channel, arg, a)
+	before, exists := c.b.store.LookupRole(name)
+	if exists && before.Config {
+		external = fmt.Errorf(roleConfig, name)
+		return
+	}
+
+	role := data.Role{Name: name, Access: *data.NewAccess(level, flags)}
+	entry := c.auditEntry(ev, setrole, name)
+	if exists {
+		entry.Before = before.Access.String()
+	}
+	entry.After = role.Access.String()
+	if internal = c.b.store.SaveRole(role, entry); internal != nil {
+		return
+	}
+
+	w.Noticef(nick, setroleSuccess, name, role.Access)
+	return
+}
+
+// delrole deletes a role.
+func (c *coreCmds) delrole(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	name := strings.ToLower(ev.Args["name"])
+
+	before, exists := c.b.store.LookupRole(name)
+	if !exists {
+		external = fmt.Errorf(delroleFailure, name)
+		return
+	} else if before.Config {
+		external = fmt.Errorf(roleConfig, name)
+		return
+	}
+
+	entry := c.auditEntry(ev, delrole, name)
+	entry.Before = before.Access.String()
+	if _, internal = c.b.store.RemoveRole(name, entry); internal != nil {
+		return
+	}
+
+	w.Noticef(ev.Nick(), delroleSuccess, name)
+	return
+}
+
+// roleHelper gives or takes a role from a user in context.
+func (c *coreCmds) roleHelper(w irc.Writer, ev *cmd.Event,
+	action, network, channel string, give bool) (internal, external error) {
+
+	uname := ev.TargetStoredUsers["user"].Username
+	role := strings.ToLower(ev.Args["role"])
+	nick := ev.Nick()
+
+	if give {
+		if _, ok := c.b.store.LookupRole(role); !ok {
+			external = fmt.Errorf(giveroleFailure, role)
+			return
+		}
+	}
+
+	scope := scopeText(network, channel)
+	var changed bool
+	var username, before, after string
+	internal = c.updateUserAudit(uname, func(access *data.StoredUser) error {
+		username = access.Username
+		before = strings.Join(access.GetRoles(network, channel), " ")
+
+		if give {
+			changed = access.GiveRole(network, channel, role)
+		} else {
+			changed = access.TakeRole(network, channel, role)
+		}
+		if !changed {
+			return data.ErrUnchanged
+		}
+
+		after = strings.Join(access.GetRoles(network, channel), " ")
+		return nil
+	}, func(*data.StoredUser, *data.StoredUser) []data.AuditEntry {
+		entry := c.auditEntry(ev, action, username)
+		entry.Network, entry.Channel = network, channel
+		entry.Before, entry.After = before, after
+		return []data.AuditEntry{entry}
+	})
+	if internal != nil {
+		return
+	}
+	if !changed {
+		if give {
+			w.Noticef(nick, giveroleFailureHas, username, role, scope)
+		} else {
+			w.Noticef(nick, takeroleFailureNo, username, role, scope)
+		}
+		return
+	}
+
+	if give {
+		w.Noticef(nick, giveroleSuccess, username, role, scope)
 	} else {
-		external = fmt.Errorf(takeFailure, arg)
+		w.Noticef(nick, takeroleSuccess, username, role, scope)
+	}
+	return
+}
+
+// permHelper grants or revokes a permission from a user.
+func (c *coreCmds) permHelper(w irc.Writer, ev *cmd.Event,
+	action string, give bool) (internal, external error) {
+
+	uname := ev.TargetStoredUsers["user"].Username
+	perm := strings.ToLower(ev.Args["permission"])
+	nick := ev.Nick()
+
+	network, channel, ok := whereScope(ev)
+	if !ok {
+		external = fmt.Errorf(grantFailureWhere, ev.Args["where"])
+		return
+	}
+
+	scope := scopeText(network, channel)
+	var has, changed bool
+	var username, before, after string
+	internal = c.updateUserAudit(uname, func(access *data.StoredUser) error {
+		username = access.Username
+		before = strings.Join(access.GetPermissions(network, channel), " ")
+
+		if give {
+			for _, p := range access.GetPermissions(network, channel) {
+				if has = p == perm; has {
+					return data.ErrUnchanged
+				}
+			}
+			changed = access.GrantPermission(network, channel, perm)
+		} else {
+			changed = access.RevokePermission(network, channel, perm)
+		}
+		if !changed {
+			return data.ErrUnchanged
+		}
+
+		after = strings.Join(access.GetPermissions(network, channel), " ")
+		return nil
+	}, func(*data.StoredUser, *data.StoredUser) []data.AuditEntry {
+		entry := c.auditEntry(ev, action, username)
+		entry.Network, entry.Channel = network, channel
+		entry.Before, entry.After = before, after
+		return []data.AuditEntry{entry}
+	})
+	switch {
+	case internal != nil:
+		return
+	case has:
+		w.Noticef(nick, grantFailureHas, username, perm, scope)
+		return
+	case !changed && give:
+		external = fmt.Errorf(grantFailure, perm)
+		return
+	case !changed:
+		w.Noticef(nick, revokeFailureNo, username, perm, scope)
+		return
 	}
 
-	var save = true
-	if all && (a.HasLevel(1) || len(flags) != 0) {
-		access.RevokeLevel(network, channel)
-		access.RevokeFlags(network, channel)
-	} else if level && a.HasLevel(1) {
-		access.RevokeLevel(network, channel)
-	} else if len(flags) != 0 {
-		access.RevokeFlags(network, channel, flags)
+	if !give {
+		w.Noticef(nick, revokeSuccess, username, perm, scope)
+		return
+	}
+
+	w.Noticef(nick, grantSuccess, username, perm, scope)
+	name := strings.TrimPrefix(perm, "-")
+	if _, ok := data.LookupPermission(name); !ok && !strings.HasSuffix(name, "*") {
+		w.Noticef(nick, grantUndeclared, name)
+	}
+	return
+}
+
+// denyHelper denies a level or flags to a user, or stops denying them.
+func (c *coreCmds) denyHelper(w irc.Writer, ev *cmd.Event,
+	action string, give bool) (internal, external error) {
+
+	uname := ev.TargetStoredUsers["user"].Username
+	rule := ev.Args["rule"]
+	nick := ev.Nick()
+
+	network, channel, ok := whereScope(ev)
+	if !ok {
+		external = fmt.Errorf(grantFailureWhere, ev.Args["where"])
+		return
+	}
+
+	var level uint8
+	var flags string
+	var all bool
+	if l, err := strconv.ParseUint(rule, 10, 8); err == nil && l > 0 {
+		level = uint8(l)
+	} else if !give && rule == takeAllArg {
+		all = true
+	} else if rgxFlags.MatchString(rule) {
+		flags = rule
 	} else {
-		save = false
-	}
-
-	if save {
-		if internal = store.SaveUser(access); internal == nil {
-			var msg string
-			var newAccess = ignoreOK(access.GetAccess(network, channel))
-			switch {
-			case len(network) != 0 && len(channel) != 0:
-				msg = fmt.Sprintf(giveSuccess, username, newAccess, channel)
-			case len(network) != 0:
-				msg = fmt.Sprintf(sgiveSuccess, username, newAccess)
-			default:
-				msg = fmt.Sprintf(ggiveSuccess, username, newAccess)
+		external = fmt.Errorf(denyFailure, rule)
+		return
+	}
+
+	scope := scopeText(network, channel)
+	var before, after *data.StoredUser
+	internal = c.updateUserAudit(uname, func(access *data.StoredUser) error {
+		before = access.Clone()
+
+		switch {
+		case give:
+			access.Deny(network, channel, level, flags)
+		case all:
+			access.RevokeDeny(network, channel)
+		case level != 0:
+			access.RevokeDenyLevel(network, channel)
+		default:
+			access.RevokeDeny(network, channel, flags)
+		}
+
+		after = access
+		if ignoreOK(access.GetAccess(network, channel)) ==
+			ignoreOK(before.GetAccess(network, channel)) {
+			return data.ErrUnchanged
+		}
+		return nil
+	}, c.auditAccess(ev, action))
+	if internal != nil {
+		return
+	}
+
+	username := after.Username
+	a := ignoreOK(after.GetAccess(network, channel))
+	if a == ignoreOK(before.GetAccess(network, channel)) {
+		if give {
+			w.Noticef(nick, denySuccess, username, a, scope)
+		} else {
+			w.Noticef(nick, undenyFailureNo, username, rule, scope)
+		}
+		return
+	}
+
+	w.Noticef(nick, denySuccess, username, a, scope)
+	return
+}
+
+// set changes a channel setting.
+func (c *coreCmds) set(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	network, ch := ev.NetworkID, ev.Args["chan"]
+	setting, ok := data.LookupSetting(ev.Args["setting"])
+	if !ok {
+		external = fmt.Errorf(settingUnknown, ev.Args["setting"])
+		return
+	}
+	if external = settingAccessError(ev, setting, network, ch); external != nil {
+		return
+	}
+
+	var before, value string
+	internal = c.b.store.UpdateChannelAudit(network, ch,
+		func(channel *data.StoredChannel) error {
+			before, _, _ = channel.Setting(setting.Name)
+			var err error
+			value, err = channel.SetSetting(setting.Name, ev.Args["value"])
+			if err != nil {
+				external = fmt.Errorf(setFailure, setting.Name,
+					strings.TrimPrefix(err.Error(), "data: "))
+				return data.ErrUnchanged
 			}
-			w.Notice(nick, msg)
+			return nil
+		}, func(_, _ *data.StoredChannel) []data.AuditEntry {
+			entry := c.auditEntry(ev, set, setting.Name)
+			entry.Network, entry.Channel = network, ch
+			entry.Before, entry.After = before, value
+			return []data.AuditEntry{entry}
+		})
+	if internal != nil || external != nil {
+		return
+	}
+
+	w.Noticef(ev.Nick(), setSuccess, setting.Name, ch, settingText(value))
+	return
+}
+
+// get shows a channel's settings, or one setting and what it takes.
+func (c *coreCmds) get(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	network, ch := ev.NetworkID, ev.Args["chan"]
+	nick := ev.Nick()
+
+	var settings []data.Setting
+	if name := ev.Args["setting"]; len(name) != 0 {
+		setting, ok := data.LookupSetting(name)
+		if !ok {
+			external = fmt.Errorf(settingUnknown, name)
+			return
 		}
+		settings = append(settings, setting)
+	} else if settings = data.Settings(); len(settings) == 0 {
+		w.Notice(nick, settingsNone)
 		return
 	}
 
-	w.Noticef(nick, takeFailureNo, username, access.String(network, channel))
+	var channel *data.StoredChannel
+	if channel, internal = c.b.store.FindChannel(network, ch); internal != nil {
+		return
+	} else if channel == nil {
+		channel = data.NewStoredChannel(network, ch)
+	}
+
+	if len(settings) > 1 {
+		w.Noticef(nick, settingsHead, ch)
+	}
+	for _, setting := range settings {
+		value, isSet, _ := channel.Setting(setting.Name)
+		if isSet {
+			w.Noticef(nick, settingValue, setting.Name, settingText(value))
+		} else {
+			w.Noticef(nick, settingDefault, setting.Name, settingText(value))
+		}
+	}
+
+	if len(settings) == 1 {
+		setting := settings[0]
+		w.Noticef(nick, settingHelp, setting.Name, setting.Type, setting.Desc)
+		if setting.Type == data.SettingEnum {
+			w.Noticef(nick, settingHelpEnum, strings.Join(setting.Values, ", "))
+		}
+		if setting.Level != 0 || len(setting.Flags) != 0 {
+			w.Noticef(nick, settingHelpNeed,
+				data.NewAccess(setting.Level, setting.Flags))
+		} else {
+			w.Notice(nick, settingHelpAny)
+		}
+	}
+	return
+}
+
+// unset returns a channel setting to its default.
+func (c *coreCmds) unset(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	network, ch := ev.NetworkID, ev.Args["chan"]
+	nick := ev.Nick()
+	setting, ok := data.LookupSetting(ev.Args["setting"])
+	if !ok {
+		external = fmt.Errorf(settingUnknown, ev.Args["setting"])
+		return
+	}
+	if external = settingAccessError(ev, setting, network, ch); external != nil {
+		return
+	}
+
+	var before string
+	var changed bool
+	internal = c.b.store.UpdateChannelAudit(network, ch,
+		func(channel *data.StoredChannel) error {
+			before, _, _ = channel.Setting(setting.Name)
+			if changed = channel.UnsetSetting(setting.Name); !changed {
+				return data.ErrUnchanged
+			}
+			return nil
+		}, func(_, _ *data.StoredChannel) []data.AuditEntry {
+			entry := c.auditEntry(ev, unset, setting.Name)
+			entry.Network, entry.Channel = network, ch
+			entry.Before = before
+			return []data.AuditEntry{entry}
+		})
+	if internal != nil {
+		return
+	}
+	if !changed {
+		w.Noticef(nick, unsetFailureNo, setting.Name, ch)
+		return
+	}
+
+	w.Noticef(nick, unsetSuccess, setting.Name, ch, settingText(setting.Default))
 	return
 }
 
+// settingAccessError checks the user has the access a setting needs to be
+// changed on a channel.
+func settingAccessError(ev *cmd.Event, setting data.Setting,
+	network, channel string) error {
+
+	if setting.CanChange(ev.StoredUser, network, channel) {
+		return nil
+	}
+	if setting.Level == 0 && len(setting.Flags) == 0 {
+		return fmt.Errorf(settingNeedAny, setting.Name, channel)
+	}
+
+	needs := data.NewAccess(setting.Level, setting.Flags)
+	return fmt.Errorf(settingAccess, setting.Name, needs, channel)
+}
+
+// settingText shows an empty setting so it can be seen.
+func settingText(value string) string {
+	if len(value) == 0 {
+		return settingEmpty
+	}
+	return value
+}
+
+// whereScope gets the network and channel from the where argument, it's
+// global when empty, the network for net, or a channel.
+func whereScope(ev *cmd.Event) (network, channel string, ok bool) {
+	where := ev.Args["where"]
+	switch {
+	case len(where) == 0:
+	case strings.EqualFold(where, grantNetArg):
+		network = ev.NetworkID
+	case ev.NetworkInfo != nil && ev.NetworkInfo.IsChannel(where):
+		network, channel = ev.NetworkID, where
+	default:
+		return "", "", false
+	}
+	return network, channel, true
+}
+
+// scopeText describes a network and channel to users.
+func scopeText(network, channel string) string {
+	switch {
+	case len(network) != 0 && len(channel) != 0:
+		return fmt.Sprintf(scopeChannel, channel)
+	case len(network) != 0:
+		return scopeNetwork
+	}
+	return scopeGlobal
+}
+
+// exportPath finds a file in the exportdir, only its base name is used so it
+// can't be anywhere else.
+func (c *coreCmds) exportPath(file string) (filename, path string,
+	external error) {
+
+	dir, ok := c.b.conf.ExportDir()
+	if !ok || len(dir) == 0 {
+		return "", "", errors.New(exportNoDir)
+	}
+
+	filename = filepath.Base(file)
+	switch filename {
+	case ".", "..", string(filepath.Separator):
+		return "", "", fmt.Errorf(exportBadFile, file)
+	}
+
+	return filename, filepath.Join(dir, filename), nil
+}
+
+// export writes the store out to a file in the exportdir.
+func (c *coreCmds) export(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	filename, path, external := c.exportPath(ev.Args["file"])
+	if external != nil {
+		return
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		external = fmt.Errorf(exportFailure, filename, err)
+		return
+	}
+
+	nUsers, nChannels, internal := c.b.store.Export(f)
+	failed, partial := internal.(data.RecordErrors)
+	if partial {
+		internal = nil
+	}
+	if err = f.Close(); internal == nil {
+		internal = err
+	}
+	if internal != nil {
+		return
+	}
+
+	w.Noticef(ev.Nick(), exportSuccess, nUsers, nChannels, filename)
+	if partial {
+		w.Noticef(ev.Nick(), exportPartial, len(failed),
+			strings.Join(failed.Keys(), ", "))
+	}
+	return
+}
+
+// imprt reads a file created by export from the exportdir into the store.
+func (c *coreCmds) imprt(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	filename, path, external := c.exportPath(ev.Args["file"])
+	if external != nil {
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		external = fmt.Errorf(importFailure, filename, err)
+		return
+	}
+	defer f.Close()
+
+	nUsers, nChannels, err := c.b.store.Import(f,
+		c.auditEntry(ev, imprt, filename))
+	if err != nil {
+		external = fmt.Errorf(importFailure, filename, err)
+		return
+	}
+
+	w.Noticef(ev.Nick(), importSuccess, nUsers, nChannels, filename)
+	return
+}
+
+// lockouts lists the throttled and locked out users and hosts.
+func (c *coreCmds) lockouts(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	nick := ev.Nick()
+	list := c.b.store.Lockouts()
+	if len(list) == 0 {
+		w.Notice(nick, lockoutsNone)
+		return
+	}
+
+	w.Noticef(nick, lockoutsHead, len(list))
+	for _, l := range list {
+		until := l.Until.Format(time.RFC3339)
+		if len(l.Username) != 0 {
+			state := lockoutsThrottle
+			if l.Locked {
+				state = lockoutsLocked
+			}
+			w.Noticef(nick, lockoutsUser, l.Username, l.Failures, state, until)
+		} else {
+			w.Noticef(nick, lockoutsHost, l.Host, l.Network, l.Failures, until)
+		}
+	}
+	return
+}
+
+// unlock clears the failed authentications of a username or hostname.
+func (c *coreCmds) unlock(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	target := ev.Args["target"]
+	if n := c.b.store.ClearLockouts(target); n > 0 {
+		w.Noticef(ev.Nick(), unlockSuccess, n, target)
+	} else {
+		w.Noticef(ev.Nick(), unlockFailure, target)
+	}
+	return
+}
+
+// backup takes a snapshot of the store.
+func (c *coreCmds) backup(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	b, err := c.b.Backup()
+	switch {
+	case err == errNoBackupDir:
+		external = errors.New(backupNoDir)
+		return
+	case err != nil:
+		external = fmt.Errorf(backupFailure, err)
+		return
+	}
+
+	w.Noticef(ev.Nick(), backupSuccess, b.Keys, b.Filename, len(b.Removed))
+	return
+}
+
+// audit searches the audit log.
+func (c *coreCmds) audit(w irc.Writer, ev *cmd.Event) (
+	internal, external error) {
+
+	var query data.AuditQuery
+	for _, filter := range ev.SplitArg("filters") {
+		kv := strings.SplitN(filter, "=", 2)
+		if len(kv) == 1 {
+			query.User = kv[0]
+			continue
+		}
+
+		switch strings.ToLower(kv[0]) {
+		case "actor":
+			query.Actor = kv[1]
+		case "target":
+			query.Target = kv[1]
+		case "action":
+			query.Action = kv[1]
+		case "net":
+			query.Network = kv[1]
+		case "chan":
+			query.Channel = kv[1]
+		default:
+			external = fmt.Errorf(auditFailure, filter)
+			return
+		}
+	}
+
+	nick := ev.Nick()
+
+	var entries []data.AuditEntry
+	if entries, internal = c.b.store.AuditLog(query); internal != nil {
+		return
+	}
+	if len(entries) == 0 {
+		w.Notice(nick, auditNone)
+		return
+	}
+
+	total := len(entries)
+	if total > auditShown {
+		entries = entries[total-auditShown:]
+	}
+
+	w.Noticef(nick, auditHead, len(entries), total)
+	for _, e := range entries {
+		scope := auditGlobal
+		if len(e.Channel) != 0 {
+			scope = e.Network + " " + e.Channel
+		} else if len(e.Network) != 0 {
+			scope = e.Network
+		}
+		w.Noticef(nick, auditLine, e.Time.Format(time.RFC3339), e.Action,
+			e.Target, scope, e.Before, e.After, e.Actor, e.Source)
+	}
+	return
+}
+
+// auditEntry starts an audit entry for a change the sender of ev made.
+func (c *coreCmds) auditEntry(ev *cmd.Event, action, target string) data.AuditEntry {
+	entry := data.AuditEntry{
+		Source: ev.Sender,
+		Action: action,
+		Target: target,
+	}
+	if ev.StoredUser != nil {
+		entry.Actor = ev.StoredUser.Username
+	}
+	return entry
+}
+
+// auditAccess audits the access a change to a user gave or took away.
+func (c *coreCmds) auditAccess(ev *cmd.Event,
+	action string) func(before, after *data.StoredUser) []data.AuditEntry {
+
+	return func(before, after *data.StoredUser) []data.AuditEntry {
+		return data.AuditAccessChanges(
+			c.auditEntry(ev, action, after.Username), before, after)
+	}
+}
+
+// auditRemoved audits the access a deleted user had.
+func (c *coreCmds) auditRemoved(ev *cmd.Event,
+	action string) func(*data.StoredUser) []data.AuditEntry {
+
+	return func(user *data.StoredUser) []data.AuditEntry {
+		entries := data.AuditAccessChanges(
+			c.auditEntry(ev, action, user.Username), user, nil)
+		if len(entries) == 0 {
+			entries = append(entries, c.auditEntry(ev, action, user.Username))
+		}
+		return entries
+	}
+}
+
+// updateUser changes a user with fn in a single transaction, see
+// data.Store.UpdateUser.
+func (c *coreCmds) updateUser(uname string,
+	fn func(*data.StoredUser) error) error {
+
+	return c.updateUserAudit(uname, fn, nil)
+}
+
+// updateUserAudit is updateUser that writes the entries made by audit with
+// the change, see data.Store.UpdateUserAudit.
+func (c *coreCmds) updateUserAudit(uname string,
+	fn func(*data.StoredUser) error,
+	audit func(before, after *data.StoredUser) []data.AuditEntry) error {
+
+	err := c.b.store.UpdateUserAudit(uname, fn, audit)
+	if err == data.ErrNotFound {
+		return fmt.Errorf(errFmtExpired, uname)
+	}
+	return err
+}
+
 // help searches for commands, and also provides details for specific commands
 func (c *coreCmds) help(w irc.Writer, ev *cmd.Event) (
 	internal, external error) {
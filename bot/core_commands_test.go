@@ -3,9 +3,13 @@ package bot
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aarondl/ultimateq/config"
 	"github.com/aarondl/ultimateq/data"
@@ -98,11 +102,11 @@ func rspChk(ts *tSetup, expected, sender string, args ...string) error {
 func prvRspChk(ts *tSetup, expected, to, sender string, args ...string) error {
 	ts.t.Helper()
 	ts.buffer.Reset()
-	err := ts.b.cmds.Dispatch(ts.writer, irc.NewEvent(
+	_, err := ts.b.coreCommands.commands.Dispatch(ts.writer, irc.NewEvent(
 		netID, netInfo, irc.PRIVMSG, sender, to, strings.Join(args, " ")),
 		ts.provider,
 	)
-	ts.b.cmds.WaitForHandlers()
+	ts.b.coreCommands.commands.WaitForHandlers()
 
 	s := ts.buffer.String()
 	if len(s) == 0 {
@@ -196,6 +200,47 @@ func TestCoreCommands_Register(t *testing.T) {
 	}
 }
 
+func TestCoreCommands_RegisterConcurrent(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	// Half of them race for the same name, and all of them to be the first.
+	writer := irc.Helper{Writer: ioutil.Discard}
+	commands := ts.b.coreCommands.commands
+	for i := 0; i < 6; i++ {
+		name := "racer"
+		if i%2 == 1 {
+			name = fmt.Sprintf("user%d", i)
+		}
+		host := fmt.Sprintf("nick%d!user%d@host%d", i, i, i)
+		_, err := commands.Dispatch(writer, irc.NewEvent(netID, netInfo,
+			irc.PRIVMSG, host, botnick, register+" "+password+" "+name),
+			ts.provider)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	commands.WaitForHandlers()
+
+	racers := 0
+	for i := 0; i < 6; i++ {
+		host := fmt.Sprintf("nick%d!user%d@host%d", i, i, i)
+		u := ts.store.AuthedUser(netID, host)
+		switch {
+		case i%2 == 1 && u == nil:
+			t.Error("Expected each other user to be registered:", host)
+		case i%2 == 0 && u != nil:
+			racers++
+		}
+	}
+	if racers != 1 {
+		t.Error("Expected one user to register the name, got:", racers)
+	}
+	if users, err := ts.store.GlobalUsers(); err != nil || len(users) != 1 {
+		t.Error("Expected exactly one user to be the first:", users, err)
+	}
+}
+
 func TestCoreCommands_Auth(t *testing.T) {
 	ts := commandsSetup(t)
 	defer commandsTeardown(ts, t)
@@ -1054,6 +1099,102 @@ func TestCoreCommands_Users(t *testing.T) {
 	}
 }
 
+func TestCoreCommands_ExportImport(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	dir, err := ioutil.TempDir("", "ultimateq-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = rspChk(ts, registerSuccessFirst, u1host, register, password, u1user)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, registerSuccess, u2host, register, password)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err = rspChk(ts, exportNoDir, u1host, export, "store.json"); err != nil {
+		t.Error(err)
+	}
+	ts.b.conf.SetExportDir(filepath.Join(dir, "exports"))
+	if err = os.Mkdir(filepath.Join(dir, "exports"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	err = rspChk(ts, ".*(G) flag(s) required.*", u2host, export, "store.json")
+	if err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, exportBadFile, u1host, export, ".."); err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, exportSuccess, u1host, export, "../store.json")
+	if err != nil {
+		t.Error(err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "store.json")); !os.IsNotExist(err) {
+		t.Error("Expected nothing to be written outside of the exportdir.")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "exports", "store.json")); err != nil {
+		t.Error("Expected the export in the exportdir:", err)
+	}
+
+	if _, err = ts.store.RemoveUser(u2user); err != nil {
+		t.Fatal(err)
+	}
+	err = rspChk(ts, importSuccess, u1host, imprt, "/elsewhere/store.json")
+	if err != nil {
+		t.Error(err)
+	}
+	if u, _ := ts.store.FindUser(u2user); u == nil {
+		t.Error("Expected the user to be imported.")
+	}
+	entries, err := ts.store.AuditLog(data.AuditQuery{Target: u2user, Action: imprt})
+	if err != nil || len(entries) != 1 || entries[0].Actor != u1user {
+		t.Errorf("Expected the import to be audited: %#v %v", entries, err)
+	}
+
+	err = rspChk(ts, importFailure, u1host, imprt, "missing.json")
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		In  string
+		Exp time.Duration
+	}{
+		{"90m", 90 * time.Minute},
+		{"1h30m", 90 * time.Minute},
+		{"7d", 7 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"d", 0},
+		{"-1h", 0},
+		{"0d", 0},
+		{"forever", 0},
+	}
+
+	for _, test := range tests {
+		got, err := parseDuration(test.In)
+		if test.Exp == 0 {
+			if err == nil {
+				t.Errorf("%v: Expected an error, got: %v", test.In, got)
+			}
+		} else if err != nil || got != test.Exp {
+			t.Errorf("%v: Expected: %v, got: %v %v", test.In, test.Exp, got, err)
+		}
+	}
+}
+
 func testGetUser(u *data.StoredUser, err error) *data.StoredUser {
 	if err == nil {
 		return u
@@ -1061,3 +1202,744 @@ func testGetUser(u *data.StoredUser, err error) *data.StoredUser {
 
 	panic(fmt.Sprintf("Failed to get user: %v", err))
 }
+
+// throttleUser fails to authenticate as username from an unknown host until
+// the store throttles it.
+func throttleUser(ts *tSetup, username string) {
+	ts.t.Helper()
+
+	for i := 0; len(ts.store.Lockouts()) == 0; i++ {
+		if i == 10 {
+			ts.t.Fatal("Expected the user to be throttled.")
+		}
+		ts.store.AuthUserTmp(netID, "nick3!user3@host3", username, "wrong")
+	}
+}
+
+func TestCoreCommands_Lockouts(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	err := rspChk(ts, registerSuccessFirst, u1host, register, password, u1user)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, registerSuccess, u2host, register, password)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err = rspChk(ts, lockoutsNone, u1host, lockouts); err != nil {
+		t.Error(err)
+	}
+	throttleUser(ts, u2user)
+
+	err = rspChk(ts, ".*(G) flag(s) required.*", u2host, lockouts)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, ".*(G) flag(s) required.*", u2host, unlock, u2user)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err = rspChk(ts, lockoutsHead+"%v", u1host, lockouts); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(ts.buffer.String(), "User ["+u2user+"]:") {
+		t.Errorf("Expected the user's failures to be listed:\n%s", ts.buffer)
+	}
+
+	if err = rspChk(ts, unlockSuccess, u1host, unlock, u2user); err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, unlockFailure, u1host, unlock, u2user); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCoreCommands_Link(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	ts.state.Update(irc.NewEvent(netID, netInfo, irc.JOIN, u2host, channel))
+
+	err := rspChk(ts, registerSuccessFirst, u1host, register, password, u1user)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, registerSuccess, u2host, register, password)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err = rspChk(ts, linkFailure, u1host, link); err != nil {
+		t.Error(err)
+	}
+
+	ts.state.Update(irc.NewEvent(netID, netInfo, irc.ACCOUNT, u1host, "acct"))
+	ts.state.Update(irc.NewEvent(netID, netInfo, irc.ACCOUNT, u2host, "acct"))
+
+	if err = rspChk(ts, linkFailureArg, u1host, link, "bogus"); err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, linkSuccess, u1host, link); err != nil {
+		t.Error(err)
+	}
+	if user, _ := ts.store.UserByAccount(netID, "acct"); user == nil ||
+		user.Username != u1user {
+		t.Error("Expected the account to be linked to the user:", user)
+	}
+	if err = rspChk(ts, linkFailureTaken, u2host, link); err != nil {
+		t.Error(err)
+	}
+
+	if err = rspChk(ts, unlinkSuccess, u1host, unlink); err != nil {
+		t.Error(err)
+	}
+	if user, _ := ts.store.UserByAccount(netID, "acct"); user != nil {
+		t.Error("Expected the account to be unlinked:", user)
+	}
+	if err = rspChk(ts, unlinkFailure, u1host, unlink); err != nil {
+		t.Error(err)
+	}
+
+	if err = rspChk(ts, linkSuccessMask, u2host, link, linkMaskArg); err != nil {
+		t.Error(err)
+	}
+	if user, _ := ts.store.UserByAccount(netID, "acct"); user == nil ||
+		user.Username != u2user {
+		t.Error("Expected the account to be linked to the user:", user)
+	}
+}
+
+func TestCoreCommands_Certs(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	fp1 := strings.Repeat("ab", 32)
+	fp2 := strings.Repeat("cd", 32)
+	fp1Colons := strings.TrimSuffix(strings.Repeat("AB:", 32), ":")
+	u1userArg := "*" + u1user
+
+	err := rspChk(ts, registerSuccessFirst, u1host, register, password, u1user)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, registerSuccess, u2host, register, password)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err = rspChk(ts, addcertInvalid, u1host, addcert, "abc"); err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, addcertSuccess, u1host, addcert, fp1Colons); err != nil {
+		t.Error(err)
+	}
+	if user, _ := ts.store.UserByCert(fp1); user == nil || user.Username != u1user {
+		t.Error("Expected the certificate to be added to the user:", user)
+	}
+	if err = rspChk(ts, addcertFailure, u1host, addcert, fp1); err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, addcertTaken, u2host, addcert, fp1); err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, ".*(G) global flag(s) required.*", u2host, addcert, fp2, u1userArg)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, addcertSuccess, u1host, addcert, fp2, u2userArg)
+	if err != nil {
+		t.Error(err)
+	}
+	if user, _ := ts.store.UserByCert(fp2); user == nil || user.Username != u2user {
+		t.Error("Expected the certificate to be added to the target:", user)
+	}
+
+	if err = rspChk(ts, delcertSuccess, u2host, delcert, fp2); err != nil {
+		t.Error(err)
+	}
+	if user, _ := ts.store.UserByCert(fp2); user != nil {
+		t.Error("Expected the certificate to be deleted:", user)
+	}
+	if err = rspChk(ts, delcertFailure, u2host, delcert, fp2); err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, ".*(G) global flag(s) required.*", u2host, delcert, fp1, u1userArg)
+	if err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, delcertSuccess, u1host, delcert, fp1Colons); err != nil {
+		t.Error(err)
+	}
+	if user, _ := ts.store.UserByCert(fp1); user != nil {
+		t.Error("Expected the certificate to be deleted:", user)
+	}
+}
+
+func TestCoreCommands_Audit(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	err := rspChk(ts, registerSuccessFirst, u1host, register, password, u1user)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, registerSuccess, u2host, register, password)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err = rspChk(ts, auditNone, u1host, audit, u2user); err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, addmaskSuccess, u1host, addmask, "*!*@newhost", u2userArg)
+	if err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, auditHead+"%v", u1host, audit, u2user); err != nil {
+		t.Error(err)
+	}
+	line := "addmask [" + u2user + "] global: [] -> [*!*@newhost] by [" +
+		u1user + "] (" + u1host + ")"
+	if !strings.Contains(ts.buffer.String(), line) {
+		t.Errorf("Expected the change to be shown:\n%s", ts.buffer)
+	}
+
+	err = rspChk(ts, auditNone, u1host, audit, u2user, "action=delmask")
+	if err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, auditFailure, u1host, audit, "bogus=1"); err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, ".*(G) flag(s) required.*", u2host, audit, u2user)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err = rspChk(ts, deluserSuccess, u1host, deluser, u2userArg); err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, auditHead+"%v", u1host, audit, "target="+u2user,
+		"action="+deluser)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCoreCommands_AlertLockout(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	err := rspChk(ts, registerSuccessFirst, u1host, register, password, u1user)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, registerSuccess, u2host, register, password)
+	if err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, ggiveSuccess, u1host, ggive, u2userArg, "1", "G"); err != nil {
+		t.Error(err)
+	}
+
+	// Admins that are only identified by services or a certificate.
+	ts.state.Update(irc.NewEvent(netID, netInfo, irc.ACCOUNT, u1host, "acct"))
+	if err = rspChk(ts, linkSuccess, u1host, link); err != nil {
+		t.Error(err)
+	}
+	fp := strings.Repeat("ef", 32)
+	if err = rspChk(ts, addcertSuccess, u1host, addcert, fp, u2userArg); err != nil {
+		t.Error(err)
+	}
+	ts.state.Update(irc.NewEvent(netID, netInfo, irc.JOIN, u2host, channel))
+	ts.state.Update(irc.NewEvent(netID, netInfo, irc.RPL_WHOISCERTFP, "irc",
+		botnick, "nick2", "has client certificate fingerprint "+fp))
+	ts.store.LogoutByUsername(u1user)
+	ts.store.LogoutByUsername(u2user)
+
+	ts.buffer.Reset()
+	ts.b.alertLockout(data.Lockout{Username: "victim", Network: netID,
+		Host: "nick3!user3@host3", Failures: 5, Until: time.Now()})
+	for _, nick := range []string{u1nick, "nick2"} {
+		if !strings.Contains(ts.buffer.String(), "NOTICE "+nick+" :User [victim]") {
+			t.Errorf("Expected %v to be alerted:\n%s", nick, ts.buffer)
+		}
+	}
+}
+
+func TestCoreCommands_GiveFor(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	err := rspChk(ts, registerSuccessFirst, u1host, register, password, u1user)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, registerSuccess, u2host, register, password)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, giveFailureFor, u1host, give, channel, u2userArg,
+		"50", "bd", giveForArg)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, giveFailureFor, u1host, give, channel, u2userArg,
+		"50", "bd", giveForArg, "soon")
+	if err != nil {
+		t.Error(err)
+	}
+	a := testGetUser(ts.store.FindUser(u2user))
+	if _, ok := a.GetAccess(netID, channel); ok {
+		t.Error("Nothing should be given by an invalid duration.")
+	}
+
+	err = rspChk(ts, giveSuccess, u1host, give, channel, u2userArg,
+		"50", "bd", giveForArg, "7d")
+	if err != nil {
+		t.Error(err)
+	}
+	a = testGetUser(ts.store.FindUser(u2user))
+	access, _ := a.GetAccess(netID, channel)
+	if !access.HasLevel(50) || !access.HasFlag('b') || !access.HasFlag('d') ||
+		access.HasFlags("for") {
+		t.Error("Expected only the level and flags to be given:", access)
+	}
+	week := time.Now().Add(7 * 24 * time.Hour).Unix()
+	if access.Expires < week-60 || access.Expires > week+60 {
+		t.Error("Expected the access to expire in a week:", access.Expires)
+	}
+
+	err = rspChk(ts, giveFailureMixed, u1host, give, channel, u2userArg, "v")
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, giveSuccess, u1host, give, channel, u2userArg,
+		"v", giveForArg, "1d")
+	if err != nil {
+		t.Error(err)
+	}
+	a = testGetUser(ts.store.FindUser(u2user))
+	access, _ = a.GetAccess(netID, channel)
+	day := time.Now().Add(24 * time.Hour).Unix()
+	if !access.HasFlag('v') || access.Expires < day-60 || access.Expires > day+60 {
+		t.Error("Expected the access to be given and renewed for a day:", access)
+	}
+}
+
+func TestCoreCommands_GiveForOnPermanent(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	err := rspChk(ts, registerSuccessFirst, u1host, register, password, u1user)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, registerSuccess, u2host, register, password)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, giveSuccess, u1host, give, channel, u2userArg, "100", "ab")
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, giveFailureMixed, u1host, give, channel, u2userArg,
+		"c", giveForArg, "7d")
+	if err != nil {
+		t.Error(err)
+	}
+
+	a := testGetUser(ts.store.FindUser(u2user))
+	access, _ := a.GetAccess(netID, channel)
+	if access.Expires != 0 || access.HasFlag('c') {
+		t.Error("Expected the permanent access to be left alone:", access)
+	}
+
+	// Even once everything that expires has, the permanent access is kept.
+	if _, err = ts.store.ExpireAccess(); err != nil {
+		t.Fatal(err)
+	}
+	a = testGetUser(ts.store.FindUser(u2user))
+	if access, _ = a.GetAccess(netID, channel); !access.HasLevel(100) ||
+		!access.HasFlags("ab") {
+
+		t.Error("Expected the permanent access to remain:", access)
+	}
+}
+
+func TestCoreCommands_Roles(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	ts.store.SetConfigRoles([]data.Role{
+		{Name: "cfgrole", Access: *data.NewAccess(10, "")},
+	})
+
+	err := rspChk(ts, registerSuccessFirst, u1host, register, password, u1user)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, registerSuccess, u2host, register, password)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, ".*(G) flag(s) required.*", u2host, setrole, "voice", "v")
+	if err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, giveFailure, u1host, setrole, "voice", "!!"); err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, roleConfig, u1host, setrole, "cfgrole", "5"); err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, setroleSuccess, u1host, setrole, "voice", "5", "v"); err != nil {
+		t.Error(err)
+	}
+	if role, ok := ts.store.LookupRole("voice"); !ok || role.Access.String() != "5 v" {
+		t.Error("Expected the role to be saved:", role)
+	}
+
+	if err = rspChk(ts, rolesHead+"%v", u2host, roles); err != nil {
+		t.Error(err)
+	}
+	for _, line := range []string{"voice: 5 v", "cfgrole: 10 (config)"} {
+		if !strings.Contains(ts.buffer.String(), line) {
+			t.Errorf("Expected the role %q to be listed:\n%s", line, ts.buffer)
+		}
+	}
+
+	err = rspChk(ts, giveroleFailure, u1host, giverole, channel, u2userArg, "none")
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, giveroleSuccess, u1host, giverole, channel, u2userArg, "voice")
+	if err != nil {
+		t.Error(err)
+	}
+	a := testGetUser(ts.store.FindUser(u2user))
+	if !a.HasLevel(netID, channel, 5) || !a.HasFlags(netID, channel, "v") {
+		t.Error("Expected the role to give its access.")
+	}
+	err = rspChk(ts, giveroleFailureHas, u1host, giverole, channel, u2userArg, "voice")
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, takeroleSuccess, u1host, takerole, channel, u2userArg, "voice")
+	if err != nil {
+		t.Error(err)
+	}
+	if a = testGetUser(ts.store.FindUser(u2user)); len(a.GetRoles(netID, channel)) != 0 {
+		t.Error("Expected the role to be taken.")
+	}
+	err = rspChk(ts, takeroleFailureNo, u1host, takerole, channel, u2userArg, "voice")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err = rspChk(ts, roleConfig, u1host, delrole, "cfgrole"); err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, delroleSuccess, u1host, delrole, "voice"); err != nil {
+		t.Error(err)
+	}
+	if _, ok := ts.store.LookupRole("voice"); ok {
+		t.Error("Expected the role to be deleted.")
+	}
+	if err = rspChk(ts, delroleFailure, u1host, delrole, "voice"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCoreCommands_Grant(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	err := data.DeclarePermissions("granttest",
+		data.Permission{Name: "granttest.delete"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = rspChk(ts, registerSuccessFirst, u1host, register, password, u1user)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, registerSuccess, u2host, register, password)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, ".*(G) flag(s) required.*", u2host, grant, u2userArg,
+		"granttest.delete")
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, grantFailureWhere, u1host, grant, u2userArg,
+		"granttest.delete", "nowhere")
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, grantFailure, u1host, grant, u2userArg, "bad!")
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, grantSuccess, u1host, grant, u2userArg,
+		"granttest.delete", channel)
+	if err != nil {
+		t.Error(err)
+	}
+	a := testGetUser(ts.store.FindUser(u2user))
+	if !a.HasPermission(netID, channel, "granttest.delete") {
+		t.Error("Expected the permission to be granted on the channel.")
+	}
+	if a.HasPermission("", "", "granttest.delete") {
+		t.Error("The permission should not be granted globally.")
+	}
+	err = rspChk(ts, grantFailureHas, u1host, grant, u2userArg,
+		"granttest.delete", channel)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, grantSuccess+"%v", u1host, grant, u2userArg,
+		"granttest.undeclared", grantNetArg)
+	if err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(ts.buffer.String(), "No extension has declared") {
+		t.Errorf("Expected an undeclared permission warning:\n%s", ts.buffer)
+	}
+	a = testGetUser(ts.store.FindUser(u2user))
+	if !a.HasPermission(netID, "", "granttest.undeclared") {
+		t.Error("Expected the permission to be granted on the network.")
+	}
+
+	err = rspChk(ts, revokeSuccess, u1host, revoke, u2userArg,
+		"granttest.delete", channel)
+	if err != nil {
+		t.Error(err)
+	}
+	a = testGetUser(ts.store.FindUser(u2user))
+	if a.HasPermission(netID, channel, "granttest.delete") {
+		t.Error("Expected the permission to be revoked.")
+	}
+	err = rspChk(ts, revokeFailureNo, u1host, revoke, u2userArg,
+		"granttest.delete", channel)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCoreCommands_Deny(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	err := rspChk(ts, registerSuccessFirst, u1host, register, password, u1user)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, registerSuccess, u2host, register, password)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, ggiveSuccess, u1host, ggive, u2userArg, "100", "v")
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, ".*(G) flag(s) required.*", u2host, deny, u2userArg, "50")
+	if err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, denyFailure, u1host, deny, u2userArg, "!"); err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, grantFailureWhere, u1host, deny, u2userArg, "50", "nowhere")
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, denySuccess, u1host, deny, u2userArg, "50", channel)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, denySuccess, u1host, deny, u2userArg, "v", grantNetArg)
+	if err != nil {
+		t.Error(err)
+	}
+	a := testGetUser(ts.store.FindUser(u2user))
+	if a.HasLevel(netID, channel, 50) || !a.HasLevel(netID, channel, 49) {
+		t.Error("Expected the level to be denied on the channel.")
+	}
+	if !a.HasLevel(netID, "", 50) {
+		t.Error("The level should only be denied on the channel.")
+	}
+	if a.HasFlags(netID, channel, "v") || !a.HasFlags("", "", "v") {
+		t.Error("Expected the flag to be denied on the network.")
+	}
+
+	err = rspChk(ts, denySuccess, u1host, undeny, u2userArg, "v", grantNetArg)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, denySuccess, u1host, undeny, u2userArg, takeAllArg, channel)
+	if err != nil {
+		t.Error(err)
+	}
+	a = testGetUser(ts.store.FindUser(u2user))
+	if !a.HasLevel(netID, channel, 100) || !a.HasFlags(netID, channel, "v") {
+		t.Error("Expected the denies to be removed.")
+	}
+	err = rspChk(ts, undenyFailureNo, u1host, undeny, u2userArg, "50", channel)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCoreCommands_Settings(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	err := data.DeclareSettings("coreset",
+		data.Setting{Name: "coreset.on", Type: data.SettingBool, Default: "on"},
+		data.Setting{Name: "coreset.mode", Type: data.SettingEnum,
+			Values: []string{"x", "y"}, Level: 50},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = rspChk(ts, registerSuccessFirst, u1host, register, password, u1user)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, registerSuccess, u2host, register, password)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, settingUnknown, u1host, set, channel, "coreset.none", "1")
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, setFailure, u1host, set, channel, "coreset.on", "maybe")
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, settingAccess, u2host, set, channel, "coreset.mode", "x")
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, settingNeedAny, u2host, set, channel, "coreset.on", "off")
+	if err != nil {
+		t.Error(err)
+	}
+	if ch, _ := ts.store.FindChannel(netID, channel); ch != nil {
+		t.Error("A user without access should not be able to save a channel.")
+	}
+	err = rspChk(ts, setSuccess, u1host, set, channel, "coreset.mode", "x")
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, setSuccess, u1host, set, channel, "coreset.on", "off")
+	if err != nil {
+		t.Error(err)
+	}
+
+	ch, err := ts.store.FindChannel(netID, channel)
+	if err != nil || ch == nil {
+		t.Fatal("Expected the channel to be saved:", err)
+	}
+	if mode, err := ch.SettingEnum("coreset.mode"); err != nil || mode != "x" {
+		t.Error("Expected the setting to be saved:", mode, err)
+	}
+
+	if err = rspChk(ts, settingValue+"%v", u2host, get, channel, "coreset.mode"); err != nil {
+		t.Error(err)
+	}
+	for _, line := range []string{"Values: x, y", "needs access (50)"} {
+		if !strings.Contains(ts.buffer.String(), line) {
+			t.Errorf("Expected %q to be shown:\n%s", line, ts.buffer)
+		}
+	}
+	if err = rspChk(ts, settingsHead+"%v", u2host, get, channel); err != nil {
+		t.Error(err)
+	}
+
+	err = rspChk(ts, settingAccess, u2host, unset, channel, "coreset.mode")
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, unsetSuccess, u1host, unset, channel, "coreset.on")
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, unsetFailureNo, u1host, unset, channel, "coreset.on")
+	if err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, settingDefault+"%v", u2host, get, channel, "coreset.on"); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(ts.buffer.String(), settingHelpAny) {
+		t.Errorf("Expected any access to be needed:\n%s", ts.buffer)
+	}
+}
+
+func TestCoreCommands_Backup(t *testing.T) {
+	ts := commandsSetup(t)
+	defer commandsTeardown(ts, t)
+
+	dir, err := ioutil.TempDir("", "corebackup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The memory store can't be backed up, so use bolt for the snapshot.
+	ts.store.Close()
+	ts.store, err = data.NewStore(data.MakeBoltStoreProvider(
+		filepath.Join(dir, "store.db")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.b.store = ts.store
+
+	err = rspChk(ts, registerSuccessFirst, u1host, register, password, u1user)
+	if err != nil {
+		t.Error(err)
+	}
+	err = rspChk(ts, registerSuccess, u2host, register, password)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err = rspChk(ts, ".*(G) flag(s) required.*", u2host, backup); err != nil {
+		t.Error(err)
+	}
+	if err = rspChk(ts, backupNoDir, u1host, backup); err != nil {
+		t.Error(err)
+	}
+
+	backups := filepath.Join(dir, "backups")
+	ts.b.conf.SetBackupDir(backups)
+	if err = rspChk(ts, backupSuccess, u1host, backup); err != nil {
+		t.Error(err)
+	}
+	files, err := ioutil.ReadDir(backups)
+	if err != nil || len(files) != 1 {
+		t.Error("Expected one backup to be taken:", files, err)
+	}
+}
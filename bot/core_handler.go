@@ -20,6 +20,14 @@ type coreHandler struct {
 	nickvalue      int
 	untilJoinScale time.Duration
 
+	// The capabilities each network has listed so far.
+	capLS map[string][]string
+
+	// The users waiting to be looked up with WHOIS on each network, and the
+	// time to wait between lookups.
+	whois      map[string]*whoisQueue
+	whoisDelay time.Duration
+
 	// Protect access to core Handler
 	protect sync.RWMutex
 }
@@ -46,6 +54,19 @@ func (c *coreHandler) Handle(w irc.Writer, ev *irc.Event) {
 		noautojoin, _ := cfg.NoAutoJoin()
 		joindelay, _ := cfg.JoinDelay()
 
+		c.protect.Lock()
+		delete(c.capLS, ev.NetworkID)
+		if q, ok := c.whois[ev.NetworkID]; ok {
+			// Anything still queued was for the previous connection.
+			q.nicks = nil
+			delete(c.whois, ev.NetworkID)
+		}
+		c.protect.Unlock()
+
+		// Servers without capabilities ignore this and register as normal,
+		// the others wait for CAP END.
+		w.Send("CAP LS 302")
+
 		if password, ok := cfg.Password(); ok {
 			w.Send("PASS :", password)
 		}
@@ -67,6 +88,17 @@ func (c *coreHandler) Handle(w irc.Writer, ev *irc.Event) {
 				}
 			}
 		}
+	case irc.CAP:
+		c.capability(w, ev)
+
+	case irc.RPL_WELCOME:
+		server := c.getServer(ev.NetworkID)
+		cfg := server.conf.Network(ev.NetworkID)
+
+		if modes, ok := cfg.UserModes(); ok && len(modes) > 0 {
+			w.Sendf("MODE %s %s", ev.Args[0], modes)
+		}
+
 	case irc.KICK, irc.ERR_BANNEDFROMCHAN:
 		server := c.getServer(ev.NetworkID)
 		cfg := server.conf.Network(ev.NetworkID)
@@ -139,9 +171,29 @@ func (c *coreHandler) Handle(w irc.Writer, ev *irc.Event) {
 			if ev.Sender == server.state.Self().Host.String() {
 				w.Send("WHO :", ev.Args[0])
 				w.Send("MODE :", ev.Args[0])
+			} else if c.wantCertFP() {
+				// The certificate fingerprint is only given by WHOIS.
+				c.queueWhois(w, ev.NetworkID, ev.Nick())
 			}
 		}
 
+	case irc.RPL_ENDOFWHO:
+		// Once the WHO sent after the bot joins a channel is done the users
+		// already on it are known, look them up like the ones that join.
+		server := c.getServer(ev.NetworkID)
+		if server.state == nil || len(ev.Args) < 2 || !c.wantCertFP() {
+			break
+		}
+
+		self := server.state.Self().Nick()
+		var nicks []string
+		for _, host := range server.state.UsersByChannel(ev.Args[1]) {
+			if nick := irc.Nick(host); !strings.EqualFold(nick, self) {
+				nicks = append(nicks, nick)
+			}
+		}
+		c.queueWhois(w, ev.NetworkID, nicks...)
+
 	case irc.RPL_MYINFO:
 		server := c.getServer(ev.NetworkID)
 		server.netInfo.ParseMyInfo(ev)
@@ -154,6 +206,134 @@ func (c *coreHandler) Handle(w irc.Writer, ev *irc.Event) {
 	}
 }
 
+// wantedCaps are the capabilities requested from servers that offer them, they
+// let the state know which services account users are identified to.
+var wantedCaps = []string{
+	irc.CapAccountNotify, irc.CapAccountTag, irc.CapExtendedJoin,
+}
+
+// capability negotiates capabilities while registering. Once the server has
+// listed them the wanted ones are requested, and registration ends whether
+// they're acknowledged or not.
+func (c *coreHandler) capability(w irc.Writer, ev *irc.Event) {
+	if len(ev.Args) < 3 {
+		return
+	}
+
+	switch strings.ToUpper(ev.Args[1]) {
+	case "LS":
+		// A * before the list means more lines are coming.
+		more := len(ev.Args) > 3 && ev.Args[2] == "*"
+		list := ev.Args[len(ev.Args)-1]
+
+		c.protect.Lock()
+		if c.capLS == nil {
+			c.capLS = make(map[string][]string)
+		}
+		for _, capability := range strings.Fields(list) {
+			// Values such as sasl=PLAIN aren't needed.
+			name := strings.SplitN(capability, "=", 2)[0]
+			c.capLS[ev.NetworkID] = append(c.capLS[ev.NetworkID], name)
+		}
+		offered := c.capLS[ev.NetworkID]
+		if !more {
+			delete(c.capLS, ev.NetworkID)
+		}
+		c.protect.Unlock()
+
+		if more {
+			return
+		}
+
+		var request []string
+		for _, want := range wantedCaps {
+			for _, capability := range offered {
+				if want == capability {
+					request = append(request, want)
+					break
+				}
+			}
+		}
+
+		if len(request) == 0 {
+			w.Send("CAP END")
+		} else {
+			w.Send("CAP REQ :", strings.Join(request, " "))
+		}
+
+	case "ACK", "NAK":
+		w.Send("CAP END")
+	}
+}
+
+// wantCertFP checks if users need to be looked up to learn their
+// certificate fingerprints, it's only worth it when some user has one.
+func (c *coreHandler) wantCertFP() bool {
+	store := c.bot.Store()
+	if store == nil {
+		return false
+	}
+	has, err := store.HasCerts()
+	return err == nil && has
+}
+
+// whoisQueue holds the nicks waiting to be looked up on a network.
+type whoisQueue struct {
+	nicks   []string
+	queued  map[string]bool
+	running bool
+}
+
+// queueWhois queues WHOIS lookups for the nicks on a network. They're sent
+// one at a time so joining a busy channel doesn't flood the server, nicks
+// that are already queued are skipped.
+func (c *coreHandler) queueWhois(w irc.Writer, netID string, nicks ...string) {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	if c.whois == nil {
+		c.whois = make(map[string]*whoisQueue)
+	}
+	q, ok := c.whois[netID]
+	if !ok {
+		q = &whoisQueue{queued: make(map[string]bool)}
+		c.whois[netID] = q
+	}
+
+	for _, nick := range nicks {
+		key := strings.ToLower(nick)
+		if q.queued[key] {
+			continue
+		}
+		q.queued[key] = true
+		q.nicks = append(q.nicks, nick)
+	}
+
+	if !q.running && len(q.nicks) > 0 {
+		q.running = true
+		go c.sendWhois(w, q)
+	}
+}
+
+// sendWhois sends the lookups in the queue until it's empty.
+func (c *coreHandler) sendWhois(w irc.Writer, q *whoisQueue) {
+	for {
+		c.protect.Lock()
+		if len(q.nicks) == 0 {
+			q.running = false
+			c.protect.Unlock()
+			return
+		}
+		nick := q.nicks[0]
+		q.nicks = q.nicks[1:]
+		delete(q.queued, strings.ToLower(nick))
+		c.protect.Unlock()
+
+		w.Send("WHOIS :", nick)
+		<-time.After(c.whoisDelay)
+	}
+}
+
 // getServer is a helper to look up the server based on w.
 func (c *coreHandler) getServer(netID string) *Server {
 	c.bot.protectServers.RLock()
@@ -6,8 +6,10 @@ import (
 	"net"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aarondl/ultimateq/config"
+	"github.com/aarondl/ultimateq/data"
 	"github.com/aarondl/ultimateq/irc"
 )
 
@@ -69,6 +71,7 @@ func TestCoreHandler_Connect(t *testing.T) {
 	realname, _ := net.Realname()
 
 	handler := coreHandler{bot: b}
+	msg0 := "CAP LS 302"
 	msg1 := fmt.Sprintf("PASS :%v", password)
 	msg2 := fmt.Sprintf("NICK :%v", nick)
 	msg3 := fmt.Sprintf("USER %v 0 * :%v", username, realname)
@@ -79,7 +82,7 @@ func TestCoreHandler_Connect(t *testing.T) {
 	endpoint := makeTestPoint(b.servers[netID])
 	handler.Handle(endpoint, ev)
 
-	expect := msg1 + msg2 + msg3
+	expect := msg0 + msg1 + msg2 + msg3
 	if got := endpoint.gets(); !strings.HasPrefix(got, expect) {
 		t.Errorf("Expected: %s, got: %s", expect, got)
 	} else if !strings.Contains(got, msg4) {
@@ -92,7 +95,55 @@ func TestCoreHandler_Connect(t *testing.T) {
 
 	net.SetNoAutoJoin(true)
 	handler.Handle(endpoint, ev)
-	expect = msg1 + msg2 + msg3
+	expect = msg0 + msg1 + msg2 + msg3
+	if got := endpoint.gets(); got != expect {
+		t.Errorf("Expected: %s, got: %s", expect, got)
+	}
+}
+
+func TestCoreHandler_Cap(t *testing.T) {
+	handler := coreHandler{}
+	endpoint := makeTestPoint(nil)
+
+	tests := []struct {
+		Args   []string
+		Expect string
+	}{
+		{[]string{"*", "LS", "*", "multi-prefix sasl=PLAIN,EXTERNAL"}, ""},
+		{[]string{"*", "LS", "account-tag extended-join"},
+			"CAP REQ :account-tag extended-join"},
+		{[]string{"*", "ACK", "account-tag extended-join"}, "CAP END"},
+		{[]string{"*", "LS", "multi-prefix"}, "CAP END"},
+		{[]string{"*", "NAK", "account-notify"}, "CAP END"},
+	}
+
+	for _, test := range tests {
+		endpoint.resetTestWritten()
+		ev := irc.NewEvent(netID, netInfo, irc.CAP, "irc", test.Args...)
+		handler.Handle(endpoint, ev)
+		if got := endpoint.gets(); got != test.Expect {
+			t.Errorf("%v: Expected: %q, got: %q", test.Args, test.Expect, got)
+		}
+	}
+}
+
+func TestCoreHandler_Welcome(t *testing.T) {
+	cnf := fakeConfig.Clone()
+	b, _ := createBot(cnf, nil, nil, devNull, false, false)
+
+	handler := coreHandler{bot: b}
+	ev := irc.NewEvent(netID, netInfo, irc.RPL_WELCOME, "", "nick",
+		"Welcome to the network nick!user@host")
+	endpoint := makeTestPoint(b.servers[netID])
+
+	handler.Handle(endpoint, ev)
+	if got := endpoint.gets(); got != "" {
+		t.Errorf("Expected no modes to be sent, got: %s", got)
+	}
+
+	cnf.Network(netID).SetUserModes("+iB")
+	handler.Handle(endpoint, ev)
+	expect := "MODE nick +iB"
 	if got := endpoint.gets(); got != expect {
 		t.Errorf("Expected: %s, got: %s", expect, got)
 	}
@@ -236,3 +287,67 @@ func TestCoreHandler_Join(t *testing.T) {
 		t.Errorf("Expected: %s, got: %s", exp, got)
 	}
 }
+
+func TestCoreHandler_Whois(t *testing.T) {
+	connProvider := func(srv string) (net.Conn, error) {
+		return nil, nil
+	}
+	storeProvider := func(_ string) (*data.Store, error) {
+		return data.NewStore(data.MemStoreProvider)
+	}
+
+	cnf := fakeConfig.Clone()
+	cnf.Network("").SetNoStore(false)
+
+	b, err := createBot(cnf, connProvider, storeProvider, devNull, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.store.Close()
+
+	user, err := data.NewStoredUser("user", "password", "*!*@host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.AddCert(strings.Repeat("ab", 32))
+	if err = b.store.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := b.servers[netID]
+	srv.handler.whoisDelay = 0
+	srv.state.Update(irc.NewEvent(netID, netInfo, irc.RPL_WELCOME, "server",
+		"WELCOME", "nick!user@host"))
+	for _, host := range []string{"nick!user@host", "a!a@a", "b!b@b"} {
+		srv.state.Update(irc.NewEvent(netID, netInfo, irc.JOIN, host, "#chan"))
+	}
+
+	endpoint := makeTestPoint(nil)
+	srv.handler.Handle(endpoint, irc.NewEvent(netID, netInfo, irc.RPL_ENDOFWHO,
+		"server", "nick", "#chan", "End of /WHO list."))
+	srv.handler.Handle(endpoint, irc.NewEvent(netID, netInfo, irc.JOIN,
+		"c!c@c", "#chan"))
+
+	for i := 0; ; i++ {
+		srv.handler.protect.Lock()
+		running := srv.handler.whois[netID].running
+		srv.handler.protect.Unlock()
+		if !running {
+			break
+		}
+		if i == 100 {
+			t.Fatal("The lookups were never sent.")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	got := endpoint.gets()
+	for _, nick := range []string{"a", "b", "c"} {
+		if !strings.Contains(got, "WHOIS :"+nick) {
+			t.Errorf("Expected a WHOIS for %v, got: %s", nick, got)
+		}
+	}
+	if strings.Contains(got, "WHOIS :nick") {
+		t.Error("The bot should not look itself up:", got)
+	}
+}
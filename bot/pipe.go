@@ -18,10 +18,12 @@ const (
 )
 
 var _ dispatch.Handler = &pipeHandler{}
+var _ dispatch.PreHandler = &pipeHandler{}
 var _ cmd.Handler = &pipeHandler{}
 
 type pipeHelper interface {
 	broadcastEvent(ext string, r *api.IRCEventResponse) bool
+	preEvent(ext string, r *api.IRCEventResponse) (stop, sent bool)
 	broadcastCmd(ext string, r *api.CmdEventResponse) bool
 	unregEvent(ext string, id uint64)
 	unregCmd(ext string, id uint64)
@@ -54,14 +56,24 @@ func (p *pipeHandler) setEventID(evID uint64) {
 }
 
 func (p *pipeHandler) Handle(w irc.Writer, ev *irc.Event) {
+	p.handle(ev, false)
+}
+
+// HandlePre sends the event to the remote pre handler and waits on its
+// verdict.
+func (p *pipeHandler) HandlePre(w irc.Writer, ev *irc.Event) bool {
+	return p.handle(ev, true)
+}
+
+func (p *pipeHandler) handle(ev *irc.Event, pre bool) (stop bool) {
 	p.mut.RLock()
 	evID := p.eventID
 	p.mut.RUnlock()
 	if evID == 0 {
-		return
+		return false
 	}
 
-	p.logger.Debug("remote event dispatch", "id", evID)
+	p.logger.Debug("remote event dispatch", "id", evID, "pre", pre)
 
 	event := &api.IRCEventResponse{
 		Id: evID,
@@ -74,9 +86,14 @@ func (p *pipeHandler) Handle(w irc.Writer, ev *irc.Event) {
 		},
 	}
 
-	sent := p.helper.broadcastEvent(p.ext, event)
+	var sent bool
+	if pre {
+		stop, sent = p.helper.preEvent(p.ext, event)
+	} else {
+		sent = p.helper.broadcastEvent(p.ext, event)
+	}
 	if sent {
-		return
+		return stop
 	}
 
 	p.logger.Debug("remote misfire", "ext", p.ext, "id", evID)
@@ -91,6 +108,7 @@ func (p *pipeHandler) Handle(w irc.Writer, ev *irc.Event) {
 		p.logger.Debug("unreg event misfire threshold", "ext", p.ext, "id", evID)
 		p.helper.unregEvent(p.ext, evID)
 	}
+	return false
 }
 
 func (p *pipeHandler) Cmd(name string, w irc.Writer, ev *cmd.Event) error {
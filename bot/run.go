@@ -1,13 +1,26 @@
 package bot
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/aarondl/ultimateq/config"
+	"github.com/aarondl/ultimateq/data"
 )
 
+var errStoreCommandUsage = errors.New("usage: export <file|->, " +
+	"import <file|->, migrate [-dry-run], reindex or rotatesecrets")
+
+// errFmtExportPartial is given when an export was written without the
+// records that could not be read.
+const errFmtExportPartial = "exported all but %d records that could not " +
+	"be read: %s"
+
 // Run makes a very typical bot. It will call the cb function passed in
 // before starting to allow registration of extensions etc. Returns error
 // if the bot could not be created. Does NOT return until dead.
@@ -15,8 +28,18 @@ import (
 // Reads configuration file from ./config.toml
 // Watches for Keyboard Input OR SIGTERM OR SIGKILL and shuts down normally.
 // Pauses after death to allow all goroutines to come to a graceful shutdown.
+// If the first argument is export, import, migrate, reindex or rotatesecrets
+// that is done to the store instead of starting the bot, see storeCommand.
 func Run(cb func(b *Bot)) error {
 	cfg := config.New().FromFile("config.toml")
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export", "import", "migrate", "reindex", "rotatesecrets":
+			return storeCommand(cfg, os.Args[1:], os.Stdin, os.Stdout)
+		}
+	}
+
 	b, err := New(cfg)
 	if err != nil {
 		return err
@@ -71,3 +94,145 @@ func Run(cb func(b *Bot)) error {
 
 	return nil
 }
+
+// storeCommand exports, imports, migrates or reindexes the store, or
+// re-encrypts its secrets with secret_key, without starting the bot, which
+// allows the store to be backed up or moved between backends offline. args
+// must be one of: export <file>, import <file> where a filename of - uses
+// stdout or stdin respectively, migrate [-dry-run], reindex or rotatesecrets.
+func storeCommand(cfg *config.Config, args []string,
+	stdin io.Reader, stdout io.Writer) (err error) {
+
+	switch {
+	case len(args) == 2 && (args[0] == "export" || args[0] == "import"):
+	case len(args) >= 1 && len(args) <= 2 && args[0] == "migrate":
+		if len(args) == 2 && args[1] != "-dry-run" {
+			return errStoreCommandUsage
+		}
+	case len(args) == 1 && (args[0] == "reindex" || args[0] == "rotatesecrets"):
+	default:
+		return errStoreCommandUsage
+	}
+
+	sfile, _ := cfg.StoreFile()
+	sbackend, _ := cfg.StoreBackend()
+	prov, err := data.MakeStoreProvider(sbackend, sfile)
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "migrate" {
+		report, err := data.MigrateStore(prov, len(args) == 2)
+		if err != nil {
+			return err
+		}
+		printMigration(stdout, report)
+		return nil
+	}
+
+	store, err := data.NewStore(prov)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := store.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	if err = setSecretKey(store, cfg); err != nil {
+		return err
+	}
+
+	if args[0] == "reindex" {
+		if err = store.RebuildIndexes(); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, "Rebuilt store indexes")
+		return nil
+	}
+
+	if args[0] == "rotatesecrets" {
+		n, err := store.RotateSecrets()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Re-encrypted %d secrets\n", n)
+		return nil
+	}
+
+	command, filename := args[0], args[1]
+	var nUsers, nChannels int
+
+	if command == "export" {
+		w := stdout
+		if filename != "-" {
+			var f *os.File
+			if f, err = os.Create(filename); err != nil {
+				return err
+			}
+			defer func() {
+				if cerr := f.Close(); err == nil {
+					err = cerr
+				}
+			}()
+			w = f
+		}
+
+		nUsers, nChannels, err = store.Export(w)
+		failed, partial := err.(data.RecordErrors)
+		if err != nil && !partial {
+			return err
+		}
+		if filename != "-" {
+			fmt.Fprintf(stdout, "Exported %d users and %d channels to %s\n",
+				nUsers, nChannels, filename)
+		}
+		if partial {
+			return fmt.Errorf(errFmtExportPartial, len(failed),
+				strings.Join(failed.Keys(), ", "))
+		}
+		return nil
+	}
+
+	r := stdin
+	if filename != "-" {
+		var f *os.File
+		if f, err = os.Open(filename); err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	importer := data.AuditEntry{Source: "cli", Action: "import"}
+	if nUsers, nChannels, err = store.Import(r, importer); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Imported %d users and %d channels\n",
+		nUsers, nChannels)
+	return nil
+}
+
+// printMigration writes a migration report for a person to read.
+func printMigration(w io.Writer, report *data.MigrationReport) {
+	verb := "Migrated"
+	if report.DryRun {
+		verb = "Would migrate"
+	}
+
+	if len(report.Applied) == 0 {
+		fmt.Fprintf(w, "Store is already at schema version %d\n", report.To)
+	} else {
+		fmt.Fprintf(w, "%s store from schema version %d to %d\n",
+			verb, report.From, report.To)
+	}
+	for _, step := range report.Applied {
+		fmt.Fprintf(w, "  step: %s\n", step)
+	}
+	for _, change := range report.Changes {
+		fmt.Fprintf(w, "  %s\n", change)
+	}
+	for _, failed := range report.Failed {
+		fmt.Fprintf(w, "  failed: %v\n", failed)
+	}
+}
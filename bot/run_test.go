@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aarondl/ultimateq/config"
+	"github.com/aarondl/ultimateq/data"
+)
+
+func TestRun_StoreCommand(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "ultimateq-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fromFile, toFile := filepath.Join(dir, "from.db"), filepath.Join(dir, "to.db")
+	from := config.New().SetStoreBackend(data.BackendBolt).SetStoreFile(fromFile)
+	to := config.New().SetStoreBackend(data.BackendBolt).SetStoreFile(toFile)
+
+	store, err := data.NewStore(data.MakeBoltStoreProvider(fromFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := data.NewStoredUser("user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, out := &bytes.Buffer{}, &bytes.Buffer{}
+	if err = storeCommand(from, []string{"export", "-"}, nil, exported); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(exported.String(), `"username": "user"`) {
+		t.Error("Expected the user to be exported, got:", exported)
+	}
+
+	if err = storeCommand(to, []string{"import", "-"}, exported, out); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := out.String(), "Imported 1 users and 0 channels\n"; exp != got {
+		t.Errorf("Expected: %q, got: %q", exp, got)
+	}
+
+	store, err = data.NewStore(data.MakeBoltStoreProvider(toFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if user, err := store.FindUser("user"); err != nil || user == nil {
+		t.Error("Expected the user to be imported:", err)
+	}
+
+	for _, args := range [][]string{
+		nil, {"export"}, {"delete", "-"}, {"migrate", "-n"},
+		{"rotatesecrets", "-"},
+	} {
+		if err = storeCommand(to, args, nil, out); err != errStoreCommandUsage {
+			t.Errorf("%v: Expected usage error, got: %v", args, err)
+		}
+	}
+}
+
+func TestRun_StoreCommandMigrate(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "ultimateq-run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := config.New().
+		SetStoreBackend(data.BackendBolt).
+		SetStoreFile(filepath.Join(dir, "store.db"))
+
+	out := &bytes.Buffer{}
+	if err = storeCommand(cfg, []string{"migrate", "-dry-run"}, nil, out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "Would migrate store from schema version 0") {
+		t.Error("Expected a dry run, got:", out)
+	}
+
+	out.Reset()
+	if err = storeCommand(cfg, []string{"migrate"}, nil, out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "Migrated store from schema version 0") {
+		t.Error("Expected a migration, got:", out)
+	}
+
+	out.Reset()
+	if err = storeCommand(cfg, []string{"migrate"}, nil, out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "Store is already at schema version") {
+		t.Error("Expected nothing to migrate, got:", out)
+	}
+
+	out.Reset()
+	if err = storeCommand(cfg, []string{"reindex"}, nil, out); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := out.String(), "Rebuilt store indexes\n"; exp != got {
+		t.Errorf("Expected: %q, got: %q", exp, got)
+	}
+
+	out.Reset()
+	if err = storeCommand(cfg, []string{"rotatesecrets"}, nil, out); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := out.String(), "Re-encrypted 0 secrets\n"; exp != got {
+		t.Errorf("Expected: %q, got: %q", exp, got)
+	}
+}
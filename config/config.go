@@ -8,12 +8,55 @@ An example configuration looks like this:
 	# defined here and all servers will use those values unless they have their
 	# own defined.
 	storefile = "/path/to/store/file.db"
+	# The database used for the storefile, one of: kv, bolt, mem
+	storebackend = "kv"
+	# How many users the store caches, and how many seconds each is cached
+	# for. A ttl of 0 keeps users cached until they're evicted to make room.
+	storecachesize = 1000
+	storecachettl = 0
+	# Authentications survive restarts, this is the most seconds one can
+	# last for. 0 lets them last until a logout or the user is not seen.
+	sessionlifetime = 604800
+	# How passwords are hashed, one of: bcrypt, argon2id, scrypt. The cost
+	# is the bcrypt cost (4-31), argon2id time (at most 16) or scrypt log2 N
+	# (at most 20), 0 is the default. Passwords are rehashed when a user
+	# next authenticates.
+	passwordhasher = "bcrypt"
+	passwordcost = 0
+	# The shortest password register and passwd accept.
+	passwordminlength = 6
+	# Changes to users' access are kept in an audit log, entries are kept
+	# for at most this many seconds and this many entries. 0 is no limit.
+	auditmaxage = 0
+	auditmaxentries = 10000
+	# Snapshots of the store are taken into backupdir every backupinterval
+	# seconds, 0 only takes them when asked. The newest backupkeep are kept
+	# and none older than backupmaxage seconds, 0 is no limit.
+	backupdir = "/path/to/backups"
+	backupinterval = 86400
+	backupkeep = 7
+	backupmaxage = 0
+	# The export and import commands only use files in exportdir, they're
+	# refused if it's not set.
+	exportdir = "/path/to/exports"
+	# Notice users when access that was given for a duration expires.
+	expirynotice = false
 	nocorecmds = false
 	loglevel = "debug"
 	logfile = "/path/to/file.log"
+	# secret_key encrypts the secrets extensions store, when it's changed
+	# the keys it replaces go in old_secret_keys until the rotatesecrets
+	# command has re-encrypted them.
 	secret_key = "myunbelievablylongandsecrettoken"
+	old_secret_keys = ["theonebefore"]
 	ignores = ["hostsuffix"]
 
+	# Roles are named levels and flags that can be given to users per
+	# network or channel, as well as the roles made with the setrole command.
+	[roles.op]
+		level = 100
+		flags = "o"
+
 	# Most of the configuration values below have healthy defaults which means
 	# you don't have to set any of them. servers, nick, username, realname is
 	# enough!
@@ -26,6 +69,9 @@ An example configuration looks like this:
 		realname = "Realname"
 		password = "Password"
 
+		# User modes to set on the bot once it has connected.
+		usermodes = "+iB"
+
 		# TLS Options
 		# If tls is on it will connect with tls.
 		# If tls_cert, tls_key are set it will send it in an attempt to perform
@@ -40,6 +86,9 @@ An example configuration looks like this:
 		# Bot Internal Database Options
 		nostate = false
 		nostore = false
+		# Access granted globally doesn't apply on this network when set,
+		# only access granted on the network or its channels does.
+		noglobalaccess = false
 
 		# Auto(Re)Join controls.
 		noautojoin = false
@@ -123,6 +172,11 @@ An example configuration looks like this:
 		tls_cert     = "/path/to/a.crt"
 		noverifycert = false
 
+		# An extension that connects with a verified client certificate whose
+		# common name is its name may change the store and read secrets
+		# through the api when storeadmin is set.
+		storeadmin = false
+
 		[exts.myext.active]
 			ircnet = ["#channel1", "#channel2"]
 
@@ -144,6 +198,37 @@ const (
 	// defaultStoreFile is where the bot will store it'n Store database if not
 	// overridden.
 	defaultStoreFile = "./store.db"
+	// defaultStoreBackend is the database the bot uses for it's Store.
+	defaultStoreBackend = "kv"
+	// defaultStoreCacheSize is how many users the Store caches.
+	defaultStoreCacheSize = uint(1000)
+	// defaultStoreCacheTTL is how many seconds the Store caches a user for,
+	// 0 caches users until they're evicted to make room.
+	defaultStoreCacheTTL = uint(0)
+	// defaultSessionLifetime is how many seconds an authentication lasts
+	// regardless of activity, 0 means forever.
+	defaultSessionLifetime = uint(0)
+	// defaultPasswordHasher is the algorithm passwords are hashed with.
+	defaultPasswordHasher = "bcrypt"
+	// defaultPasswordCost is the cost of the password hasher, 0 uses the
+	// hasher's own default.
+	defaultPasswordCost = uint(0)
+	// defaultPasswordMinLength is the shortest password users may choose.
+	defaultPasswordMinLength = uint(6)
+	// defaultAuditMaxAge is how many seconds audit entries are kept for, 0
+	// keeps them forever.
+	defaultAuditMaxAge = uint(0)
+	// defaultAuditMaxEntries is how many audit entries are kept, 0 keeps
+	// them all.
+	defaultAuditMaxEntries = uint(10000)
+	// defaultBackupInterval is how many seconds are between snapshots of
+	// the store, 0 only takes them when asked.
+	defaultBackupInterval = uint(0)
+	// defaultBackupKeep is how many snapshots of the store are kept.
+	defaultBackupKeep = uint(7)
+	// defaultBackupMaxAge is how many seconds snapshots of the store are kept
+	// for, 0 keeps them until there are too many.
+	defaultBackupMaxAge = uint(0)
 	// defaultLogLevel is the log level of the bot.
 	defaultLogLevel = "info"
 	// defaultJoinDelay is how many seconds to wait before auto (re)joining a
@@ -320,6 +405,58 @@ func (c *Config) Exts() []string {
 	return rets
 }
 
+// Role is a named level and flags defined in the config.
+type Role struct {
+	Level uint
+	Flags string
+}
+
+// Roles returns the configured roles by name.
+func (c *Config) Roles() map[string]Role {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	roles := c.values.get("roles")
+	if roles == nil {
+		return nil
+	}
+
+	rets := make(map[string]Role, len(roles))
+	for name, val := range roles {
+		m := intfToMp(val)
+		if m == nil {
+			continue
+		}
+
+		var role Role
+		switch v := m["level"].(type) {
+		case int64: // After a toml parse.
+			role.Level = uint(v)
+		case uint: // After a set.
+			role.Level = v
+		}
+		if flags, ok := m["flags"].(string); ok {
+			role.Flags = flags
+		}
+		rets[name] = role
+	}
+
+	return rets
+}
+
+// SetRole sets a role's level and flags.
+func (c *Config) SetRole(name string, role Role) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	roles := c.values.ensure("roles")
+	roles[name] = map[string]interface{}{
+		"level": role.Level,
+		"flags": role.Flags,
+	}
+	return c
+}
+
 // DisplayErrors is a helper function to log the output of all config errors to
 // the standard logger.
 func (c *Config) DisplayErrors(logger log15.Logger) {
@@ -365,6 +502,325 @@ func (c *Config) SetStoreFile(val string) *Config {
 	return c
 }
 
+// StoreBackend gets the global storebackend or defaultStoreBackend.
+func (c *Config) StoreBackend() (string, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	if val, ok := c.values["storebackend"]; ok {
+		if backend, ok := val.(string); ok {
+			return backend, true
+		}
+	}
+	return defaultStoreBackend, false
+}
+
+// SetStoreBackend sets the global storebackend.
+func (c *Config) SetStoreBackend(val string) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["storebackend"] = interface{}(val)
+	return c
+}
+
+// StoreCacheSize gets the global storecachesize or defaultStoreCacheSize.
+func (c *Config) StoreCacheSize() (uint, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	switch v := c.values["storecachesize"].(type) {
+	case int64: // After a toml parse.
+		return uint(v), true
+	case uint: // After a set.
+		return v, true
+	}
+	return defaultStoreCacheSize, false
+}
+
+// SetStoreCacheSize sets the global storecachesize.
+func (c *Config) SetStoreCacheSize(val uint) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["storecachesize"] = interface{}(val)
+	return c
+}
+
+// StoreCacheTTL gets the global storecachettl or defaultStoreCacheTTL.
+func (c *Config) StoreCacheTTL() (uint, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	switch v := c.values["storecachettl"].(type) {
+	case int64: // After a toml parse.
+		return uint(v), true
+	case uint: // After a set.
+		return v, true
+	}
+	return defaultStoreCacheTTL, false
+}
+
+// SetStoreCacheTTL sets the global storecachettl.
+func (c *Config) SetStoreCacheTTL(val uint) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["storecachettl"] = interface{}(val)
+	return c
+}
+
+// SessionLifetime gets the global sessionlifetime or defaultSessionLifetime.
+func (c *Config) SessionLifetime() (uint, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	switch v := c.values["sessionlifetime"].(type) {
+	case int64: // After a toml parse.
+		return uint(v), true
+	case uint: // After a set.
+		return v, true
+	}
+	return defaultSessionLifetime, false
+}
+
+// SetSessionLifetime sets the global sessionlifetime.
+func (c *Config) SetSessionLifetime(val uint) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["sessionlifetime"] = interface{}(val)
+	return c
+}
+
+// PasswordHasher gets the global passwordhasher or defaultPasswordHasher.
+func (c *Config) PasswordHasher() (string, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	if val, ok := c.values["passwordhasher"]; ok {
+		if hasher, ok := val.(string); ok {
+			return hasher, true
+		}
+	}
+	return defaultPasswordHasher, false
+}
+
+// SetPasswordHasher sets the global passwordhasher.
+func (c *Config) SetPasswordHasher(val string) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["passwordhasher"] = interface{}(val)
+	return c
+}
+
+// PasswordCost gets the global passwordcost or defaultPasswordCost.
+func (c *Config) PasswordCost() (uint, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	switch v := c.values["passwordcost"].(type) {
+	case int64: // After a toml parse.
+		return uint(v), true
+	case uint: // After a set.
+		return v, true
+	}
+	return defaultPasswordCost, false
+}
+
+// SetPasswordCost sets the global passwordcost.
+func (c *Config) SetPasswordCost(val uint) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["passwordcost"] = interface{}(val)
+	return c
+}
+
+// PasswordMinLength gets the global passwordminlength or
+// defaultPasswordMinLength.
+func (c *Config) PasswordMinLength() (uint, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	switch v := c.values["passwordminlength"].(type) {
+	case int64: // After a toml parse.
+		return uint(v), true
+	case uint: // After a set.
+		return v, true
+	}
+	return defaultPasswordMinLength, false
+}
+
+// SetPasswordMinLength sets the global passwordminlength.
+func (c *Config) SetPasswordMinLength(val uint) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["passwordminlength"] = interface{}(val)
+	return c
+}
+
+// AuditMaxAge gets the global auditmaxage or defaultAuditMaxAge.
+func (c *Config) AuditMaxAge() (uint, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	switch v := c.values["auditmaxage"].(type) {
+	case int64: // After a toml parse.
+		return uint(v), true
+	case uint: // After a set.
+		return v, true
+	}
+	return defaultAuditMaxAge, false
+}
+
+// SetAuditMaxAge sets the global auditmaxage.
+func (c *Config) SetAuditMaxAge(val uint) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["auditmaxage"] = interface{}(val)
+	return c
+}
+
+// AuditMaxEntries gets the global auditmaxentries or defaultAuditMaxEntries.
+func (c *Config) AuditMaxEntries() (uint, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	switch v := c.values["auditmaxentries"].(type) {
+	case int64: // After a toml parse.
+		return uint(v), true
+	case uint: // After a set.
+		return v, true
+	}
+	return defaultAuditMaxEntries, false
+}
+
+// SetAuditMaxEntries sets the global auditmaxentries.
+func (c *Config) SetAuditMaxEntries(val uint) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["auditmaxentries"] = interface{}(val)
+	return c
+}
+
+// BackupDir gets the global backupdir.
+func (c *Config) BackupDir() (string, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	if val, ok := c.values["backupdir"]; ok {
+		if backupdir, ok := val.(string); ok {
+			return backupdir, true
+		}
+	}
+	return "", false
+}
+
+// SetBackupDir sets the global backupdir.
+func (c *Config) SetBackupDir(val string) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["backupdir"] = interface{}(val)
+	return c
+}
+
+// ExportDir gets the global exportdir.
+func (c *Config) ExportDir() (string, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	if val, ok := c.values["exportdir"]; ok {
+		if exportdir, ok := val.(string); ok {
+			return exportdir, true
+		}
+	}
+	return "", false
+}
+
+// SetExportDir sets the global exportdir.
+func (c *Config) SetExportDir(val string) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["exportdir"] = interface{}(val)
+	return c
+}
+
+// BackupInterval gets the global backupinterval or defaultBackupInterval.
+func (c *Config) BackupInterval() (uint, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	switch v := c.values["backupinterval"].(type) {
+	case int64: // After a toml parse.
+		return uint(v), true
+	case uint: // After a set.
+		return v, true
+	}
+	return defaultBackupInterval, false
+}
+
+// SetBackupInterval sets the global backupinterval.
+func (c *Config) SetBackupInterval(val uint) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["backupinterval"] = interface{}(val)
+	return c
+}
+
+// BackupKeep gets the global backupkeep or defaultBackupKeep.
+func (c *Config) BackupKeep() (uint, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	switch v := c.values["backupkeep"].(type) {
+	case int64: // After a toml parse.
+		return uint(v), true
+	case uint: // After a set.
+		return v, true
+	}
+	return defaultBackupKeep, false
+}
+
+// SetBackupKeep sets the global backupkeep.
+func (c *Config) SetBackupKeep(val uint) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["backupkeep"] = interface{}(val)
+	return c
+}
+
+// BackupMaxAge gets the global backupmaxage or defaultBackupMaxAge.
+func (c *Config) BackupMaxAge() (uint, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	switch v := c.values["backupmaxage"].(type) {
+	case int64: // After a toml parse.
+		return uint(v), true
+	case uint: // After a set.
+		return v, true
+	}
+	return defaultBackupMaxAge, false
+}
+
+// SetBackupMaxAge sets the global backupmaxage.
+func (c *Config) SetBackupMaxAge(val uint) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["backupmaxage"] = interface{}(val)
+	return c
+}
+
 // LogFile gets the global logfile or defaultLogFile.
 func (c *Config) LogFile() (string, bool) {
 	c.protect.RLock()
@@ -431,6 +887,28 @@ func (c *Config) SetNoCoreCmds(val bool) *Config {
 	return c
 }
 
+// ExpiryNotice gets the value of the expirynotice variable.
+func (c *Config) ExpiryNotice() (bool, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	if val, ok := c.values["expirynotice"]; ok {
+		if notice, ok := val.(bool); ok {
+			return notice, true
+		}
+	}
+	return false, false
+}
+
+// SetExpiryNotice sets the value of the expirynotice variable.
+func (c *Config) SetExpiryNotice(val bool) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["expirynotice"] = interface{}(val)
+	return c
+}
+
 // SecretKey gets the value of the secretKey variable
 func (c *Config) SecretKey() (string, bool) {
 	c.protect.RLock()
@@ -454,23 +932,53 @@ func (c *Config) SetSecretKey(key string) *Config {
 	return c
 }
 
+// OldSecretKeys gets the secret keys that secret_key replaced.
+func (c *Config) OldSecretKeys() ([]string, bool) {
+	c.protect.RLock()
+	defer c.protect.RUnlock()
+
+	switch v := c.values["old_secret_keys"].(type) {
+	case []interface{}: // After a toml parse.
+		keys := make([]string, 0, len(v))
+		for _, k := range v {
+			if key, ok := k.(string); ok {
+				keys = append(keys, key)
+			}
+		}
+		return keys, true
+	case []string: // After a set.
+		return append([]string(nil), v...), true
+	}
+	return nil, false
+}
+
+// SetOldSecretKeys sets the secret keys that secret_key replaced.
+func (c *Config) SetOldSecretKeys(keys []string) *Config {
+	c.protect.Lock()
+	defer c.protect.Unlock()
+
+	c.values["old_secret_keys"] = interface{}(append([]string(nil), keys...))
+	return c
+}
+
 // Ignores returns the global set ignores
 func (c *Config) Ignores() ([]string, bool) {
 	c.protect.RLock()
 	defer c.protect.RUnlock()
 
-	var ignores []string
-	if val, ok := c.values["ignores"]; ok {
-		if islice, ok := val.([]interface{}); ok {
-			for _, i := range islice {
-				if s, ok := i.(string); ok {
-					ignores = append(ignores, s)
-				}
+	switch v := c.values["ignores"].(type) {
+	case []interface{}: // After a toml parse.
+		ignores := make([]string, 0, len(v))
+		for _, i := range v {
+			if s, ok := i.(string); ok {
+				ignores = append(ignores, s)
 			}
 		}
+		return ignores, true
+	case []string: // After a set.
+		return append([]string(nil), v...), true
 	}
-
-	return ignores, false
+	return nil, false
 }
 
 // SetIgnores sets the ignores array
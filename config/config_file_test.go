@@ -43,6 +43,10 @@ username = "Username"
 realname = "Realname"
 password = "Password"
 
+[roles.op]
+	level = 100
+	flags = "o"
+
 [networks.noirc]
 	servers = ["lol:3"]
 [networks.ircnet]
@@ -54,6 +58,7 @@ password = "Password"
 
 	nostate = false
 	nostore = false
+	noglobalaccess = true
 
 	noautojoin = false
 	joindelay = 5
@@ -196,6 +201,11 @@ func verifyFakeConfig(t *testing.T, conf *Config) {
 		t.Errorf("Expected: %v, got: %v", expb, got)
 	}
 
+	expb = true
+	if got, ok := net1.NoGlobalAccess(); !ok || expb != got {
+		t.Errorf("Expected: %v, got: %v", expb, got)
+	}
+
 	expb = false
 	if got, ok := net1.NoAutoJoin(); !ok || expb != got {
 		t.Errorf("Expected: %v, got: %v", expb, got)
@@ -150,6 +150,28 @@ func TestConfig_Exts(t *testing.T) {
 	}
 }
 
+func TestConfig_Roles(t *testing.T) {
+	t.Parallel()
+
+	if New().Roles() != nil {
+		t.Error("Expected roles to be empty.")
+	}
+
+	c := New().FromString(configuration)
+	roles := c.Roles()
+	if len(roles) != 1 {
+		t.Fatal("Expected one role, got:", roles)
+	}
+	if r := roles["op"]; r.Level != 100 || r.Flags != "o" {
+		t.Error("Expected op to have level 100 and flags o, got:", r)
+	}
+
+	c.SetRole("voice", Role{Flags: "v"})
+	if r, ok := c.Roles()["voice"]; !ok || r.Level != 0 || r.Flags != "v" {
+		t.Error("Expected voice to be set with flags v, got:", r)
+	}
+}
+
 func TestConfig_Contexts(t *testing.T) {
 	t.Parallel()
 
@@ -214,6 +236,118 @@ func TestConfig_Config_GetSet(t *testing.T) {
 		t.Error("Expected store file to be set, and to get a, got:", v)
 	}
 
+	if v, ok := c.StoreBackend(); ok || v != defaultStoreBackend {
+		t.Error("Expected store backend not to be set, and to get default:", v)
+	}
+	c.SetStoreBackend("bolt")
+	if v, ok := c.StoreBackend(); !ok || v != "bolt" {
+		t.Error("Expected store backend to be set, and to get bolt, got:", v)
+	}
+
+	if v, ok := c.StoreCacheSize(); ok || v != defaultStoreCacheSize {
+		t.Error("Expected store cache size not to be set, and to get default:", v)
+	}
+	c.SetStoreCacheSize(10)
+	if v, ok := c.StoreCacheSize(); !ok || v != 10 {
+		t.Error("Expected store cache size to be set, and to get 10, got:", v)
+	}
+
+	if v, ok := c.StoreCacheTTL(); ok || v != defaultStoreCacheTTL {
+		t.Error("Expected store cache ttl not to be set, and to get default:", v)
+	}
+	c.SetStoreCacheTTL(60)
+	if v, ok := c.StoreCacheTTL(); !ok || v != 60 {
+		t.Error("Expected store cache ttl to be set, and to get 60, got:", v)
+	}
+
+	if v, ok := c.SessionLifetime(); ok || v != defaultSessionLifetime {
+		t.Error("Expected session lifetime not to be set, and to get default:", v)
+	}
+	c.SetSessionLifetime(3600)
+	if v, ok := c.SessionLifetime(); !ok || v != 3600 {
+		t.Error("Expected session lifetime to be set, and to get 3600, got:", v)
+	}
+
+	if v, ok := c.PasswordHasher(); ok || v != defaultPasswordHasher {
+		t.Error("Expected password hasher not to be set, and to get default:", v)
+	}
+	c.SetPasswordHasher("argon2id")
+	if v, ok := c.PasswordHasher(); !ok || v != "argon2id" {
+		t.Error("Expected password hasher to be set, and to get argon2id, got:", v)
+	}
+
+	if v, ok := c.PasswordCost(); ok || v != defaultPasswordCost {
+		t.Error("Expected password cost not to be set, and to get default:", v)
+	}
+	c.SetPasswordCost(12)
+	if v, ok := c.PasswordCost(); !ok || v != 12 {
+		t.Error("Expected password cost to be set, and to get 12, got:", v)
+	}
+
+	if v, ok := c.PasswordMinLength(); ok || v != defaultPasswordMinLength {
+		t.Error("Expected password min length not to be set, and to get default:", v)
+	}
+	c.SetPasswordMinLength(10)
+	if v, ok := c.PasswordMinLength(); !ok || v != 10 {
+		t.Error("Expected password min length to be set, and to get 10, got:", v)
+	}
+
+	if v, ok := c.AuditMaxAge(); ok || v != defaultAuditMaxAge {
+		t.Error("Expected audit max age not to be set, and to get default:", v)
+	}
+	c.SetAuditMaxAge(86400)
+	if v, ok := c.AuditMaxAge(); !ok || v != 86400 {
+		t.Error("Expected audit max age to be set, and to get 86400, got:", v)
+	}
+
+	if v, ok := c.AuditMaxEntries(); ok || v != defaultAuditMaxEntries {
+		t.Error("Expected audit max entries not to be set, and to get default:", v)
+	}
+	c.SetAuditMaxEntries(50)
+	if v, ok := c.AuditMaxEntries(); !ok || v != 50 {
+		t.Error("Expected audit max entries to be set, and to get 50, got:", v)
+	}
+
+	if v, ok := c.BackupDir(); ok || v != "" {
+		t.Error("Expected backup dir not to be set, and to get default:", v)
+	}
+	c.SetBackupDir("backups")
+	if v, ok := c.BackupDir(); !ok || v != "backups" {
+		t.Error("Expected backup dir to be set, and to get backups, got:", v)
+	}
+
+	if v, ok := c.ExportDir(); ok || v != "" {
+		t.Error("Expected export dir not to be set, and to get default:", v)
+	}
+	c.SetExportDir("exports")
+	if v, ok := c.ExportDir(); !ok || v != "exports" {
+		t.Error("Expected export dir to be set, and to get exports, got:", v)
+	}
+
+	if v, ok := c.BackupInterval(); ok || v != defaultBackupInterval {
+		t.Error("Expected backup interval not to be set, and to get default:", v)
+	}
+	c.SetBackupInterval(3600)
+	if v, ok := c.BackupInterval(); !ok || v != 3600 {
+		t.Error("Expected backup interval to be set, and to get 3600, got:", v)
+	}
+
+	if v, ok := c.BackupKeep(); ok || v != defaultBackupKeep {
+		t.Error("Expected backup keep not to be set, and to get default:", v)
+	}
+	c.SetBackupKeep(3)
+	if v, ok := c.BackupKeep(); !ok || v != 3 {
+		t.Error("Expected backup keep to be set, and to get 3, got:", v)
+	}
+
+	if v, ok := c.BackupMaxAge(); ok || v != defaultBackupMaxAge {
+		t.Error("Expected backup max age not to be set, and to get default:", v)
+	}
+	c.SetBackupMaxAge(604800)
+	if v, ok := c.BackupMaxAge(); !ok || v != 604800 {
+		t.Error("Expected backup max age to be set, and to get 604800, got:", v)
+	}
+
 	if v, ok := c.LogFile(); ok || v != "" {
 		t.Error("Expected log file not to be set, and to get default:", v)
 	}
@@ -230,6 +364,14 @@ func TestConfig_Config_GetSet(t *testing.T) {
 		t.Error("Expected log level to be set, and to get a, got:", v)
 	}
 
+	if v, ok := c.ExpiryNotice(); ok || v != false {
+		t.Error("Expected expiry notice not to be set, and to get default:", v)
+	}
+	c.SetExpiryNotice(true)
+	if v, ok := c.ExpiryNotice(); !ok || v != true {
+		t.Error("Expected expiry notice to be set, and to get true, got:", v)
+	}
+
 	if v, ok := c.NoCoreCmds(); ok || v != false {
 		t.Error("Expected no core cmds not to be set, and to get default:", v)
 	}
@@ -245,6 +387,13 @@ func TestConfig_Config_GetSet(t *testing.T) {
 	if v, ok := c.SecretKey(); !ok || v != "a" {
 		t.Error("Expected secret key to be set, and to get a, got:", v)
 	}
+	if v, ok := c.OldSecretKeys(); ok || v != nil {
+		t.Error("Expected old secret keys not to be set, and to get default:", v)
+	}
+	c.SetOldSecretKeys([]string{"b", "c"})
+	if v, ok := c.OldSecretKeys(); !ok || len(v) != 2 || v[0] != "b" || v[1] != "c" {
+		t.Error("Expected old secret keys to be set, got:", v)
+	}
 	if v, ok := c.Ignores(); ok || v != nil {
 		t.Error("Expected ignores not to be set, and to get default:", v)
 	}
@@ -399,6 +399,15 @@ func (e *ExtNormalCTX) SetTLSInsecureSkipVerify(val bool) *ExtNormalCTX {
 	return e
 }
 
+func (e *ExtNormalCTX) StoreAdmin() (bool, bool) {
+	return getBool(e, "storeadmin", false)
+}
+
+func (e *ExtNormalCTX) SetStoreAdmin(val bool) *ExtNormalCTX {
+	setVal(e, "storeadmin", val)
+	return e
+}
+
 func (e *ExtNormalCTX) Unix() (string, bool) {
 	return getStr(e, "unix", false)
 }
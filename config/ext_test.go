@@ -29,6 +29,7 @@ func TestConfig_Ext_GetSet(t *testing.T) {
 	checkExt("Server", "", "serv", "serv2", nil, ext, t)
 	checkExt("TLSCert", "", "cert", "cert2", nil, ext, t)
 	checkExt("TLSInsecureSkipVerify", false, false, true, nil, ext, t)
+	checkExt("StoreAdmin", false, false, true, nil, ext, t)
 	checkExt("Unix", "", "unix", "unix2", nil, ext, t)
 }
 
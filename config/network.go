@@ -124,6 +124,15 @@ func (n *NetCTX) SetTLSInsecureSkipVerify(val bool) *NetCTX {
 	return n
 }
 
+func (n *NetCTX) UserModes() (string, bool) {
+	return getStr(n, "usermodes", true)
+}
+
+func (n *NetCTX) SetUserModes(val string) *NetCTX {
+	setVal(n, "usermodes", val)
+	return n
+}
+
 func (n *NetCTX) NoState() (bool, bool) {
 	return getBool(n, "nostate", true)
 }
@@ -142,6 +151,15 @@ func (n *NetCTX) SetNoStore(val bool) *NetCTX {
 	return n
 }
 
+func (n *NetCTX) NoGlobalAccess() (bool, bool) {
+	return getBool(n, "noglobalaccess", true)
+}
+
+func (n *NetCTX) SetNoGlobalAccess(val bool) *NetCTX {
+	setVal(n, "noglobalaccess", val)
+	return n
+}
+
 func (n *NetCTX) NoAutoJoin() (bool, bool) {
 	return getBool(n, "noautojoin", true)
 }
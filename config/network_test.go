@@ -22,6 +22,8 @@ func TestConfig_Network_GetSet(t *testing.T) {
 
 	check("Password", "", "password1", "password2", glb, net, t)
 
+	check("UserModes", "", "+i", "+iB", glb, net, t)
+
 	check("TLS", false, false, true, glb, net, t)
 
 	check("TLSCACert", "", "tlscert1", "tlscert2", glb, net, t)
@@ -36,6 +38,8 @@ func TestConfig_Network_GetSet(t *testing.T) {
 
 	check("NoStore", false, false, true, glb, net, t)
 
+	check("NoGlobalAccess", false, false, true, glb, net, t)
+
 	check("NoAutoJoin", false, false, true, glb, net, t)
 
 	check("JoinDelay", defaultJoinDelay, uint(20), uint(30),
@@ -15,19 +15,28 @@ type validatorRules struct {
 }
 
 var globalValidator = validatorRules{
-	stringVals: []string{"storefile", "loglevel", "logfile", "secret_key"},
-	mapVals:    []string{"ext", "exts", "networks"},
-	boolVals:   []string{"nocorecmds"},
+	stringVals: []string{
+		"storefile", "storebackend", "loglevel", "logfile", "secret_key",
+		"passwordhasher", "backupdir", "exportdir",
+	},
+	stringSliceVals: []string{"old_secret_keys"},
+	mapVals:         []string{"ext", "exts", "networks", "roles"},
+	boolVals:        []string{"nocorecmds", "expirynotice"},
+	uintVals: []string{
+		"storecachesize", "storecachettl", "sessionlifetime",
+		"passwordcost", "passwordminlength", "auditmaxage",
+		"auditmaxentries", "backupinterval", "backupkeep", "backupmaxage",
+	},
 }
 
 var networkValidator = validatorRules{
 	stringVals: []string{
 		"nick", "altnick", "username", "realname", "password",
-		"tls_ca_cert", "tls_cert", "tls_key", "prefix",
+		"tls_ca_cert", "tls_cert", "tls_key", "prefix", "usermodes",
 	},
 	stringSliceVals: []string{"servers"},
 	boolVals: []string{
-		"nostate", "nostore", "noautojoin",
+		"nostate", "nostore", "noglobalaccess", "noautojoin",
 		"noreconnect", "tls", "tls_insecure_skip_verify",
 	},
 	floatVals:  []string{"floodtimeout", "floodstep", "keepalive"},
@@ -39,6 +48,11 @@ var channelValidator = validatorRules{
 	stringVals: []string{"name", "prefix", "password"},
 }
 
+var roleValidator = validatorRules{
+	stringVals: []string{"flags"},
+	uintVals:   []string{"level"},
+}
+
 var extCommonValidator = validatorRules{
 	boolVals: []string{
 		"noreconnect",
@@ -55,7 +69,7 @@ var extGlobalValidator = validatorRules{
 
 var extNormalValidator = validatorRules{
 	stringVals: []string{"server", "exec", "tls_cert"},
-	boolVals:   []string{"noreconnect", "tls_insecure_skip_verify"},
+	boolVals:   []string{"noreconnect", "tls_insecure_skip_verify", "storeadmin"},
 }
 
 // errList is an array of errors.
@@ -167,6 +181,17 @@ func (c *Config) validateTypes(ers *errList) {
 		}
 	}
 
+	if roles := c.values.get("roles"); roles != nil {
+		for name, roleVal := range roles {
+			if role := intfToMp(roleVal); role == nil {
+				ers.addError("(roles) %s is %T but expected map [%v]",
+					name, roleVal, roleVal)
+			} else {
+				roleValidator.validateMap(name, role, ers)
+			}
+		}
+	}
+
 	if ext := c.values.get("ext"); ext != nil {
 		extCommonValidator.validateMap("ext", ext, ers)
 		extGlobalValidator.validateMap("ext", ext, ers)
@@ -173,12 +173,14 @@ func TestValidation_TypesTopLevel(t *testing.T) {
 	cfg := `
 		networks = 5
 		ext = 5
-		exts = 5`
+		exts = 5
+		roles = 5`
 
 	exps := []texpect{
 		{"global", "networks", "map", "int64"},
 		{"global", "ext", "map", "int64"},
 		{"global", "exts", "map", "int64"},
+		{"global", "roles", "map", "int64"},
 	}
 
 	typesTestHelper(cfg, exps, t)
@@ -202,7 +204,10 @@ func TestValidation_TypesMidLevel(t *testing.T) {
 	myext = 5
 
 	[exts.extension]
-	active = 5`
+	active = 5
+
+	[roles]
+	op = 5`
 
 	exps := []texpect{
 		{"global networks", "noirc", "map", "int64"},
@@ -211,6 +216,7 @@ func TestValidation_TypesMidLevel(t *testing.T) {
 		{"ext", "active", "map", "int64"},
 		{"exts", "myext", "map", "int64"},
 		{"extension", "active", "map", "int64"},
+		{"roles", "op", "map", "int64"},
 	}
 
 	typesTestHelper(cfg, exps, t)
@@ -278,10 +284,26 @@ func TestValidation_TypesLeafs(t *testing.T) {
 
 	cfg := `
 		storefile = 5
+		storebackend = 5
+		storecachesize = "big"
+		storecachettl = "long"
+		sessionlifetime = "forever"
+		passwordhasher = 5
+		passwordcost = "high"
+		passwordminlength = "long"
+		auditmaxage = "old"
+		auditmaxentries = "many"
+		backupdir = 5
+		exportdir = 5
+		backupinterval = "daily"
+		backupkeep = "some"
+		backupmaxage = "old"
 		nocorecmds = "hello"
+		expirynotice = "yes"
 		logfile = 5
 		loglevel = 5
 		secret_key = 5
+		old_secret_keys = 5
 
 		nick = 6
 		altnick = 7
@@ -289,6 +311,10 @@ func TestValidation_TypesLeafs(t *testing.T) {
 		realname = 9
 		password = 10
 
+		[roles.op]
+			level = "high"
+			flags = 5
+
 		[networks.noirc]
 			servers = "farse"
 		[networks.ircnet]
@@ -302,6 +328,7 @@ func TestValidation_TypesLeafs(t *testing.T) {
 
 			nostate = 5
 			nostore = 6
+			noglobalaccess = 7
 
 			noautojoin = 5
 			joindelay = "lol"
@@ -357,6 +384,7 @@ func TestValidation_TypesLeafs(t *testing.T) {
 			server = 5
 			tls_cert = true
 			tls_insecure_skip_verify = "what"
+			storeadmin = "what"
 
 			unix = 5
 
@@ -365,16 +393,35 @@ func TestValidation_TypesLeafs(t *testing.T) {
 
 	exps := []texpect{
 		{"global", "storefile", "string", "int64"},
+		{"global", "storebackend", "string", "int64"},
+		{"global", "storecachesize", "int", "string"},
+		{"global", "storecachettl", "int", "string"},
+		{"global", "sessionlifetime", "int", "string"},
+		{"global", "passwordhasher", "string", "int64"},
+		{"global", "passwordcost", "int", "string"},
+		{"global", "passwordminlength", "int", "string"},
+		{"global", "auditmaxage", "int", "string"},
+		{"global", "auditmaxentries", "int", "string"},
+		{"global", "backupdir", "string", "int64"},
+		{"global", "exportdir", "string", "int64"},
+		{"global", "backupinterval", "int", "string"},
+		{"global", "backupkeep", "int", "string"},
+		{"global", "backupmaxage", "int", "string"},
 		{"global", "nocorecmds", "bool", "string"},
+		{"global", "expirynotice", "bool", "string"},
 		{"global", "loglevel", "string", "int64"},
 		{"global", "logfile", "string", "int64"},
 		{"global", "secret_key", "string", "int64"},
+		{"global", "old_secret_keys", "array", "int64"},
 		{"global", "nick", "string", "int64"},
 		{"global", "altnick", "string", "int64"},
 		{"global", "username", "string", "int64"},
 		{"global", "realname", "string", "int64"},
 		{"global", "password", "string", "int64"},
 
+		{"op", "level", "int", "string"},
+		{"op", "flags", "string", "int64"},
+
 		{"noirc", "servers", "array", "string"},
 
 		{"ircnet", "servers 1", "string", "int64"},
@@ -385,6 +432,7 @@ func TestValidation_TypesLeafs(t *testing.T) {
 		{"ircnet", "tls_insecure_skip_verify", "bool", "string"},
 		{"ircnet", "nostate", "bool", "int64"},
 		{"ircnet", "nostore", "bool", "int64"},
+		{"ircnet", "noglobalaccess", "bool", "int64"},
 		{"ircnet", "noautojoin", "bool", "int64"},
 		{"ircnet", "joindelay", "int", "string"},
 		{"ircnet", "floodlenpenalty", "int", "float64"},
@@ -417,6 +465,7 @@ func TestValidation_TypesLeafs(t *testing.T) {
 		{"myext", "server", "string", "int64"},
 		{"myext", "tls_cert", "string", "bool"},
 		{"myext", "tls_insecure_skip_verify", "bool", "string"},
+		{"myext", "storeadmin", "bool", "string"},
 		{"myext active ircnet", "channel 1", "string", "int64"},
 		{"myext active ircnet", "channel 2", "string", "int64"},
 	}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/aarondl/ultimateq/api"
 )
@@ -19,10 +20,17 @@ const (
 	wholeAlphabet = `ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz`
 )
 
-// Access defines an access level and flags a-zA-Z for a user.
+// Access defines an access level and flags a-zA-Z for a user. It can also
+// deny levels and flags, a deny overrides what's allowed in the same scope
+// and in every less specific one.
 type Access struct {
 	Level uint8  `json:"level"`
 	Flags uint64 `json:"flags"`
+	// DenyLevel denies checks for this level and above, 0 denies nothing.
+	DenyLevel uint8  `json:"deny_level,omitempty"`
+	DenyFlags uint64 `json:"deny_flags,omitempty"`
+	// Expires is the unix time the access is revoked at, 0 is never.
+	Expires int64 `json:"expires,omitempty"`
 }
 
 // NewAccess creates an access type with the permissions.
@@ -95,11 +103,47 @@ func (a *Access) IsZero() bool {
 	return a.Flags == 0 && a.Level == 0
 }
 
+// SetDeny denies a level and above, and the flags. A level of 0 leaves the
+// denied level as it is.
+func (a *Access) SetDeny(level uint8, flags ...string) {
+	if level != 0 {
+		a.DenyLevel = level
+	}
+	a.DenyFlags |= getFlagBits(flags...)
+}
+
+// ClearDeny stops denying the flags, leaving flags empty stops denying
+// anything.
+func (a *Access) ClearDeny(flags ...string) {
+	if len(flags) == 0 {
+		a.DenyLevel = 0
+		a.DenyFlags = 0
+		return
+	}
+	a.DenyFlags &= ^getFlagBits(flags...)
+}
+
+// denyOnly is the access without anything it allows, what's left when the
+// allowed access expires.
+func (a Access) denyOnly() Access {
+	return Access{DenyLevel: a.DenyLevel, DenyFlags: a.DenyFlags}
+}
+
+// HasDeny checks if this instance of access denies anything.
+func (a *Access) HasDeny() bool {
+	return a.DenyLevel != 0 || a.DenyFlags != 0
+}
+
+// Expired checks if the access has an expiry that has passed.
+func (a *Access) Expired(now time.Time) bool {
+	return a.Expires != 0 && now.Unix() >= a.Expires
+}
+
 // String transforms the Access into a human-readable format.
 func (a Access) String() (str string) {
 	hasLevel := a.Level != 0
 	hasFlags := a.Flags != 0
-	if !hasLevel && !hasFlags {
+	if !hasLevel && !hasFlags && !a.HasDeny() {
 		return none
 	}
 	if hasLevel {
@@ -111,6 +155,21 @@ func (a Access) String() (str string) {
 		}
 		str += getFlagString(a.Flags)
 	}
+	if a.HasDeny() {
+		if hasLevel || hasFlags {
+			str += " "
+		}
+		str += "deny"
+		if a.DenyLevel != 0 {
+			str += " " + strconv.Itoa(int(a.DenyLevel))
+		}
+		if a.DenyFlags != 0 {
+			str += " " + getFlagString(a.DenyFlags)
+		}
+	}
+	if a.Expires != 0 {
+		str += " until " + time.Unix(a.Expires, 0).UTC().Format(time.RFC3339)
+	}
 
 	return
 }
@@ -163,12 +222,18 @@ func getFlagString(bits uint64) (flags string) {
 
 func (a Access) ToProto() *api.Access {
 	return &api.Access{
-		Level: uint32(a.Level),
-		Flags: uint64(a.Flags),
+		Level:     uint32(a.Level),
+		Flags:     uint64(a.Flags),
+		Expires:   a.Expires,
+		DenyLevel: uint32(a.DenyLevel),
+		DenyFlags: a.DenyFlags,
 	}
 }
 
 func (a *Access) FromProto(proto *api.Access) {
 	a.Level = uint8(proto.Level)
 	a.Flags = proto.Flags
+	a.Expires = proto.Expires
+	a.DenyLevel = uint8(proto.DenyLevel)
+	a.DenyFlags = proto.DenyFlags
 }
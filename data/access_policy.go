@@ -0,0 +1,180 @@
+package data
+
+import (
+	"strings"
+	"sync"
+)
+
+// AccessDecision explains the outcome of an access check.
+type AccessDecision struct {
+	Allowed bool
+	// Denied is set when a deny rule decided the check, Network and Channel
+	// are the scope of the rule that decided it.
+	Denied  bool
+	Network string
+	Channel string
+	// NoInherit is set when global access would have allowed the check but
+	// the network doesn't inherit global access.
+	NoInherit bool
+}
+
+// policy holds what a store's users resolve their access with and how their
+// passwords are hashed. Each store has its own, and the users read from a
+// store point to it so their checks follow that store.
+type policy struct {
+	protect sync.RWMutex
+	// config are the roles defined in the config, stored are the store's.
+	config map[string]Role
+	stored map[string]Role
+	// noInherit are the networks global access doesn't apply on.
+	noInherit map[string]bool
+	// hasher hashes new passwords, nil is bcrypt. minLength is the shortest
+	// password allowed.
+	hasher    PasswordHasher
+	minLength int
+}
+
+func newPolicy() *policy {
+	return &policy{
+		config:    make(map[string]Role),
+		stored:    make(map[string]Role),
+		noInherit: make(map[string]bool),
+	}
+}
+
+// attach points a user read from the store to the store's policy.
+func (s *Store) attach(user *StoredUser) *StoredUser {
+	if user != nil {
+		user.policy = s.policy
+	}
+	return user
+}
+
+// SetGlobalInheritance sets if global access applies on a network for the
+// store's users, it does on every network unless it's turned off.
+func (s *Store) SetGlobalInheritance(network string, inherit bool) {
+	s.policy.protect.Lock()
+	defer s.policy.protect.Unlock()
+
+	network = strings.ToLower(network)
+	if inherit {
+		delete(s.policy.noInherit, network)
+	} else {
+		s.policy.noInherit[network] = true
+	}
+}
+
+// inheritsGlobal checks if global access applies on a network.
+func (p *policy) inheritsGlobal(network string) bool {
+	if len(network) == 0 || p == nil {
+		return true
+	}
+
+	p.protect.RLock()
+	defer p.protect.RUnlock()
+	return !p.noInherit[strings.ToLower(network)]
+}
+
+// ruleFunc checks a single scope, both are false when the scope has no rule
+// for the check.
+type ruleFunc func(network, channel string) (allow, deny bool)
+
+// resolve walks the scopes of a network and channel from the most specific,
+// network and channel, through channel on any network and network to global.
+// The first scope with a rule decides, and a deny overrides an allow in the
+// same scope.
+func (p *policy) resolve(network, channel string, rule ruleFunc) AccessDecision {
+	type scope struct{ network, channel string }
+
+	scopes := make([]scope, 0, 4)
+	if len(network) > 0 && len(channel) > 0 {
+		scopes = append(scopes, scope{network, channel})
+	}
+	if len(channel) > 0 {
+		scopes = append(scopes, scope{"", channel})
+	}
+	if len(network) > 0 {
+		scopes = append(scopes, scope{network, ""})
+	}
+
+	inherit := p.inheritsGlobal(network)
+	if inherit {
+		scopes = append(scopes, scope{"", ""})
+	}
+
+	for _, sc := range scopes {
+		allow, deny := rule(sc.network, sc.channel)
+		switch {
+		case deny:
+			return AccessDecision{
+				Denied: true, Network: sc.network, Channel: sc.channel,
+			}
+		case allow:
+			return AccessDecision{
+				Allowed: true, Network: sc.network, Channel: sc.channel,
+			}
+		}
+	}
+
+	if !inherit {
+		if allow, deny := rule("", ""); allow && !deny {
+			return AccessDecision{NoInherit: true}
+		}
+	}
+	return AccessDecision{}
+}
+
+// ResolveLevel decides if the user has a level of access in the network and
+// channel provided.
+func (s *StoredUser) ResolveLevel(network, channel string, level uint8) AccessDecision {
+	return s.policy.resolve(network, channel, func(n, c string) (bool, bool) {
+		a, ok := s.EffectiveAccess(n, c)
+		if !ok {
+			return false, false
+		}
+		return a.Level >= level, a.DenyLevel != 0 && level >= a.DenyLevel
+	})
+}
+
+// ResolveFlag decides if the user has a flag in the network and channel
+// provided.
+func (s *StoredUser) ResolveFlag(network, channel string, flag rune) AccessDecision {
+	bit := getFlagBit(flag)
+	return s.policy.resolve(network, channel, func(n, c string) (bool, bool) {
+		a, ok := s.EffectiveAccess(n, c)
+		if !ok || bit == 0 {
+			return false, false
+		}
+		return a.Flags&bit != 0, a.DenyFlags&bit != 0
+	})
+}
+
+// ResolveFlags decides if the user has all of the flags in the network and
+// channel provided, the decision is that of the first flag they don't have.
+func (s *StoredUser) ResolveFlags(network, channel string, flags ...string) (AccessDecision, rune) {
+	decision := AccessDecision{Allowed: true}
+	for _, flagset := range flags {
+		for _, flag := range flagset {
+			if d := s.ResolveFlag(network, channel, flag); !d.Allowed {
+				return d, flag
+			}
+		}
+	}
+	return decision, 0
+}
+
+// ResolvePermission decides if the user has a permission in the network and
+// channel provided. Permissions granted with a leading - deny it.
+func (s *StoredUser) ResolvePermission(network, channel, perm string) AccessDecision {
+	perm = strings.ToLower(perm)
+	return s.policy.resolve(network, channel, func(n, c string) (allow, deny bool) {
+		for _, p := range s.Permissions[mkKey(n, c)] {
+			if strings.HasPrefix(p, "-") {
+				deny = deny || permissionMatches(p[1:], perm)
+			} else {
+				allow = allow || permissionMatches(p, perm)
+			}
+		}
+		return allow, deny
+	})
+}
@@ -0,0 +1,167 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStoredUser_DenyOverrides(t *testing.T) {
+	t.Parallel()
+	s := createStoredUser()
+
+	s.Grant("", "", 100, "abc")
+	if !s.HasLevel(network, channel, 100) || !s.HasFlags(network, channel, "abc") {
+		t.Error("Expected global access to apply everywhere.")
+	}
+
+	s.Deny(network, "", 50, "b")
+	if s.HasLevel(network, channel, 50) || !s.HasLevel(network, channel, 49) {
+		t.Error("Expected levels of 50 and above to be denied on the network.")
+	}
+	if !s.HasLevel("other", channel, 100) || !s.HasFlags("other", "", "b") {
+		t.Error("Expected the deny to only apply on the network.")
+	}
+
+	d, flag := s.ResolveFlags(network, channel, "abc")
+	if d.Allowed || !d.Denied || flag != 'b' {
+		t.Error("Expected b to be denied, got:", d, string(flag))
+	}
+	if d.Network != network || len(d.Channel) != 0 {
+		t.Error("Expected the network's deny to decide, got:", d)
+	}
+
+	s.Grant(network, channel, 0, "b")
+	if !s.HasFlags(network, channel, "abc") {
+		t.Error("Expected the channel's grant to be more specific than the deny.")
+	}
+
+	s.Deny(network, channel, 0, "b")
+	if s.HasFlags(network, channel, "b") {
+		t.Error("Expected a deny to override a grant in the same scope.")
+	}
+	if s.Has(network, channel, 1, "b") || !s.Has(network, channel, 1, "ab") {
+		t.Error("Expected Has to need any flag that isn't denied.")
+	}
+
+	s.RevokeDeny(network, channel, "b")
+	if !s.HasFlags(network, channel, "b") {
+		t.Error("Expected the deny to be gone.")
+	}
+
+	s.RevokeDenyLevel(network, "")
+	if !s.HasLevel(network, channel, 100) {
+		t.Error("Expected the level deny to be gone.")
+	}
+	if s.HasFlags(network, "", "b") {
+		t.Error("Expected the flag deny to stay.")
+	}
+
+	s.RevokeDeny(network, "")
+	if !s.HasFlags(network, "", "b") {
+		t.Error("Expected nothing to be denied.")
+	}
+}
+
+func TestStoredUser_DenyExpiry(t *testing.T) {
+	t.Parallel()
+	s := createStoredUser()
+
+	s.Grant(network, "", 10)
+	s.Deny(network, "", 0, "a")
+	s.SetExpiry(network, "", time.Now().Add(-time.Second))
+
+	if expired := s.RevokeExpired(time.Now()); len(expired) != 1 {
+		t.Error("Expected the access to expire, got:", expired)
+	}
+	a, ok := s.GetAccess(network, "")
+	if !ok || !a.IsZero() || a.DenyFlags != getFlagBits("a") {
+		t.Error("Expected the deny to outlive the access, got:", a)
+	}
+}
+
+func TestStoredUser_DenyExpiredAllow(t *testing.T) {
+	t.Parallel()
+	s := createStoredUser()
+
+	s.Grant("", "", 100, "b")
+	s.Grant(network, channel, 10)
+	s.Deny(network, channel, 50, "b")
+	s.SetExpiry(network, channel, time.Now().Add(-time.Second))
+
+	// The allow has expired but hasn't been reaped, the deny still applies.
+	a, ok := s.GetAccess(network, channel)
+	if !ok || !a.IsZero() || a.DenyLevel != 50 {
+		t.Error("Expected only the deny, got:", a, ok)
+	}
+	if s.HasLevel(network, channel, 50) || s.HasFlags(network, channel, "b") {
+		t.Error("Expected the global access not to override the deny.")
+	}
+	if !s.HasLevel(network, channel, 49) {
+		t.Error("Expected the global access below the deny to apply.")
+	}
+}
+
+func TestStoredUser_GlobalInheritance(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	s := store.attach(createStoredUser())
+	s.Grant("", "", 100)
+	s.Grant(network, "", 10)
+
+	store.SetGlobalInheritance(network, false)
+
+	if !s.HasLevel("other", "", 100) {
+		t.Error("Expected other networks to inherit global access.")
+	}
+	if !s.HasLevel(network, channel, 10) {
+		t.Error("Expected network access to apply.")
+	}
+
+	d := s.ResolveLevel(network, channel, 100)
+	if d.Allowed || !d.NoInherit {
+		t.Error("Expected global access not to apply, got:", d)
+	}
+
+	loose := createStoredUser()
+	loose.Grant("", "", 100)
+	if !loose.HasLevel(network, channel, 100) {
+		t.Error("Expected users from outside a store to inherit global access.")
+	}
+
+	store.SetGlobalInheritance(network, true)
+	if !s.HasLevel(network, channel, 100) {
+		t.Error("Expected global access to apply again.")
+	}
+}
+
+func TestStoredUser_DenyPermission(t *testing.T) {
+	t.Parallel()
+	s := createStoredUser()
+
+	s.GrantPermission("", "", "quotes.*")
+	if !s.GrantPermission(network, "", "-quotes.delete") {
+		t.Error("Expected to grant the deny.")
+	}
+
+	if !s.HasPermission(network, channel, "quotes.add") {
+		t.Error("Expected the namespace to be granted.")
+	}
+	d := s.ResolvePermission(network, channel, "quotes.delete")
+	if d.Allowed || !d.Denied || d.Network != network {
+		t.Error("Expected the permission to be denied, got:", d)
+	}
+	if !s.HasPermission("other", "", "quotes.delete") {
+		t.Error("Expected the deny to only apply on the network.")
+	}
+
+	s.GrantPermission(network, channel, "quotes.delete")
+	if !s.HasPermission(network, channel, "quotes.delete") {
+		t.Error("Expected the channel's grant to be more specific.")
+	}
+}
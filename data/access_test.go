@@ -166,6 +166,44 @@ func TestAccess_String(t *testing.T) {
 			t.Errorf("Expected: %s, was: %s", test.Expect, was)
 		}
 	}
+
+	a := NewAccess(100, "a")
+	a.SetDeny(200, "bc")
+	if was := a.String(); was != "100 a deny 200 bc" {
+		t.Error("Wrong deny string:", was)
+	}
+	a = NewAccess(0)
+	a.SetDeny(0, "b")
+	if was := a.String(); was != "deny b" {
+		t.Error("Wrong deny string:", was)
+	}
+}
+
+func TestAccess_Deny(t *testing.T) {
+	t.Parallel()
+
+	a := NewAccess(0)
+	a.SetDeny(50, "ab")
+	if !a.HasDeny() || a.DenyLevel != 50 || a.DenyFlags != getFlagBits("ab") {
+		t.Error("Expected the deny to be set:", a)
+	}
+	if !a.IsZero() {
+		t.Error("Expected a deny not to grant anything.")
+	}
+
+	a.SetDeny(0, "c")
+	if a.DenyLevel != 50 {
+		t.Error("Expected a level of 0 to leave the denied level.")
+	}
+
+	a.ClearDeny("a")
+	if a.DenyFlags != getFlagBits("bc") {
+		t.Error("Expected only a to be cleared:", a)
+	}
+	a.ClearDeny()
+	if a.HasDeny() {
+		t.Error("Expected nothing to be denied:", a)
+	}
 }
 
 func Test_getFlagBits(t *testing.T) {
@@ -253,6 +291,7 @@ func TestAccess_Protofy(t *testing.T) {
 	t.Parallel()
 
 	a := NewAccess(23, "DEFabc")
+	a.SetDeny(100, "z")
 	var b Access
 
 	b.FromProto(a.ToProto())
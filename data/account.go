@@ -0,0 +1,53 @@
+package data
+
+import (
+	"strings"
+)
+
+// AccountUser gets the user that a host identified to a services account on
+// a network authenticates as. It's nil if the account isn't linked or the
+// link requires a mask the host doesn't match.
+func (s *Store) AccountUser(network, host, account string) *StoredUser {
+	if len(account) == 0 {
+		return nil
+	}
+
+	user, err := s.UserByAccount(network, account)
+	if err != nil || user == nil {
+		return nil
+	}
+	if !user.AuthenticatesAccount(network, host, account) {
+		return nil
+	}
+	return user
+}
+
+// setAccount records the services account a host is identified to, an empty
+// account forgets it.
+// warning: Assumes the store is locked
+func (s *Store) setAccount(network, host, account string) {
+	if len(account) == 0 {
+		delete(s.accounts, network+host)
+		return
+	}
+	s.accounts[network+host] = strings.ToLower(account)
+}
+
+// moveAccount moves the services account of a host when it changes nick.
+// warning: Assumes the store is locked
+func (s *Store) moveAccount(network, oldHost, newHost string) {
+	if account, ok := s.accounts[network+oldHost]; ok {
+		delete(s.accounts, network+oldHost)
+		s.accounts[network+newHost] = account
+	}
+}
+
+// accountUser is AuthedUser for hosts authenticated by a services account.
+// warning: Assumes the store is locked
+func (s *Store) accountUser(network, host string) *StoredUser {
+	account, ok := s.accounts[network+host]
+	if !ok {
+		return nil
+	}
+	return s.AccountUser(network, host, account)
+}
@@ -0,0 +1,74 @@
+package data
+
+import (
+	"testing"
+)
+
+func TestStore_AccountAuth(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	user, err := NewStoredUser(uname, password, "*!*@host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.LinkAccount(network, "Acct", false)
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	if found, err := s.UserByAccount(network, "ACCT"); err != nil || found == nil {
+		t.Fatal("Expected to find the user by account:", err)
+	}
+	if found, _ := s.UserByAccount("othernet", "acct"); found != nil {
+		t.Error("Expected accounts to be per network, got:", found)
+	}
+
+	if s.AuthedUser(network, host) != nil {
+		t.Error("Expected an unknown account not to be authed.")
+	}
+	if got := s.AccountUser(network, host, "acct"); got == nil || got.Username != uname {
+		t.Error("Expected the account to authenticate, got:", got)
+	}
+
+	s.Update(network, StateUpdate{Account: []string{host, "acct"}})
+	if got := s.AuthedUser(network, host); got == nil || got.Username != uname {
+		t.Error("Expected the identified host to be authed, got:", got)
+	}
+
+	newHost := "newnick!user@host"
+	s.Update(network, StateUpdate{Nick: []string{host, newHost}})
+	if s.AuthedUser(network, host) != nil || s.AuthedUser(network, newHost) == nil {
+		t.Error("Expected the account to follow the nick change.")
+	}
+
+	// Requiring a mask stops hosts that don't match.
+	user.LinkAccount(network, "acct", true)
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if s.AccountUser(network, "nick!user@elsewhere", "acct") != nil {
+		t.Error("Expected a host not matching a mask to be refused.")
+	}
+	if s.AuthedUser(network, newHost) == nil {
+		t.Error("Expected a host matching a mask to be authed.")
+	}
+
+	s.Update(network, StateUpdate{Account: []string{newHost, ""}})
+	if s.AuthedUser(network, newHost) != nil {
+		t.Error("Expected logging out of services to unauthenticate.")
+	}
+
+	user.UnlinkAccount(network)
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := s.UserByAccount(network, "acct"); found != nil {
+		t.Error("Expected the unlinked account to be unindexed, got:", found)
+	}
+}
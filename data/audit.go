@@ -0,0 +1,319 @@
+package data
+
+import (
+	"encoding/binary"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// recordAudit is the kind of record an audit entry is stored as.
+	recordAudit byte = 'a'
+)
+
+var (
+	// auditPrefix begins the key of every audit entry. The rest of the key
+	// is the time and sequence of the entry so they scan in order.
+	auditPrefix = []byte("\x00audit:")
+)
+
+// AuditEntry is a change to a user's privileges. The log of them is append
+// only, entries are only removed once they're past the retention.
+type AuditEntry struct {
+	Time time.Time
+	// Actor is the username of who made the change, it's empty when they
+	// weren't authenticated.
+	Actor string
+	// Source is where the change came from, the nick!user@host of an irc
+	// user or ext:name for an extension.
+	Source string
+	// Action is the name of the command or rpc that made the change.
+	Action string
+	Target string
+
+	Network string
+	Channel string
+	// Before and After are what changed, the access in the scope or the
+	// mask or certificate that was added or removed.
+	Before string
+	After  string
+}
+
+// AuditQuery filters the audit log. Empty fields match anything and the
+// strings match without regard to case.
+type AuditQuery struct {
+	Actor  string
+	Target string
+	// User matches either the actor or the target.
+	User    string
+	Action  string
+	Network string
+	Channel string
+	Since   time.Time
+	// Limit is how many of the newest matches to return, 0 returns all.
+	Limit int
+}
+
+// matches checks if an entry passes the query.
+func (q AuditQuery) matches(e *AuditEntry) bool {
+	switch {
+	case len(q.Actor) != 0 && !strings.EqualFold(q.Actor, e.Actor),
+		len(q.Target) != 0 && !strings.EqualFold(q.Target, e.Target),
+		len(q.User) != 0 && !strings.EqualFold(q.User, e.Actor) &&
+			!strings.EqualFold(q.User, e.Target),
+		len(q.Action) != 0 && !strings.EqualFold(q.Action, e.Action),
+		len(q.Network) != 0 && !strings.EqualFold(q.Network, e.Network),
+		len(q.Channel) != 0 && !strings.EqualFold(q.Channel, e.Channel),
+		!q.Since.IsZero() && e.Time.Before(q.Since):
+		return false
+	}
+	return true
+}
+
+// AuditAccessChanges makes an entry from entry for each scope where the
+// access of before and after differ. Either may be nil for a user that's
+// being created or deleted.
+func AuditAccessChanges(entry AuditEntry, before, after *StoredUser) []AuditEntry {
+	scopes := make(map[string]bool)
+	for _, u := range []*StoredUser{before, after} {
+		if u == nil {
+			continue
+		}
+		for key := range u.Access {
+			scopes[key] = true
+		}
+	}
+
+	keys := make([]string, 0, len(scopes))
+	for key := range scopes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var entries []AuditEntry
+	for _, key := range keys {
+		var was, is string
+		if before != nil {
+			if a, ok := before.Access[key]; ok {
+				was = a.String()
+			}
+		}
+		if after != nil {
+			if a, ok := after.Access[key]; ok {
+				is = a.String()
+			}
+		}
+		if was == is {
+			continue
+		}
+
+		e := entry
+		scope := strings.SplitN(key, ":", 2)
+		e.Network, e.Channel = scope[0], scope[1]
+		e.Before, e.After = was, is
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// auditState tracks the size of the audit log so it's only scanned for
+// pruning once it's past the retention.
+type auditState struct {
+	protect sync.Mutex
+	seq     uint64
+	count   int
+	// oldest is the time of the oldest entry, zero if there are none.
+	oldest time.Time
+
+	maxAge     time.Duration
+	maxEntries int
+}
+
+// SetAuditRetention sets how long audit entries are kept for and how many
+// are kept at most, 0 for either means no limit.
+func (s *Store) SetAuditRetention(maxAge time.Duration, maxEntries int) error {
+	s.auditState.protect.Lock()
+	defer s.auditState.protect.Unlock()
+
+	s.auditState.maxAge = maxAge
+	s.auditState.maxEntries = maxEntries
+	return s.pruneAudit()
+}
+
+// Audit appends entries to the audit log, entries without a time are stamped
+// with the current time. Changes to the store should be audited by the
+// method that makes them so the entries are written with the change.
+func (s *Store) Audit(entries ...AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	err := s.db.Update(func(tx Tx) error {
+		return s.auditTx(tx, entries)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.audited(entries)
+}
+
+// auditTx writes entries to the audit log inside a transaction, entries
+// without a time are stamped. Once it's committed audited must be called.
+func (s *Store) auditTx(tx Tx, entries []AuditEntry) error {
+	now := time.Now().UTC()
+	for i := range entries {
+		e := &entries[i]
+		if e.Time.IsZero() {
+			e.Time = now
+		}
+
+		record, err := encodeRecord(recordAudit, e)
+		if err != nil {
+			return err
+		}
+
+		s.auditState.protect.Lock()
+		s.auditState.seq++
+		seq := s.auditState.seq
+		s.auditState.protect.Unlock()
+
+		if err = tx.Put(auditKey(e.Time, seq), record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// audited counts entries that were committed to the audit log and prunes it
+// if it's grown past the retention.
+func (s *Store) audited(entries []AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	s.auditState.protect.Lock()
+	defer s.auditState.protect.Unlock()
+
+	s.auditState.count += len(entries)
+	for _, e := range entries {
+		if s.auditState.oldest.IsZero() || e.Time.Before(s.auditState.oldest) {
+			s.auditState.oldest = e.Time
+		}
+	}
+	return s.pruneAudit()
+}
+
+// loadAudit counts the entries in the audit log and finds the oldest.
+func (s *Store) loadAudit() error {
+	s.auditState.protect.Lock()
+	defer s.auditState.protect.Unlock()
+
+	s.auditState.count = 0
+	s.auditState.oldest = time.Time{}
+	return s.db.Scan(auditPrefix, func(key, _ []byte) bool {
+		if s.auditState.count == 0 {
+			s.auditState.oldest = auditKeyTime(key)
+		}
+		s.auditState.count++
+		return true
+	})
+}
+
+// AuditLog returns the entries that match the query, oldest first.
+func (s *Store) AuditLog(query AuditQuery) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	var errs RecordErrors
+
+	err := s.db.Scan(auditPrefix, func(key, val []byte) bool {
+		var e AuditEntry
+		if err := decodeRecord(recordAudit, val, &e); err != nil {
+			errs = append(errs, RecordError{Key: string(key), Err: err})
+			return true
+		}
+		if query.matches(&e) {
+			entries = append(entries, e)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[len(entries)-query.Limit:]
+	}
+	if len(errs) != 0 {
+		return entries, errs
+	}
+	return entries, nil
+}
+
+// auditKey is the key of an audit entry, the sequence keeps entries made
+// at the same time apart.
+func auditKey(t time.Time, seq uint64) []byte {
+	key := make([]byte, len(auditPrefix)+16)
+	copy(key, auditPrefix)
+	binary.BigEndian.PutUint64(key[len(auditPrefix):], uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(key[len(auditPrefix)+8:], seq)
+	return key
+}
+
+// auditKeyTime is the time an audit entry was keyed with.
+func auditKeyTime(key []byte) time.Time {
+	return time.Unix(0,
+		int64(binary.BigEndian.Uint64(key[len(auditPrefix):]))).UTC()
+}
+
+// pruneAudit deletes the entries that are past the retention. The log is only
+// scanned when there are too many entries or the oldest is too old, and the
+// scan stops at the first entry that's kept.
+// warning: Assumes the audit state is locked
+func (s *Store) pruneAudit() error {
+	state := &s.auditState
+
+	var remove int
+	if state.maxEntries > 0 && state.count > state.maxEntries {
+		remove = state.count - state.maxEntries
+	}
+	var cutoff time.Time
+	if state.maxAge > 0 {
+		cutoff = time.Now().Add(-state.maxAge)
+	}
+	if remove == 0 && (cutoff.IsZero() || !state.oldest.Before(cutoff)) {
+		return nil
+	}
+
+	var keys [][]byte
+	var oldest time.Time
+	err := s.db.Scan(auditPrefix, func(key, _ []byte) bool {
+		t := auditKeyTime(key)
+		if len(keys) < remove || !cutoff.IsZero() && t.Before(cutoff) {
+			keys = append(keys, copyBytes(key))
+			return true
+		}
+		oldest = t
+		return false
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Update(func(tx Tx) error {
+		for _, key := range keys {
+			if err := tx.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	state.count -= len(keys)
+	state.oldest = oldest
+	return nil
+}
@@ -0,0 +1,363 @@
+package data
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore_Audit(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	err = s.Audit(
+		AuditEntry{Actor: "admin", Action: "give", Target: "bob", Network: network},
+		AuditEntry{Actor: "admin", Action: "take", Target: "alice"},
+		AuditEntry{Actor: "bob", Action: "addmask", Target: "bob"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := s.AuditLog(AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Action != "give" || entries[2].Action != "addmask" {
+		t.Fatalf("Expected the entries in order, got: %#v", entries)
+	}
+	if entries[0].Time.IsZero() {
+		t.Error("Expected the entries to be stamped.")
+	}
+
+	queries := []struct {
+		Query AuditQuery
+		Exp   int
+	}{
+		{AuditQuery{Actor: "ADMIN"}, 2},
+		{AuditQuery{Target: "bob"}, 2},
+		{AuditQuery{User: "bob"}, 2},
+		{AuditQuery{Action: "take"}, 1},
+		{AuditQuery{Network: network}, 1},
+		{AuditQuery{Since: time.Now().Add(time.Hour)}, 0},
+		{AuditQuery{Actor: "admin", Limit: 1}, 1},
+	}
+	for i, test := range queries {
+		if got, _ := s.AuditLog(test.Query); len(got) != test.Exp {
+			t.Errorf("%d) Expected %d entries, got: %#v", i, test.Exp, got)
+		}
+	}
+
+	if got, _ := s.AuditLog(AuditQuery{Limit: 1}); len(got) != 1 || got[0].Action != "addmask" {
+		t.Error("Expected the limit to keep the newest entry, got:", got)
+	}
+}
+
+func TestStore_AuditRetention(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	old := time.Now().Add(-2 * time.Hour)
+	for i := 0; i < 5; i++ {
+		if err = s.Audit(AuditEntry{Time: old, Action: "old"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if err = s.Audit(AuditEntry{Action: "new"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = s.SetAuditRetention(0, 6); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.AuditLog(AuditQuery{}); len(got) != 6 {
+		t.Error("Expected the oldest entries to be pruned, got:", len(got))
+	}
+
+	if err = s.SetAuditRetention(time.Hour, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := s.AuditLog(AuditQuery{})
+	if len(got) != 3 || got[0].Action != "new" {
+		t.Error("Expected the old entries to be pruned, got:", got)
+	}
+}
+
+func TestStore_AuditRetentionCount(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err = s.SetAuditRetention(time.Hour, 3); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err = s.Audit(AuditEntry{Action: "new"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, _ := s.AuditLog(AuditQuery{}); len(got) != 3 {
+		t.Error("Expected the log to be kept at the limit, got:", len(got))
+	}
+
+	err = s.Audit(AuditEntry{Time: time.Now().Add(-2 * time.Hour), Action: "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.AuditLog(AuditQuery{Action: "old"}); len(got) != 0 {
+		t.Error("Expected the old entry to be pruned, got:", got)
+	}
+
+	// Reopening the database counts what's in the log.
+	reopened, err := NewStore(func() (Backend, error) { return s.db, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := s.AuditLog(AuditQuery{})
+	if reopened.auditState.count != 3 ||
+		!reopened.auditState.oldest.Equal(entries[0].Time) {
+		t.Errorf("Expected the log to be counted, got: %d %v",
+			reopened.auditState.count, reopened.auditState.oldest)
+	}
+}
+
+func TestStore_AuditWithUserChanges(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	user := createStoredUser()
+	user.Username = uname
+	err = s.SaveUserAudit(user, func(before, after *StoredUser) []AuditEntry {
+		if before != nil {
+			t.Error("Expected a new user to have nothing before.")
+		}
+		return []AuditEntry{{Action: "adduser"}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed := user.Clone()
+	changed.Grant(network, "", 5)
+	err = s.SaveUserAudit(changed, func(before, after *StoredUser) []AuditEntry {
+		return AuditAccessChanges(AuditEntry{Action: "give"}, before, after)
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	removed, err := s.RemoveUserAudit(uname, func(u *StoredUser) []AuditEntry {
+		return AuditAccessChanges(AuditEntry{Action: "deluser"}, u, nil)
+	})
+	if err != nil || !removed {
+		t.Error("Expected the user to be removed:", err)
+	}
+	removed, err = s.RemoveUserAudit(uname, func(u *StoredUser) []AuditEntry {
+		t.Error("Audit should not be called for a missing user.")
+		return nil
+	})
+	if err != nil || removed {
+		t.Error("Expected nothing to be removed:", err)
+	}
+
+	entries, err := s.AuditLog(AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	if got := strings.Join(actions, " "); got != "adduser give deluser" {
+		t.Error("Expected each change to be audited once, got:", got)
+	}
+	if entries[1].After != "5" || entries[2].Before != "5" {
+		t.Errorf("Wrong access audited: %#v", entries)
+	}
+}
+
+func TestStore_AuditWithChanges(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	user := createStoredUser()
+	user.Username = uname
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	noAudit := func(before, after *StoredUser) []AuditEntry {
+		t.Error("Audit should not be called for an unmade change.")
+		return nil
+	}
+	err = s.UpdateUserAudit(uname, func(*StoredUser) error {
+		return ErrUnchanged
+	}, noAudit)
+	if err != nil {
+		t.Error(err)
+	}
+	failure := errors.New("failure")
+	err = s.UpdateUserAudit(uname, func(*StoredUser) error {
+		return failure
+	}, noAudit)
+	if err != failure {
+		t.Error("Expected the failure, got:", err)
+	}
+
+	err = s.UpdateUserAudit(uname, func(u *StoredUser) error {
+		u.Grant(network, "", 5)
+		return nil
+	}, func(before, after *StoredUser) []AuditEntry {
+		return AuditAccessChanges(AuditEntry{Action: "give"}, before, after)
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = s.UpdateChannelAudit(network, channel, func(*StoredChannel) error {
+		return nil
+	}, func(before, after *StoredChannel) []AuditEntry {
+		if before != nil {
+			t.Error("Expected a new channel to have nothing before.")
+		}
+		return []AuditEntry{{Action: "set"}}
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	stale := user.Clone()
+	if user, err = s.FindUser(uname); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.CompareAndSwapUserAudit(stale, noAudit); err != ErrRevisionMismatch {
+		t.Error("Expected a stale swap to fail, got:", err)
+	}
+	err = s.CompareAndSwapUserAudit(user, func(before, after *StoredUser) []AuditEntry {
+		if before == nil || before.Revision != after.Revision-1 {
+			t.Error("Expected the saved user before the swap.")
+		}
+		return []AuditEntry{{Action: "put"}}
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	ch, err := s.FindChannel(network, channel)
+	if err != nil || ch == nil {
+		t.Fatal("Expected the channel to be saved:", err)
+	}
+	if err = s.CompareAndSwapChannel(ch.Clone(), AuditEntry{Action: "putchan"}); err != nil {
+		t.Error(err)
+	}
+	if err = s.CompareAndSwapChannel(ch, AuditEntry{Action: "putchan"}); err != ErrRevisionMismatch {
+		t.Error("Expected a stale swap to fail, got:", err)
+	}
+	if _, err = s.RemoveChannel(network, channel, AuditEntry{Action: "delchan"}); err != nil {
+		t.Error(err)
+	}
+	if ok, _ := s.RemoveChannel(network, channel, AuditEntry{Action: "delchan"}); ok {
+		t.Error("The channel should already be removed.")
+	}
+
+	role := Role{Name: "auditrole", Access: *NewAccess(10, "")}
+	if err = s.SaveRole(role, AuditEntry{Action: "setrole"}); err != nil {
+		t.Error(err)
+	}
+	if _, err = s.RemoveRole(role.Name, AuditEntry{Action: "delrole"}); err != nil {
+		t.Error(err)
+	}
+	if ok, _ := s.RemoveRole(role.Name, AuditEntry{Action: "delrole"}); ok {
+		t.Error("The role should already be removed.")
+	}
+
+	removed, err := s.RemoveUserAudit(uname, func(u *StoredUser) []AuditEntry {
+		return AuditAccessChanges(AuditEntry{Action: "deluser"}, u, nil)
+	})
+	if err != nil || !removed {
+		t.Error("Expected the user to be removed:", err)
+	}
+	removed, err = s.RemoveUserAudit(uname, func(u *StoredUser) []AuditEntry {
+		t.Error("Audit should not be called for a missing user.")
+		return nil
+	})
+	if err != nil || removed {
+		t.Error("Expected nothing to be removed:", err)
+	}
+
+	entries, err := s.AuditLog(AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	want := "give set put putchan delchan setrole delrole deluser"
+	if got := strings.Join(actions, " "); got != want {
+		t.Error("Expected each change to be audited once, got:", got)
+	}
+	if entries[0].After != "5" || entries[len(entries)-1].Before != "5" {
+		t.Errorf("Wrong access audited: %#v", entries)
+	}
+}
+
+func TestAuditAccessChanges(t *testing.T) {
+	t.Parallel()
+
+	before := createStoredUser()
+	before.Grant(network, "", 5)
+	before.Grant(network, channel, 0, "o")
+
+	after := before.Clone()
+	after.Grant(network, channel, 0, "v")
+	after.Grant("", "", 100)
+
+	entry := AuditEntry{Action: "give", Target: before.Username}
+	entries := AuditAccessChanges(entry, before, after)
+	if len(entries) != 2 {
+		t.Fatalf("Expected two changed scopes, got: %#v", entries)
+	}
+	if e := entries[0]; e.Network != "" || e.Channel != "" || e.Before != "" ||
+		e.After != "100" || e.Action != "give" {
+
+		t.Errorf("Wrong global change: %#v", e)
+	}
+	// The scope is the key of the access so it's lowercase.
+	if e := entries[1]; e.Network != network || e.Channel != strings.ToLower(channel) ||
+		e.Before != "o" || e.After != "ov" {
+
+		t.Errorf("Wrong channel change: %#v", e)
+	}
+
+	if got := AuditAccessChanges(entry, before, nil); len(got) != 2 {
+		t.Error("Expected a deleted user to lose all access, got:", got)
+	}
+}
@@ -0,0 +1,236 @@
+package data
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// These are the names of the backends that can be selected with
+// MakeStoreProvider.
+const (
+	BackendKV   = "kv"
+	BackendBolt = "bolt"
+	BackendMem  = "mem"
+)
+
+var (
+	errBackendClosed = errors.New("data: backend is closed")
+	errTxReadOnly    = errors.New("data: transaction is read only")
+)
+
+// Tx is the set of operations a backend supports. Inside of a transaction
+// all of the operations are applied atomically.
+type Tx interface {
+	// Get retrieves the value for key, the value is nil if it's not found.
+	Get(key []byte) ([]byte, error)
+	// Put sets key to value, overwriting anything already there.
+	Put(key, value []byte) error
+	// Delete removes the key, it is not an error if it doesn't exist.
+	Delete(key []byte) error
+	// Scan calls fn for each key that starts with prefix in ascending key
+	// order. An empty prefix scans all keys. To stop iteration early return
+	// false from fn. The slices passed to fn must not be retained.
+	Scan(prefix []byte, fn func(key, value []byte) bool) error
+}
+
+// Backend is the key value storage that the Store persists into. The
+// operations on the Backend itself are each their own transaction.
+type Backend interface {
+	Tx
+
+	// Update runs fn inside of a transaction. If fn returns an error the
+	// transaction is rolled back, otherwise it is committed. The Tx must
+	// not be used after fn has returned.
+	Update(fn func(tx Tx) error) error
+	// View runs fn inside of a read only transaction, everything it reads
+	// is from the same point in time. Writes to the Tx fail, and it must not
+	// be used after fn has returned.
+	View(fn func(tx Tx) error) error
+	// Close the backend, freeing any resources it has.
+	Close() error
+}
+
+// MakeStoreProvider creates a provider for the named backend. The filename is
+// ignored by backends that don't persist to disk.
+func MakeStoreProvider(backend, filename string) (DbProvider, error) {
+	switch backend {
+	case "", BackendKV:
+		return MakeFileStoreProvider(filename), nil
+	case BackendBolt:
+		return MakeBoltStoreProvider(filename), nil
+	case BackendMem:
+		return MemStoreProvider, nil
+	}
+
+	return nil, fmt.Errorf("data: unknown store backend %q", backend)
+}
+
+// memBackend is a backend that keeps everything in memory and is lost when
+// closed.
+type memBackend struct {
+	protect sync.RWMutex
+	closed  bool
+	values  map[string][]byte
+}
+
+// MemStoreProvider provides memory-only database stores.
+func MemStoreProvider() (Backend, error) {
+	return &memBackend{values: make(map[string][]byte)}, nil
+}
+
+// Get the value of key.
+func (m *memBackend) Get(key []byte) ([]byte, error) {
+	m.protect.RLock()
+	defer m.protect.RUnlock()
+
+	if m.closed {
+		return nil, errBackendClosed
+	}
+	return memTx{m.values, nil}.Get(key)
+}
+
+// Put a value into key.
+func (m *memBackend) Put(key, value []byte) error {
+	return m.Update(func(tx Tx) error { return tx.Put(key, value) })
+}
+
+// Delete key.
+func (m *memBackend) Delete(key []byte) error {
+	return m.Update(func(tx Tx) error { return tx.Delete(key) })
+}
+
+// Scan the keys starting with prefix.
+func (m *memBackend) Scan(prefix []byte, fn func(key, value []byte) bool) error {
+	m.protect.RLock()
+	defer m.protect.RUnlock()
+
+	if m.closed {
+		return errBackendClosed
+	}
+	return memTx{m.values, nil}.Scan(prefix, fn)
+}
+
+// Update runs fn in a transaction. Transactions are serialized and writes
+// are held aside until fn returns successfully.
+func (m *memBackend) Update(fn func(tx Tx) error) error {
+	m.protect.Lock()
+	defer m.protect.Unlock()
+
+	if m.closed {
+		return errBackendClosed
+	}
+
+	tx := memTx{m.values, make(map[string][]byte)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	for k, v := range tx.writes {
+		if v == nil {
+			delete(m.values, k)
+		} else {
+			m.values[k] = v
+		}
+	}
+	return nil
+}
+
+// View runs fn in a read only transaction, updates wait until it's done.
+func (m *memBackend) View(fn func(tx Tx) error) error {
+	m.protect.RLock()
+	defer m.protect.RUnlock()
+
+	if m.closed {
+		return errBackendClosed
+	}
+	return fn(readOnlyTx{memTx{m.values, nil}})
+}
+
+// Close the backend.
+func (m *memBackend) Close() error {
+	m.protect.Lock()
+	defer m.protect.Unlock()
+
+	m.closed = true
+	m.values = nil
+	return nil
+}
+
+// memTx is a transaction on a memBackend. Writes are a set of pending changes
+// where a nil value is a pending delete.
+type memTx struct {
+	values map[string][]byte
+	writes map[string][]byte
+}
+
+func (t memTx) Get(key []byte) ([]byte, error) {
+	if v, ok := t.writes[string(key)]; ok {
+		return copyBytes(v), nil
+	}
+	return copyBytes(t.values[string(key)]), nil
+}
+
+func (t memTx) Put(key, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	t.writes[string(key)] = copyBytes(value)
+	return nil
+}
+
+func (t memTx) Delete(key []byte) error {
+	t.writes[string(key)] = nil
+	return nil
+}
+
+func (t memTx) Scan(prefix []byte, fn func(key, value []byte) bool) error {
+	keys := make([]string, 0, len(t.values))
+	for k := range t.values {
+		if _, ok := t.writes[k]; !ok && bytes.HasPrefix([]byte(k), prefix) {
+			keys = append(keys, k)
+		}
+	}
+	for k, v := range t.writes {
+		if v != nil && bytes.HasPrefix([]byte(k), prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v, ok := t.writes[k]
+		if !ok {
+			v = t.values[k]
+		}
+		if !fn([]byte(k), v) {
+			break
+		}
+	}
+	return nil
+}
+
+// readOnlyTx refuses the writes of the Tx it wraps.
+type readOnlyTx struct {
+	Tx
+}
+
+func (readOnlyTx) Put(key, value []byte) error {
+	return errTxReadOnly
+}
+
+func (readOnlyTx) Delete(key []byte) error {
+	return errTxReadOnly
+}
+
+// copyBytes returns a copy of b, or nil if b is nil.
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
@@ -0,0 +1,119 @@
+package data
+
+import (
+	"bytes"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// boltBucket is the bucket all of the store's keys are kept in.
+	boltBucket = []byte("store")
+	// boltOpenTimeout is how long to wait for the file lock when opening.
+	boltOpenTimeout = 5 * time.Second
+)
+
+// boltBackend stores everything in a single bucket of a bolt database.
+type boltBackend struct {
+	db *bolt.DB
+}
+
+// MakeBoltStoreProvider creates a store that keeps it's data in a bolt
+// database file, creating it if it does not exist.
+func MakeBoltStoreProvider(filename string) DbProvider {
+	return func() (Backend, error) {
+		db, err := bolt.Open(filename, 0600,
+			&bolt.Options{Timeout: boltOpenTimeout})
+		if err != nil {
+			return nil, err
+		}
+
+		err = db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(boltBucket)
+			return err
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		return &boltBackend{db: db}, nil
+	}
+}
+
+// Get the value of key.
+func (b *boltBackend) Get(key []byte) (value []byte, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		value, err = boltTx{tx.Bucket(boltBucket)}.Get(key)
+		return err
+	})
+	return value, err
+}
+
+// Put a value into key.
+func (b *boltBackend) Put(key, value []byte) error {
+	return b.Update(func(tx Tx) error { return tx.Put(key, value) })
+}
+
+// Delete key.
+func (b *boltBackend) Delete(key []byte) error {
+	return b.Update(func(tx Tx) error { return tx.Delete(key) })
+}
+
+// Scan the keys starting with prefix.
+func (b *boltBackend) Scan(prefix []byte, fn func(key, value []byte) bool) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return boltTx{tx.Bucket(boltBucket)}.Scan(prefix, fn)
+	})
+}
+
+// Update runs fn inside a bolt read-write transaction.
+func (b *boltBackend) Update(fn func(tx Tx) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx.Bucket(boltBucket)})
+	})
+}
+
+// View runs fn inside a bolt read-only transaction.
+func (b *boltBackend) View(fn func(tx Tx) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx.Bucket(boltBucket)})
+	})
+}
+
+// Close the database.
+func (b *boltBackend) Close() error {
+	return b.db.Close()
+}
+
+// boltTx wraps the store's bucket inside of a bolt transaction.
+type boltTx struct {
+	bucket *bolt.Bucket
+}
+
+func (t boltTx) Get(key []byte) ([]byte, error) {
+	// Values from bolt are only valid for the life of the transaction.
+	return copyBytes(t.bucket.Get(key)), nil
+}
+
+func (t boltTx) Put(key, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	return t.bucket.Put(key, value)
+}
+
+func (t boltTx) Delete(key []byte) error {
+	return t.bucket.Delete(key)
+}
+
+func (t boltTx) Scan(prefix []byte, fn func(key, value []byte) bool) error {
+	c := t.bucket.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if !fn(k, v) {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package data
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/cznic/kv"
+)
+
+// kvBackend stores everything in a cznic/kv database.
+type kvBackend struct {
+	db *kv.DB
+
+	// protect serializes transactions, kv transactions are not isolated so
+	// reads must also wait on them to not see uncommitted writes.
+	protect sync.RWMutex
+}
+
+// MakeFileStoreProvider is the default way to create a store by using the
+// filename and trying to open it.
+func MakeFileStoreProvider(filename string) DbProvider {
+	return func() (Backend, error) {
+		var db *kv.DB
+		opts := &kv.Options{}
+
+		_, err := os.Stat(filename)
+
+		if os.IsNotExist(err) {
+			db, err = kv.Create(filename, opts)
+		} else {
+			db, err = kv.Open(filename, opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &kvBackend{db: db}, nil
+	}
+}
+
+// Get the value of key.
+func (k *kvBackend) Get(key []byte) ([]byte, error) {
+	k.protect.RLock()
+	defer k.protect.RUnlock()
+	return kvTx{k.db}.Get(key)
+}
+
+// Put a value into key.
+func (k *kvBackend) Put(key, value []byte) error {
+	k.protect.Lock()
+	defer k.protect.Unlock()
+	return kvTx{k.db}.Put(key, value)
+}
+
+// Delete key.
+func (k *kvBackend) Delete(key []byte) error {
+	k.protect.Lock()
+	defer k.protect.Unlock()
+	return kvTx{k.db}.Delete(key)
+}
+
+// Scan the keys starting with prefix.
+func (k *kvBackend) Scan(prefix []byte, fn func(key, value []byte) bool) error {
+	k.protect.RLock()
+	defer k.protect.RUnlock()
+	return kvTx{k.db}.Scan(prefix, fn)
+}
+
+// Update runs fn inside a kv transaction.
+func (k *kvBackend) Update(fn func(tx Tx) error) error {
+	k.protect.Lock()
+	defer k.protect.Unlock()
+
+	if err := k.db.BeginTransaction(); err != nil {
+		return err
+	}
+
+	if err := fn(kvTx{k.db}); err != nil {
+		if rerr := k.db.Rollback(); rerr != nil {
+			return rerr
+		}
+		return err
+	}
+
+	return k.db.Commit()
+}
+
+// View runs fn without a kv transaction, writers wait until it's done so
+// everything it reads is committed and unchanging.
+func (k *kvBackend) View(fn func(tx Tx) error) error {
+	k.protect.RLock()
+	defer k.protect.RUnlock()
+
+	return fn(readOnlyTx{kvTx{k.db}})
+}
+
+// Close the database.
+func (k *kvBackend) Close() error {
+	return k.db.Close()
+}
+
+// kvTx performs operations directly against the database, when used in
+// Update a kv transaction has already been started.
+type kvTx struct {
+	db *kv.DB
+}
+
+func (t kvTx) Get(key []byte) ([]byte, error) {
+	return t.db.Get(nil, key)
+}
+
+func (t kvTx) Put(key, value []byte) error {
+	return t.db.Set(key, value)
+}
+
+func (t kvTx) Delete(key []byte) error {
+	return t.db.Delete(key)
+}
+
+func (t kvTx) Scan(prefix []byte, fn func(key, value []byte) bool) error {
+	e, _, err := t.db.Seek(prefix)
+	switch {
+	case err == io.EOF:
+		return nil
+	case err != nil:
+		return err
+	}
+
+	for {
+		key, val, err := e.Next()
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if !bytes.HasPrefix(key, prefix) || !fn(key, val) {
+			return nil
+		}
+	}
+}
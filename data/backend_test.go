@@ -0,0 +1,280 @@
+package data
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cznic/kv"
+)
+
+// testBackends creates one of each kind of backend, the returned function
+// cleans up any files that were made.
+func testBackends(t *testing.T) (map[string]Backend, func()) {
+	dir, err := ioutil.TempDir("", "ultimateq-backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memDB, err := MemStoreProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kvDB, err := kv.CreateMem(&kv.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	boltDB, err := MakeBoltStoreProvider(filepath.Join(dir, "bolt.db"))()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backends := map[string]Backend{
+		BackendMem:  memDB,
+		BackendKV:   &kvBackend{db: kvDB},
+		BackendBolt: boltDB,
+	}
+
+	return backends, func() {
+		for _, b := range backends {
+			b.Close()
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestBackend_GetPutDelete(t *testing.T) {
+	t.Parallel()
+
+	backends, cleanup := testBackends(t)
+	defer cleanup()
+
+	for name, b := range backends {
+		if val, err := b.Get([]byte("key")); err != nil || val != nil {
+			t.Errorf("%s: Expected nothing, got: %s %v", name, val, err)
+		}
+
+		if err := b.Put([]byte("key"), []byte("value")); err != nil {
+			t.Errorf("%s: Unexpected error: %v", name, err)
+		}
+		if val, err := b.Get([]byte("key")); err != nil || string(val) != "value" {
+			t.Errorf("%s: Expected value, got: %s %v", name, val, err)
+		}
+
+		if err := b.Delete([]byte("key")); err != nil {
+			t.Errorf("%s: Unexpected error: %v", name, err)
+		}
+		if val, err := b.Get([]byte("key")); err != nil || val != nil {
+			t.Errorf("%s: Expected nothing, got: %s %v", name, val, err)
+		}
+	}
+}
+
+func TestBackend_Scan(t *testing.T) {
+	t.Parallel()
+
+	backends, cleanup := testBackends(t)
+	defer cleanup()
+
+	for name, b := range backends {
+		for _, k := range []string{"b:2", "a:1", "b:1", "c:1"} {
+			if err := b.Put([]byte(k), []byte(k)); err != nil {
+				t.Fatalf("%s: Unexpected error: %v", name, err)
+			}
+		}
+
+		var keys []string
+		err := b.Scan([]byte("b:"), func(key, value []byte) bool {
+			keys = append(keys, string(key))
+			return true
+		})
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %v", name, err)
+		}
+		if got := strings.Join(keys, ","); got != "b:1,b:2" {
+			t.Errorf("%s: Wrong keys: %s", name, got)
+		}
+
+		keys = nil
+		err = b.Scan(nil, func(key, value []byte) bool {
+			keys = append(keys, string(key))
+			return len(keys) < 3
+		})
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %v", name, err)
+		}
+		if got := strings.Join(keys, ","); got != "a:1,b:1,b:2" {
+			t.Errorf("%s: Wrong keys: %s", name, got)
+		}
+	}
+}
+
+func TestBackend_Update(t *testing.T) {
+	t.Parallel()
+
+	backends, cleanup := testBackends(t)
+	defer cleanup()
+
+	errRollback := errors.New("rollback")
+
+	for name, b := range backends {
+		err := b.Update(func(tx Tx) error {
+			if err := tx.Put([]byte("one"), []byte("1")); err != nil {
+				return err
+			}
+			if val, err := tx.Get([]byte("one")); err != nil || string(val) != "1" {
+				t.Errorf("%s: Expected to read own write, got: %s %v", name, val, err)
+			}
+			return tx.Put([]byte("two"), []byte("2"))
+		})
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %v", name, err)
+		}
+		if val, _ := b.Get([]byte("two")); string(val) != "2" {
+			t.Errorf("%s: Expected commit, got: %s", name, val)
+		}
+
+		err = b.Update(func(tx Tx) error {
+			if err := tx.Delete([]byte("one")); err != nil {
+				return err
+			}
+			if err := tx.Put([]byte("three"), []byte("3")); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if err != errRollback {
+			t.Errorf("%s: Expected rollback error, got: %v", name, err)
+		}
+		if val, _ := b.Get([]byte("one")); string(val) != "1" {
+			t.Errorf("%s: Expected delete to be rolled back, got: %s", name, val)
+		}
+		if val, _ := b.Get([]byte("three")); val != nil {
+			t.Errorf("%s: Expected put to be rolled back, got: %s", name, val)
+		}
+	}
+}
+
+func TestBackend_View(t *testing.T) {
+	t.Parallel()
+
+	backends, cleanup := testBackends(t)
+	defer cleanup()
+
+	for name, b := range backends {
+		if err := b.Put([]byte("key"), []byte("value")); err != nil {
+			t.Fatal(name, err)
+		}
+
+		err := b.View(func(tx Tx) error {
+			if val, err := tx.Get([]byte("key")); err != nil || string(val) != "value" {
+				t.Errorf("%s: Expected to read the value, got: %s %v", name, val, err)
+			}
+			if err := tx.Put([]byte("key"), []byte("other")); err == nil {
+				t.Errorf("%s: Expected a put to fail.", name)
+			}
+			if err := tx.Delete([]byte("key")); err == nil {
+				t.Errorf("%s: Expected a delete to fail.", name)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %v", name, err)
+		}
+		if val, _ := b.Get([]byte("key")); string(val) != "value" {
+			t.Errorf("%s: Expected the value to be unchanged, got: %s", name, val)
+		}
+	}
+}
+
+func TestBackend_UpdateIsolated(t *testing.T) {
+	t.Parallel()
+
+	backends, cleanup := testBackends(t)
+	defer cleanup()
+
+	errRollback := errors.New("rollback")
+
+	for name, b := range backends {
+		read := make(chan []byte)
+		err := b.Update(func(tx Tx) error {
+			if err := tx.Put([]byte("uncommitted"), []byte("1")); err != nil {
+				return err
+			}
+			go func() {
+				val, _ := b.Get([]byte("uncommitted"))
+				read <- val
+			}()
+			time.Sleep(10 * time.Millisecond)
+			return errRollback
+		})
+		if err != errRollback {
+			t.Errorf("%s: Expected rollback error, got: %v", name, err)
+		}
+		if val := <-read; val != nil {
+			t.Errorf("%s: Expected an uncommitted write not to be read, got: %s",
+				name, val)
+		}
+	}
+}
+
+func TestBackend_MakeStoreProvider(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"", BackendKV, BackendBolt, BackendMem} {
+		if prov, err := MakeStoreProvider(name, "file.db"); err != nil || prov == nil {
+			t.Errorf("%q: Expected a provider, got: %v", name, err)
+		}
+	}
+
+	if _, err := MakeStoreProvider("nosql", "file.db"); err == nil {
+		t.Error("Expected an error for an unknown backend.")
+	}
+}
+
+func TestBackend_Store(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "ultimateq-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "store.db")
+	s, err := NewStore(MakeBoltStoreProvider(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := NewStoredUser(uname, password, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SaveUser(user); err != nil {
+		t.Error(err)
+	}
+	if err = s.SaveChannel(NewStoredChannel(network, channel)); err != nil {
+		t.Error(err)
+	}
+	if err = s.Close(); err != nil {
+		t.Error(err)
+	}
+
+	s, err = NewStore(MakeBoltStoreProvider(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if user, err := s.FindUser(uname); err != nil || user == nil {
+		t.Error("Expected the user to be persisted:", err)
+	}
+	if ch, err := s.FindChannel(network, channel); err != nil || ch == nil {
+		t.Error("Expected the channel to be persisted:", err)
+	}
+}
@@ -0,0 +1,237 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cznic/kv"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// backupPrefix and backupSuffix surround the time a backup was taken
+	// in its filename.
+	backupPrefix     = "store-"
+	backupSuffix     = ".db"
+	backupTimeFormat = "20060102-150405.000"
+)
+
+var errBackupUnsupported = errors.New("data: the store's backend can't be backed up")
+
+// snapshotter is a backend that can copy itself while it's in use.
+type snapshotter interface {
+	// snapshot writes a consistent copy of the backend to filename, it
+	// returns how many keys were copied and a provider that opens the copy.
+	snapshot(filename string) (int, DbProvider, error)
+}
+
+// Backup is a snapshot of the store taken by Store.Backup.
+type Backup struct {
+	Filename string
+	Time     time.Time
+	// Keys is how many keys the snapshot has.
+	Keys int
+	// Removed are the older backups that were rotated away.
+	Removed []string
+}
+
+// SetBackupRetention sets how long backups are kept for and how many are
+// kept at most, 0 for either means no limit.
+func (s *Store) SetBackupRetention(maxAge time.Duration, keep int) {
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	s.backupMaxAge = maxAge
+	s.backupKeep = keep
+}
+
+// Backup writes a snapshot of the store into dir while it's in use. The
+// snapshot is verified by opening it and reading every key, one that can't
+// be verified is removed. Older backups in dir are then rotated by the
+// retention, the new one is always kept.
+func (s *Store) Backup(dir string) (Backup, error) {
+	snap, ok := s.db.(snapshotter)
+	if !ok {
+		return Backup{}, errBackupUnsupported
+	}
+
+	s.backupProtect.Lock()
+	defer s.backupProtect.Unlock()
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return Backup{}, err
+	}
+
+	now := time.Now().UTC()
+	backup := Backup{
+		Filename: filepath.Join(dir,
+			backupPrefix+now.Format(backupTimeFormat)+backupSuffix),
+		Time: now,
+	}
+	if _, err := os.Stat(backup.Filename); err == nil {
+		return Backup{}, fmt.Errorf("data: backup %s already exists",
+			backup.Filename)
+	}
+
+	// The snapshot is only given its name once it's verified so a partial
+	// one is never mistaken for a backup.
+	tmp := backup.Filename + ".tmp"
+	os.Remove(tmp)
+
+	var prov DbProvider
+	var err error
+	if backup.Keys, prov, err = snap.snapshot(tmp); err == nil {
+		err = verifyBackup(prov, backup.Keys)
+	}
+	if err == nil {
+		err = os.Rename(tmp, backup.Filename)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return Backup{}, err
+	}
+
+	s.protect.Lock()
+	maxAge, keep := s.backupMaxAge, s.backupKeep
+	s.protect.Unlock()
+
+	backup.Removed, err = rotateBackups(dir, now, maxAge, keep)
+	return backup, err
+}
+
+// verifyBackup opens a snapshot and checks that it has every key.
+func verifyBackup(prov DbProvider, keys int) error {
+	db, err := prov()
+	if err != nil {
+		return fmt.Errorf("data: backup could not be opened: %v", err)
+	}
+
+	n := 0
+	err = db.Scan(nil, func(_, _ []byte) bool {
+		n++
+		return true
+	})
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	switch {
+	case err != nil:
+		return fmt.Errorf("data: backup could not be read: %v", err)
+	case n != keys:
+		return fmt.Errorf("data: backup has %d keys, expected %d", n, keys)
+	}
+	return nil
+}
+
+// rotateBackups removes the backups in dir that are older than maxAge, or
+// not among the newest keep. The newest backup is never removed.
+func rotateBackups(dir string, now time.Time, maxAge time.Duration,
+	keep int) ([]string, error) {
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type backupFile struct {
+		name string
+		time time.Time
+	}
+	var backups []backupFile
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, backupPrefix) ||
+			!strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix),
+			backupSuffix)
+		t, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{name: name, time: t})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+
+	var removed []string
+	for i, b := range backups {
+		if i == 0 {
+			continue
+		}
+		tooMany := keep > 0 && i >= keep
+		tooOld := maxAge > 0 && now.Sub(b.time) > maxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+
+		filename := filepath.Join(dir, b.name)
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed = append(removed, filename)
+	}
+	return removed, nil
+}
+
+// snapshot copies the database inside a read transaction, bolt lets writers
+// carry on while it's copied.
+func (b *boltBackend) snapshot(filename string) (int, DbProvider, error) {
+	var keys int
+	err := b.db.View(func(tx *bolt.Tx) error {
+		keys = tx.Bucket(boltBucket).Stats().KeyN
+		return tx.CopyFile(filename, 0600)
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return keys, MakeBoltStoreProvider(filename), nil
+}
+
+// snapshot copies every key into a new database, writers wait until it's
+// done.
+func (k *kvBackend) snapshot(filename string) (int, DbProvider, error) {
+	k.protect.Lock()
+	defer k.protect.Unlock()
+
+	dst, err := kv.Create(filename, &kv.Options{})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	keys := 0
+	if err = dst.BeginTransaction(); err == nil {
+		var putErr error
+		err = kvTx{k.db}.Scan(nil, func(key, value []byte) bool {
+			keys++
+			putErr = kvTx{dst}.Put(key, value)
+			return putErr == nil
+		})
+		if err == nil {
+			err = putErr
+		}
+
+		if err != nil {
+			dst.Rollback()
+		} else {
+			err = dst.Commit()
+		}
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	return keys, MakeFileStoreProvider(filename), nil
+}
@@ -0,0 +1,116 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_Backup(t *testing.T) {
+	t.Parallel()
+
+	for _, backend := range []string{BackendKV, BackendBolt} {
+		dir, err := ioutil.TempDir("", "backup")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		prov, _ := MakeStoreProvider(backend, filepath.Join(dir, "store.db"))
+		s, err := NewStore(prov)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		user, _ := NewStoredUser(uname, password)
+		if err = s.SaveUser(user); err != nil {
+			t.Fatal(err)
+		}
+
+		backups := filepath.Join(dir, "backups")
+		backup, err := s.Backup(backups)
+		if err != nil {
+			t.Fatalf("%s: %v", backend, err)
+		}
+		if backup.Keys == 0 || len(backup.Removed) != 0 {
+			t.Errorf("%s: wrong backup: %#v", backend, backup)
+		}
+
+		prov, _ = MakeStoreProvider(backend, backup.Filename)
+		restored, err := NewStore(prov)
+		if err != nil {
+			t.Fatalf("%s: %v", backend, err)
+		}
+		if u, err := restored.FindUser(uname); err != nil || u == nil {
+			t.Errorf("%s: expected the user to be backed up: %v", backend, err)
+		}
+		restored.Close()
+
+		files, _ := filepath.Glob(filepath.Join(backups, "*.tmp"))
+		if len(files) != 0 {
+			t.Errorf("%s: expected no partial backups, got: %v", backend, files)
+		}
+	}
+}
+
+func TestStore_BackupUnsupported(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err = s.Backup(os.TempDir()); err != errBackupUnsupported {
+		t.Error("Expected the mem backend not to be backed up, got:", err)
+	}
+}
+
+func TestRotateBackups(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 5, 10, 12, 0, 0, 0, time.UTC)
+	var names []string
+	for _, ago := range []time.Duration{0, time.Hour, 2 * time.Hour, 48 * time.Hour} {
+		name := backupPrefix + now.Add(-ago).Format(backupTimeFormat) + backupSuffix
+		names = append(names, name)
+		ioutil.WriteFile(filepath.Join(dir, name), nil, 0600)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "store-notes.db"), nil, 0600)
+
+	removed, err := rotateBackups(dir, now, 24*time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || filepath.Base(removed[0]) != names[3] {
+		t.Error("Expected the old backup to be removed, got:", removed)
+	}
+
+	removed, err = rotateBackups(dir, now, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || filepath.Base(removed[0]) != names[2] {
+		t.Error("Expected the third backup to be removed, got:", removed)
+	}
+
+	// The newest backup is kept no matter how old it is.
+	removed, _ = rotateBackups(dir, now.Add(time.Hour*100), time.Hour, 1)
+	if len(removed) != 1 || filepath.Base(removed[0]) != names[1] {
+		t.Error("Expected only the newest backup to remain, got:", removed)
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 2 {
+		t.Error("Expected the newest backup and other files to remain:", files)
+	}
+}
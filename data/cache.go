@@ -0,0 +1,157 @@
+package data
+
+import (
+	"container/list"
+	"time"
+)
+
+// CacheStats are the counters of the store's user cache.
+type CacheStats struct {
+	// Hits is the number of lookups that were found in the cache.
+	Hits uint64
+	// Misses is the number of lookups that had to go to the database.
+	Misses uint64
+	// Evictions is the number of users removed to make room or because
+	// they expired.
+	Evictions uint64
+
+	// Size is the number of users currently cached.
+	Size int
+	// Capacity is the most users that will be cached.
+	Capacity int
+}
+
+// userCache is a least recently used cache of users with an optional time to
+// live. It's not safe for concurrent use, the store's lock protects it.
+type userCache struct {
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+
+	order *list.List
+	items map[string]*list.Element
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// cacheEntry is the value of each element in the cache's order list.
+type cacheEntry struct {
+	username string
+	user     *StoredUser
+	expires  time.Time
+}
+
+// newUserCache creates a cache that holds up to capacity users, each for up
+// to ttl. A ttl of 0 means users never expire.
+func newUserCache(capacity int, ttl time.Duration) *userCache {
+	return &userCache{
+		capacity: capacity,
+		ttl:      ttl,
+		now:      time.Now,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get a user from the cache and mark it as recently used.
+func (c *userCache) get(username string) (*StoredUser, bool) {
+	elem, ok := c.items[username]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if c.ttl > 0 && c.now().After(entry.expires) {
+		c.removeElement(elem)
+		c.evictions++
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
+	c.order.MoveToFront(elem)
+	return entry.user, true
+}
+
+// peek at a user in the cache without affecting it's order or the counters.
+func (c *userCache) peek(username string) *StoredUser {
+	if elem, ok := c.items[username]; ok {
+		return elem.Value.(*cacheEntry).user
+	}
+	return nil
+}
+
+// put a user in the cache, evicting the least recently used users if there
+// is no room.
+func (c *userCache) put(username string, user *StoredUser) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = c.now().Add(c.ttl)
+	}
+
+	if elem, ok := c.items[username]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.user, entry.expires = user, expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[username] = c.order.PushFront(&cacheEntry{username, user, expires})
+	c.trim()
+}
+
+// remove a user from the cache.
+func (c *userCache) remove(username string) {
+	if elem, ok := c.items[username]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// reset removes every user from the cache, the counters are kept.
+func (c *userCache) reset() {
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+// resize changes the limits of the cache, evicting users if it's now too
+// large.
+func (c *userCache) resize(capacity int, ttl time.Duration) {
+	c.capacity, c.ttl = capacity, ttl
+	c.trim()
+}
+
+// len is the number of users in the cache.
+func (c *userCache) len() int {
+	return c.order.Len()
+}
+
+// stats returns the counters of the cache.
+func (c *userCache) stats() CacheStats {
+	return CacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Size:      c.order.Len(),
+		Capacity:  c.capacity,
+	}
+}
+
+// trim evicts the least recently used users until the cache fits.
+func (c *userCache) trim() {
+	for c.order.Len() > 0 && c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+		c.evictions++
+	}
+}
+
+func (c *userCache) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*cacheEntry).username)
+}
@@ -0,0 +1,129 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserCache_LRU(t *testing.T) {
+	t.Parallel()
+
+	c := newUserCache(2, 0)
+	a, b, d := &StoredUser{Username: "a"}, &StoredUser{Username: "b"},
+		&StoredUser{Username: "d"}
+
+	c.put("a", a)
+	c.put("b", b)
+	if got, ok := c.get("a"); !ok || got != a {
+		t.Error("Expected a to be cached.")
+	}
+
+	// b is now the least recently used.
+	c.put("d", d)
+	if _, ok := c.get("b"); ok {
+		t.Error("Expected b to be evicted.")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Error("Expected a to be cached.")
+	}
+	if _, ok := c.get("d"); !ok {
+		t.Error("Expected d to be cached.")
+	}
+
+	c.remove("a")
+	if c.peek("a") != nil {
+		t.Error("Expected a to be removed.")
+	}
+
+	exp := CacheStats{Hits: 3, Misses: 1, Evictions: 1, Size: 1, Capacity: 2}
+	if got := c.stats(); exp != got {
+		t.Errorf("Expected: %+v, got: %+v", exp, got)
+	}
+
+	c.put("a", a)
+	c.resize(1, 0)
+	if c.len() != 1 || c.peek("a") == nil {
+		t.Error("Expected resizing to keep only the most recent user.")
+	}
+
+	c.reset()
+	if c.len() != 0 {
+		t.Error("Expected the cache to be empty.")
+	}
+}
+
+func TestUserCache_TTL(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	c := newUserCache(10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.put("a", &StoredUser{Username: "a"})
+	now = now.Add(30 * time.Second)
+	if _, ok := c.get("a"); !ok {
+		t.Error("Expected a to be cached.")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.get("a"); ok {
+		t.Error("Expected a to expire.")
+	}
+	if got := c.stats(); got.Evictions != 1 || got.Size != 0 {
+		t.Errorf("Expected an eviction, got: %+v", got)
+	}
+}
+
+func TestUserCache_Disabled(t *testing.T) {
+	t.Parallel()
+
+	c := newUserCache(0, 0)
+	c.put("a", &StoredUser{Username: "a"})
+	if c.len() != 0 {
+		t.Error("Expected nothing to be cached.")
+	}
+}
+
+func TestStore_CacheLimits(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.SetCacheLimits(10, time.Hour)
+	for _, name := range []string{"One", "two"} {
+		if err = s.SaveUser(&StoredUser{Username: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err = s.FindUser("one"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.FindUser("three"); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := CacheStats{Hits: 1, Misses: 1, Size: 2, Capacity: 10}
+	if got := s.CacheStats(); exp != got {
+		t.Errorf("Expected: %+v, got: %+v", exp, got)
+	}
+
+	// Saving over a cached user must replace it.
+	if err = s.SaveUser(&StoredUser{Username: "one", Masks: []string{"*!*@new"}}); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := s.FindUser("ONE"); user == nil || !user.HasMask("*!*@new") {
+		t.Error("Expected the saved user, got:", user)
+	}
+
+	if _, err = s.RemoveUser("one"); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := s.FindUser("one"); user != nil {
+		t.Error("Expected the removed user to be gone, got:", user)
+	}
+}
@@ -0,0 +1,37 @@
+package data
+
+// setCertFP records the certificate fingerprint a host is connected with, an
+// empty fingerprint forgets it.
+// warning: Assumes the store is locked
+func (s *Store) setCertFP(network, host, fingerprint string) {
+	fingerprint, ok := NormalizeCertFP(fingerprint)
+	if !ok {
+		delete(s.certs, network+host)
+		return
+	}
+	s.certs[network+host] = fingerprint
+}
+
+// moveCertFP moves the certificate fingerprint of a host when it changes
+// nick.
+// warning: Assumes the store is locked
+func (s *Store) moveCertFP(network, oldHost, newHost string) {
+	if fingerprint, ok := s.certs[network+oldHost]; ok {
+		delete(s.certs, network+oldHost)
+		s.certs[network+newHost] = fingerprint
+	}
+}
+
+// certUser is AuthedUser for hosts authenticated by a certificate.
+// warning: Assumes the store is locked
+func (s *Store) certUser(network, host string) *StoredUser {
+	fingerprint, ok := s.certs[network+host]
+	if !ok {
+		return nil
+	}
+	user, err := s.UserByCert(fingerprint)
+	if err != nil {
+		return nil
+	}
+	return user
+}
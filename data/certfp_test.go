@@ -0,0 +1,66 @@
+package data
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStore_CertAuth(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	fp := strings.Repeat("ab", 32)
+
+	if has, err := s.HasCerts(); err != nil || has {
+		t.Error("Expected no certs:", err)
+	}
+
+	user, err := NewStoredUser(uname, password, "*!*@host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.AddCert(fp)
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	if has, err := s.HasCerts(); err != nil || !has {
+		t.Error("Expected certs:", err)
+	}
+	if found, err := s.UserByCert(strings.ToUpper(fp)); err != nil || found == nil {
+		t.Fatal("Expected to find the user by cert:", err)
+	}
+
+	if s.AuthedUser(network, host) != nil {
+		t.Error("Expected an unknown fingerprint not to be authed.")
+	}
+
+	s.Update(network, StateUpdate{CertFP: []string{host, fp}})
+	if got := s.AuthedUser(network, host); got == nil || got.Username != uname {
+		t.Error("Expected the host to be authed by its cert, got:", got)
+	}
+
+	newHost := "newnick!user@host"
+	s.Update(network, StateUpdate{Nick: []string{host, newHost}})
+	if s.AuthedUser(network, host) != nil || s.AuthedUser(network, newHost) == nil {
+		t.Error("Expected the cert to follow the nick change.")
+	}
+
+	s.Update(network, StateUpdate{Quit: newHost})
+	if s.AuthedUser(network, newHost) != nil {
+		t.Error("Expected quitting to forget the cert.")
+	}
+
+	user.RemoveCert(fp)
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := s.UserByCert(fp); found != nil {
+		t.Error("Expected the removed cert to be unindexed, got:", found)
+	}
+}
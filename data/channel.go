@@ -2,6 +2,7 @@ package data
 
 import (
 	"strings"
+	"time"
 
 	"github.com/aarondl/ultimateq/api"
 	"github.com/aarondl/ultimateq/irc"
@@ -12,11 +13,28 @@ const (
 	banMode = 'b'
 )
 
+var (
+	// nMaxTopicHistory is the number of topics remembered per channel.
+	nMaxTopicHistory = 10
+)
+
 // Channel encapsulates all the data associated with a channel.
 type Channel struct {
-	Name  string       `json:"name"`
-	Topic string       `json:"topic"`
-	Modes ChannelModes `json:"channel_modes"`
+	Name         string        `json:"name"`
+	Topic        string        `json:"topic"`
+	TopicSetter  string        `json:"topic_setter"`
+	TopicTime    time.Time     `json:"topic_time"`
+	Created      time.Time     `json:"created"`
+	TopicHistory []TopicChange `json:"topic_history"`
+	Modes        ChannelModes  `json:"channel_modes"`
+}
+
+// TopicChange is a topic as it was set at some point in the channel's
+// history.
+type TopicChange struct {
+	Topic  string    `json:"topic"`
+	Setter string    `json:"setter"`
+	Time   time.Time `json:"time"`
 }
 
 // NewChannel instantiates a channel object.
@@ -33,7 +51,42 @@ func NewChannel(name string, m *modeKinds) *Channel {
 
 // Clone deep copies this Channel.
 func (c *Channel) Clone() *Channel {
-	return &Channel{c.Name, c.Topic, c.Modes.Clone()}
+	var history []TopicChange
+	if c.TopicHistory != nil {
+		history = make([]TopicChange, len(c.TopicHistory))
+		copy(history, c.TopicHistory)
+	}
+
+	return &Channel{
+		Name:         c.Name,
+		Topic:        c.Topic,
+		TopicSetter:  c.TopicSetter,
+		TopicTime:    c.TopicTime,
+		Created:      c.Created,
+		TopicHistory: history,
+		Modes:        c.Modes.Clone(),
+	}
+}
+
+// SetTopic sets the topic along with who set it and when, and records it in
+// the topic history. Only the last nMaxTopicHistory topics are kept.
+func (c *Channel) SetTopic(topic, setter string, when time.Time) {
+	c.Topic = topic
+	c.TopicSetter = setter
+	c.TopicTime = when
+
+	if n := len(c.TopicHistory); n > 0 {
+		last := c.TopicHistory[n-1]
+		if last.Topic == topic && last.Setter == setter && last.Time.Equal(when) {
+			return
+		}
+	}
+
+	c.TopicHistory = append(c.TopicHistory,
+		TopicChange{Topic: topic, Setter: setter, Time: when})
+	if over := len(c.TopicHistory) - nMaxTopicHistory; over > 0 {
+		c.TopicHistory = append(c.TopicHistory[:0], c.TopicHistory[over:]...)
+	}
 }
 
 // IsBanned checks a host to see if it's banned.
@@ -119,7 +172,29 @@ func (c *Channel) ToProto() *api.StateChannel {
 
 	ch.Name = c.Name
 	ch.Topic = c.Topic
+	ch.TopicSetter = c.TopicSetter
+	ch.TopicTime = unixTime(c.TopicTime)
+	ch.Created = unixTime(c.Created)
 	ch.Modes = c.Modes.ToProto()
 
+	if len(c.TopicHistory) > 0 {
+		ch.TopicHistory = make([]*api.TopicChange, len(c.TopicHistory))
+		for i, t := range c.TopicHistory {
+			ch.TopicHistory[i] = &api.TopicChange{
+				Topic:  t.Topic,
+				Setter: t.Setter,
+				Time:   unixTime(t.Time),
+			}
+		}
+	}
+
 	return ch
 }
+
+// unixTime returns the unix timestamp of t, or 0 if t is the zero time.
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
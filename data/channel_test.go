@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestChannel_Create(t *testing.T) {
@@ -185,7 +186,9 @@ func TestChannel_JSONify(t *testing.T) {
 		t.Error(err)
 	}
 
-	jsonStr := `{"name":"a","topic":"b",` +
+	jsonStr := `{"name":"a","topic":"b","topic_setter":"",` +
+		`"topic_time":"0001-01-01T00:00:00Z",` +
+		`"created":"0001-01-01T00:00:00Z","topic_history":null,` +
 		`"channel_modes":{"modes":null,"arg_modes":null,` +
 		`"address_modes":null,"addresses":0,"mode_kinds":null}}`
 
@@ -201,3 +204,72 @@ func TestChannel_JSONify(t *testing.T) {
 		t.Error("A and B differ:", a, b)
 	}
 }
+
+func TestChannel_SetTopic(t *testing.T) {
+	t.Parallel()
+
+	ch := NewChannel("name", testKinds)
+	when := time.Unix(1000, 0)
+
+	ch.SetTopic("topic", "nick!user@host", when)
+	if exp, got := ch.Topic, "topic"; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if exp, got := ch.TopicSetter, "nick!user@host"; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if exp, got := ch.TopicTime, when; !exp.Equal(got) {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if exp, got := len(ch.TopicHistory), 1; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	// Repeating the same topic should not add to the history.
+	ch.SetTopic("topic", "nick!user@host", when)
+	if exp, got := len(ch.TopicHistory), 1; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	for i := 0; i < nMaxTopicHistory+5; i++ {
+		ch.SetTopic("topic", "nick", when.Add(time.Duration(i)*time.Second))
+	}
+	if exp, got := len(ch.TopicHistory), nMaxTopicHistory; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	last := ch.TopicHistory[len(ch.TopicHistory)-1]
+	if exp, got := last.Time, ch.TopicTime; !exp.Equal(got) {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	clone := ch.Clone()
+	clone.TopicHistory[0].Topic = "changed"
+	if exp, got := ch.TopicHistory[0].Topic, "topic"; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+}
+
+func TestChannel_ToProto(t *testing.T) {
+	t.Parallel()
+
+	ch := NewChannel("name", testKinds)
+	ch.Created = time.Unix(500, 0)
+	ch.SetTopic("topic", "nick", time.Unix(1000, 0))
+
+	proto := ch.ToProto()
+	if exp, got := proto.TopicSetter, "nick"; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if exp, got := proto.TopicTime, int64(1000); exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if exp, got := proto.Created, int64(500); exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if exp, got := len(proto.TopicHistory), 1; exp != got {
+		t.Fatalf("Expected: %v, got: %v", exp, got)
+	}
+	if exp, got := proto.TopicHistory[0].Topic, "topic"; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+}
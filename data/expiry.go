@@ -0,0 +1,96 @@
+package data
+
+import (
+	"strings"
+	"time"
+)
+
+// ExpiredAccess is access that was revoked because it expired.
+type ExpiredAccess struct {
+	Username string
+	Network  string
+	Channel  string
+	Access   Access
+}
+
+// SetExpiryHandler sets a function to call for each access that's revoked
+// by ExpireAccess. It's called without the store locked.
+func (s *Store) SetExpiryHandler(handler func(ExpiredAccess)) {
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	s.expiryHandler = handler
+}
+
+// ExpireAccess revokes the access that has expired from every user and
+// records it in the audit log.
+func (s *Store) ExpireAccess() ([]ExpiredAccess, error) {
+	s.protect.Lock()
+
+	users, err := s.indexedUsers(s.db, expiryIndexPrefix, "")
+	if err != nil {
+		s.protect.Unlock()
+		return nil, err
+	}
+
+	now := time.Now()
+	var changed []*StoredUser
+	var expired []ExpiredAccess
+	var entries []AuditEntry
+
+	for _, user := range users {
+		before := user.Clone()
+		revoked := user.RevokeExpired(now)
+		if len(revoked) == 0 {
+			continue
+		}
+
+		changed = append(changed, user)
+		expired = append(expired, revoked...)
+		entries = append(entries, AuditAccessChanges(AuditEntry{
+			Source: "store",
+			Action: "expire",
+			Target: user.Username,
+		}, before, user)...)
+	}
+
+	if len(changed) == 0 {
+		s.protect.Unlock()
+		return nil, nil
+	}
+
+	err = s.db.Update(func(tx Tx) error {
+		for _, user := range changed {
+			if err := saveUserTx(tx, user); err != nil {
+				return err
+			}
+		}
+		return s.auditTx(tx, entries)
+	})
+	for _, user := range changed {
+		if err != nil {
+			s.cache.remove(strings.ToLower(user.Username))
+		} else {
+			s.cache.put(strings.ToLower(user.Username), user.Clone())
+		}
+	}
+	if err == nil {
+		for _, user := range changed {
+			s.notifyChange(userSaved(user))
+		}
+		s.audited(entries)
+	}
+
+	handler := s.expiryHandler
+	s.protect.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if handler != nil {
+		for _, e := range expired {
+			handler(e)
+		}
+	}
+	return expired, nil
+}
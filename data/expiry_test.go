@@ -0,0 +1,163 @@
+package data
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoredUser_Expiry(t *testing.T) {
+	t.Parallel()
+
+	s := createStoredUser()
+	s.Grant(network, channel, 50, "ab")
+	s.Grant(network, "", 10)
+
+	if s.SetExpiry("other", "", time.Now()) {
+		t.Error("Should not set an expiry without access.")
+	}
+	if !s.SetExpiry(network, channel, time.Now().Add(time.Hour)) {
+		t.Fatal("Should set the expiry.")
+	}
+	if a, _ := s.GetAccess(network, channel); !strings.Contains(a.String(), " until ") {
+		t.Error("Expected the expiry to be shown, got:", a.String())
+	}
+	if !s.Has(network, channel, 50, "a") {
+		t.Error("Expected access that hasn't expired.")
+	}
+
+	s.SetExpiry(network, channel, time.Now().Add(-time.Second))
+	if _, ok := s.GetAccess(network, channel); ok {
+		t.Error("Expected expired access to be ignored.")
+	}
+	if s.HasLevel(network, channel, 50) || s.HasFlags(network, channel, "a") {
+		t.Error("Expected expired access not to be had.")
+	}
+	if !s.HasLevel(network, channel, 10) {
+		t.Error("Expected the network access to remain.")
+	}
+
+	expired := s.RevokeExpired(time.Now())
+	if len(expired) != 1 {
+		t.Fatal("Expected one expired access, got:", expired)
+	}
+	if e := expired[0]; e.Username != s.Username || e.Network != network ||
+		e.Channel != strings.ToLower(channel) || e.Access.Level != 50 {
+
+		t.Errorf("Wrong expired access: %#v", e)
+	}
+	if len(s.Access) != 1 {
+		t.Error("Expected the expired access to be removed, got:", s.Access)
+	}
+
+	// Granting over expired access starts over.
+	s.Grant("", "", 0, "c")
+	s.SetExpiry("", "", time.Now().Add(-time.Second))
+	s.Grant("", "", 0, "d")
+	if a, _ := s.GetAccess("", ""); a.HasFlag('c') || !a.HasFlag('d') || a.Expires != 0 {
+		t.Errorf("Expected only the new access, got: %#v", a)
+	}
+}
+
+func TestStoredUser_GrantUntil(t *testing.T) {
+	t.Parallel()
+
+	s := createStoredUser()
+	s.Grant(network, channel, 100, "ab")
+
+	week := time.Now().Add(7 * 24 * time.Hour)
+	if err := s.GrantUntil(network, channel, week, 0, "c"); err != ErrMixedExpiry {
+		t.Error("Expected expiring access not to be given over permanent:", err)
+	}
+	if a, _ := s.GetAccess(network, channel); a.HasFlag('c') || a.Expires != 0 {
+		t.Errorf("Expected the permanent access to be unchanged: %#v", a)
+	}
+
+	if err := s.GrantUntil(network, "", week, 10, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.GrantUntil(network, "", time.Time{}, 0, "d"); err != ErrMixedExpiry {
+		t.Error("Expected permanent access not to be given over expiring:", err)
+	}
+	if err := s.GrantUntil(network, "", week.Add(time.Hour), 0, "e"); err != nil {
+		t.Error(err)
+	}
+	a, _ := s.GetAccess(network, "")
+	if a.HasFlag('d') || !a.HasFlags("ce") || a.Expires != week.Add(time.Hour).Unix() {
+		t.Errorf("Expected the expiring access to be added to: %#v", a)
+	}
+
+	s.SetExpiry(network, "", time.Now().Add(-time.Second))
+	s.RevokeExpired(time.Now())
+	if _, ok := s.GetAccess(network, ""); ok {
+		t.Error("Expected the expiring access to be revoked.")
+	}
+	if a, _ := s.GetAccess(network, channel); !a.HasLevel(100) || !a.HasFlags("ab") {
+		t.Errorf("Expected the permanent access to remain: %#v", a)
+	}
+
+	// Once it's revoked, either kind of access can be given.
+	if err := s.GrantUntil(network, "", time.Time{}, 0, "d"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStore_ExpireAccess(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	handled := make(chan ExpiredAccess, 1)
+	s.SetExpiryHandler(func(e ExpiredAccess) { handled <- e })
+
+	user, err := NewStoredUser(uname, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Grant(network, channel, 50)
+	user.SetExpiry(network, channel, time.Now().Add(time.Hour))
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	if expired, err := s.ExpireAccess(); err != nil || len(expired) != 0 {
+		t.Fatal("Expected nothing to expire yet:", expired, err)
+	}
+
+	user.SetExpiry(network, channel, time.Now().Add(-time.Second))
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if expired, err := s.ExpireAccess(); err != nil || len(expired) != 1 {
+		t.Fatal("Expected the access to expire:", expired, err)
+	}
+
+	select {
+	case e := <-handled:
+		if e.Username != user.Username || e.Access.Level != 50 {
+			t.Errorf("Wrong expired access: %#v", e)
+		}
+	default:
+		t.Error("Expected the handler to be called.")
+	}
+
+	found, err := s.FindUser(user.Username)
+	if err != nil || found == nil {
+		t.Fatal("Expected to find the user:", err)
+	}
+	if len(found.Access) != 0 {
+		t.Error("Expected the access to be revoked, got:", found.Access)
+	}
+	if users, _ := s.indexedUsers(s.db, expiryIndexPrefix, ""); len(users) != 0 {
+		t.Error("Expected the user to be unindexed, got:", users)
+	}
+
+	entries, err := s.AuditLog(AuditQuery{Action: "expire"})
+	if err != nil || len(entries) != 1 || !strings.HasPrefix(entries[0].Before, "50 until") {
+		t.Errorf("Expected the expiry to be audited: %#v %v", entries, err)
+	}
+}
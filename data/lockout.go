@@ -0,0 +1,272 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aarondl/ultimateq/irc"
+)
+
+// These control how failed authentications are throttled. Once a username or
+// host has failed lockoutFreeAttempts times each further attempt must wait an
+// exponentially growing amount of time. An account that fails
+// lockoutThreshold times is locked for lockoutDuration. Failures are
+// forgotten after lockoutWindow without another.
+var (
+	lockoutFreeAttempts = 3
+	lockoutBackoffBase  = 2 * time.Second
+	lockoutBackoffMax   = 5 * time.Minute
+	lockoutThreshold    = 10
+	lockoutDuration     = 30 * time.Minute
+	lockoutWindow       = time.Hour
+)
+
+const (
+	// errFmtThrottled occurs when authentication is attempted too quickly
+	// after failing.
+	errFmtThrottled = "Too many failed attempts, try again in %v."
+	// errFmtLockedOut occurs when the account has failed too many times.
+	errFmtLockedOut = "User [%v] is locked out for %v."
+)
+
+// Lockout describes a username or host that is being throttled or locked out
+// because of failed authentications.
+type Lockout struct {
+	// Username is set when an account is throttled or locked.
+	Username string
+	// Network and Host are set when a host is throttled. Host is only the
+	// hostname portion of the host the attempts came from.
+	Network string
+	Host    string
+
+	Failures    int
+	LastFailure time.Time
+	// Until is when the next authentication will be allowed.
+	Until time.Time
+	// Locked is true if the account has been locked rather than throttled.
+	Locked bool
+}
+
+// authFailures tracks failed authentications for a username or a host.
+type authFailures struct {
+	Lockout
+}
+
+// retryAt is the earliest time another attempt is allowed.
+func (a *authFailures) retryAt() time.Time {
+	if a.Locked {
+		return a.Until
+	}
+	if a.Failures < lockoutFreeAttempts {
+		return time.Time{}
+	}
+
+	backoff := lockoutBackoffMax
+	if shift := uint(a.Failures - lockoutFreeAttempts); shift < 32 {
+		if b := lockoutBackoffBase << shift; b < backoff {
+			backoff = b
+		}
+	}
+	return a.LastFailure.Add(backoff)
+}
+
+// expired checks if the failures should be forgotten.
+func (a *authFailures) expired(now time.Time) bool {
+	return now.After(a.retryAt()) && now.Sub(a.LastFailure) > lockoutWindow
+}
+
+// hostFailureKey is the key hosts are tracked by. Only the hostname is used
+// so changing nick or username doesn't reset the count.
+func hostFailureKey(network, host string) (key, hostname string) {
+	hostname = strings.ToLower(irc.Host(host).Hostname())
+	return network + "\x00" + hostname, hostname
+}
+
+// SetLockoutHandler sets a function to call when an account becomes locked,
+// it's called on it's own goroutine.
+func (s *Store) SetLockoutHandler(fn func(Lockout)) {
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	s.lockoutHandler = fn
+}
+
+// Lockouts returns the usernames and hosts that are currently throttled or
+// locked out. Accounts come first, each sorted by name.
+func (s *Store) Lockouts() []Lockout {
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	now := time.Now().UTC()
+	var users, hosts []Lockout
+	for _, f := range s.userFailures {
+		if until := f.retryAt(); until.After(now) {
+			l := f.Lockout
+			l.Until = until
+			users = append(users, l)
+		}
+	}
+	for _, f := range s.hostFailures {
+		if until := f.retryAt(); until.After(now) {
+			l := f.Lockout
+			l.Until = until
+			hosts = append(hosts, l)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Network != hosts[j].Network {
+			return hosts[i].Network < hosts[j].Network
+		}
+		return hosts[i].Host < hosts[j].Host
+	})
+
+	return append(users, hosts...)
+}
+
+// ClearLockouts forgets the failed authentications of a username, or of a
+// hostname on every network. Returns the number of lockouts cleared.
+func (s *Store) ClearLockouts(usernameOrHost string) int {
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	target := strings.ToLower(usernameOrHost)
+	cleared := 0
+	if _, ok := s.userFailures[target]; ok {
+		delete(s.userFailures, target)
+		cleared++
+	}
+	for key, f := range s.hostFailures {
+		if f.Host == target {
+			delete(s.hostFailures, key)
+			cleared++
+		}
+	}
+	return cleared
+}
+
+// checkLockout returns an error if the username or host may not attempt to
+// authenticate right now.
+// warning: Assumes the store is locked
+func (s *Store) checkLockout(network, host, username string) error {
+	now := time.Now().UTC()
+	s.reapFailures(now)
+
+	hostKey, _ := hostFailureKey(network, host)
+	user, hostF := s.userFailures[username], s.hostFailures[hostKey]
+
+	if user != nil && user.Locked && user.Until.After(now) {
+		return AuthError{
+			fmt.Sprintf(errFmtLockedOut, username, roundWait(user.Until.Sub(now))),
+			AuthErrLockedOut,
+		}
+	}
+
+	var wait time.Duration
+	for _, f := range []*authFailures{user, hostF} {
+		if f == nil {
+			continue
+		}
+		if w := f.retryAt().Sub(now); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		return AuthError{
+			fmt.Sprintf(errFmtThrottled, roundWait(wait)),
+			AuthErrThrottled,
+		}
+	}
+
+	return nil
+}
+
+// recordFailure counts a failed authentication against the host, and against
+// the username if it exists. If the account becomes locked the lockout is
+// returned.
+// warning: Assumes the store is locked
+func (s *Store) recordFailure(network, host, username string,
+	userExists bool) *Lockout {
+
+	now := time.Now().UTC()
+
+	hostKey, hostname := hostFailureKey(network, host)
+	hostF, ok := s.hostFailures[hostKey]
+	if !ok {
+		hostF = &authFailures{Lockout{Network: network, Host: hostname}}
+		s.hostFailures[hostKey] = hostF
+	}
+	hostF.Failures++
+	hostF.LastFailure = now
+
+	// Unknown usernames aren't tracked so they can't be used to grow the
+	// map, the host is still throttled.
+	if !userExists {
+		return nil
+	}
+
+	user, ok := s.userFailures[username]
+	if !ok {
+		user = &authFailures{Lockout{Username: username}}
+		s.userFailures[username] = user
+	}
+	user.Failures++
+	user.LastFailure = now
+
+	if user.Failures >= lockoutThreshold && !user.Locked {
+		user.Locked = true
+		user.Until = now.Add(lockoutDuration)
+		lockout := user.Lockout
+		lockout.Network, lockout.Host = network, host
+		return &lockout
+	}
+	return nil
+}
+
+// clearFailures forgets the failures for a username and host after a
+// successful authentication.
+// warning: Assumes the store is locked
+func (s *Store) clearFailures(network, host, username string) {
+	hostKey, _ := hostFailureKey(network, host)
+	delete(s.hostFailures, hostKey)
+	delete(s.userFailures, username)
+}
+
+// reapFailures forgets failures that have expired.
+// warning: Assumes the store is locked
+func (s *Store) reapFailures(now time.Time) {
+	for key, f := range s.userFailures {
+		if f.Locked && now.After(f.Until) {
+			// The lock has served it's time, start over.
+			delete(s.userFailures, key)
+		} else if f.expired(now) {
+			delete(s.userFailures, key)
+		}
+	}
+	for key, f := range s.hostFailures {
+		if f.expired(now) {
+			delete(s.hostFailures, key)
+		}
+	}
+}
+
+// notifyLockout calls the lockout handler if an account was locked.
+// warning: Assumes the store is locked
+func (s *Store) notifyLockout(lockout *Lockout) {
+	if lockout != nil && s.lockoutHandler != nil {
+		go s.lockoutHandler(*lockout)
+	}
+}
+
+// roundWait rounds a wait up to the second for display.
+func roundWait(d time.Duration) time.Duration {
+	if r := d % time.Second; r != 0 {
+		d += time.Second - r
+	}
+	return d
+}
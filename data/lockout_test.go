@@ -0,0 +1,167 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func setupLockoutTest(t *testing.T) *Store {
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := NewStoredUser(uname, password, "*!*@*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// authFailure checks that authenticating fails with the given failure type.
+func authFailure(t *testing.T, s *Store, host, pwd string, exp AuthFailure) {
+	t.Helper()
+
+	_, err := s.AuthUserTmp(network, host, uname, pwd)
+	authErr, ok := err.(AuthError)
+	if !ok || authErr.FailureType != exp {
+		t.Errorf("Expected failure %v, got: %v", exp, err)
+	}
+}
+
+// rewindFailures makes every failure appear to be d older.
+func rewindFailures(s *Store, d time.Duration) {
+	for _, f := range s.userFailures {
+		f.LastFailure = f.LastFailure.Add(-d)
+	}
+	for _, f := range s.hostFailures {
+		f.LastFailure = f.LastFailure.Add(-d)
+	}
+}
+
+func TestLockout_Backoff(t *testing.T) {
+	t.Parallel()
+
+	s := setupLockoutTest(t)
+	defer s.Close()
+
+	for i := 0; i < lockoutFreeAttempts; i++ {
+		authFailure(t, s, host, "wrong", AuthErrBadPassword)
+	}
+
+	// Even the right password is refused while backing off.
+	authFailure(t, s, host, password, AuthErrThrottled)
+	// And changing nick doesn't help.
+	authFailure(t, s, "othernick!user@host", password, AuthErrThrottled)
+
+	lockouts := s.Lockouts()
+	if len(lockouts) != 2 {
+		t.Fatal("Expected a user and host lockout, got:", lockouts)
+	}
+	if l := lockouts[0]; l.Username != uname || l.Failures != lockoutFreeAttempts || l.Locked {
+		t.Errorf("Wrong user lockout: %#v", l)
+	}
+	if l := lockouts[1]; l.Network != network || l.Host != "host" {
+		t.Errorf("Wrong host lockout: %#v", l)
+	}
+
+	rewindFailures(s, lockoutBackoffBase)
+	if _, err := s.AuthUserTmp(network, host, uname, password); err != nil {
+		t.Fatal("Expected to authenticate after the backoff:", err)
+	}
+	if len(s.Lockouts()) != 0 {
+		t.Error("Expected success to clear the failures.")
+	}
+}
+
+func TestLockout_RetryAt(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	f := &authFailures{Lockout{LastFailure: now}}
+
+	f.Failures = lockoutFreeAttempts - 1
+	if !f.retryAt().IsZero() {
+		t.Error("Expected free attempts not to back off.")
+	}
+
+	f.Failures = lockoutFreeAttempts + 2
+	if got, exp := f.retryAt().Sub(now), 4*lockoutBackoffBase; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	f.Failures = 1000
+	if got, exp := f.retryAt().Sub(now), lockoutBackoffMax; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+}
+
+func TestLockout_Lock(t *testing.T) {
+	t.Parallel()
+
+	s := setupLockoutTest(t)
+	defer s.Close()
+
+	alerts := make(chan Lockout, 1)
+	s.SetLockoutHandler(func(l Lockout) { alerts <- l })
+
+	for i := 0; i < lockoutThreshold; i++ {
+		// Each attempt comes from somewhere new and after the backoff.
+		rewindFailures(s, lockoutBackoffMax)
+		authFailure(t, s, host, "wrong", AuthErrBadPassword)
+	}
+
+	select {
+	case l := <-alerts:
+		if l.Username != uname || !l.Locked || l.Failures != lockoutThreshold ||
+			l.Network != network || l.Host != host {
+
+			t.Errorf("Wrong lockout: %#v", l)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the lockout handler to be called.")
+	}
+
+	rewindFailures(s, lockoutBackoffMax)
+	authFailure(t, s, "nick!user@elsewhere", password, AuthErrLockedOut)
+
+	if got := s.ClearLockouts("HOST"); got != 1 {
+		t.Error("Expected to clear the host, got:", got)
+	}
+	if got := s.ClearLockouts(uname); got != 1 {
+		t.Error("Expected to clear the user, got:", got)
+	}
+	if _, err := s.AuthUserTmp(network, host, uname, password); err != nil {
+		t.Error("Expected to authenticate after clearing:", err)
+	}
+}
+
+func TestLockout_UnknownUser(t *testing.T) {
+	t.Parallel()
+
+	s := setupLockoutTest(t)
+	defer s.Close()
+
+	for i := 0; i < lockoutFreeAttempts; i++ {
+		if _, err := s.AuthUserTmp(network, host, "nobody", password); err == nil {
+			t.Fatal("Expected an error.")
+		}
+	}
+	if len(s.userFailures) != 0 {
+		t.Error("Expected unknown users not to be tracked.")
+	}
+	authFailure(t, s, host, password, AuthErrThrottled)
+
+	rewindFailures(s, lockoutWindow+lockoutBackoffMax)
+	if len(s.Lockouts()) != 0 {
+		t.Error("Expected no lockouts.")
+	}
+	if _, err := s.AuthUserTmp(network, host, uname, password); err != nil {
+		t.Error("Expected old failures to be forgotten:", err)
+	}
+	if len(s.hostFailures) != 0 {
+		t.Error("Expected the host failures to be reaped.")
+	}
+}
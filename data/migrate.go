@@ -0,0 +1,219 @@
+package data
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StoreSchemaVersion is the schema version of the records this package
+// writes. It is always the version of the last migration.
+const StoreSchemaVersion = 2
+
+var (
+	// schemaKey holds the schema version the store's records are at.
+	schemaKey = []byte("\x00schema")
+
+	// errDryRun is used to roll back the migration transaction on a dry run.
+	errDryRun = errors.New("data: dry run")
+)
+
+// Migration is a step that upgrades a store from the previous schema version
+// to Version. It's run inside of a transaction with every other pending step
+// and should describe each change it makes in the report.
+type Migration struct {
+	Version     int
+	Description string
+	Migrate     func(tx Tx, report *MigrationReport) error
+}
+
+// migrations is the registry of all the steps required to bring a store up
+// to StoreSchemaVersion, in order. New steps are appended to the end.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "stamp records with their kind and schema version",
+		Migrate:     migrateStampRecords,
+	},
+	{
+		Version:     2,
+		Description: "index users by access scope and mask",
+		Migrate:     rebuildIndexes,
+	},
+}
+
+// MigrationReport describes what migrating a store did, or on a dry run what
+// it would have done.
+type MigrationReport struct {
+	From   int
+	To     int
+	DryRun bool
+
+	// Applied is the description of each migration step that was run.
+	Applied []string
+	// Changes is a description of each change that was made.
+	Changes []string
+	// Failed are the records that could not be read. They are left as is.
+	Failed RecordErrors
+}
+
+// Changef adds a description of a change to the report.
+func (m *MigrationReport) Changef(format string, args ...interface{}) {
+	m.Changes = append(m.Changes, fmt.Sprintf(format, args...))
+}
+
+// Fail adds a record that could not be migrated to the report, a record is
+// only reported once even if several steps fail on it.
+func (m *MigrationReport) Fail(key []byte, err error) {
+	for _, failed := range m.Failed {
+		if failed.Key == string(key) {
+			return
+		}
+	}
+	m.Failed = append(m.Failed, RecordError{Key: string(key), Err: err})
+}
+
+// MigrateStore opens the store's database and brings it up to the current
+// schema version. If dryRun is true nothing is saved, but the report shows
+// what would have changed.
+func MigrateStore(prov DbProvider, dryRun bool) (*MigrationReport, error) {
+	db, err := prov()
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := migrate(db, dryRun)
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	return report, err
+}
+
+// migrate runs each migration newer than the schema version of db in a
+// single transaction.
+func migrate(db Backend, dryRun bool) (*MigrationReport, error) {
+	report := &MigrationReport{To: StoreSchemaVersion, DryRun: dryRun}
+
+	err := db.Update(func(tx Tx) error {
+		from, err := schemaVersion(tx)
+		if err != nil {
+			return err
+		}
+		report.From = from
+
+		if from > StoreSchemaVersion {
+			return fmt.Errorf("data: store schema version %d is newer than %d",
+				from, StoreSchemaVersion)
+		} else if from == StoreSchemaVersion {
+			return nil
+		}
+
+		for _, m := range migrations {
+			if m.Version <= from {
+				continue
+			}
+
+			if err = m.Migrate(tx, report); err != nil {
+				return fmt.Errorf("data: migration to version %d failed: %v",
+					m.Version, err)
+			}
+			report.Applied = append(report.Applied, m.Description)
+		}
+
+		err = tx.Put(schemaKey, []byte(strconv.Itoa(StoreSchemaVersion)))
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && err != errDryRun {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// schemaVersion reads the schema version of the store, a store without one
+// predates versioning.
+func schemaVersion(tx Tx) (int, error) {
+	val, err := tx.Get(schemaKey)
+	if err != nil || val == nil {
+		return 0, err
+	}
+
+	version, err := strconv.Atoi(string(val))
+	if err != nil {
+		return 0, fmt.Errorf("data: bad store schema version %q", val)
+	}
+	return version, nil
+}
+
+// migrateStampRecords rewrites the bare gob records that were written before
+// versioning with a record header. Users and channels were stored in the same
+// keyspace, so the kind is decided by which of them decodes to the key it's
+// stored under.
+func migrateStampRecords(tx Tx, report *MigrationReport) error {
+	type legacy struct {
+		key, val []byte
+	}
+	var records []legacy
+
+	err := tx.Scan(nil, func(key, val []byte) bool {
+		if !bytes.HasPrefix(key, metaPrefix) && len(val) > 0 &&
+			val[0] != recordMarker {
+			records = append(records, legacy{copyBytes(key), copyBytes(val)})
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		var kind byte
+		var value interface{}
+
+		user := &StoredUser{}
+		ch := &StoredChannel{}
+		if gobDecode(r.val, user) == nil && len(user.Username) > 0 &&
+			strings.ToLower(user.Username) == string(r.key) {
+
+			kind, value = recordUser, user
+		} else if gobDecode(r.val, ch) == nil && len(ch.Name) > 0 &&
+			ch.makeID() == string(r.key) {
+
+			kind, value = recordChannel, ch
+		} else {
+			report.Fail(r.key, errors.New("not a user or channel"))
+			continue
+		}
+
+		record, err := encodeRecord(kind, value)
+		if err != nil {
+			return err
+		}
+		if err = tx.Put(r.key, record); err != nil {
+			return err
+		}
+
+		if kind == recordUser {
+			report.Changef("stamped user [%s]", r.key)
+		} else {
+			report.Changef("stamped channel [%s]", r.key)
+		}
+	}
+
+	return nil
+}
+
+// gobDecode decodes a bare gob stream into v.
+func gobDecode(b []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
@@ -0,0 +1,191 @@
+package data
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strconv"
+	"testing"
+)
+
+// legacyRecord gob encodes v the way records were written before versioning.
+func legacyRecord(t *testing.T, v interface{}) []byte {
+	b := &bytes.Buffer{}
+	if err := gob.NewEncoder(b).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// legacyBackend creates a backend with unversioned records in it.
+func legacyBackend(t *testing.T) Backend {
+	db, err := MemStoreProvider()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := NewStoredUser(uname, password, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := NewStoredChannel(network, channel)
+
+	err = db.Update(func(tx Tx) error {
+		if err := tx.Put([]byte(uname), legacyRecord(t, user)); err != nil {
+			return err
+		}
+		if err := tx.Put([]byte(ch.makeID()), legacyRecord(t, ch)); err != nil {
+			return err
+		}
+		return tx.Put([]byte("junk"), []byte("junk"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestMigrate_Registry(t *testing.T) {
+	t.Parallel()
+
+	for i, m := range migrations {
+		if got, exp := m.Version, i+1; exp != got {
+			t.Errorf("Expected: %v, got: %v", exp, got)
+		}
+	}
+	if got, exp := migrations[len(migrations)-1].Version, StoreSchemaVersion; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+}
+
+func TestMigrate_DryRun(t *testing.T) {
+	t.Parallel()
+
+	db := legacyBackend(t)
+	report, err := migrate(db, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.DryRun || report.From != 0 || report.To != StoreSchemaVersion {
+		t.Errorf("Wrong report: %#v", report)
+	}
+	if got, exp := len(report.Changes), 3; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Key != "junk" {
+		t.Errorf("Expected the junk record to fail, got: %v", report.Failed)
+	}
+
+	if val, _ := db.Get(schemaKey); val != nil {
+		t.Error("Expected the schema version to be rolled back, got:", val)
+	}
+	if val, _ := db.Get([]byte(uname)); len(val) == 0 || val[0] == recordMarker {
+		t.Error("Expected the user to be left alone.")
+	}
+}
+
+func TestMigrate_Legacy(t *testing.T) {
+	t.Parallel()
+
+	db := legacyBackend(t)
+	s, err := NewStore(func() (Backend, error) { return db, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	report := s.Migration()
+	if report.DryRun || len(report.Applied) != len(migrations) {
+		t.Errorf("Wrong report: %#v", report)
+	}
+
+	if val, _ := db.Get(schemaKey); string(val) != strconv.Itoa(StoreSchemaVersion) {
+		t.Error("Expected the schema version to be saved, got:", val)
+	}
+
+	if user, err := s.FindUser(uname); err != nil || user == nil || !user.HasMask(host) {
+		t.Error("Expected the user to be migrated:", err)
+	}
+	if ch, err := s.FindChannel(network, channel); err != nil || ch == nil {
+		t.Error("Expected the channel to be migrated:", err)
+	}
+
+	// The junk record is reported each time the records are scanned.
+	chans, err := s.Channels()
+	if _, ok := err.(RecordErrors); !ok {
+		t.Error("Expected record errors, got:", err)
+	}
+	if len(chans) != 1 {
+		t.Error("Expected the readable channel, got:", chans)
+	}
+
+	// Migrating again does nothing.
+	report, err = migrate(db, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.From != StoreSchemaVersion || len(report.Applied) != 0 {
+		t.Errorf("Wrong report: %#v", report)
+	}
+}
+
+func TestMigrate_Newer(t *testing.T) {
+	t.Parallel()
+
+	db, _ := MemStoreProvider()
+	if err := db.Put(schemaKey, []byte(strconv.Itoa(StoreSchemaVersion+1))); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewStore(func() (Backend, error) { return db, nil }); err == nil {
+		t.Error("Expected an error opening a store from the future.")
+	}
+}
+
+func TestMigrate_MigrateStore(t *testing.T) {
+	t.Parallel()
+
+	report, err := MigrateStore(MemStoreProvider, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.From != 0 || len(report.Applied) != len(migrations) ||
+		len(report.Changes) != 0 {
+
+		t.Errorf("Wrong report: %#v", report)
+	}
+}
+
+func TestRecord_Header(t *testing.T) {
+	t.Parallel()
+
+	user := &StoredUser{Username: uname}
+	record, err := user.serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kind, version, _, err := recordHeader(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind != recordUser || version != StoreSchemaVersion {
+		t.Errorf("Wrong header: %c %d", kind, version)
+	}
+
+	if _, err = deserializeChannel(record); err != errRecordKind {
+		t.Error("Expected a kind error, got:", err)
+	}
+	if _, err = deserializeUser(legacyRecord(t, user)); err != errRecordNotStamped {
+		t.Error("Expected a stamp error, got:", err)
+	}
+	if _, err = deserializeUser([]byte{recordMarker, recordUser}); err != errRecordTruncated {
+		t.Error("Expected a truncated error, got:", err)
+	}
+
+	record[2] = StoreSchemaVersion + 1
+	if _, err = deserializeUser(record); err == nil {
+		t.Error("Expected an error for a newer record.")
+	}
+}
@@ -0,0 +1,465 @@
+package data
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// These are the names of the supported password hashing algorithms.
+const (
+	HasherBcrypt   = "bcrypt"
+	HasherArgon2id = "argon2id"
+	HasherScrypt   = "scrypt"
+)
+
+const (
+	argon2idPrefix = "$argon2id$"
+	scryptPrefix   = "$scrypt$"
+	// bcryptPrefix begins every bcrypt hash regardless of minor version.
+	bcryptPrefix = "$2"
+
+	argon2idDefaultTime    = 1
+	argon2idDefaultMemory  = 64 * 1024
+	argon2idDefaultThreads = 4
+	scryptDefaultLogN      = 15
+	scryptDefaultR         = 8
+	scryptDefaultP         = 1
+
+	// passwordMaxMemory is the most memory in bytes a hash may need to be
+	// verified, stored hashes needing more are rejected.
+	passwordMaxMemory = 1 << 30
+	argon2idMaxTime   = 16
+	scryptMaxLogN     = 20
+	scryptMaxR        = 32
+	scryptMaxP        = 16
+
+	passwordSaltLen = 16
+	passwordKeyLen  = 32
+)
+
+var (
+	// errUnknownHash is given when a stored password wasn't made by any
+	// supported hasher.
+	errUnknownHash = errors.New("data: Password hash algorithm not recognized")
+	// errMalformedHash is given when a stored password can't be parsed.
+	errMalformedHash = errors.New("data: Malformed password hash")
+	// errPasswordIsUsername is given when a password is the username.
+	errPasswordIsUsername = errors.New("Your password may not be your " +
+		"username.")
+)
+
+const (
+	// errFmtUnknownHasher is given when configuring a hasher that doesn't
+	// exist.
+	errFmtUnknownHasher = "data: Unknown password hasher (%v)"
+	// errFmtHasherCost is given when configuring a hasher with a cost it
+	// can't use.
+	errFmtHasherCost = "data: Password cost %v is out of range for %v"
+	// errFmtPasswordTooShort is given when a password is under the minimum
+	// length.
+	errFmtPasswordTooShort = "Your password must be at least %v characters."
+)
+
+// PasswordHasher hashes and verifies passwords with one algorithm. Every
+// hash it makes must begin with a prefix identifying the algorithm and the
+// parameters used, so that hashes outlive changes to the policy.
+type PasswordHasher interface {
+	// Name is the name of the algorithm.
+	Name() string
+	// Identify checks if the hash was made by this algorithm.
+	Identify(hash []byte) bool
+	// Hash hashes a password.
+	Hash(password []byte) ([]byte, error)
+	// Verify checks a password against a hash made by this algorithm using
+	// the parameters stored in the hash.
+	Verify(hash, password []byte) (bool, error)
+	// Current checks if a hash made by this algorithm used the hasher's
+	// current parameters.
+	Current(hash []byte) bool
+}
+
+// passwordHashers are the hashers used to identify stored passwords, their
+// parameters don't matter since verification uses the hash's.
+var passwordHashers = []PasswordHasher{
+	BcryptHasher{}, Argon2idHasher{}, ScryptHasher{},
+}
+
+// NewPasswordHasher creates a hasher by name. Cost is the bcrypt cost, the
+// argon2id time, or the scrypt log2 of N. A cost of 0 uses the default, a
+// cost the algorithm can't use or that would make logins too slow or too
+// memory hungry is an error.
+func NewPasswordHasher(name string, cost int) (PasswordHasher, error) {
+	name = strings.ToLower(name)
+	var hasher PasswordHasher
+	var ok bool
+	switch name {
+	case HasherBcrypt:
+		hasher = BcryptHasher{Cost: cost}
+		ok = cost == 0 || cost >= bcrypt.MinCost && cost <= bcrypt.MaxCost
+	case HasherArgon2id:
+		hasher = Argon2idHasher{Time: uint32(cost)}
+		ok = cost >= 0 && cost <= argon2idMaxTime
+	case HasherScrypt:
+		hasher = ScryptHasher{LogN: cost}
+		ok = cost >= 0 && cost <= scryptMaxLogN
+	default:
+		return nil, fmt.Errorf(errFmtUnknownHasher, name)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf(errFmtHasherCost, cost, name)
+	}
+	return hasher, nil
+}
+
+// SetPasswordHasher sets the hasher the store's new passwords are hashed
+// with. Passwords hashed any other way are rehashed the next time they're
+// verified.
+func (s *Store) SetPasswordHasher(hasher PasswordHasher) {
+	s.policy.protect.Lock()
+	defer s.policy.protect.Unlock()
+
+	s.policy.hasher = hasher
+}
+
+// SetPasswordMinLength sets the shortest password ValidatePassword allows.
+func (s *Store) SetPasswordMinLength(length int) {
+	s.policy.protect.Lock()
+	defer s.policy.protect.Unlock()
+
+	s.policy.minLength = length
+}
+
+// ValidatePassword checks a new password for a user against the store's
+// password rules, the error is suitable for showing to the user.
+func (s *Store) ValidatePassword(username, password string) error {
+	s.policy.protect.RLock()
+	minLength := s.policy.minLength
+	s.policy.protect.RUnlock()
+
+	if len(password) < minLength {
+		return fmt.Errorf(errFmtPasswordTooShort, minLength)
+	}
+	if strings.EqualFold(username, password) {
+		return errPasswordIsUsername
+	}
+	return nil
+}
+
+// NewStoredUser creates a user like the package's NewStoredUser but hashes
+// the password with the store's hasher. The user isn't saved.
+func (s *Store) NewStoredUser(un, pw string, masks ...string) (
+	*StoredUser, error) {
+
+	return newStoredUser(s.policy, un, pw, masks)
+}
+
+// passwordHasher is the hasher new passwords are hashed with, users that
+// aren't from a store use bcrypt.
+func (p *policy) passwordHasher() PasswordHasher {
+	if p == nil {
+		return BcryptHasher{}
+	}
+
+	p.protect.RLock()
+	defer p.protect.RUnlock()
+
+	if p.hasher == nil {
+		return BcryptHasher{}
+	}
+	return p.hasher
+}
+
+// verifyPassword checks a password against a hash made by any hasher. If it
+// matches but wasn't hashed with the current hasher rehash is true.
+func verifyPassword(current PasswordHasher, hash []byte, password string) (
+	ok, rehash bool, err error) {
+
+	var hasher PasswordHasher
+	for _, h := range passwordHashers {
+		if h.Identify(hash) {
+			hasher = h
+			break
+		}
+	}
+	if hasher == nil {
+		return false, false, errUnknownHash
+	}
+
+	if ok, err = hasher.Verify(hash, []byte(password)); !ok || err != nil {
+		return false, false, err
+	}
+
+	rehash = current.Name() != hasher.Name() || !current.Current(hash)
+	return true, rehash, nil
+}
+
+// BcryptHasher hashes passwords with bcrypt.
+type BcryptHasher struct {
+	// Cost is the bcrypt cost, 0 uses StoredUserPwdCost.
+	Cost int
+}
+
+// Name is bcrypt.
+func (b BcryptHasher) Name() string {
+	return HasherBcrypt
+}
+
+// Identify checks for a bcrypt hash.
+func (b BcryptHasher) Identify(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte(bcryptPrefix))
+}
+
+// Hash hashes a password at the hasher's cost.
+func (b BcryptHasher) Hash(password []byte) ([]byte, error) {
+	return bcrypt.GenerateFromPassword(password, b.cost())
+}
+
+// Verify checks a password against a bcrypt hash.
+func (b BcryptHasher) Verify(hash, password []byte) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(hash, password)
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Current checks if the hash was made at the hasher's cost.
+func (b BcryptHasher) Current(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err == nil && cost == b.cost()
+}
+
+func (b BcryptHasher) cost() int {
+	if b.Cost == 0 {
+		return StoredUserPwdCost
+	}
+	return b.Cost
+}
+
+// Argon2idHasher hashes passwords with argon2id. Hashes look like:
+// $argon2id$v=19$m=65536,t=1,p=4$salt$key
+type Argon2idHasher struct {
+	// Time is the number of passes over memory.
+	Time uint32
+	// Memory is in KiB.
+	Memory uint32
+	// Threads is the degree of parallelism.
+	Threads uint8
+}
+
+// Name is argon2id.
+func (a Argon2idHasher) Name() string {
+	return HasherArgon2id
+}
+
+// Identify checks for an argon2id hash.
+func (a Argon2idHasher) Identify(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte(argon2idPrefix))
+}
+
+// Hash hashes a password with a random salt.
+func (a Argon2idHasher) Hash(password []byte) ([]byte, error) {
+	a = a.withDefaults()
+	salt, err := newSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey(password, salt, a.Time, a.Memory, a.Threads,
+		passwordKeyLen)
+	return []byte(fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix,
+		argon2.Version, a.Memory, a.Time, a.Threads,
+		encodeHashPart(salt), encodeHashPart(key))), nil
+}
+
+// Verify checks a password against an argon2id hash.
+func (a Argon2idHasher) Verify(hash, password []byte) (bool, error) {
+	params, salt, key, err := a.parse(hash)
+	if err != nil {
+		return false, err
+	}
+
+	got := argon2.IDKey(password, salt, params.Time, params.Memory,
+		params.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(got, key) == 1, nil
+}
+
+// Current checks if the hash was made with the hasher's parameters.
+func (a Argon2idHasher) Current(hash []byte) bool {
+	params, _, _, err := a.parse(hash)
+	return err == nil && params == a.withDefaults()
+}
+
+func (a Argon2idHasher) withDefaults() Argon2idHasher {
+	if a.Time == 0 {
+		a.Time = argon2idDefaultTime
+	}
+	if a.Memory == 0 {
+		a.Memory = argon2idDefaultMemory
+	}
+	if a.Threads == 0 {
+		a.Threads = argon2idDefaultThreads
+	}
+	return a
+}
+
+// valid checks the parameters can be verified with, argon2 can't run with
+// none of them and too much of them would exhaust the host.
+func (a Argon2idHasher) valid() bool {
+	return a.Time >= 1 && a.Time <= argon2idMaxTime &&
+		a.Memory >= 1 && uint64(a.Memory)*1024 <= passwordMaxMemory &&
+		a.Threads >= 1
+}
+
+func (a Argon2idHasher) parse(hash []byte) (
+	params Argon2idHasher, salt, key []byte, err error) {
+
+	parts := strings.Split(strings.TrimPrefix(string(hash), argon2idPrefix), "$")
+	if len(parts) != 4 {
+		return params, nil, nil, errMalformedHash
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[0], "v=%d", &version); err != nil ||
+		version != argon2.Version {
+		return params, nil, nil, errMalformedHash
+	}
+	_, err = fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d",
+		&params.Memory, &params.Time, &params.Threads)
+	if err != nil || !params.valid() {
+		return params, nil, nil, errMalformedHash
+	}
+
+	if salt, err = decodeHashPart(parts[2]); err != nil {
+		return params, nil, nil, err
+	}
+	key, err = decodeHashPart(parts[3])
+	return params, salt, key, err
+}
+
+// ScryptHasher hashes passwords with scrypt. Hashes look like:
+// $scrypt$ln=15,r=8,p=1$salt$key
+type ScryptHasher struct {
+	// LogN is the log2 of the CPU/memory cost N.
+	LogN int
+	R    int
+	P    int
+}
+
+// Name is scrypt.
+func (s ScryptHasher) Name() string {
+	return HasherScrypt
+}
+
+// Identify checks for an scrypt hash.
+func (s ScryptHasher) Identify(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte(scryptPrefix))
+}
+
+// Hash hashes a password with a random salt.
+func (s ScryptHasher) Hash(password []byte) ([]byte, error) {
+	s = s.withDefaults()
+	salt, err := newSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := scrypt.Key(password, salt, 1<<uint(s.LogN), s.R, s.P,
+		passwordKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("%sln=%d,r=%d,p=%d$%s$%s", scryptPrefix,
+		s.LogN, s.R, s.P, encodeHashPart(salt), encodeHashPart(key))), nil
+}
+
+// Verify checks a password against an scrypt hash.
+func (s ScryptHasher) Verify(hash, password []byte) (bool, error) {
+	params, salt, key, err := s.parse(hash)
+	if err != nil {
+		return false, err
+	}
+
+	got, err := scrypt.Key(password, salt, 1<<uint(params.LogN), params.R,
+		params.P, len(key))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(got, key) == 1, nil
+}
+
+// Current checks if the hash was made with the hasher's parameters.
+func (s ScryptHasher) Current(hash []byte) bool {
+	params, _, _, err := s.parse(hash)
+	return err == nil && params == s.withDefaults()
+}
+
+func (s ScryptHasher) withDefaults() ScryptHasher {
+	if s.LogN == 0 {
+		s.LogN = scryptDefaultLogN
+	}
+	if s.R == 0 {
+		s.R = scryptDefaultR
+	}
+	if s.P == 0 {
+		s.P = scryptDefaultP
+	}
+	return s
+}
+
+// valid checks the parameters can be verified with, scrypt uses 128·r·N
+// bytes so they're bounded by passwordMaxMemory.
+func (s ScryptHasher) valid() bool {
+	return s.LogN >= 1 && s.LogN <= scryptMaxLogN &&
+		s.R >= 1 && s.R <= scryptMaxR && s.P >= 1 && s.P <= scryptMaxP &&
+		uint64(128*s.R)<<uint(s.LogN) <= passwordMaxMemory
+}
+
+func (s ScryptHasher) parse(hash []byte) (
+	params ScryptHasher, salt, key []byte, err error) {
+
+	parts := strings.Split(strings.TrimPrefix(string(hash), scryptPrefix), "$")
+	if len(parts) != 3 {
+		return params, nil, nil, errMalformedHash
+	}
+
+	_, err = fmt.Sscanf(parts[0], "ln=%d,r=%d,p=%d",
+		&params.LogN, &params.R, &params.P)
+	if err != nil || !params.valid() {
+		return params, nil, nil, errMalformedHash
+	}
+
+	if salt, err = decodeHashPart(parts[1]); err != nil {
+		return params, nil, nil, err
+	}
+	key, err = decodeHashPart(parts[2])
+	return params, salt, key, err
+}
+
+func newSalt() ([]byte, error) {
+	salt := make([]byte, passwordSaltLen)
+	_, err := rand.Read(salt)
+	return salt, err
+}
+
+func encodeHashPart(b []byte) string {
+	return base64.RawStdEncoding.EncodeToString(b)
+}
+
+func decodeHashPart(s string) ([]byte, error) {
+	b, err := base64.RawStdEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errMalformedHash
+	}
+	return b, nil
+}
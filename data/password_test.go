@@ -0,0 +1,233 @@
+package data
+
+import (
+	"bytes"
+	"testing"
+)
+
+var (
+	testArgon2id = Argon2idHasher{Time: 1, Memory: 1024, Threads: 1}
+	testScrypt   = ScryptHasher{LogN: 10, R: 8, P: 1}
+)
+
+func TestPasswordHashers(t *testing.T) {
+	hashers := []struct {
+		Hasher PasswordHasher
+		Other  PasswordHasher
+	}{
+		{BcryptHasher{}, BcryptHasher{Cost: StoredUserPwdCost + 1}},
+		{testArgon2id, Argon2idHasher{Time: 2, Memory: 1024, Threads: 1}},
+		{testScrypt, ScryptHasher{LogN: 11, R: 8, P: 1}},
+	}
+
+	for _, test := range hashers {
+		h := test.Hasher
+		hash, err := h.Hash([]byte(password))
+		if err != nil {
+			t.Fatal(h.Name(), err)
+		}
+
+		for _, other := range passwordHashers {
+			if got, exp := other.Identify(hash), other.Name() == h.Name(); exp != got {
+				t.Errorf("%v: Expected %v to identify it: %v", h.Name(),
+					other.Name(), exp)
+			}
+		}
+
+		if ok, err := h.Verify(hash, []byte(password)); !ok || err != nil {
+			t.Errorf("%v: Expected the password to verify: %v", h.Name(), err)
+		}
+		if ok, err := h.Verify(hash, []byte("wrong")); ok || err != nil {
+			t.Errorf("%v: Expected a wrong password not to verify: %v",
+				h.Name(), err)
+		}
+
+		if !h.Current(hash) {
+			t.Errorf("%v: Expected the hash to be current.", h.Name())
+		}
+		if test.Other.Current(hash) {
+			t.Errorf("%v: Expected other parameters not to be current.",
+				h.Name())
+		}
+	}
+}
+
+func TestPasswordHashers_Malformed(t *testing.T) {
+	bad := []string{
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=1$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=0$c2FsdA$a2V5",
+		"$argon2id$v=19$m=0,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=4194304,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=4294967295,p=1$c2FsdA$a2V5",
+		"$scrypt$ln=99,r=8,p=1$c2FsdA$a2V5",
+		"$scrypt$ln=21,r=8,p=1$c2FsdA$a2V5",
+		"$scrypt$ln=-1,r=8,p=1$c2FsdA$a2V5",
+		"$scrypt$ln=20,r=32,p=1$c2FsdA$a2V5",
+		"$scrypt$ln=10,r=0,p=1$c2FsdA$a2V5",
+		"$scrypt$ln=10,r=8,p=0$c2FsdA$a2V5",
+		"$scrypt$ln=10,r=8,p=1$$a2V5",
+		"plaintext",
+	}
+
+	for _, hash := range bad {
+		ok, _, err := verifyPassword(BcryptHasher{}, []byte(hash), password)
+		if ok || err == nil {
+			t.Errorf("%q: Expected an error.", hash)
+		}
+	}
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	for _, name := range []string{"bcrypt", "Argon2id", "scrypt"} {
+		if h, err := NewPasswordHasher(name, 0); err != nil || h == nil {
+			t.Errorf("%v: Expected a hasher: %v", name, err)
+		}
+	}
+
+	h, _ := NewPasswordHasher("bcrypt", 5)
+	if b := h.(BcryptHasher); b.Cost != 5 {
+		t.Error("Expected the cost to be set, got:", b.Cost)
+	}
+
+	if _, err := NewPasswordHasher("md5", 0); err == nil {
+		t.Error("Expected an unknown hasher to fail.")
+	}
+
+	bad := []struct {
+		Name string
+		Cost int
+	}{
+		{"bcrypt", 2},
+		{"bcrypt", 32},
+		{"argon2id", -1},
+		{"argon2id", argon2idMaxTime + 1},
+		{"argon2id", 1 << 32},
+		{"scrypt", -1},
+		{"scrypt", scryptMaxLogN + 1},
+		{"scrypt", 31},
+	}
+	for _, test := range bad {
+		if _, err := NewPasswordHasher(test.Name, test.Cost); err == nil {
+			t.Errorf("%v: Expected a cost of %v to fail.", test.Name, test.Cost)
+		}
+	}
+	if _, err := NewPasswordHasher("argon2id", argon2idMaxTime); err != nil {
+		t.Error("Expected the highest argon2id cost to be allowed:", err)
+	}
+	if _, err := NewPasswordHasher("scrypt", scryptMaxLogN); err != nil {
+		t.Error("Expected the highest scrypt cost to be allowed:", err)
+	}
+}
+
+func TestStoredUser_Rehash(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	user, err := s.NewStoredUser(uname, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := user.Password
+
+	if ok, rehashed := user.verifyPassword(password); !ok || rehashed {
+		t.Error("Expected a current hash not to be rehashed.")
+	}
+
+	s.SetPasswordHasher(testArgon2id)
+	if ok, rehashed := user.verifyPassword("wrong"); ok || rehashed {
+		t.Error("Expected a wrong password not to be rehashed.")
+	}
+	if !user.VerifyPassword(password) {
+		t.Fatal("Expected the old hash to verify.")
+	}
+	if bytes.Equal(old, user.Password) || !testArgon2id.Current(user.Password) {
+		t.Errorf("Expected an argon2id hash, got: %s", user.Password)
+	}
+	if ok, rehashed := user.verifyPassword(password); !ok || rehashed {
+		t.Error("Expected the new hash to verify without rehashing.")
+	}
+}
+
+func TestStore_AuthRehash(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	user, err := NewStoredUser(uname, password, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetPasswordHasher(testScrypt)
+	if _, err = s.AuthUserTmp(network, host, uname, password); err != nil {
+		t.Fatal(err)
+	}
+
+	s.cache.reset()
+	found, err := s.FindUser(uname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !testScrypt.Current(found.Password) {
+		t.Errorf("Expected the rehash to be saved, got: %s", found.Password)
+	}
+}
+
+func TestStore_PasswordPolicy(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	other, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	s.SetPasswordHasher(testScrypt)
+	s.SetPasswordMinLength(8)
+
+	if err := s.ValidatePassword(uname, "short"); err == nil {
+		t.Error("Expected a short password to fail.")
+	}
+	if err := s.ValidatePassword("username", "USERNAME"); err != errPasswordIsUsername {
+		t.Error("Expected the username to fail, got:", err)
+	}
+	if err := s.ValidatePassword(uname, "longenough"); err != nil {
+		t.Error("Expected the password to be valid:", err)
+	}
+	if err := other.ValidatePassword(uname, "short"); err != nil {
+		t.Error("Expected another store's rules not to apply:", err)
+	}
+
+	user, err := s.NewStoredUser(uname, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !testScrypt.Current(user.Password) {
+		t.Errorf("Expected the store's hasher to be used, got: %s",
+			user.Password)
+	}
+	user, err = other.NewStoredUser(uname, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !(BcryptHasher{}).Current(user.Password) {
+		t.Errorf("Expected another store's hasher not to be used, got: %s",
+			user.Password)
+	}
+}
@@ -0,0 +1,209 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// rgxPermission matches a namespaced permission like quotes.delete, the last
+// part of a grant may be a * to match everything in the namespace.
+var rgxPermission = regexp.MustCompile(
+	`^[a-z0-9_-]+(\.[a-z0-9_-]+)*(\.\*)?$`)
+
+// Permission is a named permission an extension's commands can require, it
+// extends the single letter flags with names that won't collide.
+type Permission struct {
+	Name string
+	// Extension is the extension that declared the permission.
+	Extension string
+	Desc      string
+}
+
+// permissionCatalog holds the permissions declared by extensions.
+var permissionCatalog = struct {
+	sync.RWMutex
+	perms map[string]Permission
+}{
+	perms: make(map[string]Permission),
+}
+
+// ValidPermission checks if a permission name can be required by a command.
+// Wildcards can be granted but not required.
+func ValidPermission(perm string) bool {
+	return rgxPermission.MatchString(perm) && !strings.HasSuffix(perm, "*")
+}
+
+// validGrant checks if a permission can be granted, it can be a wildcard for
+// a namespace or * for everything, and a leading - denies it instead.
+func validGrant(perm string) bool {
+	perm = strings.TrimPrefix(perm, "-")
+	return perm == "*" || rgxPermission.MatchString(perm)
+}
+
+// DeclarePermissions adds an extension's permissions to the catalog. It's an
+// error to declare a permission another extension already has, none of the
+// permissions are declared in that case.
+func DeclarePermissions(ext string, perms ...Permission) error {
+	ext = strings.ToLower(ext)
+
+	permissionCatalog.Lock()
+	defer permissionCatalog.Unlock()
+
+	declared := make([]Permission, len(perms))
+	for i, p := range perms {
+		p.Name = strings.ToLower(p.Name)
+		p.Extension = ext
+		if !ValidPermission(p.Name) {
+			return fmt.Errorf("data: invalid permission name: %q", p.Name)
+		}
+		if had, ok := permissionCatalog.perms[p.Name]; ok && had.Extension != ext {
+			return fmt.Errorf("data: permission %q is already declared by %s",
+				p.Name, had.Extension)
+		}
+		declared[i] = p
+	}
+
+	for _, p := range declared {
+		permissionCatalog.perms[p.Name] = p
+	}
+	return nil
+}
+
+// LookupPermission gets a declared permission by name.
+func LookupPermission(name string) (Permission, bool) {
+	permissionCatalog.RLock()
+	defer permissionCatalog.RUnlock()
+
+	p, ok := permissionCatalog.perms[strings.ToLower(name)]
+	return p, ok
+}
+
+// Permissions gets every declared permission sorted by name.
+func Permissions() []Permission {
+	permissionCatalog.RLock()
+	defer permissionCatalog.RUnlock()
+
+	perms := make([]Permission, 0, len(permissionCatalog.perms))
+	for _, p := range permissionCatalog.perms {
+		perms = append(perms, p)
+	}
+	sort.Slice(perms, func(i, j int) bool {
+		return perms[i].Name < perms[j].Name
+	})
+	return perms
+}
+
+// permissionMatches checks if a granted permission gives the wanted one.
+func permissionMatches(granted, wanted string) bool {
+	switch {
+	case granted == "*", granted == wanted:
+		return true
+	case strings.HasSuffix(granted, ".*"):
+		return strings.HasPrefix(wanted, granted[:len(granted)-1])
+	}
+	return false
+}
+
+// GrantPermission grants a permission in the network and channel provided.
+// Returns false if the name is invalid or it was already granted.
+func (s *StoredUser) GrantPermission(network, channel, perm string) bool {
+	perm = strings.ToLower(perm)
+	if !validGrant(perm) {
+		return false
+	}
+
+	key := mkKey(network, channel)
+	for _, p := range s.Permissions[key] {
+		if p == perm {
+			return false
+		}
+	}
+
+	if s.Permissions == nil {
+		s.Permissions = make(map[string][]string)
+	}
+	s.Permissions[key] = append(s.Permissions[key], perm)
+	sort.Strings(s.Permissions[key])
+	return true
+}
+
+// RevokePermission revokes a permission in the network and channel provided.
+// Returns false if it wasn't granted.
+func (s *StoredUser) RevokePermission(network, channel, perm string) bool {
+	key := mkKey(network, channel)
+	perm = strings.ToLower(perm)
+	perms := s.Permissions[key]
+	for i, p := range perms {
+		if p != perm {
+			continue
+		}
+
+		perms = append(perms[:i:i], perms[i+1:]...)
+		if len(perms) == 0 {
+			delete(s.Permissions, key)
+		} else {
+			s.Permissions[key] = perms
+		}
+		return true
+	}
+	return false
+}
+
+// GetPermissions returns the permissions granted in exactly the network and
+// channel provided.
+func (s *StoredUser) GetPermissions(network, channel string) []string {
+	return s.Permissions[mkKey(network, channel)]
+}
+
+// HasPermission checks if a user has been granted a permission, it's
+// resolved like Has.
+func (s *StoredUser) HasPermission(network, channel, perm string) bool {
+	return s.ResolvePermission(network, channel, perm).Allowed
+}
+
+// PermissionString turns StoredUser's permissions into a user consumable
+// format. It's empty when the user has no permissions.
+func (s *StoredUser) PermissionString(network, channel string) string {
+	var keys []string
+	for k := range s.Permissions {
+		if len(network) != 0 && !strings.HasPrefix(k, network) {
+			continue
+		}
+		if len(channel) != 0 && !strings.HasSuffix(k, channel) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+
+	var b = &bytes.Buffer{}
+	for _, k := range keys {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(b, "%s(%s)", scopeName(k),
+			strings.Join(s.Permissions[k], " "))
+	}
+	return b.String()
+}
+
+// scopeName is how a key of the Access map is shown to users.
+func scopeName(key string) string {
+	spl := strings.Split(key, ":")
+	switch n, c := spl[0], spl[1]; {
+	case len(n) > 0 && len(c) > 0:
+		return key
+	case len(n) > 0:
+		return n
+	case len(c) > 0:
+		return c
+	}
+	return "G"
+}
@@ -0,0 +1,115 @@
+package data
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeclarePermissions(t *testing.T) {
+	t.Parallel()
+
+	err := DeclarePermissions("Quotes",
+		Permission{Name: "Quotes.Delete", Desc: "Delete quotes"},
+		Permission{Name: "quotes.edit"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, ok := LookupPermission("quotes.delete")
+	if !ok || p.Extension != "quotes" || p.Desc != "Delete quotes" {
+		t.Error("Expected the permission to be declared, got:", p)
+	}
+
+	if err = DeclarePermissions("quotes", Permission{Name: "quotes.edit"}); err != nil {
+		t.Error("Expected an extension to be able to declare again:", err)
+	}
+	err = DeclarePermissions("other",
+		Permission{Name: "other.thing"},
+		Permission{Name: "quotes.delete"},
+	)
+	if err == nil {
+		t.Error("Expected a collision with another extension to fail.")
+	}
+	if _, ok := LookupPermission("other.thing"); ok {
+		t.Error("Expected nothing to be declared when one collides.")
+	}
+
+	for _, bad := range []string{"", "quotes.*", "*", "two words", "a..b"} {
+		if DeclarePermissions("bad", Permission{Name: bad}) == nil {
+			t.Errorf("Expected %q to be invalid.", bad)
+		}
+	}
+
+	var found int
+	for _, p := range Permissions() {
+		if p.Extension == "quotes" {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Error("Expected both permissions in the catalog, found:", found)
+	}
+}
+
+func TestStoredUser_Permissions(t *testing.T) {
+	t.Parallel()
+
+	s := createStoredUser()
+	if s.HasPermission(network, channel, "quotes.delete") {
+		t.Error("Expected no permissions.")
+	}
+
+	if !s.GrantPermission(network, channel, "Quotes.Delete") {
+		t.Error("Expected to grant the permission.")
+	}
+	if s.GrantPermission(network, channel, "quotes.delete") {
+		t.Error("Expected not to grant the permission twice.")
+	}
+	if s.GrantPermission("", "", "not valid") {
+		t.Error("Expected an invalid permission not to be granted.")
+	}
+
+	if !s.HasPermission(network, channel, "quotes.delete") {
+		t.Error("Expected the permission in its scope.")
+	}
+	if s.HasPermission(network, "", "quotes.delete") ||
+		s.HasPermission(network, channel, "quotes.edit") {
+		t.Error("Expected the permission only in its scope.")
+	}
+
+	s.GrantPermission(network, "", "karma.*")
+	if !s.HasPermission(network, channel, "karma.admin") ||
+		!s.HasPermission(network, "", "karma.admin.reset") {
+		t.Error("Expected the wildcard to grant the namespace.")
+	}
+	if s.HasPermission(network, "", "karmaa.admin") ||
+		s.HasPermission("", "", "karma.admin") {
+		t.Error("Expected the wildcard only for its namespace and scope.")
+	}
+
+	exp := strings.ToLower(network) + "(karma.*) " +
+		mkKey(network, channel) + "(quotes.delete)"
+	if str := s.PermissionString("", ""); str != exp {
+		t.Error("Wrong permission string:", str)
+	}
+
+	s.GrantPermission("", "", "*")
+	if !s.HasPermission("", "", "anything.at.all") {
+		t.Error("Expected * to grant everything.")
+	}
+
+	clone := s.Clone()
+	if !s.RevokePermission(network, channel, "QUOTES.DELETE") {
+		t.Error("Expected to revoke the permission.")
+	}
+	if s.RevokePermission(network, channel, "quotes.delete") {
+		t.Error("Expected not to revoke the permission twice.")
+	}
+	if len(s.GetPermissions(network, channel)) != 0 {
+		t.Error("Expected the scope to be empty.")
+	}
+	if len(clone.GetPermissions(network, channel)) != 1 {
+		t.Error("Expected the clone to keep its permissions.")
+	}
+}
@@ -0,0 +1,156 @@
+package data
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+)
+
+// Every value the store writes is a record. A record is a header followed
+// by the gob encoded payload: the recordMarker byte, a byte for the kind of
+// record, and the schema version the record was written at as a uvarint.
+//
+// Records written before versioning was introduced are a bare gob stream.
+// A gob stream always begins with a byte below 0x80 or above 0xf7 so the
+// marker can never be confused with one.
+const (
+	recordMarker byte = 0x80
+
+	recordUser    byte = 'u'
+	recordChannel byte = 'c'
+)
+
+var (
+	// metaPrefix begins every key that the store uses for it's own
+	// bookkeeping rather than for a record.
+	metaPrefix = []byte{0}
+
+	errRecordNotStamped = errors.New("data: record has no version stamp")
+	errRecordTruncated  = errors.New("data: record header is truncated")
+	// errRecordKind is returned when a record is decoded as the wrong kind,
+	// users and channels share a keyspace so this is expected when scanning.
+	errRecordKind = errors.New("data: record is of a different kind")
+)
+
+// RecordError describes a stored record that could not be read.
+type RecordError struct {
+	Key string
+	Err error
+}
+
+// Error describes the record and why it could not be read.
+func (r RecordError) Error() string {
+	return fmt.Sprintf("data: record [%s]: %v", r.Key, r.Err)
+}
+
+// RecordErrors is returned when some of the records in the store could not be
+// read. Functions that return lists of records still return every record that
+// could be read along with this error.
+type RecordErrors []RecordError
+
+// Error summarizes the failures.
+func (r RecordErrors) Error() string {
+	if len(r) == 1 {
+		return r[0].Error()
+	}
+	return fmt.Sprintf("data: %d records could not be read, first: %v",
+		len(r), r[0])
+}
+
+// Keys are the keys of the records that could not be read.
+func (r RecordErrors) Keys() []string {
+	keys := make([]string, len(r))
+	for i, e := range r {
+		keys[i] = e.Key
+	}
+	return keys
+}
+
+// encodeRecord gob encodes v and stamps it with kind and the current schema
+// version.
+func encodeRecord(kind byte, v interface{}) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	buffer.WriteByte(recordMarker)
+	buffer.WriteByte(kind)
+
+	var version [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(version[:], uint64(StoreSchemaVersion))
+	buffer.Write(version[:n])
+
+	if err := gob.NewEncoder(buffer).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// decodeRecord checks the header of a record and decodes it's payload into v.
+func decodeRecord(kind byte, record []byte, v interface{}) error {
+	k, version, payload, err := recordHeader(record)
+	if err != nil {
+		return err
+	}
+	if k != kind {
+		return errRecordKind
+	}
+	if version > StoreSchemaVersion {
+		return fmt.Errorf("data: record schema version %d is newer than %d",
+			version, StoreSchemaVersion)
+	}
+
+	return gobDecode(payload, v)
+}
+
+// recordHeader splits a record into it's header fields and payload.
+func recordHeader(record []byte) (kind byte, version int, payload []byte,
+	err error) {
+
+	if len(record) == 0 || record[0] != recordMarker {
+		return 0, 0, nil, errRecordNotStamped
+	}
+	if len(record) < 3 {
+		return 0, 0, nil, errRecordTruncated
+	}
+
+	v, n := binary.Uvarint(record[2:])
+	if n <= 0 {
+		return 0, 0, nil, errRecordTruncated
+	}
+
+	return record[1], int(v), record[2+n:], nil
+}
+
+// scanRecords calls fn with each record of kind in the store. Records of
+// other kinds are skipped, but records that can't be read are collected and
+// returned as RecordErrors after the scan completes.
+func scanRecords(tx Tx, kind byte, fn func(key, record []byte) error) error {
+	var failed RecordErrors
+
+	err := tx.Scan(nil, func(key, val []byte) bool {
+		if bytes.HasPrefix(key, metaPrefix) {
+			return true
+		}
+
+		k, _, _, err := recordHeader(val)
+		if err == nil && k != kind {
+			return true
+		}
+		if err == nil {
+			err = fn(key, val)
+		}
+		if err != nil {
+			failed = append(failed, RecordError{Key: string(key), Err: err})
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package data
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/aarondl/ultimateq/api"
+)
+
+const (
+	// recordRole is the kind of record a stored role is stored as.
+	recordRole byte = 'r'
+)
+
+var (
+	// rolePrefix begins the key of every stored role.
+	rolePrefix = []byte("\x00role:")
+
+	// errRoleName is returned when a role has no name or one with spaces.
+	errRoleName = errors.New("data: role names must be a single word")
+	// errRoleConfig is returned when changing a role that's in the config.
+	errRoleConfig = errors.New("data: role is defined in the config")
+)
+
+// Role is a named level and flags. A user given a role in a scope has the
+// role's access there as well as any access granted to them directly.
+type Role struct {
+	Name   string
+	Access Access
+	// Config is true for roles defined in the config rather than the store,
+	// they can't be changed through the store.
+	Config bool
+}
+
+// ToProto converts a role to its protocol buffer form.
+func (r Role) ToProto() *api.Role {
+	return &api.Role{
+		Name:   r.Name,
+		Access: r.Access.ToProto(),
+		Config: r.Config,
+	}
+}
+
+// FromProto fills a role from its protocol buffer form.
+func (r *Role) FromProto(proto *api.Role) {
+	r.Name = proto.Name
+	r.Config = proto.Config
+	r.Access = Access{}
+	if proto.Access != nil {
+		r.Access.FromProto(proto.Access)
+	}
+}
+
+// lookupRole gets a role by name, config roles take precedence over stored
+// ones. A user that isn't from a store has no policy and so no roles.
+func (p *policy) lookupRole(name string) (Role, bool) {
+	if p == nil {
+		return Role{}, false
+	}
+
+	p.protect.RLock()
+	defer p.protect.RUnlock()
+
+	name = strings.ToLower(name)
+	if role, ok := p.config[name]; ok {
+		return role, true
+	}
+	role, ok := p.stored[name]
+	return role, ok
+}
+
+// SetConfigRoles replaces the roles defined in the config.
+func (s *Store) SetConfigRoles(roles []Role) {
+	config := make(map[string]Role, len(roles))
+	for _, role := range roles {
+		role.Name = strings.ToLower(role.Name)
+		role.Config = true
+		config[role.Name] = role
+	}
+
+	s.policy.protect.Lock()
+	s.policy.config = config
+	s.policy.protect.Unlock()
+}
+
+// LookupRole gets a role by name, config roles take precedence over stored
+// ones.
+func (s *Store) LookupRole(name string) (Role, bool) {
+	return s.policy.lookupRole(name)
+}
+
+// Roles gets every role sorted by name.
+func (s *Store) Roles() []Role {
+	s.policy.protect.RLock()
+	defer s.policy.protect.RUnlock()
+
+	roles := make([]Role, 0, len(s.policy.config)+len(s.policy.stored))
+	for _, role := range s.policy.config {
+		roles = append(roles, role)
+	}
+	for name, role := range s.policy.stored {
+		if _, ok := s.policy.config[name]; !ok {
+			roles = append(roles, role)
+		}
+	}
+
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+	return roles
+}
+
+// storedRoles gets the roles saved in the store sorted by name.
+func (s *Store) storedRoles() []Role {
+	s.policy.protect.RLock()
+	defer s.policy.protect.RUnlock()
+
+	roles := make([]Role, 0, len(s.policy.stored))
+	for _, role := range s.policy.stored {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+	return roles
+}
+
+// SaveRole creates or replaces a role in the store, the audit entries are
+// written with it.
+func (s *Store) SaveRole(role Role, audit ...AuditEntry) error {
+	role, err := cleanRole(role)
+	if err != nil {
+		return err
+	}
+
+	// Hold the lock over the write so saves and removes of a role can't
+	// leave the database and the policy with different roles.
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	if r, ok := s.policy.lookupRole(role.Name); ok && r.Config {
+		return errRoleConfig
+	}
+
+	err = s.db.Update(func(tx Tx) error {
+		if err := saveRoleTx(tx, role); err != nil {
+			return err
+		}
+		return s.auditTx(tx, audit)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.policy.protect.Lock()
+	s.policy.stored[role.Name] = role
+	s.policy.protect.Unlock()
+	s.audited(audit)
+	return nil
+}
+
+// RemoveRole deletes a role from the store. Users keep the role's name but
+// it gives them no access unless the role is made again. The audit entries
+// are written if the role is removed.
+func (s *Store) RemoveRole(name string, audit ...AuditEntry) (bool, error) {
+	name = strings.ToLower(name)
+
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	if r, ok := s.policy.lookupRole(name); !ok {
+		return false, nil
+	} else if r.Config {
+		return false, errRoleConfig
+	}
+
+	err := s.db.Update(func(tx Tx) error {
+		if err := tx.Delete(roleKey(name)); err != nil {
+			return err
+		}
+		return s.auditTx(tx, audit)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	s.policy.protect.Lock()
+	delete(s.policy.stored, name)
+	s.policy.protect.Unlock()
+	s.audited(audit)
+	return true, nil
+}
+
+// cleanRole checks the name of a role that's going to be stored and clears
+// what a stored role can't have.
+func cleanRole(role Role) (Role, error) {
+	role.Name = strings.ToLower(role.Name)
+	role.Config = false
+	role.Access.Expires = 0
+	role.Access.DenyLevel, role.Access.DenyFlags = 0, 0
+	if len(role.Name) == 0 || strings.ContainsAny(role.Name, " \t") {
+		return role, errRoleName
+	}
+	return role, nil
+}
+
+// saveRoleTx writes a role inside a transaction.
+func saveRoleTx(tx Tx, role Role) error {
+	record, err := encodeRecord(recordRole, &role)
+	if err != nil {
+		return err
+	}
+	return tx.Put(roleKey(role.Name), record)
+}
+
+// loadRoles reads the store's roles into its policy. Roles that can't be
+// read are added to the migration report rather than keeping the store from
+// opening, the users given them have no access from them until they're
+// saved again.
+func (s *Store) loadRoles() error {
+	roles := make(map[string]Role)
+	err := s.db.Scan(rolePrefix, func(key, val []byte) bool {
+		var role Role
+		if err := decodeRecord(recordRole, val, &role); err != nil {
+			s.migration.Fail(copyBytes(key), err)
+			return true
+		}
+		roles[role.Name] = role
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	s.policy.protect.Lock()
+	s.policy.stored = roles
+	s.policy.protect.Unlock()
+	return nil
+}
+
+// roleKey is the key of a stored role.
+func roleKey(name string) []byte {
+	return append(append([]byte{}, rolePrefix...), name...)
+}
@@ -0,0 +1,206 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/aarondl/ultimateq/api"
+)
+
+func TestStoredUser_Roles(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	store.SetConfigRoles([]Role{{Name: "Op", Access: *NewAccess(100, "o")}})
+
+	loose := createStoredUser()
+	loose.GiveRole(network, channel, "op")
+	if loose.HasLevel(network, channel, 1) {
+		t.Error("Expected a user from outside a store to get nothing from roles.")
+	}
+
+	s := store.attach(createStoredUser())
+	if s.HasLevel(network, channel, 1) {
+		t.Error("Expected no access without roles.")
+	}
+
+	if !s.GiveRole(network, channel, "OP") {
+		t.Error("Expected to give the role.")
+	}
+	if s.GiveRole(network, channel, "op") {
+		t.Error("Expected not to give the role twice.")
+	}
+	if roles := s.GetRoles(network, channel); len(roles) != 1 || roles[0] != "op" {
+		t.Error("Expected the role to be stored lowercase, got:", roles)
+	}
+
+	if !s.HasLevel(network, channel, 100) || !s.HasFlags(network, channel, "o") {
+		t.Error("Expected the role to give its access.")
+	}
+	if !s.Has(network, channel, 50, "o") {
+		t.Error("Expected the role to give its access.")
+	}
+	if s.HasLevel(network, "", 1) || s.HasLevel("", channel, 1) {
+		t.Error("Expected the role to only give access in its scope.")
+	}
+	if _, ok := s.GetAccess(network, channel); ok {
+		t.Error("Expected roles not to be direct access.")
+	}
+
+	s.Grant(network, channel, 5, "v")
+	a, ok := s.EffectiveAccess(network, channel)
+	if !ok || a.Level != 100 || !a.HasFlags("ov") {
+		t.Error("Expected the union of the role and direct access, got:", a)
+	}
+
+	if str := s.RoleString("", ""); str != mkKey(network, channel)+"(op:100 o)" {
+		t.Error("Wrong role string:", str)
+	}
+
+	if !s.GiveRole("", "", "missing") {
+		t.Error("Expected to give the role.")
+	}
+	if s.HasLevel("", "", 1) {
+		t.Error("Expected a role that doesn't exist to give nothing.")
+	}
+
+	clone := s.Clone()
+	if !s.TakeRole(network, channel, "op") {
+		t.Error("Expected to take the role.")
+	}
+	if s.TakeRole(network, channel, "op") {
+		t.Error("Expected not to take the role twice.")
+	}
+	if s.HasFlags(network, channel, "o") {
+		t.Error("Expected the role's access to be gone.")
+	}
+	if len(clone.GetRoles(network, channel)) != 1 {
+		t.Error("Expected the clone to keep its roles.")
+	}
+}
+
+func TestStore_Roles(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.SetConfigRoles([]Role{{Name: "admin", Access: *NewAccess(255, "G")}})
+
+	if err = s.SaveRole(Role{Name: "admin"}); err == nil {
+		t.Error("Expected config roles not to be changed.")
+	}
+	if err = s.SaveRole(Role{Name: "two words"}); err == nil {
+		t.Error("Expected an invalid name to fail.")
+	}
+	if err = s.SaveRole(Role{Name: "Voice", Access: *NewAccess(0, "v")}); err != nil {
+		t.Fatal(err)
+	}
+	defer s.RemoveRole("voice")
+
+	roles := s.Roles()
+	if len(roles) != 2 || roles[0].Name != "admin" || !roles[0].Config ||
+		roles[1].Name != "voice" || roles[1].Config {
+		t.Error("Wrong roles:", roles)
+	}
+
+	user, err := NewStoredUser(uname, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.GiveRole(network, channel, "voice")
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := s.ChanUsers(network, channel)
+	if err != nil || len(users) != 1 {
+		t.Error("Expected the role to list the user in the channel:", users, err)
+	}
+	if !user.HasFlags(network, channel, "v") {
+		t.Error("Expected the saved user to get the role's access.")
+	}
+	if found, err := s.FindUser(uname); err != nil || !found.HasFlags(network, channel, "v") {
+		t.Error("Expected the cached user to get the role's access:", err)
+	}
+
+	other, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if _, ok := other.LookupRole("voice"); ok {
+		t.Error("Expected stores not to share their roles.")
+	}
+
+	s.policy.protect.Lock()
+	delete(s.policy.stored, "voice")
+	s.policy.protect.Unlock()
+	if err = s.loadRoles(); err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := s.LookupRole("voice"); !ok || !r.Access.HasFlags("v") {
+		t.Error("Expected the stored role to be loaded, got:", r)
+	}
+
+	if _, err = s.RemoveRole("admin"); err == nil {
+		t.Error("Expected config roles not to be removed.")
+	}
+	if ok, err := s.RemoveRole("voice"); err != nil || !ok {
+		t.Error("Expected to remove the role:", err)
+	}
+	if ok, err := s.RemoveRole("voice"); err != nil || ok {
+		t.Error("Expected the role to be gone:", err)
+	}
+
+	users, err = s.ChanUsers(network, channel)
+	if err != nil || len(users) != 0 {
+		t.Error("Expected a removed role to give no access:", users, err)
+	}
+}
+
+func TestStore_LoadRolesReportsFailures(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err = s.db.Put(roleKey("broken"), []byte("not a role")); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.loadRoles(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.LookupRole("broken"); ok {
+		t.Error("Expected the broken role not to be loaded.")
+	}
+	failed := s.Migration().Failed
+	if len(failed) != 1 || failed[0].Key != string(roleKey("broken")) {
+		t.Error("Expected the broken role to be reported, got:", failed)
+	}
+}
+
+func TestRole_Protofy(t *testing.T) {
+	t.Parallel()
+
+	role := Role{Name: "op", Access: *NewAccess(100, "o"), Config: true}
+	var got Role
+	got.FromProto(role.ToProto())
+	if got != role {
+		t.Error("Expected the role to survive a round trip, got:", got)
+	}
+
+	got.FromProto(&api.Role{Name: "bare"})
+	if got.Name != "bare" || !got.Access.IsZero() || got.Config {
+		t.Error("Expected a role without access, got:", got)
+	}
+}
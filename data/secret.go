@@ -0,0 +1,364 @@
+package data
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// secretPrefix begins the keys of secrets in a JSONStorer, it keeps them
+	// apart from other data and out of ToProto.
+	secretPrefix = "secret:"
+	// secretVersion begins every encrypted value, it's followed by the id of
+	// the key it was encrypted with and the sealed value.
+	secretVersion = "v1"
+	// secretKeyInfo binds the keys derived from secret_key to secrets so the
+	// same secret_key can be used for anything else.
+	secretKeyInfo = "ultimateq store secrets"
+)
+
+var (
+	errNoSecretKey = errors.New(
+		"data: no secret_key is set, secrets can't be stored")
+	errSecretCorrupt = errors.New("data: secret is corrupt")
+)
+
+const errFmtSecretKeyUnknown = "data: secret %q was encrypted with an " +
+	"unknown key (%s), is the old secret_key missing?"
+
+// secretKey is a key derived from a secret_key.
+type secretKey struct {
+	// id is stored with each value so it can be decrypted with the same
+	// key, it says nothing about the key itself.
+	id   string
+	aead cipher.AEAD
+}
+
+// secretKeyring holds the keys a store's secrets are encrypted and decrypted
+// with, they're replaced rather than changed.
+type secretKeyring struct {
+	current *secretKey
+	old     map[string]*secretKey
+}
+
+// SetSecretKey sets the key the store's new secrets are encrypted with.
+// Secrets encrypted with any of the old keys can still be read until they're
+// re-encrypted by RotateSecrets. An empty key means secrets can't be stored.
+func (s *Store) SetSecretKey(key string, old ...string) error {
+	var current *secretKey
+	var err error
+	if len(key) != 0 {
+		if current, err = deriveSecretKey(key); err != nil {
+			return err
+		}
+	}
+
+	oldKeys := make(map[string]*secretKey, len(old))
+	for _, o := range old {
+		if len(o) == 0 {
+			continue
+		}
+		k, err := deriveSecretKey(o)
+		if err != nil {
+			return err
+		}
+		oldKeys[k.id] = k
+	}
+
+	s.secretProtect.Lock()
+	defer s.secretProtect.Unlock()
+
+	s.secretKeys = &secretKeyring{current: current, old: oldKeys}
+	return nil
+}
+
+// keyring gets the keys the store's secrets are encrypted with.
+func (s *Store) keyring() *secretKeyring {
+	s.secretProtect.RLock()
+	defer s.secretProtect.RUnlock()
+
+	if s.secretKeys == nil {
+		return &secretKeyring{}
+	}
+	return s.secretKeys
+}
+
+// deriveSecretKey derives an AES-256-GCM key and its id from a secret_key.
+func deriveSecretKey(key string) (*secretKey, error) {
+	kdf := hkdf.New(sha256.New, []byte(key), nil, []byte(secretKeyInfo))
+	material := make([]byte, 32+4)
+	if _, err := io.ReadFull(kdf, material); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(material[:32])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secretKey{id: hex.EncodeToString(material[32:]), aead: aead}, nil
+}
+
+// encrypt seals a value with the current key. The secret's name is
+// authenticated with it so values can't be swapped between names.
+func (k *secretKeyring) encrypt(name, value string) (string, error) {
+	key := k.current
+	if key == nil {
+		return "", errNoSecretKey
+	}
+
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := key.aead.Seal(nonce, nonce, []byte(value), []byte(name))
+
+	return fmt.Sprintf("%s:%s:%s", secretVersion, key.id,
+		base64.RawStdEncoding.EncodeToString(sealed)), nil
+}
+
+// decrypt opens a value sealed by encrypt with whichever key it was sealed
+// with. It also says if that was the current key.
+func (k *secretKeyring) decrypt(name, value string) (plain string,
+	current bool, err error) {
+
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 || parts[0] != secretVersion {
+		return "", false, errSecretCorrupt
+	}
+
+	key := k.old[parts[1]]
+	if c := k.current; c != nil && c.id == parts[1] {
+		key, current = c, true
+	}
+
+	if key == nil {
+		return "", false, fmt.Errorf(errFmtSecretKeyUnknown, name, parts[1])
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil || len(sealed) < key.aead.NonceSize() {
+		return "", false, errSecretCorrupt
+	}
+	nonce, sealed := sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():]
+	opened, err := key.aead.Open(nil, nonce, sealed, []byte(name))
+	if err != nil {
+		return "", false, errSecretCorrupt
+	}
+	return string(opened), current, nil
+}
+
+// PutSecret encrypts a value with the store's secret_key and stores it in js
+// apart from the other values, it's never sent by ToProto.
+func (s *Store) PutSecret(js JSONStorer, key, value string) error {
+	encrypted, err := s.keyring().encrypt(key, value)
+	if err != nil {
+		return err
+	}
+	js[secretPrefix+key] = encrypted
+	return nil
+}
+
+// GetSecret decrypts a value stored in js by PutSecret.
+func (s *Store) GetSecret(js JSONStorer, key string) (string, bool, error) {
+	encrypted, ok := js[secretPrefix+key]
+	if !ok {
+		return "", false, nil
+	}
+
+	value, _, err := s.keyring().decrypt(key, encrypted)
+	if err != nil {
+		return "", true, err
+	}
+	return value, true, nil
+}
+
+// DeleteSecret removes a value stored by PutSecret, returns true if it was
+// there.
+func (js JSONStorer) DeleteSecret(key string) bool {
+	if _, ok := js[secretPrefix+key]; !ok {
+		return false
+	}
+	delete(js, secretPrefix+key)
+	return true
+}
+
+// Secrets decrypts all of the values stored in js by PutSecret.
+func (s *Store) Secrets(js JSONStorer) (map[string]string, error) {
+	keys := s.keyring()
+	var secrets map[string]string
+	for k, v := range js {
+		if !strings.HasPrefix(k, secretPrefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(k, secretPrefix)
+		value, _, err := keys.decrypt(name, v)
+		if err != nil {
+			return nil, err
+		}
+		if secrets == nil {
+			secrets = make(map[string]string)
+		}
+		secrets[name] = value
+	}
+	return secrets, nil
+}
+
+// CopySecrets copies the encrypted values from another JSONStorer that
+// aren't already in this one. It keeps the secrets of a record that was
+// rebuilt from a form they were left out of.
+func (js JSONStorer) CopySecrets(from JSONStorer) {
+	for k, v := range from {
+		if !strings.HasPrefix(k, secretPrefix) {
+			continue
+		}
+		if _, ok := js[k]; !ok {
+			js[k] = v
+		}
+	}
+}
+
+// rotateSecrets re-encrypts the values that weren't encrypted with the
+// current key, returns how many were.
+func (js JSONStorer) rotateSecrets(keys *secretKeyring) (int, error) {
+	n := 0
+	for k, v := range js {
+		if !strings.HasPrefix(k, secretPrefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(k, secretPrefix)
+		value, current, err := keys.decrypt(name, v)
+		if err != nil {
+			return 0, err
+		}
+		if current {
+			continue
+		}
+		if js[k], err = keys.encrypt(name, value); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// dataToProto copies the values that may be sent by ToProto.
+func dataToProto(js JSONStorer) map[string]string {
+	var data map[string]string
+	for k, v := range js {
+		if strings.HasPrefix(k, secretPrefix) {
+			continue
+		}
+		if data == nil {
+			data = make(map[string]string, len(js))
+		}
+		data[k] = v
+	}
+	return data
+}
+
+// dataFromProto copies the values from a protocol buffer, secrets can only
+// be put by PutSecret.
+func dataFromProto(data map[string]string) JSONStorer {
+	if len(data) == 0 {
+		return nil
+	}
+
+	js := make(JSONStorer, len(data))
+	for k, v := range data {
+		if !strings.HasPrefix(k, secretPrefix) {
+			js[k] = v
+		}
+	}
+	return js
+}
+
+// RotateSecrets re-encrypts every secret of every user and channel that
+// wasn't encrypted with the current secret_key, the old keys must still be
+// set so they can be decrypted. It's all or nothing, if any secret can't be
+// decrypted nothing is saved. Returns how many secrets were re-encrypted.
+func (s *Store) RotateSecrets() (int, error) {
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	// Read everything in the same transaction as the save so a change made
+	// in between can't be overwritten by the rotated copy.
+	keys := s.keyring()
+	n := 0
+	var changedUsers []*StoredUser
+	var changedChannels []*StoredChannel
+	err := s.db.Update(func(tx Tx) error {
+		users, err := s.iterate(tx, func(*StoredUser) bool { return true })
+		if err != nil {
+			return err
+		}
+		channels, err := channelsTx(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			rotated, err := user.rotateSecrets(keys)
+			if err != nil {
+				return fmt.Errorf("data: user %s: %v", user.Username, err)
+			}
+			if rotated != 0 {
+				n += rotated
+				changedUsers = append(changedUsers, user)
+			}
+		}
+		for _, ch := range channels {
+			rotated, err := ch.rotateSecrets(keys)
+			if err != nil {
+				return fmt.Errorf("data: channel %s %s: %v", ch.NetID, ch.Name,
+					err)
+			}
+			if rotated != 0 {
+				n += rotated
+				changedChannels = append(changedChannels, ch)
+			}
+		}
+
+		for _, user := range changedUsers {
+			if err := saveUserTx(tx, user); err != nil {
+				return err
+			}
+		}
+		for _, ch := range changedChannels {
+			if err := saveChannelTx(tx, ch); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	for _, user := range changedUsers {
+		s.cache.remove(strings.ToLower(user.Username))
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	for _, user := range changedUsers {
+		s.notifyChange(userSaved(user))
+	}
+	for _, ch := range changedChannels {
+		s.notifyChange(channelSaved(ch))
+	}
+	return n, nil
+}
@@ -0,0 +1,198 @@
+package data
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStore_Secrets(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	js := make(JSONStorer)
+	if err := s.PutSecret(js, "token", "hunter2"); err != errNoSecretKey {
+		t.Error("Expected no secret key to fail, got:", err)
+	}
+
+	if err := s.SetSecretKey("key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutSecret(js, "token", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if v := js[secretPrefix+"token"]; strings.Contains(v, "hunter2") {
+		t.Error("Expected the secret to be encrypted, got:", v)
+	}
+	if _, ok := js.Get("token"); ok {
+		t.Error("Expected secrets to be kept apart from other data.")
+	}
+	if v, ok, err := s.GetSecret(js, "token"); err != nil || !ok || v != "hunter2" {
+		t.Error("Expected the secret, got:", v, ok, err)
+	}
+	if _, ok, err := s.GetSecret(js, "missing"); err != nil || ok {
+		t.Error("Expected a missing secret not to be found:", ok, err)
+	}
+
+	js[secretPrefix+"other"] = js[secretPrefix+"token"]
+	if _, _, err := s.GetSecret(js, "other"); err != errSecretCorrupt {
+		t.Error("Expected a secret moved to another name to fail, got:", err)
+	}
+	delete(js, secretPrefix+"other")
+
+	other, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if _, _, err := other.GetSecret(js, "token"); err == nil {
+		t.Error("Expected stores not to share their keys.")
+	}
+
+	if err := s.SetSecretKey("new"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.GetSecret(js, "token"); err == nil {
+		t.Error("Expected a secret of an unknown key to fail.")
+	}
+	if err := s.SetSecretKey("new", "key"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _, err := s.GetSecret(js, "token"); err != nil || v != "hunter2" {
+		t.Error("Expected an old key to decrypt the secret, got:", v, err)
+	}
+
+	secrets, err := s.Secrets(js)
+	if err != nil || len(secrets) != 1 || secrets["token"] != "hunter2" {
+		t.Error("Wrong secrets:", secrets, err)
+	}
+
+	if !js.DeleteSecret("token") || js.DeleteSecret("token") {
+		t.Error("Expected the secret to be deleted once.")
+	}
+}
+
+func TestStoredUser_ProtoSecrets(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.SetSecretKey("key"); err != nil {
+		t.Fatal(err)
+	}
+
+	user, _ := NewStoredUser(uname, password)
+	user.Put("plain", "value")
+	if err := s.PutSecret(user.JSONStorer, "token", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	proto := user.ToProto()
+	if len(proto.Data) != 1 || proto.Data["plain"] != "value" ||
+		len(proto.Secrets) != 0 {
+		t.Error("Expected secrets to be left out, got:", proto.Data,
+			proto.Secrets)
+	}
+
+	if proto, err := user.ToProtoSecrets(s); err != nil {
+		t.Error(err)
+	} else if len(proto.Data) != 1 || proto.Secrets["token"] != "hunter2" {
+		t.Error("Expected the decrypted secrets, got:", proto.Data,
+			proto.Secrets)
+	}
+
+	proto.Data[secretPrefix+"forged"] = "v1:0000:AAAA"
+	var got StoredUser
+	got.FromProto(proto)
+	if _, ok := got.JSONStorer[secretPrefix+"forged"]; ok {
+		t.Error("Expected secrets not to be taken from data.")
+	}
+
+	got.CopySecrets(user.JSONStorer)
+	if v, _, err := s.GetSecret(got.JSONStorer, "token"); err != nil || v != "hunter2" {
+		t.Error("Expected the secrets to be copied, got:", v, err)
+	}
+
+	ch := NewStoredChannel(network, channel)
+	if err := s.PutSecret(ch.JSONStorer, "token", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if p := ch.ToProto(); len(p.Data) != 0 {
+		t.Error("Expected secrets to be left out, got:", p.Data)
+	}
+	if p, err := ch.ToProtoSecrets(s); err != nil || p.Secrets["token"] != "hunter2" {
+		t.Error("Expected the decrypted secrets, got:", p, err)
+	}
+}
+
+func TestStore_RotateSecrets(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.SetSecretKey("key"); err != nil {
+		t.Fatal(err)
+	}
+
+	user, _ := NewStoredUser(uname, password)
+	s.PutSecret(user.JSONStorer, "a", "1")
+	s.PutSecret(user.JSONStorer, "b", "2")
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	ch := NewStoredChannel(network, channel)
+	s.PutSecret(ch.JSONStorer, "c", "3")
+	if err = s.SaveChannel(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := s.RotateSecrets(); err != nil || n != 0 {
+		t.Error("Expected nothing to rotate, got:", n, err)
+	}
+
+	if err = s.SetSecretKey("new"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RotateSecrets(); err == nil {
+		t.Error("Expected secrets of a missing key to fail.")
+	}
+
+	if err = s.SetSecretKey("new", "key"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.RotateSecrets(); err != nil || n != 3 {
+		t.Error("Expected every secret to rotate, got:", n, err)
+	}
+
+	if err = s.SetSecretKey("new"); err != nil {
+		t.Fatal(err)
+	}
+	found, err := s.FindUser(uname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _, err := s.GetSecret(found.JSONStorer, "b"); err != nil || v != "2" {
+		t.Error("Expected the secret under the new key, got:", v, err)
+	}
+	foundCh, err := s.FindChannel(network, channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _, err := s.GetSecret(foundCh.JSONStorer, "c"); err != nil || v != "3" {
+		t.Error("Expected the secret under the new key, got:", v, err)
+	}
+	if foundCh.Revision != 2 {
+		t.Error("Expected the channel to be saved, got revision:",
+			foundCh.Revision)
+	}
+}
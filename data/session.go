@@ -0,0 +1,207 @@
+package data
+
+import (
+	"time"
+)
+
+const (
+	// recordSession is the kind of record a persisted session is stored as.
+	recordSession byte = 's'
+)
+
+var (
+	// sessionPrefix begins the key of every persisted session.
+	sessionPrefix = []byte("\x00session:")
+	// sessionSeenInterval is how stale a session's persisted LastSeen may
+	// get before being written again, this saves a write for every message.
+	sessionSeenInterval = time.Minute
+)
+
+// Session is an authentication of a host to a user. Sessions are persisted so
+// that users stay authenticated when the bot restarts.
+type Session struct {
+	Network  string
+	Host     string
+	Username string
+	// Temporary is true for sessions created by AuthUserTmp.
+	Temporary bool
+
+	Created  time.Time
+	LastSeen time.Time
+}
+
+// sessionKey is the key of the session for host on network.
+func sessionKey(network, host string) []byte {
+	key := make([]byte, 0, len(sessionPrefix)+len(network)+1+len(host))
+	key = append(key, sessionPrefix...)
+	key = append(key, network...)
+	key = append(key, 0)
+	return append(key, host...)
+}
+
+// SetSessionLifetime sets the most time a session may exist for regardless
+// of activity, 0 lets sessions live until they're logged out or time out.
+func (s *Store) SetSessionLifetime(lifetime time.Duration) {
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	s.sessionLifetime = lifetime
+	s.reap()
+}
+
+// Sessions returns a copy of the sessions on a network, or all sessions if
+// network is empty.
+func (s *Store) Sessions(network string) []Session {
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	var sessions []Session
+	for _, sess := range s.sessions {
+		if len(network) == 0 || sess.Network == network {
+			sessions = append(sessions, *sess)
+		}
+	}
+	return sessions
+}
+
+// Reconcile compares the sessions on network to the users in the state.
+// Sessions whose host is in the state are seen, and sessions whose host is
+// missing will time out unless their host is seen again soon.
+func (s *Store) Reconcile(network string, state *State) {
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	now := time.Now().UTC()
+	for key, sess := range s.sessions {
+		if sess.Network != network {
+			continue
+		}
+
+		if user, ok := state.User(sess.Host); ok && string(user.Host) == sess.Host {
+			delete(s.timeouts, key)
+			s.touchSession(sess, now)
+		} else if _, ok := s.timeouts[key]; !ok {
+			s.timeouts[key] = now.Add(defaultTimeout)
+		}
+	}
+
+	s.reap()
+}
+
+// startSession binds a host to a user and persists it.
+// warning: Assumes the store is locked
+func (s *Store) startSession(network, host, username string, temp bool) error {
+	now := time.Now().UTC()
+	key := network + host
+
+	if temp {
+		s.timeouts[key] = now.Add(defaultTimeout)
+	}
+	s.authed[key] = username
+
+	sess := &Session{
+		Network:   network,
+		Host:      host,
+		Username:  username,
+		Temporary: temp,
+		Created:   now,
+		LastSeen:  now,
+	}
+	s.sessions[key] = sess
+	s.notifyChange(sessionChange(SessionOpened, sess))
+
+	return s.saveSession(sess)
+}
+
+// endSession logs out a host and deletes it's persisted session.
+// warning: Assumes the store is locked
+func (s *Store) endSession(key string) {
+	delete(s.authed, key)
+	delete(s.timeouts, key)
+
+	if sess, ok := s.sessions[key]; ok {
+		delete(s.sessions, key)
+		s.notifyChange(sessionChange(SessionClosed, sess))
+		// Sessions are best effort, a failure here only means the
+		// session may be restored and then time out after a restart.
+		_ = s.db.Delete(sessionKey(sess.Network, sess.Host))
+	}
+}
+
+// moveSession moves a session to a new host when a user changes nick.
+// warning: Assumes the store is locked
+func (s *Store) moveSession(network, oldHost, newHost string) {
+	oldKey, newKey := network+oldHost, network+newHost
+
+	username, ok := s.authed[oldKey]
+	if !ok {
+		delete(s.timeouts, oldKey)
+		return
+	}
+
+	sess := s.sessions[oldKey]
+	s.endSession(oldKey)
+	s.authed[newKey] = username
+
+	if sess != nil {
+		sess.Host = newHost
+		sess.LastSeen = time.Now().UTC()
+		s.sessions[newKey] = sess
+		s.notifyChange(sessionChange(SessionOpened, sess))
+		_ = s.saveSession(sess)
+	}
+}
+
+// touchSession updates the last time a session was seen, it's only persisted
+// every sessionSeenInterval.
+// warning: Assumes the store is locked
+func (s *Store) touchSession(sess *Session, now time.Time) {
+	persist := now.Sub(sess.LastSeen) >= sessionSeenInterval
+	sess.LastSeen = now
+	if persist {
+		_ = s.saveSession(sess)
+	}
+}
+
+// saveSession persists a session.
+func (s *Store) saveSession(sess *Session) error {
+	record, err := encodeRecord(recordSession, sess)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(sessionKey(sess.Network, sess.Host), record)
+}
+
+// restoreSessions loads the persisted sessions. Until they're reconciled
+// against the state each session is treated as unseen and will time out.
+// warning: Assumes the store is locked
+func (s *Store) restoreSessions() error {
+	now := time.Now().UTC()
+	var bad [][]byte
+
+	err := s.db.Scan(sessionPrefix, func(key, val []byte) bool {
+		sess := &Session{}
+		if err := decodeRecord(recordSession, val, sess); err != nil {
+			bad = append(bad, copyBytes(key))
+			return true
+		}
+
+		k := sess.Network + sess.Host
+		s.authed[k] = sess.Username
+		s.timeouts[k] = now.Add(defaultTimeout)
+		s.sessions[k] = sess
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	// Sessions are disposable, one that can't be read is logged out.
+	for _, key := range bad {
+		if err = s.db.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSession_Restore(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "ultimateq-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	prov := MakeBoltStoreProvider(filepath.Join(dir, "store.db"))
+
+	s, err := NewStore(prov)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := NewStoredUser(uname, password, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.AuthUserPerma(network, host, uname, password); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.AuthUserPerma("net2", host, uname, password); err != nil {
+		t.Fatal(err)
+	}
+	s.Logout("net2", host)
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewStore(prov)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if got := s.AuthedUser(network, host); got == nil || got.Username != uname {
+		t.Error("Expected the session to be restored, got:", got)
+	}
+	if got := s.AuthedUser("net2", host); got != nil {
+		t.Error("Expected the logged out session to stay gone, got:", got)
+	}
+
+	sessions := s.Sessions("")
+	if len(sessions) != 1 {
+		t.Fatal("Expected one session, got:", sessions)
+	}
+	sess := sessions[0]
+	if sess.Network != network || sess.Host != host || sess.Username != uname ||
+		sess.Temporary || sess.Created.IsZero() || sess.LastSeen.IsZero() {
+
+		t.Errorf("Wrong session: %#v", sess)
+	}
+
+	// Until reconciled the session is waiting to be seen.
+	if _, ok := s.timeouts[network+host]; !ok {
+		t.Error("Expected a restored session to be unseen.")
+	}
+
+	st := setupNewState()
+	s.Reconcile(network, st)
+	if _, ok := s.timeouts[network+host]; !ok {
+		t.Error("Expected a missing user to stay unseen.")
+	}
+
+	st.addUser(host)
+	s.Reconcile(network, st)
+	if _, ok := s.timeouts[network+host]; ok {
+		t.Error("Expected a user in the state to be seen.")
+	}
+}
+
+func TestSession_Move(t *testing.T) {
+	t.Parallel()
+
+	s := setupUpdateTest(t)
+	defer s.Close()
+
+	if _, err := s.AuthUserPerma(network, host, uname, password); err != nil {
+		t.Fatal(err)
+	}
+
+	newHost := "newnick!user@host"
+	s.Update(network, StateUpdate{Nick: []string{host, newHost}})
+
+	if val, _ := s.db.Get(sessionKey(network, host)); val != nil {
+		t.Error("Expected the old session to be deleted.")
+	}
+	if val, _ := s.db.Get(sessionKey(network, newHost)); val == nil {
+		t.Error("Expected the session to be saved under the new host.")
+	}
+
+	s.Update(network, StateUpdate{Nick: []string{"other!user@host", "x!user@host"}})
+	if _, ok := s.authed[network+"x!user@host"]; ok {
+		t.Error("Expected an unauthed nick change not to authenticate.")
+	}
+
+	s.Update(network, StateUpdate{Quit: newHost})
+	if val, _ := s.db.Get(sessionKey(network, newHost)); val != nil {
+		t.Error("Expected the session to be deleted on quit.")
+	}
+	if len(s.Sessions(network)) != 0 {
+		t.Error("Expected no sessions.")
+	}
+}
+
+func TestSession_Lifetime(t *testing.T) {
+	t.Parallel()
+
+	s := setupUpdateTest(t)
+	defer s.Close()
+
+	if _, err := s.AuthUserPerma(network, host, uname, password); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetSessionLifetime(time.Hour)
+	if s.AuthedUser(network, host) == nil {
+		t.Error("Expected the session to be within it's lifetime.")
+	}
+
+	s.sessions[network+host].Created = time.Now().UTC().Add(-2 * time.Hour)
+	s.Update(network, StateUpdate{})
+	if s.AuthedUser(network, host) != nil {
+		t.Error("Expected the session to have expired.")
+	}
+	if val, _ := s.db.Get(sessionKey(network, host)); val != nil {
+		t.Error("Expected the expired session to be deleted.")
+	}
+}
+
+func TestSession_RestoreBad(t *testing.T) {
+	t.Parallel()
+
+	db, _ := MemStoreProvider()
+	if err := db.Put(sessionKey(network, host), []byte("bad")); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewStore(func() (Backend, error) { return db, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if len(s.Sessions("")) != 0 {
+		t.Error("Expected no sessions.")
+	}
+	if val, _ := db.Get(sessionKey(network, host)); val != nil {
+		t.Error("Expected the bad session to be deleted.")
+	}
+}
@@ -0,0 +1,357 @@
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aarondl/ultimateq/api"
+	"github.com/aarondl/ultimateq/irc"
+)
+
+// SettingType is the kind of value a channel setting holds.
+type SettingType int
+
+// The kinds of values a channel setting can hold.
+const (
+	SettingBool SettingType = iota
+	SettingInt
+	SettingDuration
+	SettingEnum
+	SettingMasks
+)
+
+// settingPrefix begins the keys of settings in a StoredChannel, it keeps
+// them apart from what extensions store there themselves.
+const settingPrefix = "setting:"
+
+// rgxSetting matches a namespaced setting like quotes.enabled.
+var rgxSetting = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)*$`)
+
+var settingTypeNames = []string{"bool", "int", "duration", "enum", "masks"}
+
+// String gets the name of the setting type.
+func (t SettingType) String() string {
+	if t < 0 || int(t) >= len(settingTypeNames) {
+		return "unknown"
+	}
+	return settingTypeNames[t]
+}
+
+// ParseSettingType gets a setting type from its name.
+func ParseSettingType(name string) (SettingType, bool) {
+	for i, n := range settingTypeNames {
+		if strings.EqualFold(n, name) {
+			return SettingType(i), true
+		}
+	}
+	return 0, false
+}
+
+// Setting is a typed channel setting declared by the core or an extension.
+type Setting struct {
+	Name string
+	// Extension is the extension that declared the setting.
+	Extension string
+	Desc      string
+	Type      SettingType
+	// Default is the value of the setting on channels that haven't set it.
+	Default string
+	// Values are the choices of an enum.
+	Values []string
+	// Level and Flags are the access needed on a channel to change it,
+	// without either any level or flag there is needed.
+	Level uint8
+	Flags string
+}
+
+// CanChange checks if a user has the access needed to change the setting on
+// a channel. A setting that doesn't declare the access it needs can be
+// changed by users with any level or flag there, so users can't change
+// channels they have nothing to do with.
+func (s Setting) CanChange(user *StoredUser, network, channel string) bool {
+	if s.Level == 0 && len(s.Flags) == 0 {
+		return user.HasLevel(network, channel, 1) ||
+			user.Has(network, channel, 0, wholeAlphabet)
+	}
+
+	hasLevel := s.Level == 0 || user.HasLevel(network, channel, s.Level)
+	hasFlags := len(s.Flags) == 0 || user.HasFlags(network, channel, s.Flags)
+	return hasLevel && hasFlags
+}
+
+// Parse checks a value for the setting and returns it the way it's stored.
+// Masks are separated by spaces or commas.
+func (s Setting) Parse(value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	switch s.Type {
+	case SettingBool:
+		switch strings.ToLower(value) {
+		case "on", "yes":
+			return "true", nil
+		case "off", "no":
+			return "false", nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("data: %s must be on or off", s.Name)
+		}
+		return strconv.FormatBool(b), nil
+	case SettingInt:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("data: %s must be a number", s.Name)
+		}
+		return strconv.FormatInt(i, 10), nil
+	case SettingDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return "", fmt.Errorf("data: %s must be a duration like 1h30m",
+				s.Name)
+		}
+		return d.String(), nil
+	case SettingEnum:
+		for _, v := range s.Values {
+			if strings.EqualFold(v, value) {
+				return v, nil
+			}
+		}
+		return "", fmt.Errorf("data: %s must be one of: %s", s.Name,
+			strings.Join(s.Values, ", "))
+	case SettingMasks:
+		masks := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+			return r == ' ' || r == ','
+		})
+		for _, m := range masks {
+			if !irc.Mask(m).IsValid() {
+				return "", fmt.Errorf("data: %s has an invalid mask: %s",
+					s.Name, m)
+			}
+		}
+		return strings.Join(masks, " "), nil
+	}
+
+	return "", fmt.Errorf("data: %s has an unknown type", s.Name)
+}
+
+// ToProto converts a setting to its protocol buffer form.
+func (s Setting) ToProto() *api.Setting {
+	return &api.Setting{
+		Name:    s.Name,
+		Ext:     s.Extension,
+		Desc:    s.Desc,
+		Type:    s.Type.String(),
+		Default: s.Default,
+		Values:  s.Values,
+		Level:   uint32(s.Level),
+		Flags:   s.Flags,
+	}
+}
+
+// FromProto fills a setting from its protocol buffer form, false is
+// returned if the type is unknown.
+func (s *Setting) FromProto(proto *api.Setting) bool {
+	var ok bool
+	if s.Type, ok = ParseSettingType(proto.Type); !ok {
+		return false
+	}
+	s.Name = proto.Name
+	s.Extension = proto.Ext
+	s.Desc = proto.Desc
+	s.Default = proto.Default
+	s.Values = proto.Values
+	s.Level = uint8(proto.Level)
+	s.Flags = proto.Flags
+	return true
+}
+
+// settingRegistry holds the settings declared by the core and extensions.
+var settingRegistry = struct {
+	sync.RWMutex
+	settings map[string]Setting
+}{
+	settings: make(map[string]Setting),
+}
+
+// DeclareSettings adds an extension's settings to the registry. It's an
+// error to declare a setting another extension already has, or one whose
+// default isn't a valid value, none of the settings are declared in that
+// case.
+func DeclareSettings(ext string, settings ...Setting) error {
+	ext = strings.ToLower(ext)
+
+	settingRegistry.Lock()
+	defer settingRegistry.Unlock()
+
+	declared := make([]Setting, len(settings))
+	for i, s := range settings {
+		s.Name = strings.ToLower(s.Name)
+		s.Extension = ext
+		if !rgxSetting.MatchString(s.Name) {
+			return fmt.Errorf("data: invalid setting name: %q", s.Name)
+		}
+		if s.Type < SettingBool || s.Type > SettingMasks {
+			return fmt.Errorf("data: setting %q has an unknown type", s.Name)
+		}
+		if s.Type == SettingEnum && len(s.Values) == 0 {
+			return fmt.Errorf("data: enum setting %q has no values", s.Name)
+		}
+		if len(s.Default) != 0 {
+			var err error
+			if s.Default, err = s.Parse(s.Default); err != nil {
+				return err
+			}
+		}
+		if had, ok := settingRegistry.settings[s.Name]; ok && had.Extension != ext {
+			return fmt.Errorf("data: setting %q is already declared by %s",
+				s.Name, had.Extension)
+		}
+		declared[i] = s
+	}
+
+	for _, s := range declared {
+		settingRegistry.settings[s.Name] = s
+	}
+	return nil
+}
+
+// LookupSetting gets a declared setting by name.
+func LookupSetting(name string) (Setting, bool) {
+	settingRegistry.RLock()
+	defer settingRegistry.RUnlock()
+
+	s, ok := settingRegistry.settings[strings.ToLower(name)]
+	return s, ok
+}
+
+// Settings gets every declared setting sorted by name.
+func Settings() []Setting {
+	settingRegistry.RLock()
+	defer settingRegistry.RUnlock()
+
+	settings := make([]Setting, 0, len(settingRegistry.settings))
+	for _, s := range settingRegistry.settings {
+		settings = append(settings, s)
+	}
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].Name < settings[j].Name
+	})
+	return settings
+}
+
+// lookupSetting gets a declared setting or an error naming it.
+func lookupSetting(name string) (Setting, error) {
+	s, ok := LookupSetting(name)
+	if !ok {
+		return s, fmt.Errorf("data: unknown setting: %q", name)
+	}
+	return s, nil
+}
+
+// Setting gets the value of a setting on the channel, or its default when
+// the channel hasn't set it. The bool is false when it's the default.
+func (s *StoredChannel) Setting(name string) (string, bool, error) {
+	setting, err := lookupSetting(name)
+	if err != nil {
+		return "", false, err
+	}
+	if val, ok := s.JSONStorer[settingPrefix+setting.Name]; ok {
+		return val, true, nil
+	}
+	return setting.Default, false, nil
+}
+
+// SetSetting changes a setting on the channel, the value is returned the
+// way it's stored.
+func (s *StoredChannel) SetSetting(name, value string) (string, error) {
+	setting, err := lookupSetting(name)
+	if err != nil {
+		return "", err
+	}
+	if value, err = setting.Parse(value); err != nil {
+		return "", err
+	}
+
+	if s.JSONStorer == nil {
+		s.JSONStorer = make(JSONStorer)
+	}
+	s.JSONStorer[settingPrefix+setting.Name] = value
+	return value, nil
+}
+
+// UnsetSetting returns a setting on the channel to its default, false is
+// returned if it wasn't set.
+func (s *StoredChannel) UnsetSetting(name string) bool {
+	key := settingPrefix + strings.ToLower(name)
+	if _, ok := s.JSONStorer[key]; !ok {
+		return false
+	}
+	delete(s.JSONStorer, key)
+	return true
+}
+
+// typedSetting gets the value of a setting that must be of a type.
+func (s *StoredChannel) typedSetting(name string, typ SettingType) (string, error) {
+	setting, err := lookupSetting(name)
+	if err != nil {
+		return "", err
+	}
+	if setting.Type != typ {
+		return "", fmt.Errorf("data: setting %q is a %v not a %v",
+			setting.Name, setting.Type, typ)
+	}
+	val, _, err := s.Setting(name)
+	return val, err
+}
+
+// SettingBool gets a bool setting, false if it has no value.
+func (s *StoredChannel) SettingBool(name string) (bool, error) {
+	val, err := s.typedSetting(name, SettingBool)
+	if err != nil || len(val) == 0 {
+		return false, err
+	}
+	return strconv.ParseBool(val)
+}
+
+// SettingInt gets an int setting, 0 if it has no value.
+func (s *StoredChannel) SettingInt(name string) (int64, error) {
+	val, err := s.typedSetting(name, SettingInt)
+	if err != nil || len(val) == 0 {
+		return 0, err
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
+
+// SettingDuration gets a duration setting, 0 if it has no value.
+func (s *StoredChannel) SettingDuration(name string) (time.Duration, error) {
+	val, err := s.typedSetting(name, SettingDuration)
+	if err != nil || len(val) == 0 {
+		return 0, err
+	}
+	return time.ParseDuration(val)
+}
+
+// SettingEnum gets an enum setting.
+func (s *StoredChannel) SettingEnum(name string) (string, error) {
+	return s.typedSetting(name, SettingEnum)
+}
+
+// SettingMasks gets a masks setting.
+func (s *StoredChannel) SettingMasks(name string) ([]irc.Mask, error) {
+	val, err := s.typedSetting(name, SettingMasks)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(val)
+	masks := make([]irc.Mask, len(fields))
+	for i, f := range fields {
+		masks[i] = irc.Mask(f)
+	}
+	return masks, nil
+}
@@ -0,0 +1,229 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aarondl/ultimateq/irc"
+)
+
+func TestSetting_Parse(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		Type   SettingType
+		Value  string
+		Expect string
+		Err    bool
+	}{
+		{SettingBool, "on", "true", false},
+		{SettingBool, "NO", "false", false},
+		{SettingBool, "1", "true", false},
+		{SettingBool, "maybe", "", true},
+		{SettingInt, " -5 ", "-5", false},
+		{SettingInt, "5.5", "", true},
+		{SettingDuration, "90m", "1h30m0s", false},
+		{SettingDuration, "soon", "", true},
+		{SettingEnum, "LOUD", "loud", false},
+		{SettingEnum, "quiet", "", true},
+		{SettingMasks, "A!b@c, *!*@host", "a!b@c *!*@host", false},
+		{SettingMasks, "", "", false},
+		{SettingMasks, "nomask", "", true},
+		{SettingType(20), "x", "", true},
+	}
+
+	for _, test := range tests {
+		setting := Setting{Name: "s", Type: test.Type, Values: []string{"loud"}}
+		got, err := setting.Parse(test.Value)
+		if test.Err != (err != nil) {
+			t.Errorf("%v %q: expected error to be %v, got: %v",
+				test.Type, test.Value, test.Err, err)
+		}
+		if got != test.Expect {
+			t.Errorf("%v %q: expected %q, got: %q",
+				test.Type, test.Value, test.Expect, got)
+		}
+	}
+}
+
+func TestDeclareSettings(t *testing.T) {
+	t.Parallel()
+
+	err := DeclareSettings("SetExt",
+		Setting{Name: "SetExt.Greet", Type: SettingBool, Default: "yes"},
+		Setting{Name: "setext.mode", Type: SettingEnum,
+			Values: []string{"a", "b"}, Level: 50},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, ok := LookupSetting("setext.greet")
+	if !ok || s.Extension != "setext" || s.Default != "true" {
+		t.Error("Expected the setting to be declared, got:", s)
+	}
+
+	if err = DeclareSettings("other", Setting{Name: "setext.greet"}); err == nil {
+		t.Error("Expected a setting of another extension to fail.")
+	}
+	if err = DeclareSettings("setext", Setting{Name: "setext.greet"}); err != nil {
+		t.Error("Expected an extension to redeclare its own setting:", err)
+	}
+
+	bad := []Setting{
+		{Name: "has space", Type: SettingBool},
+		{Name: "setext.wild.*", Type: SettingBool},
+		{Name: "setext.type", Type: SettingType(-1)},
+		{Name: "setext.enum", Type: SettingEnum},
+		{Name: "setext.int", Type: SettingInt, Default: "lots"},
+	}
+	for _, b := range bad {
+		if err = DeclareSettings("setext", Setting{Name: "setext.ok"}, b); err == nil {
+			t.Errorf("Expected %q to fail.", b.Name)
+		}
+	}
+	if _, ok = LookupSetting("setext.ok"); ok {
+		t.Error("Expected nothing to be declared when one fails.")
+	}
+
+	var names []string
+	for _, s := range Settings() {
+		if s.Extension == "setext" {
+			names = append(names, s.Name)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"setext.greet", "setext.mode"}) {
+		t.Error("Wrong settings:", names)
+	}
+}
+
+func TestStoredChannel_Settings(t *testing.T) {
+	t.Parallel()
+
+	err := DeclareSettings("chanset",
+		Setting{Name: "chanset.on", Type: SettingBool, Default: "on"},
+		Setting{Name: "chanset.count", Type: SettingInt},
+		Setting{Name: "chanset.wait", Type: SettingDuration, Default: "1m"},
+		Setting{Name: "chanset.mode", Type: SettingEnum, Values: []string{"x", "y"}},
+		Setting{Name: "chanset.ignore", Type: SettingMasks},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ch := &StoredChannel{NetID: network, Name: channel}
+	if on, err := ch.SettingBool("chanset.on"); err != nil || !on {
+		t.Error("Expected the default, got:", on, err)
+	}
+	if n, err := ch.SettingInt("chanset.count"); err != nil || n != 0 {
+		t.Error("Expected no value, got:", n, err)
+	}
+	if _, _, err := ch.Setting("chanset.missing"); err == nil {
+		t.Error("Expected an unknown setting to fail.")
+	}
+	if _, err := ch.SettingInt("chanset.on"); err == nil {
+		t.Error("Expected the wrong type to fail.")
+	}
+
+	if _, err := ch.SetSetting("chanset.count", "many"); err == nil {
+		t.Error("Expected an invalid value to fail.")
+	}
+	if v, err := ch.SetSetting("Chanset.Count", "12"); err != nil || v != "12" {
+		t.Error("Expected the value to be set, got:", v, err)
+	}
+	ch.SetSetting("chanset.on", "off")
+	ch.SetSetting("chanset.wait", "2h")
+	ch.SetSetting("chanset.mode", "Y")
+	ch.SetSetting("chanset.ignore", "*!*@a *!*@b")
+
+	if on, _ := ch.SettingBool("chanset.on"); on {
+		t.Error("Expected the setting to be off.")
+	}
+	if n, _ := ch.SettingInt("chanset.count"); n != 12 {
+		t.Error("Expected 12, got:", n)
+	}
+	if d, _ := ch.SettingDuration("chanset.wait"); d != 2*time.Hour {
+		t.Error("Expected 2h, got:", d)
+	}
+	if m, _ := ch.SettingEnum("chanset.mode"); m != "y" {
+		t.Error("Expected y, got:", m)
+	}
+	masks, _ := ch.SettingMasks("chanset.ignore")
+	if !reflect.DeepEqual(masks, []irc.Mask{"*!*@a", "*!*@b"}) {
+		t.Error("Wrong masks:", masks)
+	}
+	if _, ok := ch.Get("chanset.count"); ok {
+		t.Error("Expected settings to be kept apart from other data.")
+	}
+
+	if !ch.UnsetSetting("chanset.wait") || ch.UnsetSetting("chanset.wait") {
+		t.Error("Expected the setting to be unset once.")
+	}
+	if v, isSet, _ := ch.Setting("chanset.wait"); isSet || v != "1m0s" {
+		t.Error("Expected the default, got:", v, isSet)
+	}
+
+	clone, err := deserializeChannel(mustSerialize(t, ch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := clone.SettingInt("chanset.count"); n != 12 {
+		t.Error("Expected settings to be stored, got:", n)
+	}
+}
+
+func TestSetting_CanChange(t *testing.T) {
+	t.Parallel()
+
+	anyAccess := Setting{Name: "a.any"}
+	needs := Setting{Name: "a.needs", Level: 50, Flags: "o"}
+
+	u := createStoredUser()
+	if anyAccess.CanChange(u, network, channel) || needs.CanChange(u, network, channel) {
+		t.Error("A user without access should not change settings.")
+	}
+
+	u.Grant(network, "#other", 100, "o")
+	u.Deny(network, channel, 0, "v")
+	if anyAccess.CanChange(u, network, channel) {
+		t.Error("Access elsewhere or a deny should not change settings.")
+	}
+
+	u.Grant(network, channel, 0, "v")
+	u.RevokeDeny(network, channel)
+	if !anyAccess.CanChange(u, network, channel) || needs.CanChange(u, network, channel) {
+		t.Error("Any flag should change only settings that need nothing more.")
+	}
+
+	u.Grant(network, "", 50, "o")
+	if !needs.CanChange(u, network, channel) {
+		t.Error("Network access should change the channel's settings.")
+	}
+}
+
+func TestSetting_Protofy(t *testing.T) {
+	t.Parallel()
+
+	s := Setting{Name: "a.b", Extension: "a", Desc: "d", Type: SettingEnum,
+		Default: "x", Values: []string{"x", "y"}, Level: 5, Flags: "o"}
+	var got Setting
+	if !got.FromProto(s.ToProto()) || !reflect.DeepEqual(got, s) {
+		t.Error("Expected the setting to survive a round trip, got:", got)
+	}
+
+	p := s.ToProto()
+	p.Type = "float"
+	if got.FromProto(p) {
+		t.Error("Expected an unknown type to fail.")
+	}
+}
+
+func mustSerialize(t *testing.T, ch *StoredChannel) []byte {
+	t.Helper()
+	b, err := ch.serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -85,8 +85,11 @@ package data
 
 import (
 	"errors"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/aarondl/ultimateq/irc"
 )
@@ -103,11 +106,35 @@ type Self struct {
 	ChannelModes
 }
 
+// IsOper checks if the client is an irc operator, local or global.
+func (s Self) IsOper() bool {
+	return s.isModeSet('o') || s.isModeSet('O')
+}
+
+// ModeString returns the client's user modes in sorted order, for example:
+// +iwx. If there are no modes set it returns empty string.
+func (s Self) ModeString() string {
+	if len(s.modes) == 0 {
+		return ""
+	}
+
+	modes := make([]string, 0, len(s.modes))
+	for mode := range s.modes {
+		modes = append(modes, string(mode))
+	}
+	sort.Strings(modes)
+
+	return "+" + strings.Join(modes, "")
+}
+
 // State is the main data container. It represents the state on a network
 // including all channels, users, and the client's self.
 type State struct {
 	selfUser  *User
 	selfModes ChannelModes
+	// supportedModes are the user modes the network supports, any mode not in
+	// this list is not tracked for the client.
+	supportedModes string
 
 	channels map[string]*Channel
 	users    map[string]*User
@@ -145,6 +172,8 @@ func (s *State) SetNetworkInfo(ni *irc.NetworkInfo) error {
 		return errNetInfoMissing
 	}
 
+	s.supportedModes = ni.Usermodes()
+
 	if s.kinds != nil {
 		return s.kinds.update(ni.Prefix(), ni.Chanmodes())
 	}
@@ -160,6 +189,9 @@ func (s *State) SetNetworkInfo(ni *irc.NetworkInfo) error {
 // Self retrieves the user that the state identifies itself with. Usually the
 // client that is using the data package.
 func (s *State) Self() Self {
+	s.protect.RLock()
+	defer s.protect.RUnlock()
+
 	return Self{*s.selfUser, s.selfModes.Clone()}
 }
 
@@ -348,6 +380,98 @@ func (s *State) IsOn(nickorhost, channel string) bool {
 	return false
 }
 
+// FindUsers returns all the users whose host matches the mask, sorted by
+// nick. Matching is case insensitive. A mask without a ! or @ in it is
+// matched against the nick alone, so "nick*" is a valid search.
+func (s *State) FindUsers(mask irc.Mask) []User {
+	s.protect.RLock()
+	defer s.protect.RUnlock()
+
+	match := userMatcher(mask)
+
+	var ret []User
+	for _, u := range s.users {
+		if match(u) {
+			ret = append(ret, *u)
+		}
+	}
+	sortUsers(ret)
+	return ret
+}
+
+// FindUsersByRealname returns all the users whose realname matches the glob,
+// sorted by nick. Matching is case insensitive and supports * and ?.
+func (s *State) FindUsersByRealname(glob string) []User {
+	s.protect.RLock()
+	defer s.protect.RUnlock()
+
+	glob = strings.ToLower(glob)
+
+	var ret []User
+	for _, u := range s.users {
+		if irc.Mask(glob).Match(irc.Host(strings.ToLower(u.Realname))) {
+			ret = append(ret, *u)
+		}
+	}
+	sortUsers(ret)
+	return ret
+}
+
+// FindUsersInChannels returns all the users whose host matches the mask and
+// are on at least one of the given channels, sorted by nick. If no channels are
+// given it behaves like FindUsers.
+func (s *State) FindUsersInChannels(mask irc.Mask, channels ...string) []User {
+	if len(channels) == 0 {
+		return s.FindUsers(mask)
+	}
+
+	s.protect.RLock()
+	defer s.protect.RUnlock()
+
+	match := userMatcher(mask)
+
+	found := make(map[string]*User)
+	for _, channel := range channels {
+		cus, ok := s.channelUsers[strings.ToLower(channel)]
+		if !ok {
+			continue
+		}
+
+		for nick, cu := range cus {
+			if _, ok := found[nick]; !ok && match(cu.User) {
+				found[nick] = cu.User
+			}
+		}
+	}
+
+	var ret []User
+	for _, u := range found {
+		ret = append(ret, *u)
+	}
+	sortUsers(ret)
+	return ret
+}
+
+// userMatcher creates a case insensitive matching function for a mask.
+func userMatcher(mask irc.Mask) func(*User) bool {
+	lowered := irc.Mask(strings.ToLower(string(mask)))
+	nickOnly := !strings.ContainsAny(string(lowered), "!@")
+
+	return func(u *User) bool {
+		if nickOnly {
+			return lowered.Match(irc.Host(strings.ToLower(u.Nick())))
+		}
+		return lowered.Match(irc.Host(strings.ToLower(string(u.Host))))
+	}
+}
+
+// sortUsers sorts users by their nick.
+func sortUsers(users []User) {
+	sort.Slice(users, func(i, j int) bool {
+		return strings.ToLower(users[i].Nick()) < strings.ToLower(users[j].Nick())
+	})
+}
+
 // user looks up a user without locking.
 func (s *State) user(nickorhost string) *User {
 	return s.users[strings.ToLower(irc.Nick(nickorhost))]
@@ -529,6 +653,12 @@ type StateUpdate struct {
 	Unseen []string
 	Seen   []string
 	Quit   string
+	// Account is the host of a user and the services account they're now
+	// identified to, the account is empty when they've logged out.
+	Account []string
+	// CertFP is the host of a user and the fingerprint of the certificate
+	// they're connected with.
+	CertFP []string
 }
 
 // Update uses the irc.IrcMessage to modify the database accordingly.
@@ -541,6 +671,7 @@ func (s *State) Update(ev *irc.Event) (update StateUpdate) {
 		update.Nick = s.nick(ev)
 	case irc.JOIN:
 		update.Seen = s.join(ev)
+		update.Account = s.extendedJoin(ev)
 	case irc.PART:
 		update.Unseen = s.part(ev)
 	case irc.QUIT:
@@ -553,10 +684,16 @@ func (s *State) Update(ev *irc.Event) (update StateUpdate) {
 		update.Seen = s.topic(ev)
 	case irc.RPL_TOPIC:
 		s.rplTopic(ev)
+	case irc.RPL_TOPICWHOTIME:
+		s.rplTopicWhoTime(ev)
+	case irc.RPL_CREATIONTIME:
+		s.rplCreationTime(ev)
 	case irc.PRIVMSG, irc.NOTICE:
 		update.Seen = s.msg(ev)
 	case irc.RPL_WELCOME:
 		s.rplWelcome(ev)
+	case irc.RPL_UMODEIS:
+		s.rplUModeIs(ev)
 	case irc.RPL_NAMREPLY:
 		s.rplNameReply(ev)
 	case irc.RPL_WHOREPLY:
@@ -565,10 +702,20 @@ func (s *State) Update(ev *irc.Event) (update StateUpdate) {
 		s.rplChannelModeIs(ev)
 	case irc.RPL_BANLIST:
 		s.rplBanList(ev)
+	case irc.ACCOUNT:
+		update.Account = s.account(ev)
+	case irc.RPL_WHOISACCOUNT:
+		update.Account = s.rplWhoisAccount(ev)
+	case irc.RPL_WHOISCERTFP:
+		update.CertFP = s.rplWhoisCertFP(ev)
 
 		// TODO: Handle Whois
 	}
 
+	if account, ok := ev.Tags[irc.TagAccount]; ok && update.Account == nil {
+		update.Account = s.setAccount(ev.Sender, account)
+	}
+
 	return update
 }
 
@@ -614,6 +761,76 @@ func (s *State) join(ev *irc.Event) []string {
 	return seen
 }
 
+// extendedJoin records the account and realname of a user from a JOIN sent
+// with the extended-join capability.
+func (s *State) extendedJoin(ev *irc.Event) []string {
+	if len(ev.Args) < 3 {
+		return nil
+	}
+
+	if user := s.user(ev.Sender); user != nil {
+		user.Realname = ev.Args[2]
+	}
+	return s.setAccount(ev.Sender, ev.Args[1])
+}
+
+// account sets the services account of a user from an ACCOUNT message.
+func (s *State) account(ev *irc.Event) []string {
+	if len(ev.Args) < 1 {
+		return nil
+	}
+	return s.setAccount(ev.Sender, ev.Args[0])
+}
+
+// rplWhoisAccount sets the services account of a user from a
+// RPL_WHOISACCOUNT message.
+func (s *State) rplWhoisAccount(ev *irc.Event) []string {
+	if len(ev.Args) < 3 {
+		return nil
+	}
+	return s.setAccount(ev.Args[1], ev.Args[2])
+}
+
+// setAccount sets the services account of a user, * means they're not
+// identified. If it changed the user's host and new account are returned.
+func (s *State) setAccount(nickorhost, account string) []string {
+	user := s.user(nickorhost)
+	if user == nil {
+		return nil
+	}
+
+	if account == "*" {
+		account = ""
+	}
+	if user.Account == account {
+		return nil
+	}
+
+	user.Account = account
+	return []string{user.Host.String(), account}
+}
+
+// rplWhoisCertFP records the certificate fingerprint of a user from a
+// RPL_WHOISCERTFP message, the fingerprint ends the message.
+func (s *State) rplWhoisCertFP(ev *irc.Event) []string {
+	if len(ev.Args) < 3 {
+		return nil
+	}
+
+	user := s.user(ev.Args[1])
+	fields := strings.Fields(ev.Args[len(ev.Args)-1])
+	if user == nil || len(fields) == 0 {
+		return nil
+	}
+
+	certfp := fields[len(fields)-1]
+	if user.CertFP == certfp {
+		return nil
+	}
+	user.CertFP = certfp
+	return []string{user.Host.String(), certfp}
+}
+
 // part alters the state of the database when a PART message is received.
 func (s *State) part(ev *irc.Event) []string {
 	if ev.Sender == string(s.selfUser.Host) {
@@ -676,22 +893,51 @@ func (s *State) mode(ev *irc.Event) []string {
 			}
 		}
 		return []string{ev.Sender}
-	} else if target == s.selfUser.Nick() {
-		s.selfModes.Apply(ev.Args[1])
+	} else if s.selfUser != nil && target == strings.ToLower(s.selfUser.Nick()) {
+		if len(ev.Args) >= 2 {
+			s.selfModes.Apply(s.filterUserModes(ev.Args[1]))
+		}
 	}
 	return nil
 }
 
+// rplUModeIs alters the state of the database when a RPL_UMODEIS message is
+// received. It replaces all of the client's modes with the ones in the reply.
+func (s *State) rplUModeIs(ev *irc.Event) {
+	if len(ev.Args) < 2 {
+		return
+	}
+
+	s.selfModes = NewChannelModes(&modeKinds{})
+	s.selfModes.Apply(s.filterUserModes(ev.Args[1]))
+}
+
+// filterUserModes removes any modes from the modestring that the network
+// does not list as a user mode. If the network did not list any user modes
+// the modestring is returned untouched.
+func (s *State) filterUserModes(modestring string) string {
+	if len(s.supportedModes) == 0 {
+		return modestring
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r == '+' || r == '-' || strings.ContainsRune(s.supportedModes, r) {
+			return r
+		}
+		return -1
+	}, modestring)
+}
+
 // topic alters the state of the database when a TOPIC message is received.
 func (s *State) topic(ev *irc.Event) []string {
 	chname := strings.ToLower(ev.Args[0])
 	if ch, ok := s.channels[chname]; ok {
 		s.addUser(ev.Sender)
+		var topic string
 		if len(ev.Args) >= 2 {
-			ch.Topic = ev.Args[1]
-		} else {
-			ch.Topic = ""
+			topic = ev.Args[1]
 		}
+		ch.SetTopic(topic, ev.Sender, ev.Time)
 	}
 	return []string{ev.Sender}
 }
@@ -705,6 +951,48 @@ func (s *State) rplTopic(ev *irc.Event) {
 	}
 }
 
+// rplTopicWhoTime alters the state of the database when a RPL_TOPICWHOTIME
+// message is received.
+func (s *State) rplTopicWhoTime(ev *irc.Event) {
+	if len(ev.Args) < 4 {
+		return
+	}
+
+	chname := strings.ToLower(ev.Args[1])
+	ch, ok := s.channels[chname]
+	if !ok {
+		return
+	}
+
+	when, err := strconv.ParseInt(ev.Args[3], 10, 64)
+	if err != nil {
+		return
+	}
+
+	ch.SetTopic(ch.Topic, ev.Args[2], time.Unix(when, 0).UTC())
+}
+
+// rplCreationTime alters the state of the database when a RPL_CREATIONTIME
+// message is received.
+func (s *State) rplCreationTime(ev *irc.Event) {
+	if len(ev.Args) < 3 {
+		return
+	}
+
+	chname := strings.ToLower(ev.Args[1])
+	ch, ok := s.channels[chname]
+	if !ok {
+		return
+	}
+
+	when, err := strconv.ParseInt(ev.Args[2], 10, 64)
+	if err != nil {
+		return
+	}
+
+	ch.Created = time.Unix(when, 0).UTC()
+}
+
 // msg alters the state of the database when a PRIVMSG or NOTICE message is
 // received.
 func (s *State) msg(ev *irc.Event) []string {
@@ -727,6 +1015,7 @@ func (s *State) rplWelcome(ev *irc.Event) {
 	}
 	user := NewUser(host)
 	s.selfUser = user
+	s.selfModes = NewChannelModes(&modeKinds{})
 	s.users[strings.ToLower(user.Nick())] = user
 }
 
@@ -3,6 +3,7 @@ package data
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aarondl/ultimateq/irc"
 	"golang.org/x/crypto/bcrypt"
@@ -330,6 +331,96 @@ func TestState_NUsersByChannel(t *testing.T) {
 	}
 }
 
+func TestState_FindUsers(t *testing.T) {
+	t.Parallel()
+
+	st := setupNewState()
+	st.addUser(users[0])
+	st.addUser(users[1])
+	st.addUser("other!ident@elsewhere")
+
+	found := st.FindUsers("*!*@HOST*")
+	if got, exp := len(found), 2; exp != got {
+		t.Fatalf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := found[0].Nick(), nicks[0]; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := found[1].Nick(), nicks[1]; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	found = st.FindUsers("oth*")
+	if got, exp := len(found), 1; exp != got {
+		t.Fatalf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := found[0].Nick(), "other"; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	if found = st.FindUsers("nobody!*@*"); len(found) != 0 {
+		t.Error("Expected no users, got:", found)
+	}
+}
+
+func TestState_FindUsersByRealname(t *testing.T) {
+	t.Parallel()
+
+	st := setupNewState()
+	st.addUser(users[0])
+	st.addUser(users[1])
+	st.user(users[0]).Realname = "Real Name"
+	st.user(users[1]).Realname = "Other Name"
+
+	found := st.FindUsersByRealname("real*")
+	if got, exp := len(found), 1; exp != got {
+		t.Fatalf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := found[0].Nick(), nicks[0]; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	found = st.FindUsersByRealname("* name")
+	if got, exp := len(found), 2; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+}
+
+func TestState_FindUsersInChannels(t *testing.T) {
+	t.Parallel()
+
+	st := setupNewState()
+	st.addChannel(channels[0])
+	st.addChannel(channels[1])
+	st.addUser(users[0])
+	st.addUser(users[1])
+	st.addToChannel(users[0], channels[0])
+	st.addToChannel(users[0], channels[1])
+	st.addToChannel(users[1], channels[1])
+
+	found := st.FindUsersInChannels("*!*@*", channels[0])
+	if got, exp := len(found), 1; exp != got {
+		t.Fatalf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := found[0].Nick(), nicks[0]; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	found = st.FindUsersInChannels("*!*@*", channels...)
+	if got, exp := len(found), 2; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	found = st.FindUsersInChannels("*!*@*")
+	if got, exp := len(found), 2; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	if found = st.FindUsersInChannels("*", "#notachannel"); len(found) != 0 {
+		t.Error("Expected no users, got:", found)
+	}
+}
+
 func TestState_EachUser(t *testing.T) {
 	t.Parallel()
 
@@ -633,6 +724,107 @@ func TestState_UpdateJoin(t *testing.T) {
 	}
 }
 
+func TestState_UpdateAccount(t *testing.T) {
+	t.Parallel()
+
+	st := setupNewState()
+	st.addChannel(channels[0])
+
+	u := st.Update(&irc.Event{
+		Name:   irc.JOIN,
+		Sender: users[0],
+		Args:   []string{channels[0], "Account1", "Real Name"},
+	})
+	if len(u.Account) != 2 || u.Account[0] != users[0] || u.Account[1] != "Account1" {
+		t.Error("Expected the extended join to set the account, got:", u.Account)
+	}
+	if user, _ := st.User(users[0]); user.Account != "Account1" || user.Realname != "Real Name" {
+		t.Errorf("Wrong user: %#v", user)
+	}
+
+	u = st.Update(&irc.Event{
+		Name:        irc.PRIVMSG,
+		Sender:      users[0],
+		Args:        []string{channels[0], "hi"},
+		NetworkInfo: testNetInfo,
+		Tags:        map[string]string{irc.TagAccount: "Account1"},
+	})
+	if u.Account != nil {
+		t.Error("Expected an unchanged account not to be updated, got:", u.Account)
+	}
+
+	u = st.Update(&irc.Event{Name: irc.ACCOUNT, Sender: users[0], Args: []string{"*"}})
+	if len(u.Account) != 2 || u.Account[1] != "" {
+		t.Error("Expected logging out to clear the account, got:", u.Account)
+	}
+
+	u = st.Update(&irc.Event{
+		Name:   irc.RPL_WHOISACCOUNT,
+		Sender: "irc",
+		Args:   []string{"me", nicks[0], "other", "is logged in as"},
+	})
+	if len(u.Account) != 2 || u.Account[0] != users[0] || u.Account[1] != "other" {
+		t.Error("Expected whois to set the account, got:", u.Account)
+	}
+
+	u = st.Update(&irc.Event{Name: irc.ACCOUNT, Sender: users[1], Args: []string{"x"}})
+	if u.Account != nil {
+		t.Error("Expected an unknown user to be ignored, got:", u.Account)
+	}
+
+	u = st.Update(&irc.Event{Name: irc.ACCOUNT, Sender: users[0]})
+	if u.Account != nil {
+		t.Error("Expected a short account message to be ignored, got:", u.Account)
+	}
+	u = st.Update(&irc.Event{
+		Name:   irc.RPL_WHOISACCOUNT,
+		Sender: "irc",
+		Args:   []string{"me", nicks[0]},
+	})
+	if u.Account != nil {
+		t.Error("Expected a short whois to be ignored, got:", u.Account)
+	}
+}
+
+func TestState_UpdateCertFP(t *testing.T) {
+	t.Parallel()
+
+	st := setupNewState()
+	st.addChannel(channels[0])
+	st.addUser(users[0])
+
+	ev := &irc.Event{
+		Name:   irc.RPL_WHOISCERTFP,
+		Sender: "irc",
+		Args: []string{"me", nicks[0],
+			"has client certificate fingerprint abcdef"},
+	}
+	u := st.Update(ev)
+	if len(u.CertFP) != 2 || u.CertFP[0] != users[0] || u.CertFP[1] != "abcdef" {
+		t.Error("Expected whois to set the fingerprint, got:", u.CertFP)
+	}
+	if user, _ := st.User(users[0]); user.CertFP != "abcdef" {
+		t.Errorf("Wrong user: %#v", user)
+	}
+
+	if u = st.Update(ev); u.CertFP != nil {
+		t.Error("Expected an unchanged fingerprint not to be updated, got:",
+			u.CertFP)
+	}
+
+	ev.Args[1] = nicks[1]
+	if u = st.Update(ev); u.CertFP != nil {
+		t.Error("Expected an unknown user to be ignored, got:", u.CertFP)
+	}
+
+	for _, args := range [][]string{nil, {"me", nicks[0]}} {
+		ev = &irc.Event{Name: irc.RPL_WHOISCERTFP, Sender: "irc", Args: args}
+		if u = st.Update(ev); u.CertFP != nil {
+			t.Error("Expected a short whois to be ignored, got:", u.CertFP)
+		}
+	}
+}
+
 func TestState_UpdateJoinSelf(t *testing.T) {
 	t.Parallel()
 
@@ -1037,6 +1229,60 @@ func TestState_UpdateModeSelf(t *testing.T) {
 	}
 }
 
+func TestState_UpdateModeSelfFiltered(t *testing.T) {
+	t.Parallel()
+
+	st := setupNewState()
+
+	ev := &irc.Event{
+		Name:        irc.MODE,
+		Sender:      string(st.selfUser.Host),
+		Args:        []string{strings.ToUpper(st.selfUser.Nick()), "+iZ"},
+		NetworkInfo: testNetInfo,
+	}
+
+	st.Update(ev)
+	self := st.Self()
+	if got, exp := self.IsSet("i"), true; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := self.IsSet("Z"), false; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+}
+
+func TestState_UpdateRplUModeIs(t *testing.T) {
+	t.Parallel()
+
+	st := setupNewState()
+	st.selfModes.Set("w")
+
+	ev := &irc.Event{
+		Name:   irc.RPL_UMODEIS,
+		Sender: network,
+		Args:   []string{st.selfUser.Nick(), "+xoi"},
+	}
+
+	st.Update(ev)
+	self := st.Self()
+	if got, exp := self.ModeString(), "+iox"; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := self.IsOper(), true; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	ev.Args = []string{st.selfUser.Nick(), "+i"}
+	st.Update(ev)
+	self = st.Self()
+	if got, exp := self.ModeString(), "+i"; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := self.IsOper(), false; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+}
+
 func TestState_UpdateTopic(t *testing.T) {
 	t.Parallel()
 
@@ -1092,6 +1338,88 @@ func TestState_UpdateRplTopic(t *testing.T) {
 	}
 }
 
+func TestState_UpdateRplTopicWhoTime(t *testing.T) {
+	t.Parallel()
+
+	st := setupNewState()
+	st.addChannel(channels[0])
+
+	st.Update(&irc.Event{
+		Name:   irc.RPL_TOPIC,
+		Sender: network,
+		Args:   []string{st.selfUser.Nick(), channels[0], "topic topic"},
+	})
+	st.Update(&irc.Event{
+		Name:   irc.RPL_TOPICWHOTIME,
+		Sender: network,
+		Args:   []string{st.selfUser.Nick(), channels[0], nicks[1], "1000"},
+	})
+
+	ch, _ := st.Channel(channels[0])
+	if got, exp := ch.Topic, "topic topic"; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := ch.TopicSetter, nicks[1]; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := ch.TopicTime.Unix(), int64(1000); exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := len(ch.TopicHistory), 1; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	st.Update(&irc.Event{
+		Name:   irc.TOPIC,
+		Sender: users[0],
+		Args:   []string{channels[0], "new topic"},
+		Time:   time.Unix(2000, 0),
+	})
+
+	ch, _ = st.Channel(channels[0])
+	if got, exp := ch.TopicSetter, users[0]; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := ch.TopicTime.Unix(), int64(2000); exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := len(ch.TopicHistory), 2; exp != got {
+		t.Fatalf("Expected: %v, got: %v", exp, got)
+	}
+	if got, exp := ch.TopicHistory[0].Topic, "topic topic"; exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+}
+
+func TestState_UpdateRplCreationTime(t *testing.T) {
+	t.Parallel()
+
+	st := setupNewState()
+	st.addChannel(channels[0])
+
+	st.Update(&irc.Event{
+		Name:   irc.RPL_CREATIONTIME,
+		Sender: network,
+		Args:   []string{st.selfUser.Nick(), channels[0], "1000"},
+	})
+
+	ch, _ := st.Channel(channels[0])
+	if got, exp := ch.Created.Unix(), int64(1000); exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+
+	st.Update(&irc.Event{
+		Name:   irc.RPL_CREATIONTIME,
+		Sender: network,
+		Args:   []string{st.selfUser.Nick(), channels[0], "notanumber"},
+	})
+
+	ch, _ = st.Channel(channels[0])
+	if got, exp := ch.Created.Unix(), int64(1000); exp != got {
+		t.Errorf("Expected: %v, got: %v", exp, got)
+	}
+}
+
 func TestState_UpdateEmptyTopic(t *testing.T) {
 	t.Parallel()
 
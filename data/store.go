@@ -1,14 +1,12 @@
 package data
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"io"
-	"os"
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/cznic/kv"
 )
 
 // defaultTimeout is the default amount of time after being "unseen" that
@@ -23,6 +21,8 @@ const (
 	AuthErrBadPassword AuthFailure = iota + 1
 	AuthErrHostNotFound
 	AuthErrUserNotFound
+	AuthErrThrottled
+	AuthErrLockedOut
 )
 
 // These error messages are put into the AuthError's string field and will
@@ -51,7 +51,20 @@ func (a AuthError) Error() string {
 }
 
 var (
-	// nMaxCache is the number of users to store in the cache.
+	// ErrNotFound is returned when updating a user that isn't saved.
+	ErrNotFound = errors.New("data: record not found")
+	// ErrRevisionMismatch is returned by a compare and swap when the record
+	// was saved again after the revision being saved was read.
+	ErrRevisionMismatch = errors.New("data: record was changed since it was read")
+	// ErrUnchanged can be returned by the function given to an update to
+	// leave the record as it is, the update then returns nil.
+	ErrUnchanged = errors.New("data: record unchanged")
+
+	errRename = errors.New("data: records can't be renamed by an update")
+)
+
+var (
+	// nMaxCache is the default number of users to store in the cache.
 	nMaxCache = 1000
 	// isIninitialized is a key into the database that checks if the first
 	// user has been set.
@@ -59,35 +72,96 @@ var (
 )
 
 // DbProvider is a function that provides an internal database.
-type DbProvider func() (*kv.DB, error)
+type DbProvider func() (Backend, error)
 
 // Store is used to store StoredUser objects, and cache their lookup.
 type Store struct {
-	db *kv.DB
+	db Backend
 
 	protect  sync.Mutex
-	cache    map[string]*StoredUser
+	cache    *userCache
 	authed   map[string]string
 	timeouts map[string]time.Time
+
+	sessions        map[string]*Session
+	sessionLifetime time.Duration
+	accounts        map[string]string
+	certs           map[string]string
+
+	userFailures   map[string]*authFailures
+	hostFailures   map[string]*authFailures
+	lockoutHandler func(Lockout)
+	expiryHandler  func(ExpiredAccess)
+
+	changeHandler func(StoreChange)
+	changes       []StoreChange
+	delivering    bool
+
+	auditState auditState
+	policy     *policy
+
+	secretProtect sync.RWMutex
+	secretKeys    *secretKeyring
+
+	backupProtect sync.Mutex
+	backupMaxAge  time.Duration
+	backupKeep    int
+
+	migration *MigrationReport
 }
 
-// NewStore initializes a store type.
+// NewStore initializes a store type. The database is migrated to the current
+// schema version and the persisted sessions are restored before the store is
+// returned.
 func NewStore(prov DbProvider) (*Store, error) {
 	db, err := prov()
 	if err != nil {
 		return nil, err
 	}
 
+	report, err := migrate(db, false)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	s := &Store{
-		db:       db,
-		cache:    make(map[string]*StoredUser),
-		authed:   make(map[string]string),
-		timeouts: make(map[string]time.Time),
+		db:        db,
+		cache:     newUserCache(nMaxCache, 0),
+		authed:    make(map[string]string),
+		timeouts:  make(map[string]time.Time),
+		sessions:  make(map[string]*Session),
+		accounts:  make(map[string]string),
+		certs:     make(map[string]string),
+		policy:    newPolicy(),
+		migration: report,
+
+		userFailures: make(map[string]*authFailures),
+		hostFailures: make(map[string]*authFailures),
+	}
+
+	if err = s.restoreSessions(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err = s.loadRoles(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err = s.loadAudit(); err != nil {
+		db.Close()
+		return nil, err
 	}
 
 	return s, nil
 }
 
+// Migration returns the report of the migration that was done when the store
+// was opened.
+func (s *Store) Migration() *MigrationReport {
+	return s.migration
+}
+
 // Close closes the underlying database.
 func (s *Store) Close() error {
 	return s.db.Close()
@@ -95,92 +169,287 @@ func (s *Store) Close() error {
 
 // GlobalUsers gets users with global access
 func (s *Store) GlobalUsers() ([]*StoredUser, error) {
-	return iterate(s.db, func(ua *StoredUser) bool {
-		a, ok := ua.GetAccess("", "")
-		return ok && !a.IsZero()
-	})
+	return s.scopeUsers("", "")
 }
 
 // NetworkUsers gets users with Network access
 func (s *Store) NetworkUsers(network string) ([]*StoredUser, error) {
-	return iterate(s.db, func(ua *StoredUser) bool {
-		a, ok := ua.GetAccess(network, "")
-		return ok && !a.IsZero()
-	})
+	return s.scopeUsers(network, "")
 }
 
 // ChanUsers gets users with access to a channel
 func (s *Store) ChanUsers(network, channel string) ([]*StoredUser, error) {
-	return iterate(s.db, func(ua *StoredUser) bool {
-		a, ok := ua.GetAccess(network, channel)
-		return ok && !a.IsZero()
-	})
+	return s.scopeUsers(network, channel)
 }
 
-func iterate(db *kv.DB, filter func(*StoredUser) bool) ([]*StoredUser, error) {
-	list := make([]*StoredUser, 0)
-
-	e, err := db.SeekFirst()
-	switch {
-	case err == io.EOF:
-		return nil, nil
-	case err != nil:
+// scopeUsers looks up the users with access to a scope in the access index.
+func (s *Store) scopeUsers(network, channel string) ([]*StoredUser, error) {
+	users, err := s.indexedUsers(s.db, accessIndexPrefix, mkKey(network, channel))
+	if _, ok := err.(RecordErrors); err != nil && !ok {
 		return nil, err
 	}
 
-	var stop error
-	var val []byte
-	for ; stop == nil; _, val, stop = e.Next() {
-		if ua, err := deserializeUser(val); err == nil && filter(ua) {
+	// Guard against stale index entries.
+	list := users[:0]
+	for _, ua := range users {
+		if a, ok := ua.EffectiveAccess(network, channel); ok && !a.IsZero() {
 			list = append(list, ua)
 		}
 	}
+	if len(list) == 0 {
+		list = nil
+	}
+
+	return list, err
+}
+
+// iterate returns the users that pass filter. If some users could not be read
+// the users that could be are returned along with a RecordErrors.
+func (s *Store) iterate(tx Tx, filter func(*StoredUser) bool) ([]*StoredUser, error) {
+	var list []*StoredUser
+
+	err := scanRecords(tx, recordUser, func(_, val []byte) error {
+		ua, err := deserializeUser(val)
+		if err != nil {
+			return err
+		}
+		if filter(s.attach(ua)) {
+			list = append(list, ua)
+		}
+		return nil
+	})
+	if _, ok := err.(RecordErrors); err != nil && !ok {
+		return nil, err
+	}
 
-	return list, nil
+	return list, err
 }
 
-// SaveUser saves a user to the database.
+// SaveUser saves a user to the database, overwriting any changes saved since
+// it was read. The user's revision is updated.
 func (s *Store) SaveUser(ua *StoredUser) error {
-	var err error
-	var serialized []byte
+	return s.saveUser(ua, false, nil, nil)
+}
+
+// SaveUserAudit is SaveUser that also writes the audit entries made by audit
+// from the user before and after the save in the same transaction, before is
+// nil if the user is being created. audit must not use the store.
+func (s *Store) SaveUserAudit(ua *StoredUser,
+	audit func(before, after *StoredUser) []AuditEntry) error {
+
+	return s.saveUser(ua, false, nil, audit)
+}
 
-	serialized, err = ua.serialize()
+// CompareAndSwapUser saves a user only if the saved user is still at the
+// user's revision, a user that isn't saved is at revision 0. Otherwise
+// ErrRevisionMismatch is returned and nothing is saved. The user's revision
+// is updated.
+func (s *Store) CompareAndSwapUser(ua *StoredUser) error {
+	return s.saveUser(ua, true, nil, nil)
+}
+
+// CompareAndSwapUserAudit is CompareAndSwapUser that also writes the audit
+// entries made by audit from the user before and after the swap in the same
+// transaction, before is nil if the user is being created. audit must not
+// use the store.
+func (s *Store) CompareAndSwapUserAudit(ua *StoredUser,
+	audit func(before, after *StoredUser) []AuditEntry) error {
+
+	return s.saveUser(ua, true, nil, audit)
+}
+
+// CreateUser saves a user that isn't saved yet, it's CompareAndSwapUser at
+// revision 0 so ErrRevisionMismatch is returned if they are. If no users
+// are saved first is called to change the user before it's saved, in the
+// same transaction so only one user can ever be the first. first must not
+// use the store. Returns true if first was called.
+func (s *Store) CreateUser(ua *StoredUser,
+	first func(*StoredUser)) (isFirst bool, err error) {
+
+	ua.Revision = 0
+	err = s.saveUser(ua, true, func(tx Tx) error {
+		if first == nil {
+			return nil
+		}
+		has, err := hasAnyTx(tx)
+		if err != nil || has {
+			return err
+		}
+		first(ua)
+		isFirst = true
+		return nil
+	}, nil)
 	if err != nil {
-		return err
+		isFirst = false
 	}
+	return isFirst, err
+}
+
+// saveUser saves a user, checking the revision if cas is set. prepare is
+// called before the user is saved in the same transaction.
+func (s *Store) saveUser(ua *StoredUser, cas bool, prepare func(Tx) error,
+	audit func(before, after *StoredUser) []AuditEntry) error {
+
+	// Hold the lock over the write so the cache can't be left with a
+	// different user than the database when saves race.
+	s.protect.Lock()
+	defer s.protect.Unlock()
 
-	s.db.Set([]byte(ua.Username), serialized)
+	username := strings.ToLower(ua.Username)
+	revision := ua.Revision
+	var entries []AuditEntry
+	err := s.db.Update(func(tx Tx) error {
+		var saved *StoredUser
+		if cas || audit != nil {
+			var err error
+			if saved, err = s.userTx(tx, username); err != nil {
+				return err
+			}
+		}
+		if cas && (saved == nil && revision != 0 ||
+			saved != nil && saved.Revision != revision) {
+			return ErrRevisionMismatch
+		}
+		if prepare != nil {
+			if err := prepare(tx); err != nil {
+				return err
+			}
+		}
+		if err := saveUserTx(tx, ua); err != nil {
+			return err
+		}
+
+		if audit != nil {
+			entries = audit(saved, ua)
+		}
+		return s.auditTx(tx, entries)
+	})
 	if err != nil {
+		ua.Revision = revision
+		s.cache.remove(username)
 		return err
 	}
 
+	s.cache.put(username, s.attach(ua).Clone())
+	s.notifyChange(userSaved(ua))
+	s.audited(entries)
+	return nil
+}
+
+// UpdateUser reads a user, changes it with fn and saves it in a single
+// transaction so concurrent changes can't be lost. If fn returns an error
+// nothing is saved and it's returned, unless it's ErrUnchanged. The user
+// can't be renamed, and fn must not use the store. ErrNotFound is returned
+// if the user isn't saved.
+func (s *Store) UpdateUser(username string, fn func(*StoredUser) error) error {
+	return s.UpdateUserAudit(username, fn, nil)
+}
+
+// UpdateUserAudit is UpdateUser that also writes the audit entries made by
+// audit from the user before and after the change in the same transaction.
+// audit is only called if fn changed the user, and must not use the store.
+func (s *Store) UpdateUserAudit(username string, fn func(*StoredUser) error,
+	audit func(before, after *StoredUser) []AuditEntry) error {
+
+	username = strings.ToLower(username)
+
 	s.protect.Lock()
-	s.checkCacheLimits()
-	s.cache[ua.Username] = ua.Clone()
-	s.protect.Unlock()
+	defer s.protect.Unlock()
+
+	var user *StoredUser
+	var entries []AuditEntry
+	err := s.db.Update(func(tx Tx) (err error) {
+		if user, err = s.userTx(tx, username); err != nil {
+			return err
+		} else if user == nil {
+			return ErrNotFound
+		}
+
+		var before *StoredUser
+		if audit != nil {
+			before = user.Clone()
+		}
+		if err = fn(user); err != nil {
+			return err
+		}
+		if strings.ToLower(user.Username) != username {
+			return errRename
+		}
+		if err = saveUserTx(tx, user); err != nil {
+			return err
+		}
+
+		if audit != nil {
+			entries = audit(before, user)
+		}
+		return s.auditTx(tx, entries)
+	})
+	switch {
+	case err == ErrUnchanged:
+		return nil
+	case err != nil:
+		s.cache.remove(username)
+		return err
+	}
+
+	s.cache.put(username, user.Clone())
+	s.notifyChange(userSaved(user))
+	// The change is saved, a failed prune is tried again by the next entry.
+	s.audited(entries)
 	return nil
 }
 
+// userTx reads a user inside a transaction, nil if it isn't saved.
+func (s *Store) userTx(tx Tx, username string) (*StoredUser, error) {
+	serialized, err := tx.Get([]byte(username))
+	if err != nil || serialized == nil {
+		return nil, err
+	}
+	user, err := deserializeUser(serialized)
+	return s.attach(user), err
+}
+
 // RemoveUser removes a user from the database, returns true if successful.
 func (s *Store) RemoveUser(username string) (removed bool, err error) {
+	return s.RemoveUserAudit(username, nil)
+}
+
+// RemoveUserAudit is RemoveUser that also writes the audit entries made by
+// audit from the removed user in the same transaction. audit must not use
+// the store.
+func (s *Store) RemoveUserAudit(username string,
+	audit func(removed *StoredUser) []AuditEntry) (removed bool, err error) {
+
 	username = strings.ToLower(username)
-	var exists *StoredUser
 
 	s.protect.Lock()
 	defer s.protect.Unlock()
 
-	exists, err = s.findUser(username)
-	if err != nil || exists == nil {
-		return
-	}
+	s.cache.remove(username)
 
-	delete(s.cache, username)
+	var entries []AuditEntry
+	err = s.db.Update(func(tx Tx) error {
+		user, err := s.userTx(tx, username)
+		if err != nil || user == nil {
+			return err
+		}
+		if err = removeUserTx(tx, username); err != nil {
+			return err
+		}
 
-	err = s.db.Delete([]byte(username))
-	if err != nil {
+		removed = true
+		if audit != nil {
+			entries = audit(user)
+		}
+		return s.auditTx(tx, entries)
+	})
+	if err != nil || !removed {
+		removed = false
 		return
 	}
-	removed = true
+
+	s.notifyChange(StoreChange{Kind: UserRemoved, Username: username})
+	s.audited(entries)
 	return
 }
 
@@ -216,12 +485,17 @@ func (s *Store) authUser(
 		return s.findUser(uname)
 	}
 
+	if err = s.checkLockout(network, host, username); err != nil {
+		return nil, err
+	}
+
 	user, err = s.findUser(username)
 	if err != nil {
 		return nil, err
 	}
 
 	if user == nil {
+		s.recordFailure(network, host, username, false)
 		return nil, AuthError{
 			fmt.Sprintf(errFmtUserNotFound, username),
 			AuthErrUserNotFound,
@@ -229,27 +503,42 @@ func (s *Store) authUser(
 	}
 
 	if !user.HasMask(host) {
+		s.notifyLockout(s.recordFailure(network, host, username, true))
 		return nil, AuthError{
 			fmt.Sprintf(errFmtBadHost, host, username),
 			AuthErrHostNotFound,
 		}
 	}
 
-	if !user.VerifyPassword(password) {
+	ok, rehashed := user.verifyPassword(password)
+	if !ok {
+		s.notifyLockout(s.recordFailure(network, host, username, true))
 		return nil, AuthError{
 			fmt.Sprintf(errFmtBadPassword, username),
 			AuthErrBadPassword,
 		}
 	}
 
-	if temp {
-		s.timeouts[network+host] = time.Now().UTC().Add(defaultTimeout)
+	if rehashed {
+		// Upgrading the hash is best effort, the old one still works.
+		err = s.db.Update(func(tx Tx) error {
+			return saveUserTx(tx, user)
+		})
+		if err == nil {
+			s.cache.put(username, user.Clone())
+		}
+	}
+
+	s.clearFailures(network, host, username)
+
+	if err = s.startSession(network, host, username, temp); err != nil {
+		return nil, err
 	}
-	s.authed[network+host] = username
 	return user.Clone(), nil
 }
 
-// AuthedUser looks up a user that was authenticated previously.
+// AuthedUser looks up a user that was authenticated previously, or whose
+// services account or certificate fingerprint is known to be a user's.
 func (s *Store) AuthedUser(network, host string) *StoredUser {
 	s.protect.Lock()
 	defer s.protect.Unlock()
@@ -257,14 +546,17 @@ func (s *Store) AuthedUser(network, host string) *StoredUser {
 		user, _ := s.findUser(username)
 		return user
 	}
-	return nil
+	if user := s.accountUser(network, host); user != nil {
+		return user
+	}
+	return s.certUser(network, host)
 }
 
 // Logout logs an authenticated host out.
 func (s *Store) Logout(network, host string) {
 	s.protect.Lock()
 	defer s.protect.Unlock()
-	delete(s.authed, network+host)
+	s.endSession(network + host)
 }
 
 // LogoutByUsername logs an authenticated username out.
@@ -281,7 +573,7 @@ func (s *Store) LogoutByUsername(username string) {
 	}
 
 	for _, h := range hosts {
-		delete(s.authed, h)
+		s.endSession(h)
 	}
 }
 
@@ -291,33 +583,57 @@ func (s *Store) Update(network string, update StateUpdate) {
 	s.protect.Lock()
 	defer s.protect.Unlock()
 
+	now := time.Now().UTC()
 	for _, seen := range update.Seen {
 		delete(s.timeouts, network+seen)
+		if sess, ok := s.sessions[network+seen]; ok {
+			s.touchSession(sess, now)
+		}
 	}
 	for _, unseen := range update.Unseen {
 		if _, ok := s.timeouts[network+unseen]; !ok {
-			s.timeouts[network+unseen] = time.Now().UTC().Add(defaultTimeout)
+			s.timeouts[network+unseen] = now.Add(defaultTimeout)
 		}
+		// These will be reported again if the user is seen.
+		s.setAccount(network, unseen, "")
+		s.setCertFP(network, unseen, "")
 	}
 	if len(update.Nick) > 0 {
-		s.authed[network+update.Nick[1]] = s.authed[network+update.Nick[0]]
-		delete(s.timeouts, network+update.Nick[0])
-		delete(s.authed, network+update.Nick[0])
+		s.moveSession(network, update.Nick[0], update.Nick[1])
+		s.moveAccount(network, update.Nick[0], update.Nick[1])
+		s.moveCertFP(network, update.Nick[0], update.Nick[1])
 	}
 	if len(update.Quit) > 0 {
-		delete(s.timeouts, network+update.Quit)
-		delete(s.authed, network+update.Quit)
+		s.endSession(network + update.Quit)
+		s.setAccount(network, update.Quit, "")
+		s.setCertFP(network, update.Quit, "")
+	}
+	if len(update.Account) > 0 {
+		s.setAccount(network, update.Account[0], update.Account[1])
+	}
+	if len(update.CertFP) > 0 {
+		s.setCertFP(network, update.CertFP[0], update.CertFP[1])
 	}
 
 	s.reap()
 }
 
-// reap removes users who have exceeded their temporary auths.
+// reap removes users who have exceeded their temporary auths or the maximum
+// session lifetime.
 func (s *Store) reap() {
+	now := time.Now().UTC()
 	for key, date := range s.timeouts {
-		if time.Now().UTC().After(date) {
-			delete(s.authed, key)
-			delete(s.timeouts, key)
+		if now.After(date) {
+			s.endSession(key)
+		}
+	}
+
+	if s.sessionLifetime <= 0 {
+		return
+	}
+	for key, sess := range s.sessions {
+		if now.Sub(sess.Created) > s.sessionLifetime {
+			s.endSession(key)
 		}
 	}
 }
@@ -335,7 +651,7 @@ func (s *Store) FindUser(username string) (user *StoredUser, err error) {
 func (s *Store) findUser(username string) (user *StoredUser, err error) {
 	username = strings.ToLower(username)
 
-	if cached, ok := s.cache[username]; ok {
+	if cached, ok := s.cache.get(username); ok {
 		user = cached.Clone()
 		return user, nil
 	}
@@ -345,8 +661,7 @@ func (s *Store) findUser(username string) (user *StoredUser, err error) {
 		return user, err
 	}
 
-	s.checkCacheLimits()
-	s.cache[username] = user.Clone()
+	s.cache.put(username, user.Clone())
 	return
 }
 
@@ -354,49 +669,192 @@ func (s *Store) findUser(username string) (user *StoredUser, err error) {
 func (s *Store) fetchUser(username string) (user *StoredUser, err error) {
 	username = strings.ToLower(username)
 	var serialized []byte
-	serialized, err = s.db.Get(nil, []byte(username))
+	serialized, err = s.db.Get([]byte(username))
 	if err != nil || serialized == nil {
 		return
 	}
 
 	user, err = deserializeUser(serialized)
+	s.attach(user)
 	return
 }
 
-// SaveChannel saves a channel to the database.
+// SaveChannel saves a channel to the database, overwriting any changes saved
+// since it was read. The channel's revision is updated.
 func (s *Store) SaveChannel(sc *StoredChannel) error {
-	var err error
-	var serialized []byte
+	return s.saveChannel(sc, false, nil)
+}
+
+// CompareAndSwapChannel saves a channel only if the saved channel is still
+// at the channel's revision, a channel that isn't saved is at revision 0.
+// Otherwise ErrRevisionMismatch is returned and nothing is saved. The
+// channel's revision is updated. The audit entries are written if the
+// channel is saved.
+func (s *Store) CompareAndSwapChannel(sc *StoredChannel,
+	audit ...AuditEntry) error {
+
+	return s.saveChannel(sc, true, audit)
+}
 
-	serialized, err = sc.serialize()
+// saveChannel saves a channel, checking the revision if cas is set.
+func (s *Store) saveChannel(sc *StoredChannel, cas bool,
+	audit []AuditEntry) error {
+
+	revision := sc.Revision
+	err := s.db.Update(func(tx Tx) error {
+		if cas {
+			saved, err := channelTx(tx, sc.makeID())
+			if err != nil {
+				return err
+			}
+			if saved == nil && revision != 0 ||
+				saved != nil && saved.Revision != revision {
+				return ErrRevisionMismatch
+			}
+		}
+		if err := saveChannelTx(tx, sc); err != nil {
+			return err
+		}
+		return s.auditTx(tx, audit)
+	})
 	if err != nil {
+		sc.Revision = revision
 		return err
 	}
 
-	s.db.Set([]byte(sc.makeID()), serialized)
-	if err != nil {
+	s.protect.Lock()
+	s.notifyChange(channelSaved(sc))
+	s.protect.Unlock()
+	s.audited(audit)
+	return nil
+}
+
+// UpdateChannel reads a channel, changes it with fn and saves it in a single
+// transaction so concurrent changes can't be lost. A channel that isn't
+// saved is created. If fn returns an error nothing is saved and it's
+// returned, unless it's ErrUnchanged. The channel can't be renamed, and fn
+// must not use the store.
+func (s *Store) UpdateChannel(netID, name string,
+	fn func(*StoredChannel) error) error {
+
+	return s.UpdateChannelAudit(netID, name, fn, nil)
+}
+
+// UpdateChannelAudit is UpdateChannel that also writes the audit entries made
+// by audit from the channel before and after the change in the same
+// transaction, before is nil if the channel is being created. audit is only
+// called if fn changed the channel, and must not use the store.
+func (s *Store) UpdateChannelAudit(netID, name string,
+	fn func(*StoredChannel) error,
+	audit func(before, after *StoredChannel) []AuditEntry) error {
+
+	var channel *StoredChannel
+	var entries []AuditEntry
+	err := s.db.Update(func(tx Tx) (err error) {
+		key := (&StoredChannel{NetID: netID, Name: name}).makeID()
+		channel, err = channelTx(tx, key)
+		if err != nil {
+			return err
+		}
+
+		var before *StoredChannel
+		if channel == nil {
+			channel = NewStoredChannel(netID, name)
+		} else if audit != nil {
+			before = channel.Clone()
+		}
+
+		if err = fn(channel); err != nil {
+			return err
+		}
+		if channel.makeID() != key {
+			return errRename
+		}
+		if err = saveChannelTx(tx, channel); err != nil {
+			return err
+		}
+
+		if audit != nil {
+			entries = audit(before, channel)
+		}
+		return s.auditTx(tx, entries)
+	})
+	if err == ErrUnchanged {
+		return nil
+	} else if err != nil {
 		return err
 	}
 
+	s.protect.Lock()
+	s.notifyChange(channelSaved(channel))
+	s.protect.Unlock()
+	s.audited(entries)
 	return nil
 }
 
-// RemoveChannel removes a channel from the database, returns true if successful
-func (s *Store) RemoveChannel(netID, name string) (removed bool, err error) {
-	var exists *StoredChannel
-	exists, err = s.FindChannel(netID, name)
-	if err != nil || exists == nil {
-		return
+// saveChannelTx saves a channel, its revision is set to the one after the
+// revision that was saved.
+func saveChannelTx(tx Tx, sc *StoredChannel) error {
+	key := []byte(sc.makeID())
+	old, err := tx.Get(key)
+	if err != nil {
+		return err
+	}
+
+	var revision uint64
+	if old != nil {
+		// A channel that can't be read is replaced.
+		if saved, err := deserializeChannel(old); err == nil {
+			revision = saved.Revision
+		}
+	}
+
+	sc.Revision = revision + 1
+	serialized, err := sc.serialize()
+	if err != nil {
+		return err
 	}
+	return tx.Put(key, serialized)
+}
+
+// channelTx reads a channel inside a transaction, nil if it isn't saved.
+func channelTx(tx Tx, key string) (*StoredChannel, error) {
+	serialized, err := tx.Get([]byte(key))
+	if err != nil || serialized == nil {
+		return nil, err
+	}
+	return deserializeChannel(serialized)
+}
+
+// RemoveChannel removes a channel from the database, returns true if
+// successful. The audit entries are written if the channel is removed.
+func (s *Store) RemoveChannel(netID, name string,
+	audit ...AuditEntry) (removed bool, err error) {
 
 	ch := StoredChannel{NetID: netID, Name: name}
 	key := ch.makeID()
 
-	err = s.db.Delete([]byte(key))
-	if err != nil {
+	err = s.db.Update(func(tx Tx) error {
+		exists, err := channelTx(tx, key)
+		if err != nil || exists == nil {
+			return err
+		}
+		if err = tx.Delete([]byte(key)); err != nil {
+			return err
+		}
+		removed = true
+		return s.auditTx(tx, audit)
+	})
+	if err != nil || !removed {
+		removed = false
 		return
 	}
-	removed = true
+
+	s.protect.Lock()
+	s.notifyChange(StoreChange{Kind: ChannelRemoved, Network: netID,
+		Channel: name})
+	s.protect.Unlock()
+	s.audited(audit)
 	return
 }
 
@@ -408,7 +866,7 @@ func (s *Store) FindChannel(netID, name string) (channel *StoredChannel,
 	key := ch.makeID()
 
 	var serialized []byte
-	serialized, err = s.db.Get(nil, []byte(key))
+	serialized, err = s.db.Get([]byte(key))
 	if err != nil || serialized == nil {
 		return
 	}
@@ -417,62 +875,63 @@ func (s *Store) FindChannel(netID, name string) (channel *StoredChannel,
 	return
 }
 
-// Channels returns a slice of the channels found in the database.
+// Channels returns a slice of the channels found in the database. If some
+// channels could not be read the channels that could be are returned along
+// with a RecordErrors.
 func (s *Store) Channels() ([]*StoredChannel, error) {
-	list := make([]*StoredChannel, 0)
+	return channelsTx(s.db)
+}
 
-	e, err := s.db.SeekFirst()
-	switch {
-	case err == io.EOF:
-		return nil, nil
-	case err != nil:
-		return nil, err
-	}
+// channelsTx reads the channels inside a transaction, like Channels.
+func channelsTx(tx Tx) ([]*StoredChannel, error) {
+	var list []*StoredChannel
 
-	var stop error
-	var val []byte
-	for ; stop == nil; _, val, stop = e.Next() {
-		if ua, err := deserializeChannel(val); err == nil {
-			list = append(list, ua)
+	err := scanRecords(tx, recordChannel, func(_, val []byte) error {
+		ch, err := deserializeChannel(val)
+		if err != nil {
+			return err
 		}
+		list = append(list, ch)
+		return nil
+	})
+	if _, ok := err.(RecordErrors); err != nil && !ok {
+		return nil, err
 	}
 
-	return list, nil
+	return list, err
 }
 
-// checkCacheLimits verifies if adding one to the size of the cache will
-// cross it's boundaries, if so, it dumps the cache.
-func (s *Store) checkCacheLimits() {
-	if len(s.cache)+1 > nMaxCache {
-		s.cache = make(map[string]*StoredUser)
-	}
-}
+// SetCacheLimits sets the most users that are cached and how long each is
+// cached for, a ttl of 0 caches users until they're evicted to make room.
+func (s *Store) SetCacheLimits(size int, ttl time.Duration) {
+	s.protect.Lock()
+	defer s.protect.Unlock()
 
-// HasAny checks to see if there are any users in the database.
-func (s *Store) HasAny() (has bool, err error) {
-	var k, v []byte
-	k, v, err = s.db.First()
-	return k != nil || v != nil, err
+	s.cache.resize(size, ttl)
 }
 
-// MakeFileStoreProvider is the default way to create a store by using the
-// filename and trying to open it.
-func MakeFileStoreProvider(filename string) DbProvider {
-	return func() (db *kv.DB, err error) {
-		opts := &kv.Options{}
+// CacheStats returns the counters of the user cache.
+func (s *Store) CacheStats() CacheStats {
+	s.protect.Lock()
+	defer s.protect.Unlock()
 
-		_, err = os.Stat(filename)
+	return s.cache.stats()
+}
 
-		if os.IsNotExist(err) {
-			db, err = kv.Create(filename, opts)
-		} else {
-			db, err = kv.Open(filename, opts)
-		}
-		return
-	}
+// HasAny checks to see if there are any users in the database.
+func (s *Store) HasAny() (has bool, err error) {
+	return hasAnyTx(s.db)
 }
 
-// MemStoreProvider provides memory-only database stores.
-func MemStoreProvider() (*kv.DB, error) {
-	return kv.CreateMem(&kv.Options{})
+// hasAnyTx checks if there are any users inside a transaction.
+func hasAnyTx(tx Tx) (has bool, err error) {
+	err = tx.Scan(nil, func(key, val []byte) bool {
+		if bytes.HasPrefix(key, metaPrefix) {
+			return true
+		}
+		kind, _, _, err := recordHeader(val)
+		has = err == nil && kind == recordUser
+		return !has
+	})
+	return has, err
 }
@@ -0,0 +1,169 @@
+package data
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aarondl/ultimateq/api"
+)
+
+// ChangeKind is the kind of change a StoreChange describes.
+type ChangeKind int
+
+// The kinds of changes the store publishes.
+const (
+	UserSaved ChangeKind = iota + 1
+	UserRemoved
+	SessionOpened
+	SessionClosed
+	ChannelSaved
+	ChannelRemoved
+)
+
+var changeKindNames = []string{
+	UserSaved:      "user_saved",
+	UserRemoved:    "user_removed",
+	SessionOpened:  "session_opened",
+	SessionClosed:  "session_closed",
+	ChannelSaved:   "channel_saved",
+	ChannelRemoved: "channel_removed",
+}
+
+// String gets the name of the change kind.
+func (k ChangeKind) String() string {
+	if k < UserSaved || int(k) >= len(changeKindNames) {
+		return "unknown"
+	}
+	return changeKindNames[k]
+}
+
+// ParseChangeKind gets a change kind from its name.
+func ParseChangeKind(name string) (ChangeKind, bool) {
+	for i, n := range changeKindNames {
+		if len(n) != 0 && strings.EqualFold(n, name) {
+			return ChangeKind(i), true
+		}
+	}
+	return 0, false
+}
+
+// StoreChange is a change made to the store. Users have a Username, sessions
+// have a Username, Network and Host and channels have a Network and Channel.
+type StoreChange struct {
+	Kind     ChangeKind
+	Username string
+	Network  string
+	Host     string
+	Channel  string
+	// Revision is the revision a user or channel was saved at.
+	Revision uint64
+	Time     time.Time
+}
+
+// ToProto converts a change to its protocol buffer form.
+func (c StoreChange) ToProto() *api.StoreChange {
+	return &api.StoreChange{
+		Kind:     c.Kind.String(),
+		Username: c.Username,
+		Network:  c.Network,
+		Host:     c.Host,
+		Channel:  c.Channel,
+		Revision: c.Revision,
+		Time:     c.Time.Unix(),
+	}
+}
+
+// FromProto fills a change from its protocol buffer form, false is returned
+// if the kind is unknown.
+func (c *StoreChange) FromProto(proto *api.StoreChange) bool {
+	var ok bool
+	if c.Kind, ok = ParseChangeKind(proto.Kind); !ok {
+		return false
+	}
+	c.Username = proto.Username
+	c.Network = proto.Network
+	c.Host = proto.Host
+	c.Channel = proto.Channel
+	c.Revision = proto.Revision
+	c.Time = time.Unix(proto.Time, 0).UTC()
+	return true
+}
+
+// SetChangeHandler sets a function to call for each change made to the
+// store. Changes are given to it in the order they were made on a goroutine
+// of its own, so it may use the store but a slow handler delays the changes
+// after it.
+func (s *Store) SetChangeHandler(handler func(StoreChange)) {
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	s.changeHandler = handler
+}
+
+// notifyChange queues changes for the change handler.
+// warning: Assumes the store is locked
+func (s *Store) notifyChange(changes ...StoreChange) {
+	if s.changeHandler == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, c := range changes {
+		c.Time = now
+		s.changes = append(s.changes, c)
+	}
+
+	if !s.delivering {
+		s.delivering = true
+		go s.deliverChanges()
+	}
+}
+
+// deliverChanges gives the queued changes to the change handler until there
+// are none left.
+func (s *Store) deliverChanges() {
+	for {
+		s.protect.Lock()
+		changes, handler := s.changes, s.changeHandler
+		s.changes = nil
+		if len(changes) == 0 || handler == nil {
+			s.delivering = false
+			s.protect.Unlock()
+			return
+		}
+		s.protect.Unlock()
+
+		for _, c := range changes {
+			handler(c)
+		}
+	}
+}
+
+// userSaved is the change for saving a user.
+func userSaved(user *StoredUser) StoreChange {
+	return StoreChange{
+		Kind:     UserSaved,
+		Username: strings.ToLower(user.Username),
+		Revision: user.Revision,
+	}
+}
+
+// channelSaved is the change for saving a channel.
+func channelSaved(channel *StoredChannel) StoreChange {
+	return StoreChange{
+		Kind:     ChannelSaved,
+		Network:  channel.NetID,
+		Channel:  channel.Name,
+		Revision: channel.Revision,
+	}
+}
+
+// sessionChange is the change for opening or closing a session.
+func sessionChange(kind ChangeKind, sess *Session) StoreChange {
+	return StoreChange{
+		Kind:     kind,
+		Username: sess.Username,
+		Network:  sess.Network,
+		Host:     sess.Host,
+	}
+}
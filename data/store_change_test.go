@@ -0,0 +1,97 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChangeKind(t *testing.T) {
+	t.Parallel()
+
+	for k := UserSaved; k <= ChannelRemoved; k++ {
+		if got, ok := ParseChangeKind(k.String()); !ok || got != k {
+			t.Errorf("%v: expected to parse its own name, got: %v", k, got)
+		}
+	}
+	if _, ok := ParseChangeKind("unknown"); ok {
+		t.Error("Expected unknown to fail.")
+	}
+	if s := ChangeKind(0).String(); s != "unknown" {
+		t.Error("Expected unknown, got:", s)
+	}
+}
+
+func TestStore_Changes(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	changes := make(chan StoreChange, 20)
+	s.SetChangeHandler(func(c StoreChange) {
+		changes <- c
+	})
+
+	user, _ := NewStoredUser(uname, password, `*!*@host`)
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	err = s.UpdateUser(uname, func(u *StoredUser) error {
+		return ErrUnchanged
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.AuthUserPerma(network, host, uname, password); err != nil {
+		t.Fatal(err)
+	}
+	s.Update(network, StateUpdate{Nick: []string{host, "nick!user@host"}})
+	s.Logout(network, "nick!user@host")
+	if err = s.SaveChannel(NewStoredChannel(network, channel)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.RemoveChannel(network, channel); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.RemoveUser(uname); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []StoreChange{
+		{Kind: UserSaved, Username: uname, Revision: 1},
+		{Kind: SessionOpened, Username: uname, Network: network, Host: host},
+		{Kind: SessionClosed, Username: uname, Network: network, Host: host},
+		{Kind: SessionOpened, Username: uname, Network: network,
+			Host: "nick!user@host"},
+		{Kind: SessionClosed, Username: uname, Network: network,
+			Host: "nick!user@host"},
+		{Kind: ChannelSaved, Network: network, Channel: channel, Revision: 1},
+		{Kind: ChannelRemoved, Network: network, Channel: channel},
+		{Kind: UserRemoved, Username: uname},
+	}
+
+	for i, e := range expect {
+		var got StoreChange
+		select {
+		case got = <-changes:
+		case <-time.After(time.Second):
+			t.Fatalf("%d: timed out waiting for %v", i, e.Kind)
+		}
+
+		if got.Time.IsZero() {
+			t.Errorf("%d: expected the change to have a time", i)
+		}
+		got.Time = time.Time{}
+		if got != e {
+			t.Errorf("%d: expected %#v, got: %#v", i, e, got)
+		}
+	}
+
+	select {
+	case c := <-changes:
+		t.Error("Expected no more changes, got:", c)
+	default:
+	}
+}
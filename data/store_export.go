@@ -0,0 +1,211 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// StoreExportVersion is the version of the document written by Store.Export.
+// Import refuses documents with a version it does not understand. Version 2
+// added the stored roles.
+const StoreExportVersion = 2
+
+// StoreExport is a portable copy of all the users, channels and stored roles
+// in a Store. It is written and read as JSON.
+type StoreExport struct {
+	Version  int              `json:"version"`
+	Created  time.Time        `json:"created"`
+	Users    []*StoredUser    `json:"users"`
+	Channels []*StoredChannel `json:"channels"`
+	Roles    []Role           `json:"roles"`
+}
+
+// Export writes every user, channel and stored role in the store to w as a
+// versioned JSON document. Roles from the config are left out. The records
+// are all read at the same point in time. Records that can't be read are
+// left out of the document and returned as RecordErrors once it's written.
+func (s *Store) Export(w io.Writer) (nUsers, nChannels int, err error) {
+	var users []*StoredUser
+	var channels []*StoredChannel
+	var failed RecordErrors
+	err = s.db.View(func(tx Tx) error {
+		var err error
+		users, err = s.iterate(tx, func(*StoredUser) bool { return true })
+		if failed, err = collectRecordErrors(failed, err); err != nil {
+			return err
+		}
+		channels, err = channelsTx(tx)
+		failed, err = collectRecordErrors(failed, err)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	export := StoreExport{
+		Version:  StoreExportVersion,
+		Created:  time.Now().UTC(),
+		Users:    users,
+		Channels: channels,
+		Roles:    s.storedRoles(),
+	}
+	if export.Users == nil {
+		export.Users = []*StoredUser{}
+	}
+	if export.Channels == nil {
+		export.Channels = []*StoredChannel{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(export); err != nil {
+		return 0, 0, err
+	}
+
+	if len(failed) > 0 {
+		return len(users), len(channels), failed
+	}
+	return len(users), len(channels), nil
+}
+
+// collectRecordErrors adds the records err couldn't read to failed, any
+// other error is returned. A record that isn't a user or a channel fails
+// both of their scans, so it's only added once.
+func collectRecordErrors(failed RecordErrors, err error) (RecordErrors, error) {
+	errs, ok := err.(RecordErrors)
+	if !ok {
+		return failed, err
+	}
+
+	seen := make(map[string]bool, len(failed))
+	for _, f := range failed {
+		seen[f.Key] = true
+	}
+	for _, e := range errs {
+		if !seen[e.Key] {
+			seen[e.Key] = true
+			failed = append(failed, e)
+		}
+	}
+	return failed, nil
+}
+
+// Import reads a document created by Export and saves all of the users,
+// channels and roles inside it to the store, overwriting any that already
+// exist. The import is all or nothing, if any record fails nothing is saved.
+// Each user, channel and role is audited in the same transaction with entries
+// made from audit, a user's are the access it changed or just audit if none.
+func (s *Store) Import(r io.Reader,
+	audit AuditEntry) (nUsers, nChannels int, err error) {
+
+	var export StoreExport
+	if err = json.NewDecoder(r).Decode(&export); err != nil {
+		return 0, 0, err
+	}
+
+	if export.Version < 1 || export.Version > StoreExportVersion {
+		return 0, 0, fmt.Errorf("data: unsupported export version %d",
+			export.Version)
+	}
+
+	roles := make([]Role, len(export.Roles))
+	for i, role := range export.Roles {
+		if roles[i], err = cleanRole(role); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	var entries []AuditEntry
+	err = s.db.Update(func(tx Tx) error {
+		for _, role := range roles {
+			if r, ok := s.policy.lookupRole(role.Name); ok && r.Config {
+				return errRoleConfig
+			}
+			if err := saveRoleTx(tx, role); err != nil {
+				return err
+			}
+
+			entry := audit
+			entry.Target = role.Name
+			entries = append(entries, entry)
+		}
+
+		for i, user := range export.Users {
+			if user == nil || len(user.Username) == 0 {
+				return fmt.Errorf("data: user %d in export has no username", i)
+			}
+			user.Username = strings.ToLower(user.Username)
+			if user.Access == nil {
+				user.Access = make(map[string]Access)
+			}
+			if user.JSONStorer == nil {
+				user.JSONStorer = make(JSONStorer)
+			}
+
+			before, err := s.userTx(tx, user.Username)
+			if err != nil {
+				return err
+			}
+			if err = saveUserTx(tx, user); err != nil {
+				return err
+			}
+
+			entry := audit
+			entry.Target = user.Username
+			changes := AuditAccessChanges(entry, before, user)
+			if len(changes) == 0 {
+				changes = append(changes, entry)
+			}
+			entries = append(entries, changes...)
+		}
+
+		for i, ch := range export.Channels {
+			if ch == nil || len(ch.Name) == 0 || len(ch.NetID) == 0 {
+				return fmt.Errorf("data: channel %d in export has no name", i)
+			}
+			if ch.JSONStorer == nil {
+				ch.JSONStorer = make(JSONStorer)
+			}
+
+			if err := saveChannelTx(tx, ch); err != nil {
+				return err
+			}
+
+			entry := audit
+			entry.Target = ch.Name
+			entry.Network, entry.Channel = ch.NetID, ch.Name
+			entries = append(entries, entry)
+		}
+
+		return s.auditTx(tx, entries)
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	s.policy.protect.Lock()
+	for _, role := range roles {
+		s.policy.stored[role.Name] = role
+	}
+	s.policy.protect.Unlock()
+
+	// Any of the cached users may have been overwritten.
+	s.cache.reset()
+
+	for _, user := range export.Users {
+		s.attach(user)
+		s.notifyChange(userSaved(user))
+	}
+	for _, ch := range export.Channels {
+		s.notifyChange(channelSaved(ch))
+	}
+	s.audited(entries)
+
+	return len(export.Users), len(export.Channels), nil
+}
@@ -0,0 +1,226 @@
+package data
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStore_ExportImport(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	user, err := NewStoredUser(uname, password, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Grant(network, channel, 100, "ab")
+	user.Put("key", "value")
+	user.GiveRole(network, "", "voice")
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SaveRole(Role{Name: "voice", Access: *NewAccess(5, "v")}); err != nil {
+		t.Fatal(err)
+	}
+	s.SetConfigRoles([]Role{{Name: "op", Access: *NewAccess(100, "o")}})
+
+	ch := NewStoredChannel(network, channel)
+	ch.Put("key", "value")
+	if err = s.SaveChannel(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &bytes.Buffer{}
+	nUsers, nChannels, err := s.Export(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nUsers != 1 || nChannels != 1 {
+		t.Errorf("Expected 1 user and 1 channel, got: %d %d", nUsers, nChannels)
+	}
+
+	s2, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+
+	nUsers, nChannels, err = s2.Import(b, AuditEntry{Source: "cli", Action: "import"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nUsers != 1 || nChannels != 1 {
+		t.Errorf("Expected 1 user and 1 channel, got: %d %d", nUsers, nChannels)
+	}
+
+	entries, err := s2.AuditLog(AuditQuery{Action: "import"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Target != "voice" ||
+		entries[1].Target != uname || entries[1].After != "100 ab" ||
+		entries[2].Channel != channel {
+
+		t.Errorf("Expected the role, the user's access and the channel to be "+
+			"audited: %#v", entries)
+	}
+
+	if roles := s2.Roles(); len(roles) != 1 || roles[0].Name != "voice" {
+		t.Error("Expected only the stored role to be imported, got:", roles)
+	}
+
+	got, err := s2.FindUser(uname)
+	if err != nil || got == nil {
+		t.Fatal("Expected to find the user:", err)
+	}
+	if !got.VerifyPassword(password) {
+		t.Error("Expected the password to survive the import.")
+	}
+	if !got.HasMask(host) {
+		t.Error("Expected the mask to survive the import.")
+	}
+	if !got.HasLevel(network, channel, 100) || !got.HasFlags(network, channel, "ab") {
+		t.Error("Expected the access to survive the import.")
+	}
+	if !got.HasFlags(network, "", "v") {
+		t.Error("Expected the role to survive the import.")
+	}
+	if val, _ := got.Get("key"); val != "value" {
+		t.Error("Expected the user data to survive the import, got:", val)
+	}
+
+	gotCh, err := s2.FindChannel(network, channel)
+	if err != nil || gotCh == nil {
+		t.Fatal("Expected to find the channel:", err)
+	}
+	if val, _ := gotCh.Get("key"); val != "value" {
+		t.Error("Expected the channel data to survive the import, got:", val)
+	}
+}
+
+func TestStore_ExportUnreadable(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	user, err := NewStoredUser(uname, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SaveChannel(NewStoredChannel(network, channel)); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.db.Put([]byte("junk"), []byte("junk")); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &bytes.Buffer{}
+	nUsers, nChannels, err := s.Export(b)
+	errs, ok := err.(RecordErrors)
+	if !ok {
+		t.Fatal("Expected record errors, got:", err)
+	}
+	if keys := errs.Keys(); len(keys) != 1 || keys[0] != "junk" {
+		t.Error("Expected the junk record to be reported once, got:", keys)
+	}
+	if nUsers != 1 || nChannels != 1 {
+		t.Errorf("Expected 1 user and 1 channel, got: %d %d", nUsers, nChannels)
+	}
+
+	s2, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+
+	nUsers, nChannels, err = s2.Import(b, AuditEntry{Source: "cli", Action: "import"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nUsers != 1 || nChannels != 1 {
+		t.Errorf("Expected the readable records to be exported, got: %d %d",
+			nUsers, nChannels)
+	}
+}
+
+func TestStore_ImportOverwrites(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	user, err := NewStoredUser(uname, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	// Warm the cache so the import has to reset it.
+	if _, err = s.FindUser(uname); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := `{"version": 1, "users": [
+		{"username": "` + strings.ToUpper(uname) + `", "masks": ["*!*@new"]}
+	]}`
+	if _, _, err = s.Import(strings.NewReader(doc), AuditEntry{Action: "import"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.FindUser(uname)
+	if err != nil || got == nil {
+		t.Fatal("Expected to find the user:", err)
+	}
+	if !got.HasMask("*!*@new") {
+		t.Error("Expected the imported user to replace the cached one.")
+	}
+}
+
+func TestStore_ImportErrors(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	tests := []string{
+		`not json`,
+		`{"version": 0}`,
+		`{"version": 3}`,
+		`{"version": 2, "roles": [{"name": "two words"}]}`,
+		`{"version": 1, "users": [{"username": "a"}, {"username": ""}]}`,
+		`{"version": 1, "channels": [{"netid": "net", "name": ""}]}`,
+	}
+
+	for _, test := range tests {
+		if _, _, err := s.Import(strings.NewReader(test), AuditEntry{Action: "import"}); err == nil {
+			t.Errorf("%s: Expected an error.", test)
+		}
+	}
+
+	if ok, err := s.HasAny(); err != nil || ok {
+		t.Error("Expected failed imports to save nothing:", err)
+	}
+	if entries, err := s.AuditLog(AuditQuery{}); err != nil || len(entries) != 0 {
+		t.Error("Expected failed imports to audit nothing:", entries, err)
+	}
+}
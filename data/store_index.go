@@ -0,0 +1,286 @@
+package data
+
+import (
+	"strings"
+)
+
+// The store keeps secondary indexes of users so that access queries don't
+// have to decode every user in the database. Each index entry is an empty
+// value under a key made of the index prefix, the indexed value, a zero byte
+// and the username, so that a prefix scan yields the users in username order.
+var (
+	// accessIndexPrefix indexes users by each scope they have access in,
+	// the scope is the same as the keys of StoredUser.Access.
+	accessIndexPrefix = []byte("\x00idx:access:")
+	// maskIndexPrefix indexes users by each of their masks.
+	maskIndexPrefix = []byte("\x00idx:mask:")
+	// accountIndexPrefix indexes users by each of their linked services
+	// accounts, the value is made by accountIndexValue.
+	accountIndexPrefix = []byte("\x00idx:account:")
+	// certIndexPrefix indexes users by each of their certificate
+	// fingerprints.
+	certIndexPrefix = []byte("\x00idx:cert:")
+	// expiryIndexPrefix indexes the users that have access which expires.
+	expiryIndexPrefix = []byte("\x00idx:expiry:")
+)
+
+// accountIndexValue is the value a linked account is indexed under, accounts
+// can't contain spaces.
+func accountIndexValue(network, account string) string {
+	return network + " " + strings.ToLower(account)
+}
+
+// indexKey creates the key of an index entry.
+func indexKey(prefix []byte, value, username string) []byte {
+	key := make([]byte, 0, len(prefix)+len(value)+1+len(username))
+	key = append(key, prefix...)
+	key = append(key, value...)
+	key = append(key, 0)
+	return append(key, username...)
+}
+
+// indexKeys returns the keys of every index entry for a user.
+func indexKeys(user *StoredUser) [][]byte {
+	var keys [][]byte
+	username := strings.ToLower(user.Username)
+
+	for scope, access := range user.Access {
+		if !access.IsZero() {
+			keys = append(keys, indexKey(accessIndexPrefix, scope, username))
+		}
+	}
+	for scope := range user.Roles {
+		if a, ok := user.Access[scope]; !ok || a.IsZero() {
+			keys = append(keys, indexKey(accessIndexPrefix, scope, username))
+		}
+	}
+	for _, mask := range user.Masks {
+		keys = append(keys, indexKey(maskIndexPrefix, mask, username))
+	}
+	for network, linked := range user.Accounts {
+		keys = append(keys, indexKey(accountIndexPrefix,
+			accountIndexValue(network, linked.Account), username))
+	}
+	for _, cert := range user.Certs {
+		keys = append(keys, indexKey(certIndexPrefix, cert, username))
+	}
+	if user.expires() {
+		keys = append(keys, indexKey(expiryIndexPrefix, "", username))
+	}
+
+	return keys
+}
+
+// saveUserTx saves a user and replaces the user's index entries. The user's
+// revision is set to the one after the revision that was saved.
+func saveUserTx(tx Tx, user *StoredUser) error {
+	key := []byte(strings.ToLower(user.Username))
+	revision, err := unindexUser(tx, key)
+	if err != nil {
+		return err
+	}
+
+	user.Revision = revision + 1
+	serialized, err := user.serialize()
+	if err != nil {
+		return err
+	}
+	if err = tx.Put(key, serialized); err != nil {
+		return err
+	}
+
+	for _, k := range indexKeys(user) {
+		if err = tx.Put(k, []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeUserTx removes a user and the user's index entries.
+func removeUserTx(tx Tx, username string) error {
+	key := []byte(strings.ToLower(username))
+	if _, err := unindexUser(tx, key); err != nil {
+		return err
+	}
+	return tx.Delete(key)
+}
+
+// unindexUser deletes the index entries of the user currently saved under key
+// and returns the saved user's revision.
+func unindexUser(tx Tx, key []byte) (uint64, error) {
+	old, err := tx.Get(key)
+	if err != nil || old == nil {
+		return 0, err
+	}
+
+	user, err := deserializeUser(old)
+	if err != nil {
+		// The old entries can't be known, lookups skip any that are stale.
+		return 0, nil
+	}
+
+	for _, k := range indexKeys(user) {
+		if err = tx.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return user.Revision, nil
+}
+
+// indexedUsers returns the users under value in an index.
+func (s *Store) indexedUsers(tx Tx, prefix []byte, value string) ([]*StoredUser, error) {
+	scan := indexKey(prefix, value, "")
+
+	var keys [][]byte
+	err := tx.Scan(scan, func(key, _ []byte) bool {
+		keys = append(keys, copyBytes(key[len(scan):]))
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*StoredUser
+	var failed RecordErrors
+	for _, key := range keys {
+		serialized, err := tx.Get(key)
+		if err != nil {
+			return nil, err
+		} else if serialized == nil {
+			continue
+		}
+
+		user, err := deserializeUser(serialized)
+		if err != nil {
+			failed = append(failed, RecordError{Key: string(key), Err: err})
+			continue
+		}
+		list = append(list, s.attach(user))
+	}
+
+	if len(failed) > 0 {
+		return list, failed
+	}
+	return list, nil
+}
+
+// rebuildIndexes deletes every index entry and indexes each user again.
+// If a report is given each user that is indexed is described in it.
+func rebuildIndexes(tx Tx, report *MigrationReport) error {
+	var stale [][]byte
+	prefixes := [][]byte{
+		accessIndexPrefix, maskIndexPrefix, accountIndexPrefix, certIndexPrefix,
+		expiryIndexPrefix,
+	}
+	for _, prefix := range prefixes {
+		err := tx.Scan(prefix, func(key, _ []byte) bool {
+			stale = append(stale, copyBytes(key))
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, key := range stale {
+		if err := tx.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	var keys [][]byte
+	err := scanRecords(tx, recordUser, func(key, val []byte) error {
+		user, err := deserializeUser(val)
+		if err != nil {
+			return err
+		}
+		for _, k := range indexKeys(user) {
+			keys = append(keys, k)
+		}
+		if report != nil {
+			report.Changef("indexed user [%s]", key)
+		}
+		return nil
+	})
+	if errs, ok := err.(RecordErrors); ok && report != nil {
+		for _, failed := range errs {
+			report.Fail([]byte(failed.Key), failed.Err)
+		}
+	} else if err != nil && !ok {
+		return err
+	}
+
+	for _, key := range keys {
+		if err = tx.Put(key, []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebuildIndexes discards and recreates the indexes the store uses to look
+// up users.
+func (s *Store) RebuildIndexes() error {
+	s.protect.Lock()
+	defer s.protect.Unlock()
+
+	return s.db.Update(func(tx Tx) error {
+		return rebuildIndexes(tx, nil)
+	})
+}
+
+// UserByAccount gets the user a services account on a network is linked to,
+// nil if it isn't linked.
+func (s *Store) UserByAccount(network, account string) (*StoredUser, error) {
+	users, err := s.indexedUsers(s.db, accountIndexPrefix,
+		accountIndexValue(network, account))
+	if err != nil {
+		return nil, err
+	}
+
+	// An entry can be stale if the user it replaced couldn't be decoded.
+	for _, user := range users {
+		if linked, ok := user.Account(network); ok &&
+			linked.Account == strings.ToLower(account) {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+// UserByCert gets the user with a TLS client certificate fingerprint, nil if
+// no user has it.
+func (s *Store) UserByCert(fingerprint string) (*StoredUser, error) {
+	fingerprint, ok := NormalizeCertFP(fingerprint)
+	if !ok {
+		return nil, nil
+	}
+
+	users, err := s.indexedUsers(s.db, certIndexPrefix, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	// An entry can be stale if the user it replaced couldn't be decoded.
+	for _, user := range users {
+		if user.HasCert(fingerprint) {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+// HasCerts checks if any user has a certificate fingerprint, there's no need
+// to learn the fingerprints of hosts otherwise.
+func (s *Store) HasCerts() (has bool, err error) {
+	err = s.db.Scan(certIndexPrefix, func(_, _ []byte) bool {
+		has = true
+		return false
+	})
+	return has, err
+}
+
+// UsersByMask gets the users that have the exact mask.
+func (s *Store) UsersByMask(mask string) ([]*StoredUser, error) {
+	return s.indexedUsers(s.db, maskIndexPrefix, mask)
+}
@@ -0,0 +1,236 @@
+package data
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestStore_Indexes(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	u1 := &StoredUser{Username: "u1", Masks: []string{"*!*@one"},
+		Access: make(map[string]Access)}
+	u2 := &StoredUser{Username: "u2", Masks: []string{"*!*@one", "*!*@two"},
+		Access: make(map[string]Access)}
+	u1.Grant("", "", 100)
+	u1.Grant(network, "", 0, "a")
+	u2.Grant(network, channel, 50)
+	u2.Grant(network, "", 10)
+
+	for _, u := range []*StoredUser{u1, u2} {
+		if err = s.SaveUser(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(name string, users []*StoredUser, err error, exp ...string) {
+		t.Helper()
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %v", name, err)
+		}
+		got := make([]string, len(users))
+		for i, u := range users {
+			got[i] = u.Username
+		}
+		if fmt.Sprint(got) != fmt.Sprint(exp) {
+			t.Errorf("%s: Expected: %v, got: %v", name, exp, got)
+		}
+	}
+
+	users, err := s.GlobalUsers()
+	check("global", users, err, "u1")
+	users, err = s.NetworkUsers(network)
+	check("network", users, err, "u1", "u2")
+	users, err = s.ChanUsers(network, channel)
+	check("channel", users, err, "u2")
+	users, err = s.UsersByMask("*!*@one")
+	check("mask one", users, err, "u1", "u2")
+	users, err = s.UsersByMask("*!*@two")
+	check("mask two", users, err, "u2")
+
+	u2.Revoke(network, "")
+	u2.RemoveMask("*!*@one")
+	if err = s.SaveUser(u2); err != nil {
+		t.Fatal(err)
+	}
+	users, err = s.NetworkUsers(network)
+	check("revoked network", users, err, "u1")
+	users, err = s.UsersByMask("*!*@one")
+	check("removed mask", users, err, "u1")
+
+	if _, err = s.RemoveUser("u1"); err != nil {
+		t.Fatal(err)
+	}
+	users, err = s.GlobalUsers()
+	check("removed global", users, err)
+	users, err = s.UsersByMask("*!*@one")
+	check("removed user mask", users, err)
+
+	var nEntries int
+	err = s.db.Scan(accessIndexPrefix, func(_, _ []byte) bool {
+		nEntries++
+		return true
+	})
+	if err != nil || nEntries != 1 {
+		t.Errorf("Expected 1 access index entry, got: %d %v", nEntries, err)
+	}
+}
+
+func TestStore_RebuildIndexes(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	user := &StoredUser{Username: uname, Access: make(map[string]Access)}
+	user.Grant("", "", 100)
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lose the index entry and leave a stale one behind.
+	err = s.db.Update(func(tx Tx) error {
+		if err := tx.Delete(indexKey(accessIndexPrefix, mkKey("", ""), uname)); err != nil {
+			return err
+		}
+		return tx.Put(indexKey(accessIndexPrefix, mkKey(network, ""), uname), []byte{})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if users, _ := s.GlobalUsers(); len(users) != 0 {
+		t.Error("Expected the lost entry to hide the user, got:", users)
+	}
+	if users, _ := s.NetworkUsers(network); len(users) != 0 {
+		t.Error("Expected the stale entry to be skipped, got:", users)
+	}
+
+	if err = s.RebuildIndexes(); err != nil {
+		t.Fatal(err)
+	}
+	if users, _ := s.GlobalUsers(); len(users) != 1 {
+		t.Error("Expected the user to be found, got:", users)
+	}
+	if val, _ := s.db.Get(indexKey(accessIndexPrefix, mkKey(network, ""), uname)); val != nil {
+		t.Error("Expected the stale entry to be removed.")
+	}
+}
+
+const nBenchUsers = 20000
+
+var (
+	benchStoreOnce sync.Once
+	benchStore     *Store
+)
+
+// benchmarkStore creates a store with nBenchUsers users in it. Every hundreth
+// user has global access, every tenth has network access, and the rest have
+// access to one of a hundred channels.
+func benchmarkStore(b *testing.B) *Store {
+	benchStoreOnce.Do(func() {
+		s, err := NewStore(MemStoreProvider)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for i := 0; i < nBenchUsers; i++ {
+			user := &StoredUser{
+				Username: fmt.Sprintf("user%05d", i),
+				Password: []byte("$2a$10$notarealhashnotarealhashnotarealhashnotarealhashno"),
+				Masks:    []string{fmt.Sprintf("*!*@host%d", i%1000)},
+				Access:   make(map[string]Access),
+			}
+
+			switch {
+			case i%100 == 0:
+				user.Grant("", "", 100)
+			case i%10 == 0:
+				user.Grant(network, "", 50)
+			default:
+				user.Grant(network, fmt.Sprintf("#chan%d", i%100), 10)
+			}
+
+			if err = s.SaveUser(user); err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		benchStore = s
+	})
+
+	return benchStore
+}
+
+func BenchmarkStore_GlobalUsers(b *testing.B) {
+	s := benchmarkStore(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if users, err := s.GlobalUsers(); err != nil || len(users) != nBenchUsers/100 {
+			b.Fatal("wrong users:", len(users), err)
+		}
+	}
+}
+
+func BenchmarkStore_GlobalUsersScan(b *testing.B) {
+	s := benchmarkStore(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		users, err := s.iterate(s.db, func(ua *StoredUser) bool {
+			a, ok := ua.GetAccess("", "")
+			return ok && !a.IsZero()
+		})
+		if err != nil || len(users) != nBenchUsers/100 {
+			b.Fatal("wrong users:", len(users), err)
+		}
+	}
+}
+
+func BenchmarkStore_ChanUsers(b *testing.B) {
+	s := benchmarkStore(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := s.ChanUsers(network, "#chan1"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStore_UsersByMask(b *testing.B) {
+	s := benchmarkStore(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if users, err := s.UsersByMask("*!*@host1"); err != nil || len(users) != nBenchUsers/1000 {
+			b.Fatal("wrong users:", len(users), err)
+		}
+	}
+}
+
+func BenchmarkStore_SaveUser(b *testing.B) {
+	s := benchmarkStore(b)
+	user, err := s.FindUser("user00001")
+	if err != nil || user == nil {
+		b.Fatal("user not found:", err)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := s.SaveUser(user); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package data
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -42,7 +44,7 @@ func TestStore_SaveUser(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if len(s.cache) > 0 {
+	if s.cache.len() > 0 {
 		t.Error("Pre-warmed cache somehow exists.")
 	}
 
@@ -53,7 +55,7 @@ func TestStore_SaveUser(t *testing.T) {
 	if err != nil {
 		t.Fatal("Error adding user:", err)
 	}
-	if s.cache[ua1.Username] == nil {
+	if s.cache.peek(ua1.Username) == nil {
 		t.Error("User was not cached.")
 	}
 
@@ -61,10 +63,10 @@ func TestStore_SaveUser(t *testing.T) {
 	if err != nil {
 		t.Fatal("Error adding user:", err)
 	}
-	if s.cache[ua1.Username] != nil {
+	if s.cache.peek(ua1.Username) != nil {
 		t.Error("User should no longer be cached due to caching limits.")
 	}
-	if s.cache[ua2.Username] == nil {
+	if s.cache.peek(ua2.Username) == nil {
 		t.Error("User was not cached.")
 	}
 
@@ -98,7 +100,7 @@ func TestStore_RemoveUser(t *testing.T) {
 	if err != nil {
 		t.Fatal("Error adding user:", err)
 	}
-	if s.cache[ua1.Username] == nil {
+	if s.cache.peek(ua1.Username) == nil {
 		t.Error("User was not cached.")
 	}
 
@@ -118,7 +120,7 @@ func TestStore_RemoveUser(t *testing.T) {
 	if !removed {
 		t.Error("User was not reported as removed.")
 	}
-	if s.cache[ua1.Username] != nil {
+	if s.cache.peek(ua1.Username) != nil {
 		t.Error("User is still cached.")
 	}
 
@@ -223,7 +225,7 @@ func TestStore_AuthLogout(t *testing.T) {
 		t.Fatal("Error adding user:", err)
 	}
 
-	s.cache = make(map[string]*StoredUser)
+	s.cache.reset()
 
 	user, err := s.AuthUserPerma(network, host, uname, password)
 	if err != nil {
@@ -233,7 +235,7 @@ func TestStore_AuthLogout(t *testing.T) {
 		t.Error("Rejected good authentication.")
 	}
 
-	if len(s.cache) == 0 {
+	if s.cache.len() == 0 {
 		t.Error("Auth is not using cache.")
 	}
 	if s.AuthedUser(network, host) == nil {
@@ -264,7 +266,6 @@ func TestStore_AuthLogout(t *testing.T) {
 func setupUpdateTest(t *testing.T) (s *Store) {
 	var err error
 	s, err = NewStore(MemStoreProvider)
-	defer s.Close()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -284,6 +285,7 @@ func setupUpdateTest(t *testing.T) (s *Store) {
 func TestStore_UpdateSeen(t *testing.T) {
 	t.Parallel()
 	s := setupUpdateTest(t)
+	defer s.Close()
 
 	_, err := s.AuthUserTmp(network, host, uname, password)
 	if err != nil {
@@ -308,6 +310,7 @@ func TestStore_UpdateSeen(t *testing.T) {
 func TestStore_UpdateUnseen(t *testing.T) {
 	t.Parallel()
 	s := setupUpdateTest(t)
+	defer s.Close()
 
 	_, err := s.AuthUserPerma(network, host, uname, password)
 	if err != nil {
@@ -332,6 +335,7 @@ func TestStore_UpdateUnseen(t *testing.T) {
 func TestStore_UpdateNick(t *testing.T) {
 	t.Parallel()
 	s := setupUpdateTest(t)
+	defer s.Close()
 
 	_, err := s.AuthUserPerma(network, host, uname, password)
 	if err != nil {
@@ -362,6 +366,7 @@ func TestStore_UpdateNick(t *testing.T) {
 func TestStore_UpdateQuit(t *testing.T) {
 	t.Parallel()
 	s := setupUpdateTest(t)
+	defer s.Close()
 
 	_, err := s.AuthUserTmp(network, host, uname, password)
 	if err != nil {
@@ -390,6 +395,7 @@ func TestStore_UpdateQuit(t *testing.T) {
 func TestStore_Reap(t *testing.T) {
 	t.Parallel()
 	s := setupUpdateTest(t)
+	defer s.Close()
 
 	_, err := s.AuthUserTmp(network, host, uname, password)
 	if err != nil {
@@ -432,7 +438,7 @@ func TestStore_Finding(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if len(s.cache) > 0 {
+	if s.cache.len() > 0 {
 		t.Error("Pre-warmed cache somehow exists.")
 	}
 
@@ -448,7 +454,7 @@ func TestStore_Finding(t *testing.T) {
 		t.Fatal("Could not add user.")
 	}
 
-	s.cache = make(map[string]*StoredUser)
+	s.cache.reset()
 
 	found, err := s.fetchUser(ua1.Username)
 	if err != nil {
@@ -465,7 +471,7 @@ func TestStore_Finding(t *testing.T) {
 		t.Error("User should have been found.")
 	}
 
-	if len(s.cache) > 0 {
+	if s.cache.len() > 0 {
 		t.Error("Cache should not be warmed by fetchUser.")
 	}
 
@@ -492,7 +498,7 @@ func TestStore_Finding(t *testing.T) {
 		t.Error("User should have been found.")
 	}
 
-	if len(s.cache) != nMaxCache {
+	if s.cache.len() != nMaxCache {
 		t.Error("Cache should be being used.")
 	}
 }
@@ -804,3 +810,226 @@ func TestStore_Channels(t *testing.T) {
 		t.Error("ua2 not found.")
 	}
 }
+
+func TestStore_UpdateUser(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err = s.UpdateUser(uname, func(*StoredUser) error { return nil }); err != ErrNotFound {
+		t.Error("Expected a missing user not to be found, got:", err)
+	}
+
+	user, err := NewStoredUser(uname, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Revision != 1 {
+		t.Error("Expected the first save to be revision 1, got:", user.Revision)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := s.UpdateUser(strings.ToUpper(uname), func(u *StoredUser) error {
+				u.AddMask(fmt.Sprintf("*!*@host%d", i))
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	found, err := s.FindUser(uname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Masks) != 20 || found.Revision != 21 {
+		t.Error("Expected every update to be kept, got:", len(found.Masks),
+			found.Revision)
+	}
+
+	err = s.UpdateUser(uname, func(u *StoredUser) error {
+		u.AddMask("*!*@lost")
+		return ErrUnchanged
+	})
+	if err != nil {
+		t.Error("Expected an unchanged update not to be an error:", err)
+	}
+	errFail := errors.New("fail")
+	err = s.UpdateUser(uname, func(u *StoredUser) error {
+		u.AddMask("*!*@lost")
+		return errFail
+	})
+	if err != errFail {
+		t.Error("Expected the update's error, got:", err)
+	}
+	err = s.UpdateUser(uname, func(u *StoredUser) error {
+		u.Username = "renamed"
+		return nil
+	})
+	if err == nil {
+		t.Error("Expected a rename to fail.")
+	}
+
+	if found, _ = s.FindUser(uname); found.HasMask("*!*@lost") || found.Revision != 21 {
+		t.Error("Expected failed updates not to be saved.")
+	}
+}
+
+func TestStore_CompareAndSwapUser(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	user, err := NewStoredUser(uname, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Revision = 3
+	if err = s.CompareAndSwapUser(user); err != ErrRevisionMismatch {
+		t.Error("Expected a new user to need revision 0, got:", err)
+	}
+	if user.Revision != 3 {
+		t.Error("Expected a failed swap to keep the revision.")
+	}
+	user.Revision = 0
+	if err = s.CompareAndSwapUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := s.FindUser(uname)
+	second, _ := s.FindUser(uname)
+	first.AddMask("*!*@first")
+	second.AddMask("*!*@second")
+	if err = s.CompareAndSwapUser(first); err != nil {
+		t.Error("Expected the first swap to succeed:", err)
+	}
+	if err = s.CompareAndSwapUser(second); err != ErrRevisionMismatch {
+		t.Error("Expected the second swap to fail, got:", err)
+	}
+
+	found, _ := s.FindUser(uname)
+	if !found.HasMask("*!*@first") || found.HasMask("*!*@second") {
+		t.Error("Expected only the first swap to be saved:", found.Masks)
+	}
+}
+
+func TestStore_CreateUser(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	owner := func(u *StoredUser) { u.Grant("", "", 100) }
+
+	var wg sync.WaitGroup
+	var mut sync.Mutex
+	var firsts, created, exists int
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Every other user races for the same name.
+			name := fmt.Sprintf("user%d", i)
+			if i%2 == 0 {
+				name = uname
+			}
+			user, err := NewStoredUser(name, password)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			first, err := s.CreateUser(user, owner)
+			mut.Lock()
+			defer mut.Unlock()
+			switch {
+			case err == ErrRevisionMismatch:
+				exists++
+			case err != nil:
+				t.Error(err)
+			default:
+				created++
+			}
+			if first {
+				firsts++
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if firsts != 1 || created != 6 || exists != 4 {
+		t.Errorf("Expected one first and one of each name: %v %v %v",
+			firsts, created, exists)
+	}
+	if users, err := s.GlobalUsers(); err != nil || len(users) != 1 {
+		t.Error("Expected only the first user to get access:", users, err)
+	}
+}
+
+func TestStore_UpdateChannel(t *testing.T) {
+	t.Parallel()
+	s, err := NewStore(MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := s.UpdateChannel(network, channel, func(ch *StoredChannel) error {
+				ch.Put(fmt.Sprint(i), "x")
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	ch, err := s.FindChannel(network, channel)
+	if err != nil || ch == nil {
+		t.Fatal("Expected the channel to be created:", err)
+	}
+	if len(ch.JSONStorer) != 10 || ch.Revision != 10 {
+		t.Error("Expected every update to be kept, got:", ch.JSONStorer,
+			ch.Revision)
+	}
+
+	stale := ch.Clone()
+	ch.Put("a", "b")
+	if err = s.CompareAndSwapChannel(ch); err != nil || ch.Revision != 11 {
+		t.Error("Expected the swap to succeed:", err, ch.Revision)
+	}
+	if err = s.CompareAndSwapChannel(stale); err != ErrRevisionMismatch {
+		t.Error("Expected a stale swap to fail, got:", err)
+	}
+
+	err = s.UpdateChannel(network, channel, func(ch *StoredChannel) error {
+		ch.Name = "#other"
+		return nil
+	})
+	if err == nil {
+		t.Error("Expected a rename to fail.")
+	}
+}
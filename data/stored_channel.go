@@ -1,8 +1,6 @@
 package data
 
 import (
-	"bytes"
-	"encoding/gob"
 	"fmt"
 	"strings"
 
@@ -14,16 +12,24 @@ type StoredChannel struct {
 	NetID      string `json:"netid"`
 	Name       string `json:"name"`
 	JSONStorer `json:"data"`
+	// Revision counts the times the channel has been saved, see
+	// Store.CompareAndSwapChannel.
+	Revision uint64 `json:"revision,omitempty"`
 }
 
 // NewStoredChannel creates a new stored channel.
 func NewStoredChannel(netID, name string) *StoredChannel {
-	return &StoredChannel{netID, name, make(JSONStorer)}
+	return &StoredChannel{NetID: netID, Name: name, JSONStorer: make(JSONStorer)}
 }
 
 // Clone deep copies this StoredChannel.
 func (s *StoredChannel) Clone() *StoredChannel {
-	return &StoredChannel{s.NetID, s.Name, s.JSONStorer.Clone()}
+	return &StoredChannel{
+		NetID:      s.NetID,
+		Name:       s.Name,
+		JSONStorer: s.JSONStorer.Clone(),
+		Revision:   s.Revision,
+	}
 }
 
 // makeID is used to create a key to store this instance by.
@@ -33,26 +39,13 @@ func (s *StoredChannel) makeID() string {
 
 // serialize turns the StoredChannel into bytes for storage.
 func (s *StoredChannel) serialize() ([]byte, error) {
-	buffer := &bytes.Buffer{}
-	encoder := gob.NewEncoder(buffer)
-	err := encoder.Encode(s)
-	if err != nil {
-		return nil, err
-	}
-
-	return buffer.Bytes(), nil
+	return encodeRecord(recordChannel, s)
 }
 
 // deserializeChannel reverses the Serialize process.
 func deserializeChannel(serialized []byte) (*StoredChannel, error) {
-	buffer := &bytes.Buffer{}
-	decoder := gob.NewDecoder(buffer)
-	if _, err := buffer.Write(serialized); err != nil {
-		return nil, err
-	}
-
 	dec := &StoredChannel{}
-	err := decoder.Decode(dec)
+	err := decodeRecord(recordChannel, serialized, dec)
 	return dec, err
 }
 
@@ -61,25 +54,30 @@ func (s *StoredChannel) ToProto() *api.StoredChannel {
 
 	proto.Net = s.NetID
 	proto.Name = s.Name
+	proto.Revision = s.Revision
 
-	if len(s.JSONStorer) != 0 {
-		proto.Data = make(map[string]string, len(s.JSONStorer))
-		for k, v := range s.JSONStorer {
-			proto.Data[k] = v
-		}
-	}
+	proto.Data = dataToProto(s.JSONStorer)
 
 	return &proto
 }
 
+// ToProtoSecrets is ToProto with the channel's secrets decrypted by the
+// store's keys, it must only be used when they were explicitly asked for.
+func (s *StoredChannel) ToProtoSecrets(store *Store) (*api.StoredChannel, error) {
+	secrets, err := store.Secrets(s.JSONStorer)
+	if err != nil {
+		return nil, err
+	}
+
+	proto := s.ToProto()
+	proto.Secrets = secrets
+	return proto, nil
+}
+
 func (s *StoredChannel) FromProto(proto *api.StoredChannel) {
 	s.NetID = proto.Net
 	s.Name = proto.Name
+	s.Revision = proto.Revision
 
-	if len(proto.Data) != 0 {
-		s.JSONStorer = make(JSONStorer, len(proto.Data))
-		for k, v := range proto.Data {
-			s.JSONStorer[k] = v
-		}
-	}
+	s.JSONStorer = dataFromProto(proto.Data)
 }
@@ -2,7 +2,8 @@ package data
 
 import (
 	"bytes"
-	"encoding/gob"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -26,6 +27,10 @@ var (
 	// errDuplicateMask is given when a duplicate mask is passed into the
 	// NewStoredUser method.
 	errDuplicateMask = errors.New("data: Duplicate mask in user creation")
+	// ErrMixedExpiry is given when access that expires would be given where
+	// there's permanent access, or permanent access where there's access that
+	// expires. The access in one place all expires together.
+	ErrMixedExpiry = errors.New("data: Expiring and permanent access can't be mixed")
 )
 
 const (
@@ -42,21 +47,51 @@ const (
 // This information is protected by a username and crypted password combo.
 // Most of StoredUser's access-related methods require a network and a channel,
 // but passing in blank strings to these methods allow us to set global,
-// channel, and/or network specific access levels.
+// channel, and/or network specific access levels. Roles are kept by the same
+// keys as Access and add the access of each named role to that scope, as are
+// the namespaced permissions extensions define beyond the single letter flags.
 type StoredUser struct {
-	Username   string            `json:"username"`
-	Password   []byte            `json:"password"`
-	Masks      []string          `json:"masks"`
-	Access     map[string]Access `json:"access"`
-	JSONStorer `json:"data"`
+	Username    string                   `json:"username"`
+	Password    []byte                   `json:"password"`
+	Masks       []string                 `json:"masks"`
+	Access      map[string]Access        `json:"access"`
+	Accounts    map[string]LinkedAccount `json:"accounts,omitempty"`
+	Certs       []string                 `json:"certs,omitempty"`
+	Roles       map[string][]string      `json:"roles,omitempty"`
+	Permissions map[string][]string      `json:"permissions,omitempty"`
+	JSONStorer  `json:"data"`
+	// Revision counts the times the user has been saved, see
+	// Store.CompareAndSwapUser.
+	Revision uint64 `json:"revision,omitempty"`
+
+	// policy is the one of the store the user was read from.
+	policy *policy
 }
 
-// StoredUserPwdCost is the cost factor for bcrypt. It should not be set
-// unless the reasoning is good and the consequences are known.
+// LinkedAccount is a services account that authenticates a StoredUser on a
+// network without a password, StoredUser.Accounts holds them by network.
+type LinkedAccount struct {
+	Account string `json:"account"`
+	// RequireMask only authenticates hosts that also match one of the user's
+	// masks.
+	RequireMask bool `json:"require_mask"`
+}
+
+// StoredUserPwdCost is the cost factor for a BcryptHasher that doesn't set
+// one. It should not be set unless the reasoning is good and the consequences
+// are known.
 var StoredUserPwdCost = bcrypt.DefaultCost
 
-// NewStoredUser requires username and password but masks are optional.
+// NewStoredUser requires username and password but masks are optional. The
+// password is hashed with bcrypt, Store.NewStoredUser uses the store's
+// hasher.
 func NewStoredUser(un, pw string, masks ...string) (*StoredUser, error) {
+	return newStoredUser(nil, un, pw, masks)
+}
+
+func newStoredUser(p *policy, un, pw string, masks []string) (
+	*StoredUser, error) {
+
 	if len(un) == 0 || len(pw) == 0 {
 		return nil, errMissingUnameOrPwd
 	}
@@ -65,6 +100,7 @@ func NewStoredUser(un, pw string, masks ...string) (*StoredUser, error) {
 		Username:   strings.ToLower(un),
 		JSONStorer: make(JSONStorer),
 		Access:     make(map[string]Access),
+		policy:     p,
 	}
 
 	err := s.SetPassword(pw)
@@ -88,15 +124,42 @@ func (s *StoredUser) Clone() *StoredUser {
 		Masks:      make([]string, len(s.Masks)),
 		JSONStorer: s.JSONStorer.Clone(),
 		Access:     make(map[string]Access, len(s.Access)),
+		Revision:   s.Revision,
+		policy:     s.policy,
 	}
 
 	copy(newStoredUser.Password, s.Password)
 	copy(newStoredUser.Masks, s.Masks)
+	if s.Certs != nil {
+		newStoredUser.Certs = make([]string, len(s.Certs))
+		copy(newStoredUser.Certs, s.Certs)
+	}
 
 	for k, v := range s.Access {
 		newStoredUser.Access[k] = v
 	}
 
+	if s.Accounts != nil {
+		newStoredUser.Accounts = make(map[string]LinkedAccount, len(s.Accounts))
+		for k, v := range s.Accounts {
+			newStoredUser.Accounts[k] = v
+		}
+	}
+
+	if s.Roles != nil {
+		newStoredUser.Roles = make(map[string][]string, len(s.Roles))
+		for k, v := range s.Roles {
+			newStoredUser.Roles[k] = append([]string(nil), v...)
+		}
+	}
+
+	if s.Permissions != nil {
+		newStoredUser.Permissions = make(map[string][]string, len(s.Permissions))
+		for k, v := range s.Permissions {
+			newStoredUser.Permissions[k] = append([]string(nil), v...)
+		}
+	}
+
 	return newStoredUser
 }
 
@@ -116,10 +179,11 @@ func createStoredUser(masks ...string) *StoredUser {
 	return s
 }
 
-// SetPassword encrypts the password string, and sets the Password property.
+// SetPassword hashes the password string with the password hasher of the
+// store the user is from, and sets the Password property.
 func (s *StoredUser) SetPassword(password string) (err error) {
 	var pwd []byte
-	pwd, err = bcrypt.GenerateFromPassword([]byte(password), StoredUserPwdCost)
+	pwd, err = s.policy.passwordHasher().Hash([]byte(password))
 	if err != nil {
 		return
 	}
@@ -146,33 +210,42 @@ func (s *StoredUser) ResetPassword() (newpasswd string, err error) {
 }
 
 // VerifyPassword checks to see if the given password matches the stored
-// password.
+// password. A matching password that wasn't hashed by the password hasher of
+// the store the user is from is rehashed, the user must be saved to keep the
+// new hash.
 func (s *StoredUser) VerifyPassword(password string) bool {
-	return nil == bcrypt.CompareHashAndPassword(s.Password, []byte(password))
+	ok, _ := s.verifyPassword(password)
+	return ok
 }
 
-// serialize turns the useraccess into bytes for storage.
-func (s *StoredUser) serialize() ([]byte, error) {
-	buffer := &bytes.Buffer{}
-	encoder := gob.NewEncoder(buffer)
-	err := encoder.Encode(s)
-	if err != nil {
-		return nil, err
+// verifyPassword is VerifyPassword but also reports if the password was
+// rehashed.
+func (s *StoredUser) verifyPassword(password string) (ok, rehashed bool) {
+	current := s.policy.passwordHasher()
+	ok, rehash, err := verifyPassword(current, s.Password, password)
+	if !ok || err != nil {
+		return false, false
+	}
+
+	if rehash {
+		// A failed rehash keeps the old hash, it still works.
+		if pwd, err := current.Hash([]byte(password)); err == nil {
+			s.Password = pwd
+			rehashed = true
+		}
 	}
+	return true, rehashed
+}
 
-	return buffer.Bytes(), nil
+// serialize turns the useraccess into bytes for storage.
+func (s *StoredUser) serialize() ([]byte, error) {
+	return encodeRecord(recordUser, s)
 }
 
 // deserializeUser reverses the Serialize process.
 func deserializeUser(serialized []byte) (*StoredUser, error) {
-	buffer := &bytes.Buffer{}
-	decoder := gob.NewDecoder(buffer)
-	if _, err := buffer.Write(serialized); err != nil {
-		return nil, err
-	}
-
 	dec := &StoredUser{}
-	err := decoder.Decode(dec)
+	err := decodeRecord(recordUser, serialized, dec)
 	return dec, err
 }
 
@@ -205,114 +278,143 @@ func (s *StoredUser) RemoveMask(mask string) (deleted bool) {
 	return
 }
 
-// HasMask checks to see if this user has the given masks.
-func (s *StoredUser) HasMask(mask string) (has bool) {
-	if len(s.Masks) == 0 {
-		return true
+// NormalizeCertFP turns a SHA-256 certificate fingerprint into the lowercase
+// hex form users store, colons are allowed. Returns false if it's not a
+// SHA-256 fingerprint.
+func NormalizeCertFP(fingerprint string) (string, bool) {
+	fingerprint = strings.ToLower(strings.Replace(fingerprint, ":", "", -1))
+	if len(fingerprint) != sha256.Size*2 {
+		return "", false
 	}
-	mask = strings.ToLower(mask)
-	for _, ourMask := range s.Masks {
-		if irc.Host(mask).Match(irc.Mask(ourMask)) {
-			has = true
-			break
-		}
+	if _, err := hex.DecodeString(fingerprint); err != nil {
+		return "", false
 	}
-	return
+	return fingerprint, true
 }
 
-// Has checks if a user has the given level and flags. Where his access is
-// prioritized thusly: Global > Network > Channel
-func (s *StoredUser) Has(network, channel string,
-	level uint8, flags ...string) bool {
-
-	var searchBits = getFlagBits(flags...)
-	var hasFlags, hasLevel bool
-
-	var check = func(access Access) bool {
-		hasLevel = hasLevel || access.HasLevel(level)
-		hasFlags = hasFlags || ((searchBits & access.Flags) != 0)
-		return hasLevel && hasFlags
+// AddCert adds a TLS client certificate fingerprint to this user. If it's
+// invalid or a duplicate it returns false.
+func (s *StoredUser) AddCert(fingerprint string) bool {
+	fingerprint, ok := NormalizeCertFP(fingerprint)
+	if !ok || s.HasCert(fingerprint) {
+		return false
 	}
+	s.Certs = append(s.Certs, fingerprint)
+	return true
+}
 
-	if a, ok := s.Access[mkKey("", "")]; ok && check(a) {
-		return true
-	}
-	if len(network) > 0 {
-		if a, ok := s.Access[mkKey(network, "")]; ok && check(a) {
+// RemoveCert deletes a certificate fingerprint from this user. Returns true
+// if the fingerprint was found and deleted.
+func (s *StoredUser) RemoveCert(fingerprint string) bool {
+	fingerprint, _ = NormalizeCertFP(fingerprint)
+	for i, cert := range s.Certs {
+		if cert == fingerprint {
+			s.Certs = append(s.Certs[:i], s.Certs[i+1:]...)
 			return true
 		}
 	}
-	if len(channel) > 0 {
-		if a, ok := s.Access[mkKey("", channel)]; ok && check(a) {
-			return true
-		}
+	return false
+}
+
+// HasCert checks if this user has the certificate fingerprint.
+func (s *StoredUser) HasCert(fingerprint string) bool {
+	fingerprint, ok := NormalizeCertFP(fingerprint)
+	if !ok {
+		return false
 	}
-	if len(network) > 0 && len(channel) > 0 {
-		if a, ok := s.Access[mkKey(network, channel)]; ok && check(a) {
+	for _, cert := range s.Certs {
+		if cert == fingerprint {
 			return true
 		}
 	}
-
 	return false
 }
 
-// HasLevel checks if a user has a given level of access. Where his access is
-// prioritized thusly: Global > Network > Channel
-func (s *StoredUser) HasLevel(network, channel string, level uint8) bool {
-	if a, ok := s.Access[mkKey("", "")]; ok && a.Level >= level {
-		return true
+// LinkAccount links a services account on a network to this user, replacing
+// any account already linked there.
+func (s *StoredUser) LinkAccount(network, account string, requireMask bool) {
+	if s.Accounts == nil {
+		s.Accounts = make(map[string]LinkedAccount)
 	}
-	if len(network) > 0 {
-		if a, ok := s.Access[mkKey(network, "")]; ok && a.Level >= level {
-			return true
-		}
-	}
-	if len(channel) > 0 {
-		if a, ok := s.Access[mkKey("", channel)]; ok && a.Level >= level {
-			return true
-		}
+	s.Accounts[network] = LinkedAccount{
+		Account:     strings.ToLower(account),
+		RequireMask: requireMask,
 	}
-	if len(network) > 0 && len(channel) > 0 {
-		if a, ok := s.Access[mkKey(network, channel)]; ok && a.Level >= level {
-			return true
-		}
+}
+
+// UnlinkAccount removes the services account linked on a network. Returns
+// true if there was one.
+func (s *StoredUser) UnlinkAccount(network string) bool {
+	if _, ok := s.Accounts[network]; !ok {
+		return false
 	}
-	return false
+	delete(s.Accounts, network)
+	return true
 }
 
-// HasFlags checks if a user has a given level of access. Where his access is
-// prioritized thusly: Global > Network > Channel
-func (s *StoredUser) HasFlags(network, channel string, flags ...string) bool {
-	var searchBits = getFlagBits(flags...)
-	var haveBits uint64
+// Account gets the services account linked on a network.
+func (s *StoredUser) Account(network string) (LinkedAccount, bool) {
+	linked, ok := s.Accounts[network]
+	return linked, ok
+}
 
-	var check = func(access Access) (had bool) {
-		haveBits |= access.Flags
-		return (searchBits & haveBits) == searchBits
+// AuthenticatesAccount checks if a host identified to a services account on
+// a network is this user.
+func (s *StoredUser) AuthenticatesAccount(network, host, account string) bool {
+	linked, ok := s.Accounts[network]
+	if !ok || len(account) == 0 || linked.Account != strings.ToLower(account) {
+		return false
 	}
+	return !linked.RequireMask || s.HasMask(host)
+}
 
-	if a, ok := s.Access[mkKey("", "")]; ok && check(a) {
+// HasMask checks to see if this user has the given masks.
+func (s *StoredUser) HasMask(mask string) (has bool) {
+	if len(s.Masks) == 0 {
 		return true
 	}
-	if len(network) > 0 {
-		if a, ok := s.Access[mkKey(network, "")]; ok && check(a) {
-			return true
+	mask = strings.ToLower(mask)
+	for _, ourMask := range s.Masks {
+		if irc.Host(mask).Match(irc.Mask(ourMask)) {
+			has = true
+			break
 		}
 	}
-	if len(channel) > 0 {
-		if a, ok := s.Access[mkKey("", channel)]; ok && check(a) {
-			return true
-		}
+	return
+}
+
+// Has checks if a user has the given level and any of the flags. The most
+// specific scope with a rule decides: network and channel, channel,
+// network, then global. A deny overrides an allow in the same scope.
+func (s *StoredUser) Has(network, channel string,
+	level uint8, flags ...string) bool {
+
+	if !s.ResolveLevel(network, channel, level).Allowed {
+		return false
 	}
-	if len(network) > 0 && len(channel) > 0 {
-		if a, ok := s.Access[mkKey(network, channel)]; ok && check(a) {
-			return true
+	for _, flagset := range flags {
+		for _, flag := range flagset {
+			if s.ResolveFlag(network, channel, flag).Allowed {
+				return true
+			}
 		}
 	}
-
 	return false
 }
 
+// HasLevel checks if a user has a given level of access, it's resolved like
+// Has.
+func (s *StoredUser) HasLevel(network, channel string, level uint8) bool {
+	return s.ResolveLevel(network, channel, level).Allowed
+}
+
+// HasFlags checks if a user has all of the given flags, they're resolved
+// like Has.
+func (s *StoredUser) HasFlags(network, channel string, flags ...string) bool {
+	d, _ := s.ResolveFlags(network, channel, flags...)
+	return d.Allowed
+}
+
 // HasFlag checks if a user has a given flag. Where his access is
 // prioritized thusly: Global > Network > Channel
 // func (s *StoredUser) HasFlag(network, channel string, flag rune) bool {
@@ -339,12 +441,180 @@ func (s *StoredUser) HasFlags(network, channel string, flags ...string) bool {
 
 // GetAccess returns access using the network and channel provided. The bool
 // returns false if the user has no explicit permissions for the level
-// requested.
+// requested, or if they have expired. The denies of expired access are kept
+// like RevokeExpired does.
 func (s *StoredUser) GetAccess(network, channel string) (Access, bool) {
 	a, ok := s.Access[mkKey(network, channel)]
+	if ok && a.Expired(time.Now()) {
+		a = a.denyOnly()
+		return a, a.HasDeny()
+	}
 	return a, ok
 }
 
+// EffectiveAccess returns the access the user has in exactly the network and
+// channel provided, both granted directly and given by their roles there. The
+// bool returns false if the user has neither.
+func (s *StoredUser) EffectiveAccess(network, channel string) (Access, bool) {
+	access, ok := s.GetAccess(network, channel)
+	if roles, has := s.RoleAccess(network, channel); has {
+		if access.Level < roles.Level {
+			access.Level = roles.Level
+		}
+		access.Flags |= roles.Flags
+		access.Expires = 0
+		ok = true
+	}
+	return access, ok
+}
+
+// RoleAccess returns the access given to the user by their roles in the
+// network and channel provided. Roles that don't exist give nothing.
+func (s *StoredUser) RoleAccess(network, channel string) (Access, bool) {
+	var access Access
+	var ok bool
+	for _, name := range s.Roles[mkKey(network, channel)] {
+		role, exists := s.policy.lookupRole(name)
+		if !exists {
+			continue
+		}
+		if access.Level < role.Access.Level {
+			access.Level = role.Access.Level
+		}
+		access.Flags |= role.Access.Flags
+		ok = true
+	}
+	return access, ok
+}
+
+// GetRoles returns the names of the roles the user has in the network and
+// channel provided.
+func (s *StoredUser) GetRoles(network, channel string) []string {
+	return s.Roles[mkKey(network, channel)]
+}
+
+// GiveRole gives the user a role in the network and channel provided. Returns
+// false if they already had it.
+func (s *StoredUser) GiveRole(network, channel, role string) bool {
+	key := mkKey(network, channel)
+	role = strings.ToLower(role)
+	for _, r := range s.Roles[key] {
+		if r == role {
+			return false
+		}
+	}
+
+	if s.Roles == nil {
+		s.Roles = make(map[string][]string)
+	}
+	s.Roles[key] = append(s.Roles[key], role)
+	sort.Strings(s.Roles[key])
+	return true
+}
+
+// TakeRole takes a role from the user in the network and channel provided.
+// Returns false if they didn't have it.
+func (s *StoredUser) TakeRole(network, channel, role string) bool {
+	key := mkKey(network, channel)
+	role = strings.ToLower(role)
+	roles := s.Roles[key]
+	for i, r := range roles {
+		if r != role {
+			continue
+		}
+
+		roles = append(roles[:i:i], roles[i+1:]...)
+		if len(roles) == 0 {
+			delete(s.Roles, key)
+		} else {
+			s.Roles[key] = roles
+		}
+		return true
+	}
+	return false
+}
+
+// SetExpiry sets when the access for the network and channel is revoked, a
+// zero time makes it permanent. All of the access there expires together,
+// use GrantUntil to give access that expires without changing other access.
+// Returns false if there's no access to expire.
+func (s *StoredUser) SetExpiry(network, channel string, expires time.Time) bool {
+	key := mkKey(network, channel)
+	access, ok := s.Access[key]
+	if !ok {
+		return false
+	}
+
+	access.Expires = 0
+	if !expires.IsZero() {
+		access.Expires = expires.Unix()
+	}
+	s.Access[key] = access
+	return true
+}
+
+// GrantUntil is Grant for access that's revoked at expires, a zero time
+// makes it permanent. The access in one place all expires together, so
+// access that expires can't be given where there's permanent access or the
+// reverse, ErrMixedExpiry is returned and nothing is given. Giving access
+// that expires where there's already access that expires moves the expiry.
+func (s *StoredUser) GrantUntil(network, channel string, expires time.Time,
+	level uint8, flags ...string) error {
+
+	current, ok := s.GetAccess(network, channel)
+	had := ok && !current.IsZero()
+	if had && (current.Expires != 0) == expires.IsZero() {
+		return ErrMixedExpiry
+	}
+	if !had && level == 0 && getFlagBits(flags...) == 0 {
+		return nil
+	}
+
+	s.Grant(network, channel, level, flags...)
+	key := mkKey(network, channel)
+	access := s.Access[key]
+	access.Expires = 0
+	if !expires.IsZero() {
+		access.Expires = expires.Unix()
+	}
+	s.Access[key] = access
+	return nil
+}
+
+// RevokeExpired removes the access that has expired by now.
+func (s *StoredUser) RevokeExpired(now time.Time) []ExpiredAccess {
+	var expired []ExpiredAccess
+	for key, access := range s.Access {
+		if !access.Expired(now) {
+			continue
+		}
+
+		scope := strings.SplitN(key, ":", 2)
+		expired = append(expired, ExpiredAccess{
+			Username: s.Username,
+			Network:  scope[0],
+			Channel:  scope[1],
+			Access:   access,
+		})
+		if denied := access.denyOnly(); denied.HasDeny() {
+			s.Access[key] = denied
+		} else {
+			delete(s.Access, key)
+		}
+	}
+	return expired
+}
+
+// expires checks if any of the user's access has an expiry.
+func (s *StoredUser) expires() bool {
+	for _, access := range s.Access {
+		if access.Expires != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // Grant sets level and flags for a user. To set more specific access
 // provide network and channel names.
 // A level of 0 will not set anything. Use revoke to remove the level.
@@ -354,6 +624,9 @@ func (s *StoredUser) Grant(
 
 	key := mkKey(network, channel)
 	access := s.Access[key]
+	if access.Expired(time.Now()) {
+		access = access.denyOnly()
+	}
 	changed := false
 	if len(flags) > 0 {
 		access.SetFlags(flags...)
@@ -368,6 +641,41 @@ func (s *StoredUser) Grant(
 	}
 }
 
+// Deny denies a level and above, and flags for a user. To deny more specific
+// access provide network and channel names. A level of 0 will not set
+// anything.
+func (s *StoredUser) Deny(
+	network, channel string, level uint8, flags ...string) {
+
+	if level == 0 && getFlagBits(flags...) == 0 {
+		return
+	}
+
+	key := mkKey(network, channel)
+	access := s.Access[key]
+	access.SetDeny(level, flags...)
+	s.Access[key] = access
+}
+
+// RevokeDeny stops denying flags for a user. Leaving flags empty stops
+// denying anything.
+func (s *StoredUser) RevokeDeny(network, channel string, flags ...string) {
+	key := mkKey(network, channel)
+	if access, ok := s.Access[key]; ok && access.HasDeny() {
+		access.ClearDeny(flags...)
+		s.Access[key] = access
+	}
+}
+
+// RevokeDenyLevel stops denying a level for a user.
+func (s *StoredUser) RevokeDenyLevel(network, channel string) {
+	key := mkKey(network, channel)
+	if access, ok := s.Access[key]; ok && access.DenyLevel != 0 {
+		access.DenyLevel = 0
+		s.Access[key] = access
+	}
+}
+
 // Revoke removes a user's access. To revoke more specific access
 // provide network and channel names.
 func (s *StoredUser) Revoke(network, channel string) {
@@ -462,12 +770,47 @@ func (s *StoredUser) String(network, channel string) string {
 	return b.String()
 }
 
+// RoleString turns StoredUser's roles and the access each gives into a user
+// consumable format. It's empty when the user has no roles.
+func (s *StoredUser) RoleString(network, channel string) string {
+	var keys []string
+	for k := range s.Roles {
+		if len(network) != 0 && !strings.HasPrefix(k, network) {
+			continue
+		}
+		if len(channel) != 0 && !strings.HasSuffix(k, channel) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+
+	var b = &bytes.Buffer{}
+	for _, k := range keys {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		roles := make([]string, len(s.Roles[k]))
+		for i, name := range s.Roles[k] {
+			roles[i] = name
+			if role, ok := s.policy.lookupRole(name); ok {
+				roles[i] += ":" + role.Access.String()
+			}
+		}
+		fmt.Fprintf(b, "%s(%s)", scopeName(k), strings.Join(roles, ", "))
+	}
+	return b.String()
+}
+
 func (s *StoredUser) writeIt(b *bytes.Buffer, key string) {
 	spl := strings.Split(key, ":")
 	network, channel := spl[0], spl[1]
 
 	access, ok := s.Access[key]
-	if !ok || (access.Level == 0 && access.Flags == 0) {
+	if !ok || (access.IsZero() && !access.HasDeny()) {
 		return
 	}
 
@@ -497,10 +840,15 @@ func (s *StoredUser) ToProto() *api.StoredUser {
 	var proto api.StoredUser
 
 	proto.Username = s.Username
+	proto.Revision = s.Revision
 	proto.Password = make([]byte, len(s.Password))
 	copy(proto.Password, s.Password)
 	proto.Masks = make([]string, len(s.Masks))
 	copy(proto.Masks, s.Masks)
+	if len(s.Certs) != 0 {
+		proto.Certs = make([]string, len(s.Certs))
+		copy(proto.Certs, s.Certs)
+	}
 
 	if len(s.Access) != 0 {
 		proto.Access = make(map[string]*api.Access, len(s.Access))
@@ -509,22 +857,61 @@ func (s *StoredUser) ToProto() *api.StoredUser {
 		}
 	}
 
-	if len(s.JSONStorer) != 0 {
-		proto.Data = make(map[string]string, len(s.JSONStorer))
-		for k, v := range s.JSONStorer {
-			proto.Data[k] = v
+	if len(s.Accounts) != 0 {
+		proto.Accounts = make(map[string]*api.LinkedAccount, len(s.Accounts))
+		for k, v := range s.Accounts {
+			proto.Accounts[k] = &api.LinkedAccount{
+				Account:     v.Account,
+				RequireMask: v.RequireMask,
+			}
+		}
+	}
+
+	if len(s.Roles) != 0 {
+		proto.Roles = make(map[string]*api.RoleList, len(s.Roles))
+		for k, v := range s.Roles {
+			proto.Roles[k] = &api.RoleList{Roles: append([]string(nil), v...)}
 		}
 	}
 
+	if len(s.Permissions) != 0 {
+		proto.Permissions = make(map[string]*api.PermissionList, len(s.Permissions))
+		for k, v := range s.Permissions {
+			proto.Permissions[k] = &api.PermissionList{
+				Permissions: append([]string(nil), v...),
+			}
+		}
+	}
+
+	proto.Data = dataToProto(s.JSONStorer)
+
 	return &proto
 }
 
+// ToProtoSecrets is ToProto with the user's secrets decrypted by the store's
+// keys, it must only be used when they were explicitly asked for.
+func (s *StoredUser) ToProtoSecrets(store *Store) (*api.StoredUser, error) {
+	secrets, err := store.Secrets(s.JSONStorer)
+	if err != nil {
+		return nil, err
+	}
+
+	proto := s.ToProto()
+	proto.Secrets = secrets
+	return proto, nil
+}
+
 func (s *StoredUser) FromProto(proto *api.StoredUser) {
 	s.Username = proto.Username
+	s.Revision = proto.Revision
 	s.Password = make([]byte, len(proto.Password))
 	copy(s.Password, proto.Password)
 	s.Masks = make([]string, len(proto.Masks))
 	copy(s.Masks, proto.Masks)
+	if len(proto.Certs) != 0 {
+		s.Certs = make([]string, len(proto.Certs))
+		copy(s.Certs, proto.Certs)
+	}
 
 	if len(proto.Access) != 0 {
 		s.Access = make(map[string]Access, len(proto.Access))
@@ -535,10 +922,29 @@ func (s *StoredUser) FromProto(proto *api.StoredUser) {
 		}
 	}
 
-	if len(proto.Data) != 0 {
-		s.JSONStorer = make(JSONStorer, len(proto.Data))
-		for k, v := range proto.Data {
-			s.JSONStorer[k] = v
+	if len(proto.Accounts) != 0 {
+		s.Accounts = make(map[string]LinkedAccount, len(proto.Accounts))
+		for k, v := range proto.Accounts {
+			s.Accounts[k] = LinkedAccount{
+				Account:     v.Account,
+				RequireMask: v.RequireMask,
+			}
+		}
+	}
+
+	if len(proto.Roles) != 0 {
+		s.Roles = make(map[string][]string, len(proto.Roles))
+		for k, v := range proto.Roles {
+			s.Roles[k] = append([]string(nil), v.Roles...)
 		}
 	}
+
+	if len(proto.Permissions) != 0 {
+		s.Permissions = make(map[string][]string, len(proto.Permissions))
+		for k, v := range proto.Permissions {
+			s.Permissions[k] = append([]string(nil), v.Permissions...)
+		}
+	}
+
+	s.JSONStorer = dataFromProto(proto.Data)
 }
@@ -160,6 +160,85 @@ func TestStoredUser_HasMasks(t *testing.T) {
 	}
 }
 
+func TestStoredUser_LinkAccount(t *testing.T) {
+	t.Parallel()
+	s := createStoredUser(`*!*@host`)
+
+	if s.AuthenticatesAccount(network, host, "acct") {
+		t.Error("Should not authenticate an unlinked account.")
+	}
+
+	s.LinkAccount(network, "Acct", false)
+	if !s.AuthenticatesAccount(network, "a!b@elsewhere", "ACCT") {
+		t.Error("Should authenticate the linked account from any host.")
+	}
+	if s.AuthenticatesAccount("othernet", host, "acct") {
+		t.Error("Should not authenticate on another network.")
+	}
+	if s.AuthenticatesAccount(network, host, "") {
+		t.Error("Should not authenticate without an account.")
+	}
+
+	c := s.Clone()
+	c.LinkAccount(network, "acct", true)
+	if linked, _ := s.Account(network); linked.RequireMask {
+		t.Error("Clone should not share accounts.")
+	}
+	if c.AuthenticatesAccount(network, "a!b@elsewhere", "acct") {
+		t.Error("Should require the host to match a mask.")
+	}
+	if !c.AuthenticatesAccount(network, "a!b@host", "acct") {
+		t.Error("Should authenticate a host matching a mask.")
+	}
+
+	if !s.UnlinkAccount(network) || s.UnlinkAccount(network) {
+		t.Error("Should only unlink an account once.")
+	}
+	if _, ok := s.Account(network); ok {
+		t.Error("Should have unlinked the account.")
+	}
+}
+
+func TestStoredUser_Certs(t *testing.T) {
+	t.Parallel()
+	s := createStoredUser()
+
+	fp := strings.Repeat("AB:", 31) + "AB"
+	normal := strings.Repeat("ab", 32)
+
+	if got, ok := NormalizeCertFP(fp); !ok || got != normal {
+		t.Error("Expected the fingerprint to be normalized, got:", got)
+	}
+	for _, bad := range []string{"", "abcd", strings.Repeat("zz", 32)} {
+		if _, ok := NormalizeCertFP(bad); ok {
+			t.Errorf("Expected %q to be invalid.", bad)
+		}
+	}
+
+	if s.AddCert("abcd") {
+		t.Error("Should not add an invalid fingerprint.")
+	}
+	if !s.AddCert(fp) || s.AddCert(normal) {
+		t.Error("Should add a fingerprint once.")
+	}
+	if !s.HasCert(normal) || !s.HasCert(fp) {
+		t.Error("Should have the fingerprint.")
+	}
+
+	c := s.Clone()
+	c.RemoveCert(fp)
+	if !s.HasCert(fp) {
+		t.Error("Clone should not share certs.")
+	}
+
+	if !s.RemoveCert(fp) || s.RemoveCert(fp) {
+		t.Error("Should only remove a fingerprint once.")
+	}
+	if s.HasCert(fp) {
+		t.Error("Should have removed the fingerprint.")
+	}
+}
+
 func TestStoredUser_Has(t *testing.T) {
 	t.Parallel()
 	s := createStoredUser()
@@ -586,11 +665,18 @@ func TestStoredUser_Protofy(t *testing.T) {
 	t.Parallel()
 
 	a := &StoredUser{
-		Username:   "a",
-		Password:   []byte("b"),
-		Masks:      []string{"c"},
-		Access:     map[string]Access{"net:#chan": *NewAccess(23, "abc")},
-		JSONStorer: JSONStorer{"some": "data"},
+		Username: "a",
+		Password: []byte("b"),
+		Masks:    []string{"c"},
+		Access: map[string]Access{
+			"net:#chan": *NewAccess(23, "abc"),
+			"net:":      {Level: 5, Expires: 1234},
+		},
+		Accounts:    map[string]LinkedAccount{"net": {"acct", true}},
+		Certs:       []string{"d"},
+		Roles:       map[string][]string{":#chan": {"op", "voice"}},
+		Permissions: map[string][]string{"net:": {"quotes.*"}},
+		JSONStorer:  JSONStorer{"some": "data"},
 	}
 	var b StoredUser
 
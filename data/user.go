@@ -9,6 +9,12 @@ import (
 type User struct {
 	irc.Host `json:"host"`
 	Realname string `json:"realname"`
+	// Account is the services account the user is identified to, it's only
+	// known when the server reports it.
+	Account string `json:"account,omitempty"`
+	// CertFP is the fingerprint of the user's TLS client certificate, it's
+	// only known once they've been looked up with WHOIS.
+	CertFP string `json:"certfp,omitempty"`
 }
 
 // NewUser creates a user object from a nickname or fullhost.
@@ -40,6 +46,8 @@ func (u *User) ToProto() *api.StateUser {
 	user := new(api.StateUser)
 	user.Host = string(u.Host)
 	user.Realname = u.Realname
+	user.Account = u.Account
+	user.Certfp = u.CertFP
 
 	return user
 }
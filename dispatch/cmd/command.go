@@ -77,6 +77,10 @@ type Command struct {
 	ReqLevel uint8
 	// ReqFlags is the required flags for use.
 	ReqFlags string
+	// ReqPerms is the required permissions for use, namespaced names like
+	// quotes.delete that extensions declare with data.DeclarePermissions.
+	// Like the level and flags they're only checked when RequireAuth is set.
+	ReqPerms []string
 	// Handler the handler structure that will handle events for this command.
 	Handler Handler
 
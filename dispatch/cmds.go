@@ -21,6 +21,7 @@ const (
 	errMsgExtRequired     = `cmd: Extension name cannot be empty.`
 	errMsgDescRequired    = `cmd: Description cannot be empty.`
 	errMsgHandlerRequired = `cmd: Handler required for command registration.`
+	errFmtInvalidPerm     = `cmd: Invalid permission name: %v`
 
 	errMsgStoreDisabled = "Access Denied: Cannot use authenticated commands, " +
 		"nick or user parameters when store is disabled."
@@ -37,6 +38,9 @@ const (
 	errFmtInsuffGlobalFlags  = "Access Denied: (%v) global flag(s) required."
 	errFmtInsuffServerFlags  = "Access Denied: (%v) server flag(s) required."
 	errFmtInsuffChannelFlags = "Access Denied: (%v) channel flag(s) required."
+	errFmtInsuffPerm         = "Access Denied: (%v) permission required."
+	errFmtDenied             = "Access Denied: %v is denied %v."
+	errFmtNoInherit          = "Access Denied: %v required, global access does not apply on %v."
 	errFmtCmdNotFound        = `Error: Command not found (%v), try "help".`
 	errFmtAmbiguousCmd       = "Error: Ambiguous command (%v) found matching:" +
 		` [%v], try "help".`
@@ -56,14 +60,17 @@ type CommandDispatcher struct {
 
 	mutTrie sync.RWMutex
 	trie    *trie
+
+	middleware *middlewareRegistry
 }
 
 // NewCommandDispatcher initializes a cmds.
 func NewCommandDispatcher(fetcher pfxFetcher, core *Core) *CommandDispatcher {
 	return &CommandDispatcher{
-		Core:    core,
-		fetcher: fetcher,
-		trie:    newTrie(false),
+		Core:       core,
+		fetcher:    fetcher,
+		trie:       newTrie(false),
+		middleware: newMiddlewareRegistry(),
 	}
 }
 
@@ -83,6 +90,11 @@ func (c *CommandDispatcher) Register(network, channel string, command *cmd.Comma
 	case command.Handler == nil:
 		return 0, errors.New(errMsgHandlerRequired)
 	}
+	for _, perm := range command.ReqPerms {
+		if !data.ValidPermission(perm) {
+			return 0, errors.Errorf(errFmtInvalidPerm, perm)
+		}
+	}
 
 	c.mutTrie.Lock()
 	defer c.mutTrie.Unlock()
@@ -111,6 +123,22 @@ func (c *CommandDispatcher) Unregister(id uint64) (found bool) {
 	return c.trie.unregister(id)
 }
 
+// RegisterMiddleware registers middleware that wraps the dispatch of the
+// commands used on a network and channel, either may be empty to not filter
+// on it. It's outside of the command's access check. In return is an
+// identifier to pass to UnregisterMiddleware.
+func (c *CommandDispatcher) RegisterMiddleware(network, channel string,
+	middleware CmdMiddleware) uint64 {
+
+	return c.middleware.register(network, channel, middleware)
+}
+
+// UnregisterMiddleware uses the identifier returned by RegisterMiddleware to
+// unregister middleware, returns false if it could not be found.
+func (c *CommandDispatcher) UnregisterMiddleware(id uint64) bool {
+	return c.middleware.unregister(id)
+}
+
 // Dispatch dispatches an IrcEvent into the cmds event handlers.
 func (c *CommandDispatcher) Dispatch(writer irc.Writer, ev *irc.Event,
 	provider data.Provider) (handled bool, err error) {
@@ -166,8 +194,8 @@ func (c *CommandDispatcher) Dispatch(writer irc.Writer, ev *irc.Event,
 	}
 
 	c.mutTrie.RLock()
-	defer c.mutTrie.RUnlock()
 	handlers := c.trie.handlers(ev.NetworkID, ch, commandName)
+	c.mutTrie.RUnlock()
 
 	var command *cmd.Command
 	switch len(handlers) {
@@ -216,48 +244,52 @@ func (c *CommandDispatcher) Dispatch(writer irc.Writer, ev *irc.Event,
 	state := provider.State(ev.NetworkID)
 	store := provider.Store()
 
-	if command.RequireAuth {
-		if cmdEv.StoredUser, err = filterAccess(store, command, ev.NetworkID,
-			ch, ev); err != nil {
+	// The end of the middleware chain processes the arguments and starts the
+	// command's handler.
+	start := CmdHandlerFunc(func(writer irc.Writer, cmdEv *cmd.Event,
+		command *cmd.Command) error {
 
-			writer.Notice(nick, err.Error())
-			return true, err
-		}
-	}
-
-	if err = cmd.ProcessArgs(ev.NetworkID, command, ch, isChan, args, cmdEv, ev,
-		state, store); err != nil {
-
-		writer.Notice(nick, err.Error())
-		return true, err
-	}
+		if err := cmd.ProcessArgs(ev.NetworkID, command, ch, isChan, args,
+			cmdEv, ev, state, store); err != nil {
 
-	if state != nil {
-		if user, ok := state.User(ev.Sender); ok {
-			cmdEv.User = &user
+			return err
 		}
-		if isChan {
-			if channel, ok := state.Channel(ch); ok {
-				cmdEv.Channel = &channel
+
+		if state != nil {
+			if user, ok := state.User(ev.Sender); ok {
+				cmdEv.User = &user
 			}
-			if modes, ok := state.UserModes(ev.Sender, ch); ok {
-				cmdEv.UserChannelModes = &modes
+			if isChan {
+				if channel, ok := state.Channel(ch); ok {
+					cmdEv.Channel = &channel
+				}
+				if modes, ok := state.UserModes(ev.Sender, ch); ok {
+					cmdEv.UserChannelModes = &modes
+				}
 			}
 		}
-	}
 
-	c.HandlerStarted()
-	go func() {
-		defer c.HandlerFinished()
-		defer c.PanicHandler()
-		ok, err := cmdNameDispatch(command.Handler, commandName, writer, cmdEv)
-		if !ok {
-			err = command.Handler.Cmd(command.Name, writer, cmdEv)
-		}
-		if err != nil {
-			writer.Notice(nick, err.Error())
-		}
-	}()
+		c.HandlerStarted()
+		go func() {
+			defer c.HandlerFinished()
+			defer c.PanicHandler()
+			ok, err := cmdNameDispatch(command.Handler, commandName, writer, cmdEv)
+			if !ok {
+				err = command.Handler.Cmd(command.Name, writer, cmdEv)
+			}
+			if err != nil {
+				writer.Notice(nick, err.Error())
+			}
+		}()
+		return nil
+	})
+
+	handler := wrapCmdHandler(accessControl(store)(start),
+		c.middleware.chain(ev.NetworkID, ch))
+	if err = handler.HandleCmd(writer, cmdEv, command); err != nil {
+		writer.Notice(nick, err.Error())
+		return true, err
+	}
 
 	return true, nil
 }
@@ -313,19 +345,71 @@ func filterAccess(store *data.Store, command *cmd.Command, server, channel strin
 	}
 
 	var access = store.AuthedUser(server, ev.Sender)
+	if access == nil {
+		// The account tag identifies users the state hasn't seen.
+		access = store.AccountUser(server, ev.Sender, ev.Account())
+	}
 	if access == nil {
 		return nil, errors.New(errMsgNotAuthed)
 	}
-	if hasLevel && !access.HasLevel(server, channel, command.ReqLevel) {
-		return nil, errors.Errorf(errFmtInsuffLevel, command.ReqLevel)
+
+	// Denying the permission cmd.<ext>.<name> denies a single command.
+	cmdName := command.Extension + "." + command.Name
+	if d := access.ResolvePermission(server, channel, "cmd."+cmdName); d.Denied {
+		return nil, decisionError("command ("+cmdName+")", server, d, nil)
 	}
-	if hasFlags && !access.HasFlags(server, channel, command.ReqFlags) {
-		return nil, errors.Errorf(errFmtInsuffFlags, command.ReqFlags)
+	if hasLevel {
+		d := access.ResolveLevel(server, channel, command.ReqLevel)
+		if !d.Allowed {
+			return nil, decisionError(
+				fmt.Sprintf("level (%v)", command.ReqLevel), server, d,
+				MakeLevelError(command.ReqLevel))
+		}
+	}
+	if hasFlags {
+		d, flag := access.ResolveFlags(server, channel, command.ReqFlags)
+		if !d.Allowed {
+			return nil, decisionError(
+				fmt.Sprintf("flag (%c)", flag), server, d,
+				MakeFlagsError(command.ReqFlags))
+		}
+	}
+	for _, perm := range command.ReqPerms {
+		if d := access.ResolvePermission(server, channel, perm); !d.Allowed {
+			return nil, decisionError(
+				fmt.Sprintf("permission (%v)", perm), server, d,
+				MakePermissionError(perm))
+		}
 	}
 
 	return access, nil
 }
 
+// decisionError explains which rule failed an access check of what, or is
+// insufficient when no rule allowed it.
+func decisionError(what, server string, d data.AccessDecision,
+	insufficient error) error {
+
+	switch {
+	case d.Denied:
+		var where string
+		switch n, c := d.Network, d.Channel; {
+		case len(n) > 0 && len(c) > 0:
+			where = fmt.Sprintf("on %v (%v)", c, n)
+		case len(c) > 0:
+			where = "on " + c
+		case len(n) > 0:
+			where = "on network " + n
+		default:
+			where = "globally"
+		}
+		return errors.Errorf(errFmtDenied, what, where)
+	case d.NoInherit:
+		return errors.Errorf(errFmtNoInherit, what, server)
+	}
+	return insufficient
+}
+
 // EachCmd iterates through the commands and passes each one to a callback
 // function for consumption. These should be considered read-only. Optionally
 // the results can be filtered by network and channel.
@@ -397,6 +481,12 @@ func MakeChannelFlagsError(flagsRequired string) error {
 	return errors.Errorf(errFmtInsuffChannelFlags, flagsRequired)
 }
 
+// MakePermissionError creates an error to be shown to the user about a
+// required permission.
+func MakePermissionError(permRequired string) error {
+	return errors.Errorf(errFmtInsuffPerm, permRequired)
+}
+
 // MakeUserNotAuthedError creates an error to be shown to the user about their
 // target user not being authenticated.
 func MakeUserNotAuthedError(user string) error {
@@ -28,7 +28,7 @@ func init() {
 
 func newWriter() (*bytes.Buffer, irc.Writer) {
 	b := &bytes.Buffer{}
-	return b, irc.Helper{b}
+	return b, irc.Helper{Writer: b}
 }
 
 type testProvider struct {
@@ -74,9 +74,9 @@ func (b *commandHandler) Cmd(command string,
 	b.channel = ev.Channel
 	b.targChan = ev.TargetChannel
 	b.targUsers = ev.TargetUsers
-	b.targUserAccs = ev.TargetStoredUser
+	b.targUserAccs = ev.TargetStoredUsers
 	b.targVarUsers = ev.TargetVarUsers
-	b.targVarUserAccs = ev.TargetVarStoredUser
+	b.targVarUserAccs = ev.TargetVarStoredUsers
 	b.args = ev.Args
 
 	// Test Coverage obviously will work.
@@ -138,7 +138,6 @@ type panicHandler struct {
 
 func (p panicHandler) Cmd(command string, w irc.Writer, ev *cmd.Event) error {
 	panic(p.PanicMessage)
-	return nil
 }
 
 const (
@@ -465,7 +464,7 @@ func TestCmds_Dispatch(t *testing.T) {
 			NetworkID:   netID,
 			NetworkInfo: netInfo,
 		}
-		err = c.Dispatch(writer, ev, provider)
+		_, err = c.Dispatch(writer, ev, provider)
 		c.WaitForHandlers()
 		if handler.called != test.Called {
 			if handler.called {
@@ -588,7 +587,7 @@ func TestCmds_DispatchAuthed(t *testing.T) {
 			NetworkInfo: netInfo,
 		}
 
-		err = c.Dispatch(writer, ev, provider)
+		_, err = c.Dispatch(writer, ev, provider)
 		c.WaitForHandlers()
 		if handler.called != test.Called {
 			if handler.called {
@@ -645,6 +644,81 @@ func TestCmds_DispatchAuthed(t *testing.T) {
 	}
 }
 
+func TestCmds_DispatchDenied(t *testing.T) {
+	t.Parallel()
+
+	c := NewCommandDispatcher(pfxer, NewCore(nil))
+
+	buffer, writer := newWriter()
+	state, store, user := setupForAuth()
+	provider := testProvider{state, store}
+	user.Grant("", "", 100, "ab")
+	user.Deny(netID, "", 0, "b")
+	user.GrantPermission(netID, channel, "-cmd."+ext+"."+command)
+	if err := store.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	var table = []struct {
+		Channel   string
+		LevelReq  uint8
+		Flags     string
+		NoInherit bool
+		Called    bool
+		ErrMsg    string
+	}{
+		{"#other", 100, "a", false, true, ""},
+		{"#other", 0, "ab", false, false,
+			"Access Denied: flag (b) is denied on network " + netID + "."},
+		{channel, 0, "a", false, false,
+			"Access Denied: command (" + ext + "." + command + ") is denied " +
+				"on " + channel + " (" + netID + ")."},
+		{"#other", 100, "a", true, false,
+			"Access Denied: level (100) required, global access does not " +
+				"apply on " + netID + "."},
+	}
+
+	for _, test := range table {
+		buffer.Reset()
+		handler := &commandHandler{}
+		store.SetGlobalInheritance(netID, !test.NoInherit)
+
+		id, err := c.Register("", "", cmd.NewAuthed(ext, command, dsc, handler,
+			cmd.AnyKind, cmd.AnyScope, test.LevelReq, test.Flags))
+		if err != nil {
+			t.Errorf("Failed to register test: [%v]\n(%v)", err, test)
+			continue
+		}
+
+		ev := &irc.Event{
+			Sender:      host,
+			Name:        irc.PRIVMSG,
+			Args:        []string{test.Channel, string(prefix) + command},
+			NetworkID:   netID,
+			NetworkInfo: netInfo,
+		}
+
+		_, err = c.Dispatch(writer, ev, provider)
+		c.WaitForHandlers()
+		store.SetGlobalInheritance(netID, true)
+
+		if handler.called != test.Called {
+			t.Errorf("Expected called to be %v: %v", test.Called, test)
+		}
+		if len(test.ErrMsg) == 0 {
+			if err != nil {
+				t.Errorf("Unexpected User Error: %v\n%v", err, test)
+			}
+		} else if err == nil || err.Error() != test.ErrMsg {
+			t.Errorf("Expected error %q, got: %v", test.ErrMsg, err)
+		}
+
+		if !c.Unregister(id) {
+			t.Errorf("Failed to unregister test: %v", test)
+		}
+	}
+}
+
 func TestCmds_DispatchNils(t *testing.T) {
 	t.Parallel()
 
@@ -667,7 +741,7 @@ func TestCmds_DispatchNils(t *testing.T) {
 	if err != nil {
 		t.Error("Unexpected error:", err)
 	}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	err = chkErr(err, errMsgStoreDisabled)
 	if err != nil {
@@ -681,7 +755,7 @@ func TestCmds_DispatchNils(t *testing.T) {
 	if err != nil {
 		t.Error("Unexpected error:", err)
 	}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if handler.channel != nil {
 		t.Error("Channel should just be nil when state is disabled.")
@@ -738,7 +812,7 @@ func TestCmds_DispatchReturns(t *testing.T) {
 	for _, test := range errors {
 		buffer.Reset()
 		handler.Error = test.Error
-		err = c.Dispatch(writer, ev, provider)
+		_, err = c.Dispatch(writer, ev, provider)
 		c.WaitForHandlers()
 		err = chkStr(string(buffer.Bytes()), `NOTICE nick :`+test.ErrorMsg)
 		if err != nil {
@@ -776,7 +850,7 @@ func TestCmds_DispatchChannel(t *testing.T) {
 	}
 
 	ev.Args = []string{channel, string(prefix) + command}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if err != nil {
 		t.Error("There was an unexpected error:", err)
@@ -791,7 +865,7 @@ func TestCmds_DispatchChannel(t *testing.T) {
 	handler.targChan = nil
 	handler.args = nil
 	ev.Args = []string{channel, string(prefix) + command + " " + channel}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if err != nil {
 		t.Error("There was an unexpected error:", err)
@@ -806,14 +880,14 @@ func TestCmds_DispatchChannel(t *testing.T) {
 	handler.targChan = nil
 	handler.args = nil
 	ev.Args = []string{nick, command}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if err == nil {
 		t.Error("should have been an argument error")
 	}
 
 	ev.Args = []string{nick, command + " " + channel}
-	err = c.Dispatch(writer, ev, testProvider{nil, store})
+	_, err = c.Dispatch(writer, ev, testProvider{nil, store})
 	c.WaitForHandlers()
 	err = chkErr(err, errMsgStateDisabled)
 	if err != nil {
@@ -823,7 +897,7 @@ func TestCmds_DispatchChannel(t *testing.T) {
 	handler.targChan = nil
 	handler.args = nil
 	ev.Args = []string{nick, command + " " + channel}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if err != nil {
 		t.Error("There was an unexpected error:", err)
@@ -836,14 +910,14 @@ func TestCmds_DispatchChannel(t *testing.T) {
 	}
 
 	ev.Args = []string{channel, string(prefix) + command + " " + channel + " arg"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if err == nil {
 		t.Error("should have been an argument error")
 	}
 
 	ev.Args = []string{nick, command}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if err == nil {
 		t.Error("should have been an argument error")
@@ -880,7 +954,7 @@ func TestCmds_DispatchUsers(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " nick nick"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if err != nil {
 		t.Error("There was an unexpected error:", err)
@@ -897,7 +971,7 @@ func TestCmds_DispatchUsers(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " *user nick"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if err != nil {
 		t.Error("There was an unexpected error:", err)
@@ -911,7 +985,7 @@ func TestCmds_DispatchUsers(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " *user nick *user"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if err != nil {
 		t.Error("There was an unexpected error:", err)
@@ -928,7 +1002,7 @@ func TestCmds_DispatchUsers(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " *user nick *user nick nick"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if err != nil {
 		t.Error("There was an unexpected error:", err)
@@ -979,7 +1053,7 @@ func TestCmds_DispatchErrors(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " *baduser nick"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	err = chkErr(err, fmt.Sprintf(errFmtUserNotRegistered, "baduser"))
 	if err != nil {
@@ -987,14 +1061,14 @@ func TestCmds_DispatchErrors(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " * nick"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if err == nil {
 		t.Error("should have had an error about a username")
 	}
 
 	ev.Args = []string{nick, command + " self nick"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	err = chkErr(err, fmt.Sprintf(errFmtUserNotAuthed, "self"))
 	if err != nil {
@@ -1002,7 +1076,7 @@ func TestCmds_DispatchErrors(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " nick badnick"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	err = chkErr(err, fmt.Sprintf(errFmtUserNotFound, "badnick"))
 	if err != nil {
@@ -1010,7 +1084,7 @@ func TestCmds_DispatchErrors(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " nick nick nick badnick"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	err = chkErr(err, fmt.Sprintf(errFmtUserNotFound, "badnick"))
 	if err != nil {
@@ -1018,7 +1092,7 @@ func TestCmds_DispatchErrors(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " *user nick"}
-	err = c.Dispatch(writer, ev, testProvider{state, nil})
+	_, err = c.Dispatch(writer, ev, testProvider{state, nil})
 	c.WaitForHandlers()
 	err = chkErr(err, errMsgStoreDisabled)
 	if err != nil {
@@ -1026,7 +1100,7 @@ func TestCmds_DispatchErrors(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " nick nick"}
-	err = c.Dispatch(writer, ev, testProvider{nil, store})
+	_, err = c.Dispatch(writer, ev, testProvider{nil, store})
 	c.WaitForHandlers()
 	err = chkErr(err, errMsgStateDisabled)
 	if err != nil {
@@ -1044,7 +1118,7 @@ func TestCmds_DispatchErrors(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " nick"}
-	err = c.Dispatch(writer, ev, testProvider{nil, store})
+	_, err = c.Dispatch(writer, ev, testProvider{nil, store})
 	c.WaitForHandlers()
 	err = chkErr(err, errMsgStateDisabled)
 	if err != nil {
@@ -1081,7 +1155,7 @@ func TestCmds_DispatchVariadicUsers(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " *user nick"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if err != nil {
 		t.Error("There was an unexpected error:", err)
@@ -1114,7 +1188,7 @@ func TestCmds_DispatchVariadicUsers(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " nick nick badnick"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	err = chkErr(err, fmt.Sprintf(errFmtUserNotFound, "badnick"))
 	if err != nil {
@@ -1122,7 +1196,7 @@ func TestCmds_DispatchVariadicUsers(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " nick nick self"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	err = chkErr(err, fmt.Sprintf(errFmtUserNotAuthed, "self"))
 	if err != nil {
@@ -1130,7 +1204,7 @@ func TestCmds_DispatchVariadicUsers(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " nick nick *badusername"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	err = chkErr(err, fmt.Sprintf(errFmtUserNotRegistered, "badusername"))
 	if err != nil {
@@ -1167,7 +1241,7 @@ func TestCmds_DispatchMixUserAndChan(t *testing.T) {
 	}
 
 	ev.Args = []string{nick, command + " " + channel + " nick"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	c.WaitForHandlers()
 	if err != nil {
 		t.Error(err)
@@ -1211,7 +1285,7 @@ func TestCmds_DispatchReflection(t *testing.T) {
 		NetworkID:   netID,
 		NetworkInfo: netInfo,
 	}
-	err := c.Dispatch(writer, ev, provider)
+	_, err := c.Dispatch(writer, ev, provider)
 	if err != nil {
 		t.Error(err)
 	}
@@ -1229,7 +1303,7 @@ func TestCmds_DispatchReflection(t *testing.T) {
 
 	handler.Called, handler.CalledBad = false, false
 	ev.Args = []string{"a", "badargnum"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	if err != nil {
 		t.Error(err)
 	}
@@ -1244,7 +1318,7 @@ func TestCmds_DispatchReflection(t *testing.T) {
 
 	handler.Called, handler.CalledBad = false, false
 	ev.Args = []string{"a", "noreturn"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	if err != nil {
 		t.Error(err)
 	}
@@ -1259,7 +1333,7 @@ func TestCmds_DispatchReflection(t *testing.T) {
 
 	handler.Called, handler.CalledBad = false, false
 	ev.Args = []string{"a", "badargs"}
-	err = c.Dispatch(writer, ev, provider)
+	_, err = c.Dispatch(writer, ev, provider)
 	if err != nil {
 		t.Error(err)
 	}
@@ -1421,7 +1495,7 @@ func TestCmds_Panic(t *testing.T) {
 	c.Register("", "", tmpCmd)
 
 	ev := irc.NewEvent("", netInfo, irc.PRIVMSG, host, self, "panic")
-	err := c.Dispatch(nil, ev, provider)
+	_, err := c.Dispatch(nil, ev, provider)
 	if err != nil {
 		t.Error(err)
 	}
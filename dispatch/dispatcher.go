@@ -1,6 +1,7 @@
 package dispatch
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/aarondl/ultimateq/data"
@@ -22,17 +23,45 @@ func (h HandlerFunc) Handle(w irc.Writer, ev *irc.Event) {
 	h(w, ev)
 }
 
+// PreHandler handles an event before any Handler does, the pre handlers of
+// an event are run one at a time on the goroutine that dispatches it. If one
+// returns true the event is consumed, the pre handlers after it and every
+// Handler are skipped. That includes the bot's own handlers so only events
+// that are safe to drop should be consumed.
+type PreHandler interface {
+	HandlePre(w irc.Writer, ev *irc.Event) (stop bool)
+}
+
+// PreHandlerFunc implements the PreHandler interface
+type PreHandlerFunc func(w irc.Writer, ev *irc.Event) bool
+
+// HandlePre implements the PreHandler interface
+func (h PreHandlerFunc) HandlePre(w irc.Writer, ev *irc.Event) bool {
+	return h(w, ev)
+}
+
 // EventDispatcher dispatches simple events
 type EventDispatcher interface {
 	Register(network, channel, event string, handler Handler) uint64
+	RegisterPriority(network, channel, event string, priority int,
+		handler Handler) uint64
+	RegisterPre(network, channel, event string, priority int,
+		handler PreHandler) uint64
 	Unregister(id uint64) bool
-	Dispatch(w irc.Writer, ev *irc.Event)
+	RegisterMiddleware(network, channel string, middleware Middleware) uint64
+	UnregisterMiddleware(id uint64) bool
+	RegisterPreMiddleware(network, channel string,
+		middleware PreMiddleware) uint64
+	UnregisterPreMiddleware(id uint64) bool
+	Dispatch(w irc.Writer, ev *irc.Event) (stopped bool)
 }
 
 // CmdDispatcher dispatches complex commands
 type CmdDispatcher interface {
 	Register(network, channel string, command *cmd.Command) (uint64, error)
 	Unregister(id uint64) bool
+	RegisterMiddleware(network, channel string, middleware CmdMiddleware) uint64
+	UnregisterMiddleware(id uint64) bool
 	Dispatch(irc.Writer, *irc.Event, data.Provider) (bool, error)
 }
 
@@ -42,13 +71,19 @@ type Dispatcher struct {
 
 	trieMut sync.RWMutex
 	trie    *trie
+	seq     uint64
+
+	middleware    *middlewareRegistry
+	preMiddleware *middlewareRegistry
 }
 
 // NewDispatcher initializes an empty dispatcher ready to register events.
 func NewDispatcher(core *Core) *Dispatcher {
 	return &Dispatcher{
-		Core: core,
-		trie: newTrie(false),
+		Core:          core,
+		trie:          newTrie(false),
+		middleware:    newMiddlewareRegistry(),
+		preMiddleware: newMiddlewareRegistry(),
 	}
 }
 
@@ -58,11 +93,41 @@ func NewDispatcher(core *Core) *Dispatcher {
 // channel or event to prevent filtering on that parameter. Panics if it's
 // given a type that doesn't implement any of the correct interfaces.
 func (d *Dispatcher) Register(network, channel, event string, handler Handler) uint64 {
+	return d.register(network, channel, event, handlerEntry{handler: handler})
+}
+
+// RegisterPriority registers an event handler like Register but with a
+// priority, Register's is 0. The handlers of an event with the same priority
+// run concurrently and are all finished before those with a lower priority
+// are started.
+func (d *Dispatcher) RegisterPriority(network, channel, event string,
+	priority int, handler Handler) uint64 {
+
+	return d.register(network, channel, event,
+		handlerEntry{priority: priority, handler: handler})
+}
+
+// RegisterPre registers a handler to the pre phase of an event, see
+// PreHandler. The pre handlers with the highest priority are run first, of
+// those with the same priority the first registered is. The identifier
+// returned is passed into Unregister like any other.
+func (d *Dispatcher) RegisterPre(network, channel, event string,
+	priority int, handler PreHandler) uint64 {
+
+	return d.register(network, channel, event,
+		handlerEntry{priority: priority, pre: handler})
+}
+
+func (d *Dispatcher) register(network, channel, event string,
+	entry handlerEntry) uint64 {
+
 	if event == irc.RAW {
 		event = ""
 	}
 	d.trieMut.Lock()
-	id := d.trie.register(network, channel, event, handler)
+	d.seq++
+	entry.seq = d.seq
+	id := d.trie.register(network, channel, event, entry)
 	d.trieMut.Unlock()
 
 	return id
@@ -79,27 +144,156 @@ func (d *Dispatcher) Unregister(id uint64) bool {
 	return did
 }
 
+// RegisterMiddleware registers middleware that wraps the handlers of the
+// events on a network and channel, either may be empty to not filter on it.
+// In return is an identifier to pass to UnregisterMiddleware.
+func (d *Dispatcher) RegisterMiddleware(network, channel string,
+	middleware Middleware) uint64 {
+
+	return d.middleware.register(network, channel, middleware)
+}
+
+// UnregisterMiddleware uses the identifier returned by RegisterMiddleware to
+// unregister middleware, returns false if it could not be found.
+func (d *Dispatcher) UnregisterMiddleware(id uint64) bool {
+	return d.middleware.unregister(id)
+}
+
+// RegisterPreMiddleware registers middleware that wraps the pre handlers of
+// the events on a network and channel like RegisterMiddleware does handlers.
+// In return is an identifier to pass to UnregisterPreMiddleware.
+func (d *Dispatcher) RegisterPreMiddleware(network, channel string,
+	middleware PreMiddleware) uint64 {
+
+	return d.preMiddleware.register(network, channel, middleware)
+}
+
+// UnregisterPreMiddleware uses the identifier returned by
+// RegisterPreMiddleware to unregister middleware, returns false if it could
+// not be found.
+func (d *Dispatcher) UnregisterPreMiddleware(id uint64) bool {
+	return d.preMiddleware.unregister(id)
+}
+
 // Dispatch an IrcMessage to event handlers handling event also ensures all raw
-// handlers receive all messages.
-func (d *Dispatcher) Dispatch(w irc.Writer, ev *irc.Event) {
+// handlers receive all messages. The pre handlers are run first wrapped in
+// the pre middleware, it returns true if one of them consumed the event. Then
+// each handler is wrapped in the middleware for the event's network and
+// channel and started.
+func (d *Dispatcher) Dispatch(w irc.Writer, ev *irc.Event) (stopped bool) {
 	network := ev.NetworkID
 	event := ev.Name
-	var channel string
-	if len(ev.Args) > 0 && ev.IsTargetChan() {
-		channel = ev.Target()
-	}
+	channel := eventChannel(ev)
 
 	d.trieMut.RLock()
-	handlers := d.trie.handlers(network, channel, event)
+	found := d.trie.handlers(network, channel, event)
 	d.trieMut.RUnlock()
 
+	entries := sortHandlers(found)
+
+	var handlers []handlerEntry
+	var preChain []interface{}
+	chained := false
+	for _, entry := range entries {
+		if entry.pre == nil {
+			handlers = append(handlers, entry)
+			continue
+		}
+
+		if !chained {
+			preChain, chained = d.preMiddleware.chain(network, channel), true
+		}
+		if d.handlePre(wrapPreHandler(entry.pre, preChain), w, ev) {
+			return true
+		}
+	}
+
+	if len(handlers) == 0 {
+		return false
+	}
+
+	chain := d.middleware.chain(network, channel)
+	var groups [][]Handler
+	for i, entry := range handlers {
+		if i == 0 || entry.priority != handlers[i-1].priority {
+			groups = append(groups, nil)
+		}
+		last := len(groups) - 1
+		groups[last] = append(groups[last], wrapHandler(entry.handler, chain))
+	}
+
+	if len(groups) == 1 {
+		d.startHandlers(w, ev, groups[0], nil)
+		return false
+	}
+
+	// Each priority waits on the one before it, so they're started in order
+	// off of the dispatching goroutine.
+	d.HandlerStarted()
+	go func() {
+		defer d.HandlerFinished()
+		for _, group := range groups {
+			var wg sync.WaitGroup
+			d.startHandlers(w, ev, group, &wg)
+			wg.Wait()
+		}
+	}()
+	return false
+}
+
+// startHandlers starts each handler on its own goroutine, wg is done when
+// they've finished if it's not nil.
+func (d *Dispatcher) startHandlers(w irc.Writer, ev *irc.Event,
+	handlers []Handler, wg *sync.WaitGroup) {
+
 	for _, handler := range handlers {
-		h := handler.(Handler)
+		h := handler
 		d.HandlerStarted()
+		if wg != nil {
+			wg.Add(1)
+		}
 		go func() {
 			defer d.HandlerFinished()
+			if wg != nil {
+				defer wg.Done()
+			}
 			defer d.PanicHandler()
 			h.Handle(w, ev)
 		}()
 	}
 }
+
+// handlePre runs a pre handler, one that panics doesn't consume the event.
+func (d *Dispatcher) handlePre(handler PreHandler, w irc.Writer,
+	ev *irc.Event) (stop bool) {
+
+	defer d.PanicHandler()
+	return handler.HandlePre(w, ev)
+}
+
+// handlerEntry is a handler registered to a dispatcher, only one of handler
+// and pre is set.
+type handlerEntry struct {
+	priority int
+	seq      uint64
+	handler  Handler
+	pre      PreHandler
+}
+
+// sortHandlers orders the handlers found for an event from the highest
+// priority to the lowest, otherwise the first registered is first.
+func sortHandlers(found []interface{}) []handlerEntry {
+	entries := make([]handlerEntry, len(found))
+	for i, f := range found {
+		entries[i] = f.(handlerEntry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+		return a.seq < b.seq
+	})
+	return entries
+}
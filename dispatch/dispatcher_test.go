@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/aarondl/ultimateq/irc"
 	"gopkg.in/inconshreveable/log15.v2"
@@ -116,3 +117,89 @@ func TestDispatcherPanic(t *testing.T) {
 		t.Error("Does not contain a reference to file that panic'd")
 	}
 }
+
+func TestDispatcherPriority(t *testing.T) {
+	t.Parallel()
+	d := NewDispatcher(NewCore(nil))
+	rec := &recorder{}
+
+	handler := func(name string) Handler {
+		return HandlerFunc(func(irc.Writer, *irc.Event) {
+			if name == "high" {
+				time.Sleep(10 * time.Millisecond)
+			}
+			rec.add(name)
+		})
+	}
+
+	d.RegisterPriority("", "", irc.PRIVMSG, -1, handler("low"))
+	d.Register("", "", irc.PRIVMSG, handler("mid"))
+	d.RegisterPriority("", "", irc.PRIVMSG, 5, handler("high"))
+	d.RegisterPriority("", "", irc.PRIVMSG, 0, handler("mid"))
+
+	d.Dispatch(nil, irc.NewEvent("net", netInfo, irc.PRIVMSG, "a!b@c", "#chan", "hi"))
+	d.WaitForHandlers()
+	if got, exp := rec.String(), "high mid mid low"; got != exp {
+		t.Errorf("Expected: %q, got: %q", exp, got)
+	}
+}
+
+func TestDispatcherPre(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	logger := log15.New()
+	logger.SetHandler(log15.StreamHandler(buf, log15.LogfmtFormat()))
+	d := NewDispatcher(NewCore(logger))
+	rec := &recorder{}
+
+	d.Register("", "", irc.PRIVMSG, HandlerFunc(func(irc.Writer, *irc.Event) {
+		rec.add("handler")
+	}))
+	d.RegisterPre("", "", irc.PRIVMSG, 0, PreHandlerFunc(
+		func(_ irc.Writer, ev *irc.Event) bool {
+			rec.add("filter")
+			return ev.Message() == "spam"
+		}))
+	d.RegisterPre("", "", irc.PRIVMSG, 1, PreHandlerFunc(
+		func(irc.Writer, *irc.Event) bool {
+			rec.add("first")
+			return false
+		}))
+
+	if d.Dispatch(nil, irc.NewEvent("net", netInfo, irc.PRIVMSG, "a!b@c", "#chan", "hi")) {
+		t.Error("Expected the event not to be consumed.")
+	}
+	d.WaitForHandlers()
+	if got, exp := rec.String(), "first filter handler"; got != exp {
+		t.Errorf("Expected: %q, got: %q", exp, got)
+	}
+
+	rec.calls = nil
+	if !d.Dispatch(nil, irc.NewEvent("net", netInfo, irc.PRIVMSG, "a!b@c", "#chan", "spam")) {
+		t.Error("Expected the event to be consumed.")
+	}
+	d.WaitForHandlers()
+	if got, exp := rec.String(), "first filter"; got != exp {
+		t.Errorf("Expected: %q, got: %q", exp, got)
+	}
+
+	rec.calls = nil
+	id := d.RegisterPre("", "", "", 2, PreHandlerFunc(
+		func(irc.Writer, *irc.Event) bool {
+			panic("pre panic")
+		}))
+	if d.Dispatch(nil, irc.NewEvent("net", netInfo, irc.PRIVMSG, "a!b@c", "#chan", "hi")) {
+		t.Error("Expected a panic not to consume the event.")
+	}
+	d.WaitForHandlers()
+	if got, exp := rec.String(), "first filter handler"; got != exp {
+		t.Errorf("Expected: %q, got: %q", exp, got)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("pre panic")) {
+		t.Error("Expected the panic to be logged, got:", buf.String())
+	}
+
+	if !d.Unregister(id) || d.Unregister(id) {
+		t.Error("Expected the pre handler to be unregistered once.")
+	}
+}
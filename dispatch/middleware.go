@@ -0,0 +1,330 @@
+package dispatch
+
+import (
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aarondl/ultimateq/data"
+	"github.com/aarondl/ultimateq/dispatch/cmd"
+	"github.com/aarondl/ultimateq/irc"
+	"github.com/pkg/errors"
+	"gopkg.in/inconshreveable/log15.v2"
+)
+
+// Middleware wraps a Handler to act before or after it, an event is kept
+// from the handler by not calling next. It runs on the handler's goroutine.
+type Middleware func(next Handler) Handler
+
+// CmdHandler is what's left of dispatching a command once it's found, it
+// checks the command's access, processes its arguments and starts its
+// handler.
+type CmdHandler interface {
+	HandleCmd(w irc.Writer, ev *cmd.Event, command *cmd.Command) error
+}
+
+// CmdHandlerFunc implements the CmdHandler interface
+type CmdHandlerFunc func(w irc.Writer, ev *cmd.Event, command *cmd.Command) error
+
+// HandleCmd implements the CmdHandler interface
+func (h CmdHandlerFunc) HandleCmd(w irc.Writer, ev *cmd.Event,
+	command *cmd.Command) error {
+
+	return h(w, ev, command)
+}
+
+// CmdMiddleware wraps the dispatch of a command once it's found. It runs
+// before the command's handler is started, an error it returns is noticed to
+// the user and returned from Dispatch. A command is dropped without an error
+// by not calling next.
+type CmdMiddleware func(next CmdHandler) CmdHandler
+
+// PreMiddleware wraps a PreHandler, it runs on the dispatching goroutine
+// before the pre handler. An event is kept from the pre handler without
+// being consumed by not calling next.
+type PreMiddleware func(next PreHandler) PreHandler
+
+// middlewareEntry is middleware registered for a network and channel.
+type middlewareEntry struct {
+	// scope is 0 for global, 1 for a network and 2 for a channel.
+	scope      int
+	seq        uint64
+	middleware interface{}
+}
+
+// middlewareRegistry keeps the middleware registered to a dispatcher.
+type middlewareRegistry struct {
+	protect sync.RWMutex
+	trie    *trie
+	seq     uint64
+}
+
+func newMiddlewareRegistry() *middlewareRegistry {
+	return &middlewareRegistry{trie: newTrie(false)}
+}
+
+func (m *middlewareRegistry) register(network, channel string,
+	middleware interface{}) uint64 {
+
+	entry := middlewareEntry{middleware: middleware}
+	switch {
+	case len(channel) != 0:
+		entry.scope = 2
+	case len(network) != 0:
+		entry.scope = 1
+	}
+
+	m.protect.Lock()
+	defer m.protect.Unlock()
+
+	m.seq++
+	entry.seq = m.seq
+	return m.trie.register(network, channel, "", entry)
+}
+
+func (m *middlewareRegistry) unregister(id uint64) bool {
+	m.protect.Lock()
+	defer m.protect.Unlock()
+
+	return m.trie.unregister(id)
+}
+
+// chain gets the middleware for a network and channel from the outermost to
+// the innermost. Global middleware is outside of network middleware which is
+// outside of channel middleware, otherwise the first registered is outermost.
+func (m *middlewareRegistry) chain(network, channel string) []interface{} {
+	m.protect.RLock()
+	found := m.trie.handlers(network, channel, "")
+	m.protect.RUnlock()
+
+	if len(found) == 0 {
+		return nil
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i].(middlewareEntry), found[j].(middlewareEntry)
+		if a.scope != b.scope {
+			return a.scope < b.scope
+		}
+		return a.seq < b.seq
+	})
+	for i, f := range found {
+		found[i] = f.(middlewareEntry).middleware
+	}
+	return found
+}
+
+// wrapHandler wraps a handler in a chain of Middleware.
+func wrapHandler(handler Handler, chain []interface{}) Handler {
+	for i := len(chain) - 1; i >= 0; i-- {
+		handler = chain[i].(Middleware)(handler)
+	}
+	return handler
+}
+
+// wrapCmdHandler wraps a command handler in a chain of CmdMiddleware.
+func wrapCmdHandler(handler CmdHandler, chain []interface{}) CmdHandler {
+	for i := len(chain) - 1; i >= 0; i-- {
+		handler = chain[i].(CmdMiddleware)(handler)
+	}
+	return handler
+}
+
+// wrapPreHandler wraps a pre handler in a chain of PreMiddleware.
+func wrapPreHandler(handler PreHandler, chain []interface{}) PreHandler {
+	for i := len(chain) - 1; i >= 0; i-- {
+		handler = chain[i].(PreMiddleware)(handler)
+	}
+	return handler
+}
+
+// eventChannel gets the channel an event was sent to, empty if it wasn't.
+func eventChannel(ev *irc.Event) string {
+	if len(ev.Args) > 0 && ev.IsTargetChan() {
+		return ev.Target()
+	}
+	return ""
+}
+
+// Logging logs each event a handler is given and how long it took.
+func Logging(logger log15.Logger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w irc.Writer, ev *irc.Event) {
+			start := time.Now()
+			next.Handle(w, ev)
+			logger.Debug("Event handled", "network", ev.NetworkID,
+				"event", ev.Name, "sender", ev.Sender,
+				"took", time.Since(start))
+		})
+	}
+}
+
+// CmdLogging logs each command that's dispatched and why it failed.
+func CmdLogging(logger log15.Logger) CmdMiddleware {
+	return func(next CmdHandler) CmdHandler {
+		return CmdHandlerFunc(func(w irc.Writer, ev *cmd.Event,
+			command *cmd.Command) error {
+
+			err := next.HandleCmd(w, ev, command)
+			ctx := []interface{}{"network", ev.NetworkID,
+				"cmd", command.Extension + "." + command.Name,
+				"sender", ev.Sender}
+			if err != nil {
+				logger.Info("Command refused", append(ctx, "err", err)...)
+			} else {
+				logger.Debug("Command dispatched", ctx...)
+			}
+			return err
+		})
+	}
+}
+
+// ignored checks if a sender ends with one of the suffixes.
+func ignored(sender string, suffixes []string) bool {
+	for _, s := range suffixes {
+		if strings.HasSuffix(sender, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ignore keeps events from senders that end with any of the suffixes from
+// handlers.
+func Ignore(suffixes ...string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w irc.Writer, ev *irc.Event) {
+			if !ignored(ev.Sender, suffixes) {
+				next.Handle(w, ev)
+			}
+		})
+	}
+}
+
+// CmdIgnore drops commands from senders that end with any of the suffixes.
+func CmdIgnore(suffixes ...string) CmdMiddleware {
+	return func(next CmdHandler) CmdHandler {
+		return CmdHandlerFunc(func(w irc.Writer, ev *cmd.Event,
+			command *cmd.Command) error {
+
+			if ignored(ev.Sender, suffixes) {
+				return nil
+			}
+			return next.HandleCmd(w, ev, command)
+		})
+	}
+}
+
+// PreIgnore keeps events from senders that end with any of the suffixes from
+// pre handlers.
+func PreIgnore(suffixes ...string) PreMiddleware {
+	return func(next PreHandler) PreHandler {
+		return PreHandlerFunc(func(w irc.Writer, ev *irc.Event) bool {
+			if ignored(ev.Sender, suffixes) {
+				return false
+			}
+			return next.HandlePre(w, ev)
+		})
+	}
+}
+
+// RequireAccess keeps events from handlers unless they're from a user that
+// is authenticated and has the level and flags on the network and channel
+// they were sent to.
+func RequireAccess(provider data.Provider, level uint8,
+	flags string) Middleware {
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w irc.Writer, ev *irc.Event) {
+			store := provider.Store()
+			if store == nil {
+				return
+			}
+
+			user := store.AuthedUser(ev.NetworkID, ev.Sender)
+			if user == nil {
+				user = store.AccountUser(ev.NetworkID, ev.Sender, ev.Account())
+			}
+			if user == nil {
+				return
+			}
+
+			channel := eventChannel(ev)
+			if level != 0 &&
+				!user.ResolveLevel(ev.NetworkID, channel, level).Allowed {
+				return
+			}
+			if len(flags) != 0 {
+				if d, _ := user.ResolveFlags(ev.NetworkID, channel,
+					flags); !d.Allowed {
+					return
+				}
+			}
+			next.Handle(w, ev)
+		})
+	}
+}
+
+// accessControl checks that the user has the access a command requires, it's
+// always the innermost CmdMiddleware.
+func accessControl(store *data.Store) CmdMiddleware {
+	return func(next CmdHandler) CmdHandler {
+		return CmdHandlerFunc(func(w irc.Writer, ev *cmd.Event,
+			command *cmd.Command) (err error) {
+
+			if command.RequireAuth {
+				ev.StoredUser, err = filterAccess(store, command, ev.NetworkID,
+					eventChannel(ev.Event), ev.Event)
+				if err != nil {
+					return err
+				}
+			}
+			return next.HandleCmd(w, ev, command)
+		})
+	}
+}
+
+// PanicCapture is given what a handler panicked with and the stack of its
+// goroutine.
+type PanicCapture func(ev *irc.Event, recovered interface{}, stack []byte)
+
+// stack gets the stack of the current goroutine.
+func stack() []byte {
+	buf := make([]byte, 4096)
+	return buf[:runtime.Stack(buf, false)]
+}
+
+// Recover captures the panics of handlers.
+func Recover(capture PanicCapture) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w irc.Writer, ev *irc.Event) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					capture(ev, recovered, stack())
+				}
+			}()
+			next.Handle(w, ev)
+		})
+	}
+}
+
+// CmdRecover captures the panics of the CmdMiddleware inside of it, the
+// command fails with an internal error. Command handlers run on their own
+// goroutine so their panics are left to the dispatcher.
+func CmdRecover(capture PanicCapture) CmdMiddleware {
+	return func(next CmdHandler) CmdHandler {
+		return CmdHandlerFunc(func(w irc.Writer, ev *cmd.Event,
+			command *cmd.Command) (err error) {
+
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					capture(ev.Event, recovered, stack())
+					err = errors.Errorf(errFmtInternal, recovered)
+				}
+			}()
+			return next.HandleCmd(w, ev, command)
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package dispatch
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aarondl/ultimateq/data"
+	"github.com/aarondl/ultimateq/dispatch/cmd"
+	"github.com/aarondl/ultimateq/irc"
+)
+
+// recorder remembers the order middleware and handlers were called in.
+type recorder struct {
+	sync.Mutex
+	calls []string
+}
+
+func (r *recorder) add(call string) {
+	r.Lock()
+	defer r.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) String() string {
+	r.Lock()
+	defer r.Unlock()
+	return strings.Join(r.calls, " ")
+}
+
+func (r *recorder) middleware(name string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w irc.Writer, ev *irc.Event) {
+			r.add(name)
+			next.Handle(w, ev)
+		})
+	}
+}
+
+func (r *recorder) cmdMiddleware(name string) CmdMiddleware {
+	return func(next CmdHandler) CmdHandler {
+		return CmdHandlerFunc(func(w irc.Writer, ev *cmd.Event,
+			command *cmd.Command) error {
+
+			r.add(name)
+			return next.HandleCmd(w, ev, command)
+		})
+	}
+}
+
+type storeProvider struct {
+	store *data.Store
+}
+
+func (s storeProvider) State(string) *data.State { return nil }
+func (s storeProvider) Store() *data.Store       { return s.store }
+
+func TestDispatcher_Middleware(t *testing.T) {
+	t.Parallel()
+	d := NewDispatcher(NewCore(nil))
+	rec := &recorder{}
+
+	d.Register("", "", irc.PRIVMSG, HandlerFunc(func(irc.Writer, *irc.Event) {
+		rec.add("handler")
+	}))
+	d.RegisterMiddleware("net", "#chan", rec.middleware("channel"))
+	d.RegisterMiddleware("net", "", rec.middleware("network"))
+	d.RegisterMiddleware("", "", rec.middleware("global1"))
+	d.RegisterMiddleware("other", "", rec.middleware("other"))
+	id := d.RegisterMiddleware("", "", rec.middleware("global2"))
+
+	d.Dispatch(nil, irc.NewEvent("net", netInfo, irc.PRIVMSG, "a!b@c", "#chan", "hi"))
+	d.WaitForHandlers()
+	if got, exp := rec.String(), "global1 global2 network channel handler"; got != exp {
+		t.Errorf("Expected: %q, got: %q", exp, got)
+	}
+
+	if !d.UnregisterMiddleware(id) || d.UnregisterMiddleware(id) {
+		t.Error("Expected the middleware to be unregistered once.")
+	}
+	rec.calls = nil
+	d.Dispatch(nil, irc.NewEvent("net", netInfo, irc.PRIVMSG, "a!b@c", "nick", "hi"))
+	d.WaitForHandlers()
+	if got, exp := rec.String(), "global1 network handler"; got != exp {
+		t.Errorf("Expected: %q, got: %q", exp, got)
+	}
+
+	rec.calls = nil
+	d.RegisterMiddleware("", "", Ignore("@c"))
+	d.Dispatch(nil, irc.NewEvent("net", netInfo, irc.PRIVMSG, "a!b@c", "nick", "hi"))
+	d.WaitForHandlers()
+	if got, exp := rec.String(), "global1"; got != exp {
+		t.Errorf("Expected the ignore to stop dispatch: %q, got: %q", exp, got)
+	}
+}
+
+func TestDispatcher_PreMiddleware(t *testing.T) {
+	t.Parallel()
+	d := NewDispatcher(NewCore(nil))
+	rec := &recorder{}
+
+	d.RegisterPre("", "", irc.PRIVMSG, 0, PreHandlerFunc(
+		func(irc.Writer, *irc.Event) bool {
+			rec.add("pre")
+			return true
+		}))
+	d.Register("", "", irc.PRIVMSG, HandlerFunc(func(irc.Writer, *irc.Event) {
+		rec.add("handler")
+	}))
+	id := d.RegisterPreMiddleware("net", "#chan", func(next PreHandler) PreHandler {
+		return PreHandlerFunc(func(w irc.Writer, ev *irc.Event) bool {
+			rec.add("channel")
+			return next.HandlePre(w, ev)
+		})
+	})
+	d.RegisterPreMiddleware("", "", PreIgnore("@c"))
+
+	if d.Dispatch(nil, irc.NewEvent("net", netInfo, irc.PRIVMSG, "a!b@c", "#chan", "hi")) {
+		t.Error("Expected an ignored event not to be consumed.")
+	}
+	d.WaitForHandlers()
+	if got, exp := rec.String(), "handler"; got != exp {
+		t.Errorf("Expected the ignore to skip the pre handler: %q, got: %q", exp, got)
+	}
+
+	rec.calls = nil
+	if !d.Dispatch(nil, irc.NewEvent("net", netInfo, irc.PRIVMSG, "a!b@d", "#chan", "hi")) {
+		t.Error("Expected the event to be consumed.")
+	}
+	d.WaitForHandlers()
+	if got, exp := rec.String(), "channel pre"; got != exp {
+		t.Errorf("Expected: %q, got: %q", exp, got)
+	}
+
+	if !d.UnregisterPreMiddleware(id) || d.UnregisterPreMiddleware(id) {
+		t.Error("Expected the middleware to be unregistered once.")
+	}
+}
+
+func TestDispatcher_MiddlewareRecover(t *testing.T) {
+	t.Parallel()
+	d := NewDispatcher(NewCore(nil))
+
+	var recovered interface{}
+	var stack []byte
+	d.RegisterMiddleware("", "", Recover(func(_ *irc.Event, r interface{}, s []byte) {
+		recovered, stack = r, s
+	}))
+	d.Register("", "", "", HandlerFunc(func(irc.Writer, *irc.Event) {
+		panic("middleware panic")
+	}))
+
+	d.Dispatch(nil, irc.NewEvent("net", netInfo, irc.PRIVMSG, "a!b@c", "nick", "hi"))
+	d.WaitForHandlers()
+	if recovered != "middleware panic" {
+		t.Error("Expected the panic to be captured, got:", recovered)
+	}
+	if !bytes.Contains(stack, []byte("middleware_test.go")) {
+		t.Error("Expected the stack of the panic, got:", string(stack))
+	}
+}
+
+func TestRequireAccess(t *testing.T) {
+	t.Parallel()
+	store, err := data.NewStore(data.MemStoreProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	user, _ := data.NewStoredUser("user", "pass")
+	user.Grant("net", "#chan", 100, "a")
+	if err = store.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.AuthUserTmp("net", "a!b@c", "user", "pass"); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		Sender, Target string
+		Level          uint8
+		Flags          string
+		Called         bool
+	}{
+		{"a!b@c", "#chan", 100, "a", true},
+		{"a!b@c", "#chan", 101, "", false},
+		{"a!b@c", "#chan", 0, "b", false},
+		{"a!b@c", "#other", 100, "", false},
+		{"x!y@z", "#chan", 0, "", false},
+	}
+
+	for _, test := range tests {
+		called := false
+		h := RequireAccess(storeProvider{store}, test.Level, test.Flags)(
+			HandlerFunc(func(irc.Writer, *irc.Event) { called = true }))
+		h.Handle(nil, irc.NewEvent("net", netInfo, irc.PRIVMSG, test.Sender,
+			test.Target, "hi"))
+
+		if called != test.Called {
+			t.Errorf("%v: expected called to be %v", test, test.Called)
+		}
+	}
+}
+
+func TestCommandDispatcher_Middleware(t *testing.T) {
+	t.Parallel()
+	c := NewCommandDispatcher(func(string, string) rune { return '.' },
+		NewCore(nil))
+	rec := &recorder{}
+
+	handler := cmd.HandlerFunc(func(_ string, w irc.Writer, ev *cmd.Event) error {
+		rec.add("handler")
+		return nil
+	})
+	if _, err := c.Register("", "", cmd.New("ext", "cmd", "desc", handler,
+		cmd.AnyKind, cmd.AnyScope)); err != nil {
+		t.Fatal(err)
+	}
+
+	refuse := errors.New("refused")
+	c.RegisterMiddleware("", "#chan", func(next CmdHandler) CmdHandler {
+		return CmdHandlerFunc(func(w irc.Writer, ev *cmd.Event,
+			command *cmd.Command) error {
+
+			rec.add("channel")
+			return refuse
+		})
+	})
+	c.RegisterMiddleware("net", "", rec.cmdMiddleware("network"))
+	c.RegisterMiddleware("", "", rec.cmdMiddleware("global"))
+
+	buf := &bytes.Buffer{}
+	w := irc.Helper{Writer: buf}
+	provider := storeProvider{}
+
+	handled, err := c.Dispatch(w, irc.NewEvent("net", netInfo, irc.PRIVMSG,
+		"a!b@c", "bot", "cmd"), provider)
+	c.WaitForHandlers()
+	if !handled || err != nil {
+		t.Error("Expected the command to be handled, got:", handled, err)
+	}
+	if got, exp := rec.String(), "global network handler"; got != exp {
+		t.Errorf("Expected: %q, got: %q", exp, got)
+	}
+
+	rec.calls = nil
+	buf.Reset()
+	handled, err = c.Dispatch(w, irc.NewEvent("net", netInfo, irc.PRIVMSG,
+		"a!b@c", "#chan", ".cmd"), provider)
+	c.WaitForHandlers()
+	if !handled || err != refuse {
+		t.Error("Expected the middleware to refuse the command, got:",
+			handled, err)
+	}
+	if got, exp := rec.String(), "global network channel"; got != exp {
+		t.Errorf("Expected: %q, got: %q", exp, got)
+	}
+	if got := buf.String(); got != "NOTICE a :refused" {
+		t.Errorf("Expected the user to be noticed, got: %q", got)
+	}
+
+	rec.calls = nil
+	c.RegisterMiddleware("", "", CmdIgnore("@c"))
+	handled, err = c.Dispatch(w, irc.NewEvent("net", netInfo, irc.PRIVMSG,
+		"a!b@c", "bot", "cmd"), provider)
+	c.WaitForHandlers()
+	if !handled || err != nil || rec.String() != "global" {
+		t.Error("Expected the command to be ignored, got:", handled, err, rec)
+	}
+}
+
+func TestCommandDispatcher_MiddlewareAccess(t *testing.T) {
+	t.Parallel()
+	c := NewCommandDispatcher(func(string, string) rune { return '.' },
+		NewCore(nil))
+
+	var sawUser bool
+	handler := cmd.HandlerFunc(func(string, irc.Writer, *cmd.Event) error {
+		return nil
+	})
+	if _, err := c.Register("", "", cmd.NewAuthed("ext", "cmd", "desc",
+		handler, cmd.AnyKind, cmd.AnyScope, 100, "")); err != nil {
+		t.Fatal(err)
+	}
+	c.RegisterMiddleware("", "", func(next CmdHandler) CmdHandler {
+		return CmdHandlerFunc(func(w irc.Writer, ev *cmd.Event,
+			command *cmd.Command) error {
+
+			err := next.HandleCmd(w, ev, command)
+			sawUser = ev.StoredUser != nil
+			return err
+		})
+	})
+
+	var captured interface{}
+	c.RegisterMiddleware("", "", CmdRecover(
+		func(_ *irc.Event, r interface{}, _ []byte) { captured = r }))
+
+	buf := &bytes.Buffer{}
+	w := irc.Helper{Writer: buf}
+	_, err := c.Dispatch(w, irc.NewEvent("net", netInfo, irc.PRIVMSG,
+		"a!b@c", "bot", "cmd"), storeProvider{})
+	if err == nil || err.Error() != errMsgStoreDisabled {
+		t.Error("Expected the access check to fail, got:", err)
+	}
+	if sawUser {
+		t.Error("Expected no stored user.")
+	}
+
+	c.RegisterMiddleware("", "", func(next CmdHandler) CmdHandler {
+		return CmdHandlerFunc(func(irc.Writer, *cmd.Event, *cmd.Command) error {
+			panic("cmd panic")
+		})
+	})
+	_, err = c.Dispatch(w, irc.NewEvent("net", netInfo, irc.PRIVMSG,
+		"a!b@c", "bot", "cmd"), storeProvider{})
+	if captured != "cmd panic" || err == nil ||
+		!strings.Contains(err.Error(), "cmd panic") {
+		t.Error("Expected the panic to be captured, got:", captured, err)
+	}
+}
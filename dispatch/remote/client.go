@@ -10,6 +10,7 @@ import (
 
 	"google.golang.org/grpc/credentials"
 
+	"github.com/aarondl/ultimateq/data"
 	"github.com/aarondl/ultimateq/dispatch"
 	"github.com/aarondl/ultimateq/dispatch/cmd"
 	"github.com/aarondl/ultimateq/irc"
@@ -73,6 +74,7 @@ type Client struct {
 
 	mut      sync.RWMutex
 	events   map[uint64]dispatch.Handler
+	pre      map[uint64]dispatch.PreHandler
 	commands map[uint64]cmd.Handler
 }
 
@@ -82,6 +84,7 @@ func NewClient(extension string, client api.ExtClient) *Client {
 		extension: extension,
 		client:    client,
 		events:    make(map[uint64]dispatch.Handler),
+		pre:       make(map[uint64]dispatch.PreHandler),
 		commands:  make(map[uint64]cmd.Handler),
 	}
 
@@ -122,6 +125,9 @@ func (c *Client) Listen() error {
 	for id := range c.events {
 		eventIDs = append(eventIDs, id)
 	}
+	for id := range c.pre {
+		eventIDs = append(eventIDs, id)
+	}
 	for id := range c.commands {
 		cmdIDs = append(cmdIDs, id)
 	}
@@ -157,9 +163,10 @@ func (c *Client) Listen() error {
 
 			c.mut.RLock()
 			handler := c.events[ircEventResp.Id]
+			pre := c.pre[ircEventResp.Id]
 			c.mut.RUnlock()
 
-			if handler == nil {
+			if handler == nil && pre == nil {
 				// How did this happen?
 				continue
 			}
@@ -172,7 +179,11 @@ func (c *Client) Listen() error {
 				NetworkID: ircEventResp.Event.Net,
 			}
 
-			go handler.Handle(writer, ev)
+			if pre != nil && ircEventResp.Verdict != 0 {
+				go c.handlePre(pre, writer, ev, ircEventResp.Verdict)
+			} else if handler != nil {
+				go handler.Handle(writer, ev)
+			}
 		}
 
 		wg.Done()
@@ -230,24 +241,99 @@ func (c *Client) Listen() error {
 	return nil
 }
 
+// handlePre runs a pre handler and gives the bot its verdict.
+func (c *Client) handlePre(pre dispatch.PreHandler, w irc.Writer,
+	ev *irc.Event, verdict uint64) {
+
+	req := &api.PreVerdictRequest{
+		Ext:     c.extension,
+		Verdict: verdict,
+		Stop:    pre.HandlePre(w, ev),
+	}
+	// The bot stops waiting after a while, a late verdict is of no use.
+	_, _ = c.client.PreVerdict(context.Background(), req)
+}
+
+// ListenChanges gives handler the changes made to the bot's store in the
+// order they were made. Only the kinds given are received, or every change if
+// none are given. It blocks until the stream fails.
+func (c *Client) ListenChanges(handler func(data.StoreChange),
+	kinds ...data.ChangeKind) error {
+
+	req := &api.StoreChangesRequest{Ext: c.extension}
+	for _, k := range kinds {
+		req.Kinds = append(req.Kinds, k.String())
+	}
+
+	stream, err := c.client.StoreChanges(context.Background(), req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		var change data.StoreChange
+		if change.FromProto(resp) {
+			handler(change)
+		}
+	}
+}
+
 // Register an event handler with the bot
 func (c *Client) Register(network string, channel string, event string, handler dispatch.Handler) (uint64, error) {
-	req := &api.RegisterRequest{
-		Ext:     c.extension,
-		Network: network,
-		Channel: channel,
-		Event:   event,
+	return c.RegisterPriority(network, channel, event, 0, handler)
+}
+
+// RegisterPriority registers an event handler with the bot with a priority,
+// handlers of a higher priority finish before those of a lower one start.
+func (c *Client) RegisterPriority(network string, channel string, event string, priority int, handler dispatch.Handler) (uint64, error) {
+	id, err := c.register(network, channel, event, priority, false)
+	if err != nil {
+		return 0, err
 	}
 
-	resp, err := c.client.Register(context.Background(), req)
+	c.mut.Lock()
+	c.events[id] = handler
+	c.mut.Unlock()
+
+	return id, nil
+}
+
+// RegisterPre registers a handler that's given an event before any other
+// handler or command, it's consumed if the handler returns true. The bot
+// waits a short while on its answer. It's unregistered with Unregister.
+func (c *Client) RegisterPre(network string, channel string, event string, priority int, handler dispatch.PreHandler) (uint64, error) {
+	id, err := c.register(network, channel, event, priority, true)
 	if err != nil {
 		return 0, err
 	}
 
 	c.mut.Lock()
-	c.events[resp.Id] = handler
+	c.pre[id] = handler
 	c.mut.Unlock()
 
+	return id, nil
+}
+
+func (c *Client) register(network, channel, event string, priority int, pre bool) (uint64, error) {
+	req := &api.RegisterRequest{
+		Ext:      c.extension,
+		Network:  network,
+		Channel:  channel,
+		Event:    event,
+		Priority: int32(priority),
+		Pre:      pre,
+	}
+
+	resp, err := c.client.Register(context.Background(), req)
+	if err != nil {
+		return 0, err
+	}
+
 	return resp.Id, nil
 }
 
@@ -267,6 +353,7 @@ func (c *Client) RegisterCmd(network string, channel string, command *cmd.Comman
 			RequireAuth: command.RequireAuth,
 			ReqLevel:    int32(command.ReqLevel),
 			ReqFlags:    command.ReqFlags,
+			ReqPerms:    command.ReqPerms,
 		},
 	}
 
@@ -282,6 +369,35 @@ func (c *Client) RegisterCmd(network string, channel string, command *cmd.Comman
 	return resp.Id, nil
 }
 
+// DeclarePermissions declares the permissions the extension's commands
+// require, it should be done before the commands are registered.
+func (c *Client) DeclarePermissions(perms ...data.Permission) error {
+	req := &api.DeclarePermissionsRequest{
+		Ext:         c.extension,
+		Permissions: make([]*api.Permission, len(perms)),
+	}
+	for i, p := range perms {
+		req.Permissions[i] = &api.Permission{Name: p.Name, Desc: p.Desc}
+	}
+
+	_, err := c.client.DeclarePermissions(context.Background(), req)
+	return err
+}
+
+// DeclareSettings declares the channel settings the extension uses.
+func (c *Client) DeclareSettings(settings ...data.Setting) error {
+	req := &api.DeclareSettingsRequest{
+		Ext:      c.extension,
+		Settings: make([]*api.Setting, len(settings)),
+	}
+	for i, s := range settings {
+		req.Settings[i] = s.ToProto()
+	}
+
+	_, err := c.client.DeclareSettings(context.Background(), req)
+	return err
+}
+
 // Unregister an event handler
 func (c *Client) Unregister(id uint64) (bool, error) {
 	resp, err := c.client.Unregister(context.Background(), &api.UnregisterRequest{Id: id})
@@ -291,6 +407,7 @@ func (c *Client) Unregister(id uint64) (bool, error) {
 
 	c.mut.Lock()
 	delete(c.events, id)
+	delete(c.pre, id)
 	c.mut.Unlock()
 
 	return resp.Ok, nil
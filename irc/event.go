@@ -25,6 +25,8 @@ type Event struct {
 	NetworkID string `msgpack:"network_id"`
 	// NetworkInfo is the networks information.
 	NetworkInfo *NetworkInfo `msgpack:"-"`
+	// Tags are the IRCv3 message tags the event was sent with.
+	Tags map[string]string `msgpack:"tags"`
 }
 
 // NewEvent constructs a event object that has a timestamp.
@@ -36,7 +38,20 @@ func NewEvent(netID string, ni *NetworkInfo, name, sender string,
 		setArgs = make([]string, len(args))
 		copy(setArgs, args)
 	}
-	return &Event{name, sender, setArgs, time.Now().UTC(), netID, ni}
+	return &Event{
+		Name:        name,
+		Sender:      sender,
+		Args:        setArgs,
+		Time:        time.Now().UTC(),
+		NetworkID:   netID,
+		NetworkInfo: ni,
+	}
+}
+
+// Account returns the services account of the sender from the account
+// message tag. Will be empty string if the tag was not sent.
+func (e *Event) Account() string {
+	return e.Tags[TagAccount]
 }
 
 // Nick returns the nick of the sender. Will be empty string if it was
@@ -14,6 +14,8 @@ const (
 	PRIVMSG = "PRIVMSG"
 	QUIT    = "QUIT"
 	TOPIC   = "TOPIC"
+	CAP     = "CAP"
+	ACCOUNT = "ACCOUNT"
 
 	CTCP      = PRIVMSG
 	CTCPReply = NOTICE
@@ -22,6 +24,7 @@ const (
 // IRC Reply and Error Events. These are sent in reply to a previous event.
 const (
 	RPL_WELCOME         = "001"
+	RPL_WHOISCERTFP     = "276"
 	RPL_YOURHOST        = "002"
 	RPL_CREATED         = "003"
 	RPL_MYINFO          = "004"
@@ -38,6 +41,7 @@ const (
 	RPL_WHOISIDLE       = "317"
 	RPL_ENDOFWHOIS      = "318"
 	RPL_WHOISCHANNELS   = "319"
+	RPL_WHOISACCOUNT    = "330"
 	RPL_WHOWASUSER      = "314"
 	RPL_ENDOFWHOWAS     = "369"
 	RPL_LISTSTART       = "321"
@@ -45,8 +49,10 @@ const (
 	RPL_LISTEND         = "323"
 	RPL_UNIQOPIS        = "325"
 	RPL_CHANNELMODEIS   = "324"
+	RPL_CREATIONTIME    = "329"
 	RPL_NOTOPIC         = "331"
 	RPL_TOPIC           = "332"
+	RPL_TOPICWHOTIME    = "333"
 	RPL_INVITING        = "341"
 	RPL_SUMMONING       = "342"
 	RPL_INVITELIST      = "346"
@@ -171,3 +177,25 @@ const (
 	CONNECT    = "CONNECT"
 	DISCONNECT = "DISCONNECT"
 )
+
+// Store pseudo events are dispatched when the bot's store changes. User and
+// session events have the username as their first argument and channel
+// events the channel, saved users and channels have their revision as the
+// second. Session events are sent by the session's host.
+const (
+	STORE_USER_SAVED      = "STORE_USER_SAVED"
+	STORE_USER_REMOVED    = "STORE_USER_REMOVED"
+	STORE_SESSION_OPENED  = "STORE_SESSION_OPENED"
+	STORE_SESSION_CLOSED  = "STORE_SESSION_CLOSED"
+	STORE_CHANNEL_SAVED   = "STORE_CHANNEL_SAVED"
+	STORE_CHANNEL_REMOVED = "STORE_CHANNEL_REMOVED"
+)
+
+// IRCv3 capabilities and message tags the bot understands.
+const (
+	CapAccountTag    = "account-tag"
+	CapAccountNotify = "account-notify"
+	CapExtendedJoin  = "extended-join"
+
+	TagAccount = "account"
+)
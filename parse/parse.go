@@ -4,6 +4,7 @@ Package parse has functions to parse the irc protocol into irc.IrcMessages.
 package parse
 
 import (
+	"bytes"
 	"regexp"
 	"strings"
 
@@ -37,6 +38,16 @@ func (p ParseError) Error() string {
 // protocol message, split by \r\n, and \r\n should not be
 // present at the end of the string.
 func Parse(str []byte) (*irc.Event, error) {
+	var tags map[string]string
+	if len(str) > 0 && str[0] == '@' {
+		end := bytes.IndexByte(str, ' ')
+		if end < 0 {
+			return nil, ParseError{Irc: string(str)}
+		}
+		tags = parseTags(string(str[1:end]))
+		str = bytes.TrimLeft(str[end:], " ")
+	}
+
 	parts := ircRegex.FindSubmatch(str)
 	if parts == nil {
 		return nil, ParseError{Irc: string(str)}
@@ -57,5 +68,59 @@ func Parse(str []byte) (*irc.Event, error) {
 		}
 	}
 
-	return irc.NewEvent("", nil, name, sender, args...), nil
+	ev := irc.NewEvent("", nil, name, sender, args...)
+	ev.Tags = tags
+	return ev, nil
+}
+
+// parseTags parses the IRCv3 message tags that prefix a message, without the
+// leading @.
+func parseTags(str string) map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(str, ";") {
+		if len(tag) == 0 {
+			continue
+		}
+
+		key, value := tag, ""
+		if i := strings.IndexByte(tag, '='); i >= 0 {
+			key, value = tag[:i], unescapeTag(tag[i+1:])
+		}
+		tags[key] = value
+	}
+	return tags
+}
+
+// unescapeTag reverses the escaping of a message tag value.
+func unescapeTag(value string) string {
+	if strings.IndexByte(value, '\\') < 0 {
+		return value
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '\\' {
+			buf.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i >= len(value) {
+			break
+		}
+		switch value[i] {
+		case ':':
+			buf.WriteByte(';')
+		case 's':
+			buf.WriteByte(' ')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'n':
+			buf.WriteByte('\n')
+		default:
+			buf.WriteByte(value[i])
+		}
+	}
+	return buf.String()
 }
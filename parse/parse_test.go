@@ -77,3 +77,40 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestParse_Tags(t *testing.T) {
+	ev, err := Parse(b(`@account=bob;time=2020-01-01T00:00:00.000Z;+draft/x=a\sb\:c\;flag :nick!user@host PRIVMSG #chan :hello`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ev.Name != irc.PRIVMSG || ev.Sender != "nick!user@host" || len(ev.Args) != 2 {
+		t.Errorf("Wrong event: %#v", ev)
+	}
+
+	exp := map[string]string{
+		"account":  "bob",
+		"time":     "2020-01-01T00:00:00.000Z",
+		"+draft/x": "a b;c",
+		"flag":     "",
+	}
+	if len(ev.Tags) != len(exp) {
+		t.Errorf("Expected %d tags, got: %v", len(exp), ev.Tags)
+	}
+	for k, v := range exp {
+		if got, ok := ev.Tags[k]; !ok || got != v {
+			t.Errorf("Expected tag %s to be %q, got: %q", k, v, got)
+		}
+	}
+	if ev.Account() != "bob" {
+		t.Error("Expected the account, got:", ev.Account())
+	}
+
+	if _, err = Parse(b("@account=bob")); err == nil {
+		t.Error("Expected tags without a message to fail.")
+	}
+
+	if ev, _ = Parse(b(":irc PING :1")); ev.Tags != nil {
+		t.Error("Expected no tags, got:", ev.Tags)
+	}
+}
@@ -36,6 +36,23 @@ func (h *holder) Register(network, channel, event string, handler dispatch.Handl
 	return id
 }
 
+// RegisterPriority and save the token we get back.
+func (h *holder) RegisterPriority(network, channel, event string, priority int, handler dispatch.Handler) uint64 {
+	id := h.registrar.RegisterPriority(network, channel, event, priority, handler)
+	h.events[id] = struct{}{}
+
+	return id
+}
+
+// RegisterPre and save the token we get back, it's unregistered like any
+// other event handler.
+func (h *holder) RegisterPre(network, channel, event string, priority int, handler dispatch.PreHandler) uint64 {
+	id := h.registrar.RegisterPre(network, channel, event, priority, handler)
+	h.events[id] = struct{}{}
+
+	return id
+}
+
 // RegisterCmd and save the names we use for later
 func (h *holder) RegisterCmd(network, channel string, command *cmd.Command) (uint64, error) {
 	id, err := h.registrar.RegisterCmd(network, channel, command)
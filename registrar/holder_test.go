@@ -25,6 +25,18 @@ func (m *mockReg) Register(_, _, _ string, _ dispatch.Handler) uint64 {
 	return m.id
 }
 
+func (m *mockReg) RegisterPriority(_, _, _ string, _ int, _ dispatch.Handler) uint64 {
+	m.regs++
+	m.id++
+	return m.id
+}
+
+func (m *mockReg) RegisterPre(_, _, _ string, _ int, _ dispatch.PreHandler) uint64 {
+	m.regs++
+	m.id++
+	return m.id
+}
+
 func (m *mockReg) RegisterCmd(_, _ string, _ *cmd.Command) (uint64, error) {
 	m.cmds++
 	return 0, m.err
@@ -89,6 +101,25 @@ func TestHolder_Register(t *testing.T) {
 	m.verifyMock(t, 1, 0, 0, 0)
 }
 
+func TestHolder_RegisterPriority(t *testing.T) {
+	t.Parallel()
+
+	m := &mockReg{}
+	h := newHolder(m)
+
+	id := h.RegisterPriority("n", "c", "e", 1, nil)
+	pre := h.RegisterPre("n", "c", "e", 1, nil)
+	if _, ok := h.events[id]; !ok {
+		t.Error("did not record the registration")
+	}
+	if _, ok := h.events[pre]; !ok {
+		t.Error("did not record the pre registration")
+	}
+
+	h.unregisterAll()
+	m.verifyMock(t, 2, 2, 0, 0)
+}
+
 func TestHolder_Unregister(t *testing.T) {
 	t.Parallel()
 
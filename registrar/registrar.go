@@ -8,6 +8,8 @@ import (
 // Interface is the operations performable by a registrar.
 type Interface interface {
 	Register(network, channel, event string, handler dispatch.Handler) uint64
+	RegisterPriority(network, channel, event string, priority int, handler dispatch.Handler) uint64
+	RegisterPre(network, channel, event string, priority int, handler dispatch.PreHandler) uint64
 	RegisterCmd(network, channel string, command *cmd.Command) (uint64, error)
 
 	Unregister(id uint64) bool